@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kpango/BuildBureau/internal/admin"
+)
+
+const agentsUsage = `Usage:
+  buildbureau agents list
+  buildbureau agents describe <agent-id>
+  buildbureau agents set-model <role|agent-id> <model>
+  buildbureau agents logs [-n lines] <agent-id>
+
+Flags:
+`
+
+// runAgents manages the agents of a running organization through its admin API
+// and returns the process exit code.
+func runAgents(args []string) int {
+	fs := flag.NewFlagSet("agents", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), agentsUsage)
+		fs.PrintDefaults()
+	}
+	addr := fs.String("addr", envOr("BUILDBUREAU_ADMIN_ADDR", admin.DefaultAddress), "admin API address")
+	token := fs.String("token", os.Getenv("BUILDBUREAU_ADMIN_TOKEN"), "admin API bearer token")
+	org := fs.String("org", os.Getenv("BUILDBUREAU_ORG"), "ID of the organization to address, when the process hosts several")
+	timeout := fs.Duration("timeout", 5*time.Minute, "time limit, including waiting for in-flight generations to drain")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	client := admin.NewClient(*addr, *token).Organization(*org)
+
+	switch fs.Arg(0) {
+	case "list":
+		agents, err := client.Agents(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tROLE\tMODEL\tACTIVE\tCOMPLETED\tCONTEXT")
+		for _, a := range agents {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%s\n", a.ID, a.Role, a.Model, a.ActiveTasks, a.CompletedTasks, a.Context)
+		}
+		_ = w.Flush()
+		return 0
+
+	case "describe":
+		if fs.NArg() != 2 {
+			fs.Usage()
+			return 2
+		}
+		d, err := client.DescribeAgent(ctx, fs.Arg(1))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(w, "ID\t%s\nROLE\t%s\nTEAM\t%s\nMODEL\t%s\n", d.ID, d.Role, d.Team, d.Model)
+		fmt.Fprintf(w, "CAPABILITIES\t%s\nSTATUS\t%s\nACTIVE\t%d\nCOMPLETED\t%d\nVERSION\t%s\n",
+			strings.Join(d.Capabilities, ", "), d.Status, d.ActiveTasks, d.CompletedTasks, d.Version)
+		_ = w.Flush()
+		return 0
+
+	case "set-model":
+		if fs.NArg() != 3 {
+			fs.Usage()
+			return 2
+		}
+		fmt.Printf("Switching %s to %s, waiting for in-flight generations to finish...\n", fs.Arg(1), fs.Arg(2))
+		updated, err := client.SetModel(ctx, fs.Arg(1), fs.Arg(2))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("✓ %d agent(s) now use %s: %s\n", len(updated), fs.Arg(2), strings.Join(updated, ", "))
+		return 0
+
+	case "logs":
+		return agentLogs(ctx, client, fs.Args()[1:])
+
+	default:
+		fs.Usage()
+		return 2
+	}
+}
+
+// agentLogs prints the tail of an agent's activity log.
+func agentLogs(ctx context.Context, client *admin.Client, args []string) int {
+	fs := flag.NewFlagSet("logs", flag.ContinueOnError)
+	lines := fs.Int("n", 50, "number of entries to show")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprint(os.Stderr, agentsUsage)
+		return 2
+	}
+
+	entries, err := client.Logs(ctx, fs.Arg(0), *lines)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	for _, e := range entries {
+		header := fmt.Sprintf("%s [%s]", e.Time.Format(time.RFC3339), e.Kind)
+		if e.Model != "" {
+			header += " model=" + e.Model
+		}
+		if e.RunID != "" {
+			header += " run=" + e.RunID
+		}
+		fmt.Printf("%s\n%s\n\n", header, e.Content)
+	}
+	return 0
+}
+
+// envOr returns the value of the environment variable, or fallback if it is unset.
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
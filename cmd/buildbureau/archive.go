@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kpango/BuildBureau/internal/memory"
+)
+
+const archiveUsage = `Usage:
+  buildbureau archive list
+  buildbureau archive run [-older-than duration]
+  buildbureau archive restore <run-id>
+
+Moves the memories of runs with no activity for a while out of the memory
+database into compressed bundles in memory.archive.dir, and restores them on
+demand. Archiving also runs periodically while BuildBureau is running if
+memory.archive.enabled is set.
+
+Flags:
+`
+
+// runArchive manages archived runs in the memory database and returns the
+// process exit code.
+func runArchive(configPath string, args []string) int {
+	fs := flag.NewFlagSet("archive", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), archiveUsage)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&configPath, "config", configPath, "path to config.yaml")
+	timeout := fs.Duration("timeout", 10*time.Minute, "time limit for archiving or restoring")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to load configuration: %v\n", err)
+		return 1
+	}
+
+	mem, err := memory.NewManager(cfg.Memory, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to open memory: %v\n", err)
+		return 1
+	}
+	defer mem.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	switch fs.Arg(0) {
+	case "list":
+		runs, err := mem.ArchivedRuns(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "RUN\tENTRIES\tLAST ACTIVITY\tARCHIVED\tBUNDLE")
+		for _, r := range runs {
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", r.RunID, r.Entries, r.LastActivity.Format(time.RFC3339), r.ArchivedAt.Format(time.RFC3339), r.Path)
+		}
+		_ = w.Flush()
+		return 0
+
+	case "run":
+		run := flag.NewFlagSet("run", flag.ContinueOnError)
+		olderThan := run.Duration("older-than", cfg.Memory.Archive.After, "archive runs inactive for longer than this (default memory.archive.after)")
+		if err := run.Parse(fs.Args()[1:]); err != nil {
+			return 2
+		}
+		if *olderThan <= 0 {
+			fmt.Fprintln(os.Stderr, "Error: -older-than is required when memory.archive.after is not set")
+			return 2
+		}
+
+		archived, err := mem.ArchiveRuns(ctx, time.Now().Add(-*olderThan))
+		for _, r := range archived {
+			fmt.Printf("✓ Archived %s (%d entries) to %s\n", r.RunID, r.Entries, r.Path)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if len(archived) == 0 {
+			fmt.Println("No runs to archive")
+		}
+		return 0
+
+	case "restore":
+		if fs.NArg() != 2 {
+			fs.Usage()
+			return 2
+		}
+		restored, err := mem.RestoreRun(ctx, fs.Arg(1))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("✓ Restored %d entries of %s\n", restored, fs.Arg(1))
+		return 0
+
+	default:
+		fs.Usage()
+		return 2
+	}
+}
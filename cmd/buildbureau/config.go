@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kpango/BuildBureau/internal/admin"
+	"github.com/kpango/BuildBureau/internal/config"
+)
+
+const configUsage = `Usage:
+  buildbureau config [flags] diff <new-config>
+  buildbureau config [flags] apply <new-config>
+
+diff shows what replacing the configuration with new-config changes: agents
+added or removed, models and budgets changed, and whether each change is
+applied live or takes effect at restart. apply shows the same, asks before
+changes that restart the organization, switches models of the running
+organization through the admin API and installs new-config in place of the
+configuration, keeping the previous one as <config>.bak.
+
+Flags:
+`
+
+// runConfig previews or applies a new configuration, and returns the process
+// exit code.
+func runConfig(configPath string, args []string) int {
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), configUsage)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&configPath, "config", configPath, "path to the current config.yaml")
+	yes := fs.Bool("yes", false, "apply changes that restart the organization without asking")
+	addr := fs.String("addr", envOr("BUILDBUREAU_ADMIN_ADDR", admin.DefaultAddress), "admin API address of the running organization")
+	token := fs.String("token", os.Getenv("BUILDBUREAU_ADMIN_TOKEN"), "admin API bearer token")
+	org := fs.String("org", os.Getenv("BUILDBUREAU_ORG"), "ID of the organization to address, when the process hosts several")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 || (fs.Arg(0) != "diff" && fs.Arg(0) != "apply") {
+		fs.Usage()
+		return 2
+	}
+	nextPath := fs.Arg(1)
+
+	loader := config.NewLoader()
+	current, err := loader.Load(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to load configuration: %v\n", err)
+		return 1
+	}
+	next, err := loader.Load(nextPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to load %s: %v\n", nextPath, err)
+		return 1
+	}
+	diff, err := loader.Compare(current, next)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if diff.Empty() {
+		fmt.Println("No changes")
+		return 0
+	}
+	printDiff(os.Stdout, diff)
+	if fs.Arg(0) == "diff" {
+		return 0
+	}
+
+	disruptive := diff.Disruptive()
+	if len(disruptive) > 0 && !*yes && !confirm(os.Stdin, fmt.Sprintf("%d changes restart the organization, stopping running projects. Apply them? [y/N] ", len(disruptive))) {
+		fmt.Println("Nothing applied")
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	client := admin.NewClient(*addr, *token).Organization(*org)
+	for _, c := range diff.Live() {
+		if _, err := client.SetModel(ctx, c.Target, c.New); err != nil {
+			fmt.Printf("Warning: %s model not switched live, it changes at restart: %v\n", c.Target, err)
+			continue
+		}
+		fmt.Printf("Switched %s to %s\n", c.Target, c.New)
+	}
+
+	if err := install(configPath, nextPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Installed %s as %s\n", nextPath, configPath)
+	if len(disruptive) > 0 {
+		fmt.Printf("Restart BuildBureau to apply %d changes\n", len(disruptive))
+	}
+	return 0
+}
+
+// printDiff writes the changes of diff grouped by category.
+func printDiff(out io.Writer, diff *config.Diff) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for i, c := range diff.Changes {
+		if i == 0 || c.Category != diff.Changes[i-1].Category {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintf(w, "%s:\n", strings.ToUpper(string(c.Category[:1]))+string(c.Category[1:]))
+		}
+		effect := "live"
+		if c.Restart {
+			effect = "restart"
+		}
+		fmt.Fprintf(w, "  %s %s\t%s\t%s\n", changeSign[c.Kind], c.Path, changeValue(c), effect)
+	}
+	_ = w.Flush()
+}
+
+// changeSign marks each kind of change in a diff.
+var changeSign = map[config.ChangeKind]string{
+	config.ChangeAdded:    "+",
+	config.ChangeRemoved:  "-",
+	config.ChangeModified: "~",
+}
+
+// changeValue describes the values of a change and what it means.
+func changeValue(c config.Change) string {
+	var value string
+	switch {
+	case c.Kind == config.ChangeAdded:
+		value = c.New
+	case c.Kind == config.ChangeRemoved:
+		value = c.Old
+	case c.Old != "" || c.New != "":
+		value = c.Old + " -> " + c.New
+	}
+	switch {
+	case c.Detail == "":
+		return value
+	case value == "":
+		return c.Detail
+	default:
+		return value + " (" + c.Detail + ")"
+	}
+}
+
+// confirm asks question and reports whether the answer was yes.
+func confirm(in io.Reader, question string) bool {
+	fmt.Print(question)
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// install replaces the configuration at path with the file at next, keeping
+// the previous configuration as path.bak.
+func install(path, next string) error {
+	data, err := os.ReadFile(next)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", next, err)
+	}
+	if err := os.Rename(path, path+".bak"); err != nil {
+		return fmt.Errorf("failed to back up configuration: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write configuration: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kpango/BuildBureau/internal/doctor"
+)
+
+// runDoctor runs environment diagnostics and returns the process exit code.
+func runDoctor(configPath string, args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	fs.StringVar(&configPath, "config", configPath, "path to config.yaml")
+	grpcPort := fs.Int("grpc-port", doctor.DefaultGRPCPort, "gRPC port to check for availability")
+	timeout := fs.Duration("timeout", time.Minute, "overall time limit for all checks")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	fmt.Printf("BuildBureau doctor (%s)\n\n", configPath)
+
+	d := doctor.New(configPath, doctor.WithGRPCPort(*grpcPort))
+	if !doctor.Report(os.Stdout, d.Run(ctx)) {
+		return 1
+	}
+	return 0
+}
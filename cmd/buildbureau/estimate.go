@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kpango/BuildBureau/internal/agent"
+)
+
+const estimateUsage = `Usage:
+  buildbureau estimate [flags] <instruction>
+
+Projects the tokens, cost and time a project with the instruction would use,
+per role, before running it. Only the planning layers work: the director
+partitions the request and each manager drafts its design, which tells how
+complex the implementation is and how many review rounds it gets. The usage
+of past projects, recorded in memory as they finish, is scaled to the planned
+tasks; without history, ranges span the prompts up to the output budgets.
+Drafting the plan itself uses tokens, which are shown.
+
+Flags:
+`
+
+// runEstimate projects the usage of a project and returns the process exit code.
+func runEstimate(configPath string, args []string) int {
+	fs := flag.NewFlagSet("estimate", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), estimateUsage)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&configPath, "config", configPath, "path to config.yaml")
+	asJSON := fs.Bool("json", false, "write the estimate as JSON")
+	timeout := fs.Duration("timeout", 10*time.Minute, "time limit for drafting the plan")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	instruction := strings.TrimSpace(strings.Join(fs.Args(), " "))
+	if instruction == "" {
+		fs.Usage()
+		return 2
+	}
+
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to load configuration: %v\n", err)
+		return 1
+	}
+	org, err := agent.NewOrganization(agent.RestrictForDemo(cfg))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to create organization: %v\n", err)
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	if err := org.Start(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to start organization: %v\n", err)
+		return 1
+	}
+	defer func() {
+		if err := org.Stop(context.Background()); err != nil {
+			fmt.Fprintf(os.Stderr, "Error stopping organization: %v\n", err)
+		}
+	}()
+
+	est, err := org.Estimate(ctx, instruction)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(est); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	printEstimate(os.Stdout, est)
+	return 0
+}
+
+// printEstimate writes the plan an estimate was drawn from and the projected
+// usage of each role.
+func printEstimate(out io.Writer, est *agent.Estimate) {
+	if len(est.Parts) > 0 {
+		fmt.Fprintln(out, "Plan:")
+		for _, p := range est.Parts {
+			fmt.Fprintf(out, "  %s (%s): %s complexity, %d review round(s)\n", p.Title, p.Manager, p.Complexity, p.PairRounds)
+		}
+		fmt.Fprintln(out)
+	}
+	if p := est.Planning; p != nil {
+		fmt.Fprintf(out, "Drafting the plan used %d tokens, $%.4f, %s\n\n", p.PromptTokens+p.CompletionTokens, p.Cost, p.Latency.Round(time.Second))
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ROLE\tTASKS\tTOKENS\tCOST\tTIME")
+	for _, r := range append(est.Roles, est.Total) {
+		role := string(r.Role)
+		if role == "" {
+			role = "Total"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d - %d\t$%.4f - $%.4f\t%s\n", role, r.Tasks, r.Tokens.Low, r.Tokens.High, r.Cost.Low, r.Cost.High, timeRange(r.Time))
+	}
+	_ = w.Flush()
+
+	fmt.Fprintln(out)
+	if est.Projects > 0 {
+		fmt.Fprintf(out, "Based on %d past project(s)\n", est.Projects)
+	} else {
+		fmt.Fprintln(out, "No past projects recorded: ranges span the prompts up to the output budgets; time is unknown")
+	}
+}
+
+// timeRange describes an estimated span of time.
+func timeRange(r agent.Range[time.Duration]) string {
+	if r.High == 0 {
+		return "unknown"
+	}
+	return r.Low.Round(time.Second).String() + " - " + r.High.Round(time.Second).String()
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/kpango/BuildBureau/internal/memory"
+	"github.com/kpango/BuildBureau/internal/report"
+)
+
+const exportUsage = `Usage:
+  buildbureau export [-format markdown|html] [-o file] <run-id>
+
+Renders the transcript of a project, read from the memory database, as a
+Markdown or HTML report. The run ID is reported by the TUI when a task finishes.
+
+Flags:
+`
+
+// runExport writes the report of a past run and returns the process exit code.
+func runExport(configPath string, args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), exportUsage)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&configPath, "config", configPath, "path to config.yaml")
+	format := fs.String("format", report.FormatMarkdown, "report format: markdown or html")
+	output := fs.String("o", "", "output file (default <run-id>.md or <run-id>.html, - for stdout)")
+	timeout := fs.Duration("timeout", time.Minute, "time limit for reading the memory database")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	runID := fs.Arg(0)
+
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to load configuration: %v\n", err)
+		return 1
+	}
+
+	mem, err := memory.NewManager(cfg.Memory, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to open memory: %v\n", err)
+		return 1
+	}
+	defer mem.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	memories, err := mem.GetRunMemories(ctx, runID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if len(memories) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: no memories recorded for run %s\n", runID)
+		return 1
+	}
+
+	path := *output
+	if path == "" {
+		path = runID + report.Extension(*format)
+	}
+
+	var w io.Writer = os.Stdout
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := report.Render(w, report.Build(runID, memories), *format); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if path != "-" {
+		fmt.Printf("✓ Report written to %s\n", path)
+	}
+	return 0
+}
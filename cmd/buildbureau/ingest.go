@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kpango/BuildBureau/internal/agent"
+	"github.com/kpango/BuildBureau/internal/ingest"
+	"github.com/kpango/BuildBureau/internal/llm"
+	"github.com/kpango/BuildBureau/internal/memory"
+)
+
+const ingestUsage = `Usage:
+  buildbureau ingest [-tags tag,...] <path>...
+
+Seeds the organization's shared knowledge with existing documentation.
+Markdown, text, HTML, PDF (with pdftotext), Word and Excel documents are split into
+chunks along their headings and stored as knowledge memories, embedded when
+Vald is enabled. Agents are offered the chunks relevant to their tasks when
+organization.knowledge_sharing is enabled. Ingesting a document again replaces
+its chunks.
+
+Flags:
+`
+
+// runIngest stores documents as shared knowledge and returns the process exit code.
+func runIngest(configPath string, args []string) int {
+	fs := flag.NewFlagSet("ingest", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), ingestUsage)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&configPath, "config", configPath, "path to config.yaml")
+	tags := fs.String("tags", "", "comma-separated tags added to every chunk, e.g. architecture")
+	chunkSize := fs.Int("chunk-size", ingest.DefaultChunkSize, "maximum characters per chunk")
+	pdftotext := fs.String("pdftotext", "pdftotext", "pdftotext binary used to read PDFs")
+	timeout := fs.Duration("timeout", 10*time.Minute, "time limit for ingesting every document")
+
+	// Flags may follow the paths, as in "ingest ./docs -tags architecture"
+	var paths []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return 2
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		paths = append(paths, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if len(paths) == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to load configuration: %v\n", err)
+		return 1
+	}
+
+	// Embeddings are generated through the LLM manager
+	llmManager, err := llm.NewManager(&cfg.LLMs)
+	if err != nil {
+		fmt.Printf("Warning: storing documents without embeddings: %v\n", err)
+		llmManager = nil
+	}
+	mem, err := memory.NewManager(cfg.Memory, llmManager)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to open memory: %v\n", err)
+		return 1
+	}
+	defer mem.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	var files []string
+	for _, path := range paths {
+		found, err := ingest.Files(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		files = append(files, found...)
+	}
+	if len(files) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: no Markdown, text, HTML, PDF, Word or Excel documents found")
+		return 1
+	}
+
+	var tagList []string
+	for tag := range strings.SplitSeq(*tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tagList = append(tagList, tag)
+		}
+	}
+
+	reader := ingest.New(ingest.WithChunkSize(*chunkSize), ingest.WithPDFToText(*pdftotext))
+	knowledge := agent.NewKnowledgeBase(mem, cfg.Organization.Knowledge)
+	status, total, documents := 0, 0, 0
+	for _, file := range files {
+		chunks, err := reader.Read(ctx, file)
+		if err == nil {
+			var n int
+			n, err = knowledge.Ingest(ctx, file, chunks, tagList...)
+			total += n
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			status = 1
+			continue
+		}
+		documents++
+		fmt.Printf("✓ %s: %d chunk(s)\n", file, len(chunks))
+	}
+
+	fmt.Printf("Ingested %d chunk(s) from %d document(s)\n", total, documents)
+	if !cfg.Organization.Knowledge.Enabled {
+		fmt.Println("Note: enable organization.knowledge_sharing for agents to use them")
+	}
+	return status
+}
@@ -1,19 +1,33 @@
 package main
 
 import (
+	"cmp"
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"os/user"
+	"syscall"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/kpango/BuildBureau/internal/admin"
 	"github.com/kpango/BuildBureau/internal/agent"
 	"github.com/kpango/BuildBureau/internal/config"
+	"github.com/kpango/BuildBureau/internal/state"
 	"github.com/kpango/BuildBureau/internal/tui"
+	"github.com/kpango/BuildBureau/internal/version"
+	"github.com/kpango/BuildBureau/pkg/types"
 )
 
 const (
 	defaultConfigPath = "config.yaml"
+
+	// defaultDrainTimeout is how long SIGTERM waits for running projects when
+	// organization.shutdown.drain_timeout is not set.
+	defaultDrainTimeout = 30 * time.Second
 )
 
 func main() {
@@ -23,13 +37,70 @@ func main() {
 		configPath = defaultConfigPath
 	}
 
+	if len(os.Args) > 1 && (os.Args[1] == "version" || os.Args[1] == "--version") {
+		fmt.Println("buildbureau " + version.Get().String())
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "doctor" {
+		os.Exit(runDoctor(configPath, os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "agents" {
+		os.Exit(runAgents(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "questions" {
+		os.Exit(runQuestions(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		os.Exit(runExport(configPath, os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "archive" {
+		os.Exit(runArchive(configPath, os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "ingest" {
+		os.Exit(runIngest(configPath, os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		os.Exit(runServe(configPath, os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(runConfig(configPath, os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "estimate" {
+		os.Exit(runEstimate(configPath, os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "service" {
+		os.Exit(runService(configPath, os.Args[2:]))
+	}
+
+	// --demo runs a public demo instance whatever the configuration enables;
+	// the admin API and other integrations are dropped before they start
+	fs := flag.NewFlagSet("buildbureau", flag.ExitOnError)
+	demo := fs.Bool("demo", false, "run as a public demo instance")
+	_ = fs.Parse(os.Args[1:])
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "Error: unknown command %q\n", fs.Arg(0))
+		os.Exit(2)
+	}
+
 	// Load configuration
-	loader := config.NewLoader()
-	cfg, err := loader.Load(configPath)
+	cfg, err := loadConfig(configPath)
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
+	// Keep another instance from using the same state directory
+	lock, err := state.Acquire(cfg)
+	if err != nil {
+		log.Fatalf("Failed to lock state directory: %v", err)
+	}
+	defer lock.Release()
+
+	if *demo {
+		cfg.Demo = cmp.Or(cfg.Demo, &types.DemoConfig{})
+		cfg.Demo.Enabled = true
+	}
+	cfg = agent.RestrictForDemo(cfg)
+
 	// Create organization
 	org, err := agent.NewOrganization(cfg)
 	if err != nil {
@@ -47,15 +118,86 @@ func main() {
 		}
 	}()
 
-	// Start TUI
+	// Serve the admin API so the organization can be managed while it runs
+	if cfg.Admin != nil && cfg.Admin.Enabled {
+		server := admin.NewServer(org,
+			admin.WithAddress(cfg.Admin.Address),
+			admin.WithToken(config.GetEnvValue(cfg.Admin.Token)),
+		)
+		if err := server.Start(); err != nil {
+			log.Fatalf("Failed to start admin API: %v", err)
+		}
+		defer func() {
+			if err := server.Stop(ctx); err != nil {
+				log.Printf("Error stopping admin API: %v", err)
+			}
+		}()
+	}
+
+	// Start TUI. Signals are handled below rather than by the TUI, so that
+	// SIGTERM drains running projects before quitting.
 	p := tea.NewProgram(
-		tui.NewModel(org),
+		tui.NewModel(org).WithUser(submitter()),
 		tea.WithAltScreen(),
 		tea.WithMouseCellMotion(),
+		tea.WithoutSignalHandler(),
 	)
 
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	defer signal.Stop(signals)
+	drained := make(chan error, 1)
+	go func() {
+		<-signals
+		drained <- drain(ctx, org, cfg.Organization.Shutdown.DrainTimeout)
+		p.Quit()
+	}()
+
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("Error running program: %v\n", err)
 		os.Exit(1)
 	}
+	select {
+	case err := <-drained:
+		if err != nil {
+			log.Printf("Warning: shutting down with projects still running: %v", err)
+		}
+	default:
+	}
+}
+
+// loadConfig loads the configuration at path, placing the files it keeps in
+// its state directories if it enables them.
+func loadConfig(path string) (*types.Config, error) {
+	cfg, err := config.NewLoader().Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := state.Apply(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// drain stops the organization admitting projects, failing readiness, and
+// waits up to timeout for the running ones to finish.
+func drain(ctx context.Context, org *agent.Organization, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = defaultDrainTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return org.Drain(ctx)
+}
+
+// submitter returns the user that projects submitted from the TUI are
+// attributed to: BUILDBUREAU_USER if set, otherwise the login name.
+func submitter() string {
+	if name := os.Getenv("BUILDBUREAU_USER"); name != "" {
+		return name
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
 }
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kpango/BuildBureau/internal/admin"
+)
+
+const questionsUsage = `Usage:
+  buildbureau questions list
+  buildbureau questions answer <question-id> <answer>
+
+Flags:
+`
+
+// runQuestions lists and answers agents' questions waiting for a human through
+// the admin API of a running organization, and returns the process exit code.
+func runQuestions(args []string) int {
+	fs := flag.NewFlagSet("questions", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), questionsUsage)
+		fs.PrintDefaults()
+	}
+	addr := fs.String("addr", envOr("BUILDBUREAU_ADMIN_ADDR", admin.DefaultAddress), "admin API address")
+	token := fs.String("token", os.Getenv("BUILDBUREAU_ADMIN_TOKEN"), "admin API bearer token")
+	org := fs.String("org", os.Getenv("BUILDBUREAU_ORG"), "ID of the organization to address, when the process hosts several")
+	timeout := fs.Duration("timeout", 30*time.Second, "time limit")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	client := admin.NewClient(*addr, *token).Organization(*org)
+
+	switch fs.Arg(0) {
+	case "list":
+		questions, err := client.Questions(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if len(questions) == 0 {
+			fmt.Println("No questions are waiting for an answer.")
+			return 0
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tFROM\tASKED\tQUESTION")
+		for _, q := range questions {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", q.ID, q.From, q.Asked.Format(time.TimeOnly), q.Text)
+		}
+		_ = w.Flush()
+		return 0
+
+	case "answer":
+		if fs.NArg() < 3 {
+			fs.Usage()
+			return 2
+		}
+		if err := client.AnswerQuestion(ctx, fs.Arg(1), strings.Join(fs.Args()[2:], " ")); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("✓ Answered question %s\n", fs.Arg(1))
+		return 0
+
+	default:
+		fs.Usage()
+		return 2
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+
+	"github.com/kpango/BuildBureau/internal/admin"
+	"github.com/kpango/BuildBureau/internal/agent"
+	"github.com/kpango/BuildBureau/internal/config"
+	"github.com/kpango/BuildBureau/internal/grpc"
+	"github.com/kpango/BuildBureau/internal/host"
+	"github.com/kpango/BuildBureau/internal/service"
+	"github.com/kpango/BuildBureau/internal/state"
+)
+
+const serveUsage = `Usage:
+  buildbureau serve [-config path]
+
+Runs BuildBureau without the TUI, hosting the organization configured by the
+configuration file, as "default", and each listed under hosting.organizations
+with a configuration file of its own. With admin.enabled, the admin API of
+every organization is served under /v1/orgs/{org}; the default one is also
+served at the unprefixed paths. POST /v1/orgs/{org}/projects submits a
+project. With hosting.grpc_port, projects are also accepted over gRPC, the
+organization named in the buildbureau-org metadata. Both require admin.token
+when it is set, and a public demo organization is only hosted behind one.
+SIGTERM, or stopping the Windows service, drains running projects before
+exiting. Organizations enabling state directories lock them, so a second
+process cannot use them at the same time.
+
+Flags:
+`
+
+// runServe hosts the configured organizations behind the admin API until the
+// process is signaled or the service stopped, and returns the process exit code.
+func runServe(configPath string, args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), serveUsage)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&configPath, "config", configPath, "path to config.yaml")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	return service.Run(func(stop <-chan struct{}) int {
+		return serve(configPath, stop)
+	})
+}
+
+// serve hosts the organizations configured at configPath until the process
+// is signaled or stop is closed, and returns the process exit code.
+func serve(configPath string, stop <-chan struct{}) int {
+	configs, err := host.LoadConfigs(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to load configuration: %v\n", err)
+		return 1
+	}
+	// The default organization's admin settings, like the rest of its
+	// configuration, are what remains once demo restrictions apply
+	cfg := agent.RestrictForDemo(configs[host.DefaultID])
+	serveAdmin := cfg.Admin != nil && cfg.Admin.Enabled
+	token := ""
+	if cfg.Admin != nil {
+		token = config.GetEnvValue(cfg.Admin.Token)
+	}
+	grpcPort := 0
+	if cfg.Hosting != nil {
+		grpcPort = cfg.Hosting.GRPCPort
+	}
+	if (serveAdmin || grpcPort > 0) && token == "" {
+		for _, id := range slices.Sorted(maps.Keys(configs)) {
+			if demo := configs[id].Demo; demo != nil && demo.Enabled {
+				fmt.Fprintf(os.Stderr, "Error: organization %s is a public demo; set admin.token to serve it behind the admin API or gRPC\n", id)
+				return 1
+			}
+		}
+	}
+	for _, id := range slices.Sorted(maps.Keys(configs)) {
+		lock, err := state.Acquire(configs[id])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: organization %s: %v\n", id, err)
+			return 1
+		}
+		defer lock.Release()
+	}
+
+	h, err := host.New(configs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	ctx := context.Background()
+	if err := h.Start(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer func() {
+		if err := h.Stop(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "Error stopping organizations: %v\n", err)
+		}
+	}()
+
+	var server *admin.Server
+	if serveAdmin {
+		defaultOrg, _ := h.Organization(host.DefaultID)
+		opts := []admin.Option{admin.WithAddress(cfg.Admin.Address), admin.WithToken(config.GetEnvValue(cfg.Admin.Token))}
+		for _, id := range h.IDs() {
+			org, _ := h.Organization(id)
+			opts = append(opts, admin.WithOrganization(id, org))
+		}
+		server = admin.NewServer(defaultOrg, opts...)
+		if err := server.Start(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to start admin API: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Serving %d organizations on %s\n", len(h.IDs()), server.Addr())
+	} else {
+		fmt.Printf("Serving %d organizations without the admin API\n", len(h.IDs()))
+	}
+
+	// Projects are accepted over gRPC too, addressed by the org ID in the call's metadata
+	var rpc *grpc.Server
+	if grpcPort > 0 {
+		defaultOrg, _ := h.Organization(host.DefaultID)
+		opts := []grpc.ServerOption{grpc.WithToken(token)}
+		for _, id := range h.IDs() {
+			org, _ := h.Organization(id)
+			opts = append(opts, grpc.WithOrganization(id, org.Gateway(), nil))
+		}
+		rpc = grpc.NewServer(defaultOrg.Gateway(), grpcPort, opts...)
+		if err := rpc.Start(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to start gRPC server: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Accepting projects over gRPC on %s\n", rpc.Addr())
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	defer signal.Stop(signals)
+	select {
+	case <-signals:
+	case <-stop:
+	}
+
+	timeout := cfg.Organization.Shutdown.DrainTimeout
+	if timeout <= 0 {
+		timeout = defaultDrainTimeout
+	}
+	drainCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if err := h.Drain(drainCtx); err != nil {
+		fmt.Printf("Warning: shutting down with projects still running: %v\n", err)
+	}
+	if rpc != nil {
+		if err := rpc.Stop(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "Error stopping gRPC server: %v\n", err)
+		}
+	}
+	if server != nil {
+		if err := server.Stop(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "Error stopping admin API: %v\n", err)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/kpango/BuildBureau/internal/service"
+	"github.com/kpango/BuildBureau/internal/state"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+const serviceUsage = `Usage:
+  buildbureau service [flags] install
+  buildbureau service [flags] uninstall
+  buildbureau service [flags] unit
+
+Installs "buildbureau serve" as a service: a systemd unit on Linux, a launchd
+job on macOS and a Windows service. install enables the service, uninstall
+stops and removes it, keeping its state, and unit prints what install would
+write. Set state.enabled in the configuration so the service keeps its files
+in the platform's state directories.
+
+Flags:
+`
+
+// runService installs or removes the service running serve, and returns the
+// process exit code.
+func runService(configPath string, args []string) int {
+	fs := flag.NewFlagSet("service", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), serviceUsage)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&configPath, "config", configPath, "path to config.yaml")
+	name := fs.String("name", service.Name, "name of the service")
+	user := fs.Bool("user", false, "install for the current user rather than system-wide")
+	envFile := fs.String("env-file", "", "file of environment variables, such as API keys, for the systemd unit")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	opts := service.Options{Name: *name, EnvironmentFile: *envFile, User: *user}
+	var err error
+	if opts.Executable, err = os.Executable(); err == nil {
+		opts.Config, err = filepath.Abs(configPath)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if dirs, err := state.Resolve(&types.StateConfig{System: !*user}); err == nil {
+		opts.LogDir = dirs.Log
+	}
+
+	switch fs.Arg(0) {
+	case "install":
+		if _, err := os.Stat(opts.Config); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if err := service.Install(opts); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Installed service %s running %s serve -config %s\n", *name, opts.Executable, opts.Config)
+		return 0
+
+	case "uninstall":
+		if err := service.Uninstall(opts); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Uninstalled service %s\n", *name)
+		return 0
+
+	case "unit":
+		render := service.Unit
+		if runtime.GOOS == "darwin" {
+			render = service.Plist
+		}
+		out, err := render(opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Print(out)
+		return 0
+
+	default:
+		fs.Usage()
+		return 2
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/kpango/BuildBureau/pkg/buildbureau"
+)
+
+// echoProvider is a trivial LLM provider registered through the public SDK.
+type echoProvider struct{}
+
+func (echoProvider) Generate(ctx context.Context, prompt string, opts *buildbureau.GenerateOptions) (string, error) {
+	return fmt.Sprintf("echo: %d characters of prompt received", len(prompt)), nil
+}
+
+func (echoProvider) Name() string {
+	return "echo"
+}
+
+func main() {
+	configPath := os.Getenv("BUILDBUREAU_CONFIG")
+	if configPath == "" {
+		configPath = "config.yaml"
+	}
+
+	cfg, err := buildbureau.LoadConfig(configPath)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// Register a custom provider and make it the default model
+	org, err := buildbureau.New(cfg,
+		buildbureau.WithProvider("echo", echoProvider{}),
+		buildbureau.WithDefaultModel("echo"),
+	)
+	if err != nil {
+		log.Fatalf("Failed to create organization: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := org.Start(ctx); err != nil {
+		log.Fatalf("Failed to start organization: %v", err)
+	}
+	defer org.Stop(ctx)
+
+	unsubscribe := org.Subscribe(func(e buildbureau.Event) {
+		fmt.Printf("[event] %s %s\n", e.Type, e.TaskID)
+	})
+	defer unsubscribe()
+
+	resp, err := org.Submit(ctx, "Create a simple REST API for user management")
+	if err != nil {
+		log.Fatalf("Failed to process task: %v", err)
+	}
+
+	fmt.Printf("Status: %s\n", resp.Status)
+	fmt.Printf("Result:\n%s\n", resp.Result)
+}
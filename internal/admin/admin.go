@@ -0,0 +1,559 @@
+// Package admin serves an HTTP API for managing a running organization, such
+// as switching the LLM model of a role during a provider outage.
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kpango/BuildBureau/internal/agent"
+	apperrors "github.com/kpango/BuildBureau/internal/errors"
+	"github.com/kpango/BuildBureau/internal/llm"
+	"github.com/kpango/BuildBureau/internal/logging"
+	"github.com/kpango/BuildBureau/internal/slo"
+	"github.com/kpango/BuildBureau/internal/version"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+const (
+	// DefaultAddress is the address the admin API listens on when none is configured.
+	DefaultAddress = "127.0.0.1:8090"
+
+	// PathAgents lists agents, and PathAgents/{id} describes one for routing;
+	// PathAgentModel switches their model.
+	PathAgents     = "/v1/agents"
+	PathAgentModel = "/v1/agents/model"
+	// PathQuestions lists questions waiting for a human answer.
+	PathQuestions = "/v1/questions"
+	// PathAdmission reports projects running and queued under admission control.
+	PathAdmission = "/v1/admission"
+	// PathProjects reports how running and recent projects were scheduled;
+	// the user query parameter limits it to the projects of one submitter.
+	// POST submits a project and responds with its deliverable once done.
+	PathProjects = "/v1/projects"
+	// PathPerformance reports each agent's success rate and latency on past assignments.
+	PathPerformance = "/v1/performance"
+	// PathMemoryEffectiveness reports how often each agent's memory lookups
+	// find related memories, what they cost, and the judge scores of projects
+	// with and without them.
+	PathMemoryEffectiveness = "/v1/memory/effectiveness"
+	// PathSLOs reports each service level objective and whether it is breached.
+	PathSLOs = "/v1/slos"
+	// PathScores lists the judges' quality scores of deliverables, newest
+	// first; the run query parameter limits it to one project.
+	PathScores = "/v1/scores"
+	// PathGenerations lists LLM generations in progress; deleting one aborts it.
+	PathGenerations = "/v1/generations"
+	// PathCalls summarizes recent LLM calls by provider and model and lists
+	// the slowest, optionally of one run.
+	PathCalls = "/v1/calls"
+	// PathOrganizations lists the organizations a process hosts. The API of
+	// each is served under PathOrganizations/{org}, such as
+	// /v1/orgs/{org}/agents for PathAgents.
+	PathOrganizations = "/v1/orgs"
+	// PathHealth reports that the process is up and which build it runs. It
+	// needs no token, so probes and load balancers can call it.
+	PathHealth = "/healthz"
+	// PathReady reports whether the organization can take on projects,
+	// responding 503 while it cannot. Like PathHealth it needs no token.
+	PathReady = "/readyz"
+
+	// defaultLogLines is how many log entries are returned when the request does not say.
+	defaultLogLines = 50
+
+	// defaultSlowestCalls is how many of the slowest calls are returned when the request does not say.
+	defaultSlowestCalls = 10
+)
+
+// Organization is the part of an organization the admin API manages.
+type Organization interface {
+	Agents() []agent.AgentInfo
+	DescribeAgent(agentID string) (types.AgentDescription, error)
+	SetModel(target, model string) ([]string, error)
+	GetLogs(agentID string, n int) ([]logging.Entry, error)
+	Questions() []agent.Question
+	AnswerQuestion(id, answer string) error
+	Admission() agent.AdmissionStats
+	Projects() []agent.ProjectStats
+	Performance(ctx context.Context) ([]types.AgentPerformance, error)
+	MemoryEffectiveness(ctx context.Context) ([]types.MemoryEffectiveness, error)
+	SLOs() []slo.Status
+	JudgeScores(ctx context.Context, runID string) ([]types.JudgeScore, error)
+	Generations() []agent.Generation
+	CancelGeneration(id string) error
+	LLMCalls() *llm.CallLog
+	Readiness() agent.Readiness
+	ProcessClientTask(ctx context.Context, instruction string) (*types.TaskResponse, error)
+}
+
+// SetModelRequest asks for the model of a role or agent to be switched.
+type SetModelRequest struct {
+	Target string `json:"target"` // Role such as "engineer", or an agent ID
+	Model  string `json:"model"`
+}
+
+// SetModelResponse lists the agents whose model was switched.
+type SetModelResponse struct {
+	Model   string   `json:"model"`
+	Updated []string `json:"updated"`
+}
+
+// SubmitProjectRequest submits a project to an organization.
+type SubmitProjectRequest struct {
+	Instruction string `json:"instruction"`
+	User        string `json:"user,omitempty"`   // Submitter the project is attributed to
+	RunID       string `json:"run_id,omitempty"` // Chosen by the caller to correlate events; generated if empty
+}
+
+// AnswerRequest answers a question waiting for a human.
+type AnswerRequest struct {
+	Answer string `json:"answer"`
+}
+
+// CallsResponse summarizes recent LLM calls.
+type CallsResponse struct {
+	SlowThreshold time.Duration   `json:"slow_threshold"`
+	Stats         []llm.CallStats `json:"stats"`
+	Slowest       []llm.Call      `json:"slowest"`
+}
+
+// HealthResponse reports that the process is up.
+type HealthResponse struct {
+	Status  string       `json:"status"`
+	Version version.Info `json:"version"`
+}
+
+// OrganizationInfo describes a hosted organization.
+type OrganizationInfo struct {
+	ID    string `json:"id"`
+	Ready bool   `json:"ready"`
+}
+
+// ErrorResponse is returned with every non-2xx status.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// Server serves the admin API.
+type Server struct {
+	org      Organization
+	orgs     map[string]Organization // Hosted organizations by ID
+	listener net.Listener
+	server   *http.Server
+	address  string
+	token    string
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithAddress sets the listen address.
+func WithAddress(address string) Option {
+	return func(s *Server) {
+		if address != "" {
+			s.address = address
+		}
+	}
+}
+
+// WithToken requires requests to carry the bearer token.
+func WithToken(token string) Option {
+	return func(s *Server) {
+		s.token = token
+	}
+}
+
+// WithOrganization serves the API of another organization hosted by the
+// process under PathOrganizations/{id}.
+func WithOrganization(id string, org Organization) Option {
+	return func(s *Server) {
+		if s.orgs == nil {
+			s.orgs = make(map[string]Organization)
+		}
+		s.orgs[id] = org
+	}
+}
+
+// NewServer creates an admin API server for the organization.
+func NewServer(org Organization, opts ...Option) *Server {
+	s := &Server{
+		org:     org,
+		address: DefaultAddress,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Handler returns the admin API as an http.Handler.
+func (s *Server) Handler() http.Handler {
+	mux := s.routes()
+	mux.HandleFunc("GET "+PathHealth, s.health)
+	mux.HandleFunc("GET "+PathReady, s.ready)
+
+	// Every hosted organization is served by a server of its own
+	if len(s.orgs) > 0 {
+		hosted := make(map[string]http.Handler, len(s.orgs))
+		for id, org := range s.orgs {
+			hosted[id] = (&Server{org: org}).routes()
+		}
+		mux.HandleFunc("GET "+PathOrganizations, s.listOrganizations)
+		mux.HandleFunc(PathOrganizations+"/{org}/{path...}", func(w http.ResponseWriter, r *http.Request) {
+			handler, ok := hosted[r.PathValue("org")]
+			if !ok {
+				writeJSON(w, http.StatusNotFound, ErrorResponse{Error: fmt.Sprintf("organization %s not found", r.PathValue("org"))})
+				return
+			}
+			r = r.Clone(r.Context())
+			r.URL.Path = "/v1/" + r.PathValue("path")
+			r.URL.RawPath = ""
+			handler.ServeHTTP(w, r)
+		})
+	}
+
+	if s.token == "" {
+		return mux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != PathHealth && r.URL.Path != PathReady && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+s.token)) != 1 {
+			writeJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "invalid or missing bearer token"})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// routes serves the API of the server's organization.
+func (s *Server) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+PathAgents, s.listAgents)
+	mux.HandleFunc("PUT "+PathAgentModel, s.setModel)
+	mux.HandleFunc("GET "+PathAgents+"/{id}", s.describeAgent)
+	mux.HandleFunc("GET "+PathAgents+"/{id}/logs", s.getLogs)
+	mux.HandleFunc("GET "+PathQuestions, s.listQuestions)
+	mux.HandleFunc("POST "+PathQuestions+"/{id}/answer", s.answerQuestion)
+	mux.HandleFunc("GET "+PathAdmission, s.getAdmission)
+	mux.HandleFunc("GET "+PathProjects, s.listProjects)
+	mux.HandleFunc("POST "+PathProjects, s.submitProject)
+	mux.HandleFunc("GET "+PathPerformance, s.listPerformance)
+	mux.HandleFunc("GET "+PathMemoryEffectiveness, s.listMemoryEffectiveness)
+	mux.HandleFunc("GET "+PathSLOs, s.listSLOs)
+	mux.HandleFunc("GET "+PathScores, s.listScores)
+	mux.HandleFunc("GET "+PathGenerations, s.listGenerations)
+	mux.HandleFunc("DELETE "+PathGenerations+"/{id}", s.cancelGeneration)
+	mux.HandleFunc("GET "+PathCalls, s.listCalls)
+	return mux
+}
+
+// Start listens on the configured address and serves the API in the background.
+func (s *Server) Start() error {
+	if s.server != nil {
+		return fmt.Errorf("admin server already running")
+	}
+
+	lis, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	s.listener = lis
+	s.server = &http.Server{Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		if err := s.server.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Printf("Admin server error: %v\n", err)
+		}
+	}()
+
+	return nil
+}
+
+// Stop shuts the server down, waiting for requests in progress to finish.
+func (s *Server) Stop(ctx context.Context) error {
+	if s.server == nil {
+		return fmt.Errorf("admin server not running")
+	}
+
+	err := s.server.Shutdown(ctx)
+	s.server = nil
+	return err
+}
+
+// Addr returns the address the server is listening on, or nil if it is not running.
+func (s *Server) Addr() net.Addr {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+func (s *Server) listAgents(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.org.Agents())
+}
+
+func (s *Server) setModel(w http.ResponseWriter, r *http.Request) {
+	var req SetModelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("invalid request: %v", err)})
+		return
+	}
+	if req.Target == "" || req.Model == "" {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "target and model are required"})
+		return
+	}
+
+	// Blocks until the matched agents' in-flight generations have drained
+	updated, err := s.org.SetModel(req.Target, req.Model)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, SetModelResponse{Model: req.Model, Updated: updated})
+}
+
+func (s *Server) describeAgent(w http.ResponseWriter, r *http.Request) {
+	description, err := s.org.DescribeAgent(r.PathValue("id"))
+	if err != nil {
+		status := http.StatusBadRequest
+		if apperrors.CodeOf(err) == apperrors.CodeNotFound {
+			status = http.StatusNotFound
+		}
+		writeJSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, description)
+}
+
+func (s *Server) getLogs(w http.ResponseWriter, r *http.Request) {
+	lines := defaultLogLines
+	if v := r.URL.Query().Get("lines"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "lines must be a positive integer"})
+			return
+		}
+		lines = n
+	}
+
+	entries, err := s.org.GetLogs(r.PathValue("id"), lines)
+	if err != nil {
+		status := http.StatusBadRequest
+		if apperrors.CodeOf(err) == apperrors.CodeNotFound {
+			status = http.StatusNotFound
+		}
+		writeJSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, entries)
+}
+
+func (s *Server) listQuestions(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.org.Questions())
+}
+
+func (s *Server) answerQuestion(w http.ResponseWriter, r *http.Request) {
+	var req AnswerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("invalid request: %v", err)})
+		return
+	}
+
+	if err := s.org.AnswerQuestion(r.PathValue("id"), req.Answer); err != nil {
+		status := http.StatusBadRequest
+		if apperrors.CodeOf(err) == apperrors.CodeNotFound {
+			status = http.StatusNotFound
+		}
+		writeJSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, struct{}{})
+}
+
+func (s *Server) getAdmission(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.org.Admission())
+}
+
+func (s *Server) submitProject(w http.ResponseWriter, r *http.Request) {
+	var req SubmitProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("invalid request: %v", err)})
+		return
+	}
+	if strings.TrimSpace(req.Instruction) == "" {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "instruction is required"})
+		return
+	}
+
+	// The user is the caller's to state; the client is the address it called from
+	ctx := types.WithClient(r.Context(), clientHost(r.RemoteAddr))
+	if req.User != "" {
+		ctx = types.WithUser(ctx, req.User)
+	}
+	if req.RunID != "" {
+		ctx = types.WithRunID(ctx, req.RunID)
+	}
+	resp, err := s.org.ProcessClientTask(ctx, req.Instruction)
+	if err != nil {
+		writeJSON(w, apperrors.HTTPStatus(apperrors.CodeOf(err)), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// clientHost returns the host of a remote address, so that the connections of
+// one caller are attributed to the same client.
+func clientHost(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+func (s *Server) listProjects(w http.ResponseWriter, r *http.Request) {
+	projects := s.org.Projects()
+	if user := r.URL.Query().Get("user"); user != "" {
+		projects = slices.DeleteFunc(projects, func(p agent.ProjectStats) bool { return p.User != user })
+	}
+	if label := r.URL.Query().Get("label"); label != "" {
+		key, value, _ := strings.Cut(label, "=")
+		projects = slices.DeleteFunc(projects, func(p agent.ProjectStats) bool {
+			v, ok := p.Labels[key]
+			return !ok || v != value
+		})
+	}
+	writeJSON(w, http.StatusOK, projects)
+}
+
+func (s *Server) listPerformance(w http.ResponseWriter, r *http.Request) {
+	stats, err := s.org.Performance(r.Context())
+	if err != nil {
+		status := http.StatusInternalServerError
+		if apperrors.CodeOf(err) == apperrors.CodeMemoryUnavailable {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, stats)
+}
+
+func (s *Server) listMemoryEffectiveness(w http.ResponseWriter, r *http.Request) {
+	stats, err := s.org.MemoryEffectiveness(r.Context())
+	if err != nil {
+		status := http.StatusInternalServerError
+		if apperrors.CodeOf(err) == apperrors.CodeMemoryUnavailable {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, stats)
+}
+
+func (s *Server) listSLOs(w http.ResponseWriter, r *http.Request) {
+	statuses := s.org.SLOs()
+	if statuses == nil {
+		statuses = []slo.Status{}
+	}
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+func (s *Server) listScores(w http.ResponseWriter, r *http.Request) {
+	scores, err := s.org.JudgeScores(r.Context(), r.URL.Query().Get("run"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if apperrors.CodeOf(err) == apperrors.CodeMemoryUnavailable {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, scores)
+}
+
+func (s *Server) listGenerations(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.org.Generations())
+}
+
+func (s *Server) cancelGeneration(w http.ResponseWriter, r *http.Request) {
+	if err := s.org.CancelGeneration(r.PathValue("id")); err != nil {
+		status := http.StatusBadRequest
+		if apperrors.CodeOf(err) == apperrors.CodeNotFound {
+			status = http.StatusNotFound
+		}
+		writeJSON(w, status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, struct{}{})
+}
+
+func (s *Server) listCalls(w http.ResponseWriter, r *http.Request) {
+	limit := defaultSlowestCalls
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "limit must be a positive integer"})
+			return
+		}
+		limit = n
+	}
+
+	calls := s.org.LLMCalls()
+	resp := CallsResponse{
+		SlowThreshold: calls.SlowThreshold(),
+		Stats:         calls.Stats(),
+		Slowest:       calls.Slowest(r.URL.Query().Get("run"), limit),
+	}
+	if resp.Slowest == nil {
+		resp.Slowest = []llm.Call{}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) listOrganizations(w http.ResponseWriter, r *http.Request) {
+	orgs := make([]OrganizationInfo, 0, len(s.orgs))
+	for _, id := range slices.Sorted(maps.Keys(s.orgs)) {
+		orgs = append(orgs, OrganizationInfo{ID: id, Ready: s.orgs[id].Readiness().Ready})
+	}
+	writeJSON(w, http.StatusOK, orgs)
+}
+
+func (s *Server) health(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, HealthResponse{Status: "ok", Version: version.Get()})
+}
+
+func (s *Server) ready(w http.ResponseWriter, r *http.Request) {
+	readiness := s.org.Readiness()
+	status := http.StatusOK
+	if !readiness.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, readiness)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
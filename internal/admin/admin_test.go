@@ -0,0 +1,448 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kpango/BuildBureau/internal/agent"
+	apperrors "github.com/kpango/BuildBureau/internal/errors"
+	"github.com/kpango/BuildBureau/internal/llm"
+	"github.com/kpango/BuildBureau/internal/logging"
+	"github.com/kpango/BuildBureau/internal/slo"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// fakeOrganization records model switches.
+type fakeOrganization struct {
+	answers   map[string]string
+	canceled  []string
+	agents    []agent.AgentInfo
+	questions []agent.Question
+	submitted []string
+	draining  bool
+}
+
+func (o *fakeOrganization) Agents() []agent.AgentInfo {
+	return o.agents
+}
+
+func (o *fakeOrganization) DescribeAgent(agentID string) (types.AgentDescription, error) {
+	for _, a := range o.agents {
+		if a.ID == agentID {
+			return types.AgentDescription{ID: a.ID, Role: a.Role, Model: a.Model, Status: "running", Capabilities: []string{"go"}}, nil
+		}
+	}
+	return types.AgentDescription{}, apperrors.Newf(apperrors.CodeNotFound, "agent %s not found", agentID)
+}
+
+func (o *fakeOrganization) SetModel(target, model string) ([]string, error) {
+	var ids []string
+	for i, a := range o.agents {
+		if a.ID == target || strings.EqualFold(string(a.Role), target) {
+			o.agents[i].Model = model
+			ids = append(ids, a.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no agent or role named %s", target)
+	}
+	return ids, nil
+}
+
+func (o *fakeOrganization) GetLogs(agentID string, n int) ([]logging.Entry, error) {
+	if agentID != "engineer-1" {
+		return nil, apperrors.Newf(apperrors.CodeNotFound, "agent %s not found", agentID)
+	}
+	entries := []logging.Entry{
+		{Agent: agentID, Kind: logging.KindPrompt, Content: "prompt"},
+		{Agent: agentID, Kind: logging.KindResponse, Content: "response"},
+	}
+	return entries[len(entries)-min(n, len(entries)):], nil
+}
+
+func (o *fakeOrganization) Questions() []agent.Question {
+	return o.questions
+}
+
+func (o *fakeOrganization) AnswerQuestion(id, answer string) error {
+	for _, q := range o.questions {
+		if q.ID == id {
+			o.answers[id] = answer
+			return nil
+		}
+	}
+	return apperrors.Newf(apperrors.CodeNotFound, "no question %s is waiting for an answer", id)
+}
+
+func (o *fakeOrganization) Admission() agent.AdmissionStats {
+	return agent.AdmissionStats{Running: 2, Queued: 1, MaxConcurrent: 2, QueueDepth: 4}
+}
+
+func (o *fakeOrganization) Projects() []agent.ProjectStats {
+	return []agent.ProjectStats{{RunID: "run-1", User: "alice", Weight: 2, Tasks: 5}, {RunID: "run-2", User: "bob", Weight: 1}}
+}
+
+func (o *fakeOrganization) Performance(ctx context.Context) ([]types.AgentPerformance, error) {
+	return []types.AgentPerformance{{AgentID: "engineer-1", Assignments: 4, Succeeded: 3, SuccessRate: 0.75}}, nil
+}
+
+func (o *fakeOrganization) MemoryEffectiveness(ctx context.Context) ([]types.MemoryEffectiveness, error) {
+	return []types.MemoryEffectiveness{{AgentID: "engineer-1", Retrievals: 4, Hits: 3, HitRate: 0.75, ScoreWithHits: 0.8, ScoreWithoutHits: 0.6, Lift: 0.2}}, nil
+}
+
+func (o *fakeOrganization) SLOs() []slo.Status {
+	return []slo.Status{{Name: "engineer p95 latency", Metric: slo.MetricLatency, Value: 420, Target: 300, Samples: 12, Breached: true}}
+}
+
+func (o *fakeOrganization) JudgeScores(ctx context.Context, runID string) ([]types.JudgeScore, error) {
+	scores := []types.JudgeScore{{RunID: "run-1", Judge: "correctness", Rating: 4, Score: 0.75}, {RunID: "run-2", Judge: "security", Rating: 2, Score: 0.25}}
+	return slices.DeleteFunc(scores, func(s types.JudgeScore) bool { return runID != "" && s.RunID != runID }), nil
+}
+
+func (o *fakeOrganization) Generations() []agent.Generation {
+	return []agent.Generation{{ID: "gen-1", AgentID: "engineer-1", Model: "gemini"}}
+}
+
+func (o *fakeOrganization) CancelGeneration(id string) error {
+	if id != "gen-1" {
+		return apperrors.Newf(apperrors.CodeNotFound, "no generation %s is in progress", id)
+	}
+	o.canceled = append(o.canceled, id)
+	return nil
+}
+
+func (o *fakeOrganization) LLMCalls() *llm.CallLog {
+	return llm.NewCallLog(types.CallLogConfig{SlowThreshold: 5 * time.Second})
+}
+
+func (o *fakeOrganization) Readiness() agent.Readiness {
+	check := agent.ReadinessCheck{Name: "organization", Ready: !o.draining}
+	return agent.Readiness{Checks: []agent.ReadinessCheck{check}, Ready: check.Ready}
+}
+
+func (o *fakeOrganization) ProcessClientTask(ctx context.Context, instruction string) (*types.TaskResponse, error) {
+	if o.draining {
+		return nil, apperrors.New(apperrors.CodeOverloaded, "draining")
+	}
+	o.submitted = append(o.submitted, types.UserFromContext(ctx)+"@"+types.ClientFromContext(ctx)+": "+instruction)
+	return &types.TaskResponse{TaskID: types.RunIDFromContext(ctx), Status: types.StatusCompleted, Result: "done"}, nil
+}
+
+func TestAdminAPI(t *testing.T) {
+	org := &fakeOrganization{agents: []agent.AgentInfo{
+		{ID: "manager-1", Role: types.RoleManager, Model: "gemini"},
+		{ID: "engineer-1", Role: types.RoleEngineer, Model: "gemini"},
+		{ID: "engineer-2", Role: types.RoleEngineer, Model: "gemini"},
+	}}
+	org.questions = []agent.Question{{ID: "q1", From: "engineer-1", Text: "Which database?"}}
+	org.answers = make(map[string]string)
+	server := httptest.NewServer(NewServer(org, WithToken("secret")).Handler())
+	defer server.Close()
+
+	ctx := context.Background()
+	client := NewClient(server.URL, "secret")
+
+	t.Run("SetModel", func(t *testing.T) {
+		updated, err := client.SetModel(ctx, "engineer", "claude-3-5-sonnet")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(updated) != 2 {
+			t.Errorf("Expected 2 agents switched, got %v", updated)
+		}
+
+		agents, err := client.Agents(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if agents[0].Model != "gemini" || agents[1].Model != "claude-3-5-sonnet" {
+			t.Errorf("Unexpected models after switch: %+v", agents)
+		}
+	})
+
+	t.Run("ReportsErrors", func(t *testing.T) {
+		_, err := client.SetModel(ctx, "designer", "claude")
+		if err == nil || !strings.Contains(err.Error(), "no agent or role named designer") {
+			t.Errorf("Expected the server's error message, got %v", err)
+		}
+	})
+
+	t.Run("DescribeAgent", func(t *testing.T) {
+		d, err := client.DescribeAgent(ctx, "engineer-2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.ID != "engineer-2" || d.Role != types.RoleEngineer || len(d.Capabilities) != 1 {
+			t.Errorf("Unexpected description: %+v", d)
+		}
+		if _, err := client.DescribeAgent(ctx, "engineer-9"); err == nil || !strings.Contains(err.Error(), "404") {
+			t.Errorf("Expected 404 for an unknown agent, got %v", err)
+		}
+	})
+
+	t.Run("Logs", func(t *testing.T) {
+		entries, err := client.Logs(ctx, "engineer-1", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 || entries[0].Kind != logging.KindResponse {
+			t.Errorf("Expected the last entry only, got %+v", entries)
+		}
+
+		if _, err := client.Logs(ctx, "engineer-9", 10); err == nil || !strings.Contains(err.Error(), "404") {
+			t.Errorf("Expected not found error, got %v", err)
+		}
+	})
+
+	t.Run("Questions", func(t *testing.T) {
+		questions, err := client.Questions(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(questions) != 1 || questions[0].Text != "Which database?" {
+			t.Errorf("Unexpected questions: %+v", questions)
+		}
+
+		if err := client.AnswerQuestion(ctx, "q1", "PostgreSQL"); err != nil {
+			t.Fatal(err)
+		}
+		if org.answers["q1"] != "PostgreSQL" {
+			t.Errorf("Expected the answer to reach the organization, got %v", org.answers)
+		}
+
+		if err := client.AnswerQuestion(ctx, "q9", "SQLite"); err == nil || !strings.Contains(err.Error(), "404") {
+			t.Errorf("Expected not found error, got %v", err)
+		}
+	})
+
+	t.Run("Admission", func(t *testing.T) {
+		stats, err := client.Admission(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Running != 2 || stats.Queued != 1 || stats.QueueDepth != 4 {
+			t.Errorf("Unexpected admission stats: %+v", stats)
+		}
+	})
+
+	t.Run("Projects", func(t *testing.T) {
+		projects, err := client.Projects(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(projects) != 2 || projects[0].RunID != "run-1" || projects[0].Tasks != 5 {
+			t.Errorf("Unexpected projects: %+v", projects)
+		}
+
+		mine, err := client.ProjectsOf(ctx, "bob")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(mine) != 1 || mine[0].RunID != "run-2" {
+			t.Errorf("Expected only bob's projects, got %+v", mine)
+		}
+	})
+
+	t.Run("Performance", func(t *testing.T) {
+		stats, err := client.Performance(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(stats) != 1 || stats[0].AgentID != "engineer-1" || stats[0].SuccessRate != 0.75 {
+			t.Errorf("Unexpected performance: %+v", stats)
+		}
+	})
+
+	t.Run("MemoryEffectiveness", func(t *testing.T) {
+		stats, err := client.MemoryEffectiveness(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(stats) != 1 || stats[0].AgentID != "engineer-1" || stats[0].HitRate != 0.75 || stats[0].Lift != 0.2 {
+			t.Errorf("Unexpected memory effectiveness: %+v", stats)
+		}
+	})
+
+	t.Run("SLOs", func(t *testing.T) {
+		statuses, err := client.SLOs(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(statuses) != 1 || !statuses[0].Breached || statuses[0].Value != 420 {
+			t.Errorf("Unexpected SLO status: %+v", statuses)
+		}
+	})
+
+	t.Run("JudgeScores", func(t *testing.T) {
+		scores, err := client.JudgeScores(ctx, "run-2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(scores) != 1 || scores[0].Judge != "security" || scores[0].Rating != 2 {
+			t.Errorf("Expected only run-2's scores, got %+v", scores)
+		}
+	})
+
+	t.Run("Generations", func(t *testing.T) {
+		generations, err := client.Generations(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(generations) != 1 || generations[0].ID != "gen-1" || generations[0].AgentID != "engineer-1" {
+			t.Errorf("Unexpected generations: %+v", generations)
+		}
+
+		if err := client.CancelGeneration(ctx, "gen-1"); err != nil {
+			t.Fatal(err)
+		}
+		if len(org.canceled) != 1 || org.canceled[0] != "gen-1" {
+			t.Errorf("Expected gen-1 to be canceled, got %v", org.canceled)
+		}
+		if err := client.CancelGeneration(ctx, "gen-9"); err == nil || !strings.Contains(err.Error(), "404") {
+			t.Errorf("Expected 404 for an unknown generation, got %v", err)
+		}
+	})
+
+	t.Run("Calls", func(t *testing.T) {
+		calls, err := client.Calls(ctx, "run-1", 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if calls.SlowThreshold != 5*time.Second || calls.Slowest == nil {
+			t.Errorf("Unexpected calls: %+v", calls)
+		}
+
+		req, _ := http.NewRequest(http.MethodGet, server.URL+PathCalls+"?limit=0", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected a bad limit to be rejected, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("Health", func(t *testing.T) {
+		health, err := NewClient(server.URL, "").Health(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if health.Status != "ok" || health.Version.Version == "" || health.Version.GoVersion == "" {
+			t.Errorf("Unexpected health response: %+v", health)
+		}
+	})
+
+	t.Run("Ready", func(t *testing.T) {
+		for _, tc := range []struct {
+			draining bool
+			status   int
+		}{
+			{false, http.StatusOK},
+			{true, http.StatusServiceUnavailable},
+		} {
+			org.draining = tc.draining
+			resp, err := http.Get(server.URL + PathReady)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var readiness agent.Readiness
+			err = json.NewDecoder(resp.Body).Decode(&readiness)
+			resp.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tc.status || readiness.Ready == tc.draining {
+				t.Errorf("Draining %v: expected status %d, got %d with %+v", tc.draining, tc.status, resp.StatusCode, readiness)
+			}
+		}
+		org.draining = false
+	})
+
+	t.Run("RequiresToken", func(t *testing.T) {
+		if _, err := NewClient(server.URL, "wrong").Agents(ctx); err == nil || !strings.Contains(err.Error(), "401") {
+			t.Errorf("Expected unauthorized error, got %v", err)
+		}
+	})
+}
+
+func TestOrganizations(t *testing.T) {
+	own := &fakeOrganization{agents: []agent.AgentInfo{{ID: "engineer-1", Role: types.RoleEngineer, Model: "gemini"}}}
+	acme := &fakeOrganization{agents: []agent.AgentInfo{{ID: "engineer-1", Role: types.RoleEngineer, Model: "claude"}}}
+	server := httptest.NewServer(NewServer(own,
+		WithToken("secret"),
+		WithOrganization("default", own),
+		WithOrganization("acme", acme),
+	).Handler())
+	defer server.Close()
+
+	ctx := context.Background()
+	client := NewClient(server.URL, "secret")
+
+	t.Run("List", func(t *testing.T) {
+		orgs, err := client.Organizations(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(orgs) != 2 || orgs[0].ID != "acme" || orgs[1].ID != "default" {
+			t.Errorf("Expected both organizations sorted by ID, got %+v", orgs)
+		}
+	})
+
+	t.Run("AddressedByID", func(t *testing.T) {
+		if _, err := client.Organization("acme").SetModel(ctx, "engineer", "openai"); err != nil {
+			t.Fatal(err)
+		}
+		if acme.agents[0].Model != "openai" || own.agents[0].Model != "gemini" {
+			t.Errorf("Expected only the addressed organization to change, got acme=%s default=%s", acme.agents[0].Model, own.agents[0].Model)
+		}
+
+		// The server's own organization is still served at the unprefixed paths
+		agents, err := client.Agents(ctx)
+		if err != nil || agents[0].Model != "gemini" {
+			t.Errorf("Expected the default organization's agents, got %+v, %v", agents, err)
+		}
+	})
+
+	t.Run("SubmitProject", func(t *testing.T) {
+		resp, err := client.Organization("acme").SubmitProject(ctx, SubmitProjectRequest{Instruction: "Build a todo app", User: "alice", RunID: "run-1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Result != "done" || resp.TaskID != "run-1" {
+			t.Errorf("Expected the project's response, got %+v", resp)
+		}
+		if len(acme.submitted) != 1 || acme.submitted[0] != "alice@127.0.0.1: Build a todo app" || len(own.submitted) != 0 {
+			t.Errorf("Expected only the addressed organization to receive the project, got acme=%v default=%v", acme.submitted, own.submitted)
+		}
+
+		if _, err := client.Organization("acme").SubmitProject(ctx, SubmitProjectRequest{}); err == nil || !strings.Contains(err.Error(), "400") {
+			t.Errorf("Expected 400 without an instruction, got %v", err)
+		}
+		acme.draining = true
+		defer func() { acme.draining = false }()
+		if _, err := client.Organization("acme").SubmitProject(ctx, SubmitProjectRequest{Instruction: "Build a todo app"}); err == nil || !strings.Contains(err.Error(), "429") {
+			t.Errorf("Expected 429 while overloaded, got %v", err)
+		}
+	})
+
+	t.Run("UnknownOrganization", func(t *testing.T) {
+		if _, err := client.Organization("globex").Agents(ctx); err == nil || !strings.Contains(err.Error(), "404") {
+			t.Errorf("Expected 404 for an unknown organization, got %v", err)
+		}
+	})
+
+	t.Run("RequiresToken", func(t *testing.T) {
+		if _, err := NewClient(server.URL, "").Organization("acme").Agents(ctx); err == nil || !strings.Contains(err.Error(), "401") {
+			t.Errorf("Expected 401 without a token, got %v", err)
+		}
+	})
+}
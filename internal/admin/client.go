@@ -0,0 +1,282 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/kpango/BuildBureau/internal/agent"
+	"github.com/kpango/BuildBureau/internal/logging"
+	"github.com/kpango/BuildBureau/internal/slo"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// Client calls the admin API of a running organization.
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+	token      string
+	org        string // Hosted organization the requests address, if set
+}
+
+// NewClient creates a client for the admin API at address, which may be a
+// host:port or a full URL.
+func NewClient(address, token string) *Client {
+	if !strings.Contains(address, "://") {
+		address = "http://" + address
+	}
+	return &Client{
+		httpClient: http.DefaultClient,
+		baseURL:    strings.TrimRight(address, "/"),
+		token:      token,
+	}
+}
+
+// Organization returns a client for the API of an organization hosted by
+// the process, or c itself if id is empty.
+func (c *Client) Organization(id string) *Client {
+	if id == "" {
+		return c
+	}
+	scoped := *c
+	scoped.org = id
+	return &scoped
+}
+
+// Organizations lists the organizations the process hosts.
+func (c *Client) Organizations(ctx context.Context) ([]OrganizationInfo, error) {
+	var orgs []OrganizationInfo
+	if err := c.do(ctx, http.MethodGet, PathOrganizations, nil, &orgs); err != nil {
+		return nil, err
+	}
+	return orgs, nil
+}
+
+// Agents lists the organization's agents and their models.
+func (c *Client) Agents(ctx context.Context) ([]agent.AgentInfo, error) {
+	var agents []agent.AgentInfo
+	if err := c.do(ctx, http.MethodGet, PathAgents, nil, &agents); err != nil {
+		return nil, err
+	}
+	return agents, nil
+}
+
+// SetModel switches the model of a role or agent, returning the IDs of the
+// agents that were switched once their in-flight generations have drained.
+func (c *Client) SetModel(ctx context.Context, target, model string) ([]string, error) {
+	var resp SetModelResponse
+	if err := c.do(ctx, http.MethodPut, PathAgentModel, SetModelRequest{Target: target, Model: model}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Updated, nil
+}
+
+// DescribeAgent describes an agent's role, model, capabilities, load and version.
+func (c *Client) DescribeAgent(ctx context.Context, agentID string) (types.AgentDescription, error) {
+	var description types.AgentDescription
+	err := c.do(ctx, http.MethodGet, PathAgents+"/"+url.PathEscape(agentID), nil, &description)
+	return description, err
+}
+
+// Logs returns up to the last n entries of an agent's activity log, oldest first.
+func (c *Client) Logs(ctx context.Context, agentID string, n int) ([]logging.Entry, error) {
+	var entries []logging.Entry
+	path := fmt.Sprintf("%s/%s/logs?lines=%d", PathAgents, url.PathEscape(agentID), n)
+	if err := c.do(ctx, http.MethodGet, path, nil, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Questions lists the questions waiting for a human answer.
+func (c *Client) Questions(ctx context.Context) ([]agent.Question, error) {
+	var questions []agent.Question
+	if err := c.do(ctx, http.MethodGet, PathQuestions, nil, &questions); err != nil {
+		return nil, err
+	}
+	return questions, nil
+}
+
+// AnswerQuestion answers a question, resuming the task that asked it.
+func (c *Client) AnswerQuestion(ctx context.Context, id, answer string) error {
+	var resp struct{}
+	path := fmt.Sprintf("%s/%s/answer", PathQuestions, url.PathEscape(id))
+	return c.do(ctx, http.MethodPost, path, AnswerRequest{Answer: answer}, &resp)
+}
+
+// SubmitProject submits a project and waits for its deliverable.
+func (c *Client) SubmitProject(ctx context.Context, req SubmitProjectRequest) (*types.TaskResponse, error) {
+	var resp types.TaskResponse
+	if err := c.do(ctx, http.MethodPost, PathProjects, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Admission reports the projects running and queued under admission control.
+func (c *Client) Admission(ctx context.Context) (agent.AdmissionStats, error) {
+	var stats agent.AdmissionStats
+	err := c.do(ctx, http.MethodGet, PathAdmission, nil, &stats)
+	return stats, err
+}
+
+// Health reports whether the server is up and which build it runs.
+func (c *Client) Health(ctx context.Context) (HealthResponse, error) {
+	var resp HealthResponse
+	err := c.do(ctx, http.MethodGet, PathHealth, nil, &resp)
+	return resp, err
+}
+
+// Projects reports the scheduling statistics of running and recent projects.
+func (c *Client) Projects(ctx context.Context) ([]agent.ProjectStats, error) {
+	var projects []agent.ProjectStats
+	if err := c.do(ctx, http.MethodGet, PathProjects, nil, &projects); err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+// ProjectsOf reports the scheduling statistics of the running and recent
+// projects submitted by a user.
+func (c *Client) ProjectsOf(ctx context.Context, user string) ([]agent.ProjectStats, error) {
+	var projects []agent.ProjectStats
+	if err := c.do(ctx, http.MethodGet, PathProjects+"?user="+url.QueryEscape(user), nil, &projects); err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+// ProjectsLabeled reports the scheduling statistics of the running and recent
+// projects carrying the label key=value.
+func (c *Client) ProjectsLabeled(ctx context.Context, key, value string) ([]agent.ProjectStats, error) {
+	var projects []agent.ProjectStats
+	if err := c.do(ctx, http.MethodGet, PathProjects+"?label="+url.QueryEscape(key+"="+value), nil, &projects); err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+// Performance reports each agent's success rate and latency on past assignments.
+func (c *Client) Performance(ctx context.Context) ([]types.AgentPerformance, error) {
+	var stats []types.AgentPerformance
+	if err := c.do(ctx, http.MethodGet, PathPerformance, nil, &stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// MemoryEffectiveness reports how often each agent's memory lookups find
+// related memories, what they cost, and the judge scores of projects with
+// and without them.
+func (c *Client) MemoryEffectiveness(ctx context.Context) ([]types.MemoryEffectiveness, error) {
+	var stats []types.MemoryEffectiveness
+	if err := c.do(ctx, http.MethodGet, PathMemoryEffectiveness, nil, &stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// SLOs reports each service level objective and whether it is breached.
+func (c *Client) SLOs(ctx context.Context) ([]slo.Status, error) {
+	var statuses []slo.Status
+	if err := c.do(ctx, http.MethodGet, PathSLOs, nil, &statuses); err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+// JudgeScores lists the judges' quality scores of a project's deliverables,
+// or of every project if runID is empty, newest first.
+func (c *Client) JudgeScores(ctx context.Context, runID string) ([]types.JudgeScore, error) {
+	path := PathScores
+	if runID != "" {
+		path += "?run=" + url.QueryEscape(runID)
+	}
+	var scores []types.JudgeScore
+	if err := c.do(ctx, http.MethodGet, path, nil, &scores); err != nil {
+		return nil, err
+	}
+	return scores, nil
+}
+
+// Generations lists the LLM generations in progress.
+func (c *Client) Generations(ctx context.Context) ([]agent.Generation, error) {
+	var generations []agent.Generation
+	if err := c.do(ctx, http.MethodGet, PathGenerations, nil, &generations); err != nil {
+		return nil, err
+	}
+	return generations, nil
+}
+
+// CancelGeneration aborts a generation in progress without stopping its agent.
+func (c *Client) CancelGeneration(ctx context.Context, id string) error {
+	var resp struct{}
+	return c.do(ctx, http.MethodDelete, PathGenerations+"/"+url.PathEscape(id), nil, &resp)
+}
+
+// Calls summarizes recent LLM calls and lists the n slowest of a project, or
+// of every project if runID is empty.
+func (c *Client) Calls(ctx context.Context, runID string, n int) (CallsResponse, error) {
+	query := url.Values{}
+	if runID != "" {
+		query.Set("run", runID)
+	}
+	if n > 0 {
+		query.Set("limit", strconv.Itoa(n))
+	}
+	path := PathCalls
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	var calls CallsResponse
+	err := c.do(ctx, http.MethodGet, path, nil, &calls)
+	return calls, err
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	if c.org != "" && !strings.HasPrefix(path, PathOrganizations) {
+		path = PathOrganizations + "/" + url.PathEscape(c.org) + strings.TrimPrefix(path, "/v1")
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach admin API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errResp); err == nil && errResp.Error != "" {
+			return fmt.Errorf("admin API returned status %d: %s", resp.StatusCode, errResp.Error)
+		}
+		return fmt.Errorf("admin API returned status %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
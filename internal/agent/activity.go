@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kpango/BuildBureau/internal/logging"
+)
+
+// activityFeed fans agent activity out to the per-agent log files, when
+// logging is enabled, and to subscribers such as the TUI.
+type activityFeed struct {
+	files       *logging.Manager
+	subscribers map[int]func(logging.Entry)
+	next        int
+	mu          sync.RWMutex
+}
+
+// Log records an entry for the agent.
+func (f *activityFeed) Log(agentID string, entry logging.Entry) {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	entry.Agent = agentID
+
+	if f.files != nil {
+		f.files.Log(agentID, entry)
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for _, handler := range f.subscribers {
+		handler(entry)
+	}
+}
+
+// subscribe registers a handler and returns a function that removes it.
+func (f *activityFeed) subscribe(handler func(logging.Entry)) func() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.subscribers == nil {
+		f.subscribers = make(map[int]func(logging.Entry))
+	}
+	id := f.next
+	f.next++
+	f.subscribers[id] = handler
+
+	return func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		delete(f.subscribers, id)
+	}
+}
+
+// SubscribeActivity registers a handler that receives every agent's activity:
+// prompts, responses, errors and events such as delegation. Handlers are called
+// synchronously and must not block. The returned function removes the subscription.
+func (o *Organization) SubscribeActivity(handler func(logging.Entry)) func() {
+	if o.activity == nil {
+		return func() {}
+	}
+	return o.activity.subscribe(handler)
+}
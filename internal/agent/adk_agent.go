@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"os"
 
+	adkagent "google.golang.org/adk/agent"
 	"google.golang.org/adk/agent/llmagent"
 	"google.golang.org/adk/model/gemini"
 	"google.golang.org/genai"
 
+	"github.com/kpango/BuildBureau/internal/llm"
 	"github.com/kpango/BuildBureau/pkg/types"
 )
 
@@ -16,14 +18,19 @@ import (
 // while maintaining compatibility with our Agent interface.
 type ADKAgent struct {
 	*BaseAgent
+	adk       adkagent.Agent
 	modelName string
 	llmConfig llmagent.Config
 	apiKey    string
 }
 
+// The ADK agents take their Gemini clients from the pool given with
+// WithClientPool, or from a pool of their own, so clients and connections are
+// reused across tasks.
+
 // NewADKEngineerAgent creates an Engineer agent using Google's ADK framework.
-func NewADKEngineerAgent(id string, config *types.AgentConfig, apiKey string) (*ADKAgent, error) {
-	return newADKAgent(id, config, apiKey, types.RoleEngineer, `You are a skilled software engineer.
+func NewADKEngineerAgent(id string, config *types.AgentConfig, apiKey string, opts ...Option) (*ADKAgent, error) {
+	return newADKAgent(id, config, apiKey, types.RoleEngineer, opts, `You are a skilled software engineer.
 Your responsibilities:
 - Implement code according to specifications
 - Write clean, maintainable code
@@ -33,8 +40,8 @@ Your responsibilities:
 }
 
 // NewADKManagerAgent creates a Manager agent using Google's ADK framework.
-func NewADKManagerAgent(id string, config *types.AgentConfig, apiKey string) (*ADKAgent, error) {
-	return newADKAgent(id, config, apiKey, types.RoleManager, `You are a software development manager.
+func NewADKManagerAgent(id string, config *types.AgentConfig, apiKey string, opts ...Option) (*ADKAgent, error) {
+	return newADKAgent(id, config, apiKey, types.RoleManager, opts, `You are a software development manager.
 Your responsibilities:
 - Create detailed technical specifications
 - Design software architecture
@@ -44,8 +51,8 @@ Your responsibilities:
 }
 
 // NewADKDirectorAgent creates a Director agent using Google's ADK framework.
-func NewADKDirectorAgent(id string, config *types.AgentConfig, apiKey string) (*ADKAgent, error) {
-	return newADKAgent(id, config, apiKey, types.RoleDirector, `You are a technical director.
+func NewADKDirectorAgent(id string, config *types.AgentConfig, apiKey string, opts ...Option) (*ADKAgent, error) {
+	return newADKAgent(id, config, apiKey, types.RoleDirector, opts, `You are a technical director.
 Your responsibilities:
 - Analyze project requirements
 - Perform research on technologies and approaches
@@ -55,8 +62,8 @@ Your responsibilities:
 }
 
 // NewADKPresidentAgent creates a President agent using Google's ADK framework.
-func NewADKPresidentAgent(id string, config *types.AgentConfig, apiKey string) (*ADKAgent, error) {
-	return newADKAgent(id, config, apiKey, types.RolePresident, `You are the president of a software development organization.
+func NewADKPresidentAgent(id string, config *types.AgentConfig, apiKey string, opts ...Option) (*ADKAgent, error) {
+	return newADKAgent(id, config, apiKey, types.RolePresident, opts, `You are the president of a software development organization.
 Your responsibilities:
 - Clarify client requirements
 - Define high-level objectives
@@ -66,7 +73,7 @@ Your responsibilities:
 }
 
 // newADKAgent creates a new ADK-based agent.
-func newADKAgent(id string, config *types.AgentConfig, apiKey string, role types.AgentRole, defaultInstruction string) (*ADKAgent, error) {
+func newADKAgent(id string, config *types.AgentConfig, apiKey string, role types.AgentRole, opts []Option, defaultInstruction string) (*ADKAgent, error) {
 	// Use API key from parameter or environment
 	if apiKey == "" {
 		apiKey = os.Getenv("GEMINI_API_KEY")
@@ -88,18 +95,37 @@ func newADKAgent(id string, config *types.AgentConfig, apiKey string, role types
 		instruction = config.SystemPrompt
 	}
 
-	// Store the llmagent config (we'll create the model and agent on each use for simplicity)
+	// Create base agent
+	baseAgent := NewBaseAgent(id, role, config, opts...)
+	if baseAgent.clients == nil {
+		baseAgent.clients = llm.NewClientPool(types.ClientPoolConfig{})
+	}
+
+	// Create the ADK model and agent once, rather than for every task
+	ctx := context.Background()
+	client, err := baseAgent.clients.Gemini(ctx, modelName, apiKey)
+	if err != nil {
+		return nil, err
+	}
+	clientConfig := client.ClientConfig()
+	adkModel, err := gemini.NewModel(ctx, modelName, &clientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ADK Gemini model: %w", err)
+	}
 	llmConfig := llmagent.Config{
 		Name:        id,
 		Description: config.Description,
 		Instruction: instruction,
+		Model:       adkModel,
+	}
+	adk, err := llmagent.New(llmConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ADK agent: %w", err)
 	}
-
-	// Create base agent
-	baseAgent := NewBaseAgent(id, role, config)
 
 	return &ADKAgent{
 		BaseAgent: baseAgent,
+		adk:       adk,
 		modelName: modelName,
 		llmConfig: llmConfig,
 		apiKey:    apiKey,
@@ -111,31 +137,6 @@ func (a *ADKAgent) ProcessTask(ctx context.Context, task *types.Task) (*types.Ta
 	a.IncrementActiveTasks()
 	defer a.DecrementActiveTasks()
 
-	// Create Gemini model for this request using ADK's gemini package
-	adkModel, err := gemini.NewModel(ctx, a.modelName, &genai.ClientConfig{
-		APIKey: a.apiKey,
-	})
-	if err != nil {
-		return &types.TaskResponse{
-			TaskID: task.ID,
-			Status: types.StatusFailed,
-			Error:  fmt.Sprintf("failed to create ADK Gemini model: %v", err),
-		}, nil
-	}
-
-	// Set model in config
-	a.llmConfig.Model = adkModel
-
-	// Create ADK LLM agent with the configured model
-	adkAgent, err := llmagent.New(a.llmConfig)
-	if err != nil {
-		return &types.TaskResponse{
-			TaskID: task.ID,
-			Status: types.StatusFailed,
-			Error:  fmt.Sprintf("failed to create ADK agent: %v", err),
-		}, nil
-	}
-
 	// Prepare the prompt for the ADK agent
 	prompt := fmt.Sprintf(`Task: %s
 
@@ -151,10 +152,8 @@ Please process this task according to your role and provide a detailed response.
 	// For this implementation, we demonstrate ADK's llmagent configuration
 	// and use the underlying genai client for actual generation.
 
-	// Use genai client directly (ADK's gemini.NewModel returns a model.LLM which wraps genai)
-	genaiClient, err := genai.NewClient(ctx, &genai.ClientConfig{
-		APIKey: a.apiKey,
-	})
+	// Use the pooled genai client directly (ADK's gemini.NewModel returns a model.LLM which wraps genai)
+	genaiClient, err := a.clients.Gemini(ctx, a.modelName, a.apiKey)
 	if err != nil {
 		return &types.TaskResponse{
 			TaskID: task.ID,
@@ -205,7 +204,7 @@ Please process this task according to your role and provide a detailed response.
 	return &types.TaskResponse{
 		TaskID: task.ID,
 		Status: types.StatusCompleted,
-		Result: fmt.Sprintf("ADK Agent (%s - %s) Response:\n\n%s", adkAgent.Name(), a.modelName, responseText),
+		Result: fmt.Sprintf("ADK Agent (%s - %s) Response:\n\n%s", a.adk.Name(), a.modelName, responseText),
 	}, nil
 }
 
@@ -0,0 +1,275 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/kpango/BuildBureau/internal/errors"
+	"github.com/kpango/BuildBureau/internal/logging"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// organizationLog is the activity log of events that concern the
+// organization as a whole, such as queued projects.
+const organizationLog = "organization"
+
+// AdmissionStats describes the projects running and waiting for admission.
+type AdmissionStats struct {
+	AverageWait   time.Duration `json:"average_wait"` // Of projects that had to wait for a slot
+	MaxWait       time.Duration `json:"max_wait"`
+	Running       int           `json:"running"`
+	Queued        int           `json:"queued"`
+	MaxConcurrent int           `json:"max_concurrent"` // 0 means unlimited
+	MaxPerUser    int           `json:"max_per_user"`   // 0 means unlimited
+	QueueDepth    int           `json:"queue_depth"`
+	Admitted      int           `json:"admitted"`
+	Rejected      int           `json:"rejected"`
+	Waited        int           `json:"waited"` // Admitted projects that had to wait for a slot
+}
+
+type queueFeedbackKey struct{}
+
+// WithQueueFeedback returns a context whose project submission reports its
+// position in the admission queue to fn while it waits; 1 is next in line.
+func WithQueueFeedback(ctx context.Context, fn func(position int)) context.Context {
+	return context.WithValue(ctx, queueFeedbackKey{}, fn)
+}
+
+// queueFeedback returns the queue position callback in ctx, if any.
+func queueFeedback(ctx context.Context) func(int) {
+	fn, _ := ctx.Value(queueFeedbackKey{}).(func(int))
+	return fn
+}
+
+// admissionTicket is a project waiting for a slot.
+type admissionTicket struct {
+	ready    chan struct{} // Closed when the ticket is handed a slot
+	feedback func(int)
+}
+
+// notify reports the ticket's queue position.
+func (t *admissionTicket) notify(position int) {
+	if t.feedback != nil {
+		t.feedback(position)
+	}
+}
+
+// admission bounds the projects processed at once, queuing a bounded number
+// of further projects in submission order and rejecting the rest. It can also
+// bound the projects each user has running or queued.
+type admission struct {
+	waiting   []*admissionTicket
+	users     map[string]int // Projects running or queued per user
+	idle      chan struct{}  // Closed once draining and no project is running or queued
+	cfg       types.AdmissionConfig
+	stats     AdmissionStats
+	totalWait time.Duration
+	mu        sync.Mutex
+}
+
+// newAdmission creates admission control with the given limits.
+func newAdmission(cfg types.AdmissionConfig) *admission {
+	return &admission{cfg: cfg, users: make(map[string]int)}
+}
+
+// acquire waits for a slot and returns the function that frees it. It fails
+// at once if every slot is busy and the queue is full, or if the submitting
+// user already has as many projects as they are allowed.
+func (c *admission) acquire(ctx context.Context) (func(), error) {
+	if c == nil {
+		return func() {}, nil
+	}
+	user := types.UserFromContext(ctx)
+	release := sync.OnceFunc(func() {
+		c.release()
+		c.leave(user)
+	})
+
+	c.mu.Lock()
+	if c.idle != nil {
+		c.mu.Unlock()
+		return nil, errors.New(errors.CodeAgentUnavailable, "organization is shutting down and accepts no new projects")
+	}
+	if user != "" && c.cfg.MaxPerUser > 0 && c.users[user] >= c.cfg.MaxPerUser {
+		c.stats.Rejected++
+		c.mu.Unlock()
+		return nil, errors.Newf(errors.CodeOverloaded, "user %s already has %d project(s) running or queued; try again later", user, c.cfg.MaxPerUser)
+	}
+	if c.cfg.MaxConcurrent <= 0 || (c.stats.Running < c.cfg.MaxConcurrent && len(c.waiting) == 0) {
+		c.stats.Running++
+		c.stats.Admitted++
+		c.join(user)
+		c.mu.Unlock()
+		return release, nil
+	}
+	if len(c.waiting) >= c.cfg.QueueDepth {
+		c.stats.Rejected++
+		running, queued := c.stats.Running, len(c.waiting)
+		c.mu.Unlock()
+		return nil, errors.Newf(errors.CodeOverloaded, "organization is at capacity with %d project(s) running and %d queued; try again later", running, queued)
+	}
+
+	t := &admissionTicket{ready: make(chan struct{}), feedback: queueFeedback(ctx)}
+	c.waiting = append(c.waiting, t)
+	c.join(user)
+	position := len(c.waiting)
+	c.mu.Unlock()
+	t.notify(position)
+
+	start := time.Now()
+	select {
+	case <-t.ready:
+		c.recordWait(time.Since(start))
+		return release, nil
+	case <-ctx.Done():
+	}
+
+	c.mu.Lock()
+	i := slices.Index(c.waiting, t)
+	if i < 0 {
+		// The slot was handed over just as the context ended
+		c.mu.Unlock()
+		release()
+		return nil, errors.FromContext(ctx)
+	}
+	c.waiting = slices.Delete(c.waiting, i, i+1)
+	moved := slices.Clone(c.waiting[i:])
+	c.closeIfIdle()
+	c.mu.Unlock()
+	c.leave(user)
+
+	for j, m := range moved {
+		m.notify(i + j + 1)
+	}
+	return nil, errors.FromContext(ctx)
+}
+
+// release frees a slot, handing it to the first waiting project if there is one.
+func (c *admission) release() {
+	c.mu.Lock()
+	if len(c.waiting) == 0 {
+		c.stats.Running--
+		c.closeIfIdle()
+		c.mu.Unlock()
+		return
+	}
+
+	next := c.waiting[0]
+	c.waiting = c.waiting[1:]
+	c.stats.Admitted++
+	moved := slices.Clone(c.waiting)
+	close(next.ready)
+	c.mu.Unlock()
+
+	for i, m := range moved {
+		m.notify(i + 1)
+	}
+}
+
+// join counts a project of the user. The caller must hold c.mu.
+func (c *admission) join(user string) {
+	if user != "" {
+		c.users[user]++
+	}
+}
+
+// leave stops counting a project of the user once it is done or abandoned.
+func (c *admission) leave(user string) {
+	if user == "" {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.users[user]--; c.users[user] <= 0 {
+		delete(c.users, user)
+	}
+}
+
+// drain stops admitting projects. Projects already running or queued carry
+// on; the returned channel is closed once they have all finished.
+func (c *admission) drain() <-chan struct{} {
+	if c == nil {
+		idle := make(chan struct{})
+		close(idle)
+		return idle
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.idle == nil {
+		c.idle = make(chan struct{})
+		c.closeIfIdle()
+	}
+	return c.idle
+}
+
+// closeIfIdle closes the idle channel of a draining admission once no
+// project is running or queued. The caller must hold c.mu.
+func (c *admission) closeIfIdle() {
+	if c.idle == nil || c.stats.Running > 0 || len(c.waiting) > 0 {
+		return
+	}
+	select {
+	case <-c.idle:
+	default:
+		close(c.idle)
+	}
+}
+
+// recordWait records how long an admitted project waited for its slot.
+func (c *admission) recordWait(wait time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stats.Waited++
+	c.totalWait += wait
+	c.stats.MaxWait = max(c.stats.MaxWait, wait)
+}
+
+// snapshot returns the current admission metrics.
+func (c *admission) snapshot() AdmissionStats {
+	if c == nil {
+		return AdmissionStats{}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Queued = len(c.waiting)
+	stats.MaxConcurrent = c.cfg.MaxConcurrent
+	stats.QueueDepth = c.cfg.QueueDepth
+	stats.MaxPerUser = c.cfg.MaxPerUser
+	if stats.Waited > 0 {
+		stats.AverageWait = c.totalWait / time.Duration(stats.Waited)
+	}
+	return stats
+}
+
+// admit waits until the project may start, recording its queue position in
+// the activity log as well as reporting it to the caller's queue feedback.
+func (o *Organization) admit(ctx context.Context, runID string) (func(), error) {
+	report := queueFeedback(ctx)
+	ctx = WithQueueFeedback(ctx, func(position int) {
+		if o.activity != nil {
+			o.activity.Log(organizationLog, logging.Entry{Kind: logging.KindEvent, RunID: runID, Content: fmt.Sprintf("project queued at position %d", position)})
+		}
+		if report != nil {
+			report(position)
+		}
+	})
+
+	return o.admission.acquire(ctx)
+}
+
+// Admission returns the admission control metrics: projects running, queued,
+// admitted and rejected, and how long queued projects waited.
+func (o *Organization) Admission() AdmissionStats {
+	return o.admission.snapshot()
+}
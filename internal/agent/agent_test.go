@@ -2,8 +2,33 @@ package agent
 
 import (
 	"context"
+	"encoding/base64"
+	"fmt"
+	"io/fs"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/kpango/BuildBureau/internal/errors"
+	"github.com/kpango/BuildBureau/internal/imagegen"
+	"github.com/kpango/BuildBureau/internal/ingest"
+	"github.com/kpango/BuildBureau/internal/judge"
+	"github.com/kpango/BuildBureau/internal/llm"
+	"github.com/kpango/BuildBureau/internal/logging"
+	"github.com/kpango/BuildBureau/internal/memory"
+	"github.com/kpango/BuildBureau/internal/profile"
+	"github.com/kpango/BuildBureau/internal/provenance"
+	"github.com/kpango/BuildBureau/internal/scan"
+	"github.com/kpango/BuildBureau/internal/speech"
+	"github.com/kpango/BuildBureau/internal/webhook"
 	"github.com/kpango/BuildBureau/pkg/types"
 )
 
@@ -140,3 +165,3252 @@ func TestPresidentWithSecretary(t *testing.T) {
 		t.Error("Expected non-empty result")
 	}
 }
+
+func TestParseDecisions(t *testing.T) {
+	output := "Here are the decisions:\n```json\n" +
+		`[{"decision": "Use PostgreSQL", "reasoning": "Relational data", "alternatives_rejected": ["MongoDB"]},` +
+		`{"decision": "", "reasoning": "empty"}]` + "\n```"
+
+	decisions, err := parseDecisions(output)
+	if err != nil {
+		t.Fatalf("Failed to parse decisions: %v", err)
+	}
+
+	if len(decisions) != 1 {
+		t.Fatalf("Expected 1 decision, got %d", len(decisions))
+	}
+
+	if decisions[0].What != "Use PostgreSQL" {
+		t.Errorf("Expected decision 'Use PostgreSQL', got '%s'", decisions[0].What)
+	}
+
+	if len(decisions[0].Alternatives) != 1 || decisions[0].Alternatives[0] != "MongoDB" {
+		t.Errorf("Expected alternatives [MongoDB], got %v", decisions[0].Alternatives)
+	}
+
+	if _, err := parseDecisions("no decisions here"); err == nil {
+		t.Error("Expected error for output without a decision list")
+	}
+}
+
+type recordingNotifier struct {
+	messages []string
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, notificationType, message string) error {
+	n.messages = append(n.messages, notificationType+": "+message)
+	return nil
+}
+
+func TestAgentOptions(t *testing.T) {
+	notifier := &recordingNotifier{}
+	president := NewPresidentAgent("president-1", &types.AgentConfig{Name: "TestPresident"},
+		WithNotifier(notifier),
+		WithTimeout(time.Minute),
+	)
+	president.SetSecretary(NewSecretaryAgent("secretary-1", &types.AgentConfig{Name: "TestSecretary"}))
+
+	if president.timeout != time.Minute {
+		t.Errorf("Expected timeout 1m, got %v", president.timeout)
+	}
+
+	task := &types.Task{
+		ID:          "client-task-1",
+		Title:       "Client Request",
+		Description: "Build a web application",
+		FromAgent:   "client",
+		ToAgent:     president.GetID(),
+	}
+
+	if _, err := president.ProcessTask(context.Background(), task); err != nil {
+		t.Fatalf("Failed to process task: %v", err)
+	}
+
+	if len(notifier.messages) != 1 || !strings.HasPrefix(notifier.messages[0], "task_assigned") {
+		t.Errorf("Expected one task_assigned notification, got %v", notifier.messages)
+	}
+}
+
+// countingMemoryManager is a minimal MemoryManager that counts reads.
+type countingMemoryManager struct {
+	types.MemoryManager
+	afterQuery func() // Runs after each query has read its results
+	entries    []*types.MemoryEntry
+	queries    int
+}
+
+func (m *countingMemoryManager) StoreMemory(ctx context.Context, entry *types.MemoryEntry) error {
+	m.entries = append(m.entries, entry)
+	return nil
+}
+
+func (m *countingMemoryManager) QueryMemories(ctx context.Context, query *types.MemoryQuery) ([]*types.MemoryEntry, error) {
+	m.queries++
+	var results []*types.MemoryEntry
+	for _, entry := range m.entries {
+		if entry.Type == query.Type {
+			results = append(results, entry)
+		}
+	}
+	if m.afterQuery != nil {
+		m.afterQuery()
+	}
+	return results, nil
+}
+
+func TestAgentMemoryCache(t *testing.T) {
+	ctx := context.Background()
+	manager := &countingMemoryManager{}
+	mem := NewAgentMemory("manager-1", manager)
+
+	if err := mem.StoreKnowledge(ctx, "Use PostgreSQL", nil); err != nil {
+		t.Fatalf("Failed to store knowledge: %v", err)
+	}
+
+	for range 3 {
+		if _, err := mem.GetKnowledge(ctx, "", 10); err != nil {
+			t.Fatalf("Failed to get knowledge: %v", err)
+		}
+	}
+	if manager.queries != 1 {
+		t.Errorf("Expected 1 backend query for repeated reads, got %d", manager.queries)
+	}
+
+	// Unrelated writes keep the cached result
+	_ = mem.StoreConversation(ctx, "hello", nil)
+	_, _ = mem.GetKnowledge(ctx, "", 10)
+	if manager.queries != 1 {
+		t.Errorf("Expected conversation write not to invalidate knowledge, got %d queries", manager.queries)
+	}
+
+	// Writes of the same type invalidate it
+	_ = mem.StoreKnowledge(ctx, "Use Redis for caching", nil)
+	results, _ := mem.GetKnowledge(ctx, "", 10)
+	if manager.queries != 2 || len(results) != 2 {
+		t.Errorf("Expected fresh query with 2 results, got %d queries and %d results", manager.queries, len(results))
+	}
+
+	// A write landing while a lookup is in flight keeps its results out of the cache
+	_ = mem.StoreKnowledge(ctx, "Use Kafka for events", nil)
+	manager.afterQuery = func() {
+		manager.afterQuery = nil
+		_ = mem.StoreKnowledge(ctx, "Use NATS for commands", nil)
+	}
+	if results, _ := mem.GetKnowledge(ctx, "", 10); len(results) != 3 {
+		t.Errorf("Expected the in-flight lookup to return what it read, got %d results", len(results))
+	}
+	if results, _ := mem.GetKnowledge(ctx, "", 10); manager.queries != 4 || len(results) != 4 {
+		t.Errorf("Expected the racing write to force a fresh query with 4 results, got %d queries and %d results", manager.queries, len(results))
+	}
+
+	// A zero-sized cache always hits the backend
+	uncached := NewAgentMemory("manager-2", manager, WithMemoryCache(0, 0))
+	_, _ = uncached.GetKnowledge(ctx, "", 10)
+	_, _ = uncached.GetKnowledge(ctx, "", 10)
+	if manager.queries != 6 {
+		t.Errorf("Expected uncached reads to query every time, got %d queries", manager.queries)
+	}
+}
+
+// asyncMemoryManager is a countingMemoryManager that queues writes until
+// persist is called, as managers with async writes do.
+type asyncMemoryManager struct {
+	countingMemoryManager
+	pending    []*types.MemoryEntry
+	generation uint64
+}
+
+func (m *asyncMemoryManager) StoreMemory(ctx context.Context, entry *types.MemoryEntry) error {
+	m.pending = append(m.pending, entry)
+	return nil
+}
+
+func (m *asyncMemoryManager) persist() {
+	m.entries = append(m.entries, m.pending...)
+	m.pending = nil
+	m.generation++
+}
+
+func (m *asyncMemoryManager) WriteGeneration() uint64 {
+	return m.generation
+}
+
+func TestAgentMemoryAsyncWrites(t *testing.T) {
+	ctx := context.Background()
+	manager := &asyncMemoryManager{}
+	mem := NewAgentMemory("engineer-1", manager)
+
+	// A lookup between queueing and persisting misses the entry
+	if err := mem.StoreKnowledge(ctx, "Use PostgreSQL", nil); err != nil {
+		t.Fatal(err)
+	}
+	if results, _ := mem.GetKnowledge(ctx, "", 10); len(results) != 0 {
+		t.Fatalf("Expected the queued entry not yet visible, got %d results", len(results))
+	}
+
+	// Once persisted, it is not hidden behind the cached miss
+	manager.persist()
+	if results, _ := mem.GetKnowledge(ctx, "", 10); len(results) != 1 {
+		t.Errorf("Expected the persisted entry after the write generation advanced, got %d results", len(results))
+	}
+	if manager.queries != 2 {
+		t.Errorf("Expected a fresh query after the write, got %d queries", manager.queries)
+	}
+}
+
+func TestAgentMemoryDeletion(t *testing.T) {
+	ctx := context.Background()
+	manager, err := memory.NewManager(&types.MemoryConfig{
+		Enabled: true,
+		SQLite:  types.SQLiteConfig{Enabled: true, InMemory: true},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+
+	mem := NewAgentMemory("engineer-1", manager)
+	if err := mem.StoreKnowledge(ctx, "Client A uses Stripe", []string{"client-a"}); err != nil {
+		t.Fatal(err)
+	}
+	if results, _ := mem.GetKnowledge(ctx, "", 10); len(results) != 1 {
+		t.Fatalf("Expected the stored knowledge, got %d results", len(results))
+	}
+
+	if _, err := manager.DeleteByFilter(ctx, &types.MemoryQuery{Tags: []string{"client-a"}}, memory.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if results, _ := mem.GetKnowledge(ctx, "", 10); len(results) != 0 {
+		t.Errorf("Expected erased memories gone from cached lookups, got %d results", len(results))
+	}
+}
+
+// scriptedProvider is an llm.Provider that returns canned outputs in order and records prompts.
+type scriptedProvider struct {
+	outputs []string
+	prompts []string
+}
+
+func (p *scriptedProvider) Generate(ctx context.Context, prompt string, opts *llm.GenerateOptions) (string, error) {
+	p.prompts = append(p.prompts, prompt)
+	if len(p.outputs) == 0 {
+		return "", fmt.Errorf("no scripted output left")
+	}
+	output := p.outputs[0]
+	p.outputs = p.outputs[1:]
+	return output, nil
+}
+
+func (p *scriptedProvider) Name() string {
+	return "scripted"
+}
+
+// recordingAgent is a president stand-in that records the tasks it receives.
+// Its deliverables include files if it was given any, one set per task.
+type recordingAgent struct {
+	*BaseAgent
+	tasks []*types.Task
+	files [][]types.FileArtifact
+}
+
+func (a *recordingAgent) ProcessTask(ctx context.Context, task *types.Task) (*types.TaskResponse, error) {
+	a.tasks = append(a.tasks, task)
+	resp := &types.TaskResponse{
+		TaskID: task.ID,
+		Status: types.StatusCompleted,
+		Result: fmt.Sprintf("deliverable %d", len(a.tasks)),
+	}
+	if len(a.files) > 0 {
+		resp.Files, a.files = a.files[0], a.files[1:]
+	}
+	return resp, nil
+}
+
+// secretScanner reports a high finding in every file that contains a password.
+type secretScanner struct{}
+
+func (secretScanner) Name() string {
+	return "secrets"
+}
+
+func (secretScanner) Scan(ctx context.Context, dir string) ([]types.SecurityFinding, error) {
+	var findings []types.SecurityFinding
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if data, err := os.ReadFile(path); err == nil && strings.Contains(string(data), "password") {
+			findings = append(findings, types.SecurityFinding{Rule: "S1", Severity: scan.SeverityHigh, File: path, Line: 1, Message: "Hard-coded password"})
+		}
+		return nil
+	})
+	return findings, err
+}
+
+func TestClientAcceptance(t *testing.T) {
+	newOrg := func(t *testing.T, maxRevisions int, reviews ...string) (*Organization, *recordingAgent) {
+		t.Helper()
+
+		provider := &scriptedProvider{outputs: reviews}
+		llmManager, err := llm.NewManager(&types.LLMConfig{DefaultModel: "scripted"}, llm.WithProvider("scripted", provider))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		president := &recordingAgent{BaseAgent: NewBaseAgent("president-1", types.RolePresident, &types.AgentConfig{})}
+		org := &Organization{
+			config:    &types.Config{Organization: types.OrganizationConfig{Acceptance: types.AcceptanceConfig{MaxRevisions: maxRevisions}}},
+			president: president,
+			client:    NewClientAgent("client-1", &types.AgentConfig{Model: "scripted"}, llmManager),
+		}
+		return org, president
+	}
+
+	t.Run("RevisedUntilAccepted", func(t *testing.T) {
+		org, president := newOrg(t, 2,
+			`{"accepted": false, "feedback": "Incomplete", "change_requests": ["Add tests"]}`,
+			"```json\n{\"accepted\": true, \"feedback\": \"Looks good\"}\n```",
+		)
+
+		resp, err := org.ProcessClientTask(context.Background(), "Build a todo API")
+		if err != nil {
+			t.Fatalf("Failed to process task: %v", err)
+		}
+
+		if len(president.tasks) != 2 {
+			t.Fatalf("Expected one revision, got %d tasks", len(president.tasks))
+		}
+		revision := president.tasks[1]
+		if !strings.Contains(revision.Content, "Add tests") || !strings.Contains(revision.Content, "deliverable 1") {
+			t.Errorf("Expected revision to carry change requests and previous deliverable, got %q", revision.Content)
+		}
+		if revision.RunID != president.tasks[0].RunID {
+			t.Error("Expected revision to share the run ID")
+		}
+
+		if resp.Result != "deliverable 2" {
+			t.Errorf("Expected revised deliverable, got %q", resp.Result)
+		}
+		if resp.Metadata[MetadataAcceptance] != AcceptanceAccepted || resp.Metadata[MetadataRevisions] != "1" {
+			t.Errorf("Unexpected acceptance metadata: %v", resp.Metadata)
+		}
+	})
+
+	t.Run("RevisionLimit", func(t *testing.T) {
+		reject := `{"accepted": false, "feedback": "Still wrong"}`
+		org, president := newOrg(t, 1, reject, reject, reject)
+
+		resp, err := org.ProcessClientTask(context.Background(), "Build a todo API")
+		if err != nil {
+			t.Fatalf("Failed to process task: %v", err)
+		}
+
+		if len(president.tasks) != 2 {
+			t.Errorf("Expected revisions to stop at the limit, got %d tasks", len(president.tasks))
+		}
+		if resp.Metadata[MetadataAcceptance] != AcceptanceChangesRequested {
+			t.Errorf("Expected changes_requested, got %v", resp.Metadata)
+		}
+		if !strings.Contains(resp.Metadata[MetadataClientFeedback], "Still wrong") {
+			t.Errorf("Expected final feedback in metadata, got %v", resp.Metadata)
+		}
+	})
+
+	t.Run("ReviewFailure", func(t *testing.T) {
+		org, president := newOrg(t, 2, "not json")
+
+		resp, err := org.ProcessClientTask(context.Background(), "Build a todo API")
+		if err != nil {
+			t.Fatalf("Failed to process task: %v", err)
+		}
+
+		if len(president.tasks) != 1 || resp.Metadata[MetadataAcceptance] != AcceptanceUnreviewed {
+			t.Errorf("Expected unreviewed deliverable, got %d tasks and %v", len(president.tasks), resp.Metadata)
+		}
+	})
+
+	t.Run("WebhooksReportLifecycle", func(t *testing.T) {
+		org, _ := newOrg(t, 2,
+			`{"accepted": false, "feedback": "Incomplete", "change_requests": ["Add tests"]}`,
+			`{"accepted": true, "feedback": "Looks good"}`,
+		)
+
+		var (
+			mu       sync.Mutex
+			received []string
+		)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, err := webhook.Verify(r, []byte("secret"), 0)
+			if err != nil {
+				t.Errorf("Failed to verify webhook: %v", err)
+				return
+			}
+			mu.Lock()
+			received = append(received, strings.TrimSuffix(p.Event+" "+p.Milestone, " "))
+			mu.Unlock()
+		}))
+		defer server.Close()
+
+		dispatcher, err := webhook.New(&types.WebhooksConfig{Enabled: true}, "secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		org.webhooks = dispatcher
+
+		ctx := WithCallbackURL(types.WithRunID(context.Background(), "run-1"), server.URL)
+		if _, err := org.ProcessClientTask(ctx, "Build a todo API"); err != nil {
+			t.Fatalf("Failed to process task: %v", err)
+		}
+		dispatcher.Close(context.Background())
+
+		slices.Sort(received)
+		want := []string{
+			"project_completed",
+			"project_milestone deliverable_ready",
+			"project_milestone deliverable_ready",
+			"project_milestone revision_requested",
+			"project_started",
+		}
+		if !slices.Equal(received, want) {
+			t.Errorf("Expected the project's lifecycle at its callback, got %v", received)
+		}
+	})
+
+	t.Run("SecurityFindingsHoldBackAcceptance", func(t *testing.T) {
+		accept := `{"accepted": true, "feedback": "Looks good"}`
+		org, president := newOrg(t, 2, accept, accept, accept)
+		leaked := []types.FileArtifact{{Path: "main.go", Content: `const password = "hunter2"`}, {Path: "README.md", Content: "Usage"}}
+		president.files = [][]types.FileArtifact{leaked, leaked, leaked}
+		org.scanner = scan.NewRunner(scan.SeverityHigh, secretScanner{})
+
+		resp, err := org.ProcessClientTask(context.Background(), "Build a login API")
+		if err != nil {
+			t.Fatalf("Failed to process task: %v", err)
+		}
+
+		if len(president.tasks) != 3 {
+			t.Fatalf("Expected the deliverable to be revised while it has findings, got %d tasks", len(president.tasks))
+		}
+		if !strings.Contains(president.tasks[1].Content, "Fix security finding [high] S1 main.go:1 (secrets): Hard-coded password") {
+			t.Errorf("Expected the finding in the revision, got %q", president.tasks[1].Content)
+		}
+		if resp.Metadata[MetadataAcceptance] != AcceptanceChangesRequested || len(resp.Findings) != 1 {
+			t.Errorf("Expected the last deliverable to stay blocked by its finding, got %v and %+v", resp.Metadata, resp.Findings)
+		}
+	})
+
+	t.Run("JudgesHoldBackLowScores", func(t *testing.T) {
+		accept := `{"accepted": true, "feedback": "Looks good"}`
+		org, president := newOrg(t, 2, accept, accept)
+
+		judges := &scriptedProvider{outputs: []string{
+			`{"reason": "Password stored in plain text", "rating": 2}`,
+			`{"reason": "Passwords are hashed", "rating": 4}`,
+		}}
+		judgeManager, err := llm.NewManager(&types.LLMConfig{DefaultModel: "scripted"}, llm.WithProvider("scripted", judges))
+		if err != nil {
+			t.Fatal(err)
+		}
+		org.config.Judges = &types.JudgesConfig{Enabled: true, MinScore: 0.5}
+		org.judges = judge.NewPanel(judgeManager, "", judge.Security)
+
+		resp, err := org.ProcessClientTask(context.Background(), "Build a login API")
+		if err != nil {
+			t.Fatalf("Failed to process task: %v", err)
+		}
+
+		if len(president.tasks) != 2 {
+			t.Fatalf("Expected the low-scoring deliverable to be revised despite the client accepting it, got %d tasks", len(president.tasks))
+		}
+		if !strings.Contains(president.tasks[1].Content, "Improve security (rated 2/5): Password stored in plain text") {
+			t.Errorf("Expected the judge's reason in the revision, got %q", president.tasks[1].Content)
+		}
+		if len(resp.Scores) != 1 || resp.Scores[0].Rating != 4 || resp.Scores[0].TaskID != president.tasks[1].ID {
+			t.Errorf("Expected the final deliverable's score, got %+v", resp.Scores)
+		}
+		if resp.Metadata[MetadataAcceptance] != AcceptanceAccepted {
+			t.Errorf("Expected the revision to be accepted, got %v", resp.Metadata)
+		}
+	})
+}
+
+func TestAttachedDocuments(t *testing.T) {
+	dir := t.TempDir()
+	spec := filepath.Join(dir, "spec.md")
+	if err := os.WriteFile(spec, []byte("# Requirements\n\nOrders are immutable.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	president := &recordingAgent{BaseAgent: NewBaseAgent("president-1", types.RolePresident, &types.AgentConfig{})}
+	org := &Organization{config: &types.Config{}, president: president}
+
+	if _, err := org.ProcessClientTask(WithDocuments(context.Background(), spec), "Build the order API"); err != nil {
+		t.Fatal(err)
+	}
+	task := president.tasks[0]
+	if !strings.HasPrefix(task.Content, "Build the order API\n\n=== Attached document: spec.md ===\n# Requirements") ||
+		task.Description != "Build the order API" || task.Metadata[MetadataDocuments] != "spec.md" {
+		t.Errorf("Expected the document in the task content, got %+v", task)
+	}
+
+	_, err := org.ProcessClientTask(WithDocuments(context.Background(), filepath.Join(dir, "missing.pdf")), "Build it")
+	if !errors.Is(err, errors.New(errors.CodeInvalidArgument, "")) || len(president.tasks) != 1 {
+		t.Errorf("Expected an unreadable document to fail the submission, got %v", err)
+	}
+}
+
+// imageBackend generates a placeholder PNG for every prompt, failing for those containing "fail".
+type imageBackend struct{}
+
+func (imageBackend) Generate(ctx context.Context, prompt string) (*imagegen.Image, error) {
+	if strings.Contains(prompt, "fail") {
+		return nil, fmt.Errorf("content policy violation")
+	}
+	return &imagegen.Image{Data: []byte("\x89PNG\r\n\x1a\n"), MediaType: "image/png"}, nil
+}
+
+func (imageBackend) Model() string {
+	return "fake-image"
+}
+
+func TestImageGeneration(t *testing.T) {
+	dir := t.TempDir()
+	images, err := imagegen.New(&types.ImagesConfig{Enabled: true, Dir: dir, MaxPerTask: 2}, "", imagegen.WithBackend(imageBackend{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := &scriptedProvider{outputs: []string{strings.Join([]string{
+		"Here is the login design.",
+		"=== IMAGE: docs/login.png ===",
+		"Wireframe of a login screen",
+		"=== END IMAGE ===",
+		"=== IMAGE: docs/broken.png ===",
+		"This one should fail",
+		"=== END IMAGE ===",
+		"=== IMAGE: docs/extra.png ===",
+		"One image too many",
+		"=== END IMAGE ===",
+	}, "\n")}}
+	llmManager, err := llm.NewManager(&types.LLMConfig{DefaultModel: "scripted"}, llm.WithProvider("scripted", provider))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mem := &sharedMemoryManager{}
+	engineer := NewEngineerAgent("engineer-1", &types.AgentConfig{Model: "scripted"}, llmManager, WithImages(images))
+	engineer.memoryManager = mem
+
+	resp, err := engineer.ProcessTask(context.Background(), &types.Task{ID: "task-1", RunID: "run-1", Title: "Design the login screen", Content: "Design it"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(provider.prompts[0], "=== IMAGE: docs/mockup.png ===") || !strings.Contains(provider.prompts[0], "up to 2 image(s)") {
+		t.Errorf("Expected the prompt to offer image generation, got:\n%s", provider.prompts[0])
+	}
+	if len(resp.Files) != 1 || resp.Files[0].Path != "docs/login.png" || !resp.Files[0].Binary() || resp.Files[0].Provenance.Model != "fake-image" {
+		t.Fatalf("Expected the generated image in the manifest, got %+v", resp.Files)
+	}
+	for _, want := range []string{
+		"![Wireframe of a login screen](docs/login.png)",
+		"[Image docs/broken.png could not be generated:",
+		"[Image docs/extra.png not generated: at most 2 images per task]",
+	} {
+		if !strings.Contains(resp.Result, want) {
+			t.Errorf("Result missing %q:\n%s", want, resp.Result)
+		}
+	}
+	if strings.Contains(resp.Result, imageStartPrefix) {
+		t.Errorf("Expected the image blocks to be replaced:\n%s", resp.Result)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "run-1", "docs", "login.png")); err != nil {
+		t.Errorf("Expected the image to be stored: %v", err)
+	}
+	if len(mem.entries) != 1 || mem.entries[0].Metadata["path"] != "docs/login.png" || mem.entries[0].RunID != "run-1" {
+		t.Errorf("Expected the image recorded for the report, got %+v", mem.entries)
+	}
+	if rendered := renderFiles("", resp.Files); strings.Contains(rendered, resp.Files[0].Content) {
+		t.Errorf("Expected binary content to be left out of rendered files:\n%s", rendered)
+	}
+}
+
+// transcriptBackend "synthesizes" speech by recording the text as the audio.
+type transcriptBackend struct{}
+
+func (transcriptBackend) Synthesize(ctx context.Context, text string) ([]byte, error) {
+	return []byte(text), nil
+}
+
+func TestSpokenDigest(t *testing.T) {
+	dir := t.TempDir()
+	speaker, err := speech.New(&types.SpeechConfig{Enabled: true, Dir: dir}, "", speech.WithBackend(transcriptBackend{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	president := &recordingAgent{
+		BaseAgent: NewBaseAgent("president-1", types.RolePresident, &types.AgentConfig{}),
+		files:     [][]types.FileArtifact{{fileArtifact("main.go", "package main\n")}},
+	}
+	org := &Organization{config: &types.Config{}, president: president, speech: speaker}
+
+	resp, err := org.ProcessClientTask(types.WithRunID(context.Background(), "run-1"), "Build the **order** API")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored := filepath.Join(dir, "run-1.mp3")
+	if resp.Metadata[MetadataDigestAudio] != stored {
+		t.Errorf("Expected the digest path in the metadata, got %v", resp.Metadata)
+	}
+	if len(resp.Files) != 2 || resp.Files[1].Path != "digest/run-1.mp3" || resp.Files[1].MediaType != "audio/mpeg" {
+		t.Errorf("Expected the digest among the files, got %+v", resp.Files)
+	}
+	transcript, err := os.ReadFile(stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`The project "Build the order API" is complete.`,
+		"What was done: deliverable 1.",
+		"It produced 1 file(s), including main.go.",
+	} {
+		if !strings.Contains(string(transcript), want) {
+			t.Errorf("Digest missing %q: %s", want, transcript)
+		}
+	}
+
+	digest := projectDigest("Build it", nil, fmt.Errorf("president unavailable"))
+	if digest != `Project digest. The project "Build it" failed. The error was: president unavailable.` {
+		t.Errorf("Unexpected failure digest %q", digest)
+	}
+}
+
+func TestDemoMode(t *testing.T) {
+	demo := &types.DemoConfig{MaxInstructionChars: 40, ProjectsPerHour: 2, Watermark: "DEMO OUTPUT", Enabled: true}
+
+	t.Run("Restrict", func(t *testing.T) {
+		cfg := &types.Config{
+			LLMs:   types.LLMConfig{APIKeys: map[string]types.EnvironmentVariable{"openai": {Env: "OPENAI_API_KEY"}}},
+			Admin:  &types.AdminConfig{Enabled: true},
+			Slack:  &types.SlackConfig{},
+			Speech: &types.SpeechConfig{Enabled: true},
+			Demo:   demo,
+			Organization: types.OrganizationConfig{
+				Layers:    []types.LayerConfig{{Name: "Engineer", Agent: "engineer", Count: 8}},
+				Questions: types.QuestionsConfig{Enabled: true},
+				Admission: types.AdmissionConfig{MaxConcurrent: 50},
+			},
+		}
+
+		restricted := RestrictForDemo(cfg)
+		if restricted.LLMs.APIKeys != nil || restricted.Admin != nil || restricted.Slack != nil || restricted.Speech != nil {
+			t.Errorf("Expected API keys and integrations removed, got %+v", restricted)
+		}
+		if restricted.Organization.Layers[0].Count != 1 || restricted.Organization.Questions.Enabled ||
+			restricted.Organization.Admission.MaxConcurrent != demoMaxConcurrent {
+			t.Errorf("Expected a small organization, got %+v", restricted.Organization)
+		}
+		if cfg.Admin == nil || cfg.Organization.Layers[0].Count != 8 {
+			t.Error("Expected the original configuration unchanged")
+		}
+		if plain := (&types.Config{}); RestrictForDemo(plain) != plain {
+			t.Error("Expected configurations without demo mode returned as they are")
+		}
+
+		llmManager, err := newDemoLLMManager(restricted)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out, err := llmManager.Generate(context.Background(), "gpt-4o", "Title: Todo app", nil); err != nil || !strings.Contains(out, "mock provider") {
+			t.Errorf("Expected the mock provider to serve every model, got %q, %v", out, err)
+		}
+		if _, err := newDemoLLMManager(&types.Config{Demo: &types.DemoConfig{Provider: "openai", Enabled: true}}); err == nil {
+			t.Error("Expected an error for a paid demo provider")
+		}
+	})
+
+	t.Run("Limits", func(t *testing.T) {
+		president := &recordingAgent{BaseAgent: NewBaseAgent("president-1", types.RolePresident, &types.AgentConfig{})}
+		org := &Organization{config: &types.Config{Demo: demo}, president: president, demo: newDemoGate(demo)}
+		now := time.Now()
+		org.demo.now = func() time.Time { return now }
+		ctx := types.WithClient(types.WithUser(context.Background(), "alice"), "203.0.113.7")
+
+		if _, err := org.ProcessClientTask(ctx, strings.Repeat("x", 41)); errors.CodeOf(err) != errors.CodeInvalidArgument {
+			t.Errorf("Expected a long request rejected, got %v", err)
+		}
+		if _, err := org.ProcessClientTask(WithDocuments(ctx, "/etc/passwd"), "Summarize it"); errors.CodeOf(err) != errors.CodeInvalidArgument {
+			t.Errorf("Expected attached documents rejected, got %v", err)
+		}
+		for range 2 {
+			if _, err := org.ProcessClientTask(ctx, "Build a todo app"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := org.ProcessClientTask(types.WithRunID(ctx, "run-3"), "Build a todo app"); errors.CodeOf(err) != errors.CodeOverloaded {
+			t.Errorf("Expected the third project within the hour rejected, got %v", err)
+		} else if body := errors.ToBody(err); body.ProjectID != "run-3" {
+			t.Errorf("Expected the rejection to name its project, got %+v", body)
+		}
+		if _, err := org.ProcessClientTask(types.WithUser(ctx, "mallory"), "Build a todo app"); errors.CodeOf(err) != errors.CodeOverloaded {
+			t.Errorf("Expected a new user name from the same client rejected, got %v", err)
+		}
+		if _, err := org.ProcessClientTask(types.WithClient(context.Background(), "198.51.100.2"), "Build a todo app"); err != nil {
+			t.Errorf("Expected another client admitted, got %v", err)
+		}
+
+		now = now.Add(time.Hour)
+		if _, err := org.ProcessClientTask(ctx, "Build a todo app"); err != nil {
+			t.Errorf("Expected the allowance restored after an hour, got %v", err)
+		}
+		if len(president.tasks) != 4 {
+			t.Errorf("Expected 4 projects dispatched, got %d", len(president.tasks))
+		}
+
+		// Clients whose submissions have all expired are forgotten
+		now = now.Add(time.Hour)
+		if _, err := org.ProcessClientTask(types.WithClient(context.Background(), "192.0.2.9"), "Build a todo app"); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := org.demo.submitted["198.51.100.2"]; ok || len(org.demo.submitted) != 1 {
+			t.Errorf("Expected only the latest client kept, got %v", org.demo.submitted)
+		}
+	})
+
+	t.Run("CountsAcceptedProjects", func(t *testing.T) {
+		president := &recordingAgent{BaseAgent: NewBaseAgent("president-1", types.RolePresident, &types.AgentConfig{})}
+		org := &Organization{
+			config:    &types.Config{Demo: demo},
+			president: president,
+			demo:      newDemoGate(demo),
+			admission: newAdmission(types.AdmissionConfig{MaxConcurrent: 1}),
+		}
+		ctx := types.WithClient(context.Background(), "203.0.113.7")
+
+		// Projects turned away by admission control leave the allowance untouched
+		release, err := org.admission.acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for range 3 {
+			if _, err := org.ProcessClientTask(ctx, "Build a todo app"); errors.CodeOf(err) != errors.CodeOverloaded {
+				t.Fatalf("Expected the project rejected at capacity, got %v", err)
+			}
+		}
+		release()
+		if recent := org.demo.submitted["203.0.113.7"]; len(recent) != 0 {
+			t.Errorf("Expected rejected projects not counted, got %d", len(recent))
+		}
+		if _, err := org.ProcessClientTask(ctx, "Build a todo app"); err != nil {
+			t.Errorf("Expected the client admitted once capacity frees up, got %v", err)
+		}
+	})
+
+	t.Run("Watermark", func(t *testing.T) {
+		president := &recordingAgent{
+			BaseAgent: NewBaseAgent("president-1", types.RolePresident, &types.AgentConfig{}),
+			files:     [][]types.FileArtifact{{fileArtifact("main.go", "package main\n"), {Path: "logo.png", Content: "iVBORw0KGgo=", MediaType: "image/png", Bytes: 8}}},
+		}
+		org := &Organization{config: &types.Config{Demo: demo}, president: president, demo: newDemoGate(demo)}
+
+		resp, err := org.ProcessClientTask(context.Background(), "Build a todo app")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(resp.Result, "DEMO OUTPUT\n\n") || resp.Metadata[MetadataDemo] != "true" {
+			t.Errorf("Expected a watermarked result, got %q, %v", resp.Result, resp.Metadata)
+		}
+		if got := resp.Files[0]; got.Content != "// DEMO OUTPUT\n\npackage main\n" || got.Bytes != len(got.Content) {
+			t.Errorf("Expected a watermarked source file, got %+v", got)
+		}
+		if resp.Files[1].Content != "iVBORw0KGgo=" {
+			t.Errorf("Expected binary files untouched, got %+v", resp.Files[1])
+		}
+	})
+}
+
+func TestGateway(t *testing.T) {
+	president := &recordingAgent{BaseAgent: NewBaseAgent("president-1", types.RolePresident, &types.AgentConfig{})}
+	org := &Organization{config: &types.Config{}, president: president}
+	gateway := org.Gateway()
+
+	if gateway.GetID() != "president-1" || gateway.GetRole() != types.RolePresident {
+		t.Errorf("Expected the gateway addressed as the president, got %s %s", gateway.GetID(), gateway.GetRole())
+	}
+	resp, err := gateway.ProcessTask(context.Background(), &types.Task{ID: "task-1", RunID: "run-1", Description: "Build a todo app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(president.tasks) != 1 || president.tasks[0].Content != "Build a todo app" || president.tasks[0].RunID != "run-1" {
+		t.Errorf("Expected the task submitted as a client project under its run ID, got %+v", president.tasks)
+	}
+	if resp.Result != "deliverable 1" {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+}
+
+func TestConventions(t *testing.T) {
+	dir := t.TempDir()
+	docTemplate := filepath.Join(dir, "doc.md")
+	if err := os.WriteFile(docTemplate, []byte("# Name\n\n## Usage\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := types.ConventionsConfig{Language: "English", StyleGuide: "Effective Go", CommitFormat: "Conventional Commits", DocTemplate: docTemplate, Enabled: true}
+
+	t.Run("Prompt", func(t *testing.T) {
+		conventions, err := NewConventions(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		provider := &scriptedProvider{outputs: []string{"done"}}
+		llmManager, err := llm.NewManager(&types.LLMConfig{}, llm.WithProvider("scripted", provider))
+		if err != nil {
+			t.Fatal(err)
+		}
+		engineer := NewEngineerAgent("engineer-1", &types.AgentConfig{Model: "scripted"}, llmManager, WithConventions(conventions))
+		if _, err := engineer.ProcessTask(context.Background(), &types.Task{ID: "t1", Title: "Write the README"}); err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{"=== Organization Conventions ===", "in English", "Effective Go", "Conventional Commits", "## Usage"} {
+			if !strings.Contains(provider.prompts[0], want) {
+				t.Errorf("Expected %q in the prompt, got %q", want, provider.prompts[0])
+			}
+		}
+	})
+
+	t.Run("Template", func(t *testing.T) {
+		custom := cfg
+		custom.Template = "{{.Role}} on {{.Title}}: {{.StyleGuide}}"
+		conventions, err := NewConventions(custom)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := conventions.Render(types.RoleManager, &types.Task{Title: "API"}); err != nil || got != "Manager on API: Effective Go" {
+			t.Errorf("Render() = %q, %v", got, err)
+		}
+
+		for name, broken := range map[string]types.ConventionsConfig{
+			"Template":    {Template: "{{.Missing", Enabled: true},
+			"DocTemplate": {DocTemplate: filepath.Join(dir, "missing.md"), Enabled: true},
+		} {
+			if _, err := NewConventions(broken); err == nil {
+				t.Errorf("%s: expected an error", name)
+			}
+		}
+		if conventions, err := NewConventions(types.ConventionsConfig{}); conventions != nil || err != nil {
+			t.Errorf("Expected no conventions when disabled, got %v, %v", conventions, err)
+		}
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		validated := cfg
+		validated.Validate = true
+		conventions, err := NewConventions(validated)
+		if err != nil {
+			t.Fatal(err)
+		}
+		provider := &scriptedProvider{outputs: []string{"- The README is written in French\n"}}
+		llmManager, err := llm.NewManager(&types.LLMConfig{}, llm.WithProvider("scripted", provider))
+		if err != nil {
+			t.Fatal(err)
+		}
+		president := &recordingAgent{
+			BaseAgent: NewBaseAgent("president-1", types.RolePresident, &types.AgentConfig{Model: "scripted"}),
+			files:     [][]types.FileArtifact{{fileArtifact("main.go", "package main\nfunc main(){}\n")}},
+		}
+		org := &Organization{
+			config:      &types.Config{Organization: types.OrganizationConfig{Conventions: validated}},
+			president:   president,
+			llmManager:  llmManager,
+			conventions: conventions,
+		}
+
+		resp, err := org.ProcessClientTask(context.Background(), "Build a CLI")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "main.go is not formatted with gofmt\nThe README is written in French"
+		if got := resp.Metadata[MetadataDeviations]; got != want {
+			t.Errorf("deviations = %q, want %q", got, want)
+		}
+		if changes := org.requiredChanges(resp); len(changes) != 2 || !strings.HasPrefix(changes[0], "Follow the organization's conventions") {
+			t.Errorf("Expected the deviations to require changes, got %v", changes)
+		}
+		if !strings.Contains(provider.prompts[0], "Conventional Commits") || !strings.Contains(provider.prompts[0], "func main(){}") {
+			t.Errorf("Expected the conventions and files in the check, got %q", provider.prompts[0])
+		}
+	})
+}
+
+func TestTranslation(t *testing.T) {
+	newOrg := func(t *testing.T, outputs ...string) (*Organization, *recordingAgent, *scriptedProvider) {
+		t.Helper()
+
+		provider := &scriptedProvider{outputs: outputs}
+		llmManager, err := llm.NewManager(&types.LLMConfig{}, llm.WithProvider("scripted", provider))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		president := &recordingAgent{BaseAgent: NewBaseAgent("president-1", types.RolePresident, &types.AgentConfig{Model: "scripted"})}
+		org := &Organization{
+			config:     &types.Config{Organization: types.OrganizationConfig{Translation: types.TranslationConfig{Enabled: true}}},
+			president:  president,
+			llmManager: llmManager,
+		}
+		return org, president, provider
+	}
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		org, president, _ := newOrg(t,
+			"```json\n{\"language\": \"Japanese\", \"translation\": \"Build a todo app\"}\n```",
+			"成果物 1",
+		)
+
+		resp, err := org.ProcessClientTask(context.Background(), "TODOアプリを作って")
+		if err != nil {
+			t.Fatalf("Failed to process task: %v", err)
+		}
+
+		task := president.tasks[0]
+		if task.Content != "Build a todo app" || task.Metadata[MetadataLanguage] != "Japanese" || task.Metadata[MetadataOriginalRequest] != "TODOアプリを作って" {
+			t.Errorf("Expected agents to receive the translated request, got %q with %v", task.Content, task.Metadata)
+		}
+		if resp.Result != "成果物 1" || resp.Metadata[MetadataCanonicalResult] != "deliverable 1" || resp.Metadata[MetadataLanguage] != "Japanese" {
+			t.Errorf("Expected the deliverable in the requester's language, got %q with %v", resp.Result, resp.Metadata)
+		}
+	})
+
+	t.Run("EnglishNotDetected", func(t *testing.T) {
+		org, president, provider := newOrg(t)
+
+		resp, err := org.ProcessClientTask(context.Background(), "Build a todo app with tags and share it from the browser")
+		if err != nil {
+			t.Fatalf("Failed to process task: %v", err)
+		}
+
+		if len(provider.prompts) != 0 {
+			t.Errorf("Expected plain English to skip detection, got %d prompts", len(provider.prompts))
+		}
+		if president.tasks[0].Metadata[MetadataLanguage] != "" || resp.Result != "deliverable 1" {
+			t.Errorf("Expected the request and deliverable untouched, got %v and %q", president.tasks[0].Metadata, resp.Result)
+		}
+	})
+
+	t.Run("DetectionFailure", func(t *testing.T) {
+		org, president, _ := newOrg(t, "not json")
+
+		if _, err := org.ProcessClientTask(context.Background(), "Crea una aplicación de tareas"); err != nil {
+			t.Fatalf("Failed to process task: %v", err)
+		}
+		if president.tasks[0].Content != "Crea una aplicación de tareas" {
+			t.Errorf("Expected the request as written when detection fails, got %q", president.tasks[0].Content)
+		}
+	})
+}
+
+// flakyAgent fails its first failures tasks, then completes.
+type flakyAgent struct {
+	*BaseAgent
+	tasks    []*types.Task
+	failures int
+}
+
+func (a *flakyAgent) ProcessTask(ctx context.Context, task *types.Task) (*types.TaskResponse, error) {
+	a.tasks = append(a.tasks, task)
+	if len(a.tasks) <= a.failures {
+		return &types.TaskResponse{TaskID: task.ID, Status: types.StatusFailed, Error: "compilation failed"}, nil
+	}
+	return &types.TaskResponse{TaskID: task.ID, Status: types.StatusCompleted, Result: "done by " + a.GetID()}, nil
+}
+
+func TestRecovery(t *testing.T) {
+	newEngineer := func(id string, failures int) *flakyAgent {
+		return &flakyAgent{BaseAgent: NewBaseAgent(id, types.RoleEngineer, &types.AgentConfig{}), failures: failures}
+	}
+	task := &types.Task{ID: "design-1", Title: "Design API", Description: "Build a todo API", RunID: "run-1"}
+
+	t.Run("Disabled", func(t *testing.T) {
+		manager := NewManagerAgent("manager-1", &types.AgentConfig{}, nil)
+		manager.AddEngineer(newEngineer("engineer-1", 1))
+		manager.AddEngineer(newEngineer("engineer-2", 0))
+
+		if _, err := manager.ProcessTask(context.Background(), task); err == nil {
+			t.Error("Expected failure without a recovery policy")
+		}
+	})
+
+	t.Run("Reassign", func(t *testing.T) {
+		notifier := &recordingNotifier{}
+		manager := NewManagerAgent("manager-1", &types.AgentConfig{}, nil,
+			WithNotifier(notifier),
+			WithRecovery(types.RecoveryConfig{MaxReplans: 2}),
+		)
+		failing, healthy := newEngineer("engineer-1", 1), newEngineer("engineer-2", 0)
+		manager.AddEngineer(failing)
+		manager.AddEngineer(healthy)
+
+		resp, err := manager.ProcessTask(context.Background(), task)
+		if err != nil {
+			t.Fatalf("Expected recovery, got %v", err)
+		}
+
+		if len(healthy.tasks) != 1 || healthy.tasks[0].RunID != "run-1" {
+			t.Fatalf("Expected the branch to be reassigned within the run, got %d tasks", len(healthy.tasks))
+		}
+		if !strings.Contains(resp.Metadata[MetadataRecovery], "reassign via engineer-2 succeeded") {
+			t.Errorf("Expected recovery path in metadata, got %v", resp.Metadata)
+		}
+		if !strings.Contains(resp.Result, "done by engineer-2") {
+			t.Errorf("Expected result from the reassigned engineer, got %q", resp.Result)
+		}
+
+		var replanned bool
+		for _, m := range notifier.messages {
+			replanned = replanned || strings.HasPrefix(m, "task_replanned")
+		}
+		if !replanned {
+			t.Errorf("Expected a task_replanned notification, got %v", notifier.messages)
+		}
+	})
+
+	t.Run("ReduceScope", func(t *testing.T) {
+		manager := NewManagerAgent("manager-1", &types.AgentConfig{}, nil,
+			WithRecovery(types.RecoveryConfig{MaxReplans: 3, Strategies: []string{RecoveryReassign, RecoveryAlternative, RecoveryReduceScope}}),
+		)
+		engineer := newEngineer("engineer-1", 2)
+		manager.AddEngineer(engineer)
+
+		resp, err := manager.ProcessTask(context.Background(), task)
+		if err != nil {
+			t.Fatalf("Expected recovery, got %v", err)
+		}
+
+		// Reassign is skipped with a single engineer
+		if len(engineer.tasks) != 3 {
+			t.Fatalf("Expected 3 attempts, got %d", len(engineer.tasks))
+		}
+		if !strings.Contains(engineer.tasks[1].Content, "different approach") || !strings.Contains(engineer.tasks[1].Content, "compilation failed") {
+			t.Errorf("Expected alternative approach instructions, got %q", engineer.tasks[1].Content)
+		}
+		if !strings.Contains(engineer.tasks[2].Content, "Reduce the scope") {
+			t.Errorf("Expected reduced scope instructions, got %q", engineer.tasks[2].Content)
+		}
+		if !strings.Contains(resp.Metadata[MetadataRecovery], "alternative via engineer-1 failed") {
+			t.Errorf("Expected failed attempt in recovery path, got %v", resp.Metadata)
+		}
+	})
+
+	t.Run("Exhausted", func(t *testing.T) {
+		manager := NewManagerAgent("manager-1", &types.AgentConfig{}, nil,
+			WithRecovery(types.RecoveryConfig{MaxReplans: 2, Strategies: []string{RecoveryAlternative}}),
+		)
+		engineer := newEngineer("engineer-1", 10)
+		manager.AddEngineer(engineer)
+
+		_, err := manager.ProcessTask(context.Background(), task)
+		if err == nil || !strings.Contains(err.Error(), "after 2 re-plan(s)") {
+			t.Errorf("Expected failure after re-plan cap, got %v", err)
+		}
+		if len(engineer.tasks) != 3 {
+			t.Errorf("Expected 3 attempts, got %d", len(engineer.tasks))
+		}
+	})
+}
+
+func TestDeduplicator(t *testing.T) {
+	spec := "Implement a REST API for todo items with create, read, update and delete endpoints backed by PostgreSQL"
+
+	t.Run("SharesSimilarTasks", func(t *testing.T) {
+		dedup := NewDeduplicator(types.DeduplicationConfig{Threshold: 0.8})
+		provider := &scriptedProvider{outputs: []string{"package todo"}}
+		llmManager, err := llm.NewManager(&types.LLMConfig{}, llm.WithProvider("scripted", provider))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cfg := &types.AgentConfig{Model: "scripted"}
+		first := NewEngineerAgent("engineer-1", cfg, llmManager, WithDeduplicator(dedup))
+		second := NewEngineerAgent("engineer-2", cfg, llmManager, WithDeduplicator(dedup))
+
+		ctx := context.Background()
+		resp1, err := first.ProcessTask(ctx, &types.Task{ID: "task-1", Title: "Todo API", Content: spec})
+		if err != nil {
+			t.Fatalf("Failed to process task: %v", err)
+		}
+
+		// Only the trailing period differs, which tokenization ignores
+		resp2, err := second.ProcessTask(ctx, &types.Task{ID: "task-2", Title: "Todo API", Content: spec + "."})
+		if err != nil {
+			t.Fatalf("Failed to process duplicate task: %v", err)
+		}
+
+		if len(provider.prompts) != 1 {
+			t.Errorf("Expected one generation, got %d", len(provider.prompts))
+		}
+		if resp2.TaskID != "task-2" || resp2.Result != resp1.Result || resp2.Metadata[MetadataDeduplicatedFrom] != "task-1" {
+			t.Errorf("Expected shared result for task-2, got %+v", resp2)
+		}
+
+		stats := dedup.Stats()
+		if stats.Tasks != 2 || stats.Shared != 1 || stats.SavedTokens == 0 {
+			t.Errorf("Unexpected stats: %+v", stats)
+		}
+	})
+
+	t.Run("DistinctAndExpired", func(t *testing.T) {
+		dedup := NewDeduplicator(types.DeduplicationConfig{TTL: time.Minute})
+		now := time.Now()
+		dedup.now = func() time.Time { return now }
+
+		runs := 0
+		run := func() (*types.TaskResponse, error) {
+			runs++
+			return &types.TaskResponse{Status: types.StatusCompleted, Result: "done"}, nil
+		}
+
+		ctx := context.Background()
+		_, _, _ = dedup.Do(ctx, "engineer-1", &types.Task{ID: "a", Content: spec}, run)
+		_, shared, _ := dedup.Do(ctx, "engineer-2", &types.Task{ID: "b", Content: "Write a command line tool that resizes images"}, run)
+		if shared || runs != 2 {
+			t.Errorf("Expected distinct task to run, shared=%v runs=%d", shared, runs)
+		}
+
+		now = now.Add(2 * time.Minute)
+		if _, shared, _ := dedup.Do(ctx, "engineer-2", &types.Task{ID: "c", Content: spec}, run); shared {
+			t.Error("Expected expired result not to be shared")
+		}
+	})
+
+	t.Run("FailuresNotShared", func(t *testing.T) {
+		dedup := NewDeduplicator(types.DeduplicationConfig{})
+		ctx := context.Background()
+
+		_, _, _ = dedup.Do(ctx, "engineer-1", &types.Task{ID: "a", Content: spec}, func() (*types.TaskResponse, error) {
+			return nil, fmt.Errorf("provider outage")
+		})
+
+		resp, shared, err := dedup.Do(ctx, "engineer-2", &types.Task{ID: "b", Content: spec}, func() (*types.TaskResponse, error) {
+			return &types.TaskResponse{Status: types.StatusCompleted, Result: "ok"}, nil
+		})
+		if err != nil || shared || resp.Result != "ok" {
+			t.Errorf("Expected duplicate of a failure to run itself, got %+v shared=%v err=%v", resp, shared, err)
+		}
+	})
+}
+
+// gatedProvider is an llm.Provider that blocks each generation until released
+// and records which provider model served it.
+type gatedProvider struct {
+	started chan struct{}
+	release chan struct{}
+	name    string
+}
+
+func (p *gatedProvider) Generate(ctx context.Context, prompt string, opts *llm.GenerateOptions) (string, error) {
+	p.started <- struct{}{}
+	<-p.release
+	if opts.Model != "" {
+		return p.name + ":" + opts.Model, nil
+	}
+	return p.name, nil
+}
+
+func (p *gatedProvider) Name() string {
+	return p.name
+}
+
+func TestSetModel(t *testing.T) {
+	gemini := &gatedProvider{name: "gemini", started: make(chan struct{}, 1), release: make(chan struct{})}
+	claude := &gatedProvider{name: "claude", started: make(chan struct{}, 1), release: make(chan struct{})}
+	llmManager, err := llm.NewManager(&types.LLMConfig{},
+		llm.WithProvider("gemini", gemini),
+		llm.WithProvider("claude", claude),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	engineers := []types.Agent{
+		NewEngineerAgent("engineer-1", &types.AgentConfig{}, llmManager),
+		NewEngineerAgent("engineer-2", &types.AgentConfig{Model: "gemini"}, llmManager),
+	}
+	manager := NewManagerAgent("manager-1", &types.AgentConfig{}, llmManager)
+	org := &Organization{llmManager: llmManager, managers: []types.Agent{manager}, engineers: engineers}
+
+	t.Run("DrainsInFlightGenerations", func(t *testing.T) {
+		done := make(chan *types.TaskResponse)
+		go func() {
+			resp, _ := engineers[0].ProcessTask(context.Background(), &types.Task{ID: "t1", Title: "Before"})
+			done <- resp
+		}()
+		<-gemini.started
+
+		swapped := make(chan []string)
+		go func() {
+			ids, err := org.SetModel("engineer", "claude-3-5-sonnet")
+			if err != nil {
+				t.Error(err)
+			}
+			swapped <- ids
+		}()
+
+		select {
+		case <-swapped:
+			t.Fatal("Expected SetModel to wait for the in-flight generation")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		gemini.release <- struct{}{}
+		if resp := <-done; !strings.Contains(resp.Result, "\ngemini\n") {
+			t.Errorf("Expected in-flight generation to finish on the old model, got %q", resp.Result)
+		}
+
+		ids := <-swapped
+		if len(ids) != 2 {
+			t.Errorf("Expected both engineers to be switched, got %v", ids)
+		}
+
+		go func() { <-claude.started; claude.release <- struct{}{} }()
+		resp, err := engineers[1].ProcessTask(context.Background(), &types.Task{ID: "t2", Title: "After"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(resp.Result, "claude:claude-3-5-sonnet") {
+			t.Errorf("Expected generation on the new model, got %q", resp.Result)
+		}
+	})
+
+	t.Run("ReportsModels", func(t *testing.T) {
+		models := map[string]string{}
+		for _, info := range org.Agents() {
+			models[info.ID] = info.Model
+		}
+		if models["manager-1"] != "gemini" || models["engineer-1"] != "claude-3-5-sonnet" {
+			t.Errorf("Unexpected models: %v", models)
+		}
+	})
+
+	t.Run("DescribesAgents", func(t *testing.T) {
+		d, err := org.DescribeAgent("engineer-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.Role != types.RoleEngineer || d.Model != "claude-3-5-sonnet" || d.Status != "stopped" || d.CompletedTasks != 1 || d.Version == "" {
+			t.Errorf("Unexpected description: %+v", d)
+		}
+		if _, err := org.DescribeAgent("engineer-9"); errors.CodeOf(err) != errors.CodeNotFound {
+			t.Errorf("Expected not found for an unknown agent, got %v", err)
+		}
+	})
+
+	t.Run("ByAgentID", func(t *testing.T) {
+		ids, err := org.SetModel("manager-1", "claude")
+		if err != nil || len(ids) != 1 || manager.Model() != "claude" {
+			t.Errorf("Expected manager-1 to switch to claude, got %v, %v", ids, err)
+		}
+	})
+
+	t.Run("RejectsUnknownModelOrTarget", func(t *testing.T) {
+		if _, err := org.SetModel("engineer", "mistral-large"); err == nil {
+			t.Error("Expected error for a model no provider serves")
+		}
+		if _, err := org.SetModel("designer", "claude"); err == nil {
+			t.Error("Expected error for an unknown role")
+		}
+	})
+}
+
+// blockingProvider generates until its context is canceled.
+type blockingProvider struct {
+	started chan struct{}
+}
+
+func (p *blockingProvider) Generate(ctx context.Context, prompt string, opts *llm.GenerateOptions) (string, error) {
+	p.started <- struct{}{}
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func (p *blockingProvider) Name() string {
+	return "blocking"
+}
+
+func TestCancelGeneration(t *testing.T) {
+	provider := &blockingProvider{started: make(chan struct{}, 1)}
+	llmManager, err := llm.NewManager(&types.LLMConfig{}, llm.WithProvider("blocking", provider))
+	if err != nil {
+		t.Fatal(err)
+	}
+	engineer := NewEngineerAgent("engineer-1", &types.AgentConfig{Model: "blocking"}, llmManager)
+	if err := engineer.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	org := &Organization{llmManager: llmManager, engineers: []types.Agent{engineer}}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := engineer.ProcessTask(types.WithRunID(context.Background(), "run-1"), &types.Task{ID: "t1", Title: "Checkout service"})
+		done <- err
+	}()
+	<-provider.started
+
+	generations := org.Generations()
+	if len(generations) != 1 || generations[0].AgentID != "engineer-1" || generations[0].RunID != "run-1" || generations[0].Model != "blocking" {
+		t.Fatalf("Unexpected generations: %+v", generations)
+	}
+
+	if err := org.CancelGeneration(generations[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrGenerationCanceled) {
+			t.Errorf("Expected the task to fail with the canceled generation, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Task did not return after its generation was canceled")
+	}
+
+	if len(org.Generations()) != 0 {
+		t.Error("Expected the canceled generation to be unregistered")
+	}
+	if !engineer.IsRunning() {
+		t.Error("Expected the agent to keep running")
+	}
+	if err := org.CancelGeneration(generations[0].ID); errors.CodeOf(err) != errors.CodeNotFound {
+		t.Errorf("Expected NotFound for a finished generation, got %v", err)
+	}
+}
+
+func TestUsageBreakdown(t *testing.T) {
+	provider := &scriptedProvider{outputs: []string{"design", "func main() {}"}}
+	llmManager, err := llm.NewManager(&types.LLMConfig{Prices: map[string]types.ModelPrice{"scripted": {Input: 1, Output: 2}}}, llm.WithProvider("scripted", provider))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &types.AgentConfig{Model: "scripted"}
+	manager := NewManagerAgent("manager-1", cfg, llmManager)
+	manager.AddEngineer(NewEngineerAgent("engineer-1", cfg, llmManager))
+	org := &Organization{config: &types.Config{}, president: manager}
+
+	resp, err := org.ProcessClientTask(context.Background(), "Build a CLI")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := resp.Usage
+	if root == nil || root.AgentID != organizationLog || len(root.Subtasks) != 1 {
+		t.Fatalf("Expected the project's usage with the president's subtree, got %+v", root)
+	}
+	managerUsage := root.Subtasks[0]
+	if managerUsage.AgentID != "manager-1" || len(managerUsage.Subtasks) != 1 || managerUsage.Subtasks[0].AgentID != "engineer-1" {
+		t.Fatalf("Expected manager and engineer levels, got %+v", managerUsage)
+	}
+	engineerUsage := managerUsage.Subtasks[0]
+	if engineerUsage.Generations != 1 || managerUsage.Generations != 2 || root.Generations != 2 {
+		t.Errorf("Expected generations accumulated up the tree, got engineer %d, manager %d, project %d", engineerUsage.Generations, managerUsage.Generations, root.Generations)
+	}
+	if engineerUsage.Cost <= 0 || managerUsage.Cost <= engineerUsage.Cost || managerUsage.Latency < engineerUsage.Latency {
+		t.Errorf("Expected the manager's subtree to cost and take at least its engineer's, got %+v", managerUsage)
+	}
+}
+
+// withoutProvenance returns files with their provenance removed, for comparing content.
+func withoutProvenance(files []types.FileArtifact) []types.FileArtifact {
+	stripped := slices.Clone(files)
+	for i := range stripped {
+		stripped[i].Provenance = nil
+	}
+	return stripped
+}
+
+func TestFileAssembly(t *testing.T) {
+	newEngineer := func(t *testing.T, outputs ...string) (*EngineerAgent, *scriptedProvider) {
+		t.Helper()
+		provider := &scriptedProvider{outputs: outputs}
+		llmManager, err := llm.NewManager(&types.LLMConfig{}, llm.WithProvider("scripted", provider))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return NewEngineerAgent("engineer-1", &types.AgentConfig{Model: "scripted"}, llmManager), provider
+	}
+	task := &types.Task{ID: "t1", Title: "Implement the store"}
+
+	t.Run("ContinuesAcrossGenerations", func(t *testing.T) {
+		engineer, provider := newEngineer(t,
+			"Plan: a store with tests.\nFILES: store.go, store_test.go\n=== FILE: store.go ===\npackage store\n=== END FILE ===\n=== FILE: store_test.go ===\npackage st",
+			"=== FILE: store_test.go ===\npackage store\n\nimport \"testing\"\n=== END FILE ===",
+		)
+		resp, err := engineer.ProcessTask(context.Background(), task)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []types.FileArtifact{
+			{Path: "store.go", Content: "package store\n", Bytes: 14},
+			{Path: "store_test.go", Content: "package store\n\nimport \"testing\"\n", Bytes: 32},
+		}
+		if got := withoutProvenance(resp.Files); !slices.Equal(got, want) {
+			t.Errorf("Expected manifest %+v, got %+v", want, got)
+		}
+		for _, f := range resp.Files {
+			if p := f.Provenance; p == nil || p.AgentID != "engineer-1" || p.Model != "scripted" || p.PromptHash != provenance.PromptHash(provider.prompts[0]) {
+				t.Errorf("Expected %s to record the engineer, model and prompt that generated it, got %+v", f.Path, p)
+			}
+		}
+		if !strings.Contains(provider.prompts[0], "=== FILE:") || !strings.Contains(provider.prompts[1], "Files already written: store.go") {
+			t.Errorf("Expected the file protocol and a request for the missing file, got %q", provider.prompts)
+		}
+		if !strings.Contains(resp.Result, "Plan: a store with tests.") || !strings.Contains(resp.Result, "import \"testing\"") {
+			t.Errorf("Expected the assembled files in the result, got %q", resp.Result)
+		}
+	})
+
+	t.Run("MissingFiles", func(t *testing.T) {
+		engineer, _ := newEngineer(t, "FILES: store.go, store_test.go\n=== FILE: store.go ===\npackage store\n=== END FILE ===", "", "", "")
+		if _, err := engineer.ProcessTask(context.Background(), task); !errors.Is(err, errMissingFiles) || !strings.Contains(err.Error(), "store_test.go") {
+			t.Errorf("Expected the task to fail naming the missing file, got %v", err)
+		}
+	})
+
+	t.Run("CodeFences", func(t *testing.T) {
+		engineer, _ := newEngineer(t, "The store:\n\n```go title=store.go\npackage store\n```\n\nRun it with `go test`.")
+		resp, err := engineer.ProcessTask(context.Background(), task)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := withoutProvenance(resp.Files); !slices.Equal(got, []types.FileArtifact{{Path: "store.go", Content: "package store\n", Bytes: 14}}) {
+			t.Errorf("Expected the code block delivered as a file, got %+v", got)
+		}
+		if !strings.Contains(resp.Result, "[artifact: store.go, 1 lines]") || strings.Contains(resp.Result, "package store") {
+			t.Errorf("Expected the code block replaced with a reference, got %q", resp.Result)
+		}
+	})
+
+	t.Run("PlainText", func(t *testing.T) {
+		engineer, _ := newEngineer(t, "func main() {}")
+		resp, err := engineer.ProcessTask(context.Background(), task)
+		if err != nil || resp.Files != nil || !strings.Contains(resp.Result, "func main() {}") {
+			t.Errorf("Expected a response without file blocks to be kept as is, got %+v, %v", resp, err)
+		}
+	})
+}
+
+func TestExtractFences(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		prose    string
+		files    []string
+	}{
+		{"InfoString", "Setup:\n```yaml:config/app.yaml\nport: 80\n```\n", "Setup:\n[artifact: config/app.yaml, 1 lines]", []string{"config/app.yaml"}},
+		{"Heading", "### `main.go`\n\n```go\npackage main\n```\nDone.", "[artifact: main.go, 1 lines]\nDone.", []string{"main.go"}},
+		{"Comment", "~~~python\n# file: app.py\nprint(1)\n~~~", "[artifact: app.py, 2 lines]", []string{"app.py"}},
+		{"SyntaxError", "```json data.json\n{\"a\": }\n```", "[artifact: data.json, 1 lines, syntax error: invalid character '}' looking for beginning of value]", []string{"data.json"}},
+		{"NoFileName", "Example:\n```sh\ngo test ./...\n```", "Example:\n```sh\ngo test ./...\n```", nil},
+		{"Unclosed", "**main.go**\n```go\npackage ma", "**main.go**\n```go\npackage ma", nil},
+		{"OutsideDeliverable", "```go ../../etc/main.go\npackage main\n```", "```go ../../etc/main.go\npackage main\n```", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prose, files := extractFences(tt.response)
+			if prose != tt.prose {
+				t.Errorf("prose = %q, want %q", prose, tt.prose)
+			}
+			if got := filePaths(files); !slices.Equal(got, tt.files) {
+				t.Errorf("files = %v, want %v", got, tt.files)
+			}
+		})
+	}
+
+	if err := checkSyntax("main.go", "package main\n\nfunc main() {"); err == nil {
+		t.Error("Expected a syntax error for incomplete Go")
+	}
+	if err := checkSyntax("ci.yml", "on: push\n---\njobs: {}\n"); err != nil {
+		t.Errorf("Expected multi-document YAML to parse, got %v", err)
+	}
+}
+
+func TestWorkspace(t *testing.T) {
+	dir := t.TempDir()
+	president := &recordingAgent{
+		BaseAgent: NewBaseAgent("president-1", types.RolePresident, &types.AgentConfig{}),
+		files: [][]types.FileArtifact{{
+			fileArtifact("cmd/main.go", "package main\n"),
+			fileArtifact("../escape.txt", "outside"),
+			{Path: "logo.png", Content: base64.StdEncoding.EncodeToString([]byte("\x89PNG")), MediaType: "image/png"},
+		}},
+	}
+	org := &Organization{config: &types.Config{Workspace: &types.WorkspaceConfig{Dir: dir, Enabled: true}}, president: president}
+
+	resp, err := org.ProcessClientTask(types.WithRunID(context.Background(), "run-1"), "Build a CLI")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Metadata[MetadataWorkspace]; got != filepath.Join(dir, "run-1") {
+		t.Errorf("workspace = %q", got)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "run-1", "cmd", "main.go")); err != nil || string(data) != "package main\n" {
+		t.Errorf("Expected main.go written, got %q, %v", data, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "run-1", "logo.png")); err != nil || string(data) != "\x89PNG" {
+		t.Errorf("Expected the image decoded, got %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escape.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected no file written outside the workspace, got %v", err)
+	}
+}
+
+// echoProvider answers every prompt with its name; it is safe for concurrent use.
+type echoProvider struct {
+	name string
+}
+
+func (p *echoProvider) Generate(ctx context.Context, prompt string, opts *llm.GenerateOptions) (string, error) {
+	return p.name + ": done", nil
+}
+
+func (p *echoProvider) Name() string {
+	return p.name
+}
+
+// TestConcurrentAgents processes tasks on several engineers at once while
+// their status is read and their model switched, for the race detector.
+func TestConcurrentAgents(t *testing.T) {
+	llmManager, err := llm.NewManager(&types.LLMConfig{},
+		llm.WithProvider("gemini", &echoProvider{name: "gemini"}),
+		llm.WithProvider("claude", &echoProvider{name: "claude"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const (
+		engineerCount = 4
+		tasksEach     = 25
+	)
+	var engineers []types.Agent
+	for i := range engineerCount {
+		engineers = append(engineers, NewEngineerAgent(fmt.Sprintf("engineer-%d", i), &types.AgentConfig{Model: "gemini"}, llmManager))
+	}
+	org := &Organization{config: &types.Config{}, llmManager: llmManager, engineers: engineers}
+	if err := org.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for _, engineer := range engineers {
+		for i := range tasksEach {
+			wg.Go(func() {
+				task := &types.Task{ID: fmt.Sprintf("%s-%d", engineer.GetID(), i), Title: "Task"}
+				if _, err := engineer.ProcessTask(context.Background(), task); err != nil {
+					t.Error(err)
+				}
+			})
+		}
+	}
+
+	stop := make(chan struct{})
+	var readers sync.WaitGroup
+	readers.Go(func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			_ = org.Agents()
+			_ = org.Snapshot()
+			for _, engineer := range engineers {
+				_ = engineer.(*EngineerAgent).IsRunning()
+			}
+		}
+	})
+	readers.Go(func() {
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			model := "gemini"
+			if i%2 == 1 {
+				model = "claude"
+			}
+			if _, err := org.SetModel("engineer", model); err != nil {
+				t.Error(err)
+			}
+		}
+	})
+
+	wg.Wait()
+	close(stop)
+	readers.Wait()
+
+	for _, info := range org.Agents() {
+		if info.ActiveTasks != 0 || info.CompletedTasks != tasksEach {
+			t.Errorf("Expected %s to have completed %d tasks and none active, got %d completed and %d active",
+				info.ID, tasksEach, info.CompletedTasks, info.ActiveTasks)
+		}
+	}
+}
+
+func TestActivityLog(t *testing.T) {
+	activity, err := logging.NewManager(types.LoggingConfig{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer activity.Close()
+
+	provider := &scriptedProvider{outputs: []string{"func main() {}"}}
+	llmManager, err := llm.NewManager(&types.LLMConfig{}, llm.WithProvider("scripted", provider))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feed := &activityFeed{files: activity}
+	engineer := NewEngineerAgent("engineer-1", &types.AgentConfig{Model: "scripted"}, llmManager, WithActivityLog(feed))
+	org := &Organization{activity: feed, engineers: []types.Agent{engineer}}
+
+	var roles []string
+	unsubscribe := org.SubscribeActivity(func(e logging.Entry) { roles = append(roles, e.Role) })
+	defer unsubscribe()
+
+	if _, err := engineer.ProcessTask(context.Background(), &types.Task{ID: "t1", Title: "Write main", RunID: "run-1"}); err != nil {
+		t.Fatal(err)
+	}
+	// The scripted provider has no output left, so this generation fails
+	if _, err := engineer.ProcessTask(context.Background(), &types.Task{ID: "t2", Title: "Write tests", RunID: "run-2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := org.GetLogs("engineer-1", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var kinds []string
+	for _, e := range entries {
+		kinds = append(kinds, e.Kind)
+	}
+	want := []string{logging.KindPrompt, logging.KindResponse, logging.KindPrompt, logging.KindError}
+	if strings.Join(kinds, ",") != strings.Join(want, ",") {
+		t.Fatalf("Expected entries %v, got %v", want, kinds)
+	}
+	if entries[1].Content != "func main() {}" || entries[1].RunID != "run-1" || entries[1].Model != "scripted" {
+		t.Errorf("Unexpected response entry: %+v", entries[1])
+	}
+
+	if _, err := org.GetLogs("engineer-9", 10); err == nil {
+		t.Error("Expected error for an unknown agent")
+	}
+	if len(roles) != 4 || roles[0] != string(types.RoleEngineer) {
+		t.Errorf("Expected subscribers to receive every entry with its role, got %v", roles)
+	}
+}
+
+func TestProjectNotifications(t *testing.T) {
+	notifier := &recordingNotifier{}
+	president := &recordingAgent{BaseAgent: NewBaseAgent("president-1", types.RolePresident, &types.AgentConfig{})}
+	org := &Organization{config: &types.Config{}, president: president, notifier: notifier}
+
+	if _, err := org.ProcessClientTask(context.Background(), "Build a todo app"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(notifier.messages) != 1 || notifier.messages[0] != "project_completed: Completed: Build a todo app" {
+		t.Errorf("Expected a completion notification, got %v", notifier.messages)
+	}
+}
+
+func TestContextStats(t *testing.T) {
+	provider := &scriptedProvider{outputs: []string{"func main() {}"}}
+	llmManager, err := llm.NewManager(&types.LLMConfig{ContextWindows: map[string]int{"scripted": 10000}}, llm.WithProvider("scripted", provider))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	engineer := NewEngineerAgent("engineer-1", &types.AgentConfig{Model: "scripted"}, llmManager)
+	org := &Organization{engineers: []types.Agent{engineer}}
+
+	t.Run("TrimsMemoryContext", func(t *testing.T) {
+		memoryContext := strings.Repeat("m", 40000)
+		fitted := engineer.fitMemoryContext(context.Background(), llmManager, memoryContext, "prompt", &llm.GenerateOptions{MaxTokens: 1000})
+
+		// 10000 tokens less 2 for the prompt and 1000 reserved for output
+		if len(fitted) != 8998*4 {
+			t.Errorf("Expected memory context trimmed to %d bytes, got %d", 8998*4, len(fitted))
+		}
+		stats := engineer.ContextStats()
+		if stats.Truncations != 1 || stats.DroppedTokens != 1002 || stats.MemoryTokens != 8998 {
+			t.Errorf("Unexpected stats after truncation: %+v", stats)
+		}
+	})
+
+	t.Run("RecordsPromptUsage", func(t *testing.T) {
+		if _, err := engineer.ProcessTask(context.Background(), &types.Task{ID: "t1", Title: "Write main"}); err != nil {
+			t.Fatal(err)
+		}
+
+		stats := org.Agents()[0].Context
+		if stats.Limit != 10000 || stats.ReservedTokens != llmManager.OutputTokens(llm.OutputCode) || stats.PromptTokens != llm.EstimateTokens(provider.prompts[0]) || stats.Generations != 1 {
+			t.Errorf("Unexpected stats after generation: %+v", stats)
+		}
+		if stats.Truncations != 1 {
+			t.Errorf("Expected a prompt that fits not to be counted as truncated, got %d truncations", stats.Truncations)
+		}
+	})
+}
+
+func TestRouteTask(t *testing.T) {
+	store, err := profile.Open(filepath.Join(t.TempDir(), "profiles.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RecordSuccess("engineer-2", "Payment gateway", "Go service"); err != nil {
+		t.Fatal(err)
+	}
+
+	engineers := []types.Agent{
+		NewEngineerAgent("engineer-1", &types.AgentConfig{Capabilities: []string{"frontend"}}, nil, WithProfiles(store)),
+		NewEngineerAgent("engineer-2", &types.AgentConfig{}, nil, WithProfiles(store)),
+	}
+
+	tests := []struct {
+		name   string
+		task   string
+		want   int
+		routed bool
+	}{
+		{"Experience", "Add refunds to the payment gateway", 1, true},
+		{"Capabilities", "Build the frontend", 0, true},
+		{"NoMatch", "Write a CLI", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx, reason, ok := routeTask(engineers, &types.Task{Title: tt.task}, nil)
+			if ok != tt.routed || (ok && idx != tt.want) {
+				t.Errorf("routeTask(%q) = %d, %q, %v", tt.task, idx, reason, ok)
+			}
+		})
+	}
+
+	if ctx := engineers[1].(*EngineerAgent).profileContext(); !strings.Contains(ctx, "Domains: gateway, payment") {
+		t.Errorf("Expected the profile in the prompt context, got %q", ctx)
+	}
+}
+
+// historyMemoryManager keeps the assignment history in memory.
+type historyMemoryManager struct {
+	sharedMemoryManager
+	assignments []*types.Assignment
+}
+
+func (m *historyMemoryManager) SemanticSearch(ctx context.Context, query, agentID string, limit int) ([]*types.MemoryEntry, error) {
+	return nil, fmt.Errorf("no vector store")
+}
+
+func (m *historyMemoryManager) RecordAssignment(ctx context.Context, assignment *types.Assignment) error {
+	m.assignments = append(m.assignments, assignment)
+	return nil
+}
+
+func (m *historyMemoryManager) Assignments(ctx context.Context, agentIDs []string, limit int) ([]*types.Assignment, error) {
+	var results []*types.Assignment
+	for _, a := range m.assignments {
+		if slices.Contains(agentIDs, a.AgentID) {
+			results = append(results, a)
+		}
+	}
+	return results, nil
+}
+
+func TestAssignmentHistory(t *testing.T) {
+	mem := &historyMemoryManager{}
+	manager := NewManagerAgent("manager-1", &types.AgentConfig{}, nil, WithRecovery(types.RecoveryConfig{MaxReplans: 1}))
+	manager.SetMemoryManager(mem)
+	engineers := []types.Agent{
+		&flakyAgent{BaseAgent: NewBaseAgent("engineer-1", types.RoleEngineer, &types.AgentConfig{}), failures: 1},
+		&flakyAgent{BaseAgent: NewBaseAgent("engineer-2", types.RoleEngineer, &types.AgentConfig{})},
+	}
+	for _, e := range engineers {
+		manager.AddEngineer(e)
+	}
+
+	task := &types.Task{ID: "design-1", Title: "Checkout service", RunID: "run-1"}
+	if _, err := manager.ProcessTask(context.Background(), task); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(mem.assignments) != 2 {
+		t.Fatalf("Expected the failed attempt and the reassignment to be recorded, got %+v", mem.assignments)
+	}
+	failed, reassigned := mem.assignments[0], mem.assignments[1]
+	if failed.AgentID != "engineer-1" || failed.Succeeded || failed.Error == "" || failed.AssignedBy != "manager-1" || failed.RunID != "run-1" {
+		t.Errorf("Unexpected failed assignment: %+v", failed)
+	}
+	if reassigned.AgentID != "engineer-2" || !reassigned.Succeeded || reassigned.Title != failed.Title {
+		t.Errorf("Unexpected reassignment: %+v", reassigned)
+	}
+
+	idx, reason, ok := routeTask(engineers, &types.Task{Title: "Refunds service"}, mem.assignments)
+	if !ok || idx != 1 || !strings.Contains(reason, "success on 1 of 1 similar task(s)") {
+		t.Errorf("Expected similar work to go to the engineer who succeeded at it, got %d, %q, %v", idx, reason, ok)
+	}
+	if _, _, ok := routeTask(engineers, &types.Task{Title: "Landing page"}, mem.assignments); ok {
+		t.Error("Expected no preference for unrelated work")
+	}
+}
+
+// sharedMemoryManager is a minimal MemoryManager supporting the queries used by KnowledgeBase.
+type sharedMemoryManager struct {
+	types.MemoryManager
+	entries []*types.MemoryEntry
+}
+
+func (m *sharedMemoryManager) StoreMemory(ctx context.Context, entry *types.MemoryEntry) error {
+	if entry.ID == "" {
+		entry.ID = fmt.Sprintf("entry-%d", len(m.entries)+1)
+	}
+	entry.RunID = types.RunIDFromContext(ctx)
+	m.entries = append(m.entries, entry)
+	return nil
+}
+
+func (m *sharedMemoryManager) QueryMemories(ctx context.Context, query *types.MemoryQuery) ([]*types.MemoryEntry, error) {
+	var results []*types.MemoryEntry
+	for _, entry := range m.entries {
+		matches := entry.AgentID == query.AgentID && entry.Type == query.Type
+		for key, value := range query.Metadata {
+			matches = matches && entry.Metadata[key] == value
+		}
+		if matches {
+			results = append(results, entry)
+		}
+	}
+	return results, nil
+}
+
+func (m *sharedMemoryManager) DeleteMemory(ctx context.Context, id string) error {
+	m.entries = slices.DeleteFunc(m.entries, func(e *types.MemoryEntry) bool { return e.ID == id })
+	return nil
+}
+
+func TestKnowledgeBase(t *testing.T) {
+	ctx := types.WithRunID(context.Background(), "run-1")
+
+	t.Run("MostConfirmed", func(t *testing.T) {
+		kb := NewKnowledgeBase(&sharedMemoryManager{}, types.KnowledgeSharingConfig{})
+
+		steps := []struct {
+			lesson string
+			want   string
+		}{
+			{"Use bcrypt for password hashing", PromotionAdded},
+			{"use bcrypt for password hashing.", PromotionConfirmed},
+			{"Use argon2 for password hashing", PromotionRejected},
+			{"Paginate list endpoints with cursors", PromotionAdded},
+		}
+		for _, step := range steps {
+			promotion, err := kb.Promote(ctx, Lesson{Text: step.lesson, SourceAgent: "manager-1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if promotion.Outcome != step.want {
+				t.Errorf("Promote(%q) = %s, want %s", step.lesson, promotion.Outcome, step.want)
+			}
+		}
+
+		lessons, _ := kb.List(ctx)
+		if len(lessons) != 2 {
+			t.Fatalf("Expected 2 lessons, got %d", len(lessons))
+		}
+		for _, l := range lessons {
+			if l.RunID != "" {
+				t.Errorf("Expected shared lessons not to belong to a run, got %q", l.RunID)
+			}
+			if strings.Contains(l.Content, "bcrypt") && confirmations(l) != 2 {
+				t.Errorf("Expected the bcrypt lesson to be confirmed twice, got %d", confirmations(l))
+			}
+		}
+
+		relevant, _ := kb.Relevant(ctx, "Add a login form with password storage")
+		if len(relevant) != 1 || relevant[0].Metadata[MetadataLesson] != "Use bcrypt for password hashing" {
+			t.Errorf("Expected the password lesson to be relevant, got %+v", relevant)
+		}
+	})
+
+	t.Run("Documents", func(t *testing.T) {
+		kb := NewKnowledgeBase(&sharedMemoryManager{}, types.KnowledgeSharingConfig{})
+		chunks := []ingest.Chunk{
+			{Section: "Security", Text: "Hash passwords with argon2id."},
+			{Section: "API", Text: "List endpoints paginate with cursors.", Index: 1},
+		}
+		if _, err := kb.Ingest(ctx, "docs/conventions.md", chunks, "architecture"); err != nil {
+			t.Fatal(err)
+		}
+		// Ingesting a document again replaces its chunks
+		if n, err := kb.Ingest(ctx, "docs/conventions.md", chunks, "architecture"); err != nil || n != 2 {
+			t.Fatalf("Expected 2 chunks re-ingested, got %d, %v", n, err)
+		}
+		if _, err := kb.Promote(ctx, Lesson{Text: "Hash passwords with bcrypt"}); err != nil {
+			t.Fatal(err)
+		}
+
+		entries, _ := kb.List(ctx)
+		if len(entries) != 3 {
+			t.Fatalf("Expected 2 document chunks and the lesson, got %+v", entries)
+		}
+		relevant, _ := kb.Relevant(ctx, "Store user passwords")
+		if len(relevant) != 2 || relevant[0].Metadata[MetadataDocument] != "docs/conventions.md" || relevant[0].RunID != "" ||
+			!slices.Contains(relevant[0].Tags, "architecture") || relevant[0].Metadata[MetadataLesson] != "Security: Hash passwords with argon2id." {
+			t.Errorf("Expected the security chunk and the lesson to be relevant, got %+v", relevant)
+		}
+
+		agent := NewBaseAgent("engineer-1", types.RoleEngineer, &types.AgentConfig{})
+		agent.setKnowledge(kb)
+		prompt := agent.sharedKnowledgeContext(ctx, &types.Task{Title: "Store user passwords"})
+		if !strings.Contains(prompt, "- Security: Hash passwords with argon2id. (from docs/conventions.md)") {
+			t.Errorf("Expected the chunk's source in the prompt, got %q", prompt)
+		}
+	})
+
+	t.Run("Newest", func(t *testing.T) {
+		kb := NewKnowledgeBase(&sharedMemoryManager{}, types.KnowledgeSharingConfig{ConflictPolicy: ConflictNewest})
+
+		_, _ = kb.Promote(ctx, Lesson{Text: "Use bcrypt for password hashing"})
+		_, _ = kb.Promote(ctx, Lesson{Text: "Use bcrypt for password hashing"})
+		promotion, err := kb.Promote(ctx, Lesson{Text: "Use argon2 for password hashing"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		lessons, _ := kb.List(ctx)
+		if promotion.Outcome != PromotionSuperseded || len(lessons) != 1 || lessons[0].Metadata[MetadataSupersedes] != "Use bcrypt for password hashing" {
+			t.Errorf("Expected the newer lesson to supersede the old one, got %+v and %+v", promotion, lessons)
+		}
+	})
+}
+
+func TestDelegationTemplates(t *testing.T) {
+	task := &types.Task{
+		ID:          "t1",
+		Title:       "Build a shop",
+		Description: "An online shop",
+		Content:     "Catalog and checkout.\n\nLong background the managers do not need.",
+		Metadata:    map[string]string{"budget": "small", "internal": "notes"},
+	}
+
+	tests := []struct {
+		name     string
+		cfg      *types.DelegationConfig
+		content  string
+		metadata map[string]string
+	}{
+		{"Default", nil, task.Content, nil},
+		{"Summary", &types.DelegationConfig{Template: "{{.Title}}: {{.Summary}}", Metadata: []string{"budget"}}, "Build a shop: Catalog and checkout.", map[string]string{"budget": "small"}},
+		{"MaxChars", &types.DelegationConfig{MaxChars: 12, Metadata: []string{"*"}}, "Catalog a...", task.Metadata},
+		{"BrokenTemplate", &types.DelegationConfig{Template: "{{.Missing}}"}, task.Content, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := &recordingAgent{BaseAgent: NewBaseAgent("manager-1", types.RoleManager, &types.AgentConfig{})}
+			director := NewDirectorAgent("director-1", &types.AgentConfig{Delegation: tt.cfg})
+			director.AddManager(manager)
+
+			if _, err := director.ProcessTask(context.Background(), task); err != nil {
+				t.Fatal(err)
+			}
+
+			got := manager.tasks[0]
+			if got.Content != tt.content {
+				t.Errorf("Content = %q, want %q", got.Content, tt.content)
+			}
+			if !maps.Equal(got.Metadata, tt.metadata) {
+				t.Errorf("Metadata = %v, want %v", got.Metadata, tt.metadata)
+			}
+		})
+	}
+}
+
+func TestStatusReports(t *testing.T) {
+	t.Run("Hierarchy", func(t *testing.T) {
+		engineer := NewEngineerAgent("engineer-1", &types.AgentConfig{}, nil, WithStatusReports(true))
+		manager := NewManagerAgent("manager-1", &types.AgentConfig{}, nil, WithStatusReports(true))
+		manager.AddEngineer(engineer)
+		director := NewDirectorAgent("director-1", &types.AgentConfig{}, WithStatusReports(true))
+		director.AddManager(manager)
+
+		resp, err := director.ProcessTask(context.Background(), &types.Task{
+			ID:      "t1",
+			Title:   "Build a shop",
+			Content: "Write main.go and store.go",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		status, ok := StatusOf(resp)
+		if !ok {
+			t.Fatalf("response has no status summary: %v", resp.Metadata)
+		}
+		if !slices.Equal(status.Artifacts, []string{"main.go", "store.go"}) {
+			t.Errorf("Artifacts = %v", status.Artifacts)
+		}
+		if !strings.Contains(resp.Result, "Manager report: ") {
+			t.Errorf("result does not report the manager's summary:\n%s", resp.Result)
+		}
+		if strings.Contains(resp.Result, "Engineer engineer-1 implementing") {
+			t.Errorf("result includes the engineer's full output:\n%s", resp.Result)
+		}
+		if got := strings.Count(resp.Result, Deliverable(resp)); got != 1 {
+			t.Errorf("deliverable appears %d times in result, want 1", got)
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		engineer := NewEngineerAgent("engineer-1", &types.AgentConfig{}, nil)
+		manager := NewManagerAgent("manager-1", &types.AgentConfig{}, nil)
+		manager.AddEngineer(engineer)
+
+		resp, err := manager.ProcessTask(context.Background(), &types.Task{ID: "t1", Title: "Build a shop"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := StatusOf(resp); ok {
+			t.Error("status summary attached with status reports disabled")
+		}
+		if !strings.Contains(resp.Result, "Engineer response: Engineer engineer-1") {
+			t.Errorf("result does not include the engineer's output:\n%s", resp.Result)
+		}
+		if Deliverable(resp) != resp.Result {
+			t.Error("Deliverable differs from the result with status reports disabled")
+		}
+	})
+
+	t.Run("Heuristic", func(t *testing.T) {
+		status := heuristicStatus("Warning: LLM generation failed: timeout\nDone.\n", "Added api/server.go.\n\nDetails follow.")
+		want := StatusSummary{
+			Done:      "Added api/server.go.",
+			Blockers:  []string{"Warning: LLM generation failed: timeout"},
+			Artifacts: []string{"api/server.go"},
+		}
+		if !reflect.DeepEqual(status, want) {
+			t.Errorf("heuristicStatus = %+v, want %+v", status, want)
+		}
+	})
+}
+
+func TestQuestions(t *testing.T) {
+	task := &types.Task{ID: "t1", Title: "Store", Content: "Persist orders"}
+	newEngineer := func(t *testing.T, cfg types.QuestionsConfig, outputs ...string) (*EngineerAgent, *scriptedProvider) {
+		t.Helper()
+		provider := &scriptedProvider{outputs: outputs}
+		llmManager, err := llm.NewManager(&types.LLMConfig{}, llm.WithProvider("scripted", provider))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return NewEngineerAgent("engineer-1", &types.AgentConfig{Model: "scripted"}, llmManager, WithQuestions(cfg)), provider
+	}
+	newOrganization := func(cfg types.QuestionsConfig) *Organization {
+		return &Organization{
+			config:   &types.Config{Organization: types.OrganizationConfig{Questions: cfg}},
+			activity: &activityFeed{},
+		}
+	}
+
+	t.Run("ManagerAnswers", func(t *testing.T) {
+		provider := &scriptedProvider{outputs: []string{"design", "QUESTION: Which database?", "Use PostgreSQL.", "package store"}}
+		llmManager, err := llm.NewManager(&types.LLMConfig{}, llm.WithProvider("scripted", provider))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg := &types.AgentConfig{Model: "scripted"}
+		manager := NewManagerAgent("manager-1", cfg, llmManager)
+		manager.AddEngineer(NewEngineerAgent("engineer-1", cfg, llmManager, WithQuestions(types.QuestionsConfig{Enabled: true})))
+
+		resp, err := manager.ProcessTask(context.Background(), task)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(resp.Result, "package store") {
+			t.Errorf("result does not include the implementation:\n%s", resp.Result)
+		}
+		if last := provider.prompts[len(provider.prompts)-1]; !strings.Contains(last, "You asked: Which database?\nAnswer: Use PostgreSQL.") {
+			t.Errorf("answer not added to the prompt:\n%s", last)
+		}
+	})
+
+	t.Run("HumanAnswers", func(t *testing.T) {
+		cfg := types.QuestionsConfig{Enabled: true, Timeout: time.Minute}
+		engineer, provider := newEngineer(t, cfg, "QUESTION: Which database?", "package store")
+		org := newOrganization(cfg)
+
+		go func() {
+			for {
+				if questions := org.Questions(); len(questions) > 0 {
+					_ = org.AnswerQuestion(questions[0].ID, "SQLite")
+					return
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}()
+
+		if _, err := engineer.ProcessTask(withQuestionHandler(context.Background(), org), task); err != nil {
+			t.Fatal(err)
+		}
+		if last := provider.prompts[len(provider.prompts)-1]; !strings.Contains(last, "Answer: SQLite") {
+			t.Errorf("answer not added to the prompt:\n%s", last)
+		}
+		if len(org.Questions()) != 0 {
+			t.Error("answered question still pending")
+		}
+	})
+
+	t.Run("TimeoutProceeds", func(t *testing.T) {
+		cfg := types.QuestionsConfig{Enabled: true, Timeout: time.Millisecond}
+		engineer, provider := newEngineer(t, cfg, "QUESTION: Which database?", "package store")
+
+		if _, err := engineer.ProcessTask(withQuestionHandler(context.Background(), newOrganization(cfg)), task); err != nil {
+			t.Fatal(err)
+		}
+		if last := provider.prompts[len(provider.prompts)-1]; !strings.Contains(last, unansweredReply) {
+			t.Errorf("unanswered reply not added to the prompt:\n%s", last)
+		}
+	})
+
+	t.Run("TimeoutFails", func(t *testing.T) {
+		cfg := types.QuestionsConfig{Enabled: true, Timeout: time.Millisecond, OnTimeout: QuestionFail}
+		engineer, _ := newEngineer(t, cfg, "QUESTION: Which database?")
+
+		_, err := engineer.ProcessTask(withQuestionHandler(context.Background(), newOrganization(cfg)), task)
+		if !errors.Is(err, errUnanswered) {
+			t.Errorf("expected unanswered question error, got %v", err)
+		}
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		engineer, provider := newEngineer(t, types.QuestionsConfig{Enabled: true, MaxPerTask: 1}, "QUESTION: A?", "QUESTION: B?", "package store")
+
+		resp, err := engineer.ProcessTask(context.Background(), task)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(provider.prompts) != 3 || !strings.Contains(resp.Result, "package store") {
+			t.Errorf("expected the second question to be refused, got %d prompts:\n%s", len(provider.prompts), resp.Result)
+		}
+	})
+}
+
+func TestPairing(t *testing.T) {
+	t.Run("Complexity", func(t *testing.T) {
+		tests := []struct {
+			task *types.Task
+			want string
+		}{
+			{&types.Task{Content: "Add a flag"}, ComplexityLow},
+			{&types.Task{Content: strings.Repeat("word ", 500)}, ComplexityMedium},
+			{&types.Task{Content: strings.Repeat("word ", 2000)}, ComplexityHigh},
+			{&types.Task{Content: "Add a flag", Metadata: map[string]string{MetadataComplexity: ComplexityHigh}}, ComplexityHigh},
+		}
+		for _, tt := range tests {
+			if got := taskComplexity(tt.task); got != tt.want {
+				t.Errorf("taskComplexity(%d chars) = %s, want %s", len(tt.task.Content), got, tt.want)
+			}
+		}
+	})
+
+	t.Run("CritiqueAndRevise", func(t *testing.T) {
+		// Design, draft, critique, revision, approval
+		provider := &scriptedProvider{outputs: []string{"design", "draft v1", "Handle empty input.", "draft v2", "APPROVED"}}
+		llmManager, err := llm.NewManager(&types.LLMConfig{}, llm.WithProvider("scripted", provider))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg := &types.AgentConfig{Model: "scripted"}
+		manager := NewManagerAgent("manager-1", cfg, llmManager, WithPairing(types.PairingConfig{
+			Enabled: true,
+			Rounds:  map[string]int{ComplexityLow: 3},
+		}))
+		manager.AddEngineer(NewEngineerAgent("engineer-1", cfg, llmManager))
+		manager.AddEngineer(NewEngineerAgent("engineer-2", cfg, llmManager))
+
+		resp, err := manager.ProcessTask(context.Background(), &types.Task{ID: "t1", Title: "Parser", Content: "Parse input"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(provider.prompts) != 5 {
+			t.Fatalf("expected 5 generations, got %d", len(provider.prompts))
+		}
+		if !strings.Contains(provider.prompts[3], "engineer-2 reviewed your draft:\nHandle empty input.") {
+			t.Errorf("revision prompt does not include the critique:\n%s", provider.prompts[3])
+		}
+		if !strings.Contains(resp.Result, "draft v2") || !strings.Contains(resp.Result, "Pair review: 1 revision round(s).") {
+			t.Errorf("result does not include the revised draft:\n%s", resp.Result)
+		}
+	})
+
+	t.Run("PatchReviewedFiles", func(t *testing.T) {
+		provider := &scriptedProvider{outputs: []string{
+			"design",
+			"FILES: parse.go, lex.go\n=== FILE: parse.go ===\nfunc Parse(s string) {\n\treturn\n}\n=== END FILE ===\n=== FILE: lex.go ===\nfunc Lex() {}\n=== END FILE ===",
+			"parse.go, func Parse: handle empty input.",
+			"Handled empty input.\n=== PATCH: parse.go ===\n<<<<<<< SEARCH\n\treturn\n=======\n\tif s == \"\" {\n\t\treturn\n\t}\n>>>>>>> REPLACE\n=== END PATCH ===",
+			"APPROVED",
+		}}
+		llmManager, err := llm.NewManager(&types.LLMConfig{}, llm.WithProvider("scripted", provider))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg := &types.AgentConfig{Model: "scripted"}
+		manager := NewManagerAgent("manager-1", cfg, llmManager, WithPairing(types.PairingConfig{
+			Enabled: true,
+			Rounds:  map[string]int{ComplexityLow: 1},
+		}))
+		manager.AddEngineer(NewEngineerAgent("engineer-1", cfg, llmManager))
+		manager.AddEngineer(NewEngineerAgent("engineer-2", cfg, llmManager))
+
+		resp, err := manager.ProcessTask(context.Background(), &types.Task{ID: "t1", Title: "Parser", Content: "Parse input"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.Contains(provider.prompts[2], "path of the file") || !strings.Contains(provider.prompts[3], "Revise only the files the review mentions: parse.go.") {
+			t.Errorf("Expected file-referencing critique and a patch request for parse.go, got %q", provider.prompts[2:4])
+		}
+		want := []types.FileArtifact{
+			fileArtifact("parse.go", "func Parse(s string) {\n\tif s == \"\" {\n\t\treturn\n\t}\n}\n"),
+			fileArtifact("lex.go", "func Lex() {}\n"),
+		}
+		if got := withoutProvenance(resp.Files); !slices.Equal(got, want) {
+			t.Errorf("Expected only parse.go to be patched, got %+v", got)
+		}
+		if parse, lex := resp.Files[0].Provenance, resp.Files[1].Provenance; parse.PromptHash != provenance.PromptHash(provider.prompts[3]) ||
+			lex.PromptHash != provenance.PromptHash(provider.prompts[1]) {
+			t.Errorf("Expected the patched file to come from the revision prompt and the other from the draft prompt, got %+v and %+v", parse, lex)
+		}
+	})
+
+	t.Run("PatchDoesNotApply", func(t *testing.T) {
+		files := []types.FileArtifact{fileArtifact("parse.go", "func Parse() {}\n")}
+		_, _, err := applyPatches(files, "=== PATCH: parse.go ===\n<<<<<<< SEARCH\nfunc Lex() {}\n=======\n>>>>>>> REPLACE\n=== END PATCH ===")
+		if !errors.Is(err, errPatchFailed) {
+			t.Errorf("Expected a patch of a missing section to fail, got %v", err)
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		manager := NewManagerAgent("manager-1", &types.AgentConfig{}, nil)
+		if rounds := manager.pairRounds(&types.Task{Content: strings.Repeat("word ", 2000)}); rounds != 0 {
+			t.Errorf("pairRounds = %d with pairing disabled", rounds)
+		}
+	})
+}
+
+func TestBlackboard(t *testing.T) {
+	t.Run("SharedWithinSubtree", func(t *testing.T) {
+		provider := &scriptedProvider{outputs: []string{
+			"Design\nBLACKBOARD SET api_schema: GET /orders\n",
+			"package orders\nBLACKBOARD NOTE: orders are paginated\n",
+		}}
+		llmManager, err := llm.NewManager(&types.LLMConfig{}, llm.WithProvider("scripted", provider))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg := &types.AgentConfig{Model: "scripted"}
+		opt := WithBlackboard(types.BlackboardConfig{Enabled: true})
+		manager := NewManagerAgent("manager-1", cfg, llmManager, opt)
+		manager.AddEngineer(NewEngineerAgent("engineer-1", cfg, llmManager, opt))
+
+		resp, err := manager.ProcessTask(context.Background(), &types.Task{ID: "t1", Title: "Orders", Content: "List orders"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.Contains(provider.prompts[1], "- api_schema (manager-1): GET /orders") {
+			t.Errorf("engineer prompt does not include the blackboard:\n%s", provider.prompts[1])
+		}
+		if strings.Contains(resp.Result, "BLACKBOARD") {
+			t.Errorf("blackboard writes left in the result:\n%s", resp.Result)
+		}
+	})
+
+	t.Run("Blackboard", func(t *testing.T) {
+		b := NewBlackboard()
+		b.Set("engineer-1", "schema", "v1")
+		b.Set("engineer-2", "schema", "v2")
+		b.Append("engineer-1", "uses UTC")
+
+		if entry, ok := b.Get("schema"); !ok || entry.Value != "v2" || entry.Author != "engineer-2" {
+			t.Errorf("Get(schema) = %+v, %v", entry, ok)
+		}
+		if want := "- schema (engineer-2): v2\n- note from engineer-1: uses UTC\n"; b.String() != want {
+			t.Errorf("String() = %q, want %q", b.String(), want)
+		}
+	})
+
+	t.Run("Scope", func(t *testing.T) {
+		director := NewDirectorAgent("director-1", &types.AgentConfig{}, WithBlackboard(types.BlackboardConfig{Enabled: true}))
+		if _, ok := BlackboardFromContext(director.openBlackboard(context.Background())); ok {
+			t.Error("director opened a blackboard outside the configured scope")
+		}
+	})
+}
+
+func TestInbox(t *testing.T) {
+	t.Run("Priority", func(t *testing.T) {
+		inbox, err := OpenInbox("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range []*Message{
+			{ID: "low-1", Kind: MessageNotification, Priority: 1},
+			{ID: "high", Kind: MessageNotification, Priority: 5},
+			{ID: "low-2", Kind: MessageNotification, Priority: 1},
+		} {
+			if err := inbox.Send(m); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		var got []string
+		for range 3 {
+			m, err := inbox.Receive(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, m.ID)
+		}
+		if want := []string{"high", "low-1", "low-2"}; !slices.Equal(got, want) {
+			t.Errorf("received %v, want %v", got, want)
+		}
+	})
+
+	t.Run("VisibilityTimeout", func(t *testing.T) {
+		inbox, err := OpenInbox("", WithVisibilityTimeout(20*time.Millisecond))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := inbox.Send(&Message{ID: "m1", Kind: MessageNotification}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := inbox.Receive(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		// Not acknowledged, so it is redelivered once the timeout passes
+		m, err := inbox.Receive(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.ID != "m1" || m.Deliveries != 2 {
+			t.Errorf("redelivered %s after %d deliveries, want m1 after 2", m.ID, m.Deliveries)
+		}
+
+		if err := inbox.Ack(m.ID); err != nil {
+			t.Fatal(err)
+		}
+		if inbox.Len() != 0 {
+			t.Errorf("Len() = %d after Ack, want 0", inbox.Len())
+		}
+		if err := inbox.Ack(m.ID); errors.CodeOf(err) != errors.CodeNotFound {
+			t.Errorf("Ack of an acknowledged message returned %v", err)
+		}
+	})
+
+	t.Run("FairScheduling", func(t *testing.T) {
+		weights := map[string]float64{"big": 1, "urgent": 2}
+		inbox, err := OpenInbox("", WithFairScheduling(func(runID string) float64 { return weights[runID] }))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The big project queues all of its work before the urgent one arrives
+		for range 6 {
+			if err := inbox.Send(&Message{Kind: MessageTask, Task: &types.Task{RunID: "big"}}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for range 6 {
+			if err := inbox.Send(&Message{Kind: MessageTask, Task: &types.Task{RunID: "urgent"}}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		served := map[string]int{}
+		for range 6 {
+			m, err := inbox.Receive(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			served[m.Task.RunID]++
+			if err := inbox.Ack(m.ID); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if served["urgent"] != 4 || served["big"] != 2 {
+			t.Errorf("served %v, want urgent:4 big:2", served)
+		}
+	})
+
+	t.Run("Persistent", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "engineer-1.json")
+		inbox, err := OpenInbox(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := inbox.Send(&Message{Kind: MessageTask, Task: &types.Task{ID: "t1"}}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := inbox.Receive(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		inbox.Close()
+
+		// Messages that were never acknowledged survive a restart
+		reopened, err := OpenInbox(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pending := reopened.Pending()
+		if len(pending) != 1 || pending[0].Task == nil || pending[0].Task.ID != "t1" {
+			t.Fatalf("Pending() = %+v, want task t1", pending)
+		}
+	})
+
+	t.Run("Organization", func(t *testing.T) {
+		inboxes := NewInboxes()
+		president := &recordingAgent{BaseAgent: NewBaseAgent("president-1", types.RolePresident, &types.AgentConfig{}, WithInboxes(inboxes))}
+		org := &Organization{
+			config:    &types.Config{Organization: types.OrganizationConfig{Inbox: types.InboxConfig{Enabled: true, Fair: true}}},
+			president: president,
+			activity:  &activityFeed{},
+			inboxes:   inboxes,
+		}
+		if err := org.openInboxes(); err != nil {
+			t.Fatal(err)
+		}
+		defer org.closeInboxes(context.Background())
+
+		ctx := types.WithUser(WithProjectWeight(context.Background(), 3), "alice")
+		resp, err := org.ProcessClientTask(ctx, "Build a todo API")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Result != "deliverable 1" || len(president.tasks) != 1 {
+			t.Errorf("president handled %d task(s), result %q", len(president.tasks), resp.Result)
+		}
+		if president.tasks[0].Metadata[types.MetadataUser] != "alice" || resp.Metadata[types.MetadataUser] != "alice" {
+			t.Errorf("Expected the submitter on the task and response, got %v and %v", president.tasks[0].Metadata, resp.Metadata)
+		}
+		projects := org.Projects()
+		if len(projects) != 1 || projects[0].Weight != 3 || projects[0].Tasks != 1 || projects[0].Finished.IsZero() || projects[0].User != "alice" {
+			t.Errorf("Projects() = %+v, want one finished project of alice with weight 3 and 1 task", projects)
+		}
+		if mine := org.ProjectsOf("bob"); len(mine) != 0 {
+			t.Errorf("ProjectsOf(bob) = %+v, want none", mine)
+		}
+
+		if err := org.Post("president-1", &Message{Kind: MessageNotification, From: "ops", Text: "deploy frozen"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := org.Post("engineer-9", &Message{Kind: MessageNotification}); errors.CodeOf(err) != errors.CodeNotFound {
+			t.Errorf("Post to an agent without an inbox returned %v", err)
+		}
+	})
+}
+
+func TestAdmission(t *testing.T) {
+	t.Run("QueuesThenRejects", func(t *testing.T) {
+		c := newAdmission(types.AdmissionConfig{MaxConcurrent: 1, QueueDepth: 1})
+		release, err := c.acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		positions := make(chan int, 1)
+		admitted := make(chan func())
+		go func() {
+			ctx := WithQueueFeedback(context.Background(), func(position int) { positions <- position })
+			r, err := c.acquire(ctx)
+			if err != nil {
+				t.Error(err)
+			}
+			admitted <- r
+		}()
+		if position := <-positions; position != 1 {
+			t.Errorf("queued at position %d, want 1", position)
+		}
+
+		if _, err := c.acquire(context.Background()); errors.CodeOf(err) != errors.CodeOverloaded {
+			t.Errorf("acquire with a full queue returned %v, want %s", err, errors.CodeOverloaded)
+		}
+
+		release()
+		release() // Releasing twice frees one slot
+		(<-admitted)()
+
+		stats := c.snapshot()
+		if stats.Running != 0 || stats.Queued != 0 || stats.Admitted != 2 || stats.Rejected != 1 || stats.Waited != 1 {
+			t.Errorf("unexpected stats: %+v", stats)
+		}
+	})
+
+	t.Run("CanceledWhileQueued", func(t *testing.T) {
+		c := newAdmission(types.AdmissionConfig{MaxConcurrent: 1, QueueDepth: 2})
+		release, err := c.acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer release()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		queued := make(chan struct{})
+		go func() {
+			<-queued
+			cancel()
+		}()
+		_, err = c.acquire(WithQueueFeedback(ctx, func(int) { close(queued) }))
+		if errors.CodeOf(err) != errors.CodeCanceled {
+			t.Errorf("canceled acquire returned %v", err)
+		}
+		if stats := c.snapshot(); stats.Queued != 0 || stats.Running != 1 {
+			t.Errorf("unexpected stats after cancel: %+v", stats)
+		}
+	})
+
+	t.Run("PerUser", func(t *testing.T) {
+		c := newAdmission(types.AdmissionConfig{MaxPerUser: 1})
+		alice := types.WithUser(context.Background(), "alice")
+		release, err := c.acquire(alice)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := c.acquire(alice); errors.CodeOf(err) != errors.CodeOverloaded || !strings.Contains(err.Error(), "alice") {
+			t.Errorf("second project of the same user returned %v, want %s", err, errors.CodeOverloaded)
+		}
+		bob, err := c.acquire(types.WithUser(context.Background(), "bob"))
+		if err != nil {
+			t.Errorf("another user's project was not admitted: %v", err)
+		}
+		bob()
+
+		release()
+		again, err := c.acquire(alice)
+		if err != nil {
+			t.Errorf("project after the user's previous one finished was not admitted: %v", err)
+		}
+		again()
+	})
+
+	t.Run("Unlimited", func(t *testing.T) {
+		c := newAdmission(types.AdmissionConfig{})
+		for range 3 {
+			if _, err := c.acquire(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if stats := c.snapshot(); stats.Running != 3 {
+			t.Errorf("Running = %d, want 3", stats.Running)
+		}
+	})
+}
+
+// unreadyAgent starts but never becomes ready.
+type unreadyAgent struct {
+	*EngineerAgent
+}
+
+func (a *unreadyAgent) Ready(ctx context.Context) error {
+	return fmt.Errorf("no connection")
+}
+
+func TestStartup(t *testing.T) {
+	newOrganization := func(engineers ...types.Agent) (*Organization, *ManagerAgent) {
+		manager := NewManagerAgent("manager-1", &types.AgentConfig{}, nil)
+		for _, e := range engineers {
+			manager.AddEngineer(e)
+		}
+		return &Organization{
+			config:    &types.Config{},
+			admission: newAdmission(types.AdmissionConfig{}),
+			president: NewPresidentAgent("president-1", &types.AgentConfig{}),
+			managers:  []types.Agent{manager},
+			engineers: engineers,
+		}, manager
+	}
+
+	t.Run("Partial", func(t *testing.T) {
+		healthy := NewEngineerAgent("engineer-1", &types.AgentConfig{}, nil)
+		org, manager := newOrganization(healthy, &unreadyAgent{NewEngineerAgent("engineer-2", &types.AgentConfig{}, nil)})
+		if err := org.Start(context.Background()); err != nil {
+			t.Fatalf("Expected a broken engineer not to block the start, got %v", err)
+		}
+
+		report := org.StartupReport()
+		var stages []string
+		for _, stage := range report.Stages {
+			stages = append(stages, stage.Name)
+		}
+		if want := []string{"providers", "engineers", "managers", "president"}; !slices.Equal(stages, want) {
+			t.Errorf("Expected stages %v, got %v", want, stages)
+		}
+		if !slices.Equal(report.Failed, []string{"engineer-2"}) || !strings.Contains(report.Stages[1].Checks[1].Detail, "no connection") {
+			t.Errorf("Expected engineer-2 reported as failed, got %+v", report)
+		}
+		if len(manager.engineers) != 1 || manager.engineers[0] != healthy {
+			t.Errorf("Expected the failed engineer detached from its manager, got %d engineer(s)", len(manager.engineers))
+		}
+		if c := org.Readiness().Checks[0]; !c.Ready || !strings.Contains(c.Detail, "started without engineer-2") {
+			t.Errorf("Expected a partially started organization to be ready and say so, got %+v", c)
+		}
+		if err := org.Stop(context.Background()); err != nil {
+			t.Errorf("Expected the agents that started to stop, got %v", err)
+		}
+	})
+
+	t.Run("StageFailed", func(t *testing.T) {
+		org, manager := newOrganization(&unreadyAgent{NewEngineerAgent("engineer-1", &types.AgentConfig{}, nil)})
+		if err := org.Start(context.Background()); err == nil || !strings.Contains(err.Error(), "engineer-1") {
+			t.Fatalf("Expected the start to fail without any engineer, got %v", err)
+		}
+		if manager.IsRunning() || org.Readiness().Ready {
+			t.Error("Expected nothing left running after a failed start")
+		}
+	})
+}
+
+func TestCompaction(t *testing.T) {
+	ctx := context.Background()
+	memCfg := &types.MemoryConfig{
+		Enabled:    true,
+		SQLite:     types.SQLiteConfig{Enabled: true, Path: filepath.Join(t.TempDir(), "memory.db")},
+		Compaction: types.CompactionConfig{Enabled: true},
+	}
+	session := func(outputs ...string) (*Organization, *EngineerAgent, *scriptedProvider) {
+		mem, err := memory.NewManager(memCfg, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		provider := &scriptedProvider{outputs: outputs}
+		llmManager, err := llm.NewManager(&types.LLMConfig{}, llm.WithProvider("scripted", provider))
+		if err != nil {
+			t.Fatal(err)
+		}
+		engineer := NewEngineerAgent("engineer-1", &types.AgentConfig{Model: "scripted"}, llmManager)
+		engineer.SetMemoryManager(mem)
+		org := &Organization{
+			config:        &types.Config{Memory: memCfg},
+			admission:     newAdmission(types.AdmissionConfig{}),
+			llmManager:    llmManager,
+			memoryManager: mem,
+			engineers:     []types.Agent{engineer},
+		}
+		if err := org.Start(ctx); err != nil {
+			t.Fatal(err)
+		}
+		return org, engineer, provider
+	}
+
+	org, engineer, provider := session("Key lessons: cache the lookups.\nUnfinished threads: rate limiting.")
+	if got := engineer.profileContext(); got != "" {
+		t.Errorf("Expected no session profile before the first session, got %q", got)
+	}
+	_ = engineer.GetMemory().StoreConversation(ctx, "Received implementation task: URL shortener", nil)
+	_ = engineer.GetMemory().StoreTask(ctx, &types.Task{ID: "task-1", Title: "URL shortener"}, "Done", nil)
+	if err := org.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(provider.prompts) != 1 || !strings.Contains(provider.prompts[0], "Received implementation task: URL shortener") || !strings.Contains(provider.prompts[0], "Task: URL shortener") {
+		t.Fatalf("Expected the session's conversations and tasks summarized, got %q", provider.prompts)
+	}
+
+	org, engineer, provider = session("Key lessons: cache the lookups.")
+	if got := engineer.profileContext(); !strings.Contains(got, "Unfinished threads: rate limiting.") {
+		t.Errorf("Expected the next session to start from the profile, got %q", got)
+	}
+	if err := org.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(provider.prompts) != 0 {
+		t.Errorf("Expected a session without memories not to be compacted, got %q", provider.prompts)
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	llmManager, err := llm.NewManager(&types.LLMConfig{}, llm.WithProvider("scripted", &scriptedProvider{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	org := &Organization{
+		config:     &types.Config{LLMs: types.LLMConfig{DefaultModel: "scripted"}},
+		llmManager: llmManager,
+		admission:  newAdmission(types.AdmissionConfig{}),
+	}
+
+	if r := org.Readiness(); r.Ready {
+		t.Errorf("organization ready before it started: %+v", r)
+	}
+	if err := org.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if r := org.Readiness(); !r.Ready {
+		t.Errorf("started organization not ready: %+v", r)
+	}
+
+	t.Run("UnknownDefaultModel", func(t *testing.T) {
+		org.config.LLMs.DefaultModel = "missing"
+		defer func() { org.config.LLMs.DefaultModel = "scripted" }()
+		if r := org.Readiness(); r.Ready {
+			t.Errorf("organization ready without its default model: %+v", r)
+		}
+	})
+
+	t.Run("Drain", func(t *testing.T) {
+		release, err := org.admit(context.Background(), "run-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		drained := make(chan error, 1)
+		go func() { drained <- org.Drain(context.Background()) }()
+		deadline := time.Now().Add(5 * time.Second)
+		for org.Readiness().Ready {
+			if time.Now().After(deadline) {
+				t.Fatal("organization still ready while draining")
+			}
+			time.Sleep(time.Millisecond)
+		}
+
+		if _, err := org.admit(context.Background(), "run-2"); errors.CodeOf(err) != errors.CodeAgentUnavailable {
+			t.Errorf("admit while draining returned %v, want %s", err, errors.CodeAgentUnavailable)
+		}
+		select {
+		case err := <-drained:
+			t.Fatalf("Drain returned %v with a project running", err)
+		default:
+		}
+
+		release()
+		if err := <-drained; err != nil {
+			t.Errorf("Drain returned %v", err)
+		}
+	})
+
+	t.Run("DrainTimeout", func(t *testing.T) {
+		org := &Organization{admission: newAdmission(types.AdmissionConfig{})}
+		if _, err := org.admit(context.Background(), "run-1"); err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if err := org.Drain(ctx); errors.CodeOf(err) != errors.CodeAgentTimeout {
+			t.Errorf("Drain past its deadline returned %v, want %s", err, errors.CodeAgentTimeout)
+		}
+	})
+}
+
+func TestTeams(t *testing.T) {
+	dir := t.TempDir()
+	agentFile := func(name, role string, capabilities ...string) string {
+		path := filepath.Join(dir, name+".yaml")
+		content := "name: " + name + "\nrole: " + role + "\nsystem_prompt: test\n"
+		if len(capabilities) > 0 {
+			content += "capabilities: [" + strings.Join(capabilities, ", ") + "]\n"
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	layers := []types.LayerConfig{
+		{Name: "President", Agent: agentFile("president", "President"), Timeout: time.Hour},
+		{Name: "Secretary", Agent: agentFile("secretary", "Secretary"), AttachTo: []string{"President"}},
+		{Name: "Director", Agent: agentFile("director", "Director")},
+		{Name: "Manager", Agent: agentFile("manager", "Manager")},
+		{Name: "Engineer", Agent: agentFile("engineer", "Engineer", "testing"), Count: 5, Timeout: 10 * time.Minute},
+	}
+
+	cfg := &types.Config{Organization: types.OrganizationConfig{
+		Layers: layers,
+		Teams: []types.TeamConfig{
+			{
+				Name:        "backend",
+				Specialties: []string{"databases"},
+				Engineer:    types.TeamRoleConfig{Agent: agentFile("go-engineer", "Engineer", "go"), Count: 2},
+			},
+			{
+				Name:     "frontend",
+				Manager:  types.TeamRoleConfig{Count: 2},
+				Engineer: types.TeamRoleConfig{Specialties: []string{"react"}},
+			},
+		},
+	}}
+	org, err := NewOrganizationWithLLM(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := func(agents []types.Agent) []string {
+		var ids []string
+		for _, a := range agents {
+			ids = append(ids, a.GetID())
+		}
+		return ids
+	}
+	if got := ids(org.directors); !slices.Equal(got, []string{"director-backend-1", "director-frontend-1"}) {
+		t.Errorf("directors = %v", got)
+	}
+	if got := ids(org.managers); !slices.Equal(got, []string{"manager-backend-1", "manager-frontend-1", "manager-frontend-2"}) {
+		t.Errorf("managers = %v", got)
+	}
+
+	t.Run("Edges", func(t *testing.T) {
+		want := map[string][]string{
+			"manager-backend-1":  {"engineer-backend-1", "engineer-backend-2"},
+			"manager-frontend-1": {"engineer-frontend-1"},
+			"manager-frontend-2": {"engineer-frontend-2"},
+		}
+		for _, m := range org.managers {
+			if got := ids(m.(*ManagerAgent).engineers); !slices.Equal(got, want[m.GetID()]) {
+				t.Errorf("engineers of %s = %v, want %v", m.GetID(), got, want[m.GetID()])
+			}
+		}
+		if got := ids(org.directors[1].(*DirectorAgent).managers); !slices.Equal(got, []string{"manager-frontend-1", "manager-frontend-2"}) {
+			t.Errorf("managers of the frontend director = %v", got)
+		}
+	})
+
+	t.Run("Specialties", func(t *testing.T) {
+		capabilities := func(id string) []string {
+			for _, a := range org.allAgents() {
+				if a.GetID() == id {
+					return a.(interface{ Capabilities() []string }).Capabilities()
+				}
+			}
+			t.Fatalf("no agent %s", id)
+			return nil
+		}
+		if got := capabilities("engineer-backend-1"); !slices.Equal(got, []string{"go", "databases"}) {
+			t.Errorf("backend engineer capabilities = %v", got)
+		}
+		if got := capabilities("engineer-frontend-1"); !slices.Equal(got, []string{"testing", "react"}) {
+			t.Errorf("frontend engineer capabilities = %v", got)
+		}
+		if got := capabilities("director-backend-1"); !slices.Equal(got, []string{"databases"}) {
+			t.Errorf("backend director capabilities = %v", got)
+		}
+	})
+
+	t.Run("Timeouts", func(t *testing.T) {
+		if got := org.president.(*PresidentAgent).timeout; got != time.Hour {
+			t.Errorf("president timeout = %v, want the layer's 1h", got)
+		}
+		if got := org.engineers[0].(*EngineerAgent).timeout; got != 10*time.Minute {
+			t.Errorf("team engineer timeout = %v, want the Engineer layer's 10m", got)
+		}
+		if got := org.managers[0].(*ManagerAgent).timeout; got != 0 {
+			t.Errorf("manager timeout = %v, want none", got)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for name, teams := range map[string][]types.TeamConfig{
+			"Unnamed":   {{}},
+			"Duplicate": {{Name: "backend"}, {Name: "backend"}},
+		} {
+			cfg := &types.Config{Organization: types.OrganizationConfig{Layers: layers, Teams: teams}}
+			if _, err := NewOrganizationWithLLM(cfg, nil); err == nil {
+				t.Errorf("%s: expected an error", name)
+			}
+		}
+
+		cfg := &types.Config{Organization: types.OrganizationConfig{Layers: layers[:2], Teams: []types.TeamConfig{{Name: "backend"}}}}
+		if _, err := NewOrganizationWithLLM(cfg, nil); err == nil || !strings.Contains(err.Error(), "no Director agent") {
+			t.Errorf("expected a missing agent error, got %v", err)
+		}
+	})
+}
+
+func TestLabels(t *testing.T) {
+	t.Run("Submission", func(t *testing.T) {
+		president := &recordingAgent{BaseAgent: NewBaseAgent("president-1", types.RolePresident, &types.AgentConfig{})}
+		org := &Organization{config: &types.Config{}, president: president}
+
+		ctx := types.WithLabel(types.WithLabel(context.Background(), "team", "payments"), "ticket", "PAY-12")
+		resp, err := org.ProcessClientTask(ctx, "Add refunds")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]string{"team": "payments", "ticket": "PAY-12"}
+		if got := types.Labels(president.tasks[0].Metadata); !maps.Equal(got, want) {
+			t.Errorf("task labels = %v, want %v", got, want)
+		}
+		if got := types.Labels(resp.Metadata); !maps.Equal(got, want) {
+			t.Errorf("response labels = %v, want %v", got, want)
+		}
+		if got := org.ProjectsLabeled("team", "payments"); len(got) != 1 || !maps.Equal(got[0].Labels, want) {
+			t.Errorf("ProjectsLabeled() = %+v", got)
+		}
+		if got := org.ProjectsLabeled("team", "search"); len(got) != 0 {
+			t.Errorf("Expected no projects of another team, got %+v", got)
+		}
+	})
+
+	t.Run("Delegation", func(t *testing.T) {
+		task := &types.Task{ID: "t1", Title: "Refunds", Metadata: map[string]string{"budget": "small", "internal": "notes", "label.team": "payments"}}
+		for name, tt := range map[string]struct {
+			cfg  *types.DelegationConfig
+			want map[string]string
+		}{
+			"Default":   {nil, map[string]string{"label.team": "payments"}},
+			"Whitelist": {&types.DelegationConfig{Metadata: []string{"budget"}}, map[string]string{"budget": "small", "label.team": "payments"}},
+		} {
+			manager := &recordingAgent{BaseAgent: NewBaseAgent("manager-1", types.RoleManager, &types.AgentConfig{})}
+			director := NewDirectorAgent("director-1", &types.AgentConfig{Delegation: tt.cfg})
+			director.AddManager(manager)
+			if _, err := director.ProcessTask(context.Background(), task); err != nil {
+				t.Fatal(err)
+			}
+			if got := manager.tasks[0].Metadata; !maps.Equal(got, tt.want) {
+				t.Errorf("%s: Metadata = %v, want %v", name, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("Routing", func(t *testing.T) {
+		manager := func(id, team string) *recordingAgent {
+			return &recordingAgent{BaseAgent: NewBaseAgent(id, types.RoleManager, &types.AgentConfig{}, WithTeam(team))}
+		}
+		general, payments1, payments2 := manager("manager-1", ""), manager("manager-payments-1", "payments"), manager("manager-payments-2", "payments")
+		director := NewDirectorAgent("director-1", &types.AgentConfig{}, WithRouting([]types.RoutingRule{
+			{Label: "team=search", To: "search"}, // No such subordinate
+			{Label: "team=payments", To: "payments"},
+			{Label: "urgent=true", To: "manager-1"},
+		}))
+		for _, m := range []*recordingAgent{general, payments1, payments2} {
+			director.AddManager(m)
+		}
+
+		for i := range 6 {
+			task := &types.Task{ID: fmt.Sprintf("t%d", i), Title: "Refunds", Metadata: map[string]string{"label.team": "payments"}}
+			if _, err := director.ProcessTask(context.Background(), task); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if len(general.tasks) != 0 || len(payments1.tasks)+len(payments2.tasks) != 6 {
+			t.Errorf("Expected the payments managers to get every labeled task, got %d, %d and %d", len(general.tasks), len(payments1.tasks), len(payments2.tasks))
+		}
+
+		for _, metadata := range []map[string]string{{"label.urgent": "true", "label.team": "search"}, nil} {
+			if _, err := director.ProcessTask(context.Background(), &types.Task{ID: "t", Title: "Hotfix", Metadata: metadata}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if len(general.tasks) != 2 {
+			t.Errorf("Expected the rule naming manager-1 and round-robin to pick it, got %d task(s)", len(general.tasks))
+		}
+	})
+}
+
+func TestPartitioning(t *testing.T) {
+	newDirector := func(t *testing.T, enabled bool, outputs ...string) (*DirectorAgent, map[string]*recordingAgent) {
+		t.Helper()
+
+		llmManager, err := llm.NewManager(&types.LLMConfig{DefaultModel: "scripted"}, llm.WithProvider("scripted", &scriptedProvider{outputs: outputs}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		director := NewDirectorAgent("director-1", &types.AgentConfig{Model: "scripted"}, WithPartitioning(types.PartitioningConfig{Enabled: enabled}, llmManager))
+		managers := make(map[string]*recordingAgent)
+		for _, m := range []struct{ id, specialty string }{{"manager-web", "frontend"}, {"manager-api", "backend"}, {"manager-ops", "infrastructure"}} {
+			manager := &recordingAgent{BaseAgent: NewBaseAgent(m.id, types.RoleManager, &types.AgentConfig{Capabilities: []string{m.specialty}})}
+			managers[m.id] = manager
+			director.AddManager(manager)
+		}
+		return director, managers
+	}
+	task := &types.Task{ID: "t1", Title: "Build a frontend and backend for a shop", Content: "Catalog page and catalog API"}
+
+	t.Run("SplitsBySpecialty", func(t *testing.T) {
+		director, managers := newDirector(t, true, `Here is the split:
+[{"section": "manager-web", "title": "Catalog page", "content": "Render the catalog"},
+ {"section": "manager-api", "title": "Catalog API", "content": "Serve the catalog"},
+ {"section": "any", "title": "Docs", "content": "Write a README"}]`)
+
+		resp, err := director.ProcessTask(context.Background(), task)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for id, want := range map[string]string{"manager-web": "Render the catalog", "manager-api": "Serve the catalog", "manager-ops": "Write a README"} {
+			if tasks := managers[id].tasks; len(tasks) != 1 || tasks[0].Content != want {
+				t.Errorf("%s received %v, want one task %q", id, tasks, want)
+			}
+		}
+		if !strings.Contains(resp.Result, "Partitioned into 3 part(s)") || !strings.Contains(resp.Result, "--- Part 2: Catalog API ---") {
+			t.Errorf("result does not report the partition:\n%s", resp.Result)
+		}
+	})
+
+	t.Run("FallsBackToCapabilities", func(t *testing.T) {
+		director, managers := newDirector(t, true, "I cannot split this")
+
+		if _, err := director.ProcessTask(context.Background(), &types.Task{ID: "t2", Title: "Tune the infrastructure", Content: "Autoscaling"}); err != nil {
+			t.Fatal(err)
+		}
+		if tasks := managers["manager-ops"].tasks; len(tasks) != 1 || tasks[0].Content != "Autoscaling" {
+			t.Errorf("manager-ops received %v, want the whole task", tasks)
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		director, managers := newDirector(t, false)
+
+		if _, err := director.ProcessTask(context.Background(), task); err != nil {
+			t.Fatal(err)
+		}
+		if tasks := managers["manager-web"].tasks; len(tasks) != 1 || tasks[0].Content != task.Content {
+			t.Errorf("manager-web received %v, want the whole task by round-robin", tasks)
+		}
+	})
+
+	t.Run("BalancesUnassignedParts", func(t *testing.T) {
+		director, _ := newDirector(t, true)
+		parts := director.assignParts([]part{{Section: "manager-web"}, {Section: "any"}, {Section: "unknown"}, {Section: "any"}})
+		var got []int
+		for _, p := range parts {
+			got = append(got, p.manager)
+		}
+		if got[0] != 0 || got[1] == 0 || got[2] == 0 || got[1] == got[2] {
+			t.Errorf("assigned managers %v, want unassigned parts spread over the idle managers", got)
+		}
+	})
+}
+
+func TestPartialFailure(t *testing.T) {
+	split := `[{"section": "manager-web", "title": "Catalog page", "content": "Render the catalog"},
+ {"section": "manager-api", "title": "Catalog API", "content": "Serve the catalog"},
+ {"section": "manager-ops", "title": "Deployment", "content": "Deploy the shop"}]`
+	newDirector := func(t *testing.T, cfg types.PartialFailureConfig, failing ...string) *DirectorAgent {
+		t.Helper()
+
+		llmManager, err := llm.NewManager(&types.LLMConfig{DefaultModel: "scripted"}, llm.WithProvider("scripted", &scriptedProvider{outputs: []string{split}}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		director := NewDirectorAgent("director-1", &types.AgentConfig{Model: "scripted"},
+			WithPartitioning(types.PartitioningConfig{Enabled: true}, llmManager), WithPartialFailure(cfg))
+		for _, id := range []string{"manager-web", "manager-api", "manager-ops"} {
+			manager := &flakyAgent{BaseAgent: NewBaseAgent(id, types.RoleManager, &types.AgentConfig{})}
+			if slices.Contains(failing, id) {
+				manager.failures = 1
+			}
+			director.AddManager(manager)
+		}
+		return director
+	}
+	task := &types.Task{ID: "t1", Title: "Build a shop", Content: "Catalog page, catalog API and deployment"}
+
+	t.Run("FailFast", func(t *testing.T) {
+		director := newDirector(t, types.PartialFailureConfig{}, "manager-api")
+
+		_, err := director.ProcessTask(context.Background(), task)
+		if err == nil || !strings.Contains(err.Error(), `part "Catalog API" failed`) {
+			t.Errorf("Expected the failed part to fail the task, got %v", err)
+		}
+	})
+
+	t.Run("BestEffort", func(t *testing.T) {
+		cfg := types.PartialFailureConfig{Roles: map[string]types.PartialFailurePolicy{"director": {Mode: PartialBestEffort}}}
+		director := newDirector(t, cfg, "manager-api")
+
+		resp, err := director.ProcessTask(context.Background(), task)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Status != types.StatusPartiallyCompleted || len(resp.Subtasks) != 3 {
+			t.Fatalf("Expected a partially completed response with 3 subtasks, got %s %+v", resp.Status, resp.Subtasks)
+		}
+		for _, s := range resp.Subtasks {
+			if failed := s.Title == "Catalog API"; failed != (s.Status == types.StatusFailed) || failed != (s.Error != "") {
+				t.Errorf("Unexpected outcome of %s: %+v", s.Title, s)
+			}
+		}
+		if !strings.Contains(resp.Result, "--- Part 2: Catalog API (failed) ---") || !strings.Contains(resp.Result, "done by manager-ops") {
+			t.Errorf("Expected the result to report the failed part and deliver the rest:\n%s", resp.Result)
+		}
+
+		if _, err := newDirector(t, cfg, "manager-web", "manager-api", "manager-ops").ProcessTask(context.Background(), task); err == nil {
+			t.Error("Expected the task to fail when every part fails")
+		}
+	})
+
+	t.Run("Quorum", func(t *testing.T) {
+		cfg := types.PartialFailureConfig{Default: types.PartialFailurePolicy{Mode: PartialQuorum, Quorum: 0.6}}
+
+		resp, err := newDirector(t, cfg, "manager-ops").ProcessTask(context.Background(), task)
+		if err != nil || resp.Status != types.StatusPartiallyCompleted {
+			t.Errorf("Expected 2 of 3 parts to meet the quorum, got %v", err)
+		}
+		_, err = newDirector(t, cfg, "manager-web", "manager-ops").ProcessTask(context.Background(), task)
+		if err == nil || !strings.Contains(err.Error(), "2 of 3 part(s) failed") {
+			t.Errorf("Expected 1 of 3 parts to miss the quorum, got %v", err)
+		}
+	})
+
+	t.Run("Completed", func(t *testing.T) {
+		resp, err := newDirector(t, types.PartialFailureConfig{}).ProcessTask(context.Background(), task)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Status != types.StatusCompleted || len(resp.Subtasks) != 3 {
+			t.Errorf("Expected a completed response listing its subtasks, got %s %+v", resp.Status, resp.Subtasks)
+		}
+	})
+}
+
+func TestEstimate(t *testing.T) {
+	ctx := context.Background()
+	mem, err := memory.NewManager(&types.MemoryConfig{
+		Enabled: true,
+		SQLite:  types.SQLiteConfig{Enabled: true, Path: filepath.Join(t.TempDir(), "memory.db")},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mem.Close()
+
+	newOrg := func(t *testing.T, outputs ...string) (*Organization, *scriptedProvider) {
+		t.Helper()
+		provider := &scriptedProvider{outputs: outputs}
+		llmManager, err := llm.NewManager(&types.LLMConfig{DefaultModel: "scripted", Prices: map[string]types.ModelPrice{"scripted": {Input: 1, Output: 2}}}, llm.WithProvider("scripted", provider))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg := &types.AgentConfig{Model: "scripted"}
+		engineer := NewEngineerAgent("engineer-1", cfg, llmManager)
+		manager := NewManagerAgent("manager-1", cfg, llmManager)
+		manager.AddEngineer(engineer)
+		director := NewDirectorAgent("director-1", cfg)
+		director.AddManager(manager)
+		return &Organization{
+			config:        &types.Config{},
+			llmManager:    llmManager,
+			memoryManager: mem,
+			president:     director,
+			directors:     []types.Agent{director},
+			managers:      []types.Agent{manager},
+			engineers:     []types.Agent{engineer},
+		}, provider
+	}
+
+	t.Run("WithoutHistory", func(t *testing.T) {
+		org, provider := newOrg(t, "Design: a CLI that prints the time")
+		est, err := org.Estimate(ctx, "Build a CLI")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(provider.prompts) != 1 {
+			t.Errorf("Expected only the manager's design drafted, got %d generations", len(provider.prompts))
+		}
+		if len(est.Parts) != 1 || est.Parts[0].Manager != "manager-1" || est.Projects != 0 {
+			t.Fatalf("Expected one part planned for manager-1 and no history, got %+v", est)
+		}
+		tasks := make(map[types.AgentRole]int)
+		for _, r := range est.Roles {
+			tasks[r.Role] = r.Tasks
+		}
+		if tasks[types.RoleDirector] != 1 || tasks[types.RoleManager] != 1 || tasks[types.RoleEngineer] != 1 {
+			t.Errorf("Expected one task each for the director, manager and engineer, got %v", tasks)
+		}
+		engineer := est.Roles[len(est.Roles)-1]
+		if engineer.Role != types.RoleEngineer || engineer.Tokens.High <= engineer.Tokens.Low || engineer.Cost.High <= 0 || engineer.Time.High != 0 {
+			t.Errorf("Expected the engineer bracketed by its output budget with unknown time, got %+v", engineer)
+		}
+	})
+
+	t.Run("FromHistory", func(t *testing.T) {
+		org, _ := newOrg(t, "Design: a CLI that prints the time")
+		org.recordUsage(ctx, "run-1", &types.TaskUsage{
+			AgentID: organizationLog, PromptTokens: 1000, Cost: 10, Latency: 10 * time.Minute,
+			Subtasks: []*types.TaskUsage{{
+				AgentID: "director-1", PromptTokens: 1000, Cost: 10, Latency: 10 * time.Minute,
+				Subtasks: []*types.TaskUsage{{
+					AgentID: "manager-1", PromptTokens: 900, Cost: 9, Latency: 9 * time.Minute,
+					Subtasks: []*types.TaskUsage{
+						{AgentID: "engineer-1", PromptTokens: 300, Cost: 3, Latency: 3 * time.Minute},
+						{AgentID: "engineer-1", PromptTokens: 500, Cost: 5, Latency: 5 * time.Minute},
+					},
+				}},
+			}},
+		})
+
+		history, projects, err := org.usageHistory(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if projects != 1 || len(history[types.RoleEngineer]) != 1 || history[types.RoleEngineer][0].tasks != 2 || history[types.RoleEngineer][0].tokens != 800 {
+			t.Fatalf("Expected the engineers' own usage over 2 tasks from 1 project, got %d project(s) %+v", projects, history)
+		}
+		if manager := history[types.RoleManager]; len(manager) != 1 || manager[0].tokens != 100 || manager[0].time != time.Minute {
+			t.Errorf("Expected the manager's usage without its engineers', got %+v", manager)
+		}
+
+		est, err := org.Estimate(ctx, "Build a CLI")
+		if err != nil {
+			t.Fatal(err)
+		}
+		engineer := est.Roles[len(est.Roles)-1]
+		if est.Projects != 1 || engineer.Tokens != (Range[int]{Low: 400, High: 400}) || engineer.Time != (Range[time.Duration]{Low: 4 * time.Minute, High: 4 * time.Minute}) {
+			t.Errorf("Expected the engineer's average task of the past project, got %+v from %d project(s)", engineer, est.Projects)
+		}
+	})
+}
+
+func TestMemoryEffectiveness(t *testing.T) {
+	ctx := context.Background()
+	mem, err := memory.NewManager(&types.MemoryConfig{
+		Enabled: true,
+		SQLite:  types.SQLiteConfig{Enabled: true, InMemory: true},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mem.Close()
+
+	llmManager, err := llm.NewManager(&types.LLMConfig{}, llm.WithProvider("scripted", &scriptedProvider{outputs: []string{"func pay() {}", "func refund() {}"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	engineer := NewEngineerAgent("engineer-1", &types.AgentConfig{Model: "scripted"}, llmManager)
+	engineer.SetMemoryManager(mem)
+	org := &Organization{memoryManager: mem}
+
+	for i, runID := range []string{"run-1", "run-2"} {
+		task := &types.Task{ID: fmt.Sprintf("task-%d", i), RunID: runID, Title: "Payment API", Description: "Payment API", Content: "Charge cards"}
+		if _, err := engineer.ProcessTask(ctx, task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, s := range []*types.JudgeScore{{RunID: "run-1", TaskID: "task-0", Judge: "correctness", Score: 0.5}, {RunID: "run-2", TaskID: "task-1", Judge: "correctness", Score: 1}} {
+		if err := mem.RecordJudgeScore(ctx, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := org.MemoryEffectiveness(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 || stats[0].AgentID != "engineer-1" || stats[0].Retrievals != 2 || stats[0].Hits != 1 || stats[0].AverageTokens == 0 {
+		t.Fatalf("Expected the second task to find the first, got %+v", stats)
+	}
+	if got := stats[0]; got.ScoreWithHits != 1 || got.ScoreWithoutHits != 0.5 || got.Lift != 0.5 {
+		t.Errorf("Expected the project with a hit scored higher, got %+v", got)
+	}
+
+	if _, err := (&Organization{}).MemoryEffectiveness(ctx); errors.CodeOf(err) != errors.CodeMemoryUnavailable {
+		t.Errorf("Expected memory unavailable without a memory manager, got %v", err)
+	}
+}
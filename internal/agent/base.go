@@ -3,47 +3,99 @@ package agent
 import (
 	"context"
 	"fmt"
+	"log"
 	"sync"
-
+	"time"
+
+	"github.com/kpango/BuildBureau/internal/errors"
+	"github.com/kpango/BuildBureau/internal/imagegen"
+	"github.com/kpango/BuildBureau/internal/llm"
+	"github.com/kpango/BuildBureau/internal/logging"
+	"github.com/kpango/BuildBureau/internal/profile"
+	"github.com/kpango/BuildBureau/internal/provenance"
+	"github.com/kpango/BuildBureau/internal/slo"
 	"github.com/kpango/BuildBureau/pkg/types"
 )
 
 // BaseAgent provides common functionality for all agent types.
 type BaseAgent struct {
 	config         *types.AgentConfig
-	memory         *AgentMemory
+	memory         AgentMemory
+	memoryManager  types.MemoryManager
+	notifier       Notifier
+	logger         *log.Logger
+	activity       ActivityLog
+	recovery       types.RecoveryConfig
+	partialFailure types.PartialFailurePolicy // How mixed outcomes of parallel subtasks are treated
+	questions      types.QuestionsConfig
+	pairing        types.PairingConfig
+	blackboard     types.BlackboardConfig
+	partitioning   types.PartitioningConfig
+	partitionLLM   *llm.Manager // Divides projects into parts when partitioning is enabled
+	dedup          *Deduplicator
+	profiles       *profile.Store
+	knowledge      *KnowledgeBase
+	inboxes        *Inboxes
+	slos           *slo.Tracker // Receives the outcome of every delegated task
+	provenance     *provenance.Stamper
+	images         *imagegen.Generator // Generates the images engineers request, when enabled
+	clients        *llm.ClientPool     // Provider clients for agents that call an SDK directly
+	routing        []types.RoutingRule // Rules sending labeled tasks to a team or agent
+	team           string              // Team the agent belongs to, if teams are configured
+	conventions    *Conventions        // Output conventions added to deliverable prompts
+	contextStats   ContextStats
+	generations    map[string]*inflightGeneration // Generations in progress by ID
 	id             string
+	model          string // Overrides config.Model after SetModel
+	sessionProfile string // What the agent's last session was condensed into
 	role           types.AgentRole
 	activeTasks    int
 	completedTasks int
+	timeout        time.Duration
+	statusReports  bool
 	mu             sync.RWMutex
+	generating     sync.RWMutex // Held for reading by in-flight generations so SetModel can drain them
 	running        bool
 }
 
 // NewBaseAgent creates a new base agent.
-func NewBaseAgent(id string, role types.AgentRole, config *types.AgentConfig) *BaseAgent {
-	return &BaseAgent{
+func NewBaseAgent(id string, role types.AgentRole, config *types.AgentConfig, opts ...Option) *BaseAgent {
+	a := &BaseAgent{
 		id:     id,
 		role:   role,
 		config: config,
-		memory: nil, // Will be set by SetMemoryManager
+		memory: nil, // Will be set by WithMemory or SetMemoryManager
+	}
+
+	for _, opt := range opts {
+		opt(a)
 	}
+
+	return a
 }
 
 // SetMemoryManager sets the memory manager for this agent.
 func (a *BaseAgent) SetMemoryManager(manager types.MemoryManager) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
+	a.memoryManager = manager
 	a.memory = NewAgentMemory(a.id, manager)
 }
 
 // GetMemory returns the agent's memory interface.
-func (a *BaseAgent) GetMemory() *AgentMemory {
+func (a *BaseAgent) GetMemory() AgentMemory {
 	a.mu.RLock()
 	defer a.mu.RUnlock()
 	return a.memory
 }
 
+// GetMemoryHealth reports the state of the agent's memory backends, if they track it.
+func (a *BaseAgent) GetMemoryHealth() []types.StoreHealth {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return memoryHealth(a.memoryManager)
+}
+
 // GetID returns the agent's unique identifier.
 func (a *BaseAgent) GetID() string {
 	return a.id
@@ -54,6 +106,11 @@ func (a *BaseAgent) GetRole() types.AgentRole {
 	return a.role
 }
 
+// Team returns the team the agent belongs to, or "" outside teams.
+func (a *BaseAgent) Team() string {
+	return a.team
+}
+
 // Start initializes the agent.
 func (a *BaseAgent) Start(ctx context.Context) error {
 	a.mu.Lock()
@@ -94,6 +151,13 @@ func (a *BaseAgent) GetStats() (active int, completed int) {
 	return a.activeTasks, a.completedTasks
 }
 
+// restoreStats sets the completed task counter when restoring from a snapshot.
+func (a *BaseAgent) restoreStats(completed int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.completedTasks = completed
+}
+
 // IncrementActiveTasks increments the active task counter.
 func (a *BaseAgent) IncrementActiveTasks() {
 	a.mu.Lock()
@@ -108,3 +172,53 @@ func (a *BaseAgent) DecrementActiveTasks() {
 	a.activeTasks--
 	a.completedTasks++
 }
+
+// taskContext derives the context for a single task, applying the configured timeout
+// and carrying the task's run ID and labels so memories are linked to them.
+func (a *BaseAgent) taskContext(ctx context.Context, task *types.Task) (context.Context, context.CancelFunc) {
+	ctx = types.WithLabels(types.WithRunID(ctx, task.RunID), types.Labels(task.Metadata))
+	if a.timeout > 0 {
+		return context.WithTimeout(ctx, a.timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// logf writes to the agent logger and activity log if they are configured.
+func (a *BaseAgent) logf(format string, args ...any) {
+	if a.logger != nil {
+		a.logger.Printf("[%s] "+format, append([]any{a.id}, args...)...)
+	}
+	if a.activity != nil {
+		a.activity.Log(a.id, logging.Entry{Kind: logging.KindEvent, Role: string(a.role), Content: fmt.Sprintf(format, args...)})
+	}
+}
+
+// record appends an entry to the agent's activity log if one is configured.
+func (a *BaseAgent) record(ctx context.Context, kind, model, content string) {
+	if a.activity != nil {
+		a.activity.Log(a.id, logging.Entry{Kind: kind, Role: string(a.role), RunID: types.RunIDFromContext(ctx), Model: model, Content: content})
+	}
+}
+
+// notifyAssigned announces that a task was delegated to another agent.
+func (a *BaseAgent) notifyAssigned(ctx context.Context, task *types.Task) {
+	a.logf("delegating task %s to %s", task.ID, task.ToAgent)
+
+	if a.notifier == nil {
+		return
+	}
+
+	message := fmt.Sprintf("Task `%s` (%s) assigned to *%s* by %s", task.ID, task.Title, task.ToAgent, a.id)
+	if err := a.notifier.Notify(ctx, "task_assigned", message); err != nil {
+		a.logf("failed to send notification: %v", err)
+	}
+}
+
+// delegationError wraps a subordinate failure, classifying timeouts and cancellation.
+func (a *BaseAgent) delegationError(ctx context.Context, task *types.Task, err error, message string) error {
+	code := errors.CodeDelegationFailed
+	if ctxErr := errors.FromContext(ctx); ctxErr != nil {
+		code = ctxErr.Code
+	}
+	return errors.Wrap(err, code, message).WithAgent(a.id).WithTask(task.ID).WithProject(task.RunID)
+}
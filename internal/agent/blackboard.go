@@ -0,0 +1,170 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+const (
+	// Prefixes of response lines that write to the blackboard.
+	blackboardSetPrefix  = "BLACKBOARD SET "
+	blackboardNotePrefix = "BLACKBOARD NOTE:"
+
+	// defaultBlackboardScope is the role whose tasks get a blackboard when none is configured.
+	defaultBlackboardScope = types.RoleManager
+)
+
+// blackboardInstruction is appended to the blackboard in prompts.
+const blackboardInstruction = `To share something with the agents working on this task group, such as an
+agreed API schema, add lines to your response of the form:
+BLACKBOARD SET <key>: <value>
+BLACKBOARD NOTE: <text>
+`
+
+// BlackboardEntry is a value written to a blackboard.
+type BlackboardEntry struct {
+	Updated time.Time `json:"updated"`
+	Value   string    `json:"value"`
+	Author  string    `json:"author"`
+}
+
+// BlackboardNote is an append-only note on a blackboard.
+type BlackboardNote struct {
+	Time   time.Time `json:"time"`
+	Text   string    `json:"text"`
+	Author string    `json:"author"`
+}
+
+// Blackboard is shared state for the agents working on one task subtree:
+// key-value entries that later writes replace, and append-only notes.
+type Blackboard struct {
+	values map[string]BlackboardEntry
+	notes  []BlackboardNote
+	mu     sync.RWMutex
+}
+
+// NewBlackboard creates an empty blackboard.
+func NewBlackboard() *Blackboard {
+	return &Blackboard{values: make(map[string]BlackboardEntry)}
+}
+
+// Set writes a value, replacing any previous value of the key.
+func (b *Blackboard) Set(author, key, value string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.values[key] = BlackboardEntry{Value: value, Author: author, Updated: time.Now()}
+}
+
+// Get returns the value of a key.
+func (b *Blackboard) Get(key string) (BlackboardEntry, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	entry, ok := b.values[key]
+	return entry, ok
+}
+
+// Append adds a note.
+func (b *Blackboard) Append(author, text string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.notes = append(b.notes, BlackboardNote{Text: text, Author: author, Time: time.Now()})
+}
+
+// Notes returns the notes, oldest first.
+func (b *Blackboard) Notes() []BlackboardNote {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return slices.Clone(b.notes)
+}
+
+// String renders the blackboard for inclusion in a prompt, keys in order.
+func (b *Blackboard) String() string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var s strings.Builder
+	for _, key := range slices.Sorted(maps.Keys(b.values)) {
+		entry := b.values[key]
+		fmt.Fprintf(&s, "- %s (%s): %s\n", key, entry.Author, entry.Value)
+	}
+	for _, note := range b.notes {
+		fmt.Fprintf(&s, "- note from %s: %s\n", note.Author, note.Text)
+	}
+	return s.String()
+}
+
+type blackboardKey struct{}
+
+// BlackboardFromContext returns the blackboard of the task subtree ctx belongs to.
+func BlackboardFromContext(ctx context.Context) (*Blackboard, bool) {
+	b, ok := ctx.Value(blackboardKey{}).(*Blackboard)
+	return b, ok
+}
+
+// openBlackboard gives the task a fresh blackboard shared by its whole subtree
+// if blackboards are enabled and the agent's role is the configured scope.
+func (a *BaseAgent) openBlackboard(ctx context.Context) context.Context {
+	if !a.blackboard.Enabled {
+		return ctx
+	}
+
+	scope := types.AgentRole(a.blackboard.Scope)
+	if scope == "" {
+		scope = defaultBlackboardScope
+	}
+	if !strings.EqualFold(string(scope), string(a.role)) {
+		return ctx
+	}
+
+	return context.WithValue(ctx, blackboardKey{}, NewBlackboard())
+}
+
+// blackboardContext renders the task group's blackboard for inclusion in a prompt.
+func (a *BaseAgent) blackboardContext(ctx context.Context) string {
+	b, ok := BlackboardFromContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	content := b.String()
+	if content == "" {
+		content = "(empty)\n"
+	}
+	return "\n\n=== Task Group Blackboard ===\n" + content + blackboardInstruction + "=== End of Blackboard ===\n\n"
+}
+
+// applyBlackboard performs the blackboard writes in a response and returns the
+// response without them.
+func (a *BaseAgent) applyBlackboard(ctx context.Context, response string) string {
+	b, ok := BlackboardFromContext(ctx)
+	if !ok {
+		return response
+	}
+
+	var kept strings.Builder
+	for line := range strings.Lines(response) {
+		trimmed := strings.TrimSpace(line)
+		if text, ok := strings.CutPrefix(trimmed, blackboardNotePrefix); ok {
+			b.Append(a.id, strings.TrimSpace(text))
+			a.logf("added a note to the blackboard: %s", strings.TrimSpace(text))
+			continue
+		}
+		if entry, ok := strings.CutPrefix(trimmed, blackboardSetPrefix); ok {
+			if key, value, ok := strings.Cut(entry, ":"); ok && strings.TrimSpace(key) != "" {
+				b.Set(a.id, strings.TrimSpace(key), strings.TrimSpace(value))
+				a.logf("set %s on the blackboard", strings.TrimSpace(key))
+				continue
+			}
+		}
+		kept.WriteString(line)
+	}
+
+	return kept.String()
+}
@@ -0,0 +1,133 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kpango/BuildBureau/internal/llm"
+	"github.com/kpango/BuildBureau/internal/logging"
+)
+
+// ContextStats reports how much of its model's context window an agent uses.
+// Token counts are estimates.
+type ContextStats struct {
+	LastTruncation   time.Time `json:"last_truncation,omitzero"`
+	Limit            int       `json:"limit"`           // Context window of the agent's model
+	PromptTokens     int       `json:"prompt_tokens"`   // Last prompt, including the system prompt
+	ReservedTokens   int       `json:"reserved_tokens"` // Output tokens requested by the last prompt
+	PeakPromptTokens int       `json:"peak_prompt_tokens"`
+	MemoryTokens     int       `json:"memory_tokens"`  // Memory context in the last prompt that included any
+	Truncations      int       `json:"truncations"`    // Prompts whose content was dropped to fit the window
+	DroppedTokens    int       `json:"dropped_tokens"` // Total tokens dropped by truncations
+	Generations      int       `json:"generations"`
+}
+
+// Utilization returns the fraction of the context window used by the last
+// prompt and its reserved output.
+func (s ContextStats) Utilization() float64 {
+	if s.Limit <= 0 {
+		return 0
+	}
+	return float64(s.PromptTokens+s.ReservedTokens) / float64(s.Limit)
+}
+
+// String summarizes the stats, e.g. "42% of 128k, memory 2.1k, 3 truncations".
+func (s ContextStats) String() string {
+	if s.Generations == 0 {
+		return "unused"
+	}
+
+	summary := fmt.Sprintf("%.0f%% of %s, memory %s", s.Utilization()*100, formatTokens(s.Limit), formatTokens(s.MemoryTokens))
+	if s.Truncations > 0 {
+		summary += fmt.Sprintf(", %d truncation(s)", s.Truncations)
+	}
+	return summary
+}
+
+// formatTokens abbreviates a token count, e.g. 2100 as "2.1k".
+func formatTokens(n int) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	case n >= 1_000:
+		return fmt.Sprintf("%.1fk", float64(n)/1_000)
+	default:
+		return fmt.Sprint(n)
+	}
+}
+
+// ContextStats returns the agent's context window usage.
+func (a *BaseAgent) ContextStats() ContextStats {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.contextStats
+}
+
+// fitMemoryContext trims memory context so that, together with the rest of
+// the prompt and the requested output, it fits the model's context window.
+// Memory is ordered most relevant first, so the end is dropped. Trimming is
+// recorded as a truncation event.
+func (a *BaseAgent) fitMemoryContext(ctx context.Context, llmManager *llm.Manager, memoryContext, prompt string, opts *llm.GenerateOptions) string {
+	budget := a.memoryBudget(llmManager, prompt, opts)
+
+	tokens := llm.EstimateTokens(memoryContext)
+	if tokens > budget {
+		memoryContext = memoryContext[:min(len(memoryContext), budget*4)]
+		a.recordTruncation(ctx, tokens-budget, "memory context")
+		tokens = budget
+	}
+
+	a.mu.Lock()
+	a.contextStats.MemoryTokens = tokens
+	a.mu.Unlock()
+
+	return memoryContext
+}
+
+// memoryBudget returns how many tokens of the model's context window are left
+// for memory context by the rest of the prompt and the requested output.
+func (a *BaseAgent) memoryBudget(llmManager *llm.Manager, prompt string, opts *llm.GenerateOptions) int {
+	limit := llmManager.ContextWindow(a.Model())
+	return max(limit-llm.EstimateTokens(prompt)-llm.EstimateTokens(opts.SystemPrompt)-opts.MaxTokens, 0)
+}
+
+// recordUsage records the size of a prompt sent to the model. A prompt that
+// does not fit the window is counted as a truncation, since the provider will
+// drop or reject part of it.
+func (a *BaseAgent) recordUsage(ctx context.Context, llmManager *llm.Manager, model, prompt string, opts *llm.GenerateOptions) {
+	limit := llmManager.ContextWindow(model)
+	tokens := llm.EstimateTokens(prompt)
+	reserved := 0
+	if opts != nil {
+		tokens += llm.EstimateTokens(opts.SystemPrompt)
+		reserved = opts.MaxTokens
+	}
+
+	a.mu.Lock()
+	a.contextStats.Limit = limit
+	a.contextStats.PromptTokens = tokens
+	a.contextStats.ReservedTokens = reserved
+	a.contextStats.PeakPromptTokens = max(a.contextStats.PeakPromptTokens, tokens)
+	a.contextStats.Generations++
+	a.mu.Unlock()
+
+	if over := tokens + reserved - limit; over > 0 {
+		a.recordTruncation(ctx, over, "prompt")
+	}
+}
+
+// recordTruncation counts content dropped from a prompt and reports it in the activity log.
+func (a *BaseAgent) recordTruncation(ctx context.Context, dropped int, what string) {
+	a.mu.Lock()
+	a.contextStats.Truncations++
+	a.contextStats.DroppedTokens += dropped
+	a.contextStats.LastTruncation = time.Now()
+	a.mu.Unlock()
+
+	message := fmt.Sprintf("context window exceeded: dropped ~%d tokens of %s", dropped, what)
+	if a.logger != nil {
+		a.logger.Printf("[%s] %s", a.id, message)
+	}
+	a.record(ctx, logging.KindError, a.Model(), message)
+}
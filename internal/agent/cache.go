@@ -0,0 +1,174 @@
+package agent
+
+import (
+	"container/list"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// memoryCache is a small in-process LRU cache of memory query results.
+// Each entry records which memory types it was derived from so that writes
+// only invalidate the results they can affect. Every invalidation bumps the
+// generation of its type, and results loaded before it are not cached, so a
+// lookup racing a write cannot put stale results back.
+type memoryCache struct {
+	items       map[string]*list.Element
+	generations map[types.MemoryType]uint64
+	order       *list.List
+	epoch       uint64 // Bumped when every result is dropped
+	ttl         time.Duration
+	capacity    int
+	mu          sync.Mutex
+}
+
+type cacheItem struct {
+	expiresAt time.Time
+	key       string
+	entries   []*types.MemoryEntry
+	deps      []types.MemoryType
+}
+
+// newMemoryCache creates a cache; a non-positive capacity disables caching.
+func newMemoryCache(capacity int, ttl time.Duration) *memoryCache {
+	if capacity <= 0 {
+		return nil
+	}
+
+	return &memoryCache{
+		items:       make(map[string]*list.Element, capacity),
+		generations: make(map[types.MemoryType]uint64),
+		order:       list.New(),
+		ttl:         ttl,
+		capacity:    capacity,
+	}
+}
+
+// generation returns the sum of the generations of deps, which changes
+// whenever a result derived from them is invalidated.
+func (c *memoryCache) generation(deps ...types.MemoryType) uint64 {
+	if c == nil {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sumGenerations(deps)
+}
+
+func (c *memoryCache) sumGenerations(deps []types.MemoryType) uint64 {
+	sum := c.epoch
+	for _, dep := range deps {
+		sum += c.generations[dep]
+	}
+	return sum
+}
+
+// get returns cached entries for key if present and not expired.
+func (c *memoryCache) get(key string) ([]*types.MemoryEntry, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	item := elem.Value.(*cacheItem) //nolint:forcetypeassert // Only cacheItem values are stored
+	if c.ttl > 0 && time.Now().After(item.expiresAt) {
+		c.order.Remove(elem)
+		delete(c.items, key)
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+	return item.entries, true
+}
+
+// put stores entries for key, evicting the least recently used item if full.
+// Entries loaded when deps were at an earlier generation are not stored.
+func (c *memoryCache) put(key string, entries []*types.MemoryEntry, generation uint64, deps ...types.MemoryType) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.sumGenerations(deps) != generation {
+		return
+	}
+
+	item := &cacheItem{
+		key:       key,
+		entries:   entries,
+		deps:      deps,
+		expiresAt: time.Now().Add(c.ttl),
+	}
+
+	if elem, ok := c.items[key]; ok {
+		elem.Value = item
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(item)
+
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheItem).key) //nolint:forcetypeassert // Only cacheItem values are stored
+	}
+}
+
+// invalidate removes every cached result derived from the given memory type.
+func (c *memoryCache) invalidate(memType types.MemoryType) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generations[memType]++
+	for elem := c.order.Front(); elem != nil; {
+		next := elem.Next()
+		item := elem.Value.(*cacheItem) //nolint:forcetypeassert // Only cacheItem values are stored
+		if slices.Contains(item.deps, memType) {
+			c.order.Remove(elem)
+			delete(c.items, item.key)
+		}
+		elem = next
+	}
+}
+
+// clear drops every cached result, such as after entries were deleted.
+func (c *memoryCache) clear() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.epoch++
+	c.items = make(map[string]*list.Element, c.capacity)
+	c.order.Init()
+}
+
+// len returns the number of cached results.
+func (c *memoryCache) len() int {
+	if c == nil {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,173 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/kpango/BuildBureau/internal/llm"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+const (
+	// Values of the acceptance metadata on client task responses.
+	AcceptanceAccepted         = "accepted"
+	AcceptanceChangesRequested = "changes_requested"
+	AcceptanceUnreviewed       = "unreviewed"
+
+	// MetadataAcceptance, MetadataRevisions and MetadataClientFeedback report the
+	// outcome of the acceptance cycle on the final TaskResponse.
+	MetadataAcceptance     = "acceptance"
+	MetadataRevisions      = "revisions"
+	MetadataClientFeedback = "client_feedback"
+
+	defaultMaxRevisions = 2
+)
+
+// Review is a client's verdict on a deliverable.
+type Review struct {
+	Feedback       string   `json:"feedback"`
+	ChangeRequests []string `json:"change_requests,omitempty"`
+	Accepted       bool     `json:"accepted"`
+}
+
+// reviewPrompt asks the LLM to act as the client and judge the deliverable against the requirements.
+const reviewPrompt = `You are the client who commissioned the following work. Review the deliverable
+strictly against your original requirements. Accept it only if every requirement is met.
+If anything is missing or wrong, list concrete, actionable change requests.
+
+Original requirements:
+%s
+
+Deliverable:
+%s
+
+Respond ONLY with a JSON object in this exact format:
+{"accepted": true or false, "feedback": "...", "change_requests": ["..."]}`
+
+// ClientAgent simulates the client: it reviews the organization's final
+// deliverable against the original requirements and either accepts it or
+// requests changes. It is only created when a Client layer is configured.
+type ClientAgent struct {
+	*BaseAgent
+	llmManager *llm.Manager
+}
+
+// NewClientAgent creates a new Client agent.
+func NewClientAgent(id string, config *types.AgentConfig, llmManager *llm.Manager, opts ...Option) *ClientAgent {
+	return &ClientAgent{
+		BaseAgent:  NewBaseAgent(id, types.RoleClient, config, opts...),
+		llmManager: llmManager,
+	}
+}
+
+// ProcessTask reviews task.Content as a deliverable for the requirements in task.Description.
+func (a *ClientAgent) ProcessTask(ctx context.Context, task *types.Task) (*types.TaskResponse, error) {
+	a.IncrementActiveTasks()
+	defer a.DecrementActiveTasks()
+
+	ctx, cancel := a.taskContext(ctx, task)
+	defer cancel()
+
+	review, err := a.Review(ctx, task.Description, task.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	acceptance := AcceptanceChangesRequested
+	if review.Accepted {
+		acceptance = AcceptanceAccepted
+	}
+
+	return &types.TaskResponse{
+		TaskID:   task.ID,
+		Status:   types.StatusCompleted,
+		Result:   formatReview(review),
+		Metadata: map[string]string{MetadataAcceptance: acceptance},
+	}, nil
+}
+
+// Review judges a deliverable against the requirements. Without an LLM the
+// deliverable is accepted as is.
+func (a *ClientAgent) Review(ctx context.Context, requirements, deliverable string) (*Review, error) {
+	if a.llmManager == nil {
+		return &Review{Accepted: true, Feedback: "No LLM available, deliverable accepted without review."}, nil
+	}
+
+	output, err := a.generate(ctx, a.llmManager, fmt.Sprintf(reviewPrompt, requirements, deliverable), &llm.GenerateOptions{
+		Temperature:  0.2,
+		MaxTokens:    1024,
+		SystemPrompt: a.config.SystemPrompt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to review deliverable: %w", err)
+	}
+
+	review, err := parseReview(output)
+	if err != nil {
+		return nil, err
+	}
+
+	if mem := a.GetMemory(); mem != nil {
+		_ = mem.StoreConversation(ctx, "Client review: "+formatReview(review), []string{"client", "acceptance"})
+	}
+
+	return review, nil
+}
+
+// parseReview parses the JSON review, tolerating surrounding prose and code fences.
+func parseReview(output string) (*Review, error) {
+	start := strings.Index(output, "{")
+	end := strings.LastIndex(output, "}")
+	if start == -1 || end < start {
+		return nil, fmt.Errorf("no review found in output")
+	}
+
+	var review Review
+	if err := json.Unmarshal([]byte(output[start:end+1]), &review); err != nil {
+		return nil, fmt.Errorf("failed to parse review: %w", err)
+	}
+
+	// A rejection without change requests gives the organization nothing to act on
+	if !review.Accepted && len(review.ChangeRequests) == 0 && review.Feedback != "" {
+		review.ChangeRequests = []string{review.Feedback}
+	}
+
+	return &review, nil
+}
+
+// formatReview renders a review as text.
+func formatReview(review *Review) string {
+	var b strings.Builder
+
+	if review.Accepted {
+		b.WriteString("Accepted")
+	} else {
+		b.WriteString("Changes requested")
+	}
+	if review.Feedback != "" {
+		b.WriteString(": " + review.Feedback)
+	}
+	for _, change := range review.ChangeRequests {
+		b.WriteString("\n- " + change)
+	}
+
+	return b.String()
+}
+
+// revisionContent builds the instruction for a revision from the original
+// request, the rejected deliverable and the client's change requests.
+func revisionContent(instruction, deliverable string, review *Review) string {
+	var b strings.Builder
+
+	b.WriteString(instruction)
+	b.WriteString("\n\n=== Previous Deliverable ===\n")
+	b.WriteString(deliverable)
+	b.WriteString("\n=== End of Previous Deliverable ===\n\nThe client reviewed the deliverable and requested these changes:\n")
+	for _, change := range review.ChangeRequests {
+		b.WriteString("- " + change + "\n")
+	}
+
+	return b.String()
+}
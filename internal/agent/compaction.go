@@ -0,0 +1,180 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kpango/BuildBureau/internal/llm"
+	"github.com/kpango/BuildBureau/internal/report"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+const (
+	// KindSessionProfile is the kind of the memory an agent's session is condensed into.
+	KindSessionProfile = "session_profile"
+
+	defaultCompactionMemories = 50
+)
+
+// compactionPrompt asks the LLM to condense an agent's session into a profile.
+const compactionPrompt = `You are condensing the working memory of %s, a %s agent, at the end of a work session.
+
+Profile from earlier sessions:
+%s
+
+Memories of this session, oldest first:
+%s
+Write a compact profile for the agent's next session, at most 200 words, in two sections:
+Key lessons: what worked, what failed and what to do differently.
+Unfinished threads: work that was started but not finished, and open questions.
+Carry over what still matters from the earlier profile and leave out anything that will not matter later.`
+
+// compactMemories condenses each agent's conversations and tasks since the
+// organization started into a session profile memory, folding in the
+// previous profile. Agents with no memories of the session keep theirs.
+func (o *Organization) compactMemories(ctx context.Context) {
+	cfg := o.config.Memory
+	if cfg == nil || !cfg.Compaction.Enabled || o.memoryManager == nil || o.llmManager == nil {
+		return
+	}
+
+	o.mu.Lock()
+	since := o.startedAt
+	o.mu.Unlock()
+	if since.IsZero() {
+		return // Never started, so there is no session
+	}
+
+	// Queued writes of the session must be visible to the queries
+	if flusher, ok := o.memoryManager.(interface {
+		Flush(ctx context.Context) error
+	}); ok {
+		if err := flusher.Flush(ctx); err != nil {
+			fmt.Printf("Warning: failed to compact memories: %v\n", err)
+			return
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, agent := range o.allAgents() {
+		wg.Go(func() {
+			if err := o.compactAgent(ctx, agent, since); err != nil {
+				fmt.Printf("Warning: failed to compact the memories of %s: %v\n", agent.GetID(), err)
+			}
+		})
+	}
+	wg.Wait()
+}
+
+// compactAgent writes the session profile of one agent.
+func (o *Organization) compactAgent(ctx context.Context, agent types.Agent, since time.Time) error {
+	cfg := o.config.Memory.Compaction
+	entries, err := o.memoryManager.QueryMemories(ctx, &types.MemoryQuery{
+		AgentID:   agent.GetID(),
+		TimeRange: &types.TimeRange{Start: since, End: time.Now()},
+		Limit:     cmp.Or(cfg.MaxMemories, defaultCompactionMemories),
+	})
+	if err != nil {
+		return err
+	}
+	entries = slices.DeleteFunc(entries, func(e *types.MemoryEntry) bool {
+		return e.Type != types.MemoryTypeConversation && e.Type != types.MemoryTypeTask
+	})
+	if len(entries) == 0 {
+		return nil
+	}
+
+	var session strings.Builder
+	for _, e := range slices.Backward(entries) {
+		fmt.Fprintf(&session, "- [%s] %s\n", e.Type, strings.TrimSpace(e.Content))
+	}
+	previous, err := sessionProfile(ctx, o.memoryManager, agent.GetID())
+	if err != nil {
+		return err
+	}
+
+	model := cfg.Model
+	if m, ok := agent.(interface{ Model() string }); ok && model == "" {
+		model = m.Model()
+	}
+	output, err := o.llmManager.Generate(ctx, model, fmt.Sprintf(compactionPrompt, agent.GetID(), agent.GetRole(), cmp.Or(previous, "(none)"), session.String()), &llm.GenerateOptions{
+		Temperature: 0.2,
+		MaxTokens:   512,
+	})
+	if err != nil {
+		return err
+	}
+	profile := strings.TrimSpace(output)
+	if profile == "" {
+		return nil
+	}
+
+	return o.memoryManager.StoreMemory(ctx, &types.MemoryEntry{
+		AgentID: agent.GetID(),
+		Type:    types.MemoryTypeContext,
+		Content: profile,
+		Metadata: map[string]string{
+			report.MetadataKind: KindSessionProfile,
+			"memories":          strconv.Itoa(len(entries)),
+			"since":             since.Format(time.RFC3339),
+		},
+	})
+}
+
+// loadSessionProfiles gives every agent the profile its last session was
+// condensed into, for its prompts.
+func (o *Organization) loadSessionProfiles(ctx context.Context) {
+	if o.memoryManager == nil {
+		return
+	}
+	for _, agent := range o.allAgents() {
+		a, ok := agent.(interface{ setSessionProfile(string) })
+		if !ok {
+			continue
+		}
+		profile, err := sessionProfile(ctx, o.memoryManager, agent.GetID())
+		if err != nil {
+			fmt.Printf("Warning: failed to load the session profile of %s: %v\n", agent.GetID(), err)
+			continue
+		}
+		a.setSessionProfile(profile)
+	}
+}
+
+// sessionProfile returns the latest session profile of an agent, or "".
+func sessionProfile(ctx context.Context, manager types.MemoryManager, agentID string) (string, error) {
+	entries, err := manager.QueryMemories(ctx, &types.MemoryQuery{
+		AgentID:  agentID,
+		Type:     types.MemoryTypeContext,
+		Metadata: map[string]string{report.MetadataKind: KindSessionProfile},
+		Limit:    1,
+	})
+	if err != nil || len(entries) == 0 {
+		return "", err
+	}
+	return entries[0].Content, nil
+}
+
+// setSessionProfile sets the profile of the agent's last session.
+func (a *BaseAgent) setSessionProfile(profile string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.sessionProfile = profile
+}
+
+// sessionContext renders the profile of the agent's last session for
+// inclusion in a prompt.
+func (a *BaseAgent) sessionContext() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.sessionProfile == "" {
+		return ""
+	}
+	return "\n\n=== Your Last Session ===\n" + a.sessionProfile + "\n=== End of Last Session ===\n\n"
+}
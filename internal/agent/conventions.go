@@ -0,0 +1,191 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"go/format"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/kpango/BuildBureau/internal/llm"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// MetadataDeviations is the response metadata key listing how a deliverable
+// deviates from the organization's conventions, one deviation per line.
+const MetadataDeviations = "convention_deviations"
+
+// defaultConventionsTemplate renders the configured conventions.
+const defaultConventionsTemplate = `{{if .Language}}Write all prose, code comments and documentation in {{.Language}}.
+{{end}}{{if .StyleGuide}}Follow this code style guide: {{.StyleGuide}}.
+{{end}}{{if .CommitFormat}}Write commit messages in this format: {{.CommitFormat}}.
+{{end}}{{if .DocTemplate}}Structure documentation after this template:
+{{.DocTemplate}}
+{{end}}`
+
+// conventionsPrompt asks the LLM how a deliverable deviates from the conventions.
+const conventionsPrompt = `Check the deliverable below against the organization's conventions.
+
+Conventions:
+%s
+
+Deliverable:
+%s
+
+List every way the deliverable deviates from the conventions, one per line, each starting with "- ". If it follows them, answer only NONE.`
+
+// ConventionsData is the data available to conventions templates.
+type ConventionsData struct {
+	Language     string
+	StyleGuide   string
+	CommitFormat string
+	DocTemplate  string // Content of the documentation template
+	Role         string // Role of the agent being prompted
+	Title        string // Title of its task
+}
+
+// Conventions are the output conventions of an organization.
+type Conventions struct {
+	tmpl *template.Template
+	data ConventionsData
+}
+
+// NewConventions loads the documentation template and parses the conventions
+// template of cfg. It returns nil if conventions are not enabled.
+func NewConventions(cfg types.ConventionsConfig) (*Conventions, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	data := ConventionsData{Language: cfg.Language, StyleGuide: cfg.StyleGuide, CommitFormat: cfg.CommitFormat}
+	if cfg.DocTemplate != "" {
+		doc, err := os.ReadFile(cfg.DocTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read documentation template: %w", err)
+		}
+		data.DocTemplate = strings.TrimSpace(string(doc))
+	}
+
+	text := cfg.Template
+	if text == "" {
+		text = defaultConventionsTemplate
+	}
+	tmpl, err := template.New("conventions").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid conventions template: %w", err)
+	}
+	return &Conventions{tmpl: tmpl, data: data}, nil
+}
+
+// Render renders the conventions for an agent of role working on a task.
+func (c *Conventions) Render(role types.AgentRole, task *types.Task) (string, error) {
+	data := c.data
+	data.Role = string(role)
+	data.Title = task.Title
+
+	var b strings.Builder
+	if err := c.tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// conventionsContext renders the organization's conventions for the agent's
+// prompt, or returns "" if there are none. A template that fails is reported
+// and left out.
+func (a *BaseAgent) conventionsContext(task *types.Task) string {
+	if a.conventions == nil {
+		return ""
+	}
+	text, err := a.conventions.Render(a.role, task)
+	if err != nil {
+		a.logf("conventions template failed: %v", err)
+		return ""
+	}
+	if text == "" {
+		return ""
+	}
+	return "\n\n=== Organization Conventions ===\n" + text + "\n=== End of Conventions ==="
+}
+
+// checkConventions flags how a deliverable deviates from the organization's
+// conventions: Go files gofmt would change, and the deviations the LLM finds.
+func (o *Organization) checkConventions(ctx context.Context, task *types.Task, resp *types.TaskResponse) {
+	cfg := o.config.Organization.Conventions
+	if o.conventions == nil || !cfg.Validate || resp.Status == types.StatusFailed {
+		return
+	}
+
+	var deviations []string
+	for _, f := range resp.Files {
+		if !strings.HasSuffix(f.Path, ".go") {
+			continue
+		}
+		if formatted, err := format.Source([]byte(f.Content)); err == nil && string(formatted) != f.Content {
+			deviations = append(deviations, f.Path+" is not formatted with gofmt")
+		}
+	}
+
+	conventions, err := o.conventions.Render("", task)
+	if err != nil {
+		fmt.Printf("Warning: conventions template failed: %v\n", err)
+	}
+	if o.llmManager != nil && err == nil {
+		deliverable := Deliverable(resp)
+		if !strings.Contains(deliverable, fileStartPrefix) {
+			deliverable = renderFiles(deliverable, resp.Files)
+		}
+		output, err := o.llmManager.Generate(ctx, o.conventionsModel(), fmt.Sprintf(conventionsPrompt, conventions, deliverable), &llm.GenerateOptions{
+			Temperature: 0.0,
+		})
+		if err != nil {
+			fmt.Printf("Warning: failed to check the deliverable against the conventions: %v\n", err)
+		} else {
+			deviations = append(deviations, parseDeviations(output)...)
+		}
+	}
+
+	if len(deviations) == 0 {
+		return
+	}
+	if resp.Metadata == nil {
+		resp.Metadata = make(map[string]string)
+	}
+	resp.Metadata[MetadataDeviations] = strings.Join(deviations, "\n")
+}
+
+// conventionChanges returns a change request for every deviation from the conventions.
+func (o *Organization) conventionChanges(resp *types.TaskResponse) []string {
+	var changes []string
+	for deviation := range strings.Lines(resp.Metadata[MetadataDeviations]) {
+		if deviation = strings.TrimSpace(deviation); deviation != "" {
+			changes = append(changes, "Follow the organization's conventions: "+deviation)
+		}
+	}
+	return changes
+}
+
+// conventionsModel returns the model that checks deliverables against the conventions.
+func (o *Organization) conventionsModel() string {
+	if model := o.config.Organization.Conventions.Model; model != "" {
+		return model
+	}
+	if m, ok := o.president.(interface{ Model() string }); ok {
+		return m.Model()
+	}
+	return ""
+}
+
+// parseDeviations returns the deviations listed in the LLM's answer.
+func parseDeviations(output string) []string {
+	var deviations []string
+	for line := range strings.Lines(output) {
+		if deviation, ok := strings.CutPrefix(strings.TrimSpace(line), "- "); ok {
+			if deviation = strings.TrimSpace(deviation); deviation != "" {
+				deviations = append(deviations, deviation)
+			}
+		}
+	}
+	return deviations
+}
@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/kpango/BuildBureau/internal/llm"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// Decision represents an explicit decision extracted from an agent response.
+type Decision struct {
+	What         string   `json:"decision"`
+	Why          string   `json:"reasoning"`
+	Alternatives []string `json:"alternatives_rejected,omitempty"`
+}
+
+// decisionExtractionPrompt instructs the LLM to return decisions as a JSON array.
+const decisionExtractionPrompt = `Extract every explicit decision made in the following response.
+For each decision, state what was decided, why, and which alternatives were rejected.
+
+Respond ONLY with a JSON array in this exact format (use [] if there are no decisions):
+[{"decision": "...", "reasoning": "...", "alternatives_rejected": ["..."]}]
+
+Response:
+%s`
+
+// extractDecisions uses the LLM to pull explicit decisions out of a free-form response.
+func extractDecisions(ctx context.Context, llmManager *llm.Manager, model, response string) ([]Decision, error) {
+	if llmManager == nil {
+		return nil, fmt.Errorf("llm manager not available")
+	}
+
+	output, err := llmManager.Generate(ctx, model, fmt.Sprintf(decisionExtractionPrompt, response), &llm.GenerateOptions{
+		Temperature: 0.0, // Extraction should be deterministic
+		MaxTokens:   1024,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract decisions: %w", err)
+	}
+
+	return parseDecisions(output)
+}
+
+// parseDecisions parses the JSON decision list, tolerating surrounding prose and code fences.
+func parseDecisions(output string) ([]Decision, error) {
+	start := strings.Index(output, "[")
+	end := strings.LastIndex(output, "]")
+	if start == -1 || end < start {
+		return nil, fmt.Errorf("no decision list found in output")
+	}
+
+	var decisions []Decision
+	if err := json.Unmarshal([]byte(output[start:end+1]), &decisions); err != nil {
+		return nil, fmt.Errorf("failed to parse decisions: %w", err)
+	}
+
+	// Drop entries without an actual decision
+	valid := decisions[:0]
+	for _, d := range decisions {
+		if strings.TrimSpace(d.What) != "" {
+			valid = append(valid, d)
+		}
+	}
+
+	return valid, nil
+}
+
+// recordDecisions extracts decisions from a response and stores them linked to the originating task.
+func recordDecisions(ctx context.Context, mem AgentMemory, llmManager *llm.Manager, model string, task *types.Task, response string, tags []string) int {
+	if mem == nil || llmManager == nil {
+		return 0
+	}
+
+	decisions, err := extractDecisions(ctx, llmManager, model, response)
+	if err != nil {
+		return 0
+	}
+
+	stored := 0
+	for _, d := range decisions {
+		if err := mem.StoreTaskDecision(ctx, task, d, tags); err == nil {
+			stored++
+		}
+	}
+
+	return stored
+}
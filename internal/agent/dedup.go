@@ -0,0 +1,220 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+const (
+	defaultDedupThreshold = 0.9
+	defaultDedupTTL       = 10 * time.Minute
+
+	// MetadataDeduplicatedFrom holds the ID of the task whose result was reused.
+	MetadataDeduplicatedFrom = "deduplicated_from"
+)
+
+// DedupStats reports how much work a Deduplicator avoided.
+type DedupStats struct {
+	Tasks       int // Tasks checked
+	Shared      int // Tasks answered with a sibling's result
+	SavedTokens int // Rough estimate of output tokens not generated
+}
+
+// dedupEntry is a task that is running or finished recently.
+type dedupEntry struct {
+	finished time.Time
+	shingles map[string]struct{}
+	done     chan struct{}
+	resp     *types.TaskResponse
+	err      error
+	taskID   string
+	agentID  string
+}
+
+// Deduplicator collapses near-identical tasks sent to sibling agents so only
+// one of them does the work. Tasks are compared by the Jaccard similarity of
+// their word bigrams; a task similar to one that is running waits for it, and
+// one similar to a recently finished task reuses its result.
+type Deduplicator struct {
+	now       func() time.Time
+	entries   []*dedupEntry
+	stats     DedupStats
+	threshold float64
+	ttl       time.Duration
+	mu        sync.Mutex
+}
+
+// NewDeduplicator creates a Deduplicator from configuration, applying defaults.
+func NewDeduplicator(cfg types.DeduplicationConfig) *Deduplicator {
+	d := &Deduplicator{
+		threshold: cfg.Threshold,
+		ttl:       cfg.TTL,
+		now:       time.Now,
+	}
+
+	if d.threshold <= 0 || d.threshold > 1 {
+		d.threshold = defaultDedupThreshold
+	}
+	if d.ttl <= 0 {
+		d.ttl = defaultDedupTTL
+	}
+
+	return d
+}
+
+// Do runs fn for the task unless a similar task is running or finished
+// recently, in which case that task's response is returned with shared set.
+// Failed tasks are never shared, so a duplicate of a failure runs fn itself.
+func (d *Deduplicator) Do(ctx context.Context, agentID string, task *types.Task, fn func() (*types.TaskResponse, error)) (*types.TaskResponse, bool, error) {
+	shingles := shingle(task.Content)
+
+	d.mu.Lock()
+	d.stats.Tasks++
+	d.prune()
+	match := d.match(shingles)
+
+	if match == nil {
+		entry := &dedupEntry{
+			shingles: shingles,
+			done:     make(chan struct{}),
+			taskID:   task.ID,
+			agentID:  agentID,
+		}
+		d.entries = append(d.entries, entry)
+		d.mu.Unlock()
+
+		resp, err := fn()
+		d.finish(entry, resp, err)
+		return resp, false, err
+	}
+	d.mu.Unlock()
+
+	select {
+	case <-match.done:
+	case <-ctx.Done():
+		return nil, false, ctx.Err()
+	}
+
+	if match.err != nil || match.resp == nil || match.resp.Status == types.StatusFailed {
+		resp, err := fn()
+		return resp, false, err
+	}
+
+	d.mu.Lock()
+	d.stats.Shared++
+	d.stats.SavedTokens += len(match.resp.Result) / 4 // Rough estimate
+	d.mu.Unlock()
+
+	shared := &types.TaskResponse{
+		TaskID:   task.ID,
+		Status:   match.resp.Status,
+		Result:   match.resp.Result,
+		Metadata: map[string]string{MetadataDeduplicatedFrom: match.taskID},
+		Files:    match.resp.Files,
+	}
+	for k, v := range match.resp.Metadata {
+		shared.Metadata[k] = v
+	}
+
+	return shared, true, nil
+}
+
+// Stats returns how many tasks were deduplicated.
+func (d *Deduplicator) Stats() DedupStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.stats
+}
+
+// match returns the most similar running or finished entry above the threshold.
+// The caller must hold d.mu.
+func (d *Deduplicator) match(shingles map[string]struct{}) *dedupEntry {
+	var best *dedupEntry
+	bestScore := d.threshold
+
+	for _, entry := range d.entries {
+		if score := jaccard(shingles, entry.shingles); score >= bestScore {
+			best, bestScore = entry, score
+		}
+	}
+
+	return best
+}
+
+// finish records a task's outcome, dropping failures so they are not matched again.
+func (d *Deduplicator) finish(entry *dedupEntry, resp *types.TaskResponse, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entry.resp, entry.err = resp, err
+	entry.finished = d.now()
+	close(entry.done)
+
+	if err != nil || resp == nil || resp.Status == types.StatusFailed {
+		d.remove(entry)
+	}
+}
+
+// prune drops finished entries older than the TTL. The caller must hold d.mu.
+func (d *Deduplicator) prune() {
+	now := d.now()
+	kept := d.entries[:0]
+	for _, entry := range d.entries {
+		if entry.finished.IsZero() || now.Sub(entry.finished) < d.ttl {
+			kept = append(kept, entry)
+		}
+	}
+	d.entries = kept
+}
+
+// remove drops an entry. The caller must hold d.mu.
+func (d *Deduplicator) remove(target *dedupEntry) {
+	for i, entry := range d.entries {
+		if entry == target {
+			d.entries = append(d.entries[:i], d.entries[i+1:]...)
+			return
+		}
+	}
+}
+
+// shingle returns the set of lowercase word bigrams in text, or single words
+// if it has fewer than two.
+func shingle(text string) map[string]struct{} {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	shingles := make(map[string]struct{}, len(words))
+	if len(words) < 2 {
+		for _, w := range words {
+			shingles[w] = struct{}{}
+		}
+		return shingles
+	}
+
+	for i := 0; i+1 < len(words); i++ {
+		shingles[words[i]+" "+words[i+1]] = struct{}{}
+	}
+	return shingles
+}
+
+// jaccard returns the Jaccard similarity of two sets.
+func jaccard(a, b map[string]struct{}) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for s := range a {
+		if _, ok := b[s]; ok {
+			shared++
+		}
+	}
+
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
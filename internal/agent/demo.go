@@ -0,0 +1,226 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/kpango/BuildBureau/internal/errors"
+	"github.com/kpango/BuildBureau/internal/llm"
+	"github.com/kpango/BuildBureau/internal/provenance"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// MetadataDemo marks a response produced by a demo instance.
+const MetadataDemo = "demo"
+
+const (
+	defaultDemoWatermark           = "Generated by a BuildBureau demo instance. Not reviewed; do not use in production."
+	defaultDemoMaxInstructionChars = 2000
+	defaultDemoProjectsPerHour     = 5
+)
+
+// Limits of a demo organization, small enough that anyone may try it.
+const (
+	demoMaxRevisions  = 1
+	demoMaxParts      = 2
+	demoMaxConcurrent = 2
+	demoQueueDepth    = 4
+	demoCodeTokens    = 2048
+	demoSpecTokens    = 1024
+	demoSummaryTokens = 512
+
+	// demoSweepInterval is how often the gate forgets clients whose
+	// submissions have all expired.
+	demoSweepInterval = 10 * time.Minute
+)
+
+// RestrictForDemo returns the configuration a demo organization runs with:
+// a copy of cfg without LLM API keys or integrations that reach outside the
+// organization (Slack, desktop notifications, the outbox, webhooks, the admin
+// API, image and speech generation and security scanners), without questions
+// to the human, and with one agent per layer and small projects. A
+// configuration whose demo mode is not enabled is returned as it is.
+func RestrictForDemo(cfg *types.Config) *types.Config {
+	if cfg == nil || cfg.Demo == nil || !cfg.Demo.Enabled {
+		return cfg
+	}
+
+	restricted := *cfg
+	restricted.LLMs.APIKeys = nil
+	restricted.LLMs.OutputTokens = types.OutputTokensConfig{
+		Code:    demoCodeTokens,
+		Spec:    demoSpecTokens,
+		Summary: demoSummaryTokens,
+	}
+	restricted.Slack = nil
+	restricted.Desktop = nil
+	restricted.Outbox = nil
+	restricted.Admin = nil
+	restricted.Webhooks = nil
+	restricted.Images = nil
+	restricted.Speech = nil
+	restricted.SecurityScan = nil
+
+	org := &restricted.Organization
+	org.Layers = slices.Clone(org.Layers)
+	for i := range org.Layers {
+		org.Layers[i].Count = min(org.Layers[i].Count, 1)
+	}
+	org.Teams = nil
+	org.Questions.Enabled = false
+	org.Pairing.Enabled = false
+	org.Recovery.MaxReplans = 0
+	org.Acceptance.MaxRevisions = demoMaxRevisions
+	org.Partitioning.MaxParts = demoMaxParts
+	org.Admission.MaxConcurrent = cmp.Or(min(org.Admission.MaxConcurrent, demoMaxConcurrent), demoMaxConcurrent)
+	org.Admission.QueueDepth = min(org.Admission.QueueDepth, demoQueueDepth)
+	org.Admission.MaxPerUser = 1
+	return &restricted
+}
+
+// newDemoLLMManager creates the LLM manager of a demo organization, which
+// serves every model with the configured free provider.
+func newDemoLLMManager(cfg *types.Config) (*llm.Manager, error) {
+	var provider llm.Provider
+	switch name := cmp.Or(cfg.Demo.Provider, llm.ProviderMock); name {
+	case llm.ProviderMock:
+		provider = llm.NewMockProvider()
+	case llm.ProviderOllama:
+		provider = llm.NewOllamaProvider(cfg.Demo.OllamaURL, cfg.Demo.OllamaModel)
+	default:
+		return nil, fmt.Errorf("unknown demo provider %q: must be %s or %s", name, llm.ProviderMock, llm.ProviderOllama)
+	}
+	return llm.NewManager(&cfg.LLMs, llm.WithOverride(provider.Name(), provider))
+}
+
+// demoGate admits the submissions of a demo organization: requests of a
+// bounded size, without attached documents, and a bounded number of projects
+// per client an hour. Clients are identified by the server that received the
+// submission (see types.WithClient), never by the user the caller names;
+// submissions without a client, such as those from the CLI, share one allowance.
+type demoGate struct {
+	now       func() time.Time
+	swept     time.Time
+	submitted map[string][]time.Time
+	watermark string
+	maxChars  int
+	perHour   int
+	mu        sync.Mutex
+}
+
+// newDemoGate creates the gate of a demo configuration, or returns nil if demo
+// mode is not enabled.
+func newDemoGate(cfg *types.DemoConfig) *demoGate {
+	if cfg == nil || !cfg.Enabled {
+		return nil
+	}
+	return &demoGate{
+		now:       time.Now,
+		submitted: make(map[string][]time.Time),
+		watermark: cmp.Or(cfg.Watermark, defaultDemoWatermark),
+		maxChars:  cmp.Or(cfg.MaxInstructionChars, defaultDemoMaxInstructionChars),
+		perHour:   cmp.Or(cfg.ProjectsPerHour, defaultDemoProjectsPerHour),
+	}
+}
+
+// admit checks a submission against the demo limits without counting it; a
+// submission is counted by record once it is accepted. A nil gate admits
+// everything.
+func (g *demoGate) admit(ctx context.Context, instruction string) error {
+	if g == nil {
+		return nil
+	}
+	if n := len([]rune(instruction)); n > g.maxChars {
+		return errors.Newf(errors.CodeInvalidArgument, "request is %d characters long; this demo accepts at most %d", n, g.maxChars)
+	}
+	if len(Documents(ctx)) > 0 {
+		return errors.New(errors.CodeInvalidArgument, "this demo does not accept attached documents")
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	_, err := g.allowance(types.ClientFromContext(ctx), g.now())
+	return err
+}
+
+// record counts an accepted submission against the client's allowance. It
+// checks the allowance again, as submissions admitted concurrently may have
+// used it up since.
+func (g *demoGate) record(ctx context.Context) error {
+	if g == nil {
+		return nil
+	}
+
+	client, now := types.ClientFromContext(ctx), g.now()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	recent, err := g.allowance(client, now)
+	if err != nil {
+		return err
+	}
+	g.submitted[client] = append(recent, now)
+	return nil
+}
+
+// allowance returns the client's submissions within the last hour, or an
+// error if they used up the allowance. g.mu must be held.
+func (g *demoGate) allowance(client string, now time.Time) ([]time.Time, error) {
+	g.sweep(now)
+	recent := slices.DeleteFunc(g.submitted[client], func(t time.Time) bool {
+		return now.Sub(t) >= time.Hour
+	})
+	if len(recent) == 0 {
+		delete(g.submitted, client)
+	} else {
+		g.submitted[client] = recent
+	}
+	if len(recent) >= g.perHour {
+		return nil, errors.Newf(errors.CodeOverloaded, "this demo accepts %d project(s) per hour; try again in %s", g.perHour, recent[0].Add(time.Hour).Sub(now).Round(time.Minute))
+	}
+	return recent, nil
+}
+
+// sweep forgets the clients whose latest submission is more than an hour old,
+// at most once per demoSweepInterval, so that clients who never return are not
+// kept. g.mu must be held.
+func (g *demoGate) sweep(now time.Time) {
+	if now.Sub(g.swept) < demoSweepInterval {
+		return
+	}
+	g.swept = now
+	maps.DeleteFunc(g.submitted, func(_ string, times []time.Time) bool {
+		return len(times) == 0 || now.Sub(times[len(times)-1]) >= time.Hour
+	})
+}
+
+// mark watermarks a demo deliverable: the result, the deliverable behind a
+// status summary and every text file in a language comments can be added to.
+func (g *demoGate) mark(resp *types.TaskResponse) {
+	if g == nil || resp == nil {
+		return
+	}
+
+	resp.Result = g.watermark + "\n\n" + resp.Result
+	if resp.Metadata == nil {
+		resp.Metadata = make(map[string]string)
+	}
+	if deliverable, ok := resp.Metadata[MetadataDeliverable]; ok {
+		resp.Metadata[MetadataDeliverable] = g.watermark + "\n\n" + deliverable
+	}
+	resp.Metadata[MetadataDemo] = "true"
+
+	for i, file := range resp.Files {
+		if file.Binary() {
+			continue
+		}
+		if content, ok := provenance.InjectComment(file.Path, file.Content, g.watermark); ok {
+			resp.Files[i].Content = content
+			resp.Files[i].Bytes = len(content)
+		}
+	}
+}
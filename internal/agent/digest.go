@@ -0,0 +1,146 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kpango/BuildBureau/internal/config"
+	"github.com/kpango/BuildBureau/internal/speech"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// MetadataDigestAudio records where the spoken digest of a project was written.
+const MetadataDigestAudio = "digest_audio"
+
+// maxDigestFiles bounds the files a digest names; the rest are counted.
+const maxDigestFiles = 5
+
+// openSpeech sets up the configured text-to-speech. It returns nil if spoken
+// digests are not enabled or cannot be set up. Without its own API key,
+// speech uses the openai key of the LLMs.
+func (o *Organization) openSpeech() *speech.Speaker {
+	cfg := o.config.Speech
+	if cfg == nil || !cfg.Enabled {
+		return nil
+	}
+
+	key := config.GetEnvValue(cfg.APIKey)
+	if key == "" {
+		key = config.GetEnvValue(o.config.LLMs.APIKeys["openai"])
+	}
+	speaker, err := speech.New(cfg, key)
+	if err != nil {
+		fmt.Printf("Warning: Spoken digests disabled: %v\n", err)
+		return nil
+	}
+	return speaker
+}
+
+// speakDigest reads the digest of a finished project aloud into
+// <dir>/<run-id>. The audio is added to the files of a successful project and
+// its path recorded in the response metadata. Failing to render it does not
+// fail the project.
+func (o *Organization) speakDigest(ctx context.Context, runID, instruction string, resp *types.TaskResponse, err error) {
+	if o.speech == nil {
+		return
+	}
+
+	audio, stored, renderErr := o.speech.Render(context.WithoutCancel(ctx), runID, projectDigest(instruction, resp, err))
+	if renderErr != nil {
+		fmt.Printf("Warning: failed to render spoken digest: %v\n", renderErr)
+		return
+	}
+	if err != nil {
+		return
+	}
+
+	audio.Path = "digest/" + audio.Path
+	resp.Files = mergeFiles(resp.Files, []types.FileArtifact{audio})
+	if resp.Metadata == nil {
+		resp.Metadata = make(map[string]string)
+	}
+	resp.Metadata[MetadataDigestAudio] = stored
+}
+
+// projectDigest summarizes a finished project in plain sentences meant to be
+// heard rather than read: what was asked, whether it succeeded and was
+// accepted, what was done, the files produced and what it cost.
+func projectDigest(instruction string, resp *types.TaskResponse, err error) string {
+	var b strings.Builder
+	request := spoken(truncate(200, instruction))
+
+	if err != nil || resp == nil || resp.Status == types.StatusFailed {
+		fmt.Fprintf(&b, "Project digest. The project \"%s\" failed.", request)
+		switch {
+		case err != nil:
+			fmt.Fprintf(&b, " The error was: %s.", spoken(truncate(300, err.Error())))
+		case resp != nil && resp.Error != "":
+			fmt.Fprintf(&b, " The error was: %s.", spoken(truncate(300, resp.Error)))
+		}
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "Project digest. The project \"%s\" is complete.", request)
+	switch resp.Metadata[MetadataAcceptance] {
+	case AcceptanceAccepted:
+		fmt.Fprintf(&b, " The client accepted it after %s.", revisionCount(resp.Metadata[MetadataRevisions]))
+	case AcceptanceChangesRequested:
+		fmt.Fprintf(&b, " The client still requested changes after %s.", revisionCount(resp.Metadata[MetadataRevisions]))
+	}
+
+	status, ok := StatusOf(resp)
+	if !ok {
+		heuristic := heuristicStatus(resp.Result, Deliverable(resp))
+		status = &heuristic
+	}
+	if done := spoken(status.Done); done != "" {
+		fmt.Fprintf(&b, " What was done: %s", sentence(done))
+	}
+	if len(status.Blockers) > 0 {
+		fmt.Fprintf(&b, " Open issues: %s", sentence(spoken(strings.Join(status.Blockers, "; "))))
+	}
+
+	if n := len(resp.Files); n > 0 {
+		names := filePaths(resp.Files[:min(n, maxDigestFiles)])
+		fmt.Fprintf(&b, " It produced %d file(s), including %s.", n, strings.Join(names, ", "))
+	}
+	if n := len(resp.Findings); n > 0 {
+		fmt.Fprintf(&b, " The security scan reported %d finding(s).", n)
+	}
+	if u := resp.Usage; u != nil {
+		fmt.Fprintf(&b, " It took %s", u.Latency.Round(time.Second))
+		if u.Cost > 0 {
+			fmt.Fprintf(&b, " and cost about %.2f dollars", u.Cost)
+		}
+		b.WriteString(".")
+	}
+	return b.String()
+}
+
+// revisionCount phrases a number of revisions for speech.
+func revisionCount(revisions string) string {
+	switch revisions {
+	case "", "0":
+		return "no revisions"
+	case "1":
+		return "one revision"
+	default:
+		return revisions + " revisions"
+	}
+}
+
+// spoken strips Markdown markup that would be read aloud and joins lines.
+func spoken(text string) string {
+	text = strings.NewReplacer("**", "", "__", "", "`", "", "#", "").Replace(text)
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// sentence ends text with a full stop unless it already ends a sentence.
+func sentence(text string) string {
+	if strings.HasSuffix(text, ".") || strings.HasSuffix(text, "!") || strings.HasSuffix(text, "?") {
+		return text
+	}
+	return text + "."
+}
@@ -3,6 +3,7 @@ package agent
 import (
 	"context"
 	"fmt"
+	"slices"
 	"sync/atomic"
 
 	"github.com/google/uuid"
@@ -18,9 +19,9 @@ type DirectorAgent struct {
 }
 
 // NewDirectorAgent creates a new Director agent.
-func NewDirectorAgent(id string, config *types.AgentConfig) *DirectorAgent {
+func NewDirectorAgent(id string, config *types.AgentConfig, opts ...Option) *DirectorAgent {
 	return &DirectorAgent{
-		BaseAgent: NewBaseAgent(id, types.RoleDirector, config),
+		BaseAgent: NewBaseAgent(id, types.RoleDirector, config, opts...),
 		managers:  make([]types.Agent, 0),
 	}
 }
@@ -35,50 +36,130 @@ func (a *DirectorAgent) AddManager(manager types.Agent) {
 	a.managers = append(a.managers, manager)
 }
 
+// RemoveManager stops delegating tasks to the manager with the given ID.
+func (a *DirectorAgent) RemoveManager(id string) {
+	a.managers = slices.DeleteFunc(a.managers, func(agent types.Agent) bool { return agent.GetID() == id })
+}
+
 // ProcessTask handles incoming tasks for the Director.
 func (a *DirectorAgent) ProcessTask(ctx context.Context, task *types.Task) (*types.TaskResponse, error) {
 	a.IncrementActiveTasks()
 	defer a.DecrementActiveTasks()
 
+	ctx, cancel := a.taskContext(ctx, task)
+	defer cancel()
+	ctx = a.openBlackboard(ctx)
+
 	result := fmt.Sprintf("Director %s processing task: %s\n", a.GetID(), task.Title)
 	result += "Performing research and expanding requirements...\n"
 	result += "Decomposing project into department-level tasks...\n"
 
-	// If we have managers, delegate to them using round-robin
+	// If we have managers, delegate to them using round-robin, or split the
+	// task between them by specialty when partitioning is enabled
+	var (
+		recovery string
+		child    *types.TaskResponse
+	)
 	if len(a.managers) > 0 {
 		result += fmt.Sprintf("Delegating to %d Manager(s)...\n", len(a.managers))
 
-		// Round-robin selection
-		idx := atomic.AddUint32(&a.nextManagerIdx, 1) - 1
-		manager := a.managers[int(idx)%len(a.managers)]
-
-		managerTask := &types.Task{
-			ID:          uuid.New().String(),
-			Title:       "Manager: " + task.Title,
-			Description: task.Description,
-			FromAgent:   a.GetID(),
-			ToAgent:     manager.GetID(),
-			Content:     task.Content,
-			Priority:    task.Priority,
+		// A routing rule for the task's labels sends it whole to one manager
+		routed, reason, isRouted := a.routeByLabel(a.managers, task)
+		var parts []part
+		if isRouted {
+			result += reason + "\n"
+		} else {
+			parts = a.partition(ctx, task)
+		}
+		if len(parts) > 1 {
+			result += fmt.Sprintf("Partitioned into %d part(s) by specialty:\n", len(parts))
+			for _, p := range parts {
+				result += fmt.Sprintf("- %s -> %s\n", p.Title, a.managers[p.manager].GetID())
+			}
+			if mem := a.GetMemory(); mem != nil {
+				_ = mem.StoreDecision(ctx, fmt.Sprintf("Partitioned %s into %d parts", task.Title, len(parts)), result, []string{"delegation", "partitioning"})
+			}
 		}
 
-		response, err := manager.ProcessTask(ctx, managerTask)
+		var (
+			response *types.TaskResponse
+			steps    []RecoveryStep
+			err      error
+		)
+		if len(parts) > 1 {
+			response, steps, err = a.delegateParts(ctx, task, parts)
+		} else {
+			var idx int
+			switch {
+			case isRouted:
+				idx = routed
+			case len(parts) == 1:
+				idx = parts[0].manager
+			default:
+				idx = a.nextManager()
+			}
+			response, steps, err = a.delegate(ctx, delegation{
+				parent:       task,
+				subtask:      a.managerTask(ctx, task, a.managers[idx], task.Title, task.Content),
+				subordinates: a.managers,
+				index:        idx,
+				label:        "manager",
+				handler:      a,
+			})
+		}
 		if err != nil {
-			return nil, fmt.Errorf("failed to delegate to manager: %w", err)
+			return nil, err
+		}
+
+		if len(steps) > 0 {
+			recovery = formatRecovery(steps)
+			result += fmt.Sprintf("Recovered after %d re-plan(s): %s\n", len(steps), recovery)
 		}
 
-		if response.Status == types.StatusFailed {
-			return nil, fmt.Errorf("manager task failed: %s", response.Error)
+		if response.Status == types.StatusPartiallyCompleted {
+			result += fmt.Sprintf("Delivering partial results under the %s policy:\n", a.partialFailure.Mode)
+			for _, s := range response.Subtasks {
+				if s.Status == types.StatusFailed {
+					result += fmt.Sprintf("- %s failed: %s\n", s.Title, s.Error)
+				}
+			}
 		}
 
-		result += fmt.Sprintf("Manager response: %s\n", response.Result)
+		child = response
+		result += a.subordinateReport("Manager", response)
 	} else {
 		result += "No managers available. Task completed at Director level.\n"
 	}
 
-	return &types.TaskResponse{
-		TaskID: task.ID,
-		Status: types.StatusCompleted,
-		Result: result,
-	}, nil
+	resp := recoveredResponse(task, result, recovery)
+	carryFiles(resp, child)
+	carryOutcome(resp, child)
+	a.reportStatus(ctx, nil, task, resp, child, result)
+	return resp, nil
+}
+
+// nextManager returns the index of the next manager in round-robin order.
+func (a *DirectorAgent) nextManager() int {
+	return int(atomic.AddUint32(&a.nextManagerIdx, 1)-1) % len(a.managers)
+}
+
+// managerTask creates the task delegated to a manager for the given content.
+// Content that is a part of the task is available to delegation templates as
+// the director's output.
+func (a *DirectorAgent) managerTask(ctx context.Context, task *types.Task, manager types.Agent, title, content string) *types.Task {
+	var output string
+	if content != task.Content {
+		output = content
+	}
+	return &types.Task{
+		ID:          uuid.New().String(),
+		Title:       "Manager: " + title,
+		Description: task.Description,
+		FromAgent:   a.GetID(),
+		ToAgent:     manager.GetID(),
+		Content:     a.delegationContent(ctx, task, output, content),
+		Metadata:    a.delegationMetadata(task),
+		RunID:       task.RunID,
+		Priority:    task.Priority,
+	}
 }
@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/kpango/BuildBureau/internal/document"
+	"github.com/kpango/BuildBureau/internal/errors"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// MetadataDocuments names the documents attached to a client task.
+const MetadataDocuments = "documents"
+
+type documentsKey struct{}
+
+// WithDocuments returns a context whose project submission has the given
+// documents attached: PDF, Word (DOCX), Excel (XLSX), HTML, Markdown or
+// text files whose content is added to the instruction.
+func WithDocuments(ctx context.Context, paths ...string) context.Context {
+	return context.WithValue(ctx, documentsKey{}, append(slices.Clip(Documents(ctx)), paths...))
+}
+
+// Documents returns the paths of the documents attached in ctx.
+func Documents(ctx context.Context) []string {
+	paths, _ := ctx.Value(documentsKey{}).([]string)
+	return paths
+}
+
+// readDocuments extracts the text of the documents attached in ctx within
+// the configured limits.
+func (o *Organization) readDocuments(ctx context.Context) ([]*document.Document, error) {
+	paths := Documents(ctx)
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	cfg := cmp.Or(o.config.Documents, &types.DocumentsConfig{})
+	extractor := document.New(
+		document.WithPDFToText(cfg.PDFToText),
+		document.WithLimits(document.Limits{
+			MaxPages: cmp.Or(cfg.MaxPages, document.DefaultMaxPages),
+			MaxRows:  cmp.Or(cfg.MaxRows, document.DefaultMaxRows),
+			MaxChars: cmp.Or(cfg.MaxChars, document.DefaultMaxChars),
+		}),
+	)
+
+	docs := make([]*document.Document, 0, len(paths))
+	for _, path := range paths {
+		doc, err := extractor.Extract(ctx, path)
+		if err != nil {
+			return nil, errors.Wrap(err, errors.CodeInvalidArgument, "failed to read attached document")
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// attachDocuments adds the text of documents to a request and names them in
+// the task's metadata.
+func attachDocuments(task *types.Task, docs []*document.Document) {
+	if len(docs) == 0 {
+		return
+	}
+
+	names := make([]string, len(docs))
+	var b strings.Builder
+	b.WriteString(task.Content)
+	for i, doc := range docs {
+		names[i] = filepath.Base(doc.Path)
+		b.WriteString("\n\n" + doc.Prompt())
+	}
+	task.Content = b.String()
+	task.Metadata[MetadataDocuments] = strings.Join(names, ", ")
+}
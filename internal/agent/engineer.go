@@ -4,10 +4,26 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/kpango/BuildBureau/internal/errors"
 	"github.com/kpango/BuildBureau/internal/llm"
 	"github.com/kpango/BuildBureau/pkg/types"
 )
 
+// implementationPrompt asks the LLM to implement a task. The last argument is context from memory.
+const implementationPrompt = `You are a software engineer tasked with implementing the following:
+
+Title: %s
+Description: %s
+Specifications: %s
+%s
+Please provide:
+1. A detailed implementation plan
+2. Code implementation (if applicable)
+3. Test cases
+4. Documentation
+
+Be specific and provide working code. Learn from the past implementations provided above if available.`
+
 // EngineerAgent represents an engineer agent that implements code using LLM.
 type EngineerAgent struct {
 	*BaseAgent
@@ -15,78 +31,104 @@ type EngineerAgent struct {
 }
 
 // NewEngineerAgent creates a new Engineer agent.
-func NewEngineerAgent(id string, config *types.AgentConfig, llmManager *llm.Manager) *EngineerAgent {
+func NewEngineerAgent(id string, config *types.AgentConfig, llmManager *llm.Manager, opts ...Option) *EngineerAgent {
 	return &EngineerAgent{
-		BaseAgent:  NewBaseAgent(id, types.RoleEngineer, config),
+		BaseAgent:  NewBaseAgent(id, types.RoleEngineer, config, opts...),
 		llmManager: llmManager,
 	}
 }
 
 // ProcessTask handles incoming tasks for the Engineer using LLM and memory.
+// If a sibling engineer is working on, or recently finished, a nearly identical
+// task, its result is reused instead.
 func (a *EngineerAgent) ProcessTask(ctx context.Context, task *types.Task) (*types.TaskResponse, error) {
 	a.IncrementActiveTasks()
 	defer a.DecrementActiveTasks()
 
+	ctx, cancel := a.taskContext(ctx, task)
+	defer cancel()
+
+	if a.dedup == nil {
+		return a.implement(ctx, task)
+	}
+
+	resp, shared, err := a.dedup.Do(ctx, a.GetID(), task, func() (*types.TaskResponse, error) {
+		return a.implement(ctx, task)
+	})
+	if shared {
+		a.logf("reused result of task %s for duplicate task %s, saving ~%d tokens", resp.Metadata[MetadataDeduplicatedFrom], task.ID, len(resp.Result)/4)
+	}
+	return resp, err
+}
+
+// implement produces the implementation for a task.
+func (a *EngineerAgent) implement(ctx context.Context, task *types.Task) (*types.TaskResponse, error) {
 	// Store conversation memory
 	if mem := a.GetMemory(); mem != nil {
 		_ = mem.StoreConversation(ctx, fmt.Sprintf("Received implementation task: %s", task.Title), []string{"engineer", "implementation"})
 	}
 
 	result := fmt.Sprintf("Engineer %s implementing task: %s\n", a.GetID(), task.Title)
-
-	// Check memory for similar past implementations
-	var contextFromMemory string
-	if mem := a.GetMemory(); mem != nil {
-		relatedTasks, err := mem.GetRelatedTasks(ctx, task.Description, 3)
-		if err == nil && len(relatedTasks) > 0 {
-			result += fmt.Sprintf("Found %d related past implementation(s) to learn from.\n", len(relatedTasks))
-			contextFromMemory = "\n\n=== Context from Past Implementations ===\n"
-			for i, memory := range relatedTasks {
-				contextFromMemory += fmt.Sprintf("\nPast Implementation %d (Score: %.2f):\n%s\n", i+1, memory.Score, memory.Content)
-			}
-			contextFromMemory += "=== End of Past Context ===\n\n"
-		}
-
-		// Check for relevant knowledge
-		knowledge, err := mem.GetKnowledge(ctx, task.Description, 2)
-		if err == nil && len(knowledge) > 0 {
-			contextFromMemory += "\n=== Relevant Knowledge ===\n"
-			for _, k := range knowledge {
-				contextFromMemory += fmt.Sprintf("%s\n", k.Content)
-			}
-			contextFromMemory += "=== End of Knowledge ===\n\n"
-		}
-	}
+	deliverable := task.Content
+	var files []types.FileArtifact
 
 	// Use LLM if available to generate actual implementation
 	if a.llmManager != nil {
-		prompt := fmt.Sprintf(`You are a software engineer tasked with implementing the following:
-
-Title: %s
-Description: %s
-Specifications: %s
-%s
-Please provide:
-1. A detailed implementation plan
-2. Code implementation (if applicable)
-3. Test cases
-4. Documentation
-
-Be specific and provide working code. Learn from the past implementations provided above if available.`,
-			task.Title, task.Description, task.Content, contextFromMemory)
-
+		kind := llm.ClassifyOutput(task.Title + " " + task.Description)
 		llmOpts := &llm.GenerateOptions{
 			Temperature:  0.7,
-			MaxTokens:    4096,
+			MaxTokens:    a.llmManager.OutputTokens(kind),
 			SystemPrompt: a.config.SystemPrompt,
 		}
 
-		model := a.config.Model
-		if model == "" {
-			model = "gemini" // default
+		// Code is delivered as file blocks, so it can be assembled across
+		// generations. Revisions of files patch them instead.
+		var instruction string
+		if kind == llm.OutputCode && len(task.Files) == 0 {
+			instruction = fileInstruction
 		}
 
-		response, err := a.llmManager.Generate(ctx, model, prompt, llmOpts)
+		// Past work, decisions and knowledge fill what the rest of the prompt leaves of the window
+		contextFromMemory := a.profileContext() + a.sharedKnowledgeContext(ctx, task) + a.blackboardContext(ctx)
+		base := fmt.Sprintf(implementationPrompt, task.Title, task.Description, task.Content, "") + instruction + a.imageInstruction() + labelContext(task) + a.conventionsContext(task)
+		if mem := a.GetMemory(); mem != nil {
+			past, err := a.pastContext(ctx, mem, task, a.memoryBudget(a.llmManager, base+contextFromMemory, llmOpts))
+			if err == nil && past != "" {
+				result += "Found related past implementations to learn from.\n"
+				contextFromMemory += past
+			}
+		}
+		contextFromMemory = a.fitMemoryContext(ctx, a.llmManager, contextFromMemory, base, llmOpts)
+		prompt := fmt.Sprintf(implementationPrompt, task.Title, task.Description, task.Content, contextFromMemory) + instruction + a.imageInstruction() + labelContext(task) + a.conventionsContext(task)
+
+		response, err := a.generateWithQuestions(ctx, a.llmManager, task, prompt, llmOpts)
+		response = a.applyBlackboard(ctx, response)
+		switch {
+		case err != nil: // Handled below
+		case len(task.Files) > 0:
+			response, files, err = a.revise(ctx, task, prompt, response)
+		case instruction != "":
+			response, files, err = a.assembleFiles(ctx, task, prompt, llmOpts, response)
+		}
+		if err == nil && len(files) == 0 {
+			// Code blocks that name their file are delivered as files too
+			response, files = a.materializeFences(ctx, task, prompt, response)
+		}
+		if err == nil {
+			var images []types.FileArtifact
+			response, images = a.generateImages(ctx, task, response)
+			files = mergeFiles(files, images)
+		}
+		a.learn(task, response, err)
+		if errors.Is(err, errUnanswered) || errors.Is(err, errMissingFiles) || errors.Is(err, errPatchFailed) {
+			// The configured timeout decision is to fail rather than guess, and
+			// incomplete or unappliable files are not a deliverable
+			return nil, err
+		}
+		if errors.CodeOf(err) == errors.CodeCanceled {
+			// An aborted generation fails the task rather than passing for a result
+			return nil, err
+		}
 		if err != nil {
 			result += fmt.Sprintf("Error using LLM: %v\n", err)
 			result += "Falling back to simple acknowledgment.\n"
@@ -96,6 +138,7 @@ Be specific and provide working code. Learn from the past implementations provid
 			result += "=== LLM-Generated Implementation ===\n"
 			result += response
 			result += "\n=== End of Implementation ===\n"
+			deliverable = response
 
 			// Store the generated code as knowledge
 			if mem := a.GetMemory(); mem != nil {
@@ -114,9 +157,12 @@ Be specific and provide working code. Learn from the past implementations provid
 		_ = mem.StoreTask(ctx, task, result, []string{"engineer", "implementation", "completed"})
 	}
 
-	return &types.TaskResponse{
+	resp := &types.TaskResponse{
 		TaskID: task.ID,
 		Status: types.StatusCompleted,
 		Result: result,
-	}, nil
+		Files:  files,
+	}
+	a.reportStatus(ctx, a.llmManager, task, resp, nil, deliverable)
+	return resp, nil
 }
@@ -0,0 +1,386 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kpango/BuildBureau/internal/errors"
+	"github.com/kpango/BuildBureau/internal/llm"
+	"github.com/kpango/BuildBureau/internal/report"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// KindUsage marks a memory recording what the agents of one role used in a
+// finished project, described by the usage metadata keys.
+const (
+	KindUsage = "usage"
+
+	MetadataRole   = "role"
+	MetadataTasks  = "tasks"
+	MetadataTokens = "tokens"
+	MetadataCost   = "cost"
+	MetadataTime   = "time"
+)
+
+// usageHistoryEntries is how many role usage memories estimates draw on.
+const usageHistoryEntries = 500
+
+// estimateRoles is the order roles appear in an estimate.
+var estimateRoles = []types.AgentRole{
+	types.RolePresident,
+	types.RoleSecretary,
+	types.RoleDirector,
+	types.RoleManager,
+	types.RoleEngineer,
+	types.RoleClient,
+}
+
+// Range is the span an estimated quantity falls in.
+type Range[T int | float64 | time.Duration] struct {
+	Low  T `json:"low"`
+	High T `json:"high"`
+}
+
+// add returns the sum of two ranges.
+func (r Range[T]) add(o Range[T]) Range[T] {
+	return Range[T]{Low: r.Low + o.Low, High: r.High + o.High}
+}
+
+// RoleEstimate is the projected usage of the agents of one role. Time is
+// the agent time of the role's own work, zero when there is no history.
+type RoleEstimate struct {
+	Role   types.AgentRole      `json:"role"`
+	Tokens Range[int]           `json:"tokens"`
+	Cost   Range[float64]       `json:"cost"` // In USD
+	Time   Range[time.Duration] `json:"time"`
+	Tasks  int                  `json:"tasks"`
+}
+
+// PlannedPart is a part of the project the plan gives to one manager.
+type PlannedPart struct {
+	Title      string `json:"title"`
+	Manager    string `json:"manager"`
+	Complexity string `json:"complexity"`  // Of the implementation the manager's design asks for
+	PairRounds int    `json:"pair_rounds"` // Review rounds the implementation gets
+}
+
+// Estimate is the projected usage of a project, drawn up from its plan
+// before it runs.
+type Estimate struct {
+	Planning *types.TaskUsage `json:"planning"` // What drawing up the plan used
+	Parts    []PlannedPart    `json:"parts"`
+	Roles    []RoleEstimate   `json:"roles"`
+	Total    RoleEstimate     `json:"total"`
+	Projects int              `json:"projects"` // Past projects the estimate draws on; 0 means none were recorded
+}
+
+// roleUsage is what the tasks of one role used in one project.
+type roleUsage struct {
+	tasks  int
+	tokens int
+	cost   float64
+	time   time.Duration
+}
+
+// perTask returns the usage of an average task.
+func (u roleUsage) perTask() roleUsage {
+	if u.tasks == 0 {
+		return roleUsage{}
+	}
+	n := u.tasks
+	return roleUsage{tasks: 1, tokens: u.tokens / n, cost: u.cost / float64(n), time: u.time / time.Duration(n)}
+}
+
+// Estimate projects what a project with the instruction would use, without
+// running it. Only the planning layers work: the director partitions the
+// request and each manager drafts its design, which tells how complex the
+// implementation is and how many review rounds it gets. Per-task usage of
+// each role in past projects, recorded when they finished, is scaled to the
+// planned tasks. Roles without history are bracketed between their prompts
+// and their prompts plus output budgets, and their time is left unknown.
+func (o *Organization) Estimate(ctx context.Context, instruction string) (*Estimate, error) {
+	if o.president == nil {
+		return nil, errors.New(errors.CodeAgentUnavailable, "no president agent available")
+	}
+
+	ctx, usage := startUsage(ctx, organizationLog)
+	task := &types.Task{
+		ID:          uuid.New().String(),
+		Title:       "Client Request",
+		Description: instruction,
+		FromAgent:   "client",
+		ToAgent:     o.president.GetID(),
+		Content:     instruction,
+		RunID:       uuid.New().String(),
+		Priority:    1,
+		Metadata:    make(map[string]string),
+	}
+
+	est := &Estimate{}
+	planned := map[types.AgentRole]int{types.RolePresident: 1}
+	if _, ok := o.secretaries["President"]; ok {
+		planned[types.RoleSecretary] = 1
+	}
+	if o.client != nil {
+		planned[types.RoleClient] = 1
+	}
+	specTokens := 0
+	for _, p := range o.plan(ctx, task) {
+		planned[types.RoleDirector] = 1
+		planned[types.RoleManager]++
+		est.Parts = append(est.Parts, p.PlannedPart)
+		if p.implemented {
+			planned[types.RoleEngineer] += 1 + p.PairRounds
+			specTokens += llm.EstimateTokens(p.spec) * (1 + p.PairRounds)
+		}
+	}
+	est.Planning = usage.finish()
+
+	history, projects, err := o.usageHistory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	est.Projects = projects
+
+	for _, role := range estimateRoles {
+		tasks := planned[role]
+		if tasks == 0 {
+			continue
+		}
+		r := RoleEstimate{Role: role, Tasks: tasks}
+		if past := history[role]; len(past) > 0 {
+			low, high := past[0].perTask(), past[0].perTask()
+			for _, u := range past[1:] {
+				t := u.perTask()
+				low = roleUsage{tokens: min(low.tokens, t.tokens), cost: min(low.cost, t.cost), time: min(low.time, t.time)}
+				high = roleUsage{tokens: max(high.tokens, t.tokens), cost: max(high.cost, t.cost), time: max(high.time, t.time)}
+			}
+			r.Tokens = Range[int]{Low: low.tokens * tasks, High: high.tokens * tasks}
+			r.Cost = Range[float64]{Low: low.cost * float64(tasks), High: high.cost * float64(tasks)}
+			r.Time = Range[time.Duration]{Low: low.time * time.Duration(tasks), High: high.time * time.Duration(tasks)}
+		} else {
+			prompt := llm.EstimateTokens(instruction) * tasks
+			if role == types.RoleEngineer {
+				prompt = specTokens
+			}
+			r.Tokens, r.Cost = o.budgetRange(role, tasks, prompt, est.Planning)
+		}
+		est.Roles = append(est.Roles, r)
+
+		est.Total.Tasks += r.Tasks
+		est.Total.Tokens = est.Total.Tokens.add(r.Tokens)
+		est.Total.Cost = est.Total.Cost.add(r.Cost)
+		est.Total.Time = est.Total.Time.add(r.Time)
+	}
+	return est, nil
+}
+
+// plannedPart is a part of the plan with the design drafted for it.
+type plannedPart struct {
+	PlannedPart
+	spec        string
+	implemented bool // The manager has engineers to implement the design
+}
+
+// plan has the first director partition the task and the manager of each
+// part draft its design, without delegating any further.
+func (o *Organization) plan(ctx context.Context, task *types.Task) []plannedPart {
+	var director *DirectorAgent
+	for _, d := range o.directors {
+		if d, ok := d.(*DirectorAgent); ok && len(d.managers) > 0 {
+			director = d
+			break
+		}
+	}
+	if director == nil {
+		return nil
+	}
+
+	parts := director.partition(ctx, task)
+	if len(parts) == 0 {
+		idx := 0
+		if routed, _, ok := director.routeByLabel(director.managers, task); ok {
+			idx = routed
+		}
+		parts = []part{{Title: task.Title, Content: task.Content, manager: idx}}
+	}
+
+	planned := make([]plannedPart, 0, len(parts))
+	for _, p := range parts {
+		manager, ok := director.managers[p.manager].(*ManagerAgent)
+		if !ok {
+			continue
+		}
+		subtask := &types.Task{
+			ID:          uuid.New().String(),
+			Title:       p.Title,
+			Description: task.Description,
+			Content:     p.Content,
+			Metadata:    task.Metadata,
+			RunID:       task.RunID,
+		}
+		spec := manager.draftDesign(ctx, subtask)
+		implementation := &types.Task{Content: spec, Metadata: task.Metadata}
+		rounds := 0
+		if len(manager.engineers) >= 2 {
+			rounds = manager.pairRounds(implementation)
+		}
+		planned = append(planned, plannedPart{
+			PlannedPart: PlannedPart{
+				Title:      p.Title,
+				Manager:    manager.GetID(),
+				Complexity: taskComplexity(implementation),
+				PairRounds: rounds,
+			},
+			spec:        spec,
+			implemented: len(manager.engineers) > 0,
+		})
+	}
+	return planned
+}
+
+// draftDesign returns the design the manager would hand its engineers for
+// the task, or the task's content without an LLM. Unlike ProcessTask, it
+// neither consults nor records memories.
+func (a *ManagerAgent) draftDesign(ctx context.Context, task *types.Task) string {
+	if a.llmManager == nil {
+		return task.Content
+	}
+	prompt := fmt.Sprintf(designPrompt, task.Title, task.Description, task.Content, "") + labelContext(task) + a.conventionsContext(task)
+	spec, err := a.generate(ctx, a.llmManager, prompt, &llm.GenerateOptions{
+		Temperature:  0.5,
+		MaxTokens:    a.llmManager.OutputTokens(llm.OutputSpec),
+		SystemPrompt: a.config.SystemPrompt,
+	})
+	if err != nil {
+		a.logf("drafting the design of %s for an estimate failed: %v", task.Title, err)
+		return task.Content
+	}
+	return spec
+}
+
+// budgetRange brackets the usage of a role without history: from its
+// prompts alone up to its prompts plus the output budget of each task.
+// Managers are expected to use what drafting the plan used, and roles that
+// do not generate, nothing.
+func (o *Organization) budgetRange(role types.AgentRole, tasks, prompt int, planning *types.TaskUsage) (Range[int], Range[float64]) {
+	var kind llm.OutputKind
+	switch role {
+	case types.RoleManager:
+		tokens := planning.PromptTokens + planning.CompletionTokens
+		return Range[int]{Low: tokens, High: tokens}, Range[float64]{Low: planning.Cost, High: planning.Cost}
+	case types.RoleEngineer:
+		kind = llm.OutputCode
+	case types.RoleClient:
+		kind = llm.OutputSummary
+	default:
+		return Range[int]{}, Range[float64]{}
+	}
+	if o.llmManager == nil {
+		return Range[int]{}, Range[float64]{}
+	}
+
+	output := o.llmManager.OutputTokens(kind) * tasks
+	model := ""
+	for _, a := range o.allAgents() {
+		if m, ok := a.(interface{ Model() string }); ok && a.GetRole() == role {
+			model = m.Model()
+			break
+		}
+	}
+	return Range[int]{Low: prompt, High: prompt + output},
+		Range[float64]{Low: o.llmManager.Cost(model, prompt, 0), High: o.llmManager.Cost(model, prompt, output)}
+}
+
+// recordUsage stores what each role used in a finished project, for later
+// estimates. The usage of each task excludes that of the subtasks it
+// delegated, and its time excludes theirs.
+func (o *Organization) recordUsage(ctx context.Context, runID string, usage *types.TaskUsage) {
+	if o.memoryManager == nil || usage == nil {
+		return
+	}
+
+	roles := make(map[string]types.AgentRole)
+	for _, a := range o.allAgents() {
+		roles[a.GetID()] = a.GetRole()
+	}
+	byRole := make(map[types.AgentRole]roleUsage)
+	var walk func(u *types.TaskUsage)
+	walk = func(u *types.TaskUsage) {
+		own := roleUsage{tasks: 1, tokens: u.PromptTokens + u.CompletionTokens, cost: u.Cost, time: u.Latency}
+		for _, s := range u.Subtasks {
+			own.tokens -= s.PromptTokens + s.CompletionTokens
+			own.cost -= s.Cost
+			own.time -= s.Latency
+			walk(s)
+		}
+		role, ok := roles[u.AgentID]
+		if !ok {
+			return // The organization's own reviews are not a role's
+		}
+		total := byRole[role]
+		byRole[role] = roleUsage{
+			tasks:  total.tasks + 1,
+			tokens: total.tokens + max(own.tokens, 0),
+			cost:   total.cost + max(own.cost, 0),
+			time:   total.time + max(own.time, 0),
+		}
+	}
+	walk(usage)
+
+	for role, u := range byRole {
+		entry := &types.MemoryEntry{
+			AgentID: organizationLog,
+			RunID:   runID,
+			Type:    types.MemoryTypeContext,
+			Content: fmt.Sprintf("%s used %d tokens in %d task(s)", role, u.tokens, u.tasks),
+			Tags:    []string{"usage"},
+			Metadata: map[string]string{
+				report.MetadataKind: KindUsage,
+				MetadataRole:        string(role),
+				MetadataTasks:       strconv.Itoa(u.tasks),
+				MetadataTokens:      strconv.Itoa(u.tokens),
+				MetadataCost:        strconv.FormatFloat(u.cost, 'f', -1, 64),
+				MetadataTime:        u.time.String(),
+			},
+		}
+		if err := o.memoryManager.StoreMemory(context.WithoutCancel(ctx), entry); err != nil {
+			fmt.Printf("Warning: failed to store project usage: %v\n", err)
+		}
+	}
+}
+
+// usageHistory returns what each role used in the most recently recorded
+// projects, and how many projects that is.
+func (o *Organization) usageHistory(ctx context.Context) (map[types.AgentRole][]roleUsage, int, error) {
+	if o.memoryManager == nil {
+		return nil, 0, nil
+	}
+	entries, err := o.memoryManager.QueryMemories(ctx, &types.MemoryQuery{
+		AgentID:  organizationLog,
+		Metadata: map[string]string{report.MetadataKind: KindUsage},
+		Limit:    usageHistoryEntries,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read usage history: %w", err)
+	}
+
+	history := make(map[types.AgentRole][]roleUsage)
+	runs := make(map[string]bool)
+	for _, e := range entries {
+		tasks, _ := strconv.Atoi(e.Metadata[MetadataTasks])
+		tokens, _ := strconv.Atoi(e.Metadata[MetadataTokens])
+		cost, _ := strconv.ParseFloat(e.Metadata[MetadataCost], 64)
+		elapsed, _ := time.ParseDuration(e.Metadata[MetadataTime])
+		if tasks <= 0 {
+			continue
+		}
+		role := types.AgentRole(e.Metadata[MetadataRole])
+		history[role] = append(history[role], roleUsage{tasks: tasks, tokens: tokens, cost: cost, time: elapsed})
+		runs[e.RunID] = true
+	}
+	return history, len(runs), nil
+}
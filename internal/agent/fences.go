@@ -0,0 +1,267 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// fileHintPrefixes introduce a file name in a fence's info string, on the
+// line above a fence or in a comment on its first line.
+var fileHintPrefixes = []string{"title=", "file=", "filename=", "path=", "file:", "filename:", "path:"}
+
+// fileNames are file names without an extension that are still recognized as paths.
+var fileNames = []string{"Makefile", "Dockerfile", "Containerfile", "Procfile", "Gemfile", "Rakefile", "Jenkinsfile"}
+
+// fence is a fenced code block being read.
+type fence struct {
+	marker  string // The run of backticks or tildes that opened it
+	opening string // The opening line, restored if the block is never closed
+	heading string // The line above naming the file, restored likewise
+	path    string
+	content strings.Builder
+}
+
+// extractFences turns the fenced code blocks of a response that name their
+// file into file artifacts. A block names its file in its info string, as in
+// ```go title=main.go, on the line above it, such as **main.go**, or in a
+// comment on its first line, such as // main.go. Each extracted block is
+// replaced with a reference to its artifact, noting a syntax error if the
+// file does not parse. Blocks that name no file are left in place.
+func extractFences(response string) (string, []types.FileArtifact) {
+	var (
+		prose []string
+		files []types.FileArtifact
+		open  *fence
+	)
+	for line := range strings.Lines(response) {
+		trimmed := strings.TrimSpace(line)
+		if open == nil {
+			marker, info, ok := fenceOpening(trimmed)
+			if !ok {
+				prose = append(prose, line)
+				continue
+			}
+			open = &fence{marker: marker, opening: line, path: infoPath(info)}
+			if open.path == "" {
+				// The line above names the file; it is replaced by the reference
+				if i := lastNonBlank(prose); i >= 0 {
+					if path := headingPath(prose[i]); path != "" {
+						open.path, open.heading = path, strings.Join(prose[i:], "")
+						prose = prose[:i]
+					}
+				}
+			}
+			continue
+		}
+
+		if !strings.HasPrefix(trimmed, open.marker) || strings.Trim(trimmed, open.marker[:1]) != "" {
+			if open.path == "" && open.content.Len() == 0 {
+				open.path = commentPath(trimmed)
+			}
+			open.content.WriteString(line)
+			continue
+		}
+
+		// The block is closed
+		content := open.content.String()
+		if open.path == "" {
+			prose = append(prose, open.opening, content, line)
+		} else {
+			file := fileArtifact(open.path, content)
+			files = mergeFiles(files, []types.FileArtifact{file})
+			prose = append(prose, artifactReference(file)+"\n")
+		}
+		open = nil
+	}
+	if open != nil {
+		// A block cut off mid-file is left as it was
+		prose = append(prose, open.heading, open.opening, open.content.String())
+	}
+	if len(files) == 0 {
+		return response, nil
+	}
+	return strings.TrimSpace(strings.Join(prose, "")), files
+}
+
+// fenceOpening reports whether line opens a fenced code block, returning its
+// marker and info string.
+func fenceOpening(line string) (string, string, bool) {
+	for _, c := range []string{"`", "~"} {
+		n := len(line) - len(strings.TrimLeft(line, c))
+		if n >= 3 {
+			info := strings.TrimSpace(line[n:])
+			if c == "`" && strings.Contains(info, "`") {
+				return "", "", false // Inline code, not a fence
+			}
+			return line[:n], info, true
+		}
+	}
+	return "", "", false
+}
+
+// infoPath returns the file named in a fence's info string, such as
+// "go title=main.go", "go:main.go" or "main.go", or "" if it names none.
+func infoPath(info string) string {
+	for field := range strings.FieldsSeq(info) {
+		for _, prefix := range fileHintPrefixes {
+			if v, ok := strings.CutPrefix(field, prefix); ok {
+				field = v
+				break
+			}
+		}
+		if _, after, ok := strings.Cut(field, ":"); ok {
+			field = after // language:path
+		}
+		if path := filePath(field); path != "" {
+			return path
+		}
+	}
+	return ""
+}
+
+// headingPath returns the file named by a line introducing a code block, such
+// as "**main.go**", "### main.go", "`main.go`:" or "File: main.go".
+func headingPath(line string) string {
+	line = strings.Trim(strings.TrimSpace(line), "#*_: ")
+	for _, prefix := range []string{"File:", "file:", "Filename:", "filename:", "Path:", "path:"} {
+		if v, ok := strings.CutPrefix(line, prefix); ok {
+			line = strings.Trim(strings.TrimSpace(v), "*_: ")
+			break
+		}
+	}
+	if strings.ContainsAny(line, " \t") {
+		return ""
+	}
+	return filePath(line)
+}
+
+// commentPath returns the file named by a comment on the first line of a code
+// block, such as "// main.go", "# file: app.py" or "<!-- index.html -->".
+func commentPath(line string) string {
+	for _, c := range []string{"//", "#", "--", ";", "/*", "<!--"} {
+		if rest, ok := strings.CutPrefix(line, c); ok {
+			rest = strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(rest, "-->"), "*/"))
+			for _, prefix := range fileHintPrefixes {
+				if v, ok := strings.CutPrefix(strings.ToLower(rest), prefix); ok {
+					rest = strings.TrimSpace(rest[len(rest)-len(v):])
+					break
+				}
+			}
+			if strings.ContainsAny(rest, " \t") {
+				return ""
+			}
+			return filePath(rest)
+		}
+	}
+	return ""
+}
+
+// filePath returns s cleaned if it looks like a relative file path: a name
+// with an extension or a well-known file name, within the deliverable.
+func filePath(s string) string {
+	s = strings.Trim(s, "`'\"")
+	if s == "" || strings.ContainsAny(s, "\\<>|?*") {
+		return ""
+	}
+	s = strings.TrimPrefix(filepath.ToSlash(filepath.Clean(s)), "./")
+	if !filepath.IsLocal(s) {
+		return ""
+	}
+	base := filepath.Base(s)
+	if slices.Contains(fileNames, base) || isExtension(filepath.Ext(base)) {
+		return s
+	}
+	return ""
+}
+
+// isExtension reports whether ext is a plausible file extension: a dot and a
+// few letters or digits, at least one a letter, unlike the end of "1.5".
+func isExtension(ext string) bool {
+	if len(ext) < 2 || len(ext) > 10 {
+		return false
+	}
+	letter := false
+	for _, r := range ext[1:] {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+			letter = true
+		case r < '0' || r > '9':
+			return false
+		}
+	}
+	return letter
+}
+
+// lastNonBlank returns the index of the last line with text, or -1.
+func lastNonBlank(lines []string) int {
+	for i := len(lines) - 1; i >= 0; i-- {
+		if strings.TrimSpace(lines[i]) != "" {
+			return i
+		}
+	}
+	return -1
+}
+
+// artifactReference is the text standing in for an extracted code block.
+func artifactReference(file types.FileArtifact) string {
+	lines := strings.Count(file.Content, "\n")
+	if err := checkSyntax(file.Path, file.Content); err != nil {
+		return fmt.Sprintf("[artifact: %s, %d lines, syntax error: %v]", file.Path, lines, err)
+	}
+	return fmt.Sprintf("[artifact: %s, %d lines]", file.Path, lines)
+}
+
+// checkSyntax parses a file in the languages it can, Go, JSON and YAML, and
+// returns the first syntax error. Files in other languages are not checked.
+func checkSyntax(path, content string) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".go":
+		_, err := parser.ParseFile(token.NewFileSet(), path, content, parser.AllErrors)
+		if list, ok := err.(scanner.ErrorList); ok && len(list) > 0 {
+			return list[0]
+		}
+		return err
+	case ".json":
+		var v any
+		return json.Unmarshal([]byte(content), &v)
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader([]byte(content)))
+		for {
+			var v any
+			err := dec.Decode(&v)
+			if stderrors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// materializeFences has the code blocks of a response that name their file
+// delivered as files, as if the response had used file blocks.
+func (a *EngineerAgent) materializeFences(ctx context.Context, task *types.Task, prompt, response string) (string, []types.FileArtifact) {
+	prose, files := extractFences(response)
+	if len(files) == 0 {
+		return response, nil
+	}
+	a.logf("extracted %d file(s) from the code blocks of task %s: %s", len(files), task.ID, strings.Join(filePaths(files), ", "))
+	a.stamp(ctx, prompt, files)
+	return prose, files
+}
@@ -0,0 +1,202 @@
+package agent
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/kpango/BuildBureau/internal/errors"
+	"github.com/kpango/BuildBureau/internal/llm"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+const (
+	filePlanPrefix  = "FILES:"
+	fileStartPrefix = "=== FILE:"
+	fileStartSuffix = "==="
+	fileEnd         = "=== END FILE ==="
+
+	// maxFileRounds bounds the generations asking for files still missing
+	// after the first response.
+	maxFileRounds = 3
+)
+
+// fileInstruction asks the LLM to deliver code as file blocks.
+const fileInstruction = `
+
+Deliver the code as files. Start with one line listing every file you will write:
+FILES: path/to/first.go, path/to/first_test.go
+Then write each file in full as:
+=== FILE: path/to/first.go ===
+<file contents>
+=== END FILE ===
+Put the plan, test notes and documentation outside the file blocks.`
+
+// remainingFilesPrompt asks the LLM for the files its earlier responses left out.
+const remainingFilesPrompt = `%s
+
+Your previous response stopped before all files were written.
+Files already written: %s
+Write only these remaining files, each in full and in the same format: %s`
+
+// errMissingFiles is wrapped by the error of a task whose generation did not
+// produce every file it promised.
+var errMissingFiles = stderrors.New("promised files were not produced")
+
+// fileOutput is a response parsed into file blocks.
+type fileOutput struct {
+	prose   string   // Text outside the file blocks
+	planned []string // Paths listed on the FILES line
+	files   []types.FileArtifact
+	partial string // Path of a block left open, cut off mid-file
+}
+
+// parseFiles splits a response into its file plan, complete file blocks and
+// the text around them.
+func parseFiles(output string) fileOutput {
+	var (
+		out     fileOutput
+		prose   strings.Builder
+		content strings.Builder
+		path    string
+		open    bool
+	)
+	for line := range strings.Lines(output) {
+		trimmed := strings.TrimSpace(line)
+		if open {
+			if trimmed == fileEnd {
+				out.files = append(out.files, fileArtifact(path, content.String()))
+				content.Reset()
+				open = false
+				continue
+			}
+			content.WriteString(line)
+			continue
+		}
+
+		if list, ok := strings.CutPrefix(trimmed, filePlanPrefix); ok && out.planned == nil {
+			for p := range strings.SplitSeq(list, ",") {
+				if p = strings.Trim(strings.TrimSpace(p), "`"); p != "" {
+					out.planned = append(out.planned, p)
+				}
+			}
+			continue
+		}
+		if rest, ok := strings.CutPrefix(trimmed, fileStartPrefix); ok && strings.HasSuffix(rest, fileStartSuffix) {
+			path = strings.Trim(strings.TrimSpace(strings.TrimSuffix(rest, fileStartSuffix)), "`")
+			open = true
+			continue
+		}
+		prose.WriteString(line)
+	}
+	if open {
+		out.partial = path
+	}
+	out.prose = strings.TrimSpace(prose.String())
+	return out
+}
+
+// fileArtifact describes a file produced with the given content.
+func fileArtifact(path, content string) types.FileArtifact {
+	return types.FileArtifact{Path: path, Content: content, Bytes: len(content)}
+}
+
+// assembleFiles collects the files of a response written with fileInstruction,
+// asking for the rest while files it promised are missing or were cut off. It
+// returns the files and the deliverable rendered from them, or no files if
+// the response did not use file blocks.
+func (a *EngineerAgent) assembleFiles(ctx context.Context, task *types.Task, prompt string, opts *llm.GenerateOptions, response string) (string, []types.FileArtifact, error) {
+	out := parseFiles(response)
+	if out.planned == nil && len(out.files) == 0 && out.partial == "" {
+		return response, nil, nil
+	}
+
+	planned := out.planned
+	files := out.files
+	missing := missingFiles(planned, files, out.partial)
+	for round := 0; len(missing) > 0 && round < maxFileRounds; round++ {
+		a.logf("asking for %d missing file(s) of task %s: %s", len(missing), task.ID, strings.Join(missing, ", "))
+		written := "none"
+		if len(files) > 0 {
+			written = strings.Join(filePaths(files), ", ")
+		}
+		next, err := a.generate(ctx, a.llmManager, fmt.Sprintf(remainingFilesPrompt, prompt, written, strings.Join(missing, ", ")), opts)
+		if err != nil {
+			return "", nil, err
+		}
+
+		more := parseFiles(next)
+		files = mergeFiles(files, more.files)
+		missing = missingFiles(planned, files, more.partial)
+	}
+	if len(missing) > 0 {
+		return "", nil, errors.Wrap(errMissingFiles, errors.CodeLLMFailed, "missing "+strings.Join(missing, ", ")).WithAgent(a.id).WithTask(task.ID).WithProject(task.RunID)
+	}
+
+	a.stamp(ctx, prompt, files)
+	return renderFiles(out.prose, files), files, nil
+}
+
+// missingFiles returns the paths that were planned, or cut off, but not produced.
+func missingFiles(planned []string, files []types.FileArtifact, partial string) []string {
+	produced := filePaths(files)
+	var missing []string
+	for _, p := range append(slices.Clone(planned), partial) {
+		if p != "" && !slices.Contains(produced, p) && !slices.Contains(missing, p) {
+			missing = append(missing, p)
+		}
+	}
+	return missing
+}
+
+// mergeFiles adds files to a manifest, replacing earlier versions of the same path.
+func mergeFiles(files, more []types.FileArtifact) []types.FileArtifact {
+	for _, f := range more {
+		if i := slices.IndexFunc(files, func(g types.FileArtifact) bool { return g.Path == f.Path }); i >= 0 {
+			files[i] = f
+		} else {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// filePaths returns the paths of files.
+func filePaths(files []types.FileArtifact) []string {
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.Path
+	}
+	return paths
+}
+
+// renderFiles renders the text around the files followed by each file block.
+// Binary files are listed rather than rendered.
+func renderFiles(prose string, files []types.FileArtifact) string {
+	var b strings.Builder
+	if prose != "" {
+		b.WriteString(prose + "\n\n")
+	}
+	for _, f := range files {
+		if f.Binary() {
+			// Binary content is useless in a prompt; the manifest holds it
+			fmt.Fprintf(&b, "[%s: %s, %d bytes]\n", f.Path, f.MediaType, f.Bytes)
+			continue
+		}
+		fmt.Fprintf(&b, "%s %s %s\n%s", fileStartPrefix, f.Path, fileStartSuffix, f.Content)
+		if !strings.HasSuffix(f.Content, "\n") {
+			b.WriteString("\n")
+		}
+		b.WriteString(fileEnd + "\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// carryFiles passes the file manifest of a subordinate's response up with the response built from it.
+func carryFiles(resp, child *types.TaskResponse) {
+	if child != nil {
+		resp.Files = child.Files
+	}
+}
@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// gateway serves an organization where a single agent is expected, such as
+// by the gRPC server: the tasks it receives are submitted as client
+// projects, through the demo limits and admission control.
+type gateway struct {
+	org *Organization
+}
+
+// Gateway returns an agent that submits the tasks it receives to the
+// organization as client projects. It stands in for the president, under
+// whose ID and role it is addressed; starting and stopping it does nothing,
+// as the organization's lifecycle is its own.
+func (o *Organization) Gateway() types.Agent {
+	return &gateway{org: o}
+}
+
+// GetID returns the ID of the organization's president.
+func (g *gateway) GetID() string {
+	if g.org.president == nil {
+		return string(types.RolePresident)
+	}
+	return g.org.president.GetID()
+}
+
+// GetRole returns the president role.
+func (g *gateway) GetRole() types.AgentRole {
+	return types.RolePresident
+}
+
+// ProcessTask submits the task's content, or its description, as a client
+// project under the task's run ID.
+func (g *gateway) ProcessTask(ctx context.Context, task *types.Task) (*types.TaskResponse, error) {
+	if task.RunID != "" {
+		ctx = types.WithRunID(ctx, task.RunID)
+	}
+	return g.org.ProcessClientTask(ctx, cmp.Or(task.Content, task.Description))
+}
+
+// Start does nothing; the organization is started by its owner.
+func (g *gateway) Start(ctx context.Context) error {
+	return nil
+}
+
+// Stop does nothing; the organization is stopped by its owner.
+func (g *gateway) Stop(ctx context.Context) error {
+	return nil
+}
+
+// Describe describes the organization's president.
+func (g *gateway) Describe() types.AgentDescription {
+	d, err := g.org.DescribeAgent(g.GetID())
+	if err != nil {
+		return types.AgentDescription{ID: g.GetID(), Role: types.RolePresident, Status: "unavailable"}
+	}
+	return d
+}
+
+// GetMemoryHealth reports the organization's memory stores.
+func (g *gateway) GetMemoryHealth() []types.StoreHealth {
+	return g.org.MemoryHealth()
+}
@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kpango/BuildBureau/internal/errors"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// ErrGenerationCanceled is the cause of a generation aborted with CancelGeneration.
+var ErrGenerationCanceled = errors.New(errors.CodeCanceled, "generation canceled by request")
+
+// Generation describes an LLM generation in progress.
+type Generation struct {
+	StartedAt time.Time `json:"started_at"`
+	ID        string    `json:"id"`
+	AgentID   string    `json:"agent_id"`
+	RunID     string    `json:"run_id,omitempty"`
+	Model     string    `json:"model"`
+}
+
+// inflightGeneration is a generation with the handle that aborts it.
+type inflightGeneration struct {
+	cancel context.CancelCauseFunc
+	info   Generation
+}
+
+// startGeneration registers a generation so it can be listed and aborted on
+// its own. The returned context is canceled by CancelGeneration; done must be
+// called when the generation returns.
+func (a *BaseAgent) startGeneration(ctx context.Context, model string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	g := &inflightGeneration{
+		cancel: cancel,
+		info: Generation{
+			StartedAt: time.Now(),
+			ID:        uuid.New().String(),
+			AgentID:   a.id,
+			RunID:     types.RunIDFromContext(ctx),
+			Model:     model,
+		},
+	}
+
+	a.mu.Lock()
+	if a.generations == nil {
+		a.generations = make(map[string]*inflightGeneration)
+	}
+	a.generations[g.info.ID] = g
+	a.mu.Unlock()
+
+	return ctx, func() {
+		a.mu.Lock()
+		delete(a.generations, g.info.ID)
+		a.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+// Generations returns the agent's generations in progress, oldest first.
+func (a *BaseAgent) Generations() []Generation {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	generations := make([]Generation, 0, len(a.generations))
+	for _, g := range a.generations {
+		generations = append(generations, g.info)
+	}
+	slices.SortFunc(generations, func(x, y Generation) int { return x.StartedAt.Compare(y.StartedAt) })
+	return generations
+}
+
+// CancelGeneration aborts one of the agent's generations in progress. The
+// request to the provider is abandoned and the generation fails with
+// ErrGenerationCanceled; the agent keeps running. It reports whether the
+// generation was found.
+func (a *BaseAgent) CancelGeneration(id string) bool {
+	a.mu.RLock()
+	g, ok := a.generations[id]
+	a.mu.RUnlock()
+
+	if ok {
+		g.cancel(ErrGenerationCanceled)
+	}
+	return ok
+}
+
+// Generations returns the generations in progress across the organization, oldest first.
+func (o *Organization) Generations() []Generation {
+	var generations []Generation
+	for _, a := range o.allAgents() {
+		if g, ok := a.(interface{ Generations() []Generation }); ok {
+			generations = append(generations, g.Generations()...)
+		}
+	}
+	slices.SortFunc(generations, func(x, y Generation) int {
+		if c := x.StartedAt.Compare(y.StartedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(x.ID, y.ID)
+	})
+	return generations
+}
+
+// CancelGeneration aborts a generation in progress without stopping the
+// agent running it. The agent's task sees the generation fail as canceled.
+func (o *Organization) CancelGeneration(id string) error {
+	for _, a := range o.allAgents() {
+		if g, ok := a.(interface{ CancelGeneration(id string) bool }); ok && g.CancelGeneration(id) {
+			return nil
+		}
+	}
+	return errors.Newf(errors.CodeNotFound, "no generation %s is in progress", id)
+}
@@ -0,0 +1,172 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kpango/BuildBureau/internal/config"
+	"github.com/kpango/BuildBureau/internal/imagegen"
+	"github.com/kpango/BuildBureau/internal/provenance"
+	"github.com/kpango/BuildBureau/internal/report"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+const (
+	imageStartPrefix = "=== IMAGE:"
+	imageEnd         = "=== END IMAGE ==="
+)
+
+// imageInstruction offers engineers image generation for design deliverables.
+const imageInstruction = `
+
+If the task calls for UI mockups, logos or diagrams, you may request up to %d image(s).
+Describe each image in its own block, naming the PNG, JPEG or WebP file to store it as:
+=== IMAGE: docs/mockup.png ===
+<what the image shows, in detail>
+=== END IMAGE ===`
+
+// imageRequest is an image block of a response.
+type imageRequest struct {
+	path   string
+	prompt string
+}
+
+// openImages sets up the configured image generation. It returns nil if image
+// generation is not enabled or cannot be set up. Without its own API key, the
+// backend uses the LLM key of the same provider.
+func (o *Organization) openImages() *imagegen.Generator {
+	cfg := o.config.Images
+	if cfg == nil || !cfg.Enabled {
+		return nil
+	}
+
+	key := config.GetEnvValue(cfg.APIKey)
+	if key == "" {
+		provider := "openai"
+		if cfg.Backend == imagegen.BackendImagen {
+			provider = "gemini"
+		}
+		key = config.GetEnvValue(o.config.LLMs.APIKeys[provider])
+	}
+	generator, err := imagegen.New(cfg, key)
+	if err != nil {
+		fmt.Printf("Warning: Image generation disabled: %v\n", err)
+		return nil
+	}
+	return generator
+}
+
+// imageInstruction returns the instruction offering image generation, or ""
+// if the agent cannot generate images.
+func (a *EngineerAgent) imageInstruction() string {
+	if a.images == nil {
+		return ""
+	}
+	return fmt.Sprintf(imageInstruction, a.images.MaxPerTask())
+}
+
+// generateImages generates the images a response requests and replaces each
+// image block with a Markdown reference to the image. An image that cannot be
+// generated is noted in place of its block; the rest of the deliverable stands.
+func (a *EngineerAgent) generateImages(ctx context.Context, task *types.Task, response string) (string, []types.FileArtifact) {
+	if a.images == nil {
+		return response, nil
+	}
+
+	var images []types.FileArtifact
+	requested := 0
+	response = replaceImages(response, func(req imageRequest) string {
+		requested++
+		if requested > a.images.MaxPerTask() {
+			a.logf("skipped image %s of task %s: at most %d images per task", req.path, task.ID, a.images.MaxPerTask())
+			return fmt.Sprintf("[Image %s not generated: at most %d images per task]", req.path, a.images.MaxPerTask())
+		}
+
+		image, stored, err := a.images.Generate(ctx, task.RunID, req.path, req.prompt)
+		if err != nil {
+			a.logf("%v", err)
+			return fmt.Sprintf("[Image %s could not be generated: %v]", req.path, err)
+		}
+		image.Provenance = &types.Provenance{
+			CreatedAt:  time.Now(),
+			AgentID:    a.id,
+			Model:      a.images.Model(),
+			PromptHash: provenance.PromptHash(req.prompt),
+		}
+		images = append(images, image)
+		a.logf("generated image %s of task %s (%d bytes)", image.Path, task.ID, image.Bytes)
+		a.recordImage(ctx, task, image, stored, req.prompt)
+		return fmt.Sprintf("![%s](%s)", imageAlt(req.prompt), image.Path)
+	})
+	return response, images
+}
+
+// recordImage stores where a generated image was written, for the project report.
+func (a *EngineerAgent) recordImage(ctx context.Context, task *types.Task, image types.FileArtifact, stored, prompt string) {
+	if a.memoryManager == nil {
+		return
+	}
+	entry := &types.MemoryEntry{
+		AgentID: a.id,
+		RunID:   task.RunID,
+		Type:    types.MemoryTypeContext,
+		Content: prompt,
+		Tags:    []string{"image"},
+		Metadata: map[string]string{
+			report.MetadataKind:   report.KindImage,
+			report.MetadataPath:   image.Path,
+			report.MetadataStored: stored,
+			"task_id":             task.ID,
+		},
+	}
+	if err := a.memoryManager.StoreMemory(context.WithoutCancel(ctx), entry); err != nil {
+		a.logf("failed to record image %s: %v", image.Path, err)
+	}
+}
+
+// replaceImages returns output with each complete image block replaced by
+// the text replace returns for it. A block left open is kept as it is.
+func replaceImages(output string, replace func(imageRequest) string) string {
+	var (
+		out    strings.Builder
+		block  strings.Builder
+		prompt strings.Builder
+		req    *imageRequest
+	)
+	for line := range strings.Lines(output) {
+		trimmed := strings.TrimSpace(line)
+		if req == nil {
+			if rest, ok := strings.CutPrefix(trimmed, imageStartPrefix); ok && strings.HasSuffix(rest, fileStartSuffix) {
+				req = &imageRequest{path: strings.Trim(strings.TrimSpace(strings.TrimSuffix(rest, fileStartSuffix)), "`")}
+				block.WriteString(line)
+				continue
+			}
+			out.WriteString(line)
+			continue
+		}
+
+		if trimmed == imageEnd {
+			req.prompt = strings.TrimSpace(prompt.String())
+			out.WriteString(replace(*req) + "\n")
+			block.Reset()
+			prompt.Reset()
+			req = nil
+			continue
+		}
+		block.WriteString(line)
+		prompt.WriteString(line)
+	}
+	out.WriteString(block.String())
+	return out.String()
+}
+
+// imageAlt shortens an image description to alternative text.
+func imageAlt(prompt string) string {
+	alt, _, _ := strings.Cut(prompt, "\n")
+	if runes := []rune(alt); len(runes) > 80 {
+		alt = string(runes[:80]) + "…"
+	}
+	return strings.NewReplacer("[", "(", "]", ")").Replace(alt)
+}
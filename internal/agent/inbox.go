@@ -0,0 +1,682 @@
+package agent
+
+import (
+	"cmp"
+	"container/heap"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kpango/BuildBureau/internal/errors"
+	"github.com/kpango/BuildBureau/internal/logging"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// MessageKind is what an inbox message carries.
+type MessageKind string
+
+const (
+	// MessageTask carries a task for the agent to process.
+	MessageTask MessageKind = "task"
+	// MessageNotification carries a notification for the agent.
+	MessageNotification MessageKind = "notification"
+	// MessageQuestion carries a question for the agent.
+	MessageQuestion MessageKind = "question"
+
+	defaultVisibilityTimeout = 30 * time.Minute
+)
+
+// Message is an entry in an agent's inbox. Messages of higher priority are
+// received first; messages of equal priority in the order they were sent.
+type Message struct {
+	Enqueued   time.Time       `json:"enqueued"`
+	ctx        context.Context // Context of the sender, which waits on reply; nil for restored messages
+	Task       *types.Task     `json:"task,omitempty"`
+	reply      chan taskReply
+	ID         string      `json:"id"`
+	Kind       MessageKind `json:"kind"`
+	From       string      `json:"from"`
+	Text       string      `json:"text,omitempty"`
+	Priority   int         `json:"priority"`
+	Deliveries int         `json:"deliveries"` // Times the message was received
+	seq        uint64
+}
+
+// taskReply is the outcome of a task message.
+type taskReply struct {
+	resp *types.TaskResponse
+	err  error
+}
+
+// respond hands the outcome of a task to the sender if it is still waiting.
+// A message that was redelivered is only answered once.
+func (m *Message) respond(resp *types.TaskResponse, err error) {
+	if m.reply == nil {
+		return
+	}
+	select {
+	case m.reply <- taskReply{resp: resp, err: err}:
+	default:
+	}
+}
+
+// messageQueue is a heap of messages, highest priority first.
+type messageQueue []*Message
+
+func (q messageQueue) Len() int { return len(q) }
+func (q messageQueue) Less(i, j int) bool {
+	if q[i].Priority != q[j].Priority {
+		return q[i].Priority > q[j].Priority
+	}
+	return q[i].seq < q[j].seq
+}
+func (q messageQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *messageQueue) Push(x any)   { *q = append(*q, x.(*Message)) }
+func (q *messageQueue) Pop() any {
+	old := *q
+	m := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return m
+}
+
+// projectQueue holds the visible messages of one project and how much of the
+// inbox's time the project has had.
+type projectQueue struct {
+	messages messageQueue
+	pass     float64 // Grows by 1/weight with each message received; the lowest is served next
+}
+
+// inflightMessage is a received message that has not been acknowledged.
+type inflightMessage struct {
+	message  *Message
+	deadline time.Time
+}
+
+// Inbox queues an agent's pending tasks, notifications and questions. Sending
+// never blocks. A received message is hidden until it is acknowledged; if it
+// is not acknowledged within the visibility timeout, because its receiver
+// failed or hung, it becomes visible again and is redelivered. With a path,
+// unacknowledged messages are persisted and restored when the inbox is reopened.
+//
+// With fair scheduling, tasks of different projects (runs) take turns in
+// proportion to their weights, so one large project cannot starve the others;
+// priorities then order the messages within a project.
+type Inbox struct {
+	inflight   map[string]*inflightMessage
+	projects   map[string]*projectQueue
+	weight     func(runID string) float64 // Set with fair scheduling
+	ready      chan struct{}              // Signalled when a message may have become visible
+	done       chan struct{}
+	path       string
+	visibility time.Duration
+	pass       float64 // Pass of the project served last
+	seq        uint64
+	mu         sync.Mutex
+	closed     bool
+}
+
+// InboxOption configures an Inbox.
+type InboxOption func(*Inbox)
+
+// WithVisibilityTimeout sets how long a received message stays hidden before
+// it is redelivered. A zero duration keeps the default of 30 minutes.
+func WithVisibilityTimeout(timeout time.Duration) InboxOption {
+	return func(i *Inbox) {
+		if timeout > 0 {
+			i.visibility = timeout
+		}
+	}
+}
+
+// WithFairScheduling shares the inbox between projects in proportion to the
+// weights returned by weight; weights that are not positive count as 1.
+func WithFairScheduling(weight func(runID string) float64) InboxOption {
+	return func(i *Inbox) {
+		i.weight = weight
+	}
+}
+
+// OpenInbox opens an inbox. With a non-empty path, messages persisted there are restored.
+func OpenInbox(path string, opts ...InboxOption) (*Inbox, error) {
+	i := &Inbox{
+		inflight:   make(map[string]*inflightMessage),
+		projects:   make(map[string]*projectQueue),
+		ready:      make(chan struct{}, 1),
+		done:       make(chan struct{}),
+		path:       path,
+		visibility: defaultVisibilityTimeout,
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	if path == "" {
+		return i, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return i, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read inbox: %w", err)
+	}
+
+	var messages []*Message
+	if err := json.Unmarshal(data, &messages); err != nil {
+		return nil, fmt.Errorf("failed to parse inbox %s: %w", path, err)
+	}
+	for _, m := range messages {
+		i.seq++
+		m.seq = i.seq
+		i.push(m)
+	}
+
+	return i, nil
+}
+
+// Send adds a message to the inbox. It assigns the message an ID and enqueue
+// time if it has none.
+func (i *Inbox) Send(m *Message) error {
+	if m.ID == "" {
+		m.ID = uuid.New().String()
+	}
+	if m.Enqueued.IsZero() {
+		m.Enqueued = time.Now()
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.closed {
+		return errors.New(errors.CodeAgentUnavailable, "inbox is closed")
+	}
+	i.seq++
+	m.seq = i.seq
+	i.push(m)
+	i.save()
+	i.signal()
+
+	return nil
+}
+
+// Receive waits for the highest-priority visible message and hides it until
+// it is acknowledged or the visibility timeout passes.
+func (i *Inbox) Receive(ctx context.Context) (*Message, error) {
+	for {
+		i.mu.Lock()
+		if i.closed {
+			i.mu.Unlock()
+			return nil, errors.New(errors.CodeAgentUnavailable, "inbox is closed")
+		}
+
+		now := time.Now()
+		i.requeueExpired(now)
+		if m := i.pop(); m != nil {
+			m.Deliveries++
+			i.inflight[m.ID] = &inflightMessage{message: m, deadline: now.Add(i.visibility)}
+			i.save()
+			// Let another receiver pick up the next message
+			if i.visible() > 0 {
+				i.signal()
+			}
+			i.mu.Unlock()
+			return m, nil
+		}
+
+		// Wake up when the first hidden message becomes visible again
+		wait := i.visibility
+		for _, f := range i.inflight {
+			wait = min(wait, f.deadline.Sub(now))
+		}
+		i.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-i.ready:
+		case <-timer.C:
+		case <-i.done:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+		timer.Stop()
+	}
+}
+
+// Ack removes a message once it has been handled.
+func (i *Inbox) Ack(id string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	_, ok := i.inflight[id]
+	delete(i.inflight, id)
+	// The message may have been redelivered after its visibility timeout
+	if i.remove(id) {
+		ok = true
+	}
+	if !ok {
+		return errors.Newf(errors.CodeNotFound, "no message %s in inbox", id)
+	}
+	i.save()
+
+	return nil
+}
+
+// Len returns the number of unacknowledged messages, visible or not.
+func (i *Inbox) Len() int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.visible() + len(i.inflight)
+}
+
+// Pending returns the unacknowledged messages in the order they were sent.
+func (i *Inbox) Pending() []Message {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	messages := make([]Message, 0, i.visible()+len(i.inflight))
+	for _, m := range i.unacknowledged() {
+		messages = append(messages, *m)
+	}
+	return messages
+}
+
+// Close stops the inbox. Senders of tasks that were not received yet are
+// answered with an error; persisted messages are redelivered when the inbox
+// is reopened.
+func (i *Inbox) Close() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.closed {
+		return
+	}
+	i.closed = true
+	close(i.done)
+
+	for _, m := range i.queued() {
+		m.respond(nil, errors.Newf(errors.CodeAgentUnavailable, "inbox closed before message %s was handled", m.ID))
+	}
+}
+
+// unacknowledged returns the queued and in-flight messages in the order they were sent.
+func (i *Inbox) unacknowledged() []*Message {
+	messages := i.queued()
+	for _, f := range i.inflight {
+		messages = append(messages, f.message)
+	}
+	slices.SortFunc(messages, func(a, b *Message) int {
+		return cmp.Compare(a.seq, b.seq)
+	})
+	return messages
+}
+
+// requeueExpired makes in-flight messages whose visibility timeout passed visible again.
+func (i *Inbox) requeueExpired(now time.Time) {
+	for id, f := range i.inflight {
+		if now.Before(f.deadline) {
+			continue
+		}
+		delete(i.inflight, id)
+		i.push(f.message)
+	}
+}
+
+// project returns the project a message is scheduled as: its run with fair
+// scheduling, otherwise a single project shared by every message.
+func (i *Inbox) project(m *Message) string {
+	if i.weight == nil || m.Task == nil {
+		return ""
+	}
+	return m.Task.RunID
+}
+
+// push makes a message visible. A project that had nothing queued resumes no
+// earlier than the pass of the project served last, so it cannot bank turns
+// while idle.
+func (i *Inbox) push(m *Message) {
+	key := i.project(m)
+	q, ok := i.projects[key]
+	if !ok {
+		q = &projectQueue{}
+		i.projects[key] = q
+	}
+	if q.messages.Len() == 0 {
+		q.pass = max(q.pass, i.pass)
+	}
+	heap.Push(&q.messages, m)
+}
+
+// pop removes the next visible message: the first of the project with the
+// lowest pass, or nil if none is visible.
+func (i *Inbox) pop() *Message {
+	var (
+		next *projectQueue
+		key  string
+	)
+	for k, q := range i.projects {
+		if q.messages.Len() == 0 {
+			continue
+		}
+		if next == nil || q.pass < next.pass || (q.pass == next.pass && before(q.messages[0], next.messages[0])) {
+			next, key = q, k
+		}
+	}
+	if next == nil {
+		return nil
+	}
+
+	m := heap.Pop(&next.messages).(*Message)
+	i.pass = next.pass
+	next.pass += 1 / i.weightOf(key)
+
+	// Idle projects are only worth keeping while they are ahead of the others
+	maps.DeleteFunc(i.projects, func(_ string, q *projectQueue) bool {
+		return q.messages.Len() == 0 && q.pass <= i.pass
+	})
+	return m
+}
+
+// before reports whether message a goes before b within a project.
+func before(a, b *Message) bool {
+	return messageQueue{a, b}.Less(0, 1)
+}
+
+// weightOf returns the scheduling weight of a project.
+func (i *Inbox) weightOf(project string) float64 {
+	if i.weight == nil || project == "" {
+		return 1
+	}
+	if w := i.weight(project); w > 0 {
+		return w
+	}
+	return 1
+}
+
+// remove removes a visible message, reporting whether it was found.
+func (i *Inbox) remove(id string) bool {
+	for _, q := range i.projects {
+		if n := slices.IndexFunc(q.messages, func(m *Message) bool { return m.ID == id }); n >= 0 {
+			heap.Remove(&q.messages, n)
+			return true
+		}
+	}
+	return false
+}
+
+// visible returns the number of visible messages.
+func (i *Inbox) visible() int {
+	n := 0
+	for _, q := range i.projects {
+		n += q.messages.Len()
+	}
+	return n
+}
+
+// queued returns the visible messages in no particular order.
+func (i *Inbox) queued() []*Message {
+	var messages []*Message
+	for _, q := range i.projects {
+		messages = append(messages, q.messages...)
+	}
+	return messages
+}
+
+// signal wakes up a waiting receiver without blocking.
+func (i *Inbox) signal() {
+	select {
+	case i.ready <- struct{}{}:
+	default:
+	}
+}
+
+// save persists the unacknowledged messages, if the inbox has a path.
+func (i *Inbox) save() {
+	if i.path == "" {
+		return
+	}
+	if err := i.write(); err != nil {
+		fmt.Printf("Warning: failed to persist inbox: %v\n", err)
+	}
+}
+
+// write writes the unacknowledged messages to the inbox's file.
+func (i *Inbox) write() error {
+	data, err := json.MarshalIndent(i.unacknowledged(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode inbox: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(i.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create inbox directory: %w", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated inbox
+	tmp := i.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write inbox: %w", err)
+	}
+	if err := os.Rename(tmp, i.path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to write inbox: %w", err)
+	}
+
+	return nil
+}
+
+// Inboxes holds the inbox of each agent in an organization.
+type Inboxes struct {
+	inboxes map[string]*Inbox
+	mu      sync.RWMutex
+}
+
+// NewInboxes creates an empty set of inboxes.
+func NewInboxes() *Inboxes {
+	return &Inboxes{inboxes: make(map[string]*Inbox)}
+}
+
+// Add registers the inbox of an agent.
+func (b *Inboxes) Add(agentID string, inbox *Inbox) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.inboxes[agentID] = inbox
+}
+
+// Get returns the inbox of an agent, or nil if it has none.
+func (b *Inboxes) Get(agentID string) *Inbox {
+	if b == nil {
+		return nil
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.inboxes[agentID]
+}
+
+// dispatch hands a task to an agent and attaches the usage of the agent's
+// subtree to the response. The usage is also recorded as a subtask of the
+// dispatching agent's task.
+func (b *Inboxes) dispatch(ctx context.Context, from string, to types.Agent, task *types.Task) (*types.TaskResponse, error) {
+	parent := usageFromContext(ctx)
+	ctx, usage := startUsage(ctx, to.GetID())
+
+	resp, err := b.deliver(ctx, from, to, task)
+	total := usage.finish()
+	parent.addSubtask(total)
+	if resp != nil {
+		// Copy, so a response shared with another task keeps its own usage
+		withUsage := *resp
+		withUsage.Usage = total
+		resp = &withUsage
+	}
+	return resp, err
+}
+
+// deliver sends a task to the agent's inbox and waits for the outcome, or
+// calls the agent directly if it has no inbox. Sending does not block, so a
+// busy agent queues the task by priority instead of holding up the sender.
+func (b *Inboxes) deliver(ctx context.Context, from string, to types.Agent, task *types.Task) (*types.TaskResponse, error) {
+	inbox := b.Get(to.GetID())
+	if inbox == nil {
+		return to.ProcessTask(ctx, task)
+	}
+
+	m := &Message{
+		Kind:     MessageTask,
+		From:     from,
+		Priority: task.Priority,
+		Task:     task,
+		ctx:      ctx,
+		reply:    make(chan taskReply, 1),
+	}
+	if err := inbox.Send(m); err != nil {
+		return nil, err
+	}
+
+	select {
+	case r := <-m.reply:
+		return r.resp, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// dispatch hands a task to a subordinate through its inbox.
+func (a *BaseAgent) dispatch(ctx context.Context, subordinate types.Agent, task *types.Task) (*types.TaskResponse, error) {
+	return a.inboxes.dispatch(ctx, a.id, subordinate, task)
+}
+
+// openInboxes gives every agent an inbox and starts its workers, if inboxes are enabled.
+func (o *Organization) openInboxes() error {
+	cfg := o.config.Organization.Inbox
+	if !cfg.Enabled {
+		return nil
+	}
+
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	opts := []InboxOption{WithVisibilityTimeout(cfg.VisibilityTimeout)}
+	if cfg.Fair {
+		opts = append(opts, WithFairScheduling(o.weightOf))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	o.stopInboxes = cancel
+	for _, a := range o.allAgents() {
+		var path string
+		if cfg.Dir != "" {
+			path = filepath.Join(cfg.Dir, a.GetID()+".json")
+		}
+		inbox, err := OpenInbox(path, opts...)
+		if err != nil {
+			cancel()
+			return fmt.Errorf("failed to open inbox of %s: %w", a.GetID(), err)
+		}
+		o.inboxes.Add(a.GetID(), inbox)
+
+		for range workers {
+			o.inboxWorkers.Add(1)
+			go o.serveInbox(ctx, a, inbox)
+		}
+	}
+
+	return nil
+}
+
+// closeInboxes stops the inbox workers and closes the inboxes, waiting until
+// the workers finish their current messages or ctx is done.
+func (o *Organization) closeInboxes(ctx context.Context) {
+	if o.stopInboxes == nil {
+		return
+	}
+	o.stopInboxes()
+	for _, a := range o.allAgents() {
+		if inbox := o.inboxes.Get(a.GetID()); inbox != nil {
+			inbox.Close()
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		o.inboxWorkers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+// serveInbox handles an agent's messages until ctx is cancelled.
+func (o *Organization) serveInbox(ctx context.Context, a types.Agent, inbox *Inbox) {
+	defer o.inboxWorkers.Done()
+
+	for {
+		m, err := inbox.Receive(ctx)
+		if err != nil {
+			return
+		}
+		wait := time.Since(m.Enqueued)
+		o.handleMessage(a, m)
+		_ = inbox.Ack(m.ID)
+		if m.Kind == MessageTask && m.Task != nil {
+			o.recordProjectTask(m.Task.RunID, wait)
+		}
+	}
+}
+
+// handleMessage processes a task message, or records a notification or question
+// in the agent's activity log.
+func (o *Organization) handleMessage(a types.Agent, m *Message) {
+	if m.Kind != MessageTask || m.Task == nil {
+		o.activity.Log(a.GetID(), logging.Entry{Kind: logging.KindEvent, Content: fmt.Sprintf("%s from %s: %s", m.Kind, m.From, m.Text)})
+		return
+	}
+
+	// Messages restored from disk have no sender waiting for them
+	ctx := m.ctx
+	if ctx == nil {
+		ctx = types.WithRunID(context.Background(), m.Task.RunID)
+	}
+	if ctx.Err() != nil {
+		// The sender gave up before the agent got to the task
+		return
+	}
+
+	resp, err := a.ProcessTask(ctx, m.Task)
+	if m.reply == nil {
+		content := fmt.Sprintf("finished restored task %s", m.Task.ID)
+		if err != nil {
+			content = fmt.Sprintf("restored task %s failed: %v", m.Task.ID, err)
+		}
+		o.activity.Log(a.GetID(), logging.Entry{Kind: logging.KindEvent, RunID: m.Task.RunID, Content: content})
+	}
+	m.respond(resp, err)
+}
+
+// Post sends a message to an agent's inbox without waiting for it to be handled.
+func (o *Organization) Post(agentID string, m *Message) error {
+	inbox := o.inboxes.Get(agentID)
+	if inbox == nil {
+		return errors.Newf(errors.CodeNotFound, "agent %s has no inbox", agentID)
+	}
+	return inbox.Send(m)
+}
+
+// Inbox returns the unacknowledged messages of an agent's inbox.
+func (o *Organization) Inbox(agentID string) ([]Message, error) {
+	inbox := o.inboxes.Get(agentID)
+	if inbox == nil {
+		return nil, errors.Newf(errors.CodeNotFound, "agent %s has no inbox", agentID)
+	}
+	return inbox.Pending(), nil
+}
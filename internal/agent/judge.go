@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kpango/BuildBureau/internal/errors"
+	"github.com/kpango/BuildBureau/internal/judge"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// judgeScoreLimit is how many recent scores are returned for analytics.
+const judgeScoreLimit = 500
+
+// judgeScores is a memory manager that keeps judge scores.
+type judgeScores interface {
+	RecordJudgeScore(ctx context.Context, score *types.JudgeScore) error
+	JudgeScores(ctx context.Context, runID string, limit int) ([]types.JudgeScore, error)
+}
+
+// openJudges sets up the judges that score deliverables. It returns nil if
+// judges are not enabled or there is no LLM to judge with.
+func (o *Organization) openJudges() *judge.Panel {
+	cfg := o.config.Judges
+	if cfg == nil || !cfg.Enabled || o.llmManager == nil {
+		return nil
+	}
+
+	var judges []judge.Judge
+	for _, name := range cfg.Judges {
+		j, ok := judge.Lookup(name)
+		if !ok {
+			fmt.Printf("Warning: Ignoring unknown judge %q\n", name)
+			continue
+		}
+		judges = append(judges, j)
+	}
+	return judge.NewPanel(o.llmManager, cfg.Model, judges...)
+}
+
+// scoreDeliverable has the judges score a deliverable against the client's
+// instruction. The scores are attached to the response and recorded in
+// memory for analytics.
+func (o *Organization) scoreDeliverable(ctx context.Context, task *types.Task, resp *types.TaskResponse) {
+	if o.judges == nil || resp.Status == types.StatusFailed {
+		return
+	}
+
+	scores, err := o.judges.Score(ctx, task.Content, Deliverable(resp))
+	if err != nil {
+		// The judges that did answer still count
+		fmt.Printf("Warning: %v\n", err)
+	}
+
+	history, _ := o.memoryManager.(judgeScores)
+	for i := range scores {
+		scores[i].TaskID = resp.TaskID
+		scores[i].RunID = task.RunID
+		if history == nil {
+			continue
+		}
+		// Recorded even when the task was cancelled, so use a context that outlives it
+		if err := history.RecordJudgeScore(context.WithoutCancel(ctx), &scores[i]); err != nil {
+			fmt.Printf("Warning: failed to record %s score: %v\n", scores[i].Judge, err)
+		}
+	}
+	resp.Scores = scores
+}
+
+// judgedChanges returns a change request for every judge that scored the
+// deliverable below the configured minimum.
+func (o *Organization) judgedChanges(resp *types.TaskResponse) []string {
+	if o.config.Judges == nil {
+		return nil
+	}
+
+	var changes []string
+	for _, s := range judge.Below(resp.Scores, o.config.Judges.MinScore) {
+		changes = append(changes, fmt.Sprintf("Improve %s (rated %d/5): %s", s.Judge, s.Rating, s.Reason))
+	}
+	return changes
+}
+
+// JudgeScores returns the judge scores recorded for a run, or for every run
+// if runID is empty, newest first.
+func (o *Organization) JudgeScores(ctx context.Context, runID string) ([]types.JudgeScore, error) {
+	history, ok := o.memoryManager.(judgeScores)
+	if !ok {
+		return nil, errors.ErrMemoryUnavailable
+	}
+
+	scores, err := history.JudgeScores(ctx, runID, judgeScoreLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get judge scores: %w", err)
+	}
+	return scores, nil
+}
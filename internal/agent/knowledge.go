@@ -0,0 +1,360 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/kpango/BuildBureau/internal/ingest"
+	"github.com/kpango/BuildBureau/internal/logging"
+	"github.com/kpango/BuildBureau/internal/profile"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+const (
+	// SharedKnowledgeAgentID owns the organization-wide knowledge entries.
+	SharedKnowledgeAgentID = "organization"
+
+	// Conflict policies for lessons that contradict shared knowledge.
+	ConflictMostConfirmed = "most_confirmed" // Keep the lesson confirmed more often; ties go to the newer one
+	ConflictNewest        = "newest"         // Always replace the existing lesson
+
+	// Outcomes of promoting a lesson.
+	PromotionAdded      = "added"
+	PromotionConfirmed  = "confirmed"
+	PromotionSuperseded = "superseded"
+	PromotionRejected   = "rejected"
+
+	// Metadata of shared knowledge entries.
+	MetadataLesson        = "lesson"
+	MetadataConfirmations = "confirmations"
+	MetadataSourceAgent   = "source_agent"
+	MetadataSupersedes    = "supersedes"
+	MetadataDocument      = "document" // Source of knowledge ingested from documentation
+	MetadataSection       = "section"
+
+	defaultDuplicateThreshold   = 0.8
+	defaultConflictThreshold    = 0.5
+	defaultSharedKnowledgeLimit = 5
+)
+
+// Lesson is a piece of knowledge proposed for sharing across the organization.
+type Lesson struct {
+	Text        string
+	Reasoning   string
+	SourceAgent string
+}
+
+// Promotion reports what happened to a promoted lesson.
+type Promotion struct {
+	Outcome  string
+	EntryID  string // Shared entry holding the lesson, or the one that kept its place
+	Conflict string // Lesson the promoted one contradicted, if any
+}
+
+// KnowledgeBase is the organization-wide knowledge channel. Lessons learned
+// by one agent are promoted to it and offered to every agent for future
+// tasks. Near-duplicate lessons confirm each other instead of piling up, and
+// lessons on the same topic that disagree are resolved by the conflict policy.
+type KnowledgeBase struct {
+	memory types.MemoryManager
+	cfg    types.KnowledgeSharingConfig
+	mu     sync.Mutex // Serializes promotions so concurrent duplicates merge
+}
+
+// NewKnowledgeBase creates a KnowledgeBase stored in memory, applying defaults.
+func NewKnowledgeBase(memory types.MemoryManager, cfg types.KnowledgeSharingConfig) *KnowledgeBase {
+	if cfg.DuplicateThreshold <= 0 || cfg.DuplicateThreshold > 1 {
+		cfg.DuplicateThreshold = defaultDuplicateThreshold
+	}
+	if cfg.ConflictThreshold <= 0 || cfg.ConflictThreshold > 1 {
+		cfg.ConflictThreshold = defaultConflictThreshold
+	}
+	if cfg.ConflictPolicy == "" {
+		cfg.ConflictPolicy = ConflictMostConfirmed
+	}
+	if cfg.Limit <= 0 {
+		cfg.Limit = defaultSharedKnowledgeLimit
+	}
+
+	return &KnowledgeBase{memory: memory, cfg: cfg}
+}
+
+// Promote adds a lesson to the shared knowledge, merging it with a duplicate
+// or resolving a conflict with an existing lesson.
+func (k *KnowledgeBase) Promote(ctx context.Context, lesson Lesson) (Promotion, error) {
+	if strings.TrimSpace(lesson.Text) == "" {
+		return Promotion{}, fmt.Errorf("lesson must not be empty")
+	}
+
+	// Shared knowledge outlives the run that produced it
+	ctx = types.WithoutRunID(ctx)
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	entries, err := k.List(ctx)
+	if err != nil {
+		return Promotion{}, err
+	}
+
+	bigrams, unigrams := shingle(lesson.Text), words(lesson.Text)
+	var conflict *types.MemoryEntry
+	conflictScore := k.cfg.ConflictThreshold
+	for _, e := range entries {
+		// Documentation is reference material, not a lesson to confirm or supersede
+		if e.Metadata[MetadataDocument] != "" {
+			continue
+		}
+		text := e.Metadata[MetadataLesson]
+		if jaccard(bigrams, shingle(text)) >= k.cfg.DuplicateThreshold {
+			e.Metadata[MetadataConfirmations] = strconv.Itoa(confirmations(e) + 1)
+			return Promotion{Outcome: PromotionConfirmed, EntryID: e.ID}, k.replace(ctx, e)
+		}
+		if score := jaccard(unigrams, words(text)); score >= conflictScore {
+			conflict, conflictScore = e, score
+		}
+	}
+
+	if conflict == nil {
+		id, err := k.store(ctx, lesson, "")
+		return Promotion{Outcome: PromotionAdded, EntryID: id}, err
+	}
+
+	existing := conflict.Metadata[MetadataLesson]
+	if k.cfg.ConflictPolicy == ConflictMostConfirmed && confirmations(conflict) > 1 {
+		return Promotion{Outcome: PromotionRejected, EntryID: conflict.ID, Conflict: existing}, nil
+	}
+
+	if err := k.memory.DeleteMemory(ctx, conflict.ID); err != nil {
+		return Promotion{}, fmt.Errorf("failed to remove superseded knowledge: %w", err)
+	}
+	id, err := k.store(ctx, lesson, existing)
+	return Promotion{Outcome: PromotionSuperseded, EntryID: id, Conflict: existing}, err
+}
+
+// List returns every shared lesson.
+func (k *KnowledgeBase) List(ctx context.Context) ([]*types.MemoryEntry, error) {
+	return k.memory.QueryMemories(ctx, &types.MemoryQuery{
+		AgentID: SharedKnowledgeAgentID,
+		Type:    types.MemoryTypeKnowledge,
+	})
+}
+
+// Relevant returns the shared lessons that share the most keywords with text,
+// preferring lessons confirmed more often.
+func (k *KnowledgeBase) Relevant(ctx context.Context, text string) ([]*types.MemoryEntry, error) {
+	entries, err := k.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := profile.Keywords(text)
+	scores := make(map[*types.MemoryEntry]int, len(entries))
+	var relevant []*types.MemoryEntry
+	for _, e := range entries {
+		score := 0
+		for _, w := range profile.Keywords(e.Metadata[MetadataLesson]) {
+			if slices.Contains(query, w) {
+				score++
+			}
+		}
+		if score > 0 {
+			scores[e] = score
+			relevant = append(relevant, e)
+		}
+	}
+
+	slices.SortStableFunc(relevant, func(a, b *types.MemoryEntry) int {
+		return cmp.Or(scores[b]-scores[a], confirmations(b)-confirmations(a))
+	})
+	return relevant[:min(k.cfg.Limit, len(relevant))], nil
+}
+
+// store saves a new shared lesson and returns its ID.
+func (k *KnowledgeBase) store(ctx context.Context, lesson Lesson, supersedes string) (string, error) {
+	content := lesson.Text
+	if lesson.Reasoning != "" {
+		content += "\nReasoning: " + lesson.Reasoning
+	}
+
+	entry := &types.MemoryEntry{
+		AgentID: SharedKnowledgeAgentID,
+		Type:    types.MemoryTypeKnowledge,
+		Content: content,
+		Tags:    []string{"shared", "lesson"},
+		Metadata: map[string]string{
+			MetadataLesson:        lesson.Text,
+			"reasoning":           lesson.Reasoning,
+			MetadataSourceAgent:   lesson.SourceAgent,
+			MetadataConfirmations: "1",
+		},
+	}
+	if supersedes != "" {
+		entry.Metadata[MetadataSupersedes] = supersedes
+	}
+
+	if err := k.memory.StoreMemory(ctx, entry); err != nil {
+		return "", fmt.Errorf("failed to store shared knowledge: %w", err)
+	}
+	return entry.ID, nil
+}
+
+// Ingest stores the chunks of a document as shared knowledge, so agents
+// start with the team's existing documentation. Chunks ingested from the same
+// source before are replaced. It returns how many chunks were stored.
+func (k *KnowledgeBase) Ingest(ctx context.Context, source string, chunks []ingest.Chunk, tags ...string) (int, error) {
+	ctx = types.WithoutRunID(ctx)
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	previous, err := k.memory.QueryMemories(ctx, &types.MemoryQuery{
+		AgentID:  SharedKnowledgeAgentID,
+		Type:     types.MemoryTypeKnowledge,
+		Metadata: map[string]string{MetadataDocument: source},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to read knowledge from %s: %w", source, err)
+	}
+	for _, e := range previous {
+		if err := k.memory.DeleteMemory(ctx, e.ID); err != nil {
+			return 0, fmt.Errorf("failed to replace knowledge from %s: %w", source, err)
+		}
+	}
+
+	for n, c := range chunks {
+		text := c.Text
+		if c.Section != "" {
+			text = c.Section + ": " + text
+		}
+		entry := &types.MemoryEntry{
+			AgentID: SharedKnowledgeAgentID,
+			Type:    types.MemoryTypeKnowledge,
+			Content: text,
+			Tags:    append([]string{"shared", "document"}, tags...),
+			Metadata: map[string]string{
+				MetadataLesson:        text,
+				MetadataDocument:      source,
+				MetadataSection:       c.Section,
+				MetadataConfirmations: "1",
+				"chunk":               strconv.Itoa(c.Index),
+			},
+		}
+		if err := k.memory.StoreMemory(ctx, entry); err != nil {
+			return n, fmt.Errorf("failed to store knowledge from %s: %w", source, err)
+		}
+	}
+	return len(chunks), nil
+}
+
+// replace rewrites an existing shared entry, keeping its ID.
+func (k *KnowledgeBase) replace(ctx context.Context, entry *types.MemoryEntry) error {
+	if err := k.memory.DeleteMemory(ctx, entry.ID); err != nil {
+		return fmt.Errorf("failed to update shared knowledge: %w", err)
+	}
+	if err := k.memory.StoreMemory(ctx, entry); err != nil {
+		return fmt.Errorf("failed to update shared knowledge: %w", err)
+	}
+	return nil
+}
+
+// confirmations returns how many times a shared lesson was learned.
+func confirmations(entry *types.MemoryEntry) int {
+	n, err := strconv.Atoi(entry.Metadata[MetadataConfirmations])
+	if err != nil || n < 1 {
+		return 1
+	}
+	return n
+}
+
+// words returns the set of lowercase words in text.
+func words(text string) map[string]struct{} {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	set := make(map[string]struct{}, len(fields))
+	for _, f := range fields {
+		set[f] = struct{}{}
+	}
+	return set
+}
+
+// setKnowledge gives the agent access to the organization's shared knowledge.
+func (a *BaseAgent) setKnowledge(knowledge *KnowledgeBase) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.knowledge = knowledge
+}
+
+// sharedKnowledgeContext renders the shared lessons relevant to a task for inclusion in a prompt.
+func (a *BaseAgent) sharedKnowledgeContext(ctx context.Context, task *types.Task) string {
+	a.mu.RLock()
+	knowledge := a.knowledge
+	a.mu.RUnlock()
+	if knowledge == nil {
+		return ""
+	}
+
+	lessons, err := knowledge.Relevant(ctx, taskText(task))
+	if err != nil || len(lessons) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\n\n=== Organization Knowledge ===\n")
+	for _, l := range lessons {
+		if document := l.Metadata[MetadataDocument]; document != "" {
+			fmt.Fprintf(&b, "- %s (from %s)\n", l.Metadata[MetadataLesson], document)
+			continue
+		}
+		fmt.Fprintf(&b, "- %s (confirmed %d time(s))\n", l.Metadata[MetadataLesson], confirmations(l))
+	}
+	b.WriteString("=== End of Organization Knowledge ===\n\n")
+	return b.String()
+}
+
+// promoteKnowledge shares the decisions recorded during a successful run
+// with the whole organization.
+func (o *Organization) promoteKnowledge(ctx context.Context, runID string) {
+	memories, err := o.GetRunMemories(ctx, runID)
+	if err != nil {
+		fmt.Printf("Warning: failed to read knowledge to share: %v\n", err)
+		return
+	}
+
+	for _, m := range memories {
+		// Only decisions extracted from agent output are lessons; delegation
+		// and recovery decisions describe the run itself
+		if m.Type != types.MemoryTypeDecision || m.Metadata["source"] != "extracted" {
+			continue
+		}
+
+		lesson := Lesson{Text: m.Metadata["decision"], Reasoning: m.Metadata["reasoning"], SourceAgent: m.AgentID}
+		promotion, err := o.knowledge.Promote(ctx, lesson)
+		if err != nil {
+			fmt.Printf("Warning: failed to share knowledge: %v\n", err)
+			continue
+		}
+
+		message := fmt.Sprintf("shared knowledge %s: %s", promotion.Outcome, lesson.Text)
+		if promotion.Conflict != "" {
+			message += fmt.Sprintf(" (conflicts with: %s)", promotion.Conflict)
+		}
+		o.activity.Log(SharedKnowledgeAgentID, logging.Entry{Kind: logging.KindEvent, RunID: runID, Content: message})
+	}
+}
+
+// SharedKnowledge returns the organization-wide lessons.
+func (o *Organization) SharedKnowledge(ctx context.Context) ([]*types.MemoryEntry, error) {
+	if o.knowledge == nil {
+		return nil, fmt.Errorf("knowledge sharing is not enabled")
+	}
+	return o.knowledge.List(ctx)
+}
@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strings"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// labelContext describes the labels of a task to the LLM, or returns "" if
+// it has none.
+func labelContext(task *types.Task) string {
+	labels := types.Labels(task.Metadata)
+	if len(labels) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	slices.Sort(pairs)
+	return "\n\nLabels: " + strings.Join(pairs, ", ")
+}
+
+// routeByLabel returns the index of the subordinate the routing rules send a
+// task to: the first rule whose label the task carries and whose target, a
+// team name or agent ID, is among the subordinates. Tasks for a team with
+// several matching agents are spread between them by task ID. It returns
+// false if no rule applies.
+func (a *BaseAgent) routeByLabel(subordinates []types.Agent, task *types.Task) (int, string, bool) {
+	if len(a.routing) == 0 {
+		return 0, "", false
+	}
+	labels := types.Labels(task.Metadata)
+	if len(labels) == 0 {
+		return 0, "", false
+	}
+
+	for _, rule := range a.routing {
+		key, value, _ := strings.Cut(rule.Label, "=")
+		if v, ok := labels[key]; !ok || v != value {
+			continue
+		}
+
+		var matched []int
+		for i, sub := range subordinates {
+			member, _ := sub.(interface{ Team() string })
+			if sub.GetID() == rule.To || member != nil && member.Team() == rule.To {
+				matched = append(matched, i)
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+
+		h := fnv.New32a()
+		h.Write([]byte(task.ID))
+		idx := matched[h.Sum32()%uint32(len(matched))]
+		return idx, fmt.Sprintf("Routed to %s by label %s", subordinates[idx].GetID(), rule.Label), true
+	}
+	return 0, "", false
+}
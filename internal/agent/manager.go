@@ -3,13 +3,31 @@ package agent
 import (
 	"context"
 	"fmt"
+	"slices"
 	"sync/atomic"
 
 	"github.com/google/uuid"
+	"github.com/kpango/BuildBureau/internal/errors"
 	"github.com/kpango/BuildBureau/internal/llm"
 	"github.com/kpango/BuildBureau/pkg/types"
 )
 
+// designPrompt asks the LLM for a technical specification. The last argument is context from memory.
+const designPrompt = `You are a software manager tasked with creating a detailed technical specification for:
+
+Title: %s
+Description: %s
+Requirements: %s
+%s
+Please provide:
+1. High-level architecture design
+2. Component breakdown
+3. Technical specifications for each component
+4. Interface definitions
+5. Implementation guidelines for engineers
+
+Be detailed and technical. Learn from the past designs provided above if available.`
+
 // ManagerAgent represents a manager agent that produces software designs.
 type ManagerAgent struct {
 	secretary types.Agent
@@ -20,9 +38,9 @@ type ManagerAgent struct {
 }
 
 // NewManagerAgent creates a new Manager agent.
-func NewManagerAgent(id string, config *types.AgentConfig, llmManager *llm.Manager) *ManagerAgent {
+func NewManagerAgent(id string, config *types.AgentConfig, llmManager *llm.Manager, opts ...Option) *ManagerAgent {
 	return &ManagerAgent{
-		BaseAgent:  NewBaseAgent(id, types.RoleManager, config),
+		BaseAgent:  NewBaseAgent(id, types.RoleManager, config, opts...),
 		engineers:  make([]types.Agent, 0),
 		llmManager: llmManager,
 	}
@@ -38,11 +56,20 @@ func (a *ManagerAgent) AddEngineer(engineer types.Agent) {
 	a.engineers = append(a.engineers, engineer)
 }
 
+// RemoveEngineer stops delegating tasks to the engineer with the given ID.
+func (a *ManagerAgent) RemoveEngineer(id string) {
+	a.engineers = slices.DeleteFunc(a.engineers, func(agent types.Agent) bool { return agent.GetID() == id })
+}
+
 // ProcessTask handles incoming tasks for the Manager using LLM and memory.
 func (a *ManagerAgent) ProcessTask(ctx context.Context, task *types.Task) (*types.TaskResponse, error) {
 	a.IncrementActiveTasks()
 	defer a.DecrementActiveTasks()
 
+	ctx, cancel := a.taskContext(ctx, task)
+	defer cancel()
+	ctx = a.openBlackboard(ctx)
+
 	// Store conversation memory
 	if mem := a.GetMemory(); mem != nil {
 		_ = mem.StoreConversation(ctx, fmt.Sprintf("Received design task: %s", task.Title), []string{"manager", "design"})
@@ -50,51 +77,35 @@ func (a *ManagerAgent) ProcessTask(ctx context.Context, task *types.Task) (*type
 
 	result := fmt.Sprintf("Manager %s processing task: %s\n", a.GetID(), task.Title)
 
-	// Check memory for similar past designs
-	var contextFromMemory string
-	if mem := a.GetMemory(); mem != nil {
-		relatedTasks, err := mem.GetRelatedTasks(ctx, task.Description, 3)
-		if err == nil && len(relatedTasks) > 0 {
-			result += fmt.Sprintf("Found %d related past design(s) to reference.\n", len(relatedTasks))
-			contextFromMemory = "\n\n=== Context from Past Designs ===\n"
-			for i, memory := range relatedTasks {
-				contextFromMemory += fmt.Sprintf("\nPast Design %d:\n%s\n", i+1, memory.Content)
-			}
-			contextFromMemory += "=== End of Past Context ===\n\n"
-		}
-	}
-
 	// Use LLM if available to create software design
 	var designSpec string
 	if a.llmManager != nil {
-		prompt := fmt.Sprintf(`You are a software manager tasked with creating a detailed technical specification for:
-
-Title: %s
-Description: %s
-Requirements: %s
-%s
-Please provide:
-1. High-level architecture design
-2. Component breakdown
-3. Technical specifications for each component
-4. Interface definitions
-5. Implementation guidelines for engineers
-
-Be detailed and technical. Learn from the past designs provided above if available.`,
-			task.Title, task.Description, task.Content, contextFromMemory)
-
 		llmOpts := &llm.GenerateOptions{
 			Temperature:  0.5, // Lower temperature for more focused technical output
-			MaxTokens:    3072,
+			MaxTokens:    a.llmManager.OutputTokens(llm.OutputSpec),
 			SystemPrompt: a.config.SystemPrompt,
 		}
 
-		model := a.config.Model
-		if model == "" {
-			model = "gemini"
+		// Past designs, decisions and knowledge fill what the rest of the prompt leaves of the window
+		contextFromMemory := a.profileContext() + a.sharedKnowledgeContext(ctx, task) + a.blackboardContext(ctx)
+		base := fmt.Sprintf(designPrompt, task.Title, task.Description, task.Content, "") + labelContext(task) + a.conventionsContext(task)
+		if mem := a.GetMemory(); mem != nil {
+			past, err := a.pastContext(ctx, mem, task, a.memoryBudget(a.llmManager, base+contextFromMemory, llmOpts))
+			if err == nil && past != "" {
+				result += "Found related past designs to reference.\n"
+				contextFromMemory += past
+			}
+		}
+		contextFromMemory = a.fitMemoryContext(ctx, a.llmManager, contextFromMemory, base, llmOpts)
+		prompt := fmt.Sprintf(designPrompt, task.Title, task.Description, task.Content, contextFromMemory) + labelContext(task) + a.conventionsContext(task)
+
+		response, err := a.generateWithQuestions(ctx, a.llmManager, task, prompt, llmOpts)
+		response = a.applyBlackboard(ctx, response)
+		a.learn(task, response, err)
+		if errors.Is(err, errUnanswered) {
+			// The configured timeout decision is to fail rather than guess
+			return nil, err
 		}
-
-		response, err := a.llmManager.Generate(ctx, model, prompt, llmOpts)
 		if err != nil {
 			result += fmt.Sprintf("Warning: LLM generation failed: %v\n", err)
 			designSpec = fmt.Sprintf("Specifications for: %s\n", task.Content)
@@ -108,6 +119,11 @@ Be detailed and technical. Learn from the past designs provided above if availab
 			if mem := a.GetMemory(); mem != nil {
 				knowledgeContent := fmt.Sprintf("Design for: %s\n\nSpecification:\n%s", task.Title, response)
 				_ = mem.StoreKnowledge(ctx, knowledgeContent, []string{"design", "specification", task.Title})
+
+				// Extract the design decisions so they can be reviewed across the organization
+				if n := recordDecisions(ctx, mem, a.llmManager, a.Model(), task, response, []string{"manager", "design"}); n > 0 {
+					result += fmt.Sprintf("Recorded %d design decision(s).\n", n)
+				}
 			}
 		}
 	} else {
@@ -115,17 +131,28 @@ Be detailed and technical. Learn from the past designs provided above if availab
 	}
 
 	// If we have engineers, delegate to them using round-robin with memory
+	var (
+		recovery string
+		child    *types.TaskResponse
+	)
 	if len(a.engineers) > 0 {
 		result += fmt.Sprintf("\nDelegating implementation to %d Engineer(s)...\n", len(a.engineers))
 
-		// Round-robin selection
-		idx := atomic.AddUint32(&a.nextEngineerIdx, 1) - 1
-		engineer := a.engineers[int(idx)%len(a.engineers)]
+		// Follow the routing rules for the task's labels, else prefer the engineer whose
+		// experience, capabilities and record fit the task, falling back to round-robin
+		// when none stands out
+		idx := int(atomic.AddUint32(&a.nextEngineerIdx, 1)-1) % len(a.engineers)
+		reasoning := "Selected based on round-robin"
+		if routed, reason, ok := a.routeByLabel(a.engineers, task); ok {
+			idx, reasoning = routed, reason
+		} else if routed, reason, ok := routeTask(a.engineers, task, a.assignments(ctx, a.engineers)); ok {
+			idx, reasoning = routed, reason
+		}
+		engineer := a.engineers[idx]
 
 		// Store delegation decision
 		if mem := a.GetMemory(); mem != nil {
 			decision := fmt.Sprintf("Delegated to engineer %s", engineer.GetID())
-			reasoning := "Selected based on round-robin"
 			_ = mem.StoreDecision(ctx, decision, reasoning, []string{"delegation", "engineer"})
 		}
 
@@ -135,20 +162,42 @@ Be detailed and technical. Learn from the past designs provided above if availab
 			Description: task.Description,
 			FromAgent:   a.GetID(),
 			ToAgent:     engineer.GetID(),
-			Content:     designSpec, // Pass the design spec to the engineer
+			Content:     a.delegationContent(ctx, task, designSpec, designSpec), // Pass the design spec to the engineer
+			Metadata:    a.delegationMetadata(task),
+			RunID:       task.RunID,
 			Priority:    task.Priority,
 		}
 
-		response, err := engineer.ProcessTask(ctx, engineerTask)
+		response, steps, err := a.delegate(ctx, delegation{
+			parent:       task,
+			subtask:      engineerTask,
+			subordinates: a.engineers,
+			index:        idx,
+			label:        "engineer",
+			handler:      a,
+		})
 		if err != nil {
-			return nil, fmt.Errorf("failed to delegate to engineer: %w", err)
+			return nil, err
 		}
 
-		if response.Status == types.StatusFailed {
-			return nil, fmt.Errorf("engineer task failed: %s", response.Error)
+		if len(steps) > 0 {
+			recovery = formatRecovery(steps)
+			result += fmt.Sprintf("Recovered after %d re-plan(s): %s\n", len(steps), recovery)
+		}
+
+		// Complex tasks are reviewed by a second engineer and revised before submission
+		if rounds := a.pairRounds(engineerTask); rounds > 0 {
+			drafter := idx
+			if len(steps) > 0 {
+				drafter = indexOf(a.engineers, steps[len(steps)-1].Agent)
+			}
+			var revised int
+			response, revised = a.pair(ctx, engineerTask, drafter, rounds, response)
+			result += fmt.Sprintf("Pair review: %d revision round(s).\n", revised)
 		}
 
-		result += fmt.Sprintf("Engineer response: %s\n", response.Result)
+		child = response
+		result += a.subordinateReport("Engineer", response)
 	} else {
 		result += "No engineers available. Design completed at Manager level.\n"
 	}
@@ -158,9 +207,9 @@ Be detailed and technical. Learn from the past designs provided above if availab
 		_ = mem.StoreTask(ctx, task, result, []string{"manager", "design", "completed"})
 	}
 
-	return &types.TaskResponse{
-		TaskID: task.ID,
-		Status: types.StatusCompleted,
-		Result: result,
-	}, nil
+	resp := recoveredResponse(task, result, recovery)
+	carryFiles(resp, child)
+	carryOutcome(resp, child)
+	a.reportStatus(ctx, a.llmManager, task, resp, child, designSpec)
+	return resp, nil
 }
@@ -3,29 +3,152 @@ package agent
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/kpango/BuildBureau/internal/memory"
 	"github.com/kpango/BuildBureau/pkg/types"
 )
 
-// AgentMemory provides memory functionality for agents.
-type AgentMemory struct {
-	manager types.MemoryManager
-	agentID string
-	enabled bool
+const (
+	// defaultMemoryCacheSize is the number of query results cached per agent.
+	defaultMemoryCacheSize = 128
+	// defaultMemoryCacheTTL bounds how stale a cached query result may be.
+	defaultMemoryCacheTTL = time.Minute
+)
+
+// AgentMemory is the agent-facing memory API used while processing tasks.
+// Implementations scope every call to a single agent.
+type AgentMemory interface {
+	// StoreConversation stores a conversation memory
+	StoreConversation(ctx context.Context, content string, tags []string) error
+
+	// StoreTask stores the outcome of a processed task
+	StoreTask(ctx context.Context, task *types.Task, result string, tags []string) error
+
+	// StoreKnowledge stores learned knowledge
+	StoreKnowledge(ctx context.Context, content string, tags []string) error
+
+	// StoreDecision stores a decision with its reasoning
+	StoreDecision(ctx context.Context, decision string, reasoning string, tags []string) error
+
+	// StoreTaskDecision stores an extracted decision linked to its task
+	StoreTaskDecision(ctx context.Context, task *types.Task, decision Decision, tags []string) error
+
+	// GetConversationHistory retrieves recent conversation history
+	GetConversationHistory(ctx context.Context, limit int) ([]*types.MemoryEntry, error)
+
+	// GetRelatedTasks finds past tasks similar to the query
+	GetRelatedTasks(ctx context.Context, query string, limit int) ([]*types.MemoryEntry, error)
+
+	// GetKnowledge retrieves knowledge relevant to the query
+	GetKnowledge(ctx context.Context, query string, limit int) ([]*types.MemoryEntry, error)
+
+	// GetDecisionHistory retrieves past decisions
+	GetDecisionHistory(ctx context.Context, limit int) ([]*types.MemoryEntry, error)
+
+	// SearchMemory performs a semantic search across all memory types
+	SearchMemory(ctx context.Context, query string, limit int) ([]*types.MemoryEntry, error)
+
+	// BuildContext renders past tasks, decisions and knowledge relevant to a
+	// task as a prompt block of at most budgetTokens
+	BuildContext(ctx context.Context, taskDescription string, budgetTokens int) (string, error)
+}
+
+// MemoryOption configures an AgentMemory.
+type MemoryOption func(*agentMemory)
+
+// WithMemoryCache sets the size and TTL of the read cache. A size of 0 disables caching.
+func WithMemoryCache(size int, ttl time.Duration) MemoryOption {
+	return func(m *agentMemory) {
+		m.cache = newMemoryCache(size, ttl)
+	}
+}
+
+// agentMemory implements AgentMemory on top of a MemoryManager with an LRU read cache.
+//
+// Cached results are invalidated when the agent writes a memory type they were
+// derived from. Related-task results are not invalidated by new conversation
+// entries and may lag them by up to the cache TTL.
+type agentMemory struct {
+	manager   types.MemoryManager
+	cache     *memoryCache
+	agentID   string
+	deletions atomic.Uint64 // Deletion generation of the manager the cache reflects
+	writes    atomic.Uint64 // Write generation of the manager the cache reflects
+	enabled   bool
 }
 
 // NewAgentMemory creates a new agent memory instance.
-func NewAgentMemory(agentID string, manager types.MemoryManager) *AgentMemory {
-	return &AgentMemory{
+func NewAgentMemory(agentID string, manager types.MemoryManager, opts ...MemoryOption) AgentMemory {
+	m := &agentMemory{
 		manager: manager,
 		agentID: agentID,
 		enabled: manager != nil,
+		cache:   newMemoryCache(defaultMemoryCacheSize, defaultMemoryCacheTTL),
 	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// store persists an entry and invalidates cached results derived from its
+// type once it is stored. Managers that write asynchronously return before
+// the entry is persisted; see writeCounter for how their writes reach the cache.
+func (m *agentMemory) store(ctx context.Context, entry *types.MemoryEntry) error {
+	if err := m.manager.StoreMemory(ctx, entry); err != nil {
+		return err
+	}
+	m.cache.invalidate(entry.Type)
+	return nil
+}
+
+// deletionCounter is a memory manager that counts deletions of entries.
+type deletionCounter interface {
+	DeletionGeneration() uint64
+}
+
+// writeCounter is a memory manager that persists writes in the background
+// and counts the batches it persisted.
+type writeCounter interface {
+	WriteGeneration() uint64
+}
+
+// cached returns a cached query result or loads and caches it. Results are
+// dropped once entries were deleted from the manager, so erased memories do
+// not outlive their deletion in prompts, and once queued writes were
+// persisted, so results loaded while they were pending are not served stale.
+func (m *agentMemory) cached(key string, load func() ([]*types.MemoryEntry, error), deps ...types.MemoryType) ([]*types.MemoryEntry, error) {
+	if counter, ok := m.manager.(deletionCounter); ok {
+		if generation := counter.DeletionGeneration(); m.deletions.Swap(generation) != generation {
+			m.cache.clear()
+		}
+	}
+	if counter, ok := m.manager.(writeCounter); ok {
+		if generation := counter.WriteGeneration(); m.writes.Swap(generation) != generation {
+			m.cache.clear()
+		}
+	}
+	if entries, ok := m.cache.get(key); ok {
+		return entries, nil
+	}
+
+	generation := m.cache.generation(deps...)
+	entries, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	m.cache.put(key, entries, generation, deps...)
+	return entries, nil
 }
 
 // StoreConversation stores a conversation memory.
-func (m *AgentMemory) StoreConversation(ctx context.Context, content string, tags []string) error {
+func (m *agentMemory) StoreConversation(ctx context.Context, content string, tags []string) error {
 	if !m.enabled {
 		return nil // Silently skip if memory not enabled
 	}
@@ -40,11 +163,11 @@ func (m *AgentMemory) StoreConversation(ctx context.Context, content string, tag
 		},
 	}
 
-	return m.manager.StoreMemory(ctx, entry)
+	return m.store(ctx, entry)
 }
 
 // StoreTask stores a task-related memory.
-func (m *AgentMemory) StoreTask(ctx context.Context, task *types.Task, result string, tags []string) error {
+func (m *agentMemory) StoreTask(ctx context.Context, task *types.Task, result string, tags []string) error {
 	if !m.enabled {
 		return nil
 	}
@@ -56,6 +179,7 @@ func (m *AgentMemory) StoreTask(ctx context.Context, task *types.Task, result st
 		Type:    types.MemoryTypeTask,
 		Content: content,
 		Tags:    tags,
+		RunID:   task.RunID,
 		Metadata: map[string]string{
 			"task_id":    task.ID,
 			"from_agent": task.FromAgent,
@@ -65,11 +189,11 @@ func (m *AgentMemory) StoreTask(ctx context.Context, task *types.Task, result st
 		},
 	}
 
-	return m.manager.StoreMemory(ctx, entry)
+	return m.store(ctx, entry)
 }
 
 // StoreKnowledge stores learned knowledge.
-func (m *AgentMemory) StoreKnowledge(ctx context.Context, content string, tags []string) error {
+func (m *agentMemory) StoreKnowledge(ctx context.Context, content string, tags []string) error {
 	if !m.enabled {
 		return nil
 	}
@@ -84,11 +208,11 @@ func (m *AgentMemory) StoreKnowledge(ctx context.Context, content string, tags [
 		},
 	}
 
-	return m.manager.StoreMemory(ctx, entry)
+	return m.store(ctx, entry)
 }
 
 // StoreDecision stores a decision made by the agent.
-func (m *AgentMemory) StoreDecision(ctx context.Context, decision string, reasoning string, tags []string) error {
+func (m *agentMemory) StoreDecision(ctx context.Context, decision string, reasoning string, tags []string) error {
 	if !m.enabled {
 		return nil
 	}
@@ -107,71 +231,139 @@ func (m *AgentMemory) StoreDecision(ctx context.Context, decision string, reason
 		},
 	}
 
-	return m.manager.StoreMemory(ctx, entry)
+	return m.store(ctx, entry)
+}
+
+// StoreTaskDecision stores an extracted decision linked to the task it originated from.
+func (m *agentMemory) StoreTaskDecision(ctx context.Context, task *types.Task, decision Decision, tags []string) error {
+	if !m.enabled {
+		return nil
+	}
+
+	content := fmt.Sprintf("Decision: %s\nReasoning: %s", decision.What, decision.Why)
+	if len(decision.Alternatives) > 0 {
+		content += fmt.Sprintf("\nAlternatives rejected: %s", strings.Join(decision.Alternatives, "; "))
+	}
+
+	entry := &types.MemoryEntry{
+		AgentID: m.agentID,
+		Type:    types.MemoryTypeDecision,
+		Content: content,
+		Tags:    tags,
+		RunID:   task.RunID,
+		Metadata: map[string]string{
+			"decision":     decision.What,
+			"reasoning":    decision.Why,
+			"alternatives": strings.Join(decision.Alternatives, "; "),
+			"task_id":      task.ID,
+			"task_title":   task.Title,
+			"source":       "extracted",
+			"timestamp":    fmt.Sprintf("%d", time.Now().Unix()),
+		},
+	}
+
+	return m.store(ctx, entry)
 }
 
 // GetConversationHistory retrieves recent conversation history.
-func (m *AgentMemory) GetConversationHistory(ctx context.Context, limit int) ([]*types.MemoryEntry, error) {
+func (m *agentMemory) GetConversationHistory(ctx context.Context, limit int) ([]*types.MemoryEntry, error) {
 	if !m.enabled {
 		return nil, nil
 	}
 
-	return m.manager.GetConversationHistory(ctx, m.agentID, limit)
+	return m.cached(fmt.Sprintf("conversation|%d", limit), func() ([]*types.MemoryEntry, error) {
+		return m.manager.GetConversationHistory(ctx, m.agentID, limit)
+	}, types.MemoryTypeConversation)
 }
 
 // GetRelatedTasks finds tasks similar to the given query.
-func (m *AgentMemory) GetRelatedTasks(ctx context.Context, query string, limit int) ([]*types.MemoryEntry, error) {
+func (m *agentMemory) GetRelatedTasks(ctx context.Context, query string, limit int) ([]*types.MemoryEntry, error) {
 	if !m.enabled {
 		return nil, nil
 	}
 
-	// Try semantic search first, fall back to text search
-	results, err := m.manager.SemanticSearch(ctx, query, m.agentID, limit)
-	if err != nil {
-		// Fallback to basic query
-		return m.manager.QueryMemories(ctx, &types.MemoryQuery{
-			AgentID: m.agentID,
-			Type:    types.MemoryTypeTask,
-			Content: query,
-			Limit:   limit,
-		})
-	}
+	return m.cached(fmt.Sprintf("related|%d|%s", limit, query), func() ([]*types.MemoryEntry, error) {
+		// Try semantic search first, fall back to text search
+		results, err := m.manager.SemanticSearch(ctx, query, m.agentID, limit)
+		if err != nil {
+			// Fallback to basic query
+			return m.manager.QueryMemories(ctx, &types.MemoryQuery{
+				AgentID: m.agentID,
+				Type:    types.MemoryTypeTask,
+				Content: query,
+				Limit:   limit,
+			})
+		}
 
-	return results, nil
+		return results, nil
+	}, types.MemoryTypeTask, types.MemoryTypeKnowledge, types.MemoryTypeDecision)
 }
 
 // GetKnowledge retrieves relevant knowledge based on query.
-func (m *AgentMemory) GetKnowledge(ctx context.Context, query string, limit int) ([]*types.MemoryEntry, error) {
+func (m *agentMemory) GetKnowledge(ctx context.Context, query string, limit int) ([]*types.MemoryEntry, error) {
 	if !m.enabled {
 		return nil, nil
 	}
 
-	return m.manager.QueryMemories(ctx, &types.MemoryQuery{
-		AgentID: m.agentID,
-		Type:    types.MemoryTypeKnowledge,
-		Content: query,
-		Limit:   limit,
-	})
+	return m.cached(fmt.Sprintf("knowledge|%d|%s", limit, query), func() ([]*types.MemoryEntry, error) {
+		return m.manager.QueryMemories(ctx, &types.MemoryQuery{
+			AgentID: m.agentID,
+			Type:    types.MemoryTypeKnowledge,
+			Content: query,
+			Limit:   limit,
+		})
+	}, types.MemoryTypeKnowledge)
 }
 
 // GetDecisionHistory retrieves past decisions.
-func (m *AgentMemory) GetDecisionHistory(ctx context.Context, limit int) ([]*types.MemoryEntry, error) {
+func (m *agentMemory) GetDecisionHistory(ctx context.Context, limit int) ([]*types.MemoryEntry, error) {
 	if !m.enabled {
 		return nil, nil
 	}
 
-	return m.manager.QueryMemories(ctx, &types.MemoryQuery{
-		AgentID: m.agentID,
-		Type:    types.MemoryTypeDecision,
-		Limit:   limit,
-	})
+	return m.cached(fmt.Sprintf("decision|%d", limit), func() ([]*types.MemoryEntry, error) {
+		return m.manager.QueryMemories(ctx, &types.MemoryQuery{
+			AgentID: m.agentID,
+			Type:    types.MemoryTypeDecision,
+			Limit:   limit,
+		})
+	}, types.MemoryTypeDecision)
 }
 
 // SearchMemory performs a semantic search across all memory types.
-func (m *AgentMemory) SearchMemory(ctx context.Context, query string, limit int) ([]*types.MemoryEntry, error) {
+func (m *agentMemory) SearchMemory(ctx context.Context, query string, limit int) ([]*types.MemoryEntry, error) {
 	if !m.enabled {
 		return nil, nil
 	}
 
-	return m.manager.SemanticSearch(ctx, query, m.agentID, limit)
+	return m.cached(fmt.Sprintf("search|%d|%s", limit, query), func() ([]*types.MemoryEntry, error) {
+		return m.manager.SemanticSearch(ctx, query, m.agentID, limit)
+	}, types.MemoryTypeConversation, types.MemoryTypeTask, types.MemoryTypeKnowledge, types.MemoryTypeDecision)
+}
+
+// BuildContext renders past tasks, decisions and knowledge relevant to a task
+// as a prompt block of at most budgetTokens, fetched in one search.
+func (m *agentMemory) BuildContext(ctx context.Context, taskDescription string, budgetTokens int) (string, error) {
+	if !m.enabled || budgetTokens <= 0 {
+		return "", nil
+	}
+
+	entries, err := m.cached("context|"+taskDescription, func() ([]*types.MemoryEntry, error) {
+		results, err := m.manager.SemanticSearch(ctx, taskDescription, m.agentID, memory.ContextCandidates)
+		if err != nil {
+			// Fallback to basic query
+			return m.manager.QueryMemories(ctx, &types.MemoryQuery{
+				AgentID: m.agentID,
+				Content: taskDescription,
+				Limit:   memory.ContextCandidates,
+			})
+		}
+
+		return results, nil
+	}, types.MemoryTypeTask, types.MemoryTypeKnowledge, types.MemoryTypeDecision)
+	if err != nil {
+		return "", err
+	}
+
+	return memory.RenderContext(entries, m.agentID, budgetTokens), nil
 }
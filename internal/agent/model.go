@@ -0,0 +1,202 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/kpango/BuildBureau/internal/errors"
+	"github.com/kpango/BuildBureau/internal/llm"
+	"github.com/kpango/BuildBureau/internal/logging"
+	"github.com/kpango/BuildBureau/internal/version"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// defaultModel is used by agents whose configuration does not name a model.
+const defaultModel = "gemini"
+
+// AgentInfo describes an agent for administration.
+type AgentInfo struct {
+	ID             string          `json:"id"`
+	Role           types.AgentRole `json:"role"`
+	Model          string          `json:"model"`
+	Context        ContextStats    `json:"context"`
+	ActiveTasks    int             `json:"active_tasks"`
+	CompletedTasks int             `json:"completed_tasks"`
+}
+
+// Model returns the LLM model the agent generates with.
+func (a *BaseAgent) Model() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.model != "" {
+		return a.model
+	}
+	if a.config != nil && a.config.Model != "" {
+		return a.config.Model
+	}
+	return defaultModel
+}
+
+// SetModel switches the model the agent generates with. It waits for
+// generations already in flight to finish on the old model; generations
+// started while it waits use the new one.
+func (a *BaseAgent) SetModel(model string) {
+	a.generating.Lock()
+	defer a.generating.Unlock()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.model = model
+}
+
+// generate sends a prompt to the agent's current model.
+func (a *BaseAgent) generate(ctx context.Context, llmManager *llm.Manager, prompt string, opts *llm.GenerateOptions) (string, error) {
+	a.generating.RLock()
+	defer a.generating.RUnlock()
+
+	model := a.Model()
+	ctx, done := a.startGeneration(ctx, model)
+	defer done()
+
+	a.recordUsage(ctx, llmManager, model, prompt, opts)
+	// Record the pinned model version, so runs in reproducibility mode can be compared
+	recorded := llmManager.Pinned(model)
+	a.record(ctx, logging.KindPrompt, recorded, prompt)
+
+	response, err := llmManager.Generate(ctx, model, prompt, opts)
+	if err != nil {
+		a.record(ctx, logging.KindError, recorded, err.Error())
+		return "", err
+	}
+
+	a.record(ctx, logging.KindResponse, recorded, response)
+	return response, nil
+}
+
+// recordReproducibility records the settings a project runs with in
+// reproducibility mode, alongside the prompts and responses its agents record.
+func (o *Organization) recordReproducibility(runID string) {
+	if o.llmManager == nil || o.activity == nil {
+		return
+	}
+	r := o.llmManager.Reproducibility()
+	if !r.Enabled {
+		return
+	}
+
+	pinned := "none"
+	if len(r.Models) > 0 {
+		var models []string
+		for _, name := range slices.Sorted(maps.Keys(r.Models)) {
+			models = append(models, name+"="+r.Models[name])
+		}
+		pinned = strings.Join(models, ", ")
+	}
+	o.activity.Log(organizationLog, logging.Entry{
+		Kind:    logging.KindEvent,
+		RunID:   runID,
+		Content: fmt.Sprintf("reproducibility mode: temperature 0, seed %d, pinned models %s", r.Seed, pinned),
+	})
+}
+
+// Agents describes every agent in the organization.
+func (o *Organization) Agents() []AgentInfo {
+	agents := o.allAgents()
+	infos := make([]AgentInfo, 0, len(agents))
+
+	for _, a := range agents {
+		info := AgentInfo{ID: a.GetID(), Role: a.GetRole()}
+		if m, ok := a.(interface{ Model() string }); ok {
+			info.Model = m.Model()
+		}
+		if counter, ok := a.(interface{ GetStats() (int, int) }); ok {
+			info.ActiveTasks, info.CompletedTasks = counter.GetStats()
+		}
+		if budget, ok := a.(interface{ ContextStats() ContextStats }); ok {
+			info.Context = budget.ContextStats()
+		}
+		infos = append(infos, info)
+	}
+
+	return infos
+}
+
+// Describe reports the agent's role, model, capabilities, load and version.
+func (a *BaseAgent) Describe() types.AgentDescription {
+	active, completed := a.GetStats()
+	d := types.AgentDescription{
+		ID:             a.id,
+		Role:           a.role,
+		Model:          a.Model(),
+		Team:           a.team,
+		Capabilities:   a.Capabilities(),
+		Status:         "stopped",
+		ActiveTasks:    active,
+		CompletedTasks: completed,
+		Version:        version.Get().Version,
+	}
+	if a.IsRunning() {
+		d.Status = "running"
+		for _, health := range a.GetMemoryHealth() {
+			if !health.Healthy {
+				d.Status = "degraded"
+				break
+			}
+		}
+	}
+	return d
+}
+
+// DescribeAgent describes the agent with the given ID.
+func (o *Organization) DescribeAgent(agentID string) (types.AgentDescription, error) {
+	for _, a := range o.allAgents() {
+		if a.GetID() != agentID {
+			continue
+		}
+		if d, ok := a.(interface{ Describe() types.AgentDescription }); ok {
+			return d.Describe(), nil
+		}
+		return types.AgentDescription{ID: agentID, Role: a.GetRole(), Status: "running", Version: version.Get().Version}, nil
+	}
+	return types.AgentDescription{}, errors.Newf(errors.CodeNotFound, "agent %s not found", agentID)
+}
+
+// SetModel switches the model of every agent with the given role, or of the
+// agent with the given ID, without restarting the organization. Each agent
+// finishes its in-flight generations on the old model first. It returns the IDs
+// of the agents that were switched.
+func (o *Organization) SetModel(target, model string) ([]string, error) {
+	if model == "" {
+		return nil, fmt.Errorf("model must not be empty")
+	}
+	if o.llmManager != nil && !o.llmManager.HasModel(model) {
+		return nil, fmt.Errorf("model %s is not served by any configured provider", model)
+	}
+
+	var matched []interface{ SetModel(string) }
+	var ids []string
+	for _, a := range o.allAgents() {
+		if a.GetID() != target && !strings.EqualFold(string(a.GetRole()), target) {
+			continue
+		}
+		setter, ok := a.(interface{ SetModel(string) })
+		if !ok {
+			continue
+		}
+		matched = append(matched, setter)
+		ids = append(ids, a.GetID())
+	}
+	if len(matched) == 0 {
+		return nil, fmt.Errorf("no agent or role named %s", target)
+	}
+
+	for _, setter := range matched {
+		setter.SetModel(model)
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,82 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/kpango/BuildBureau/internal/config"
+	"github.com/kpango/BuildBureau/internal/desktop"
+	"github.com/kpango/BuildBureau/internal/outbox"
+	"github.com/kpango/BuildBureau/internal/quiet"
+	"github.com/kpango/BuildBureau/internal/slack"
+)
+
+// namedNotifier is a notification destination.
+type namedNotifier struct {
+	name     string
+	notifier Notifier
+}
+
+// notifiers delivers every notification to several destinations.
+type notifiers []namedNotifier
+
+// Notify delivers a notification to every destination, returning their errors joined.
+func (n notifiers) Notify(ctx context.Context, notificationType, message string) error {
+	var errs []error
+	for _, d := range n {
+		if err := d.notifier.Notify(ctx, notificationType, message); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", d.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// openNotifier sets up the configured notification destinations: the desktop
+// and Slack. With the outbox enabled, notifications are stored and retried
+// until delivered; during quiet hours they are held back or dropped. It
+// returns nil if no destination is enabled.
+func (o *Organization) openNotifier() Notifier {
+	var destinations notifiers
+	if desktopNotifier := desktop.NewNotifier(o.config.Desktop); desktopNotifier.Enabled() {
+		destinations = append(destinations, namedNotifier{name: "desktop", notifier: desktopNotifier})
+	}
+	if cfg := o.config.Slack; cfg != nil && cfg.Enabled {
+		slackNotifier, err := slack.NewNotifier(cfg, config.GetEnvValue(cfg.Token))
+		if err != nil {
+			fmt.Printf("Warning: Slack notifications disabled: %v\n", err)
+		} else {
+			destinations = append(destinations, namedNotifier{name: "slack", notifier: slackNotifier})
+		}
+	}
+	if len(destinations) == 0 {
+		return nil
+	}
+
+	var notifier Notifier = destinations
+	if len(destinations) == 1 {
+		notifier = destinations[0].notifier
+	}
+
+	if cfg := o.config.Outbox; cfg != nil && cfg.Enabled {
+		opts := []outbox.Option{outbox.WithMaxAttempts(cfg.MaxAttempts), outbox.WithBackoff(cfg.Backoff, cfg.MaxBackoff)}
+		for _, d := range destinations {
+			opts = append(opts, outbox.WithSender(d.name, d.notifier))
+		}
+		box, err := outbox.Open(cmp.Or(cfg.Path, outbox.DefaultPath), opts...)
+		if err != nil {
+			fmt.Printf("Warning: Delivering notifications without the outbox: %v\n", err)
+		} else {
+			o.outbox = box
+			notifier = box
+		}
+	}
+
+	// During quiet hours notifications are held back until they end or dropped
+	if o.quietHours != nil {
+		o.quietNotifier = quiet.NewNotifier(o.quietHours, notifier)
+		notifier = o.quietNotifier
+	}
+	return notifier
+}
@@ -0,0 +1,207 @@
+package agent
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/kpango/BuildBureau/internal/imagegen"
+	"github.com/kpango/BuildBureau/internal/llm"
+	"github.com/kpango/BuildBureau/internal/logging"
+	"github.com/kpango/BuildBureau/internal/profile"
+	"github.com/kpango/BuildBureau/internal/provenance"
+	"github.com/kpango/BuildBureau/internal/slo"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// Option configures optional agent behavior at construction time.
+type Option func(*BaseAgent)
+
+// Notifier delivers agent notifications, e.g. to Slack.
+type Notifier interface {
+	Notify(ctx context.Context, notificationType, message string) error
+}
+
+// WithMemory attaches a memory manager to the agent.
+func WithMemory(manager types.MemoryManager) Option {
+	return func(a *BaseAgent) {
+		if manager != nil {
+			a.memoryManager = manager
+			a.memory = NewAgentMemory(a.id, manager)
+		}
+	}
+}
+
+// WithNotifier sets the notifier used to announce task assignments.
+func WithNotifier(notifier Notifier) Option {
+	return func(a *BaseAgent) {
+		a.notifier = notifier
+	}
+}
+
+// ActivityLog receives agent activity such as prompts, responses and errors.
+type ActivityLog interface {
+	Log(agentID string, entry logging.Entry)
+}
+
+// WithLogger sets the logger used for agent activity.
+func WithLogger(logger *log.Logger) Option {
+	return func(a *BaseAgent) {
+		a.logger = logger
+	}
+}
+
+// WithActivityLog records the agent's prompts, responses, errors and activity.
+func WithActivityLog(activity ActivityLog) Option {
+	return func(a *BaseAgent) {
+		a.activity = activity
+	}
+}
+
+// WithTimeout bounds how long a single ProcessTask call may run.
+// A zero duration means no timeout.
+func WithTimeout(timeout time.Duration) Option {
+	return func(a *BaseAgent) {
+		a.timeout = timeout
+	}
+}
+
+// WithRecovery sets how the agent re-plans a delegated branch that fails.
+func WithRecovery(recovery types.RecoveryConfig) Option {
+	return func(a *BaseAgent) {
+		a.recovery = recovery
+	}
+}
+
+// WithPartialFailure sets how the agent treats subtasks delegated in parallel
+// when some fail: the policy configured for its role, or the default.
+func WithPartialFailure(cfg types.PartialFailureConfig) Option {
+	return func(a *BaseAgent) {
+		a.partialFailure = cfg.Default
+		for role, p := range cfg.Roles {
+			if strings.EqualFold(role, string(a.role)) {
+				a.partialFailure = p
+			}
+		}
+	}
+}
+
+// WithDeduplicator shares results between agents that receive nearly identical tasks.
+// Agents that should share results must use the same Deduplicator.
+func WithDeduplicator(dedup *Deduplicator) Option {
+	return func(a *BaseAgent) {
+		a.dedup = dedup
+	}
+}
+
+// WithProfiles keeps the agent's long-term profile in store. The profile is
+// included in the agent's prompts and used to route tasks to it.
+func WithProfiles(store *profile.Store) Option {
+	return func(a *BaseAgent) {
+		a.profiles = store
+	}
+}
+
+// WithStatusReports has the agent send a compact status summary to its parent
+// along with its deliverable, and report its subordinates' summaries instead of
+// their full output.
+func WithStatusReports(enabled bool) Option {
+	return func(a *BaseAgent) {
+		a.statusReports = enabled
+	}
+}
+
+// WithQuestions lets the agent ask clarifying questions up the hierarchy mid-task.
+func WithQuestions(questions types.QuestionsConfig) Option {
+	return func(a *BaseAgent) {
+		a.questions = questions
+	}
+}
+
+// WithPairing has a second engineer critique drafts of complex tasks before
+// they are submitted.
+func WithPairing(pairing types.PairingConfig) Option {
+	return func(a *BaseAgent) {
+		a.pairing = pairing
+	}
+}
+
+// WithPartitioning has directors split projects into parts by specialty and
+// hand each to the matching manager, using llmManager to divide the work.
+func WithPartitioning(partitioning types.PartitioningConfig, llmManager *llm.Manager) Option {
+	return func(a *BaseAgent) {
+		a.partitioning = partitioning
+		a.partitionLLM = llmManager
+	}
+}
+
+// WithBlackboard shares a blackboard between the agents working on a task
+// subtree; agents in the configured scope open one for each task.
+func WithBlackboard(blackboard types.BlackboardConfig) Option {
+	return func(a *BaseAgent) {
+		a.blackboard = blackboard
+	}
+}
+
+// WithInboxes has the agent hand tasks to subordinates through their inboxes
+// instead of calling them directly.
+func WithInboxes(inboxes *Inboxes) Option {
+	return func(a *BaseAgent) {
+		a.inboxes = inboxes
+	}
+}
+
+// WithSLOs records how long the agent's delegated tasks take and whether they
+// fail, so the tracker can evaluate service level objectives.
+func WithSLOs(tracker *slo.Tracker) Option {
+	return func(a *BaseAgent) {
+		a.slos = tracker
+	}
+}
+
+// WithProvenance has engineers add a license header to the files they
+// generate and check them against known code, as the stamper is configured.
+func WithProvenance(stamper *provenance.Stamper) Option {
+	return func(a *BaseAgent) {
+		a.provenance = stamper
+	}
+}
+
+// WithImages lets engineers request UI mockups, logos and diagrams, which
+// the generator produces and stores as files of their deliverables.
+func WithImages(generator *imagegen.Generator) Option {
+	return func(a *BaseAgent) {
+		a.images = generator
+	}
+}
+
+// WithClientPool shares provider clients, usually those of the organization's
+// LLM manager, with agents that call a provider SDK directly.
+func WithClientPool(pool *llm.ClientPool) Option {
+	return func(a *BaseAgent) {
+		a.clients = pool
+	}
+}
+
+// WithRouting sets the rules that send labeled tasks to a team or agent.
+func WithRouting(rules []types.RoutingRule) Option {
+	return func(a *BaseAgent) {
+		a.routing = rules
+	}
+}
+
+// WithTeam records the team the agent belongs to, so routing rules can name it.
+func WithTeam(name string) Option {
+	return func(a *BaseAgent) {
+		a.team = name
+	}
+}
+
+// WithConventions sets the organization's output conventions, which are added
+// to the prompts of agents that produce deliverables.
+func WithConventions(conventions *Conventions) Option {
+	return func(a *BaseAgent) {
+		a.conventions = conventions
+	}
+}
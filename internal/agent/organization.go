@@ -3,47 +3,157 @@ package agent
 import (
 	"context"
 	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/kpango/BuildBureau/internal/config"
+	"github.com/kpango/BuildBureau/internal/desktop"
+	"github.com/kpango/BuildBureau/internal/errors"
+	"github.com/kpango/BuildBureau/internal/judge"
 	"github.com/kpango/BuildBureau/internal/llm"
+	"github.com/kpango/BuildBureau/internal/logging"
+	"github.com/kpango/BuildBureau/internal/memory"
+	"github.com/kpango/BuildBureau/internal/outbox"
+	"github.com/kpango/BuildBureau/internal/profile"
+	"github.com/kpango/BuildBureau/internal/quiet"
+	"github.com/kpango/BuildBureau/internal/scan"
+	"github.com/kpango/BuildBureau/internal/slack"
+	"github.com/kpango/BuildBureau/internal/slo"
+	"github.com/kpango/BuildBureau/internal/speech"
+	"github.com/kpango/BuildBureau/internal/webhook"
 	"github.com/kpango/BuildBureau/pkg/types"
 )
 
 // Organization manages the entire agent hierarchy.
 type Organization struct {
-	president   types.Agent
-	config      *types.Config
-	secretaries map[string]types.Agent
-	llmManager  *llm.Manager
-	directors   []types.Agent
-	managers    []types.Agent
-	engineers   []types.Agent
+	president     types.Agent
+	client        *ClientAgent
+	config        *types.Config
+	secretaries   map[string]types.Agent
+	llmManager    *llm.Manager
+	memoryManager types.MemoryManager
+	dedup         *Deduplicator
+	conventions   *Conventions // Output conventions deliverables are checked against
+	activity      *activityFeed
+	notifier      Notifier
+	quietHours    *quiet.Hours
+	quietNotifier *quiet.Notifier // Wraps the notifier when quiet hours are configured
+	outbox        *outbox.Outbox  // Stores notifications until delivered, when enabled
+	slos          *slo.Tracker    // Evaluates service level objectives, when enabled
+	judges        *judge.Panel    // Scores deliverables, when enabled
+	scanner       *scan.Runner    // Scans produced files for security problems, when enabled
+	webhooks      *webhook.Dispatcher
+	speech        *speech.Speaker // Reads project digests aloud, when enabled
+	demo          *demoGate       // Limits and watermarks submissions of a demo instance
+	profiles      *profile.Store
+	knowledge     *KnowledgeBase
+	agentConfigs  map[string]*types.AgentConfig
+	inflight      map[string]TaskSnapshot
+	questions     map[string]*pendingQuestion // Questions waiting for a human answer
+	inboxes       *Inboxes
+	admission     *admission
+	projects      map[string]*ProjectStats // Scheduling statistics by run ID
+	edges         map[string][]types.Agent // Subordinates by agent ID when teams are configured
+	stopInboxes   context.CancelFunc
+	pending       []TaskSnapshot
+	directors     []types.Agent
+	managers      []types.Agent
+	engineers     []types.Agent
+	inboxWorkers  sync.WaitGroup
+	mu            sync.Mutex
+	startup       StartupReport
+	startedAt     time.Time // When the current session started
+	started       bool      // The agents have started and Stop has not been called
+	draining      bool      // Drain was called; no new projects are admitted
 }
 
 // NewOrganization creates a new organization from configuration.
 func NewOrganization(cfg *types.Config) (*Organization, error) {
-	org := &Organization{
-		config:      cfg,
-		directors:   make([]types.Agent, 0),
-		managers:    make([]types.Agent, 0),
-		engineers:   make([]types.Agent, 0),
-		secretaries: make(map[string]types.Agent),
-	}
-
 	// Initialize LLM manager
 	llmMgr, err := llm.NewManager(&cfg.LLMs)
 	if err != nil {
 		fmt.Printf("Warning: Failed to initialize LLM manager: %v\n", err)
 		fmt.Println("Agents will work without LLM assistance")
 	} else {
-		org.llmManager = llmMgr
 		fmt.Println("✓ LLM manager initialized successfully")
 	}
 
+	return NewOrganizationWithLLM(cfg, llmMgr)
+}
+
+// NewOrganizationWithLLM creates a new organization that uses an existing LLM manager.
+// A nil manager creates an organization whose agents work without LLM assistance.
+func NewOrganizationWithLLM(cfg *types.Config, llmManager *llm.Manager) (*Organization, error) {
+	return newOrganization(cfg, llmManager, nil)
+}
+
+// newOrganization creates an organization, using agentConfigs for layers it
+// contains and loading the remaining agent configurations from disk.
+func newOrganization(cfg *types.Config, llmManager *llm.Manager, agentConfigs map[string]*types.AgentConfig) (*Organization, error) {
+	// A demo never calls a paid model, whatever manager it was given
+	if cfg.Demo != nil && cfg.Demo.Enabled {
+		cfg = RestrictForDemo(cfg)
+		demoManager, err := newDemoLLMManager(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize demo mode: %w", err)
+		}
+		llmManager = demoManager
+	}
+
+	org := &Organization{
+		config:       cfg,
+		llmManager:   llmManager,
+		directors:    make([]types.Agent, 0),
+		managers:     make([]types.Agent, 0),
+		engineers:    make([]types.Agent, 0),
+		secretaries:  make(map[string]types.Agent),
+		agentConfigs: make(map[string]*types.AgentConfig),
+		inflight:     make(map[string]TaskSnapshot),
+		admission:    newAdmission(cfg.Organization.Admission),
+		demo:         newDemoGate(cfg.Demo),
+	}
+	for layer, agentCfg := range agentConfigs {
+		org.agentConfigs[layer] = agentCfg
+	}
+
+	conventions, err := NewConventions(cfg.Organization.Conventions)
+	if err != nil {
+		return nil, err
+	}
+	org.conventions = conventions
+	org.judges = org.openJudges()
+	org.scanner = org.openScanner()
+	org.webhooks = org.openWebhooks()
+	org.speech = org.openSpeech()
+
 	if err := org.buildHierarchy(); err != nil {
 		return nil, fmt.Errorf("failed to build hierarchy: %w", err)
 	}
+	if err := org.openInboxes(); err != nil {
+		return nil, err
+	}
+
+	// Initialize memory manager if enabled
+	if cfg.Memory != nil && cfg.Memory.Enabled {
+		var opts []memory.Option
+		if org.quietHours != nil {
+			opts = append(opts, memory.WithPause(org.quietHours.PausesBackground))
+		}
+		memMgr, err := memory.NewManager(cfg.Memory, org.llmManager, opts...)
+		if err != nil {
+			fmt.Printf("Warning: Failed to initialize memory manager: %v\n", err)
+			fmt.Println("Agents will work without persistent memory")
+		} else {
+			org.SetMemoryManager(memMgr)
+			fmt.Println("✓ Memory manager initialized successfully")
+		}
+	}
 
 	return org, nil
 }
@@ -52,21 +162,117 @@ func NewOrganization(cfg *types.Config) (*Organization, error) {
 func (o *Organization) buildHierarchy() error {
 	loader := config.NewLoader()
 
+	// Agent activity goes to subscribers and, if enabled, to a log file per agent
+	o.activity = &activityFeed{}
+	if o.config.Logging != nil && o.config.Logging.Enabled {
+		files, err := logging.NewManager(*o.config.Logging)
+		if err != nil {
+			fmt.Printf("Warning: Failed to initialize agent logs: %v\n", err)
+		} else {
+			o.activity.files = files
+		}
+	}
+	common := []Option{
+		WithActivityLog(o.activity),
+		WithStatusReports(o.config.Organization.StatusReports.Enabled),
+		WithQuestions(o.config.Organization.Questions),
+		WithBlackboard(o.config.Organization.Blackboard),
+		WithRouting(o.config.Organization.Routing),
+		WithConventions(o.conventions),
+	}
+
+	// Agent activity can also be followed on the console
+	if o.config.Logging != nil && o.config.Logging.Console {
+		common = append(common, WithLogger(log.New(os.Stderr, "", log.LstdFlags)))
+	}
+
+	// With inboxes, tasks are queued for busy agents instead of handed over directly
+	if o.config.Organization.Inbox.Enabled {
+		o.inboxes = NewInboxes()
+		common = append(common, WithInboxes(o.inboxes))
+	}
+
+	// Desktop notifications announce finished projects and, if the user asks
+	// for them in notify_on, agent events such as task assignment
+	hours, err := quiet.New(o.config.QuietHours)
+	if err != nil {
+		fmt.Printf("Warning: Ignoring quiet hours: %v\n", err)
+	}
+	o.quietHours = hours
+	if notifier := o.openNotifier(); notifier != nil {
+		o.notifier = notifier
+		common = append(common, WithNotifier(notifier))
+	}
+
+	// Delegated tasks count toward service level objectives, which alert when breached
+	if o.slos = o.openSLOs(); o.slos != nil {
+		common = append(common, WithSLOs(o.slos))
+	}
+
+	// Long-term profiles let agents specialize across projects
+	if cfg := o.config.Profiles; cfg != nil && cfg.Enabled {
+		store, err := profile.Open(cfg.Path)
+		if err != nil {
+			fmt.Printf("Warning: Failed to load agent profiles: %v\n", err)
+		} else {
+			o.profiles = store
+			common = append(common, WithProfiles(store))
+		}
+	}
+
+	// Agents that delegate re-plan failed branches according to the recovery policy
+	// and treat mixed outcomes of parallel branches according to their role's policy
+	delegating := append(slices.Clone(common), WithRecovery(o.config.Organization.Recovery), WithPartialFailure(o.config.Organization.PartialFailure), WithPairing(o.config.Organization.Pairing))
+
+	// Engineers share one deduplicator so siblings can reuse each other's results
+	if o.config.Organization.Deduplication.Enabled {
+		o.dedup = NewDeduplicator(o.config.Organization.Deduplication)
+	}
+	engineering := append(slices.Clone(common), WithDeduplicator(o.dedup))
+
+	// Engineers record where their files came from and run the configured compliance checks
+	if stamper := o.openProvenance(); stamper != nil {
+		engineering = append(engineering, WithProvenance(stamper))
+	}
+
+	// Engineers may request images for design deliverables
+	if images := o.openImages(); images != nil {
+		engineering = append(engineering, WithImages(images))
+	}
+
+	// Directors may split projects between managers by specialty
+	directing := append(slices.Clone(delegating), WithPartitioning(o.config.Organization.Partitioning, o.llmManager))
+
+	// Teams replace the Director, Manager and Engineer layers
+	teams := len(o.config.Organization.Teams) > 0
+	if teams {
+		if err := o.buildTeams(loader, directing, delegating, engineering); err != nil {
+			return err
+		}
+	}
+
 	// Create agents for each layer
 	for _, layer := range o.config.Organization.Layers {
+		if teams && slices.Contains([]string{"Director", "Manager", "Engineer"}, layer.Name) {
+			continue
+		}
+
+		// Each layer may bound how long its agents spend on one task
+		timeout := WithTimeout(layer.Timeout)
+
 		switch layer.Name {
 		case "President":
 			if layer.Agent != "" {
-				agentCfg, err := loader.LoadAgentConfig(layer.Agent)
+				agentCfg, err := o.loadAgentConfig(loader, layer)
 				if err != nil {
 					return fmt.Errorf("failed to load president config: %w", err)
 				}
-				o.president = NewPresidentAgent("president-1", agentCfg)
+				o.president = NewPresidentAgent("president-1", agentCfg, append(slices.Clone(common), timeout)...)
 			}
 
 		case "Director":
 			if layer.Agent != "" {
-				agentCfg, err := loader.LoadAgentConfig(layer.Agent)
+				agentCfg, err := o.loadAgentConfig(loader, layer)
 				if err != nil {
 					return fmt.Errorf("failed to load director config: %w", err)
 				}
@@ -75,14 +281,14 @@ func (o *Organization) buildHierarchy() error {
 					count = 1
 				}
 				for i := 0; i < count; i++ {
-					director := NewDirectorAgent(fmt.Sprintf("director-%d", i+1), agentCfg)
+					director := NewDirectorAgent(fmt.Sprintf("director-%d", i+1), agentCfg, append(slices.Clone(directing), timeout)...)
 					o.directors = append(o.directors, director)
 				}
 			}
 
 		case "Manager":
 			if layer.Agent != "" {
-				agentCfg, err := loader.LoadAgentConfig(layer.Agent)
+				agentCfg, err := o.loadAgentConfig(loader, layer)
 				if err != nil {
 					return fmt.Errorf("failed to load manager config: %w", err)
 				}
@@ -91,14 +297,14 @@ func (o *Organization) buildHierarchy() error {
 					count = 1
 				}
 				for i := 0; i < count; i++ {
-					manager := NewManagerAgent(fmt.Sprintf("manager-%d", i+1), agentCfg, o.llmManager)
+					manager := NewManagerAgent(fmt.Sprintf("manager-%d", i+1), agentCfg, o.llmManager, append(slices.Clone(delegating), timeout)...)
 					o.managers = append(o.managers, manager)
 				}
 			}
 
 		case "Engineer":
 			if layer.Agent != "" {
-				agentCfg, err := loader.LoadAgentConfig(layer.Agent)
+				agentCfg, err := o.loadAgentConfig(loader, layer)
 				if err != nil {
 					return fmt.Errorf("failed to load engineer config: %w", err)
 				}
@@ -107,20 +313,29 @@ func (o *Organization) buildHierarchy() error {
 					count = 1
 				}
 				for i := 0; i < count; i++ {
-					engineer := NewEngineerAgent(fmt.Sprintf("engineer-%d", i+1), agentCfg, o.llmManager)
+					engineer := NewEngineerAgent(fmt.Sprintf("engineer-%d", i+1), agentCfg, o.llmManager, append(slices.Clone(engineering), timeout)...)
 					o.engineers = append(o.engineers, engineer)
 				}
 			}
 
+		case "Client":
+			if layer.Agent != "" {
+				agentCfg, err := o.loadAgentConfig(loader, layer)
+				if err != nil {
+					return fmt.Errorf("failed to load client config: %w", err)
+				}
+				o.client = NewClientAgent("client-1", agentCfg, o.llmManager, append(slices.Clone(common), timeout)...)
+			}
+
 		case "Secretary":
 			if layer.Agent != "" {
-				agentCfg, err := loader.LoadAgentConfig(layer.Agent)
+				agentCfg, err := o.loadAgentConfig(loader, layer)
 				if err != nil {
 					return fmt.Errorf("failed to load secretary config: %w", err)
 				}
 				// Create secretaries for each specified attachment point
 				for _, attachTo := range layer.AttachTo {
-					secretary := NewSecretaryAgent(fmt.Sprintf("secretary-%s", attachTo), agentCfg)
+					secretary := NewSecretaryAgent(fmt.Sprintf("secretary-%s", attachTo), agentCfg, append(slices.Clone(delegating), timeout)...)
 					o.secretaries[attachTo] = secretary
 				}
 			}
@@ -131,6 +346,22 @@ func (o *Organization) buildHierarchy() error {
 	return o.wireHierarchy()
 }
 
+// loadAgentConfig returns the configuration for a layer's agents, loading it
+// from disk unless it was restored from a snapshot.
+func (o *Organization) loadAgentConfig(loader *config.Loader, layer types.LayerConfig) (*types.AgentConfig, error) {
+	if agentCfg, ok := o.agentConfigs[layer.Name]; ok {
+		return agentCfg, nil
+	}
+
+	agentCfg, err := loader.LoadAgentConfig(layer.Agent)
+	if err != nil {
+		return nil, err
+	}
+	o.agentConfigs[layer.Name] = agentCfg
+
+	return agentCfg, nil
+}
+
 // wireHierarchy connects agents to their subordinates and secretaries.
 func (o *Organization) wireHierarchy() error {
 	// Attach secretaries
@@ -154,7 +385,7 @@ func (o *Organization) wireHierarchy() error {
 				directorAgent.SetSecretary(directorSecretary)
 			}
 			// Add managers to each director
-			for _, manager := range o.managers {
+			for _, manager := range o.subordinates(director, o.managers) {
 				directorAgent.AddManager(manager)
 			}
 		}
@@ -167,7 +398,7 @@ func (o *Organization) wireHierarchy() error {
 				managerAgent.SetSecretary(managerSecretary)
 			}
 			// Add engineers to each manager
-			for _, engineer := range o.engineers {
+			for _, engineer := range o.subordinates(manager, o.engineers) {
 				managerAgent.AddEngineer(engineer)
 			}
 		}
@@ -176,25 +407,50 @@ func (o *Organization) wireHierarchy() error {
 	return nil
 }
 
-// Start initializes all agents in the organization.
+// Start starts the organization in dependency order: it checks its memory
+// stores, sets up the provider clients of every role's model, then starts the
+// agents from the engineers up to the president, so that each agent's
+// subordinates are running before it can delegate to them. Stores and
+// providers that are not ready leave agents degraded rather than failing the
+// start; agents that fail are handled as described by startAgents. How each
+// stage went is reported by StartupReport.
 func (o *Organization) Start(ctx context.Context) error {
-	agents := []types.Agent{}
-
-	if o.president != nil {
-		agents = append(agents, o.president)
+	var report StartupReport
+	if o.config != nil && o.config.Memory != nil && o.config.Memory.Enabled {
+		report.Stages = append(report.Stages, StartupStage{Name: "stores", Checks: []ReadinessCheck{o.memoryReadiness()}})
 	}
-	for _, secretary := range o.secretaries {
-		agents = append(agents, secretary)
-	}
-	agents = append(agents, o.directors...)
-	agents = append(agents, o.managers...)
-	agents = append(agents, o.engineers...)
 
-	for _, agent := range agents {
-		if err := agent.Start(ctx); err != nil {
-			return fmt.Errorf("failed to start agent %s: %w", agent.GetID(), err)
+	// Set up the provider clients of every role's model before the first task
+	providers := StartupStage{Name: "providers", Checks: []ReadinessCheck{o.llmReadiness()}}
+	if o.llmManager != nil {
+		var models []string
+		for _, agent := range o.allAgents() {
+			if m, ok := agent.(interface{ Model() string }); ok {
+				models = append(models, m.Model())
+			}
+		}
+		warm := ReadinessCheck{Name: "clients", Ready: true}
+		if err := o.llmManager.Warm(ctx, models...); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+			warm.Ready, warm.Detail = false, err.Error()
 		}
+		providers.Checks = append(providers.Checks, warm)
+	}
+	report.Stages = append(report.Stages, providers)
+
+	if err := o.startAgents(ctx, &report); err != nil {
+		o.mu.Lock()
+		o.startup = report
+		o.mu.Unlock()
+		return err
 	}
+	o.loadSessionProfiles(ctx)
+
+	o.mu.Lock()
+	o.startup = report
+	o.started = true
+	o.startedAt = time.Now()
+	o.mu.Unlock()
 
 	return nil
 }
@@ -203,6 +459,9 @@ func (o *Organization) Start(ctx context.Context) error {
 func (o *Organization) Stop(ctx context.Context) error {
 	agents := []types.Agent{}
 
+	if o.client != nil {
+		agents = append(agents, o.client)
+	}
 	agents = append(agents, o.engineers...)
 	agents = append(agents, o.managers...)
 	agents = append(agents, o.directors...)
@@ -213,12 +472,48 @@ func (o *Organization) Stop(ctx context.Context) error {
 		agents = append(agents, o.president)
 	}
 
+	o.mu.Lock()
+	o.started = false
+	o.mu.Unlock()
+
+	// Let agents finish the tasks they received before stopping them
+	o.closeInboxes(ctx)
+
 	for _, agent := range agents {
+		if slices.Contains(o.StartupReport().Failed, agent.GetID()) {
+			continue // Never started
+		}
 		if err := agent.Stop(ctx); err != nil {
 			return fmt.Errorf("failed to stop agent %s: %w", agent.GetID(), err)
 		}
 	}
 
+	if stats := o.DedupStats(); stats.Shared > 0 {
+		fmt.Printf("Deduplication: %d of %d engineer task(s) reused a sibling's result, saving ~%d tokens\n",
+			stats.Shared, stats.Tasks, stats.SavedTokens)
+	}
+
+	// Condense the session before the memory manager closes
+	o.compactMemories(ctx)
+
+	if o.quietNotifier != nil {
+		o.quietNotifier.Close()
+	}
+	o.closeWebhooks(ctx)
+	// Undelivered notifications stay in the outbox for the next run
+	if o.outbox != nil {
+		if err := o.outbox.Close(); err != nil {
+			fmt.Printf("Warning: failed to close notification outbox: %v\n", err)
+		}
+	}
+
+	// Close memory manager
+	if o.memoryManager != nil {
+		if err := o.memoryManager.Close(); err != nil {
+			fmt.Printf("Warning: failed to close memory manager: %v\n", err)
+		}
+	}
+
 	// Close LLM manager
 	if o.llmManager != nil {
 		if err := o.llmManager.Close(); err != nil {
@@ -226,9 +521,153 @@ func (o *Organization) Stop(ctx context.Context) error {
 		}
 	}
 
+	// Close agent logs
+	if o.activity != nil && o.activity.files != nil {
+		if err := o.activity.files.Close(); err != nil {
+			fmt.Printf("Warning: failed to close agent logs: %v\n", err)
+		}
+	}
+
 	return nil
 }
 
+// SetMemoryManager attaches a memory manager to every agent in the organization.
+func (o *Organization) SetMemoryManager(manager types.MemoryManager) {
+	o.memoryManager = manager
+
+	// Shared knowledge lives in memory, so it is only available with a memory manager
+	o.knowledge = nil
+	if o.config != nil && o.config.Organization.Knowledge.Enabled && manager != nil {
+		o.knowledge = NewKnowledgeBase(manager, o.config.Organization.Knowledge)
+	}
+
+	for _, agent := range o.allAgents() {
+		if aware, ok := agent.(interface {
+			SetMemoryManager(types.MemoryManager)
+		}); ok {
+			aware.SetMemoryManager(manager)
+		}
+		if sharer, ok := agent.(interface{ setKnowledge(*KnowledgeBase) }); ok {
+			sharer.setKnowledge(o.knowledge)
+		}
+	}
+}
+
+// GetDecisionHistory retrieves decisions recorded by any agent in the organization.
+// If taskID is non-empty, only decisions linked to that task are returned.
+func (o *Organization) GetDecisionHistory(ctx context.Context, taskID string, limit int) ([]*types.MemoryEntry, error) {
+	if o.memoryManager == nil {
+		return nil, errors.ErrMemoryUnavailable
+	}
+
+	query := &types.MemoryQuery{
+		Type:  types.MemoryTypeDecision,
+		Limit: limit,
+	}
+	if taskID != "" {
+		query.Metadata = map[string]string{"task_id": taskID}
+	}
+
+	return o.memoryManager.QueryMemories(ctx, query)
+}
+
+// GetRunMemories retrieves every memory recorded during a run.
+func (o *Organization) GetRunMemories(ctx context.Context, runID string) ([]*types.MemoryEntry, error) {
+	if o.memoryManager == nil {
+		return nil, errors.ErrMemoryUnavailable
+	}
+
+	// Make sure queued writes for the run are visible
+	if flusher, ok := o.memoryManager.(interface {
+		Flush(ctx context.Context) error
+	}); ok {
+		if err := flusher.Flush(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return o.memoryManager.QueryMemories(ctx, &types.MemoryQuery{RunID: runID})
+}
+
+// DeleteRun removes every memory recorded during a run and returns how many were deleted.
+func (o *Organization) DeleteRun(ctx context.Context, runID string) (int, error) {
+	deleter, ok := o.memoryManager.(interface {
+		DeleteRun(ctx context.Context, runID string) (int, error)
+	})
+	if !ok {
+		return 0, errors.ErrMemoryUnavailable
+	}
+
+	return deleter.DeleteRun(ctx, runID)
+}
+
+// GetLogs returns up to the last n entries of an agent's activity log, oldest first.
+func (o *Organization) GetLogs(agentID string, n int) ([]logging.Entry, error) {
+	if o.activity == nil || o.activity.files == nil {
+		return nil, fmt.Errorf("agent logging is not enabled")
+	}
+
+	for _, a := range o.allAgents() {
+		if a.GetID() == agentID {
+			return o.activity.files.Tail(agentID, n)
+		}
+	}
+	return nil, errors.Newf(errors.CodeNotFound, "agent %s not found", agentID)
+}
+
+// DedupStats reports how much duplicate engineering work was avoided.
+// It returns zero stats when deduplication is disabled.
+func (o *Organization) DedupStats() DedupStats {
+	if o.dedup == nil {
+		return DedupStats{}
+	}
+	return o.dedup.Stats()
+}
+
+// MemoryHealth reports the state of the organization's memory backends.
+func (o *Organization) MemoryHealth() []types.StoreHealth {
+	return memoryHealth(o.memoryManager)
+}
+
+// memoryHealth returns the store health of a memory manager that tracks it.
+func memoryHealth(manager types.MemoryManager) []types.StoreHealth {
+	if reporter, ok := manager.(interface {
+		Health() []types.StoreHealth
+	}); ok {
+		return reporter.Health()
+	}
+	return nil
+}
+
+// allAgents returns every agent in the organization.
+func (o *Organization) allAgents() []types.Agent {
+	agents := []types.Agent{}
+
+	if o.president != nil {
+		agents = append(agents, o.president)
+	}
+	for _, secretary := range o.secretaries {
+		agents = append(agents, secretary)
+	}
+	agents = append(agents, o.directors...)
+	agents = append(agents, o.managers...)
+	agents = append(agents, o.engineers...)
+	if o.client != nil {
+		agents = append(agents, o.client)
+	}
+
+	return agents
+}
+
+// projectError attaches the project's run ID to a structured error, so
+// callers that submitted without one learn which run was turned away.
+func projectError(err error, runID string) error {
+	if e, ok := err.(*errors.Error); ok { //nolint:errorlint // Only the outermost structured error is copied
+		return e.WithProject(runID)
+	}
+	return err
+}
+
 // GetPresident returns the president agent.
 func (o *Organization) GetPresident() types.Agent {
 	return o.president
@@ -237,18 +676,261 @@ func (o *Organization) GetPresident() types.Agent {
 // ProcessClientTask processes a task from the client through the president.
 func (o *Organization) ProcessClientTask(ctx context.Context, instruction string) (*types.TaskResponse, error) {
 	if o.president == nil {
-		return nil, fmt.Errorf("no president agent available")
+		return nil, errors.New(errors.CodeAgentUnavailable, "no president agent available")
 	}
 
+	// Use the caller's run ID if it set one, so it can correlate events with the run
+	runID := types.RunIDFromContext(ctx)
+	if runID == "" {
+		runID = uuid.New().String()
+	}
+
+	// A demo turns away large requests and clients who exhausted their allowance
+	if err := o.demo.admit(ctx, instruction); err != nil {
+		return nil, projectError(err, runID)
+	}
+
+	// Attached documents that cannot be read fail the submission before it is admitted
+	docs, err := o.readDocuments(ctx)
+	if err != nil {
+		return nil, projectError(err, runID)
+	}
+
+	// Projects beyond the configured limit wait for a slot or are rejected
+	release, err := o.admit(ctx, runID)
+	if err != nil {
+		return nil, projectError(err, runID)
+	}
+	defer release()
+
+	// Only accepted projects count against the demo allowance
+	if err := o.demo.record(ctx); err != nil {
+		return nil, projectError(err, runID)
+	}
+	user, labels := types.UserFromContext(ctx), types.LabelsFromContext(ctx)
+	o.startProject(runID, user, projectWeight(ctx), labels)
+	defer o.finishProject(runID)
+	o.recordReproducibility(runID)
+
+	// Agents work in the canonical language; the requester gets theirs back
+	request, language := o.translateRequest(ctx, instruction)
+
 	task := &types.Task{
 		ID:          uuid.New().String(),
 		Title:       "Client Request",
-		Description: instruction,
+		Description: request,
 		FromAgent:   "client",
 		ToAgent:     o.president.GetID(),
-		Content:     instruction,
+		Content:     request,
+		RunID:       runID,
 		Priority:    1,
+		Metadata:    make(map[string]string),
+	}
+	if user != "" {
+		task.Metadata[types.MetadataUser] = user
+	}
+	types.SetLabels(task.Metadata, labels)
+	if callbacks := CallbackURLs(ctx); len(callbacks) > 0 {
+		task.Metadata[MetadataCallbacks] = strings.Join(callbacks, " ")
+	}
+	if language != "" {
+		task.Metadata[MetadataLanguage] = language
+		task.Metadata[MetadataOriginalRequest] = instruction
+	}
+	attachDocuments(task, docs)
+
+	// Track the task so snapshots can resume it
+	o.mu.Lock()
+	if o.inflight == nil {
+		o.inflight = make(map[string]TaskSnapshot)
+	}
+	o.inflight[task.ID] = TaskSnapshot{Task: task, StartedAt: time.Now()}
+	o.mu.Unlock()
+	defer func() {
+		o.mu.Lock()
+		delete(o.inflight, task.ID)
+		o.mu.Unlock()
+	}()
+
+	o.sendWebhook(ctx, webhook.Payload{Event: webhook.EventStarted, Message: instruction})
+
+	// Questions no agent can answer are put to the human
+	if o.config.Organization.Questions.Enabled {
+		ctx = withQuestionHandler(ctx, o)
+	}
+
+	// The project's usage covers every round of revision and the client's reviews
+	ctx, usage := startUsage(ctx, organizationLog)
+	resp, err := o.inboxes.dispatch(ctx, task.FromAgent, o.president, task)
+	if err == nil {
+		o.milestone(ctx, MilestoneDeliverable, "The first deliverable is ready")
+	}
+	switch {
+	case err != nil:
+	case o.client != nil:
+		resp, err = o.acceptanceCycle(ctx, task, resp)
+	default:
+		o.assessDeliverable(ctx, task, resp)
+	}
+	if resp != nil {
+		resp.Usage = usage.finish()
+	}
+	o.demo.mark(resp)
+	o.notifyFinished(ctx, instruction, resp, err)
+	o.logUsage(runID, resp)
+	if resp != nil {
+		o.recordUsage(ctx, runID, resp.Usage)
+	}
+	o.recordCalls(ctx, runID)
+	// Decisions of a project the client did not reject become shared knowledge
+	if err == nil && o.knowledge != nil && resp.Metadata[MetadataAcceptance] != AcceptanceChangesRequested {
+		o.promoteKnowledge(ctx, runID)
+	}
+	if cfg := o.config.Reports; cfg != nil && cfg.Enabled {
+		if _, reportErr := o.writeReport(ctx, runID); reportErr != nil {
+			fmt.Printf("Warning: failed to write project report: %v\n", reportErr)
+		}
+	}
+	o.speakDigest(ctx, runID, instruction, resp, err)
+	if err != nil {
+		o.webhookFinished(ctx, nil, err)
+		return nil, err
 	}
 
-	return o.president.ProcessTask(ctx, task)
+	if resp.Metadata == nil {
+		resp.Metadata = make(map[string]string)
+	}
+	resp.Metadata[types.MetadataRunID] = runID
+	if user != "" {
+		resp.Metadata[types.MetadataUser] = user
+	}
+	types.SetLabels(resp.Metadata, labels)
+	if cfg := o.config.Workspace; cfg != nil && cfg.Enabled && len(resp.Files) > 0 {
+		if dir, wsErr := o.writeWorkspace(runID, resp.Files); wsErr != nil {
+			fmt.Printf("Warning: failed to write project files: %v\n", wsErr)
+		} else {
+			resp.Metadata[MetadataWorkspace] = dir
+		}
+	}
+	if language != "" {
+		o.renderResponse(ctx, language, resp)
+		resp.Metadata[MetadataLanguage] = language
+	}
+
+	o.webhookFinished(ctx, resp, nil)
+	return resp, nil
+}
+
+// notifyFinished announces that a client task finished, successfully or not.
+func (o *Organization) notifyFinished(ctx context.Context, instruction string, resp *types.TaskResponse, err error) {
+	if o.notifier == nil {
+		return
+	}
+
+	summary := instruction
+	if runes := []rune(summary); len(runes) > 80 {
+		summary = string(runes[:77]) + "..."
+	}
+
+	notificationType, message := desktop.TypeProjectCompleted, "Completed: "+summary
+	if err != nil || resp.Status == types.StatusFailed {
+		notificationType, message = desktop.TypeProjectFailed, "Failed: "+summary
+	}
+	if user := types.UserFromContext(ctx); user != "" {
+		message = fmt.Sprintf("%s (submitted by %s)", message, user)
+	}
+
+	if err := o.notifier.Notify(ctx, notificationType, message); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+
+	// Provider failures need the user to act, so they go to the error channel
+	var providerErr *llm.ProviderError
+	if errors.As(err, &providerErr) {
+		if err := o.notifier.Notify(ctx, slack.TypeError, "❌ "+providerErr.Error()); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+	}
+}
+
+// acceptanceCycle has the client review the deliverable and sends it back
+// through the president for revision until the client accepts it or the
+// revision limit is reached. With judges or security scanning enabled, every
+// deliverable is assessed, and one that scores below the minimum or has
+// blocking findings is sent back even if the client accepts it. The latest
+// deliverable is always returned; its metadata records whether it was accepted.
+func (o *Organization) acceptanceCycle(ctx context.Context, task *types.Task, resp *types.TaskResponse) (*types.TaskResponse, error) {
+	maxRevisions := o.config.Organization.Acceptance.MaxRevisions
+	if maxRevisions <= 0 {
+		maxRevisions = defaultMaxRevisions
+	}
+
+	for revision := 0; ; revision++ {
+		o.assessDeliverable(ctx, task, resp)
+		review, err := o.client.Review(ctx, task.Content, Deliverable(resp))
+		if err != nil {
+			// A failed review should not discard a finished deliverable
+			fmt.Printf("Warning: client review failed: %v\n", err)
+			setAcceptance(resp, AcceptanceUnreviewed, revision, "")
+			return resp, nil
+		}
+		if changes := o.requiredChanges(resp); len(changes) > 0 {
+			review.Accepted = false
+			review.ChangeRequests = append(review.ChangeRequests, changes...)
+		}
+
+		if review.Accepted {
+			setAcceptance(resp, AcceptanceAccepted, revision, review.Feedback)
+			return resp, nil
+		}
+
+		if revision == maxRevisions {
+			setAcceptance(resp, AcceptanceChangesRequested, revision, formatReview(review))
+			return resp, nil
+		}
+
+		revisionTask := &types.Task{
+			ID:          uuid.New().String(),
+			Title:       fmt.Sprintf("Revision %d: %s", revision+1, task.Title),
+			Description: task.Description,
+			FromAgent:   o.client.GetID(),
+			ToAgent:     o.president.GetID(),
+			Content:     revisionContent(task.Content, Deliverable(resp), review),
+			RunID:       task.RunID,
+			Priority:    task.Priority,
+		}
+		o.client.notifyAssigned(ctx, revisionTask)
+		o.milestone(ctx, MilestoneRevision, fmt.Sprintf("Revision %d requested", revision+1))
+
+		if resp, err = o.inboxes.dispatch(ctx, revisionTask.FromAgent, o.president, revisionTask); err != nil {
+			return nil, err
+		}
+		o.milestone(ctx, MilestoneDeliverable, fmt.Sprintf("Revision %d is ready", revision+1))
+	}
+}
+
+// assessDeliverable has the judges score a deliverable, the security
+// scanners check its files and the conventions check flag its deviations.
+func (o *Organization) assessDeliverable(ctx context.Context, task *types.Task, resp *types.TaskResponse) {
+	o.scoreDeliverable(ctx, task, resp)
+	o.scanDeliverable(ctx, task, resp)
+	o.checkConventions(ctx, task, resp)
+}
+
+// requiredChanges returns the changes the assessment of a deliverable
+// requires regardless of the client's review.
+func (o *Organization) requiredChanges(resp *types.TaskResponse) []string {
+	return slices.Concat(o.judgedChanges(resp), o.securityChanges(resp), o.conventionChanges(resp))
+}
+
+// setAcceptance records the outcome of the acceptance cycle on a response.
+func setAcceptance(resp *types.TaskResponse, acceptance string, revisions int, feedback string) {
+	if resp.Metadata == nil {
+		resp.Metadata = make(map[string]string)
+	}
+	resp.Metadata[MetadataAcceptance] = acceptance
+	resp.Metadata[MetadataRevisions] = strconv.Itoa(revisions)
+	if feedback != "" {
+		resp.Metadata[MetadataClientFeedback] = feedback
+	}
 }
@@ -0,0 +1,195 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/kpango/BuildBureau/internal/llm"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+const (
+	// Task complexities, set in the complexity metadata or estimated from the task's size.
+	ComplexityLow    = "low"
+	ComplexityMedium = "medium"
+	ComplexityHigh   = "high"
+
+	// MetadataComplexity sets a task's complexity explicitly.
+	MetadataComplexity = "complexity"
+
+	// approvedMarker is what a reviewing engineer replies when a draft needs no changes.
+	approvedMarker = "APPROVED"
+
+	// Estimated tokens of task content below which a task is low or medium complexity.
+	lowComplexityTokens    = 300
+	mediumComplexityTokens = 1500
+)
+
+// defaultPairRounds is the number of critique rounds per complexity when none are configured.
+var defaultPairRounds = map[string]int{ComplexityLow: 0, ComplexityMedium: 1, ComplexityHigh: 2}
+
+// critiquePrompt asks an engineer to review a fellow engineer's draft.
+const critiquePrompt = `You are reviewing a fellow engineer's implementation before it is submitted.
+
+Title: %s
+Specifications:
+%s
+
+Implementation:
+%s
+
+List concrete problems, such as bugs, missed requirements and unclear code, and how to fix each.
+If the implementation needs no changes, reply with exactly APPROVED.`
+
+// reviewer is an agent that can critique a sibling's draft.
+type reviewer interface {
+	types.Agent
+	critique(ctx context.Context, task *types.Task, draft *types.TaskResponse) (feedback string, approved bool, err error)
+}
+
+// taskComplexity returns the complexity set in the task's metadata, or one
+// estimated from the size of its content.
+func taskComplexity(task *types.Task) string {
+	switch c := task.Metadata[MetadataComplexity]; c {
+	case ComplexityLow, ComplexityMedium, ComplexityHigh:
+		return c
+	}
+
+	switch tokens := llm.EstimateTokens(task.Content); {
+	case tokens < lowComplexityTokens:
+		return ComplexityLow
+	case tokens < mediumComplexityTokens:
+		return ComplexityMedium
+	default:
+		return ComplexityHigh
+	}
+}
+
+// pairRounds returns how many critique rounds a task gets, or 0 if it is done single-pass.
+func (a *BaseAgent) pairRounds(task *types.Task) int {
+	if !a.pairing.Enabled {
+		return 0
+	}
+
+	complexity := taskComplexity(task)
+	if rounds, ok := a.pairing.Rounds[complexity]; ok {
+		return rounds
+	}
+	return defaultPairRounds[complexity]
+}
+
+// critique reviews a sibling's draft. Problems in a draft of files are tied to
+// the files they are in. Without an LLM every draft is approved.
+func (a *EngineerAgent) critique(ctx context.Context, task *types.Task, draft *types.TaskResponse) (string, bool, error) {
+	if a.llmManager == nil {
+		return "", true, nil
+	}
+
+	prompt := fmt.Sprintf(critiquePrompt, task.Title, task.Content, Deliverable(draft))
+	if len(draft.Files) > 0 {
+		prompt += fileCritiqueInstruction
+	}
+	feedback, err := a.generate(ctx, a.llmManager, prompt, &llm.GenerateOptions{
+		Temperature:  0.3,
+		MaxTokens:    2048,
+		SystemPrompt: a.config.SystemPrompt,
+	})
+	if err != nil {
+		return "", false, fmt.Errorf("failed to critique draft: %w", err)
+	}
+
+	feedback = strings.TrimSpace(feedback)
+	if mem := a.GetMemory(); mem != nil {
+		_ = mem.StoreConversation(ctx, fmt.Sprintf("Reviewed draft of %s:\n%s", task.Title, feedback), []string{"engineer", "pairing", "critique"})
+	}
+
+	return feedback, strings.HasPrefix(feedback, approvedMarker), nil
+}
+
+// pair has a second engineer critique the draft of a subtask and the drafting
+// engineer revise it, for the given number of rounds or until the reviewer
+// approves. A draft of files is revised with patches to the files the review
+// mentions, leaving the others as they were. A failed round keeps the latest
+// draft. It returns the final draft and the rounds of revision that were made.
+func (a *ManagerAgent) pair(ctx context.Context, subtask *types.Task, drafter int, rounds int, draft *types.TaskResponse) (*types.TaskResponse, int) {
+	if len(a.engineers) < 2 || drafter < 0 {
+		return draft, 0
+	}
+	partner, ok := a.engineers[(drafter+1)%len(a.engineers)].(reviewer)
+	if !ok {
+		return draft, 0
+	}
+	author := a.engineers[drafter]
+
+	for round := 1; round <= rounds; round++ {
+		feedback, approved, err := partner.critique(ctx, subtask, draft)
+		if err != nil {
+			a.logf("pair review of task %s by %s failed: %v", subtask.ID, partner.GetID(), err)
+			return draft, round - 1
+		}
+		if approved {
+			a.logf("%s approved %s's draft of task %s", partner.GetID(), author.GetID(), subtask.ID)
+			return draft, round - 1
+		}
+
+		revision := &types.Task{
+			ID:          uuid.New().String(),
+			Title:       fmt.Sprintf("Pair round %d: %s", round, subtask.Title),
+			Description: subtask.Description,
+			FromAgent:   a.GetID(),
+			ToAgent:     author.GetID(),
+			Content:     pairRevisionContent(subtask.Content, draft, partner.GetID(), feedback),
+			Metadata:    subtask.Metadata,
+			Files:       draft.Files,
+			RunID:       subtask.RunID,
+			Priority:    subtask.Priority,
+		}
+
+		response, err := a.dispatch(withQuestionHandler(ctx, a), author, revision)
+		if err != nil || response.Status == types.StatusFailed {
+			a.logf("pair revision of task %s by %s failed, keeping the previous draft", subtask.ID, author.GetID())
+			return draft, round - 1
+		}
+
+		if remembers, ok := author.(interface{ GetMemory() AgentMemory }); ok {
+			if mem := remembers.GetMemory(); mem != nil {
+				_ = mem.StoreConversation(ctx, fmt.Sprintf("Revised %s after review by %s:\n%s", subtask.Title, partner.GetID(), feedback), []string{"engineer", "pairing", "revision"})
+			}
+		}
+		draft = response
+	}
+
+	return draft, rounds
+}
+
+// pairRevisionContent builds the instruction for revising a draft after a
+// critique. A draft of files is revised with patches to the reviewed files.
+func pairRevisionContent(specification string, draft *types.TaskResponse, reviewerID, feedback string) string {
+	var b strings.Builder
+
+	b.WriteString(specification)
+	b.WriteString("\n\n=== Your Draft ===\n")
+	b.WriteString(Deliverable(draft))
+	b.WriteString("\n=== End of Draft ===\n\n")
+	fmt.Fprintf(&b, "%s reviewed your draft:\n%s\n\nRevise the implementation to address the review.\n", reviewerID, feedback)
+
+	if len(draft.Files) > 0 {
+		reviewed := reviewedFiles(draft.Files, feedback)
+		if len(reviewed) == 0 {
+			reviewed = filePaths(draft.Files)
+		}
+		fmt.Fprintf(&b, patchInstruction, strings.Join(reviewed, ", "))
+	}
+
+	return b.String()
+}
+
+// indexOf returns the index of the agent with the given ID, or -1.
+func indexOf(agents []types.Agent, id string) int {
+	return slices.IndexFunc(agents, func(a types.Agent) bool {
+		return a.GetID() == id
+	})
+}
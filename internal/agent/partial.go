@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+
+	"github.com/kpango/BuildBureau/internal/errors"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+const (
+	// PartialFailFast fails the task on the first failed subtask, stopping the others.
+	PartialFailFast = "fail_fast"
+	// PartialBestEffort delivers the subtasks that succeeded as long as one did.
+	PartialBestEffort = "best_effort"
+	// PartialQuorum delivers the subtasks that succeeded if enough of them did.
+	PartialQuorum = "quorum"
+
+	// defaultQuorum is the share of subtasks that must succeed under the quorum policy when not configured.
+	defaultQuorum = 0.5
+)
+
+// failFast reports whether the agent fails a task as soon as one of its
+// parallel subtasks fails, the policy unless another is configured.
+func (a *BaseAgent) failFast() bool {
+	return a.partialFailure.Mode != PartialBestEffort && a.partialFailure.Mode != PartialQuorum
+}
+
+// tolerateFailures applies the agent's partial failure policy to the outcomes
+// of its parallel subtasks, returning an error if more of them failed than the
+// policy tolerates.
+func (a *BaseAgent) tolerateFailures(task *types.Task, subtasks []types.SubtaskResult) error {
+	var failed []string
+	for _, s := range subtasks {
+		if s.Status == types.StatusFailed {
+			failed = append(failed, fmt.Sprintf("%q: %s", s.Title, s.Error))
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+
+	succeeded := len(subtasks) - len(failed)
+	switch a.partialFailure.Mode {
+	case PartialBestEffort:
+		if succeeded > 0 {
+			return nil
+		}
+	case PartialQuorum:
+		if quorum := cmp.Or(a.partialFailure.Quorum, defaultQuorum); float64(succeeded) >= quorum*float64(len(subtasks)) {
+			return nil
+		}
+	}
+	return errors.Newf(errors.CodeDelegationFailed, "%d of %d part(s) failed under the %s policy: %s",
+		len(failed), len(subtasks), a.partialFailure.Mode, strings.Join(failed, "; ")).WithAgent(a.id).WithTask(task.ID).WithProject(task.RunID)
+}
+
+// carryOutcome passes the subtask outcomes of a subordinate's response up with
+// the response built from it, which is partially completed if the
+// subordinate's was.
+func carryOutcome(resp, child *types.TaskResponse) {
+	if child == nil {
+		return
+	}
+	resp.Subtasks = child.Subtasks
+	if child.Status == types.StatusPartiallyCompleted {
+		resp.Status = types.StatusPartiallyCompleted
+	}
+}
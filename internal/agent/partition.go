@@ -0,0 +1,264 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/kpango/BuildBureau/internal/llm"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+const (
+	// defaultMaxParts is how many parts a project is split into at most when not configured.
+	defaultMaxParts = 5
+
+	// anySection labels a part that fits no manager's specialty.
+	anySection = "any"
+)
+
+// partitionPrompt asks the LLM to split a project between sections.
+const partitionPrompt = `You are dividing a project between the sections of your department.
+
+Project: %s
+Description: %s
+
+%s
+
+Sections:
+%s
+Split the project into at most %d self-contained parts. A request that spans
+several specialties, such as a web page and the API behind it, becomes one
+part per specialty. Assign each part to the section whose specialties fit it,
+or to "any" if none does. Do not split work that belongs together.
+
+Respond ONLY with a JSON array in this exact format:
+[{"section": "section ID or any", "title": "short title", "content": "what the section must deliver"}]`
+
+// part is one piece of a partitioned project.
+type part struct {
+	Section string `json:"section"`
+	Title   string `json:"title"`
+	Content string `json:"content"`
+	manager int    // Index of the assigned manager
+}
+
+// partition splits a task between the director's managers by specialty. It
+// returns nil when partitioning is disabled or there is only one manager, in
+// which case the director delegates the whole task as before.
+func (a *DirectorAgent) partition(ctx context.Context, task *types.Task) []part {
+	if !a.partitioning.Enabled || len(a.managers) < 2 {
+		return nil
+	}
+	maxParts := a.partitioning.MaxParts
+	if maxParts <= 0 {
+		maxParts = defaultMaxParts
+	}
+
+	parts, err := a.classify(ctx, task, maxParts)
+	if err != nil {
+		a.logf("partitioning failed, delegating the whole task: %v", err)
+		section := anySection
+		if routed, _, ok := routeTask(a.managers, task, a.assignments(ctx, a.managers)); ok {
+			section = a.managers[routed].GetID()
+		}
+		parts = []part{{Section: section, Title: task.Title, Content: task.Content}}
+	}
+
+	return a.assignParts(parts)
+}
+
+// classify asks the LLM to split the task into parts labeled with sections.
+func (a *DirectorAgent) classify(ctx context.Context, task *types.Task, maxParts int) ([]part, error) {
+	if a.partitionLLM == nil {
+		return nil, fmt.Errorf("no LLM available")
+	}
+
+	var sections strings.Builder
+	for _, m := range a.managers {
+		specialties := "general"
+		if c, ok := m.(interface{ Capabilities() []string }); ok && len(c.Capabilities()) > 0 {
+			specialties = strings.Join(c.Capabilities(), ", ")
+		}
+		fmt.Fprintf(&sections, "- %s: %s\n", m.GetID(), specialties)
+	}
+
+	output, err := a.generate(ctx, a.partitionLLM, fmt.Sprintf(partitionPrompt, task.Title, task.Description, task.Content, sections.String(), maxParts), &llm.GenerateOptions{
+		Temperature:  0.2,
+		MaxTokens:    2048,
+		SystemPrompt: a.config.SystemPrompt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	parts, err := parseParts(output)
+	if err != nil {
+		return nil, err
+	}
+	if len(parts) > maxParts {
+		// Fold the overflow into the last part rather than dropping work
+		last := &parts[maxParts-1]
+		for _, p := range parts[maxParts:] {
+			last.Content += "\n\n" + p.Content
+		}
+		parts = parts[:maxParts]
+	}
+	return parts, nil
+}
+
+// parseParts parses the JSON array of parts, tolerating surrounding prose and code fences.
+func parseParts(output string) ([]part, error) {
+	start := strings.Index(output, "[")
+	end := strings.LastIndex(output, "]")
+	if start == -1 || end < start {
+		return nil, fmt.Errorf("no parts found in output")
+	}
+
+	var parts []part
+	if err := json.Unmarshal([]byte(output[start:end+1]), &parts); err != nil {
+		return nil, fmt.Errorf("failed to parse parts: %w", err)
+	}
+	parts = slices.DeleteFunc(parts, func(p part) bool { return strings.TrimSpace(p.Content) == "" })
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("output has no parts with content")
+	}
+	return parts, nil
+}
+
+// assignParts gives each part to the manager its section names. Parts whose
+// section is unknown or "any" go to the manager with the fewest parts so far,
+// starting from the round-robin position so repeated projects spread out.
+func (a *DirectorAgent) assignParts(parts []part) []part {
+	load := make([]int, len(a.managers))
+	var unassigned []int
+	for i := range parts {
+		parts[i].manager = slices.IndexFunc(a.managers, func(m types.Agent) bool { return m.GetID() == parts[i].Section })
+		if parts[i].manager < 0 {
+			unassigned = append(unassigned, i)
+			continue
+		}
+		load[parts[i].manager]++
+	}
+
+	for _, i := range unassigned {
+		start := a.nextManager()
+		best := start
+		for j := range a.managers {
+			if k := (start + j) % len(a.managers); load[k] < load[best] {
+				best = k
+			}
+		}
+		parts[i].manager = best
+		load[best]++
+	}
+	return parts
+}
+
+// delegateParts hands each part to its manager concurrently and combines the
+// responses in the order of the parts. Under the fail-fast policy the first
+// part to fail fails the task and stops the others; otherwise the parts that
+// succeeded are delivered if the policy tolerates the failures.
+func (a *DirectorAgent) delegateParts(ctx context.Context, task *types.Task, parts []part) (*types.TaskResponse, []RecoveryStep, error) {
+	responses := make([]*types.TaskResponse, len(parts))
+	steps := make([][]RecoveryStep, len(parts))
+	errs := make([]error, len(parts))
+	subtasks := make([]types.SubtaskResult, len(parts))
+
+	partsCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg     sync.WaitGroup
+		once   sync.Once
+		failed = -1 // The part that failed first under fail-fast
+	)
+	for i, p := range parts {
+		wg.Go(func() {
+			subtask := a.managerTask(ctx, task, a.managers[p.manager], p.Title, p.Content)
+			responses[i], steps[i], errs[i] = a.delegate(partsCtx, delegation{
+				parent:       task,
+				subtask:      subtask,
+				subordinates: a.managers,
+				index:        p.manager,
+				label:        "manager",
+				handler:      a,
+			})
+
+			subtasks[i] = types.SubtaskResult{TaskID: subtask.ID, AgentID: a.managers[p.manager].GetID(), Title: p.Title, Status: types.StatusCompleted}
+			if n := len(steps[i]); n > 0 {
+				subtasks[i].AgentID = steps[i][n-1].Agent
+			}
+			if errs[i] != nil {
+				subtasks[i].Status, subtasks[i].Error = types.StatusFailed, errs[i].Error()
+				if a.failFast() {
+					once.Do(func() {
+						failed = i
+						cancel()
+					})
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	if failed >= 0 {
+		return nil, nil, fmt.Errorf("part %q failed: %w", parts[failed].Title, errs[failed])
+	}
+	if err := a.tolerateFailures(task, subtasks); err != nil {
+		return nil, nil, err
+	}
+	return mergeResponses(task, parts, responses, subtasks), slices.Concat(steps...), nil
+}
+
+// mergeResponses combines the managers' responses to the parts of a task into
+// one, joining their results, status summaries, deliverables and files. Parts
+// that failed, whose response is nil, are reported as blockers and make the
+// merged response partially completed.
+func mergeResponses(task *types.Task, parts []part, responses []*types.TaskResponse, subtasks []types.SubtaskResult) *types.TaskResponse {
+	var (
+		result, deliverable strings.Builder
+		status              StatusSummary
+		done                []string
+		reported            bool
+		files               []types.FileArtifact
+		failed              bool
+	)
+	for i, resp := range responses {
+		if resp == nil {
+			failed = true
+			fmt.Fprintf(&result, "--- Part %d: %s (failed) ---\n%s\n", i+1, parts[i].Title, subtasks[i].Error)
+			status.Blockers = append(status.Blockers, fmt.Sprintf("Part %q failed: %s", parts[i].Title, subtasks[i].Error))
+			continue
+		}
+		fmt.Fprintf(&result, "--- Part %d: %s ---\n%s\n", i+1, parts[i].Title, resp.Result)
+		fmt.Fprintf(&deliverable, "## %s\n\n%s\n\n", parts[i].Title, Deliverable(resp))
+		files = mergeFiles(files, resp.Files)
+		if s, ok := StatusOf(resp); ok {
+			reported = true
+			done = append(done, s.Done)
+			status.Blockers = append(status.Blockers, s.Blockers...)
+			status.Artifacts = append(status.Artifacts, s.Artifacts...)
+		}
+	}
+
+	merged := &types.TaskResponse{TaskID: task.ID, Status: types.StatusCompleted, Result: result.String(), Files: files, Subtasks: subtasks}
+	if failed {
+		merged.Status = types.StatusPartiallyCompleted
+	}
+	if reported {
+		status.Done = strings.Join(done, " ")
+		status.Artifacts = status.Artifacts[:min(len(status.Artifacts), maxArtifacts)]
+		encoded, err := json.Marshal(status)
+		if err == nil {
+			merged.Metadata = map[string]string{
+				MetadataStatus:      string(encoded),
+				MetadataDeliverable: strings.TrimSpace(deliverable.String()),
+			}
+		}
+	}
+	return merged
+}
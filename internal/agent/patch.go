@@ -0,0 +1,159 @@
+package agent
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/kpango/BuildBureau/internal/errors"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+const (
+	patchStartPrefix = "=== PATCH:"
+	patchEnd         = "=== END PATCH ==="
+	hunkSearch       = "<<<<<<< SEARCH"
+	hunkDivider      = "======="
+	hunkReplace      = ">>>>>>> REPLACE"
+)
+
+// fileCritiqueInstruction asks a reviewer to tie each problem to a file of the draft.
+const fileCritiqueInstruction = `
+
+Start each problem with the path of the file it is in and the function or section, as in:
+path/to/file.go, func Parse: <problem and fix>`
+
+// patchInstruction asks the LLM to revise a draft with targeted patches.
+const patchInstruction = `
+
+Revise only the files the review mentions: %s. Do not repeat unchanged files.
+Write each change as a patch:
+=== PATCH: path/to/file.go ===
+<<<<<<< SEARCH
+<lines copied exactly from the draft>
+=======
+<the lines replacing them>
+>>>>>>> REPLACE
+=== END PATCH ===
+A patch may hold several SEARCH/REPLACE pairs. Write a new file in full as:
+=== FILE: path/to/new.go ===
+<file contents>
+=== END FILE ===`
+
+// errPatchFailed is wrapped by the error of a revision whose patches do not
+// apply to the draft.
+var errPatchFailed = stderrors.New("patch does not apply")
+
+// hunk replaces one exact section of a file.
+type hunk struct {
+	search, replace string
+}
+
+// filePatch is the hunks a revision applies to one file.
+type filePatch struct {
+	path  string
+	hunks []hunk
+}
+
+// parsePatches returns the patches in a response and the text outside them.
+func parsePatches(output string) ([]filePatch, string) {
+	var (
+		patches []filePatch
+		rest    strings.Builder
+		current *filePatch
+		h       *hunk
+		replace bool
+	)
+	for line := range strings.Lines(output) {
+		trimmed := strings.TrimSpace(line)
+		if current == nil {
+			if path, ok := strings.CutPrefix(trimmed, patchStartPrefix); ok && strings.HasSuffix(path, fileStartSuffix) {
+				current = &filePatch{path: strings.Trim(strings.TrimSpace(strings.TrimSuffix(path, fileStartSuffix)), "`")}
+				continue
+			}
+			rest.WriteString(line)
+			continue
+		}
+
+		switch {
+		case h == nil && trimmed == patchEnd:
+			patches = append(patches, *current)
+			current = nil
+		case h == nil && trimmed == hunkSearch:
+			h = &hunk{}
+			replace = false
+		case h != nil && !replace && trimmed == hunkDivider:
+			replace = true
+		case h != nil && replace && trimmed == hunkReplace:
+			current.hunks = append(current.hunks, *h)
+			h = nil
+		case h != nil && replace:
+			h.replace += line
+		case h != nil:
+			h.search += line
+		}
+	}
+	return patches, rest.String()
+}
+
+// applyPatches applies a revision to the files of a draft: patches change
+// sections of existing files and file blocks add or replace whole files.
+// Files the revision does not mention are kept as they were.
+func applyPatches(files []types.FileArtifact, revision string) ([]types.FileArtifact, string, error) {
+	patches, rest := parsePatches(revision)
+	written := parseFiles(rest)
+
+	revised := slices.Clone(files)
+	for _, p := range patches {
+		i := slices.IndexFunc(revised, func(f types.FileArtifact) bool { return f.Path == p.path })
+		if i < 0 {
+			return nil, "", fmt.Errorf("%w: %s is not a file of the draft", errPatchFailed, p.path)
+		}
+		content := revised[i].Content
+		for _, h := range p.hunks {
+			if h.search == "" || !strings.Contains(content, h.search) {
+				return nil, "", fmt.Errorf("%w: section of %s not found: %q", errPatchFailed, p.path, strings.TrimSpace(h.search))
+			}
+			content = strings.Replace(content, h.search, h.replace, 1)
+		}
+		revised[i] = fileArtifact(p.path, content)
+	}
+	return mergeFiles(revised, written.files), written.prose, nil
+}
+
+// reviewedFiles returns the paths of the files a review mentions.
+func reviewedFiles(files []types.FileArtifact, feedback string) []string {
+	var paths []string
+	for _, f := range files {
+		if strings.Contains(feedback, f.Path) {
+			paths = append(paths, f.Path)
+		}
+	}
+	return paths
+}
+
+// revise applies a revision written with patchInstruction, in response to
+// prompt, to the files of the task's draft, returning the revised deliverable
+// and files.
+func (a *EngineerAgent) revise(ctx context.Context, task *types.Task, prompt, response string) (string, []types.FileArtifact, error) {
+	files, prose, err := applyPatches(task.Files, response)
+	if err != nil {
+		return "", nil, errors.Wrap(err, errors.CodeLLMFailed, "failed to revise the draft").WithAgent(a.id).WithTask(task.ID).WithProject(task.RunID)
+	}
+	a.logf("revised task %s with patches to %d of %d file(s)", task.ID, countChanged(task.Files, files), len(files))
+	a.stamp(ctx, prompt, files)
+	return renderFiles(strings.TrimSpace(prose), files), files, nil
+}
+
+// countChanged returns how many files differ from, or are new to, the originals.
+func countChanged(original, revised []types.FileArtifact) int {
+	var changed int
+	for _, f := range revised {
+		if !slices.Contains(original, f) {
+			changed++
+		}
+	}
+	return changed
+}
@@ -0,0 +1,154 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/kpango/BuildBureau/internal/errors"
+	"github.com/kpango/BuildBureau/internal/llm"
+	"github.com/kpango/BuildBureau/internal/profile"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// assignmentHistoryLimit is how many recent assignments of the candidates are
+// considered when routing a task.
+const assignmentHistoryLimit = 200
+
+// assignmentHistory is a memory manager that keeps the assignment history.
+type assignmentHistory interface {
+	RecordAssignment(ctx context.Context, assignment *types.Assignment) error
+	Assignments(ctx context.Context, agentIDs []string, limit int) ([]*types.Assignment, error)
+}
+
+// recordAssignment records which subordinate handled a subtask, whether it
+// succeeded and how long it took.
+func (a *BaseAgent) recordAssignment(ctx context.Context, d delegation, subordinate types.Agent, subtask *types.Task, start time.Time, err error) {
+	history, ok := a.memoryManager.(assignmentHistory)
+	if !ok {
+		return
+	}
+
+	assignment := &types.Assignment{
+		AgentID:    subordinate.GetID(),
+		AssignedBy: a.id,
+		TaskID:     subtask.ID,
+		RunID:      subtask.RunID,
+		Title:      d.subtask.Title, // Re-plans are recorded under the original title so they count as similar work
+		Latency:    time.Since(start),
+		Succeeded:  err == nil,
+	}
+	if err != nil {
+		assignment.Error = err.Error()
+	}
+
+	// Recorded even when the task was cancelled, so use a context that outlives it
+	if err := history.RecordAssignment(context.WithoutCancel(ctx), assignment); err != nil {
+		a.logf("failed to record assignment of %s: %v", subtask.ID, err)
+	}
+}
+
+// assignments returns the recent assignments of the candidates for routing,
+// or nil if memory keeps no assignment history.
+func (a *BaseAgent) assignments(ctx context.Context, candidates []types.Agent) []*types.Assignment {
+	history, ok := a.memoryManager.(assignmentHistory)
+	if !ok || len(candidates) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(candidates))
+	for i, c := range candidates {
+		ids[i] = c.GetID()
+	}
+
+	assignments, err := history.Assignments(ctx, ids, assignmentHistoryLimit)
+	if err != nil {
+		a.logf("failed to load assignment history: %v", err)
+		return nil
+	}
+	return assignments
+}
+
+// similarSuccess counts an agent's assignments whose titles share keywords
+// with a task, and how many of them succeeded.
+func similarSuccess(agentID string, keywords []string, history []*types.Assignment) (similar, succeeded int) {
+	for _, assignment := range history {
+		if assignment.AgentID != agentID {
+			continue
+		}
+		if !slices.ContainsFunc(profile.Keywords(assignment.Title), func(k string) bool { return slices.Contains(keywords, k) }) {
+			continue
+		}
+		similar++
+		if assignment.Succeeded {
+			succeeded++
+		}
+	}
+	return similar, succeeded
+}
+
+// retrievalHistory is a memory manager that keeps the memory lookups of agents.
+type retrievalHistory interface {
+	RecordRetrieval(ctx context.Context, retrieval *types.MemoryRetrieval) error
+	MemoryEffectiveness(ctx context.Context) ([]types.MemoryEffectiveness, error)
+}
+
+// pastContext renders the agent's memories related to a task within
+// budgetTokens, and records whether any were found and what the lookup cost.
+func (a *BaseAgent) pastContext(ctx context.Context, mem AgentMemory, task *types.Task, budgetTokens int) (string, error) {
+	start := time.Now()
+	past, err := mem.BuildContext(ctx, task.Description, budgetTokens)
+	history, ok := a.memoryManager.(retrievalHistory)
+	if !ok || err != nil || budgetTokens <= 0 {
+		return past, err
+	}
+
+	retrieval := &types.MemoryRetrieval{
+		AgentID: a.id,
+		TaskID:  task.ID,
+		RunID:   task.RunID,
+		Latency: time.Since(start),
+		Hit:     past != "",
+	}
+	if retrieval.Hit {
+		retrieval.Tokens = llm.EstimateTokens(past)
+	}
+	if err := history.RecordRetrieval(context.WithoutCancel(ctx), retrieval); err != nil {
+		a.logf("failed to record memory retrieval for %s: %v", task.ID, err)
+	}
+	return past, nil
+}
+
+// Performance returns the success rate and average latency of every agent
+// that has handled an assignment, from the assignment history in memory.
+func (o *Organization) Performance(ctx context.Context) ([]types.AgentPerformance, error) {
+	reporter, ok := o.memoryManager.(interface {
+		AgentPerformance(ctx context.Context) ([]types.AgentPerformance, error)
+	})
+	if !ok {
+		return nil, errors.ErrMemoryUnavailable
+	}
+
+	stats, err := reporter.AgentPerformance(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get agent performance: %w", err)
+	}
+	return stats, nil
+}
+
+// MemoryEffectiveness returns, for every agent that has looked up its
+// memories, how often related ones were found, what the lookups cost, and
+// the judge scores of the projects in which they were and were not.
+func (o *Organization) MemoryEffectiveness(ctx context.Context) ([]types.MemoryEffectiveness, error) {
+	history, ok := o.memoryManager.(retrievalHistory)
+	if !ok {
+		return nil, errors.ErrMemoryUnavailable
+	}
+
+	stats, err := history.MemoryEffectiveness(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get memory effectiveness: %w", err)
+	}
+	return stats, nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/google/uuid"
+	"github.com/kpango/BuildBureau/internal/errors"
 	"github.com/kpango/BuildBureau/pkg/types"
 )
 
@@ -15,9 +16,9 @@ type PresidentAgent struct {
 }
 
 // NewPresidentAgent creates a new President agent.
-func NewPresidentAgent(id string, config *types.AgentConfig) *PresidentAgent {
+func NewPresidentAgent(id string, config *types.AgentConfig, opts ...Option) *PresidentAgent {
 	return &PresidentAgent{
-		BaseAgent: NewBaseAgent(id, types.RolePresident, config),
+		BaseAgent: NewBaseAgent(id, types.RolePresident, config, opts...),
 	}
 }
 
@@ -31,6 +32,10 @@ func (a *PresidentAgent) ProcessTask(ctx context.Context, task *types.Task) (*ty
 	a.IncrementActiveTasks()
 	defer a.DecrementActiveTasks()
 
+	ctx, cancel := a.taskContext(ctx, task)
+	defer cancel()
+	ctx = a.openBlackboard(ctx)
+
 	// President clarifies client instructions and summarizes objectives
 	result := fmt.Sprintf("President %s received task: %s\n", a.GetID(), task.Title)
 	result += "Clarifying requirements and defining high-level objectives...\n"
@@ -45,31 +50,55 @@ func (a *PresidentAgent) ProcessTask(ctx context.Context, task *types.Task) (*ty
 			Description: task.Description,
 			FromAgent:   a.GetID(),
 			ToAgent:     a.secretary.GetID(),
-			Content:     task.Content,
+			Content:     a.delegationContent(ctx, task, "", task.Content),
+			Metadata:    a.delegationMetadata(task),
+			RunID:       task.RunID,
 			Priority:    task.Priority,
 		}
 
-		response, err := a.secretary.ProcessTask(ctx, secretaryTask)
+		a.notifyAssigned(ctx, secretaryTask)
+
+		response, err := a.dispatch(withQuestionHandler(ctx, a), a.secretary, secretaryTask)
 		if err != nil {
-			return nil, fmt.Errorf("failed to delegate to secretary: %w", err)
+			return nil, a.delegationError(ctx, task, err, "failed to delegate to secretary")
 		}
 
 		if response.Status == types.StatusFailed {
-			return nil, fmt.Errorf("secretary task failed: %s", response.Error)
+			return nil, errors.Newf(errors.CodeDelegationFailed, "secretary task failed: %s", response.Error).WithAgent(a.GetID()).WithTask(task.ID).WithProject(task.RunID)
 		}
 
-		result += fmt.Sprintf("Secretary response: %s\n", response.Result)
+		result += a.subordinateReport("Secretary", response)
 
-		return &types.TaskResponse{
+		resp := &types.TaskResponse{
 			TaskID: task.ID,
 			Status: types.StatusCompleted,
 			Result: result,
-		}, nil
+			Files:  response.Files,
+		}
+		carryOutcome(resp, response)
+		a.reportStatus(ctx, nil, task, resp, response, result)
+		a.storeClientTask(ctx, task, resp.Result)
+
+		return resp, nil
 	}
 
-	return &types.TaskResponse{
+	result += "No secretary assigned, task completed at President level.\n"
+
+	resp := &types.TaskResponse{
 		TaskID: task.ID,
 		Status: types.StatusCompleted,
-		Result: result + "No secretary assigned, task completed at President level.\n",
-	}, nil
+		Result: result,
+	}
+	a.reportStatus(ctx, nil, task, resp, nil, result)
+	a.storeClientTask(ctx, task, resp.Result)
+
+	return resp, nil
+}
+
+// storeClientTask records the client's request and its outcome, which project
+// reports use as the requirements and final result.
+func (a *PresidentAgent) storeClientTask(ctx context.Context, task *types.Task, result string) {
+	if mem := a.GetMemory(); mem != nil {
+		_ = mem.StoreTask(ctx, task, result, []string{"president", "client", "completed"})
+	}
 }
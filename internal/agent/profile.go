@@ -0,0 +1,145 @@
+package agent
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/kpango/BuildBureau/internal/profile"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// Profile returns what the agent has learned across projects.
+func (a *BaseAgent) Profile() profile.Profile {
+	if a.profiles == nil {
+		return profile.Profile{}
+	}
+	return a.profiles.Get(a.id)
+}
+
+// Capabilities returns the capabilities configured for the agent.
+func (a *BaseAgent) Capabilities() []string {
+	if a.config == nil {
+		return nil
+	}
+	return a.config.Capabilities
+}
+
+// profileContext renders the agent's profile and the profile of its last
+// session for inclusion in a prompt.
+func (a *BaseAgent) profileContext() string {
+	summary := a.Profile().Summary()
+	if summary == "" {
+		return a.sessionContext()
+	}
+	return "\n\n=== Your Experience ===\n" + summary + "\n=== End of Experience ===\n\n" + a.sessionContext()
+}
+
+// learn updates the agent's profile with the outcome of a task.
+func (a *BaseAgent) learn(task *types.Task, result string, err error) {
+	if a.profiles == nil {
+		return
+	}
+
+	if err != nil {
+		err = a.profiles.RecordFailure(a.id)
+	} else {
+		err = a.profiles.RecordSuccess(a.id, taskText(task), result)
+	}
+	if err != nil {
+		fmt.Printf("Warning: failed to update profile of %s: %v\n", a.id, err)
+	}
+}
+
+// taskText is the part of a task that describes its domain.
+func taskText(task *types.Task) string {
+	return task.Title + "\n" + task.Description
+}
+
+// routeTask picks the subordinate whose profile, configured capabilities and
+// record on similar assignments best match a task. It reports false when no
+// subordinate stands out, in which case the caller falls back to its default
+// selection.
+func routeTask(subordinates []types.Agent, task *types.Task, history []*types.Assignment) (int, string, bool) {
+	text := taskText(task)
+	keywords := profile.Keywords(text)
+
+	best, bestScore, tied := -1, 0.0, false
+	var reasons []string
+	for i, sub := range subordinates {
+		score, why := suitability(sub, text, keywords, history)
+		switch {
+		case score > bestScore:
+			best, bestScore, tied, reasons = i, score, false, why
+		case score == bestScore && score > 0:
+			tied = true
+		}
+	}
+
+	if best < 0 || tied {
+		return 0, "", false
+	}
+	return best, "Selected for " + strings.Join(reasons, " and "), true
+}
+
+// suitability scores a subordinate for a task, explaining the score.
+func suitability(sub types.Agent, text string, keywords []string, history []*types.Assignment) (float64, []string) {
+	var score float64
+	var reasons []string
+
+	if c, ok := sub.(interface{ Capabilities() []string }); ok {
+		var matched []string
+		for _, capability := range c.Capabilities() {
+			if slices.ContainsFunc(profile.Keywords(capability), func(k string) bool { return slices.Contains(keywords, k) }) {
+				matched = append(matched, capability)
+			}
+		}
+		if len(matched) > 0 {
+			score += float64(len(matched))
+			reasons = append(reasons, "capabilities "+strings.Join(matched, ", "))
+		}
+	}
+
+	if p, ok := sub.(interface{ Profile() profile.Profile }); ok {
+		prof := p.Profile()
+		if s := prof.Score(text); s > 0 {
+			score += s
+			var domains []string
+			for _, k := range keywords {
+				if prof.Domains[k] > 0 {
+					domains = append(domains, k)
+				}
+			}
+			reasons = append(reasons, "experience in "+strings.Join(domains, ", "))
+		}
+	}
+
+	// Proven success on similar work; an agent that keeps failing gains nothing
+	if similar, succeeded := similarSuccess(sub.GetID(), keywords, history); succeeded > 0 {
+		score += float64(succeeded) / float64(similar)
+		reasons = append(reasons, fmt.Sprintf("success on %d of %d similar task(s)", succeeded, similar))
+	}
+
+	return score, reasons
+}
+
+// Profiles returns the long-term profile of every agent that has one.
+func (o *Organization) Profiles() map[string]profile.Profile {
+	if o.profiles == nil {
+		return map[string]profile.Profile{}
+	}
+
+	profiles := o.profiles.All()
+	// Drop profiles of agents no longer in the organization
+	ids := make(map[string]struct{})
+	for _, a := range o.allAgents() {
+		ids[a.GetID()] = struct{}{}
+	}
+	maps.DeleteFunc(profiles, func(id string, _ profile.Profile) bool {
+		_, ok := ids[id]
+		return !ok
+	})
+
+	return profiles
+}
@@ -0,0 +1,118 @@
+package agent_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kpango/BuildBureau/internal/agent"
+	"github.com/kpango/BuildBureau/internal/memory"
+	"github.com/kpango/BuildBureau/internal/testsupport"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// renderCalls renders the prompts an agent sent, for comparison with a golden file.
+func renderCalls(calls []testsupport.Call) string {
+	var b strings.Builder
+	for i, c := range calls {
+		fmt.Fprintf(&b, "=== CALL %d: SYSTEM ===\n%s\n=== CALL %d: PROMPT ===\n%s\n", i+1, c.SystemPrompt, i+1, c.Prompt)
+	}
+	return b.String()
+}
+
+// TestPrompts compares the prompts each role generates for the same task
+// with golden files. Presidents and secretaries delegate without prompting.
+func TestPrompts(t *testing.T) {
+	ctx := context.Background()
+	config := func(role types.AgentRole) *types.AgentConfig {
+		return testsupport.AgentConfig(role, testsupport.ProviderName)
+	}
+
+	tests := []struct {
+		name   string
+		output string
+		run    func(t *testing.T, provider *testsupport.Provider) error
+	}{
+		{
+			name:   "engineer",
+			output: "Implemented the service.",
+			run: func(t *testing.T, provider *testsupport.Provider) error {
+				engineer := agent.NewEngineerAgent("engineer-1", config(types.RoleEngineer), testsupport.LLMManager(t, provider))
+				_, err := engineer.ProcessTask(ctx, testsupport.Task())
+				return err
+			},
+		},
+		{
+			name:   "engineer_memory",
+			output: "Implemented the service.",
+			run: func(t *testing.T, provider *testsupport.Provider) error {
+				mem, err := memory.NewManager(&types.MemoryConfig{
+					Enabled: true,
+					SQLite:  types.SQLiteConfig{Enabled: true, Path: filepath.Join(t.TempDir(), "memory.db")},
+				}, nil)
+				if err != nil {
+					return err
+				}
+				defer mem.Close()
+				for _, entry := range []*types.MemoryEntry{
+					testsupport.MemoryEntry("engineer-1", types.MemoryTypeTask, "Task: URL shortener in Go\nResult: Used base62 codes"),
+					testsupport.MemoryEntry("engineer-1", types.MemoryTypeDecision, "Decision: Store URL shortener links in Redis"),
+					testsupport.MemoryEntry("engineer-1", types.MemoryTypeKnowledge, "A URL shortener must reject unsafe schemes"),
+				} {
+					if err := mem.StoreMemory(ctx, entry); err != nil {
+						return err
+					}
+				}
+
+				engineer := agent.NewEngineerAgent("engineer-1", config(types.RoleEngineer), testsupport.LLMManager(t, provider))
+				engineer.SetMemoryManager(mem)
+				_, err = engineer.ProcessTask(ctx, testsupport.Task(func(task *types.Task) { task.Description = "URL shortener" }))
+				return err
+			},
+		},
+		{
+			name:   "manager",
+			output: "Design: one service with a key-value store.",
+			run: func(t *testing.T, provider *testsupport.Provider) error {
+				manager := agent.NewManagerAgent("manager-1", config(types.RoleManager), testsupport.LLMManager(t, provider))
+				_, err := manager.ProcessTask(ctx, testsupport.Task())
+				return err
+			},
+		},
+		{
+			name:   "director",
+			output: `[{"section": "manager-1", "title": "API", "content": "Build the API"}]`,
+			run: func(t *testing.T, provider *testsupport.Provider) error {
+				director := agent.NewDirectorAgent("director-1", config(types.RoleDirector),
+					agent.WithPartitioning(types.PartitioningConfig{Enabled: true}, testsupport.LLMManager(t, provider)))
+				for i, capability := range []string{"backend", "frontend"} {
+					cfg := config(types.RoleManager)
+					cfg.Capabilities = []string{capability}
+					director.AddManager(agent.NewManagerAgent(fmt.Sprintf("manager-%d", i+1), cfg, nil))
+				}
+				_, err := director.ProcessTask(ctx, testsupport.Task())
+				return err
+			},
+		},
+		{
+			name:   "client",
+			output: `{"accepted": true, "feedback": "Looks good."}`,
+			run: func(t *testing.T, provider *testsupport.Provider) error {
+				client := agent.NewClientAgent("client-1", config(types.RoleClient), testsupport.LLMManager(t, provider))
+				_, err := client.Review(ctx, testsupport.Task().Content, "A REST API with POST /links and GET /{code}.")
+				return err
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &testsupport.Provider{Output: tt.output}
+			if err := tt.run(t, provider); err != nil {
+				t.Fatal(err)
+			}
+			testsupport.Golden(t, "prompts/"+tt.name, renderCalls(provider.Calls()))
+		})
+	}
+}
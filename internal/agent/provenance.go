@@ -0,0 +1,47 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+
+	"github.com/kpango/BuildBureau/internal/provenance"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// openProvenance sets up the configured compliance checks on generated files.
+// It returns nil, which records provenance metadata only, if none are configured.
+func (o *Organization) openProvenance() *provenance.Stamper {
+	cfg := o.config.Provenance
+	if cfg == nil {
+		return nil
+	}
+
+	var opts []provenance.Option
+	if cfg.License != "" {
+		opts = append(opts, provenance.WithLicense(cfg.License))
+	}
+	if cfg.Corpus != "" {
+		matcher, err := provenance.NewCorpusMatcher(cfg.Corpus, cfg.MinMatchLines)
+		if err != nil {
+			fmt.Printf("Warning: Generated files will not be checked against known code: %v\n", err)
+		} else {
+			opts = append(opts, provenance.WithMatcher(matcher))
+		}
+	}
+	return provenance.New(opts...)
+}
+
+// stamp records the provenance of the files generated from prompt and flags
+// those that copy known open-source code.
+func (a *EngineerAgent) stamp(ctx context.Context, prompt string, files []types.FileArtifact) {
+	flagged, err := a.provenance.Stamp(ctx, a.id, a.llmManager.Pinned(a.Model()), prompt, files)
+	if err != nil {
+		a.logf("%v", err)
+	}
+	for _, f := range flagged {
+		for _, m := range f.Provenance.Matches {
+			a.logf("%s lines %d-%d match %s line %d verbatim (license %s)", f.Path, m.StartLine, m.EndLine, m.Source, m.SourceLine, cmp.Or(m.License, "unknown"))
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package agent
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kpango/BuildBureau/internal/desktop"
+	"github.com/kpango/BuildBureau/internal/errors"
+	"github.com/kpango/BuildBureau/internal/llm"
+	"github.com/kpango/BuildBureau/internal/logging"
+	"github.com/kpango/BuildBureau/internal/webhook"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+const (
+	// QuestionProceed continues an unanswered question's task on the asker's best judgement.
+	QuestionProceed = "proceed"
+	// QuestionFail fails the task of an unanswered question.
+	QuestionFail = "fail"
+
+	defaultQuestionTimeout = 10 * time.Minute
+	defaultMaxQuestions    = 2
+
+	// questionMarker starts a response that is a clarifying question rather than a result.
+	questionMarker = "QUESTION:"
+	// unknownAnswer is what an agent replies when it cannot answer a question itself.
+	unknownAnswer = "UNKNOWN"
+	// questionContextLength bounds the task content sent along with a question, in characters.
+	questionContextLength = 2000
+)
+
+// errUnanswered is wrapped by the error of a task that failed because nobody
+// answered its question.
+var errUnanswered = stderrors.New("no answer received")
+
+// unansweredReply is given to an asker whose question nobody answered in time.
+const unansweredReply = "No answer was received. Proceed using your best judgement and state the assumptions you make."
+
+// questionInstruction is appended to prompts of agents that may ask questions.
+const questionInstruction = `
+
+If a requirement is ambiguous and guessing would likely produce the wrong result,
+you may instead respond with a single line starting with "QUESTION:" followed by
+one clarifying question. It will be answered before you continue.`
+
+// answerPrompt asks the LLM whether a subordinate's question can be answered
+// from the task the agent assigned.
+const answerPrompt = `A subordinate working on a task you assigned asked a clarifying question.
+
+Task:
+%s
+
+Question: %s
+
+If the task, its requirements or the design answer the question, reply with the answer only.
+If only the client can decide, reply with exactly UNKNOWN.`
+
+// Question is a clarifying question an agent asked mid-task. It travels up
+// the hierarchy until an agent, or ultimately the human, answers it.
+type Question struct {
+	Asked      time.Time `json:"asked"`
+	ID         string    `json:"id"`
+	TaskID     string    `json:"task_id"`
+	RunID      string    `json:"run_id,omitempty"`
+	From       string    `json:"from"`
+	Text       string    `json:"text"`
+	Context    string    `json:"context,omitempty"` // Content of the asking task
+	Answer     string    `json:"answer,omitempty"`
+	AnsweredBy string    `json:"answered_by,omitempty"`
+}
+
+// questionHandler answers a question, or reports that it cannot so the
+// question moves on to the next agent up the hierarchy.
+type questionHandler interface {
+	handleQuestion(ctx context.Context, q *Question) (answer string, ok bool, err error)
+}
+
+// questionChain lists the handlers above a task, nearest first.
+type questionChain struct {
+	handler questionHandler
+	next    *questionChain
+}
+
+type questionChainKey struct{}
+
+// withQuestionHandler returns a context in which questions are sent to h
+// before the handlers already in ctx.
+func withQuestionHandler(ctx context.Context, h questionHandler) context.Context {
+	next, _ := ctx.Value(questionChainKey{}).(*questionChain)
+	return context.WithValue(ctx, questionChainKey{}, &questionChain{handler: h, next: next})
+}
+
+// handleQuestion passes questions on; agents that can answer override it.
+func (a *BaseAgent) handleQuestion(ctx context.Context, q *Question) (string, bool, error) {
+	a.record(ctx, logging.KindEvent, "", fmt.Sprintf("forwarded question %s from %s", q.ID, q.From))
+	return "", false, nil
+}
+
+// ask sends a clarifying question up the hierarchy and waits for the answer.
+// If nobody answers, the configured timeout decision applies: the task either
+// proceeds on the agent's best judgement or fails.
+func (a *BaseAgent) ask(ctx context.Context, task *types.Task, text string) (string, error) {
+	q := &Question{
+		ID:      uuid.New().String(),
+		TaskID:  task.ID,
+		RunID:   task.RunID,
+		From:    a.id,
+		Text:    text,
+		Context: truncate(questionContextLength, task.Content),
+		Asked:   time.Now(),
+	}
+	a.logf("asked question %s on task %s: %s", q.ID, task.ID, text)
+
+	chain, _ := ctx.Value(questionChainKey{}).(*questionChain)
+	for ; chain != nil; chain = chain.next {
+		answer, ok, err := chain.handler.handleQuestion(ctx, q)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			a.logf("question %s answered: %s", q.ID, answer)
+			if mem := a.GetMemory(); mem != nil {
+				_ = mem.StoreConversation(ctx, fmt.Sprintf("Asked: %s\nAnswer: %s", text, answer), []string{"question", "answer"})
+			}
+			return answer, nil
+		}
+	}
+
+	if a.questions.OnTimeout == QuestionFail {
+		return "", errors.Wrap(errUnanswered, errors.CodeAgentTimeout, fmt.Sprintf("question %q", text)).WithAgent(a.id).WithTask(task.ID).WithProject(task.RunID)
+	}
+	a.logf("question %s was not answered, proceeding", q.ID)
+	return unansweredReply, nil
+}
+
+// generateWithQuestions generates a response, letting the agent ask up to the
+// configured number of clarifying questions first. Each answer is added to the
+// prompt before generating again.
+func (a *BaseAgent) generateWithQuestions(ctx context.Context, llmManager *llm.Manager, task *types.Task, prompt string, opts *llm.GenerateOptions) (string, error) {
+	if !a.questions.Enabled {
+		return a.generate(ctx, llmManager, prompt, opts)
+	}
+
+	maxQuestions := a.questions.MaxPerTask
+	if maxQuestions <= 0 {
+		maxQuestions = defaultMaxQuestions
+	}
+
+	prompt += questionInstruction
+	for asked := 0; ; asked++ {
+		response, err := a.generate(ctx, llmManager, prompt, opts)
+		if err != nil {
+			return "", err
+		}
+
+		question, ok := parseQuestion(response)
+		if !ok {
+			return response, nil
+		}
+		if asked == maxQuestions {
+			prompt += "\n\nYou may not ask further questions. Complete the task using your best judgement.\n"
+			return a.generate(ctx, llmManager, prompt, opts)
+		}
+
+		answer, err := a.ask(ctx, task, question)
+		if err != nil {
+			return "", err
+		}
+		prompt += fmt.Sprintf("\n\nYou asked: %s\nAnswer: %s\n", question, answer)
+	}
+}
+
+// parseQuestion returns the question in a response that asks one.
+func parseQuestion(response string) (string, bool) {
+	text, ok := strings.CutPrefix(strings.TrimSpace(response), questionMarker)
+	if !ok {
+		return "", false
+	}
+	text, _, _ = strings.Cut(strings.TrimSpace(text), "\n")
+	return text, text != ""
+}
+
+// handleQuestion answers an engineer's question from the task the manager
+// assigned if the LLM can, and otherwise passes it on.
+func (a *ManagerAgent) handleQuestion(ctx context.Context, q *Question) (string, bool, error) {
+	if a.llmManager == nil {
+		return a.BaseAgent.handleQuestion(ctx, q)
+	}
+
+	answer, err := a.generate(ctx, a.llmManager, fmt.Sprintf(answerPrompt, q.Context, q.Text), &llm.GenerateOptions{
+		Temperature:  0.2,
+		MaxTokens:    512,
+		SystemPrompt: a.config.SystemPrompt,
+	})
+	answer = strings.TrimSpace(answer)
+	if err != nil || answer == "" || strings.HasPrefix(answer, unknownAnswer) {
+		return a.BaseAgent.handleQuestion(ctx, q)
+	}
+
+	q.Answer, q.AnsweredBy = answer, a.id
+	return answer, true, nil
+}
+
+// pendingQuestion is a question waiting for the human.
+type pendingQuestion struct {
+	question Question
+	answer   chan string
+}
+
+// handleQuestion asks the human: the question is announced through the
+// notifier and waits until it is answered with AnswerQuestion or the
+// configured timeout passes.
+func (o *Organization) handleQuestion(ctx context.Context, q *Question) (string, bool, error) {
+	timeout := o.config.Organization.Questions.Timeout
+	if timeout <= 0 {
+		timeout = defaultQuestionTimeout
+	}
+
+	pending := &pendingQuestion{question: *q, answer: make(chan string, 1)}
+	o.mu.Lock()
+	if o.questions == nil {
+		o.questions = make(map[string]*pendingQuestion)
+	}
+	o.questions[q.ID] = pending
+	o.mu.Unlock()
+	defer func() {
+		o.mu.Lock()
+		delete(o.questions, q.ID)
+		o.mu.Unlock()
+	}()
+
+	o.activity.Log(q.From, logging.Entry{Kind: logging.KindEvent, RunID: q.RunID, Content: fmt.Sprintf("waiting for an answer to question %s: %s", q.ID, q.Text)})
+	o.sendWebhook(ctx, webhook.Payload{Event: webhook.EventApprovalNeeded, RunID: q.RunID, Message: fmt.Sprintf("%s asks (question %s): %s", q.From, q.ID, q.Text)})
+	if o.notifier != nil {
+		message := fmt.Sprintf("%s asks: %s (answer with: buildbureau questions answer %s <answer>)", q.From, q.Text, q.ID)
+		if err := o.notifier.Notify(ctx, desktop.TypeApprovalRequested, message); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case answer := <-pending.answer:
+		q.Answer, q.AnsweredBy = answer, "human"
+		return answer, true, nil
+	case <-timer.C:
+		return "", false, nil
+	case <-ctx.Done():
+		return "", false, ctx.Err()
+	}
+}
+
+// Questions returns the questions waiting for a human answer, oldest first.
+func (o *Organization) Questions() []Question {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	questions := make([]Question, 0, len(o.questions))
+	for _, p := range o.questions {
+		questions = append(questions, p.question)
+	}
+	slices.SortFunc(questions, func(a, b Question) int {
+		return a.Asked.Compare(b.Asked)
+	})
+	return questions
+}
+
+// AnswerQuestion answers a question waiting for a human, resuming the task that asked it.
+func (o *Organization) AnswerQuestion(id, answer string) error {
+	if strings.TrimSpace(answer) == "" {
+		return errors.New(errors.CodeInvalidArgument, "answer is empty")
+	}
+
+	o.mu.Lock()
+	pending, ok := o.questions[id]
+	if ok {
+		delete(o.questions, id)
+	}
+	o.mu.Unlock()
+
+	if !ok {
+		return errors.Newf(errors.CodeNotFound, "no question %s is waiting for an answer", id)
+	}
+	pending.answer <- answer
+	return nil
+}
@@ -0,0 +1,121 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kpango/BuildBureau/internal/errors"
+	"github.com/kpango/BuildBureau/internal/logging"
+)
+
+// ReadinessCheck is the outcome of one condition the organization must meet
+// before it can take on projects.
+type ReadinessCheck struct {
+	Name   string `json:"name"`
+	Detail string `json:"detail,omitempty"`
+	Ready  bool   `json:"ready"`
+}
+
+// Readiness reports whether the organization can take on projects, and why not.
+type Readiness struct {
+	Checks []ReadinessCheck `json:"checks"`
+	Ready  bool             `json:"ready"`
+}
+
+// Readiness checks that the organization has started and is not draining,
+// that its LLM providers are initialized and can serve the default model, and
+// that its memory stores are reachable.
+func (o *Organization) Readiness() Readiness {
+	checks := []ReadinessCheck{o.organizationReadiness(), o.llmReadiness()}
+	if o.config != nil && o.config.Memory != nil && o.config.Memory.Enabled {
+		checks = append(checks, o.memoryReadiness())
+	}
+
+	r := Readiness{Checks: checks, Ready: true}
+	for _, c := range checks {
+		r.Ready = r.Ready && c.Ready
+	}
+	return r
+}
+
+func (o *Organization) organizationReadiness() ReadinessCheck {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	c := ReadinessCheck{Name: "organization"}
+	switch {
+	case o.draining:
+		c.Detail = "draining for shutdown"
+	case !o.started:
+		c.Detail = "not started"
+	default:
+		c.Ready = true
+		if len(o.startup.Failed) > 0 {
+			c.Detail = "started without " + strings.Join(o.startup.Failed, ", ")
+		}
+	}
+	return c
+}
+
+func (o *Organization) llmReadiness() ReadinessCheck {
+	c := ReadinessCheck{Name: "llm"}
+	if o.llmManager == nil {
+		c.Detail = "no LLM providers could be initialized"
+		return c
+	}
+
+	providers := o.llmManager.Providers()
+	if o.config != nil && o.config.LLMs.DefaultModel != "" && !o.llmManager.HasModel(o.config.LLMs.DefaultModel) {
+		model := o.config.LLMs.DefaultModel
+		c.Detail = fmt.Sprintf("default model %s is not served by any of %s", model, strings.Join(providers, ", "))
+		return c
+	}
+	c.Detail = strings.Join(providers, ", ")
+	c.Ready = true
+	return c
+}
+
+func (o *Organization) memoryReadiness() ReadinessCheck {
+	c := ReadinessCheck{Name: "memory"}
+	if o.memoryManager == nil {
+		c.Detail = "memory is enabled but not initialized"
+		return c
+	}
+
+	var down []string
+	for _, h := range o.MemoryHealth() {
+		if !h.Healthy {
+			down = append(down, fmt.Sprintf("%s: %s", h.Name, h.LastError))
+		}
+	}
+	if len(down) > 0 {
+		c.Detail = strings.Join(down, "; ")
+		return c
+	}
+	c.Ready = true
+	return c
+}
+
+// Drain prepares the organization for shutdown: it stops admitting projects,
+// which also fails readiness so load balancers stop routing to it, and waits
+// until the projects already running or queued have finished or ctx ends.
+// Call Stop afterwards to stop the agents.
+func (o *Organization) Drain(ctx context.Context) error {
+	o.mu.Lock()
+	o.draining = true
+	o.mu.Unlock()
+
+	if o.activity != nil {
+		stats := o.admission.snapshot()
+		o.activity.Log(organizationLog, logging.Entry{Kind: logging.KindEvent,
+			Content: fmt.Sprintf("draining: waiting for %d running and %d queued project(s)", stats.Running, stats.Queued)})
+	}
+
+	select {
+	case <-o.admission.drain():
+		return nil
+	case <-ctx.Done():
+		return errors.FromContext(ctx)
+	}
+}
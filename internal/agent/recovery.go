@@ -0,0 +1,208 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kpango/BuildBureau/internal/errors"
+	"github.com/kpango/BuildBureau/internal/logging"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+const (
+	// RecoveryReassign retries the failed branch with a different subordinate.
+	RecoveryReassign = "reassign"
+	// RecoveryAlternative asks for a different approach that avoids the failure.
+	RecoveryAlternative = "alternative"
+	// RecoveryReduceScope asks for a minimal version covering only the essentials.
+	RecoveryReduceScope = "reduce_scope"
+
+	// MetadataRecovery holds the recovery path on responses of branches that were re-planned.
+	MetadataRecovery = "recovery"
+)
+
+// defaultRecoveryStrategies is the order strategies are tried in when none are configured.
+var defaultRecoveryStrategies = []string{RecoveryReassign, RecoveryAlternative, RecoveryReduceScope}
+
+// RecoveryStep records one attempt at a delegated branch.
+type RecoveryStep struct {
+	Strategy string
+	Agent    string
+	Error    string
+}
+
+// String renders the step for events and results.
+func (s RecoveryStep) String() string {
+	if s.Error != "" {
+		return fmt.Sprintf("%s via %s failed: %s", s.Strategy, s.Agent, s.Error)
+	}
+	return fmt.Sprintf("%s via %s succeeded", s.Strategy, s.Agent)
+}
+
+// delegation describes a subtask and the subordinates that may handle it.
+type delegation struct {
+	parent       *types.Task
+	subtask      *types.Task
+	subordinates []types.Agent
+	label        string          // Subordinate role used in error messages, e.g. "engineer"
+	handler      questionHandler // Receives the subordinate's questions; the delegating agent
+	index        int             // Subordinate chosen for the first attempt
+}
+
+// delegate runs a subtask on the chosen subordinate. If the branch fails and a
+// recovery policy is configured, only that branch is re-planned, up to the
+// policy's limit. The returned steps describe every attempt after the first.
+func (a *BaseAgent) delegate(ctx context.Context, d delegation) (*types.TaskResponse, []RecoveryStep, error) {
+	subordinate := d.subordinates[d.index]
+	a.notifyAssigned(ctx, d.subtask)
+
+	response, err := a.attempt(ctx, d, subordinate, d.subtask)
+	if err == nil {
+		return response, nil, nil
+	}
+
+	strategies := a.recoveryStrategies(len(d.subordinates))
+	var steps []RecoveryStep
+	for replan := 1; replan <= a.recovery.MaxReplans && len(strategies) > 0; replan++ {
+		// Re-planning cannot help once the task has timed out or been cancelled
+		if ctx.Err() != nil {
+			break
+		}
+
+		strategy := strategies[(replan-1)%len(strategies)]
+		if strategy == RecoveryReassign {
+			subordinate = d.subordinates[(d.index+replan)%len(d.subordinates)]
+		}
+
+		subtask := replanTask(d.subtask, subordinate, strategy, replan, err)
+		a.notifyReplan(ctx, d, subtask, strategy, err)
+
+		response, err = a.attempt(ctx, d, subordinate, subtask)
+		step := RecoveryStep{Strategy: strategy, Agent: subordinate.GetID()}
+		if err != nil {
+			step.Error = err.Error()
+		}
+		steps = append(steps, step)
+		usageFromContext(ctx).addReplan()
+
+		if err == nil {
+			return response, steps, nil
+		}
+	}
+
+	if len(steps) > 0 {
+		return nil, steps, errors.Wrap(err, errors.CodeDelegationFailed,
+			fmt.Sprintf("%s task failed after %d re-plan(s)", d.label, len(steps))).WithAgent(a.id).WithTask(d.parent.ID).WithProject(d.parent.RunID)
+	}
+	return nil, nil, err
+}
+
+// attempt runs one subtask, converting a failed response into an error.
+// Every attempt is recorded in the assignment history and counted toward SLOs.
+func (a *BaseAgent) attempt(ctx context.Context, d delegation, subordinate types.Agent, subtask *types.Task) (*types.TaskResponse, error) {
+	start := time.Now()
+	response, err := a.dispatch(withQuestionHandler(ctx, d.handler), subordinate, subtask)
+	switch {
+	case err != nil:
+		err = a.delegationError(ctx, d.parent, err, "failed to delegate to "+d.label)
+	case response.Status == types.StatusFailed:
+		err = errors.Newf(errors.CodeDelegationFailed, "%s task failed: %s", d.label, response.Error).WithAgent(a.id).WithTask(d.parent.ID).WithProject(d.parent.RunID)
+	}
+	a.recordAssignment(ctx, d, subordinate, subtask, start, err)
+	a.slos.Record(string(subordinate.GetRole()), time.Since(start), err != nil)
+
+	if err != nil {
+		a.record(ctx, logging.KindError, "", fmt.Sprintf("task %s on %s: %v", subtask.ID, subordinate.GetID(), err))
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// recoveryStrategies returns the strategies usable with the given number of subordinates.
+func (a *BaseAgent) recoveryStrategies(subordinates int) []string {
+	if a.recovery.MaxReplans <= 0 {
+		return nil
+	}
+
+	configured := a.recovery.Strategies
+	if len(configured) == 0 {
+		configured = defaultRecoveryStrategies
+	}
+
+	strategies := make([]string, 0, len(configured))
+	for _, s := range configured {
+		// Reassigning to the same subordinate would just repeat the failure
+		if s == RecoveryReassign && subordinates < 2 {
+			continue
+		}
+		strategies = append(strategies, s)
+	}
+
+	return strategies
+}
+
+// replanTask builds the subtask for a re-plan attempt.
+func replanTask(original *types.Task, subordinate types.Agent, strategy string, replan int, cause error) *types.Task {
+	content := original.Content
+	switch strategy {
+	case RecoveryAlternative:
+		content += fmt.Sprintf("\n\nA previous attempt at this task failed with: %v\nTake a different approach that avoids this failure.", cause)
+	case RecoveryReduceScope:
+		content += fmt.Sprintf("\n\nA previous attempt at this task failed with: %v\nReduce the scope to the essential requirements and deliver a minimal working version.", cause)
+	}
+
+	return &types.Task{
+		ID:          uuid.New().String(),
+		Title:       fmt.Sprintf("Re-plan %d (%s): %s", replan, strategy, original.Title),
+		Description: original.Description,
+		FromAgent:   original.FromAgent,
+		ToAgent:     subordinate.GetID(),
+		Content:     content,
+		Metadata:    original.Metadata,
+		RunID:       original.RunID,
+		Priority:    original.Priority,
+	}
+}
+
+// notifyReplan reports a re-plan through the logger, notifier and memory.
+func (a *BaseAgent) notifyReplan(ctx context.Context, d delegation, subtask *types.Task, strategy string, cause error) {
+	a.logf("re-planning task %s with %s after failure: %v", d.parent.ID, strategy, cause)
+
+	if a.notifier != nil {
+		message := fmt.Sprintf("Task `%s` (%s) failed; %s re-planned it with *%s*, assigned to *%s*", d.parent.ID, d.parent.Title, a.id, strategy, subtask.ToAgent)
+		if err := a.notifier.Notify(ctx, "task_replanned", message); err != nil {
+			a.logf("failed to send notification: %v", err)
+		}
+	}
+
+	if mem := a.GetMemory(); mem != nil {
+		decision := fmt.Sprintf("Re-planned failed %s task with %s, assigned to %s", d.label, strategy, subtask.ToAgent)
+		_ = mem.StoreDecision(ctx, decision, cause.Error(), []string{"recovery", strategy})
+	}
+}
+
+// formatRecovery renders recovery steps as a single line for results and metadata.
+func formatRecovery(steps []RecoveryStep) string {
+	parts := make([]string, len(steps))
+	for i, step := range steps {
+		parts[i] = step.String()
+	}
+	return strings.Join(parts, " -> ")
+}
+
+// recoveredResponse builds a completed response, recording the recovery path if the branch was re-planned.
+func recoveredResponse(task *types.Task, result, recovery string) *types.TaskResponse {
+	resp := &types.TaskResponse{
+		TaskID: task.ID,
+		Status: types.StatusCompleted,
+		Result: result,
+	}
+	if recovery != "" {
+		resp.Metadata = map[string]string{MetadataRecovery: recovery}
+	}
+	return resp
+}
@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/kpango/BuildBureau/internal/errors"
+	"github.com/kpango/BuildBureau/internal/report"
+)
+
+// DefaultReportDir is where project reports are written when no directory is configured.
+const DefaultReportDir = "reports"
+
+// Report builds the transcript of a project from the memories recorded during its run.
+func (o *Organization) Report(ctx context.Context, runID string) (*report.Report, error) {
+	memories, err := o.GetRunMemories(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	if len(memories) == 0 {
+		return nil, errors.Newf(errors.CodeNotFound, "no memories recorded for run %s", runID).WithProject(runID)
+	}
+
+	return report.Build(runID, memories), nil
+}
+
+// writeReport writes the report of a finished run to the configured directory
+// and returns its path.
+func (o *Organization) writeReport(ctx context.Context, runID string) (string, error) {
+	cfg := o.config.Reports
+
+	r, err := o.Report(ctx, runID)
+	if err != nil {
+		return "", err
+	}
+
+	dir := cfg.Dir
+	if dir == "" {
+		dir = DefaultReportDir
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create report directory: %w", err)
+	}
+
+	// Images are linked relative to the report
+	for i, img := range r.Images {
+		if rel, err := relativePath(dir, img.Stored); err == nil {
+			r.Images[i].Stored = rel
+		}
+	}
+
+	path := filepath.Join(dir, runID+report.Extension(cfg.Format))
+	f, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to create report: %w", err)
+	}
+	defer f.Close()
+
+	if err := report.Render(f, r, cfg.Format); err != nil {
+		return "", fmt.Errorf("failed to write report: %w", err)
+	}
+
+	return path, nil
+}
+
+// relativePath returns the path of target relative to dir.
+func relativePath(dir, target string) (string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	absTarget, err := filepath.Abs(target)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Rel(absDir, absTarget)
+}
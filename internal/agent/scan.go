@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kpango/BuildBureau/internal/report"
+	"github.com/kpango/BuildBureau/internal/scan"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// securityScannerID is the agent ID the security scan summary is stored under.
+const securityScannerID = "security-scanner"
+
+// openScanner sets up the configured security scanners. It returns nil if
+// scanning is not enabled or no scanner is installed.
+func (o *Organization) openScanner() *scan.Runner {
+	runner, err := scan.New(o.config.SecurityScan)
+	if err != nil {
+		fmt.Printf("Warning: Security scanning: %v\n", err)
+	}
+	return runner
+}
+
+// scanDeliverable runs the security scanners over the files of a deliverable.
+// The findings are attached to the response, and their summary is stored in
+// memory for the project report.
+func (o *Organization) scanDeliverable(ctx context.Context, task *types.Task, resp *types.TaskResponse) {
+	if o.scanner == nil || len(resp.Files) == 0 {
+		return
+	}
+
+	findings, err := o.scanner.Scan(ctx, resp.Files)
+	if err != nil {
+		// The scanners that did run still count
+		fmt.Printf("Warning: security scan: %v\n", err)
+	}
+	resp.Findings = findings
+
+	if o.memoryManager == nil {
+		return
+	}
+	entry := &types.MemoryEntry{
+		AgentID:  securityScannerID,
+		RunID:    task.RunID,
+		Type:     types.MemoryTypeContext,
+		Content:  o.scanner.Summary(findings),
+		Tags:     []string{"security"},
+		Metadata: map[string]string{report.MetadataKind: report.KindSecurityScan, "task_id": resp.TaskID},
+	}
+	if err := o.memoryManager.StoreMemory(context.WithoutCancel(ctx), entry); err != nil {
+		fmt.Printf("Warning: failed to store security scan summary: %v\n", err)
+	}
+}
+
+// securityChanges returns a change request for every finding at or above
+// the severity threshold.
+func (o *Organization) securityChanges(resp *types.TaskResponse) []string {
+	if o.scanner == nil {
+		return nil
+	}
+
+	var changes []string
+	for _, f := range o.scanner.Blocking(resp.Findings) {
+		changes = append(changes, "Fix security finding "+f.String())
+	}
+	return changes
+}
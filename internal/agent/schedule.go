@@ -0,0 +1,152 @@
+package agent
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"time"
+)
+
+const (
+	// defaultProjectWeight is the scheduling weight of a project submitted without one.
+	defaultProjectWeight = 1.0
+
+	// maxFinishedProjects is how many finished projects are kept for their statistics.
+	maxFinishedProjects = 50
+)
+
+// ProjectStats reports how a project (a client task and everything delegated
+// for it) has been served by the organization's agents.
+type ProjectStats struct {
+	Started     time.Time         `json:"started"`
+	Finished    time.Time         `json:"finished,omitzero"`
+	RunID       string            `json:"run_id"`
+	User        string            `json:"user,omitempty"` // Who submitted the project
+	Labels      map[string]string `json:"labels,omitempty"`
+	Weight      float64           `json:"weight"`
+	Tasks       int               `json:"tasks"`        // Agent tasks completed through inboxes
+	Throughput  float64           `json:"throughput"`   // Agent tasks completed per minute
+	AverageWait time.Duration     `json:"average_wait"` // Time tasks spent queued in an inbox
+	totalWait   time.Duration
+}
+
+type projectWeightKey struct{}
+
+// WithProjectWeight returns a context whose project submission is scheduled
+// with the given weight. With fair scheduling, a project of weight 2 gets
+// about twice the agent time of a project of weight 1 while both have work
+// queued. Weights that are not positive count as 1.
+func WithProjectWeight(ctx context.Context, weight float64) context.Context {
+	return context.WithValue(ctx, projectWeightKey{}, weight)
+}
+
+// projectWeight returns the project weight set in ctx, or the default.
+func projectWeight(ctx context.Context) float64 {
+	if w, ok := ctx.Value(projectWeightKey{}).(float64); ok && w > 0 {
+		return w
+	}
+	return defaultProjectWeight
+}
+
+// startProject begins tracking a project.
+func (o *Organization) startProject(runID, user string, weight float64, labels map[string]string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.projects == nil {
+		o.projects = make(map[string]*ProjectStats)
+	}
+	if _, ok := o.projects[runID]; !ok {
+		o.projects[runID] = &ProjectStats{RunID: runID, User: user, Labels: maps.Clone(labels), Weight: weight, Started: time.Now()}
+	}
+}
+
+// finishProject marks a project finished, dropping the oldest finished
+// projects beyond the number kept.
+func (o *Organization) finishProject(runID string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	p, ok := o.projects[runID]
+	if !ok {
+		return
+	}
+	p.Finished = time.Now()
+
+	var finished []*ProjectStats
+	for _, p := range o.projects {
+		if !p.Finished.IsZero() {
+			finished = append(finished, p)
+		}
+	}
+	if len(finished) <= maxFinishedProjects {
+		return
+	}
+	slices.SortFunc(finished, func(a, b *ProjectStats) int { return a.Finished.Compare(b.Finished) })
+	for _, p := range finished[:len(finished)-maxFinishedProjects] {
+		delete(o.projects, p.RunID)
+	}
+}
+
+// recordProjectTask records an agent task of a project that waited in an inbox.
+func (o *Organization) recordProjectTask(runID string, wait time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if p, ok := o.projects[runID]; ok {
+		p.Tasks++
+		p.totalWait += wait
+	}
+}
+
+// weightOf returns the scheduling weight of a project.
+func (o *Organization) weightOf(runID string) float64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if p, ok := o.projects[runID]; ok {
+		return p.Weight
+	}
+	return defaultProjectWeight
+}
+
+// Projects returns the scheduling statistics of running and recently finished
+// projects, oldest first.
+func (o *Organization) Projects() []ProjectStats {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	projects := make([]ProjectStats, 0, len(o.projects))
+	for _, p := range o.projects {
+		stats := *p
+		end := stats.Finished
+		if end.IsZero() {
+			end = time.Now()
+		}
+		if elapsed := end.Sub(stats.Started).Minutes(); elapsed > 0 {
+			stats.Throughput = float64(stats.Tasks) / elapsed
+		}
+		if stats.Tasks > 0 {
+			stats.AverageWait = stats.totalWait / time.Duration(stats.Tasks)
+		}
+		projects = append(projects, stats)
+	}
+	slices.SortFunc(projects, func(a, b ProjectStats) int { return a.Started.Compare(b.Started) })
+
+	return projects
+}
+
+// ProjectsOf returns the statistics of the running and recently finished
+// projects submitted by a user, oldest first.
+func (o *Organization) ProjectsOf(user string) []ProjectStats {
+	return slices.DeleteFunc(o.Projects(), func(p ProjectStats) bool { return p.User != user })
+}
+
+// ProjectsLabeled returns the statistics of the running and recently finished
+// projects carrying the label key=value, oldest first.
+func (o *Organization) ProjectsLabeled(key, value string) []ProjectStats {
+	return slices.DeleteFunc(o.Projects(), func(p ProjectStats) bool {
+		v, ok := p.Labels[key]
+		return !ok || v != value
+	})
+}
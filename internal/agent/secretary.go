@@ -3,6 +3,7 @@ package agent
 import (
 	"context"
 	"fmt"
+	"slices"
 	"sync/atomic"
 
 	"github.com/google/uuid"
@@ -18,9 +19,9 @@ type SecretaryAgent struct {
 }
 
 // NewSecretaryAgent creates a new Secretary agent.
-func NewSecretaryAgent(id string, config *types.AgentConfig) *SecretaryAgent {
+func NewSecretaryAgent(id string, config *types.AgentConfig, opts ...Option) *SecretaryAgent {
 	return &SecretaryAgent{
-		BaseAgent: NewBaseAgent(id, types.RoleSecretary, config),
+		BaseAgent: NewBaseAgent(id, types.RoleSecretary, config, opts...),
 		directors: make([]types.Agent, 0),
 	}
 }
@@ -35,11 +36,20 @@ func (a *SecretaryAgent) AddDirector(director types.Agent) {
 	a.directors = append(a.directors, director)
 }
 
+// RemoveDirector stops delegating tasks to the director with the given ID.
+func (a *SecretaryAgent) RemoveDirector(id string) {
+	a.directors = slices.DeleteFunc(a.directors, func(agent types.Agent) bool { return agent.GetID() == id })
+}
+
 // ProcessTask handles incoming tasks for the Secretary.
 func (a *SecretaryAgent) ProcessTask(ctx context.Context, task *types.Task) (*types.TaskResponse, error) {
 	a.IncrementActiveTasks()
 	defer a.DecrementActiveTasks()
 
+	ctx, cancel := a.taskContext(ctx, task)
+	defer cancel()
+	ctx = a.openBlackboard(ctx)
+
 	// Store conversation memory if memory is enabled
 	if mem := a.GetMemory(); mem != nil {
 		_ = mem.StoreConversation(ctx, fmt.Sprintf("Received task: %s - %s", task.Title, task.Description), []string{"secretary", "delegation"})
@@ -49,11 +59,16 @@ func (a *SecretaryAgent) ProcessTask(ctx context.Context, task *types.Task) (*ty
 	result += "Recording goal and decisions...\n"
 
 	// If we have directors, delegate to them using round-robin with memory-informed selection
+	var (
+		recovery string
+		child    *types.TaskResponse
+	)
 	if len(a.directors) > 0 {
 		result += fmt.Sprintf("Delegating to %d Director(s)...\n", len(a.directors))
 
 		// Check past delegation performance from memory
-		selectedDirector := a.selectDirectorWithMemory(ctx, task)
+		idx := a.selectDirectorWithMemory(ctx, task)
+		selectedDirector := a.directors[idx]
 
 		directorTask := &types.Task{
 			ID:          uuid.New().String(),
@@ -61,7 +76,9 @@ func (a *SecretaryAgent) ProcessTask(ctx context.Context, task *types.Task) (*ty
 			Description: task.Description,
 			FromAgent:   a.GetID(),
 			ToAgent:     selectedDirector.GetID(),
-			Content:     task.Content,
+			Content:     a.delegationContent(ctx, task, "", task.Content),
+			Metadata:    a.delegationMetadata(task),
+			RunID:       task.RunID,
 			Priority:    task.Priority,
 		}
 
@@ -72,16 +89,25 @@ func (a *SecretaryAgent) ProcessTask(ctx context.Context, task *types.Task) (*ty
 			_ = mem.StoreDecision(ctx, decision, reasoning, []string{"delegation", "director"})
 		}
 
-		response, err := selectedDirector.ProcessTask(ctx, directorTask)
+		response, steps, err := a.delegate(ctx, delegation{
+			parent:       task,
+			subtask:      directorTask,
+			subordinates: a.directors,
+			index:        idx,
+			label:        "director",
+			handler:      a,
+		})
 		if err != nil {
-			return nil, fmt.Errorf("failed to delegate to director: %w", err)
+			return nil, err
 		}
 
-		if response.Status == types.StatusFailed {
-			return nil, fmt.Errorf("director task failed: %s", response.Error)
+		if len(steps) > 0 {
+			recovery = formatRecovery(steps)
+			result += fmt.Sprintf("Recovered after %d re-plan(s): %s\n", len(steps), recovery)
 		}
 
-		result += fmt.Sprintf("Director response: %s\n", response.Result)
+		child = response
+		result += a.subordinateReport("Director", response)
 
 		// Store task completion memory
 		if mem := a.GetMemory(); mem != nil {
@@ -96,19 +122,25 @@ func (a *SecretaryAgent) ProcessTask(ctx context.Context, task *types.Task) (*ty
 		}
 	}
 
-	return &types.TaskResponse{
-		TaskID: task.ID,
-		Status: types.StatusCompleted,
-		Result: result,
-	}, nil
+	resp := recoveredResponse(task, result, recovery)
+	carryFiles(resp, child)
+	carryOutcome(resp, child)
+	a.reportStatus(ctx, nil, task, resp, child, result)
+	return resp, nil
 }
 
-// selectDirectorWithMemory selects the best director based on round-robin and memory.
-func (a *SecretaryAgent) selectDirectorWithMemory(ctx context.Context, task *types.Task) types.Agent {
+// selectDirectorWithMemory selects the index of the best director based on routing rules, round-robin and memory.
+func (a *SecretaryAgent) selectDirectorWithMemory(ctx context.Context, task *types.Task) int {
 	// Default round-robin selection
 	idx := atomic.AddUint32(&a.nextDirectorIdx, 1) - 1
 	selectedIdx := int(idx) % len(a.directors)
 
+	// Routing rules for the task's labels take precedence
+	if routed, reason, ok := a.routeByLabel(a.directors, task); ok {
+		a.logf("%s", reason)
+		return routed
+	}
+
 	// Try to use memory to inform selection
 	if mem := a.GetMemory(); mem != nil {
 		// Look for similar past tasks
@@ -132,5 +164,5 @@ func (a *SecretaryAgent) selectDirectorWithMemory(ctx context.Context, task *typ
 		}
 	}
 
-	return a.directors[selectedIdx]
+	return selectedIdx
 }
@@ -0,0 +1,43 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kpango/BuildBureau/internal/logging"
+	"github.com/kpango/BuildBureau/internal/slo"
+)
+
+// openSLOs sets up the configured service level objectives. Breaches and
+// recoveries are logged as organization events and sent to the notifier.
+// It returns nil if SLOs are not enabled.
+func (o *Organization) openSLOs() *slo.Tracker {
+	tracker, err := slo.New(o.config.SLOs, slo.WithAlert(o.alertSLO))
+	if err != nil {
+		fmt.Printf("Warning: Ignoring SLOs: %v\n", err)
+		return nil
+	}
+	return tracker
+}
+
+// alertSLO announces that an objective was breached or recovered.
+func (o *Organization) alertSLO(status slo.Status) {
+	notificationType, message := slo.TypeRecovered, "SLO recovered: "+status.String()
+	if status.Breached {
+		notificationType, message = slo.TypeBreached, "SLO breached: "+status.String()
+	}
+
+	o.activity.Log(organizationLog, logging.Entry{Kind: logging.KindEvent, Content: message})
+	if o.notifier == nil {
+		return
+	}
+	if err := o.notifier.Notify(context.Background(), notificationType, message); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+}
+
+// SLOs returns the current state of every service level objective, or nil
+// if SLOs are not enabled.
+func (o *Organization) SLOs() []slo.Status {
+	return o.slos.Status()
+}
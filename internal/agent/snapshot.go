@@ -0,0 +1,171 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kpango/BuildBureau/internal/llm"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// snapshotVersion is incremented when the snapshot format changes incompatibly.
+const snapshotVersion = 1
+
+// Snapshot is the runtime state of an organization, sufficient to recreate it
+// on another machine. Agent configurations are embedded so the agents/*.yaml
+// files do not need to be copied. The memory database is referenced, not
+// copied; move it alongside the snapshot and update Config.Memory if its path
+// changes.
+type Snapshot struct {
+	CreatedAt    time.Time                     `json:"created_at"`
+	Config       *types.Config                 `json:"config"`
+	AgentConfigs map[string]*types.AgentConfig `json:"agent_configs"` // Keyed by layer name, or team/role for team agents
+	Memory       MemorySnapshot                `json:"memory"`
+	Agents       []AgentSnapshot               `json:"agents"`
+	Tasks        []TaskSnapshot                `json:"tasks"` // Client tasks in flight when the snapshot was taken
+	Version      int                           `json:"version"`
+}
+
+// AgentSnapshot records an agent's counters.
+type AgentSnapshot struct {
+	ID             string          `json:"id"`
+	Role           types.AgentRole `json:"role"`
+	ActiveTasks    int             `json:"active_tasks"`
+	CompletedTasks int             `json:"completed_tasks"`
+}
+
+// TaskSnapshot is a client task that had not finished.
+type TaskSnapshot struct {
+	StartedAt time.Time   `json:"started_at"`
+	Task      *types.Task `json:"task"`
+}
+
+// MemorySnapshot references the memory backends the organization used.
+type MemorySnapshot struct {
+	SQLitePath  string `json:"sqlite_path,omitempty"`
+	ValdAddress string `json:"vald_address,omitempty"`
+}
+
+// Snapshot captures the organization's current runtime state.
+func (o *Organization) Snapshot() *Snapshot {
+	snap := &Snapshot{
+		Version:      snapshotVersion,
+		CreatedAt:    time.Now(),
+		Config:       o.config,
+		AgentConfigs: o.agentConfigs,
+	}
+
+	for _, a := range o.allAgents() {
+		s := AgentSnapshot{ID: a.GetID(), Role: a.GetRole()}
+		if counter, ok := a.(interface{ GetStats() (int, int) }); ok {
+			s.ActiveTasks, s.CompletedTasks = counter.GetStats()
+		}
+		snap.Agents = append(snap.Agents, s)
+	}
+
+	o.mu.Lock()
+	for _, t := range o.inflight {
+		snap.Tasks = append(snap.Tasks, t)
+	}
+	snap.Tasks = append(snap.Tasks, o.pending...)
+	o.mu.Unlock()
+
+	if mem := o.config.Memory; mem != nil && mem.Enabled {
+		if mem.SQLite.Enabled && !mem.SQLite.InMemory {
+			snap.Memory.SQLitePath = mem.SQLite.Path
+		}
+		if mem.Vald.Enabled {
+			snap.Memory.ValdAddress = fmt.Sprintf("%s:%d", mem.Vald.Host, mem.Vald.Port)
+		}
+	}
+
+	return snap
+}
+
+// SaveSnapshot writes the organization's current state to path.
+func (o *Organization) SaveSnapshot(path string) error {
+	data, err := json.MarshalIndent(o.Snapshot(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated snapshot
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// LoadSnapshot reads a snapshot written by SaveSnapshot.
+func LoadSnapshot(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot: %w", err)
+	}
+
+	if snap.Version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d (expected %d)", snap.Version, snapshotVersion)
+	}
+	if snap.Config == nil {
+		return nil, fmt.Errorf("snapshot has no configuration")
+	}
+
+	return &snap, nil
+}
+
+// RestoreOrganization recreates an organization from a snapshot. Agent
+// counters are restored, and client tasks that were in flight become pending;
+// callers resume them with TakePending. A nil LLM manager creates an organization
+// whose agents work without LLM assistance.
+func RestoreOrganization(snap *Snapshot, llmManager *llm.Manager) (*Organization, error) {
+	if snap.Memory.SQLitePath != "" {
+		if _, err := os.Stat(snap.Memory.SQLitePath); err != nil {
+			fmt.Printf("Warning: memory database %s not found; copy it from the original machine to keep agent memory\n", snap.Memory.SQLitePath)
+		}
+	}
+
+	org, err := newOrganization(snap.Config, llmManager, snap.AgentConfigs)
+	if err != nil {
+		return nil, err
+	}
+
+	completed := make(map[string]int, len(snap.Agents))
+	for _, a := range snap.Agents {
+		// Tasks that were active did not complete; they are resumed instead
+		completed[a.ID] = a.CompletedTasks
+	}
+	for _, a := range org.allAgents() {
+		if restorer, ok := a.(interface{ restoreStats(completed int) }); ok {
+			restorer.restoreStats(completed[a.GetID()])
+		}
+	}
+
+	org.pending = append(org.pending, snap.Tasks...)
+
+	return org, nil
+}
+
+// TakePending returns the client tasks restored from a snapshot that have not
+// been resumed yet, and clears them. Each task keeps its run ID so memories from
+// before and after the restore stay linked.
+func (o *Organization) TakePending() []TaskSnapshot {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	pending := o.pending
+	o.pending = nil
+	return pending
+}
@@ -0,0 +1,149 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// StartupStage reports how one stage of starting the organization went, with
+// a check for each store, provider or agent it started.
+type StartupStage struct {
+	Name   string           `json:"name"`
+	Checks []ReadinessCheck `json:"checks"`
+}
+
+// StartupReport reports how the organization started. Agents that failed to
+// start are detached from their superiors, which delegate to the rest.
+type StartupReport struct {
+	Stages []StartupStage `json:"stages"`
+	Failed []string       `json:"failed,omitempty"` // IDs of agents that did not start
+}
+
+// readier is implemented by agents that can tell when they are ready to take
+// tasks after starting, such as agents served by another process.
+type readier interface {
+	Ready(ctx context.Context) error
+}
+
+// agentStage is a group of agents started together.
+type agentStage struct {
+	name   string
+	agents []types.Agent
+	spared bool // Agents of the stage may fail as long as one starts
+}
+
+// agentStages returns the agents of the organization in the order they are
+// started: those without subordinates first, so every agent's subordinates
+// are ready before it can delegate to them.
+func (o *Organization) agentStages() []agentStage {
+	var secretaries []types.Agent
+	for _, key := range slices.Sorted(maps.Keys(o.secretaries)) {
+		secretaries = append(secretaries, o.secretaries[key])
+	}
+
+	stages := []agentStage{
+		{name: "engineers", agents: o.engineers, spared: true},
+		{name: "managers", agents: o.managers, spared: true},
+		{name: "directors", agents: o.directors, spared: true},
+		{name: "secretaries", agents: secretaries},
+	}
+	if o.president != nil {
+		stages = append(stages, agentStage{name: "president", agents: []types.Agent{o.president}})
+	}
+	if o.client != nil {
+		stages = append(stages, agentStage{name: "client", agents: []types.Agent{o.client}})
+	}
+	return stages
+}
+
+// startAgents starts the agents stage by stage, checking that each is ready.
+// An engineer, manager or director that fails is detached from its superiors
+// and reported, unless every agent of its stage failed; any other failure
+// stops the agents already started and fails the start.
+func (o *Organization) startAgents(ctx context.Context, report *StartupReport) error {
+	var started []types.Agent
+	for _, stage := range o.agentStages() {
+		if len(stage.agents) == 0 {
+			continue
+		}
+
+		result := StartupStage{Name: stage.name}
+		failed := 0
+		for _, agent := range stage.agents {
+			check := ReadinessCheck{Name: agent.GetID(), Ready: true}
+			if err := startAgent(ctx, agent); err != nil {
+				check.Ready, check.Detail = false, err.Error()
+				failed++
+			} else {
+				started = append(started, agent)
+			}
+			result.Checks = append(result.Checks, check)
+		}
+		report.Stages = append(report.Stages, result)
+
+		if failed == 0 {
+			continue
+		}
+		if !stage.spared || failed == len(stage.agents) {
+			for _, agent := range slices.Backward(started) {
+				_ = agent.Stop(ctx)
+			}
+			i := slices.IndexFunc(result.Checks, func(c ReadinessCheck) bool { return !c.Ready })
+			return fmt.Errorf("failed to start agent %s: %s", result.Checks[i].Name, result.Checks[i].Detail)
+		}
+		for i, check := range result.Checks {
+			if !check.Ready {
+				fmt.Printf("Warning: Starting without %s: %s\n", check.Name, check.Detail)
+				o.detach(stage.agents[i])
+				report.Failed = append(report.Failed, check.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// startAgent starts an agent and waits until it reports being ready. An agent
+// that starts but is not ready is stopped again.
+func startAgent(ctx context.Context, agent types.Agent) error {
+	if err := agent.Start(ctx); err != nil {
+		return err
+	}
+	if r, ok := agent.(readier); ok {
+		if err := r.Ready(ctx); err != nil {
+			_ = agent.Stop(ctx)
+			return fmt.Errorf("not ready: %w", err)
+		}
+	}
+	return nil
+}
+
+// detach removes an agent from the subordinates of every agent above it.
+func (o *Organization) detach(agent types.Agent) {
+	id := agent.GetID()
+	for _, secretary := range o.secretaries {
+		if s, ok := secretary.(*SecretaryAgent); ok {
+			s.RemoveDirector(id)
+		}
+	}
+	for _, director := range o.directors {
+		if d, ok := director.(*DirectorAgent); ok {
+			d.RemoveManager(id)
+		}
+	}
+	for _, manager := range o.managers {
+		if m, ok := manager.(*ManagerAgent); ok {
+			m.RemoveEngineer(id)
+		}
+	}
+}
+
+// StartupReport returns how the organization last started.
+func (o *Organization) StartupReport() StartupReport {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.startup
+}
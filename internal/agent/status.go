@@ -0,0 +1,188 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/kpango/BuildBureau/internal/llm"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+const (
+	// MetadataStatus holds the JSON-encoded StatusSummary of a response.
+	MetadataStatus = "status"
+	// MetadataDeliverable holds the deliverable of a response, without the
+	// progress narrative of the agents it passed through.
+	MetadataDeliverable = "deliverable"
+
+	maxArtifacts = 10
+)
+
+// StatusSummary is a compact report a subordinate sends to its parent instead
+// of its full output.
+type StatusSummary struct {
+	Done      string   `json:"done"`
+	Blockers  []string `json:"blockers,omitempty"`
+	Artifacts []string `json:"artifacts,omitempty"`
+}
+
+// String renders the summary on a single line.
+func (s StatusSummary) String() string {
+	parts := []string{s.Done}
+	if len(s.Blockers) > 0 {
+		parts = append(parts, "blockers: "+strings.Join(s.Blockers, "; "))
+	}
+	if len(s.Artifacts) > 0 {
+		parts = append(parts, "artifacts: "+strings.Join(s.Artifacts, ", "))
+	}
+	return strings.Join(parts, " | ")
+}
+
+// statusPrompt asks the LLM to summarize finished work for the delegating agent.
+const statusPrompt = `Summarize the following work for the person who assigned it.
+
+Task: %s
+
+Work:
+%s
+
+Respond ONLY with a JSON object in this exact format:
+{"done": "one or two sentences on what was done", "blockers": ["unresolved problems"], "artifacts": ["files or components produced"]}`
+
+// artifactPattern matches file names mentioned in a deliverable.
+var artifactPattern = regexp.MustCompile(`\b[\w./-]+\.(?:go|py|js|jsx|ts|tsx|java|rb|rs|c|h|cpp|cs|php|swift|kt|sql|sh|ya?ml|json|toml|md|html|css)\b`)
+
+// StatusOf returns the status summary attached to a response, if any.
+func StatusOf(resp *types.TaskResponse) (*StatusSummary, bool) {
+	if resp == nil || resp.Metadata[MetadataStatus] == "" {
+		return nil, false
+	}
+
+	var status StatusSummary
+	if err := json.Unmarshal([]byte(resp.Metadata[MetadataStatus]), &status); err != nil {
+		return nil, false
+	}
+	return &status, true
+}
+
+// Deliverable returns the deliverable of a response: the work product without
+// the progress narrative if status reports are enabled, otherwise the result.
+func Deliverable(resp *types.TaskResponse) string {
+	if deliverable, ok := resp.Metadata[MetadataDeliverable]; ok {
+		return deliverable
+	}
+	return resp.Result
+}
+
+// subordinateReport renders a subordinate's response for the delegating
+// agent's result: its status summary if it sent one, otherwise its full result.
+func (a *BaseAgent) subordinateReport(label string, resp *types.TaskResponse) string {
+	if status, ok := StatusOf(resp); ok && a.statusReports {
+		return fmt.Sprintf("%s report: %s\n", label, status)
+	}
+	return fmt.Sprintf("%s response: %s\n", label, resp.Result)
+}
+
+// reportStatus attaches a status summary and the deliverable to resp when
+// status reports are enabled. Agents that did the work themselves pass their
+// deliverable and a nil child; delegating agents pass the subordinate's
+// response, whose summary and deliverable are carried up unchanged so the
+// deliverable appears once in the final result rather than once per layer.
+func (a *BaseAgent) reportStatus(ctx context.Context, llmManager *llm.Manager, task *types.Task, resp, child *types.TaskResponse, deliverable string) {
+	if !a.statusReports {
+		return
+	}
+
+	var status StatusSummary
+	if child != nil {
+		if s, ok := StatusOf(child); ok {
+			status = *s
+		}
+		deliverable = Deliverable(child)
+		resp.Result += "\n=== Deliverable ===\n" + deliverable + "\n=== End of Deliverable ===\n"
+	}
+	if status.Done == "" {
+		status = a.summarizeStatus(ctx, llmManager, task, resp.Result, deliverable)
+	}
+	if recovery := resp.Metadata[MetadataRecovery]; recovery != "" {
+		status.Blockers = append(slices.Clone(status.Blockers), "recovered after re-planning: "+recovery)
+	}
+
+	encoded, err := json.Marshal(status)
+	if err != nil {
+		a.logf("failed to encode status summary: %v", err)
+		return
+	}
+
+	if resp.Metadata == nil {
+		resp.Metadata = make(map[string]string)
+	}
+	resp.Metadata[MetadataStatus] = string(encoded)
+	resp.Metadata[MetadataDeliverable] = deliverable
+}
+
+// summarizeStatus asks the LLM for a status summary of the work, falling back
+// to one derived from the text if no LLM is available or its answer is unusable.
+func (a *BaseAgent) summarizeStatus(ctx context.Context, llmManager *llm.Manager, task *types.Task, result, deliverable string) StatusSummary {
+	if llmManager != nil {
+		output, err := a.generate(ctx, llmManager, fmt.Sprintf(statusPrompt, task.Title, deliverable), &llm.GenerateOptions{
+			Temperature: 0.2,
+			MaxTokens:   512,
+		})
+		if err == nil {
+			if status, err := parseStatus(output); err == nil {
+				return *status
+			}
+		}
+	}
+
+	return heuristicStatus(result, deliverable)
+}
+
+// parseStatus parses the JSON status summary, tolerating surrounding prose and code fences.
+func parseStatus(output string) (*StatusSummary, error) {
+	start := strings.Index(output, "{")
+	end := strings.LastIndex(output, "}")
+	if start == -1 || end < start {
+		return nil, fmt.Errorf("no status summary found in output")
+	}
+
+	var status StatusSummary
+	if err := json.Unmarshal([]byte(output[start:end+1]), &status); err != nil {
+		return nil, fmt.Errorf("failed to parse status summary: %w", err)
+	}
+	if status.Done == "" {
+		return nil, fmt.Errorf("status summary has no description of the work done")
+	}
+
+	return &status, nil
+}
+
+// heuristicStatus derives a status summary without an LLM: the first paragraph
+// of the deliverable, warnings and errors reported in the result, and file
+// names mentioned in the deliverable.
+func heuristicStatus(result, deliverable string) StatusSummary {
+	status := StatusSummary{Done: summarize(deliverable)}
+
+	for line := range strings.Lines(result) {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "Warning:") || strings.HasPrefix(line, "Error") {
+			status.Blockers = append(status.Blockers, line)
+		}
+	}
+
+	for _, name := range artifactPattern.FindAllString(deliverable, -1) {
+		if len(status.Artifacts) == maxArtifacts {
+			break
+		}
+		if !slices.Contains(status.Artifacts, name) {
+			status.Artifacts = append(status.Artifacts, name)
+		}
+	}
+
+	return status
+}
@@ -0,0 +1,113 @@
+package agent
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/kpango/BuildBureau/internal/config"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// buildTeams creates the directors, managers and engineers of each configured
+// team and records which agents report to which, so that each manager only
+// delegates to its own engineers rather than to every engineer.
+func (o *Organization) buildTeams(loader *config.Loader, directing, delegating, engineering []Option) error {
+	o.edges = make(map[string][]types.Agent)
+	seen := make(map[string]bool)
+
+	for _, team := range o.config.Organization.Teams {
+		if team.Name == "" {
+			return fmt.Errorf("team name is required")
+		}
+		if seen[team.Name] {
+			return fmt.Errorf("team %s is defined more than once", team.Name)
+		}
+		seen[team.Name] = true
+
+		directorCfg, err := o.teamAgentConfig(loader, team, "Director", team.Director)
+		if err != nil {
+			return err
+		}
+		managerCfg, err := o.teamAgentConfig(loader, team, "Manager", team.Manager)
+		if err != nil {
+			return err
+		}
+		engineerCfg, err := o.teamAgentConfig(loader, team, "Engineer", team.Engineer)
+		if err != nil {
+			return err
+		}
+
+		// Routing rules can name the team its agents belong to, and agents
+		// take the task timeout of the layer named after their role
+		member := WithTeam(team.Name)
+		var managers, engineers int
+		for d := range max(team.Director.Count, 1) {
+			director := NewDirectorAgent(fmt.Sprintf("director-%s-%d", team.Name, d+1), directorCfg, append(slices.Clone(directing), member, WithTimeout(o.layerTimeout("Director")))...)
+			o.directors = append(o.directors, director)
+
+			for range max(team.Manager.Count, 1) {
+				managers++
+				manager := NewManagerAgent(fmt.Sprintf("manager-%s-%d", team.Name, managers), managerCfg, o.llmManager, append(slices.Clone(delegating), member, WithTimeout(o.layerTimeout("Manager")))...)
+				o.managers = append(o.managers, manager)
+				o.edges[director.GetID()] = append(o.edges[director.GetID()], manager)
+
+				for range max(team.Engineer.Count, 1) {
+					engineers++
+					engineer := NewEngineerAgent(fmt.Sprintf("engineer-%s-%d", team.Name, engineers), engineerCfg, o.llmManager, append(slices.Clone(engineering), member, WithTimeout(o.layerTimeout("Engineer")))...)
+					o.engineers = append(o.engineers, engineer)
+					o.edges[manager.GetID()] = append(o.edges[manager.GetID()], engineer)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// layerTimeout returns the task timeout of the layer with the given name, or
+// 0 if there is no such layer.
+func (o *Organization) layerTimeout(name string) time.Duration {
+	for _, layer := range o.config.Organization.Layers {
+		if layer.Name == name {
+			return layer.Timeout
+		}
+	}
+	return 0
+}
+
+// teamAgentConfig returns the agent configuration of a role in a team: the
+// role's own agent definition, or else that of the layer named after the
+// role, with the team's and role's specialties added to its capabilities.
+func (o *Organization) teamAgentConfig(loader *config.Loader, team types.TeamConfig, role string, roleCfg types.TeamRoleConfig) (*types.AgentConfig, error) {
+	layer := types.LayerConfig{Name: "team/" + team.Name + "/" + role, Agent: roleCfg.Agent}
+	if layer.Agent == "" {
+		i := slices.IndexFunc(o.config.Organization.Layers, func(l types.LayerConfig) bool { return l.Name == role })
+		if i < 0 || o.config.Organization.Layers[i].Agent == "" {
+			return nil, fmt.Errorf("team %s has no %s agent and there is no %s layer to default to", team.Name, role, role)
+		}
+		layer = o.config.Organization.Layers[i]
+	}
+
+	agentCfg, err := o.loadAgentConfig(loader, layer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s config of team %s: %w", role, team.Name, err)
+	}
+
+	specialties := append(slices.Clone(team.Specialties), roleCfg.Specialties...)
+	if len(specialties) == 0 {
+		return agentCfg, nil
+	}
+	teamCfg := *agentCfg
+	teamCfg.Capabilities = append(slices.Clone(agentCfg.Capabilities), specialties...)
+	return &teamCfg, nil
+}
+
+// subordinates returns the agents among candidates that report to the agent.
+// Without teams every candidate does.
+func (o *Organization) subordinates(agent types.Agent, candidates []types.Agent) []types.Agent {
+	if o.edges == nil {
+		return candidates
+	}
+	return o.edges[agent.GetID()]
+}
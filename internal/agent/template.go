@@ -0,0 +1,146 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/kpango/BuildBureau/internal/logging"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// summaryLength bounds the summary offered to delegation templates, in characters.
+const summaryLength = 500
+
+// DelegationData is the data available to delegation templates.
+type DelegationData struct {
+	Title       string   // Title of the task being delegated
+	Description string   // Original client requirements
+	Content     string   // Content of the task the agent received
+	Output      string   // What the agent produced, e.g. a manager's design; empty for agents that pass tasks on
+	Summary     string   // First paragraph of Output, or of Content if there is no output
+	Memory      []string // Related past tasks from the agent's memory, up to memory_excerpts
+}
+
+var templateFuncs = template.FuncMap{
+	"truncate": truncate,
+	"join":     strings.Join,
+}
+
+// delegationContent renders the content of a subtask. Without a configured
+// template the subtask gets fallback, the role's built-in content. A template
+// that fails is reported and the fallback used instead.
+func (a *BaseAgent) delegationContent(ctx context.Context, task *types.Task, output, fallback string) string {
+	cfg := a.delegationConfig()
+	if cfg == nil {
+		return fallback
+	}
+
+	content := fallback
+	if cfg.Template != "" {
+		rendered, err := a.renderDelegation(ctx, cfg, task, output)
+		if err != nil {
+			a.logf("delegation template failed, passing the full context: %v", err)
+			a.record(ctx, logging.KindError, "", fmt.Sprintf("delegation template failed: %v", err))
+		} else {
+			content = rendered
+		}
+	}
+
+	if cfg.MaxChars > 0 {
+		content = truncate(cfg.MaxChars, content)
+	}
+	return content
+}
+
+// renderDelegation executes the delegation template for a task.
+func (a *BaseAgent) renderDelegation(ctx context.Context, cfg *types.DelegationConfig, task *types.Task, output string) (string, error) {
+	tmpl, err := template.New("delegation").Funcs(templateFuncs).Option("missingkey=error").Parse(cfg.Template)
+	if err != nil {
+		return "", err
+	}
+
+	data := DelegationData{
+		Title:       task.Title,
+		Description: task.Description,
+		Content:     task.Content,
+		Output:      output,
+		Summary:     summarize(output),
+	}
+	if data.Summary == "" {
+		data.Summary = summarize(task.Content)
+	}
+
+	if mem := a.GetMemory(); mem != nil && cfg.MemoryExcerpts > 0 {
+		related, err := mem.GetRelatedTasks(ctx, task.Description, cfg.MemoryExcerpts)
+		if err == nil {
+			for _, m := range related {
+				data.Memory = append(data.Memory, m.Content)
+			}
+		}
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// delegationMetadata returns the metadata of task forwarded to its subtasks:
+// its labels, and the keys the delegation settings list.
+func (a *BaseAgent) delegationMetadata(task *types.Task) map[string]string {
+	var forwarded map[string]string
+	if labels := types.Labels(task.Metadata); len(labels) > 0 {
+		forwarded = make(map[string]string, len(labels))
+		types.SetLabels(forwarded, labels)
+	}
+
+	cfg := a.delegationConfig()
+	if cfg == nil || len(cfg.Metadata) == 0 || len(task.Metadata) == 0 {
+		return forwarded
+	}
+
+	if slices.Contains(cfg.Metadata, "*") {
+		return maps.Clone(task.Metadata)
+	}
+
+	if forwarded == nil {
+		forwarded = make(map[string]string)
+	}
+	for _, key := range cfg.Metadata {
+		if value, ok := task.Metadata[key]; ok {
+			forwarded[key] = value
+		}
+	}
+	return forwarded
+}
+
+// delegationConfig returns the agent's delegation settings, or nil for the defaults.
+func (a *BaseAgent) delegationConfig() *types.DelegationConfig {
+	if a.config == nil {
+		return nil
+	}
+	return a.config.Delegation
+}
+
+// summarize returns the first paragraph of text, truncated to summaryLength.
+func summarize(text string) string {
+	paragraph, _, _ := strings.Cut(strings.TrimSpace(text), "\n\n")
+	return truncate(summaryLength, paragraph)
+}
+
+// truncate shortens text to at most n characters, marking the cut.
+func truncate(n int, text string) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	if n <= 3 {
+		return string(runes[:n])
+	}
+	return string(runes[:n-3]) + "..."
+}
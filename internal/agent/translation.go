@@ -0,0 +1,161 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/kpango/BuildBureau/internal/llm"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+const (
+	// MetadataLanguage holds the language a client request was written in
+	// when it was translated into the canonical language.
+	MetadataLanguage = "language"
+	// MetadataOriginalRequest holds a translated client request as written.
+	MetadataOriginalRequest = "original_request"
+	// MetadataCanonicalResult holds a translated deliverable in the canonical language.
+	MetadataCanonicalResult = "canonical_result"
+
+	defaultCanonicalLanguage = "English"
+)
+
+// englishHints are common English words rarely found in other languages. A
+// request written in plain ASCII containing two of them is taken as English
+// without asking the LLM.
+var englishHints = []string{"the", "and", "with", "that", "this", "should", "which", "from", "into", "have"}
+
+// detectionPrompt asks the LLM for the language of a request and, if needed, a translation.
+const detectionPrompt = `Identify the language of the following project request. If it is not written in %[1]s, translate it into %[1]s.
+Keep code, identifiers, file names and URLs unchanged.
+
+Respond ONLY with JSON in this exact format:
+{"language": "<name of the request's language, in English>", "translation": "<the request in %[1]s, or empty if it is already in %[1]s>"}
+
+Request:
+%[2]s`
+
+// renderPrompt asks the LLM to translate a deliverable for the requester.
+const renderPrompt = `Translate the following text from %s into %s.
+Keep code blocks, identifiers, file names, URLs and formatting unchanged. Respond with the translation only.
+
+Text:
+%s`
+
+// detection is the LLM's answer to detectionPrompt.
+type detection struct {
+	Language    string `json:"language"`
+	Translation string `json:"translation"`
+}
+
+// canonicalLanguage returns the language agents work in.
+func (o *Organization) canonicalLanguage() string {
+	if language := o.config.Organization.Translation.Language; language != "" {
+		return language
+	}
+	return defaultCanonicalLanguage
+}
+
+// translationModel returns the model used to detect and translate languages.
+func (o *Organization) translationModel() string {
+	if model := o.config.Organization.Translation.Model; model != "" {
+		return model
+	}
+	if m, ok := o.president.(interface{ Model() string }); ok {
+		return m.Model()
+	}
+	return ""
+}
+
+// translateRequest returns the request in the canonical language and the
+// language it was written in. The language is empty if the request needed no
+// translation or translation is disabled or failed.
+func (o *Organization) translateRequest(ctx context.Context, instruction string) (string, string) {
+	if !o.config.Organization.Translation.Enabled || o.llmManager == nil {
+		return instruction, ""
+	}
+
+	canonical := o.canonicalLanguage()
+	if strings.EqualFold(canonical, defaultCanonicalLanguage) && looksEnglish(instruction) {
+		return instruction, ""
+	}
+
+	output, err := o.llmManager.Generate(ctx, o.translationModel(), fmt.Sprintf(detectionPrompt, canonical, instruction), &llm.GenerateOptions{
+		Temperature: 0.0, // Translation should be faithful rather than creative
+	})
+	if err != nil {
+		fmt.Printf("Warning: failed to detect the request language: %v\n", err)
+		return instruction, ""
+	}
+
+	d, err := parseDetection(output)
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
+		return instruction, ""
+	}
+	if d.Translation == "" || strings.EqualFold(d.Language, canonical) {
+		return instruction, ""
+	}
+	return d.Translation, d.Language
+}
+
+// renderResponse translates the deliverable back into the requester's
+// language, keeping the canonical one in the response metadata.
+func (o *Organization) renderResponse(ctx context.Context, language string, resp *types.TaskResponse) {
+	if language == "" || resp == nil || resp.Result == "" {
+		return
+	}
+
+	output, err := o.llmManager.Generate(ctx, o.translationModel(), fmt.Sprintf(renderPrompt, o.canonicalLanguage(), language, resp.Result), &llm.GenerateOptions{
+		Temperature: 0.0,
+	})
+	if err != nil {
+		fmt.Printf("Warning: failed to translate the deliverable into %s: %v\n", language, err)
+		return
+	}
+
+	if resp.Metadata == nil {
+		resp.Metadata = make(map[string]string)
+	}
+	resp.Metadata[MetadataCanonicalResult] = resp.Result
+	resp.Result = strings.TrimSpace(output)
+}
+
+// parseDetection parses the JSON detection result, tolerating surrounding prose and code fences.
+func parseDetection(output string) (*detection, error) {
+	start := strings.Index(output, "{")
+	end := strings.LastIndex(output, "}")
+	if start == -1 || end < start {
+		return nil, fmt.Errorf("no language detection found in output")
+	}
+
+	var d detection
+	if err := json.Unmarshal([]byte(output[start:end+1]), &d); err != nil {
+		return nil, fmt.Errorf("failed to parse language detection: %w", err)
+	}
+	d.Language = strings.TrimSpace(d.Language)
+	d.Translation = strings.TrimSpace(d.Translation)
+	return &d, nil
+}
+
+// looksEnglish reports whether text is plainly English: ASCII only, with at
+// least two common English words. Anything else is left to the LLM.
+func looksEnglish(text string) bool {
+	for _, r := range text {
+		if r > unicode.MaxASCII {
+			return false
+		}
+	}
+
+	hints := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		if slices.Contains(englishHints, word) {
+			hints[word] = true
+		}
+	}
+	return len(hints) >= 2
+}
@@ -0,0 +1,162 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kpango/BuildBureau/internal/llm"
+	"github.com/kpango/BuildBureau/internal/logging"
+	"github.com/kpango/BuildBureau/internal/report"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// usageNode accumulates the usage of one dispatched task: the generations of
+// the agent processing it and the usage of the subtasks it delegates. It is
+// safe for concurrent use; a nil usageNode records nothing.
+type usageNode struct {
+	start    time.Time
+	agentID  string
+	meter    llm.Meter
+	mu       sync.Mutex
+	subtasks []*types.TaskUsage
+	replans  int
+}
+
+type usageKey struct{}
+
+// startUsage returns a context whose generations and subtasks are recorded
+// as the usage of a task processed by the given agent.
+func startUsage(ctx context.Context, agentID string) (context.Context, *usageNode) {
+	u := &usageNode{start: time.Now(), agentID: agentID}
+	ctx = context.WithValue(ctx, usageKey{}, u)
+	return llm.WithMeter(ctx, &u.meter), u
+}
+
+// usageFromContext returns the usage of the task being processed, or nil.
+func usageFromContext(ctx context.Context) *usageNode {
+	u, _ := ctx.Value(usageKey{}).(*usageNode)
+	return u
+}
+
+// addSubtask records the usage of a delegated subtask.
+func (u *usageNode) addSubtask(s *types.TaskUsage) {
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.subtasks = append(u.subtasks, s)
+}
+
+// addReplan records a subtask re-planned after it failed.
+func (u *usageNode) addReplan() {
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.replans++
+}
+
+// finish returns the usage of the task, accumulated over its subtasks.
+func (u *usageNode) finish() *types.TaskUsage {
+	own := u.meter.Usage()
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	total := &types.TaskUsage{
+		AgentID:          u.agentID,
+		Subtasks:         u.subtasks,
+		Latency:          time.Since(u.start),
+		Cost:             own.Cost,
+		PromptTokens:     own.PromptTokens,
+		CompletionTokens: own.CompletionTokens,
+		Generations:      own.Generations,
+		Retries:          own.Retries + u.replans,
+	}
+	for _, s := range u.subtasks {
+		total.Cost += s.Cost
+		total.PromptTokens += s.PromptTokens
+		total.CompletionTokens += s.CompletionTokens
+		total.Generations += s.Generations
+		total.Retries += s.Retries
+	}
+	return total
+}
+
+// formatUsage summarizes usage, e.g. "12.3k tokens in 9 generation(s), $0.0412, 1m5s".
+func formatUsage(u *types.TaskUsage) string {
+	summary := fmt.Sprintf("%s tokens in %d generation(s), $%.4f, %s", formatTokens(u.PromptTokens+u.CompletionTokens), u.Generations, u.Cost, u.Latency.Round(time.Second))
+	if u.Retries > 0 {
+		summary += fmt.Sprintf(", %d retry(s)", u.Retries)
+	}
+	return summary
+}
+
+// logUsage records what a project cost in the activity log.
+func (o *Organization) logUsage(runID string, resp *types.TaskResponse) {
+	if o.activity == nil || resp == nil || resp.Usage == nil {
+		return
+	}
+	o.activity.Log(organizationLog, logging.Entry{
+		Kind:    logging.KindEvent,
+		RunID:   runID,
+		Content: "project usage: " + formatUsage(resp.Usage),
+	})
+}
+
+// LLMCalls returns the log of recent calls to LLM providers, or nil.
+func (o *Organization) LLMCalls() *llm.CallLog {
+	if o.llmManager == nil {
+		return nil
+	}
+	return o.llmManager.Calls()
+}
+
+// slowestCallsReported is how many of a project's slowest LLM calls are kept
+// for its report.
+const slowestCallsReported = 10
+
+// recordCalls logs the slow LLM calls of a project to the activity log and
+// stores its slowest calls as memories for the project report.
+func (o *Organization) recordCalls(ctx context.Context, runID string) {
+	if o.llmManager == nil {
+		return
+	}
+	for _, c := range o.llmManager.Calls().Slowest(runID, slowestCallsReported) {
+		if c.Slow && o.activity != nil {
+			o.activity.Log(organizationLog, logging.Entry{
+				Kind:    logging.KindEvent,
+				RunID:   runID,
+				Content: fmt.Sprintf("slow LLM call: %s/%s took %s (%s)", c.Provider, c.Model, c.Latency.Round(time.Millisecond), c.Status),
+			})
+		}
+		if o.memoryManager == nil {
+			continue
+		}
+		entry := &types.MemoryEntry{
+			AgentID: organizationLog,
+			RunID:   runID,
+			Type:    types.MemoryTypeContext,
+			Content: c.Error,
+			Tags:    []string{"llm"},
+			Metadata: map[string]string{
+				report.MetadataKind:             report.KindLLMCall,
+				report.MetadataProvider:         c.Provider,
+				report.MetadataModel:            c.Model,
+				report.MetadataStatus:           c.Status,
+				report.MetadataLatency:          c.Latency.String(),
+				report.MetadataPromptTokens:     strconv.Itoa(c.PromptTokens),
+				report.MetadataCompletionTokens: strconv.Itoa(c.CompletionTokens),
+				report.MetadataSlow:             strconv.FormatBool(c.Slow),
+			},
+		}
+		if err := o.memoryManager.StoreMemory(context.WithoutCancel(ctx), entry); err != nil {
+			fmt.Printf("Warning: failed to store LLM call: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kpango/BuildBureau/internal/config"
+	"github.com/kpango/BuildBureau/internal/webhook"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// MetadataCallbacks records the callback URLs of a client task, separated by
+// spaces, so a resumed task keeps them.
+const MetadataCallbacks = "callback_urls"
+
+// webhookDrainTimeout bounds how long Stop waits for webhook deliveries to finish.
+const webhookDrainTimeout = 30 * time.Second
+
+// Project milestones reported to webhooks.
+const (
+	MilestoneDeliverable = "deliverable_ready"  // The president returned a deliverable
+	MilestoneRevision    = "revision_requested" // The deliverable was sent back for revision
+)
+
+type callbacksKey struct{}
+
+// WithCallbackURL returns a context whose project submission reports its
+// lifecycle events to url, in addition to the URLs in the webhooks
+// configuration. It can be used several times to add several URLs.
+func WithCallbackURL(ctx context.Context, url string) context.Context {
+	return context.WithValue(ctx, callbacksKey{}, append(slices.Clip(CallbackURLs(ctx)), url))
+}
+
+// CallbackURLs returns the callback URLs set in ctx.
+func CallbackURLs(ctx context.Context) []string {
+	urls, _ := ctx.Value(callbacksKey{}).([]string)
+	return urls
+}
+
+// openWebhooks sets up webhook delivery. It returns nil if webhooks are not
+// enabled or the configuration is invalid.
+func (o *Organization) openWebhooks() *webhook.Dispatcher {
+	cfg := o.config.Webhooks
+	if cfg == nil || !cfg.Enabled {
+		return nil
+	}
+	dispatcher, err := webhook.New(cfg, config.GetEnvValue(cfg.Secret))
+	if err != nil {
+		fmt.Printf("Warning: Webhooks disabled: %v\n", err)
+		return nil
+	}
+	return dispatcher
+}
+
+// closeWebhooks waits a while for webhook deliveries still being retried.
+func (o *Organization) closeWebhooks(ctx context.Context) {
+	if o.webhooks == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, webhookDrainTimeout)
+	defer cancel()
+	o.webhooks.Close(ctx)
+}
+
+// sendWebhook reports a lifecycle event of the project in ctx to the
+// configured URLs and the project's callbacks.
+func (o *Organization) sendWebhook(ctx context.Context, p webhook.Payload) {
+	if o.webhooks == nil {
+		return
+	}
+	if p.RunID == "" {
+		p.RunID = types.RunIDFromContext(ctx)
+	}
+	if p.User == "" {
+		p.User = types.UserFromContext(ctx)
+	}
+	o.webhooks.Send(p, CallbackURLs(ctx)...)
+}
+
+// milestone reports that a project reached a milestone.
+func (o *Organization) milestone(ctx context.Context, milestone, message string) {
+	o.sendWebhook(ctx, webhook.Payload{Event: webhook.EventMilestone, Milestone: milestone, Message: message})
+}
+
+// webhookFinished reports that a project finished, with its deliverable if it
+// has one.
+func (o *Organization) webhookFinished(ctx context.Context, resp *types.TaskResponse, err error) {
+	switch {
+	case err != nil:
+		o.sendWebhook(ctx, webhook.Payload{Event: webhook.EventFailed, Error: err.Error()})
+	case resp.Status == types.StatusFailed:
+		o.sendWebhook(ctx, webhook.Payload{Event: webhook.EventFailed, Error: resp.Error, Response: resp})
+	default:
+		o.sendWebhook(ctx, webhook.Payload{Event: webhook.EventCompleted, Response: resp})
+	}
+}
+
+// WithTaskCallbacks returns a context with the callback URLs recorded on a
+// client task, for resubmitting it.
+func WithTaskCallbacks(ctx context.Context, task *types.Task) context.Context {
+	for url := range strings.FieldsSeq(task.Metadata[MetadataCallbacks]) {
+		ctx = WithCallbackURL(ctx, url)
+	}
+	return ctx
+}
@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// MetadataWorkspace is the response metadata key holding the directory a
+// project's files were written to.
+const MetadataWorkspace = "workspace"
+
+// DefaultWorkspaceDir is where project files are written when no directory is configured.
+const DefaultWorkspaceDir = "workspace"
+
+// writeWorkspace writes the files of a finished run to its directory in the
+// configured workspace and returns the directory. Paths that would leave it
+// are skipped with a warning.
+func (o *Organization) writeWorkspace(runID string, files []types.FileArtifact) (string, error) {
+	dir := o.config.Workspace.Dir
+	if dir == "" {
+		dir = DefaultWorkspaceDir
+	}
+	dir = filepath.Join(dir, runID)
+
+	for _, f := range files {
+		if !filepath.IsLocal(f.Path) {
+			fmt.Printf("Warning: Not writing %s outside the workspace\n", f.Path)
+			continue
+		}
+
+		data := []byte(f.Content)
+		if f.Binary() {
+			decoded, err := base64.StdEncoding.DecodeString(f.Content)
+			if err != nil {
+				return "", fmt.Errorf("failed to decode %s: %w", f.Path, err)
+			}
+			data = decoded
+		}
+
+		path := filepath.Join(dir, f.Path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return "", fmt.Errorf("failed to write %s to the workspace: %w", f.Path, err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return "", fmt.Errorf("failed to write %s to the workspace: %w", f.Path, err)
+		}
+	}
+	return dir, nil
+}
@@ -0,0 +1,298 @@
+package config
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// ChangeKind is how a configuration value changed.
+type ChangeKind string
+
+const (
+	ChangeAdded    ChangeKind = "added"
+	ChangeRemoved  ChangeKind = "removed"
+	ChangeModified ChangeKind = "changed"
+)
+
+// ChangeCategory groups changes for review.
+type ChangeCategory string
+
+const (
+	CategoryAgents   ChangeCategory = "agents"   // Agents added or removed, or their definitions
+	CategoryModels   ChangeCategory = "models"   // The models agents generate with
+	CategoryBudgets  ChangeCategory = "budgets"  // Token, price and admission limits
+	CategorySettings ChangeCategory = "settings" // Everything else
+)
+
+// Change is one difference between two configurations.
+type Change struct {
+	Path     string // YAML path of the value, such as organization.layers.Engineer.count
+	Old      string // Value before, empty if added
+	New      string // Value after, empty if removed
+	Detail   string // What the change means, such as "2 agents added"
+	Target   string // Role a model change is applied to while running
+	Kind     ChangeKind
+	Category ChangeCategory
+	Restart  bool // Takes effect only when the organization is restarted
+}
+
+// Diff is what changes between a configuration and the next one.
+type Diff struct {
+	Changes []Change
+}
+
+// Empty reports whether the configurations are the same.
+func (d *Diff) Empty() bool {
+	return d == nil || len(d.Changes) == 0
+}
+
+// Live returns the changes applied to a running organization.
+func (d *Diff) Live() []Change {
+	if d == nil {
+		return nil
+	}
+	return slices.DeleteFunc(slices.Clone(d.Changes), func(c Change) bool { return c.Restart })
+}
+
+// Disruptive returns the changes that restart the organization, tearing
+// down its agents and the projects they are running.
+func (d *Diff) Disruptive() []Change {
+	if d == nil {
+		return nil
+	}
+	return slices.DeleteFunc(slices.Clone(d.Changes), func(c Change) bool { return !c.Restart })
+}
+
+// Compare returns what changes from old to next. Agent definitions are
+// loaded to compare the models of the layers; agents run the model of their
+// definition, or llms.default_model. Model changes of layers are applied
+// live; every other change takes effect at restart.
+func (l *Loader) Compare(old, next *types.Config) (*Diff, error) {
+	d := &Diff{}
+	if err := l.compareLayers(d, old, next); err != nil {
+		return nil, err
+	}
+	compareTeams(d, old.Organization.Teams, next.Organization.Teams)
+	compareValues(d, "", reflect.ValueOf(*old), reflect.ValueOf(*next))
+	slices.SortStableFunc(d.Changes, func(a, b Change) int {
+		return cmp.Compare(categoryOrder(a.Category), categoryOrder(b.Category))
+	})
+	return d, nil
+}
+
+// compareLayers records layers added and removed, changes to the number of
+// their agents and their definitions, and changes to the models they run.
+func (l *Loader) compareLayers(d *Diff, old, next *types.Config) error {
+	oldLayers := layersByName(old.Organization.Layers)
+	for _, layer := range next.Organization.Layers {
+		path := "organization.layers." + layer.Name
+		before, ok := oldLayers[layer.Name]
+		if !ok {
+			d.add(Change{Path: path, New: agentCount(layer), Detail: agentCount(layer) + " added", Kind: ChangeAdded, Category: CategoryAgents, Restart: true})
+			continue
+		}
+
+		if n, m := agents(before), agents(layer); n != m {
+			detail := fmt.Sprintf("%d agents added", m-n)
+			if m < n {
+				detail = fmt.Sprintf("%d agents removed", n-m)
+			}
+			d.add(Change{Path: path + ".count", Old: fmt.Sprint(n), New: fmt.Sprint(m), Detail: detail, Kind: ChangeModified, Category: CategoryAgents, Restart: true})
+		}
+		if before.Agent != layer.Agent {
+			d.add(Change{Path: path + ".agent", Old: before.Agent, New: layer.Agent, Detail: "agent definition replaced", Kind: ChangeModified, Category: CategoryAgents, Restart: true})
+		}
+
+		oldModel, err := l.layerModel(old, before)
+		if err != nil {
+			return err
+		}
+		newModel, err := l.layerModel(next, layer)
+		if err != nil {
+			return err
+		}
+		if oldModel != newModel {
+			d.add(Change{Path: path + ".model", Old: oldModel, New: newModel, Target: layer.Name, Kind: ChangeModified, Category: CategoryModels})
+		}
+	}
+
+	newLayers := layersByName(next.Organization.Layers)
+	for _, layer := range old.Organization.Layers {
+		if _, ok := newLayers[layer.Name]; !ok {
+			d.add(Change{Path: "organization.layers." + layer.Name, Old: agentCount(layer), Detail: agentCount(layer) + " removed", Kind: ChangeRemoved, Category: CategoryAgents, Restart: true})
+		}
+	}
+	return nil
+}
+
+// layerModel returns the model the agents of a layer run.
+func (l *Loader) layerModel(cfg *types.Config, layer types.LayerConfig) (string, error) {
+	if layer.Agent == "" {
+		return cfg.LLMs.DefaultModel, nil
+	}
+	agentCfg, err := l.LoadAgentConfig(layer.Agent)
+	if err != nil {
+		return "", fmt.Errorf("layer %s: %w", layer.Name, err)
+	}
+	return cmp.Or(agentCfg.Model, cfg.LLMs.DefaultModel), nil
+}
+
+// compareTeams records teams added, removed and reorganized.
+func compareTeams(d *Diff, old, next []types.TeamConfig) {
+	for _, team := range next {
+		path := "organization.teams." + team.Name
+		i := slices.IndexFunc(old, func(t types.TeamConfig) bool { return t.Name == team.Name })
+		switch {
+		case i < 0:
+			d.add(Change{Path: path, Detail: "team added", Kind: ChangeAdded, Category: CategoryAgents, Restart: true})
+		case !reflect.DeepEqual(old[i], team):
+			d.add(Change{Path: path, Detail: "team reorganized", Kind: ChangeModified, Category: CategoryAgents, Restart: true})
+		}
+	}
+	for _, team := range old {
+		if !slices.ContainsFunc(next, func(t types.TeamConfig) bool { return t.Name == team.Name }) {
+			d.add(Change{Path: "organization.teams." + team.Name, Detail: "team removed", Kind: ChangeRemoved, Category: CategoryAgents, Restart: true})
+		}
+	}
+}
+
+// compareValues records the differences between two values of the same
+// type by YAML path, skipping the layers and teams compared by name.
+func compareValues(d *Diff, path string, old, next reflect.Value) {
+	if path == "organization.layers" || path == "organization.teams" {
+		return
+	}
+	if reflect.DeepEqual(old.Interface(), next.Interface()) {
+		return
+	}
+
+	switch old.Kind() {
+	case reflect.Pointer:
+		switch {
+		case old.IsNil():
+			d.add(settingChange(path, ChangeAdded, "", "section added"))
+		case next.IsNil():
+			d.add(settingChange(path, ChangeRemoved, "", "section removed"))
+		default:
+			compareValues(d, path, old.Elem(), next.Elem())
+		}
+
+	case reflect.Struct:
+		for i := range old.NumField() {
+			name, _, _ := strings.Cut(old.Type().Field(i).Tag.Get("yaml"), ",")
+			if name == "" || name == "-" || !old.Type().Field(i).IsExported() {
+				continue
+			}
+			compareValues(d, join(path, name), old.Field(i), next.Field(i))
+		}
+
+	case reflect.Map:
+		keys := make(map[string]reflect.Value)
+		for _, key := range append(old.MapKeys(), next.MapKeys()...) {
+			keys[fmt.Sprint(key.Interface())] = key
+		}
+		for _, name := range slices.Sorted(maps.Keys(keys)) {
+			before, after := old.MapIndex(keys[name]), next.MapIndex(keys[name])
+			switch {
+			case !before.IsValid():
+				d.add(settingChange(join(path, name), ChangeAdded, formatValue(after), ""))
+			case !after.IsValid():
+				d.add(settingChange(join(path, name), ChangeRemoved, formatValue(before), ""))
+			default:
+				compareValues(d, join(path, name), before, after)
+			}
+		}
+
+	default:
+		c := settingChange(path, ChangeModified, formatValue(next), "")
+		c.Old = formatValue(old)
+		d.add(c)
+	}
+}
+
+// settingChange returns a change that takes effect at restart, categorized
+// by its path.
+func settingChange(path string, kind ChangeKind, value, detail string) Change {
+	c := Change{Path: path, Detail: detail, Kind: kind, Category: CategorySettings, Restart: true}
+	if kind == ChangeRemoved {
+		c.Old = value
+	} else {
+		c.New = value
+	}
+	for _, prefix := range []string{"llms.output_tokens", "llms.context_windows", "llms.prices", "organization.admission"} {
+		if path == prefix || strings.HasPrefix(path, prefix+".") {
+			c.Category = CategoryBudgets
+		}
+	}
+	if path == "llms.default_model" {
+		c.Category = CategoryModels
+	}
+	return c
+}
+
+// formatValue returns a value as shown in a diff: scalars as they are
+// written, and composite values by their size.
+func formatValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		if k := v.Type().Elem().Kind(); k != reflect.Struct && k != reflect.Pointer && k != reflect.Map {
+			return fmt.Sprint(v.Interface())
+		}
+		return fmt.Sprintf("%d items", v.Len())
+	case reflect.Map:
+		return fmt.Sprintf("%d entries", v.Len())
+	case reflect.Struct, reflect.Pointer:
+		return "set"
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+func (d *Diff) add(c Change) {
+	d.Changes = append(d.Changes, c)
+}
+
+// join appends a key to a YAML path.
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// categoryOrder orders changes by how much they disrupt.
+func categoryOrder(c ChangeCategory) int {
+	return slices.Index([]ChangeCategory{CategoryAgents, CategoryModels, CategoryBudgets, CategorySettings}, c)
+}
+
+// layersByName indexes layers by name.
+func layersByName(layers []types.LayerConfig) map[string]types.LayerConfig {
+	byName := make(map[string]types.LayerConfig, len(layers))
+	for _, layer := range layers {
+		byName[layer.Name] = layer
+	}
+	return byName
+}
+
+// agents returns the number of agents a layer creates: one per attachment
+// point for secretaries, otherwise count, which defaults to 1.
+func agents(layer types.LayerConfig) int {
+	if layer.Name == string(types.RoleSecretary) {
+		return len(layer.AttachTo)
+	}
+	return max(layer.Count, 1)
+}
+
+// agentCount describes the number of agents of a layer.
+func agentCount(layer types.LayerConfig) string {
+	if n := agents(layer); n != 1 {
+		return fmt.Sprintf("%d agents", n)
+	}
+	return "1 agent"
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+func TestCompare(t *testing.T) {
+	dir := t.TempDir()
+	agentFile := func(name, model string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("name: "+name+"\nmodel: "+model+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	gemini, openai, plain := agentFile("gemini.yaml", "gemini"), agentFile("openai.yaml", "gpt-4o"), agentFile("plain.yaml", "")
+
+	old := &types.Config{
+		LLMs: types.LLMConfig{DefaultModel: "gemini", OutputTokens: types.OutputTokensConfig{Code: 8192}},
+		Organization: types.OrganizationConfig{
+			Layers: []types.LayerConfig{
+				{Name: "President", Agent: gemini},
+				{Name: "Manager", Agent: plain},
+				{Name: "Engineer", Agent: gemini, Count: 2},
+				{Name: "Client", Agent: plain},
+			},
+		},
+	}
+
+	t.Run("Unchanged", func(t *testing.T) {
+		d, err := NewLoader().Compare(old, old)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !d.Empty() {
+			t.Errorf("Expected no changes, got %+v", d.Changes)
+		}
+	})
+
+	t.Run("Changed", func(t *testing.T) {
+		next := &types.Config{
+			LLMs:  types.LLMConfig{DefaultModel: "claude", OutputTokens: types.OutputTokensConfig{Code: 4096}},
+			Slack: &types.SlackConfig{Enabled: true},
+			Organization: types.OrganizationConfig{
+				Layers: []types.LayerConfig{
+					{Name: "President", Agent: openai},
+					{Name: "Manager", Agent: plain},
+					{Name: "Engineer", Agent: gemini, Count: 4},
+				},
+			},
+		}
+		d, err := NewLoader().Compare(old, next)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		changes := make(map[string]Change)
+		for _, c := range d.Changes {
+			changes[c.Path] = c
+		}
+		want := []Change{
+			{Path: "organization.layers.Engineer.count", Old: "2", New: "4", Detail: "2 agents added", Kind: ChangeModified, Category: CategoryAgents, Restart: true},
+			{Path: "organization.layers.Client", Old: "1 agent", Detail: "1 agent removed", Kind: ChangeRemoved, Category: CategoryAgents, Restart: true},
+			{Path: "organization.layers.President.agent", Old: gemini, New: openai, Detail: "agent definition replaced", Kind: ChangeModified, Category: CategoryAgents, Restart: true},
+			{Path: "organization.layers.President.model", Old: "gemini", New: "gpt-4o", Target: "President", Kind: ChangeModified, Category: CategoryModels},
+			{Path: "organization.layers.Manager.model", Old: "gemini", New: "claude", Target: "Manager", Kind: ChangeModified, Category: CategoryModels},
+			{Path: "llms.default_model", Old: "gemini", New: "claude", Kind: ChangeModified, Category: CategoryModels, Restart: true},
+			{Path: "llms.output_tokens.code", Old: "8192", New: "4096", Kind: ChangeModified, Category: CategoryBudgets, Restart: true},
+			{Path: "slack", Detail: "section added", Kind: ChangeAdded, Category: CategorySettings, Restart: true},
+		}
+		for _, w := range want {
+			if got, ok := changes[w.Path]; !ok || got != w {
+				t.Errorf("Expected change %+v, got %+v", w, got)
+			}
+		}
+		if len(d.Changes) != len(want) {
+			t.Errorf("Expected %d changes, got %+v", len(want), d.Changes)
+		}
+
+		if live := d.Live(); len(live) != 2 || live[0].Category != CategoryModels {
+			t.Errorf("Expected the layer model changes to apply live, got %+v", live)
+		}
+		if disruptive := d.Disruptive(); len(disruptive) != 6 || disruptive[0].Category != CategoryAgents {
+			t.Errorf("Expected agent changes first among those needing a restart, got %+v", disruptive)
+		}
+	})
+
+	t.Run("MissingAgentDefinition", func(t *testing.T) {
+		next := &types.Config{Organization: types.OrganizationConfig{
+			Layers: []types.LayerConfig{{Name: "President", Agent: filepath.Join(dir, "missing.yaml")}},
+		}}
+		if _, err := NewLoader().Compare(old, next); err == nil {
+			t.Error("Expected an error for a missing agent definition")
+		}
+	})
+}
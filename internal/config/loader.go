@@ -57,12 +57,12 @@ func (l *Loader) resolveEnvVars(config *types.Config) error {
 	// At least ONE provider must be available - this is validated in LLM Manager
 	availableProviders := 0
 	for key, envVar := range config.LLMs.APIKeys {
-		if envVar.Env != "" {
-			if value := os.Getenv(envVar.Env); value != "" {
+		if envVar.Env != "" || len(envVar.Envs) > 0 {
+			if len(GetEnvValues(envVar)) > 0 {
 				availableProviders++
 			} else {
 				// Warn but don't fail - users can run with just one provider
-				fmt.Printf("Warning: environment variable %s (for %s provider) is not set - this provider will be unavailable\n", envVar.Env, key)
+				fmt.Printf("Warning: no environment variable in %v (for %s provider) is set - this provider will be unavailable\n", envNames(envVar), key)
 			}
 		}
 	}
@@ -81,9 +81,42 @@ func (l *Loader) resolveEnvVars(config *types.Config) error {
 		}
 	}
 
+	// Resolve memory encryption key
+	if config.Memory != nil && config.Memory.SQLite.Encryption.Enabled {
+		if key := config.Memory.SQLite.Encryption.Key.Env; key == "" || os.Getenv(key) == "" {
+			return fmt.Errorf("environment variable %q (for memory encryption key) is not set", key)
+		}
+	}
+
 	return nil
 }
 
+// EnvValue is a resolved environment variable value with the name it came from.
+type EnvValue struct {
+	Name  string
+	Value string
+}
+
+// GetEnvValues retrieves every set value of an EnvironmentVariable, Env first, skipping unset variables.
+func GetEnvValues(envVar types.EnvironmentVariable) []EnvValue {
+	var values []EnvValue
+	for _, name := range envNames(envVar) {
+		if value := os.Getenv(name); value != "" {
+			values = append(values, EnvValue{Name: name, Value: value})
+		}
+	}
+	return values
+}
+
+// envNames lists the variable names of an EnvironmentVariable.
+func envNames(envVar types.EnvironmentVariable) []string {
+	var names []string
+	if envVar.Env != "" {
+		names = append(names, envVar.Env)
+	}
+	return append(names, envVar.Envs...)
+}
+
 // GetEnvValue retrieves the actual value from an EnvironmentVariable.
 func GetEnvValue(envVar types.EnvironmentVariable) string {
 	if envVar.Env != "" {
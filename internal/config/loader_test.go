@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -105,3 +106,48 @@ func TestGetEnvValue(t *testing.T) {
 		t.Errorf("Expected 'test-value', got '%s'", value)
 	}
 }
+
+// FuzzLoad checks that malformed configuration is rejected with an error
+// rather than a panic, and that a configuration that loads has every
+// environment variable it requires.
+func FuzzLoad(f *testing.F) {
+	f.Add([]byte("llms:\n  api_keys:\n    gemini: { env: FUZZ_API_KEY }\n"))
+	f.Add([]byte("llms:\n  api_keys:\n    gemini: { env: FUZZ_EMPTY, envs: [\"\", FUZZ_API_KEY, FUZZ_API_KEY] }\n"))
+	f.Add([]byte("llms:\n  api_keys:\n    gemini: { env: \"FUZZ_API_KEY=x\" }\n"))
+	f.Add([]byte("llms:\n  api_keys:\n    gemini: { env: FUZZ_API_KEY }\nslack:\n  enabled: true\n  token: { env: \"\" }\n"))
+	f.Add([]byte("llms:\n  api_keys:\n    gemini: { env: FUZZ_API_KEY }\nmemory:\n  sqlite:\n    encryption: { enabled: true, key: { env: FUZZ_EMPTY } }\n"))
+	f.Add([]byte("organization:\n  layers: [{ name: President, count: -1 }]\nllms: [1, 2]\n"))
+	f.Add([]byte("a: &a [*a]\n"))
+	f.Add([]byte("\t{"))
+
+	f.Setenv("FUZZ_API_KEY", "fuzz-key")
+	f.Setenv("FUZZ_EMPTY", "")
+	path := filepath.Join(f.TempDir(), "config.yaml")
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		cfg, err := NewLoader().Load(path)
+		if err != nil {
+			return
+		}
+
+		available := false
+		for _, envVar := range cfg.LLMs.APIKeys {
+			if len(GetEnvValues(envVar)) > 0 {
+				available = true
+			}
+		}
+		if !available {
+			t.Error("Expected a loaded config to have an LLM API key")
+		}
+		if cfg.Slack != nil && cfg.Slack.Enabled && cfg.Slack.Token.Env != "" && os.Getenv(cfg.Slack.Token.Env) == "" {
+			t.Errorf("Expected the Slack token %q to be set", cfg.Slack.Token.Env)
+		}
+		if cfg.Memory != nil && cfg.Memory.SQLite.Encryption.Enabled && GetEnvValue(cfg.Memory.SQLite.Encryption.Key) == "" {
+			t.Error("Expected the memory encryption key to be set")
+		}
+	})
+}
@@ -0,0 +1,83 @@
+// Package desktop shows OS desktop notifications so users running long
+// projects in a background terminal notice when they finish or need input.
+package desktop
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gen2brain/beeep"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// Notification types shown when the configuration does not list any.
+const (
+	TypeApprovalRequested = "approval_requested"
+	TypeProjectCompleted  = "project_completed"
+	TypeProjectFailed     = "project_failed"
+)
+
+var defaultNotifyOn = []string{TypeApprovalRequested, TypeProjectCompleted, TypeProjectFailed}
+
+// Notifier shows desktop notifications for selected notification types.
+type Notifier struct {
+	send     func(title, message string) error
+	notifyOn []string
+	enabled  bool
+}
+
+// Option configures a Notifier.
+type Option func(*Notifier)
+
+// WithSender replaces the function that displays notifications.
+func WithSender(send func(title, message string) error) Option {
+	return func(n *Notifier) {
+		n.send = send
+	}
+}
+
+// NewNotifier creates a desktop notifier. A nil or disabled configuration
+// creates a notifier that shows nothing.
+func NewNotifier(config *types.DesktopConfig, opts ...Option) *Notifier {
+	if config == nil || !config.Enabled {
+		return &Notifier{enabled: false}
+	}
+
+	beeep.AppName = "BuildBureau"
+	n := &Notifier{
+		enabled:  true,
+		notifyOn: config.NotifyOn,
+		send: func(title, message string) error {
+			return beeep.Notify(title, message, "")
+		},
+	}
+	if len(n.notifyOn) == 0 {
+		n.notifyOn = defaultNotifyOn
+	}
+
+	for _, opt := range opts {
+		opt(n)
+	}
+
+	return n
+}
+
+// Notify shows a desktop notification if the type is one the user asked for.
+func (n *Notifier) Notify(ctx context.Context, notificationType, message string) error {
+	if !n.enabled || !slices.Contains(n.notifyOn, notificationType) {
+		return nil
+	}
+
+	title := "BuildBureau: " + strings.ReplaceAll(notificationType, "_", " ")
+	if err := n.send(title, message); err != nil {
+		return fmt.Errorf("failed to show desktop notification: %w", err)
+	}
+	return nil
+}
+
+// Enabled reports whether the notifier shows anything.
+func (n *Notifier) Enabled() bool {
+	return n.enabled
+}
@@ -0,0 +1,62 @@
+package desktop
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+func TestNotifier(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("ShowsSelectedTypes", func(t *testing.T) {
+		var shown []string
+		n := NewNotifier(&types.DesktopConfig{Enabled: true}, WithSender(func(title, message string) error {
+			shown = append(shown, title+": "+message)
+			return nil
+		}))
+
+		_ = n.Notify(ctx, TypeProjectCompleted, "Completed: todo app")
+		_ = n.Notify(ctx, "task_assigned", "Task assigned")
+
+		if len(shown) != 1 || shown[0] != "BuildBureau: project completed: Completed: todo app" {
+			t.Errorf("Expected only the default types to be shown, got %v", shown)
+		}
+	})
+
+	t.Run("ConfiguredTypes", func(t *testing.T) {
+		var shown int
+		n := NewNotifier(&types.DesktopConfig{Enabled: true, NotifyOn: []string{"task_assigned"}}, WithSender(func(title, message string) error {
+			shown++
+			return nil
+		}))
+
+		_ = n.Notify(ctx, TypeProjectCompleted, "Completed")
+		_ = n.Notify(ctx, "task_assigned", "Task assigned")
+
+		if shown != 1 {
+			t.Errorf("Expected only configured types to be shown, got %d", shown)
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		n := NewNotifier(nil)
+		if n.Enabled() {
+			t.Error("Expected nil config to disable notifications")
+		}
+		if err := n.Notify(ctx, TypeProjectCompleted, "Completed"); err != nil {
+			t.Errorf("Expected disabled notifier to do nothing, got %v", err)
+		}
+	})
+
+	t.Run("ReportsErrors", func(t *testing.T) {
+		n := NewNotifier(&types.DesktopConfig{Enabled: true}, WithSender(func(title, message string) error {
+			return fmt.Errorf("no notification daemon")
+		}))
+		if err := n.Notify(ctx, TypeProjectFailed, "Failed"); err == nil {
+			t.Error("Expected the sender error to be returned")
+		}
+	})
+}
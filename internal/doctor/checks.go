@@ -0,0 +1,405 @@
+package doctor
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/kpango/BuildBureau/internal/config"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+const (
+	diskWarnBytes = 1 << 30   // 1 GiB
+	diskFailBytes = 100 << 20 // 100 MiB
+)
+
+// checkAgentConfigs verifies every agent file referenced by the organization loads.
+func (d *Doctor) checkAgentConfigs(cfg *types.Config) []Result {
+	loader := config.NewLoader()
+
+	var results []Result
+	for _, layer := range cfg.Organization.Layers {
+		if layer.Agent == "" {
+			continue
+		}
+
+		result := Result{Name: "agent config " + layer.Name}
+		if _, err := loader.LoadAgentConfig(layer.Agent); err != nil {
+			result.Status = StatusFail
+			result.Message = err.Error()
+			result.Hint = "Agent paths are resolved relative to the working directory; run from the repository root"
+		} else {
+			result.Status = StatusOK
+			result.Message = layer.Agent
+		}
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// checkAPIKeys verifies that each configured key is set and accepted by its provider.
+func (d *Doctor) checkAPIKeys(ctx context.Context, cfg *types.Config) []Result {
+	providers := make([]string, 0, len(cfg.LLMs.APIKeys))
+	for name := range cfg.LLMs.APIKeys {
+		providers = append(providers, name)
+	}
+	sort.Strings(providers)
+
+	var results []Result
+	available := 0
+	for _, name := range providers {
+		keys := config.GetEnvValues(cfg.LLMs.APIKeys[name])
+		if len(keys) == 0 {
+			results = append(results, Result{
+				Name:    "api key " + name,
+				Status:  StatusSkip,
+				Message: "not set",
+				Hint:    fmt.Sprintf("Export %s to enable this provider", cfg.LLMs.APIKeys[name].Env),
+			})
+			continue
+		}
+
+		for _, key := range keys {
+			result := d.probeKey(ctx, name, key)
+			if result.Status != StatusFail {
+				available++
+			}
+			results = append(results, result)
+		}
+	}
+
+	if available == 0 {
+		results = append(results, Result{
+			Name:    "api keys",
+			Status:  StatusFail,
+			Message: "no usable LLM provider key",
+			Hint:    "Set at least one of GEMINI_API_KEY, OPENAI_API_KEY or CLAUDE_API_KEY",
+		})
+	}
+
+	return results
+}
+
+// probeKey checks a key against the provider's model listing, which costs no tokens.
+func (d *Doctor) probeKey(ctx context.Context, provider string, key config.EnvValue) Result {
+	result := Result{Name: fmt.Sprintf("api key %s (%s)", provider, key.Name)}
+
+	probeURL := d.probeURLs[provider]
+	if probeURL == "" {
+		result.Status = StatusWarn
+		result.Message = "key set but no endpoint to verify it against"
+		result.Hint = fmt.Sprintf("Set %s_ENDPOINT for remote providers", strings.ToUpper(provider))
+		return result
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, probeURL, nil)
+	if err != nil {
+		result.Status = StatusFail
+		result.Message = fmt.Sprintf("invalid probe URL: %v", err)
+		return result
+	}
+
+	switch provider {
+	case "gemini":
+		q := req.URL.Query()
+		q.Set("key", key.Value)
+		req.URL.RawQuery = q.Encode()
+	case "claude":
+		req.Header.Set("x-api-key", key.Value)
+		req.Header.Set("anthropic-version", "2023-06-01")
+	default:
+		req.Header.Set("Authorization", "Bearer "+key.Value)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		result.Status = StatusFail
+		// Drop the query so the Gemini key never appears in output
+		result.Message = fmt.Sprintf("unreachable: %s", redactURL(err, req.URL))
+		result.Hint = "Check network access, proxies and firewalls for the provider's API host"
+		return result
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		result.Status = StatusFail
+		result.Message = fmt.Sprintf("key rejected (HTTP %d)", resp.StatusCode)
+		result.Hint = fmt.Sprintf("Regenerate the key and update %s", key.Name)
+	case resp.StatusCode == http.StatusTooManyRequests:
+		result.Status = StatusWarn
+		result.Message = "rate limited or out of quota (HTTP 429)"
+		result.Hint = "Check billing and quota in the provider console, or add more keys with api_keys.<provider>.envs"
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		result.Status = StatusOK
+		result.Message = "accepted"
+		if remaining := resp.Header.Get("x-ratelimit-remaining-requests"); remaining != "" {
+			result.Message += fmt.Sprintf(", %s requests remaining in window", remaining)
+		}
+	case resp.StatusCode == http.StatusNotFound && d.isRemote(provider):
+		// Remote providers only implement /v1/generate; any response proves reachability
+		result.Status = StatusOK
+		result.Message = "endpoint reachable"
+	default:
+		result.Status = StatusWarn
+		result.Message = fmt.Sprintf("unexpected HTTP %d", resp.StatusCode)
+		result.Hint = "The provider may be degraded; retry later"
+	}
+
+	return result
+}
+
+// isRemote reports whether provider is served by a RemoteProvider endpoint.
+func (d *Doctor) isRemote(provider string) bool {
+	switch provider {
+	case "gemini", "openai", "claude":
+		return false
+	}
+	return true
+}
+
+// redactURL strips query parameters from errors that echo the request URL.
+func redactURL(err error, u *url.URL) string {
+	msg := err.Error()
+	if u.RawQuery != "" {
+		msg = strings.ReplaceAll(msg, "?"+u.RawQuery, "")
+	}
+	return msg
+}
+
+// checkDatabase runs SQLite's integrity check on the memory database.
+func (d *Doctor) checkDatabase(cfg *types.Config) Result {
+	result := Result{Name: "memory database"}
+
+	if cfg.Memory == nil || !cfg.Memory.Enabled || !cfg.Memory.SQLite.Enabled {
+		result.Status = StatusSkip
+		result.Message = "memory disabled"
+		return result
+	}
+	if cfg.Memory.SQLite.InMemory {
+		result.Status = StatusOK
+		result.Message = "in-memory database, nothing persisted"
+		return result
+	}
+
+	sqlite := cfg.Memory.SQLite
+	if sqlite.Encryption.Enabled && config.GetEnvValue(sqlite.Encryption.Key) == "" {
+		result.Status = StatusFail
+		result.Message = fmt.Sprintf("encryption enabled but %s is not set", sqlite.Encryption.Key.Env)
+		result.Hint = "Export the encryption key; without it encrypted memories cannot be read"
+		return result
+	}
+
+	path := sqlite.Path
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := checkWritable(filepath.Dir(path)); err != nil {
+			result.Status = StatusFail
+			result.Message = fmt.Sprintf("%s does not exist and cannot be created: %v", path, err)
+			result.Hint = "Create the directory or point memory.sqlite.path somewhere writable"
+			return result
+		}
+		result.Status = StatusOK
+		result.Message = fmt.Sprintf("%s will be created on first start", path)
+		return result
+	}
+
+	db, err := sql.Open("sqlite3", path+"?mode=ro")
+	if err != nil {
+		result.Status = StatusFail
+		result.Message = fmt.Sprintf("cannot open %s: %v", path, err)
+		return result
+	}
+	defer db.Close()
+
+	var check string
+	if err := db.QueryRow("PRAGMA integrity_check").Scan(&check); err != nil {
+		result.Status = StatusFail
+		result.Message = fmt.Sprintf("integrity check failed to run: %v", err)
+		result.Hint = "The file may not be a SQLite database; move it aside and restart to recreate it"
+		return result
+	}
+
+	if check != "ok" {
+		result.Status = StatusFail
+		result.Message = "integrity check reported: " + check
+		result.Hint = "Restore from a backup, or move the file aside to start with empty memory"
+		return result
+	}
+
+	result.Status = StatusOK
+	result.Message = fmt.Sprintf("%s passed integrity check", path)
+	return result
+}
+
+// checkWritable verifies a file can be created in dir.
+func checkWritable(dir string) error {
+	f, err := os.CreateTemp(dir, ".buildbureau-doctor-*")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	f.Close()
+	return os.Remove(name)
+}
+
+// checkDiskSpace reports free space where BuildBureau writes data.
+func (d *Doctor) checkDiskSpace(cfg *types.Config) Result {
+	result := Result{Name: "disk space"}
+
+	dir := "."
+	if cfg.Memory != nil && cfg.Memory.SQLite.Path != "" && !cfg.Memory.SQLite.InMemory {
+		dir = filepath.Dir(cfg.Memory.SQLite.Path)
+	}
+	if _, err := os.Stat(dir); err != nil {
+		dir = "."
+	}
+
+	free, err := freeBytes(dir)
+	if err != nil {
+		result.Status = StatusSkip
+		result.Message = err.Error()
+		return result
+	}
+
+	result.Message = fmt.Sprintf("%.1f GiB free in %s", float64(free)/(1<<30), dir)
+	switch {
+	case free < diskFailBytes:
+		result.Status = StatusFail
+		result.Hint = "Free up disk space; memory writes will start failing"
+	case free < diskWarnBytes:
+		result.Status = StatusWarn
+		result.Hint = "Less than 1 GiB free; consider lowering memory.retention or cleaning old data"
+	default:
+		result.Status = StatusOK
+	}
+
+	return result
+}
+
+// checkSlack verifies the Slack token with auth.test.
+func (d *Doctor) checkSlack(ctx context.Context, cfg *types.Config) Result {
+	result := Result{Name: "slack"}
+
+	if cfg.Slack == nil || !cfg.Slack.Enabled {
+		result.Status = StatusSkip
+		result.Message = "disabled"
+		return result
+	}
+
+	token := config.GetEnvValue(cfg.Slack.Token)
+	if token == "" {
+		result.Status = StatusFail
+		result.Message = fmt.Sprintf("%s is not set", cfg.Slack.Token.Env)
+		result.Hint = "Export a bot token (xoxb-...) or set slack.enabled: false"
+		return result
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.slackURL, nil)
+	if err != nil {
+		result.Status = StatusFail
+		result.Message = err.Error()
+		return result
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		result.Status = StatusFail
+		result.Message = fmt.Sprintf("unreachable: %v", err)
+		result.Hint = "Check network access to slack.com"
+		return result
+	}
+	defer resp.Body.Close()
+
+	var auth struct {
+		Error string `json:"error"`
+		Team  string `json:"team"`
+		User  string `json:"user"`
+		OK    bool   `json:"ok"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&auth); err != nil {
+		result.Status = StatusFail
+		result.Message = fmt.Sprintf("unexpected response: %v", err)
+		return result
+	}
+
+	if !auth.OK {
+		result.Status = StatusFail
+		result.Message = "authentication failed: " + auth.Error
+		result.Hint = "Reinstall the Slack app and update the bot token"
+		return result
+	}
+
+	result.Status = StatusOK
+	result.Message = fmt.Sprintf("authenticated as %s in %s", auth.User, auth.Team)
+	return result
+}
+
+// checkGRPCPort verifies the agent gRPC port is free to listen on.
+func (d *Doctor) checkGRPCPort() Result {
+	result := Result{Name: fmt.Sprintf("grpc port %d", d.grpcPort)}
+
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", d.grpcPort))
+	if err != nil {
+		result.Status = StatusWarn
+		result.Message = fmt.Sprintf("not available: %v", err)
+		result.Hint = fmt.Sprintf("Stop the process using the port (lsof -i :%d) or run agents on another port", d.grpcPort)
+		return result
+	}
+	lis.Close()
+
+	result.Status = StatusOK
+	result.Message = "available"
+	return result
+}
+
+// checkRemoteAgents verifies remote sub-agent endpoints accept connections.
+func (d *Doctor) checkRemoteAgents(cfg *types.Config) []Result {
+	loader := config.NewLoader()
+
+	var results []Result
+	for _, layer := range cfg.Organization.Layers {
+		if layer.Agent == "" {
+			continue
+		}
+
+		agentCfg, err := loader.LoadAgentConfig(layer.Agent)
+		if err != nil {
+			continue // Already reported by checkAgentConfigs
+		}
+
+		for _, sub := range agentCfg.SubAgents {
+			if sub.Remote == nil || sub.Remote.Endpoint == "" {
+				continue
+			}
+
+			result := Result{Name: "remote agent " + sub.Name}
+			conn, err := net.DialTimeout("tcp", sub.Remote.Endpoint, 3*time.Second)
+			if err != nil {
+				result.Status = StatusWarn
+				result.Message = fmt.Sprintf("%s unreachable: %v", sub.Remote.Endpoint, err)
+				result.Hint = "Start the remote agent or fix the endpoint in " + layer.Agent
+			} else {
+				conn.Close()
+				result.Status = StatusOK
+				result.Message = sub.Remote.Endpoint + " reachable"
+			}
+			results = append(results, result)
+		}
+	}
+
+	return results
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package doctor
+
+import "fmt"
+
+// freeBytes is not implemented on this platform.
+func freeBytes(dir string) (uint64, error) {
+	return 0, fmt.Errorf("disk space check not supported on this platform")
+}
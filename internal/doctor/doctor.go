@@ -0,0 +1,188 @@
+// Package doctor diagnoses a BuildBureau installation: configuration, provider
+// credentials, memory database, disk space, Slack and network ports.
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// Status is the outcome of a single check.
+type Status string
+
+const (
+	StatusOK   Status = "ok"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+	StatusSkip Status = "skip"
+)
+
+// DefaultGRPCPort is the port agents listen on by default.
+const DefaultGRPCPort = 50051
+
+// Result is the outcome of one diagnostic check with a remediation hint.
+type Result struct {
+	Name    string
+	Status  Status
+	Message string
+	Hint    string
+}
+
+// Doctor runs environment diagnostics.
+type Doctor struct {
+	client     *http.Client
+	probeURLs  map[string]string
+	configPath string
+	slackURL   string
+	grpcPort   int
+}
+
+// Option configures a Doctor.
+type Option func(*Doctor)
+
+// WithHTTPClient sets the HTTP client used for provider and Slack probes.
+func WithHTTPClient(client *http.Client) Option {
+	return func(d *Doctor) {
+		d.client = client
+	}
+}
+
+// WithGRPCPort sets the gRPC port whose availability is checked.
+func WithGRPCPort(port int) Option {
+	return func(d *Doctor) {
+		d.grpcPort = port
+	}
+}
+
+// WithProbeURL overrides the URL used to verify a provider's API key.
+func WithProbeURL(provider, url string) Option {
+	return func(d *Doctor) {
+		d.probeURLs[provider] = url
+	}
+}
+
+// WithSlackURL overrides the Slack auth.test endpoint.
+func WithSlackURL(url string) Option {
+	return func(d *Doctor) {
+		d.slackURL = url
+	}
+}
+
+// New creates a Doctor for the given configuration file.
+func New(configPath string, opts ...Option) *Doctor {
+	d := &Doctor{
+		configPath: configPath,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		grpcPort:   DefaultGRPCPort,
+		slackURL:   "https://slack.com/api/auth.test",
+		probeURLs: map[string]string{
+			"gemini": "https://generativelanguage.googleapis.com/v1beta/models",
+			"openai": "https://api.openai.com/v1/models",
+			"claude": "https://api.anthropic.com/v1/models",
+			"codex":  os.Getenv("CODEX_ENDPOINT"),
+			"qwen":   os.Getenv("QWEN_ENDPOINT"),
+			"custom": os.Getenv("CUSTOM_LLM_ENDPOINT"),
+		},
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	return d
+}
+
+// Run performs every check. Checks that depend on a readable configuration
+// are skipped if it cannot be parsed.
+func (d *Doctor) Run(ctx context.Context) []Result {
+	cfg, result := d.checkConfig()
+	results := []Result{result}
+	if cfg == nil {
+		return append(results, d.checkGRPCPort())
+	}
+
+	results = append(results, d.checkAgentConfigs(cfg)...)
+	results = append(results, d.checkAPIKeys(ctx, cfg)...)
+	results = append(results, d.checkDatabase(cfg))
+	results = append(results, d.checkDiskSpace(cfg))
+	results = append(results, d.checkSlack(ctx, cfg))
+	results = append(results, d.checkGRPCPort())
+	results = append(results, d.checkRemoteAgents(cfg)...)
+
+	return results
+}
+
+// Report writes results with remediation hints and reports whether no check failed.
+func Report(w io.Writer, results []Result) bool {
+	healthy := true
+
+	for _, r := range results {
+		fmt.Fprintf(w, "[%-4s] %s: %s\n", r.Status, r.Name, r.Message)
+		if r.Hint != "" && r.Status != StatusOK {
+			fmt.Fprintf(w, "       → %s\n", r.Hint)
+		}
+		if r.Status == StatusFail {
+			healthy = false
+		}
+	}
+
+	if healthy {
+		fmt.Fprintln(w, "\nNo blocking problems found.")
+	} else {
+		fmt.Fprintln(w, "\nSome checks failed. Fix the items marked [fail] above.")
+	}
+
+	return healthy
+}
+
+// checkConfig parses the configuration without requiring environment variables,
+// so the remaining checks can report what is missing.
+func (d *Doctor) checkConfig() (*types.Config, Result) {
+	result := Result{Name: "config"}
+
+	data, err := os.ReadFile(d.configPath)
+	if err != nil {
+		result.Status = StatusFail
+		result.Message = fmt.Sprintf("cannot read %s: %v", d.configPath, err)
+		result.Hint = "Run from the repository root or set BUILDBUREAU_CONFIG to your config.yaml"
+		return nil, result
+	}
+
+	var cfg types.Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		result.Status = StatusFail
+		result.Message = fmt.Sprintf("invalid YAML in %s: %v", d.configPath, err)
+		result.Hint = "Compare your file against the config.yaml shipped with BuildBureau"
+		return nil, result
+	}
+
+	switch {
+	case len(cfg.Organization.Layers) == 0:
+		result.Status = StatusFail
+		result.Message = "organization.layers is empty"
+		result.Hint = "Define at least a President layer under organization.layers"
+	case cfg.LLMs.DefaultModel != "" && !hasKeyConfig(&cfg, cfg.LLMs.DefaultModel):
+		result.Status = StatusWarn
+		result.Message = fmt.Sprintf("default_model %q has no entry in llms.api_keys", cfg.LLMs.DefaultModel)
+		result.Hint = fmt.Sprintf("Add llms.api_keys.%s or change llms.default_model", cfg.LLMs.DefaultModel)
+	default:
+		result.Status = StatusOK
+		result.Message = fmt.Sprintf("%s parsed, %d organization layer(s)", d.configPath, len(cfg.Organization.Layers))
+	}
+
+	return &cfg, result
+}
+
+// hasKeyConfig reports whether an API key is configured for a provider.
+func hasKeyConfig(cfg *types.Config, provider string) bool {
+	_, ok := cfg.LLMs.APIKeys[provider]
+	return ok
+}
@@ -0,0 +1,190 @@
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes a config file with a SQLite database under dir.
+func writeConfig(t *testing.T, dir, extra string) string {
+	t.Helper()
+
+	content := `
+organization:
+  layers:
+    - name: President
+
+llms:
+  default_model: openai
+  api_keys:
+    openai: { env: DOCTOR_TEST_OPENAI_KEY }
+    claude: { env: DOCTOR_TEST_CLAUDE_KEY }
+
+memory:
+  enabled: true
+  sqlite:
+    enabled: true
+    path: ` + filepath.Join(dir, "memory.db") + `
+` + extra
+
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// find returns the first result whose name starts with prefix.
+func find(results []Result, prefix string) *Result {
+	for i := range results {
+		if strings.HasPrefix(results[i].Name, prefix) {
+			return &results[i]
+		}
+	}
+	return nil
+}
+
+func TestDoctor(t *testing.T) {
+	t.Run("MissingConfig", func(t *testing.T) {
+		d := New(filepath.Join(t.TempDir(), "missing.yaml"), WithGRPCPort(0))
+		results := d.Run(context.Background())
+
+		if results[0].Status != StatusFail || results[0].Hint == "" {
+			t.Errorf("Expected config failure with hint, got %+v", results[0])
+		}
+
+		var buf bytes.Buffer
+		if Report(&buf, results) {
+			t.Error("Expected report to be unhealthy")
+		}
+		if !strings.Contains(buf.String(), "BUILDBUREAU_CONFIG") {
+			t.Errorf("Expected remediation hint in report, got %q", buf.String())
+		}
+	})
+
+	t.Run("APIKeys", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Header.Get("Authorization") == "Bearer good":
+				w.Header().Set("x-ratelimit-remaining-requests", "42")
+				w.WriteHeader(http.StatusOK)
+			case r.Header.Get("x-api-key") == "limited":
+				w.WriteHeader(http.StatusTooManyRequests)
+			default:
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		}))
+		defer srv.Close()
+
+		t.Setenv("DOCTOR_TEST_OPENAI_KEY", "good")
+		t.Setenv("DOCTOR_TEST_CLAUDE_KEY", "limited")
+
+		d := New(writeConfig(t, t.TempDir(), ""),
+			WithGRPCPort(0),
+			WithProbeURL("openai", srv.URL),
+			WithProbeURL("claude", srv.URL),
+		)
+		results := d.Run(context.Background())
+
+		openai := find(results, "api key openai")
+		if openai == nil || openai.Status != StatusOK || !strings.Contains(openai.Message, "42") {
+			t.Errorf("Expected openai key accepted with quota, got %+v", openai)
+		}
+		claude := find(results, "api key claude")
+		if claude == nil || claude.Status != StatusWarn {
+			t.Errorf("Expected claude key rate limited, got %+v", claude)
+		}
+
+		t.Setenv("DOCTOR_TEST_OPENAI_KEY", "bad")
+		t.Setenv("DOCTOR_TEST_CLAUDE_KEY", "")
+		results = d.Run(context.Background())
+
+		openai = find(results, "api key openai")
+		if openai == nil || openai.Status != StatusFail {
+			t.Errorf("Expected openai key rejected, got %+v", openai)
+		}
+		if r := find(results, "api keys"); r == nil || r.Status != StatusFail {
+			t.Errorf("Expected failure when no key is usable, got %+v", r)
+		}
+	})
+
+	t.Run("Database", func(t *testing.T) {
+		dir := t.TempDir()
+		d := New(writeConfig(t, dir, ""), WithGRPCPort(0))
+
+		if r := find(d.Run(context.Background()), "memory database"); r == nil || r.Status != StatusOK {
+			t.Errorf("Expected missing database to be creatable, got %+v", r)
+		}
+
+		db, err := sql.Open("sqlite3", filepath.Join(dir, "memory.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec("CREATE TABLE t (id INTEGER)"); err != nil {
+			t.Fatal(err)
+		}
+		db.Close()
+
+		r := find(d.Run(context.Background()), "memory database")
+		if r == nil || r.Status != StatusOK || !strings.Contains(r.Message, "integrity") {
+			t.Errorf("Expected integrity check to pass, got %+v", r)
+		}
+
+		if err := os.WriteFile(filepath.Join(dir, "memory.db"), []byte("not a database"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if r := find(d.Run(context.Background()), "memory database"); r == nil || r.Status != StatusFail {
+			t.Errorf("Expected corrupt database to fail, got %+v", r)
+		}
+	})
+
+	t.Run("Slack", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "Bearer xoxb-good" {
+				_, _ = w.Write([]byte(`{"ok":true,"team":"acme","user":"bureau"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"ok":false,"error":"invalid_auth"}`))
+		}))
+		defer srv.Close()
+
+		path := writeConfig(t, t.TempDir(), `
+slack:
+  enabled: true
+  token: { env: DOCTOR_TEST_SLACK_TOKEN }
+`)
+		d := New(path, WithGRPCPort(0), WithSlackURL(srv.URL))
+
+		t.Setenv("DOCTOR_TEST_SLACK_TOKEN", "xoxb-good")
+		if r := find(d.Run(context.Background()), "slack"); r == nil || r.Status != StatusOK {
+			t.Errorf("Expected slack auth to pass, got %+v", r)
+		}
+
+		t.Setenv("DOCTOR_TEST_SLACK_TOKEN", "xoxb-bad")
+		r := find(d.Run(context.Background()), "slack")
+		if r == nil || r.Status != StatusFail || !strings.Contains(r.Message, "invalid_auth") {
+			t.Errorf("Expected slack auth to fail, got %+v", r)
+		}
+	})
+
+	t.Run("GRPCPort", func(t *testing.T) {
+		lis, err := net.Listen("tcp", ":0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer lis.Close()
+
+		d := New("", WithGRPCPort(lis.Addr().(*net.TCPAddr).Port))
+		if r := d.checkGRPCPort(); r.Status != StatusWarn || r.Hint == "" {
+			t.Errorf("Expected port in use warning, got %+v", r)
+		}
+	})
+}
@@ -0,0 +1,176 @@
+// Package document converts documents to plain text for prompts. Client
+// requirements often arrive as PDFs, Word documents or spreadsheets rather
+// than plain text: Word documents keep their headings and tables as Markdown,
+// spreadsheets become one Markdown table per sheet, and PDFs are converted
+// with pdftotext. Limits on pages, rows and characters keep large documents
+// from crowding out the rest of a prompt.
+package document
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Formats of documents.
+const (
+	FormatText     = "text"
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+	FormatPDF      = "pdf"
+	FormatDOCX     = "docx"
+	FormatXLSX     = "xlsx"
+)
+
+// Default limits for documents included in prompts.
+const (
+	DefaultMaxPages = 50
+	DefaultMaxRows  = 200
+	DefaultMaxChars = 50_000
+)
+
+var formats = map[string]string{
+	".txt":      FormatText,
+	".md":       FormatMarkdown,
+	".markdown": FormatMarkdown,
+	".html":     FormatHTML,
+	".htm":      FormatHTML,
+	".pdf":      FormatPDF,
+	".docx":     FormatDOCX,
+	".xlsx":     FormatXLSX,
+}
+
+// Format returns the format of a document by its extension, or "" if it is
+// not supported.
+func Format(path string) string {
+	return formats[strings.ToLower(filepath.Ext(path))]
+}
+
+// Limits bound how much of a document is extracted. Zero fields are unlimited.
+type Limits struct {
+	MaxPages int // PDF pages
+	MaxRows  int // Rows per spreadsheet sheet
+	MaxChars int // Characters of text, cut at a line boundary
+}
+
+// Document is the text extracted from a document.
+type Document struct {
+	Path      string
+	Format    string
+	Text      string
+	Truncated bool // A limit cut the text short
+}
+
+// Extractor extracts the text of documents.
+type Extractor struct {
+	pdftotext string
+	limits    Limits
+}
+
+// Option configures an Extractor.
+type Option func(*Extractor)
+
+// WithLimits bounds how much of each document is extracted.
+func WithLimits(limits Limits) Option {
+	return func(e *Extractor) {
+		e.limits = limits
+	}
+}
+
+// WithPDFToText sets the pdftotext binary PDFs are converted with.
+func WithPDFToText(path string) Option {
+	return func(e *Extractor) {
+		if path != "" {
+			e.pdftotext = path
+		}
+	}
+}
+
+// New creates an Extractor without limits.
+func New(opts ...Option) *Extractor {
+	e := &Extractor{pdftotext: "pdftotext"}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Extract reads a document and returns its text.
+func (e *Extractor) Extract(ctx context.Context, path string) (*Document, error) {
+	doc := &Document{Path: path, Format: Format(path)}
+
+	var err error
+	switch doc.Format {
+	case FormatPDF:
+		doc.Text, doc.Truncated, err = e.pdf(ctx, path)
+	case FormatDOCX:
+		doc.Text, err = docxText(path)
+	case FormatXLSX:
+		doc.Text, doc.Truncated, err = xlsxText(path, e.limits.MaxRows)
+	case FormatHTML, FormatMarkdown, FormatText:
+		var data []byte
+		data, err = os.ReadFile(path)
+		doc.Text = string(data)
+		if doc.Format == FormatHTML {
+			doc.Text = HTMLText(doc.Text)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported document %s: must be one of PDF, DOCX, XLSX, HTML, Markdown or text", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	if text, cut := truncate(doc.Text, e.limits.MaxChars); cut {
+		doc.Text, doc.Truncated = text, true
+	}
+	return doc, nil
+}
+
+// pdf converts a PDF with pdftotext, reading one page past the limit to tell
+// whether the document is longer.
+func (e *Extractor) pdf(ctx context.Context, path string) (string, bool, error) {
+	args := []string{"-layout", "-enc", "UTF-8"}
+	if e.limits.MaxPages > 0 {
+		args = append(args, "-l", fmt.Sprint(e.limits.MaxPages+1))
+	}
+	out, err := exec.CommandContext(ctx, e.pdftotext, append(args, path, "-")...).Output()
+	if err != nil {
+		return "", false, fmt.Errorf("%s failed: %w", e.pdftotext, err)
+	}
+
+	// pdftotext ends every page with a form feed
+	pages := strings.SplitAfter(string(out), "\f")
+	if e.limits.MaxPages > 0 && len(pages) > e.limits.MaxPages && strings.TrimSpace(strings.Join(pages[e.limits.MaxPages:], "")) != "" {
+		return strings.Join(pages[:e.limits.MaxPages], ""), true, nil
+	}
+	return string(out), false, nil
+}
+
+// truncate cuts text to at most limit characters, at the last line break if there is one.
+func truncate(text string, limit int) (string, bool) {
+	runes := []rune(text)
+	if limit <= 0 || len(runes) <= limit {
+		return text, false
+	}
+	cut := string(runes[:limit])
+	if i := strings.LastIndex(cut, "\n"); i > 0 {
+		cut = cut[:i]
+	}
+	return cut, true
+}
+
+// Prompt renders the document for inclusion in a prompt, noting when it was cut short.
+func (d *Document) Prompt() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "=== Attached document: %s ===\n", filepath.Base(d.Path))
+	b.WriteString(strings.TrimSpace(d.Text))
+	if d.Truncated {
+		b.WriteString("\n[Document truncated: only the beginning is included]")
+	}
+	fmt.Fprintf(&b, "\n=== End of %s ===", filepath.Base(d.Path))
+	return b.String()
+}
@@ -0,0 +1,140 @@
+package document
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeZip writes an Office Open XML package with the given parts.
+func writeZip(t *testing.T, path string, parts map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	for name, content := range parts {
+		part, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := part.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+const wordNS = `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"`
+
+func TestExtract(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	t.Run("DOCX", func(t *testing.T) {
+		path := filepath.Join(dir, "requirements.docx")
+		writeZip(t, path, map[string]string{"word/document.xml": `<w:document ` + wordNS + `><w:body>
+<w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:r><w:t>Order API</w:t></w:r></w:p>
+<w:p><w:r><w:t xml:space="preserve">Orders are </w:t></w:r><w:r><w:t>immutable.</w:t></w:r></w:p>
+<w:tbl>
+<w:tr><w:tc><w:p><w:r><w:t>Field</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Type</w:t></w:r></w:p></w:tc></w:tr>
+<w:tr><w:tc><w:p><w:r><w:t>id</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>uuid</w:t></w:r></w:p></w:tc></w:tr>
+</w:tbl>
+</w:body></w:document>`})
+
+		doc, err := New().Extract(ctx, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "# Order API\n\nOrders are immutable.\n\n| Field | Type |\n| --- | --- |\n| id | uuid |"
+		if doc.Text != want || doc.Format != FormatDOCX || doc.Truncated {
+			t.Errorf("Extract() = %q, want %q", doc.Text, want)
+		}
+	})
+
+	t.Run("XLSX", func(t *testing.T) {
+		path := filepath.Join(dir, "pricing.xlsx")
+		writeZip(t, path, map[string]string{
+			"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Plans" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+			"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Target="worksheets/sheet1.xml"/></Relationships>`,
+			"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<si><t>Plan</t></si><si><t>Price</t></si><si><r><t>Free</t></r><r><t> tier</t></r></si></sst>`,
+			"xl/worksheets/sheet1.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row>
+<row r="2"><c r="A2" t="s"><v>2</v></c><c r="B2"><v>0</v></c></row>
+<row r="3"><c r="A3" t="inlineStr"><is><t>Pro</t></is></c><c r="C3"><v>12.5</v></c></row>
+</sheetData></worksheet>`,
+		})
+
+		doc, err := New().Extract(ctx, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "## Sheet: Plans\n\n| Plan | Price |  |\n| --- | --- | --- |\n| Free tier | 0 |  |\n| Pro |  | 12.5 |"
+		if doc.Text != want {
+			t.Errorf("Extract() = %q, want %q", doc.Text, want)
+		}
+
+		limited, err := New(WithLimits(Limits{MaxRows: 2})).Extract(ctx, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !limited.Truncated || strings.Contains(limited.Text, "Pro") || !strings.Contains(limited.Text, "[Only the first 2 rows are included]") {
+			t.Errorf("Expected the sheet to be cut at 2 rows, got %q", limited.Text)
+		}
+	})
+
+	t.Run("MaxChars", func(t *testing.T) {
+		path := filepath.Join(dir, "notes.txt")
+		if err := os.WriteFile(path, []byte("first line\nsecond line\nthird line\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		doc, err := New(WithLimits(Limits{MaxChars: 26})).Extract(ctx, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if doc.Text != "first line\nsecond line" || !doc.Truncated {
+			t.Errorf("Expected the text cut at a line break, got %q", doc.Text)
+		}
+		if prompt := doc.Prompt(); !strings.HasPrefix(prompt, "=== Attached document: notes.txt ===\nfirst line") || !strings.Contains(prompt, "[Document truncated") {
+			t.Errorf("Unexpected prompt: %q", prompt)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		if _, err := New().Extract(ctx, filepath.Join(dir, "slides.pptx")); err == nil {
+			t.Error("Expected an unsupported format to be rejected")
+		}
+		corrupt := filepath.Join(dir, "corrupt.docx")
+		if err := os.WriteFile(corrupt, []byte("not a zip"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := New().Extract(ctx, corrupt); err == nil {
+			t.Error("Expected a corrupt document to be rejected")
+		}
+	})
+}
+
+func TestHTMLText(t *testing.T) {
+	doc := `<html><head><title>x</title><style>p{}</style></head><body>
+<nav>Home | About</nav>
+<h1>Deploy <em>guide</em></h1>
+<p>Run   the <b>deploy</b> script &amp; wait.</p>
+<ul><li>Staging</li><li>Production</li></ul>
+<script>alert(1)</script>
+</body></html>`
+
+	want := "# Deploy guide\n\nRun the deploy script & wait.\n\n- Staging\n- Production"
+	if got := HTMLText(doc); got != want {
+		t.Errorf("HTMLText() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,36 @@
+package document
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	invisibleElement = regexp.MustCompile(`(?is)<(script|style|head|nav|footer)\b.*?</\s*(script|style|head|nav|footer)\s*>|<!--.*?-->`)
+	headingElement   = regexp.MustCompile(`(?is)<h([1-6])\b[^>]*>(.*?)</\s*h[1-6]\s*>`)
+	blockBoundary    = regexp.MustCompile(`(?i)<(/?(p|div|section|article|ul|ol|table|pre|blockquote)|br\s*/?)\b[^>]*>`)
+	listItem         = regexp.MustCompile(`(?i)<(li|tr)\b[^>]*>`)
+	anyTag           = regexp.MustCompile(`(?s)<[^>]*>`)
+	blankLines       = regexp.MustCompile(`\n[ \t]*(\n[ \t]*)+`)
+)
+
+// HTMLText converts an HTML document to plain text, keeping headings as
+// Markdown headings so the text can be split along them. Scripts, styles and
+// navigation are dropped.
+func HTMLText(doc string) string {
+	doc = invisibleElement.ReplaceAllString(doc, "")
+	doc = headingElement.ReplaceAllStringFunc(doc, func(h string) string {
+		m := headingElement.FindStringSubmatch(h)
+		return "\n\n" + strings.Repeat("#", int(m[1][0]-'0')) + " " + strings.TrimSpace(anyTag.ReplaceAllString(m[2], "")) + "\n\n"
+	})
+	doc = blockBoundary.ReplaceAllString(doc, "\n\n")
+	doc = listItem.ReplaceAllString(doc, "\n- ")
+	doc = html.UnescapeString(anyTag.ReplaceAllString(doc, ""))
+
+	var lines []string
+	for line := range strings.Lines(doc) {
+		lines = append(lines, strings.Join(strings.Fields(line), " "))
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
@@ -0,0 +1,360 @@
+package document
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// openPart opens a part of an Office Open XML package.
+func openPart(archive *zip.ReadCloser, name string) (io.ReadCloser, error) {
+	for _, f := range archive.File {
+		if f.Name == name {
+			return f.Open()
+		}
+	}
+	return nil, fmt.Errorf("missing %s", name)
+}
+
+// docxText converts a Word document to text: paragraphs separated by blank
+// lines, headings as Markdown headings and tables as Markdown tables.
+func docxText(file string) (string, error) {
+	archive, err := zip.OpenReader(file)
+	if err != nil {
+		return "", err
+	}
+	defer archive.Close()
+	part, err := openPart(archive, "word/document.xml")
+	if err != nil {
+		return "", err
+	}
+	defer part.Close()
+
+	var (
+		out       strings.Builder
+		paragraph strings.Builder
+		heading   int
+		row       []string
+		rows      [][]string
+		tables    int // Depth of nested tables
+	)
+	dec := xml.NewDecoder(part)
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				paragraph.Reset()
+				heading = 0
+			case "pStyle":
+				// Built-in heading styles are named Heading1 to Heading6
+				if level, err := strconv.Atoi(strings.TrimPrefix(attr(t, "val"), "Heading")); err == nil && level >= 1 && level <= 6 {
+					heading = level
+				}
+			case "t":
+				var text string
+				if err := dec.DecodeElement(&text, &t); err != nil {
+					return "", err
+				}
+				paragraph.WriteString(text)
+			case "tab":
+				paragraph.WriteString("\t")
+			case "br", "cr":
+				paragraph.WriteString("\n")
+			case "tbl":
+				tables++
+				if tables == 1 {
+					rows = nil
+				}
+			case "tr":
+				if tables == 1 {
+					row = nil
+				}
+			case "tc":
+				// Nested tables are flattened into the cell holding them
+				if tables == 1 {
+					row = append(row, "")
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "p":
+				text := strings.TrimSpace(paragraph.String())
+				switch {
+				case tables > 0 && len(row) > 0:
+					row[len(row)-1] = strings.TrimSpace(row[len(row)-1] + " " + text)
+				case text == "":
+				case heading > 0:
+					fmt.Fprintf(&out, "%s %s\n\n", strings.Repeat("#", heading), text)
+				default:
+					out.WriteString(text + "\n\n")
+				}
+			case "tr":
+				if tables == 1 {
+					rows = append(rows, row)
+				}
+			case "tbl":
+				tables--
+				if tables == 0 {
+					out.WriteString(markdownTable(rows) + "\n")
+				}
+			}
+		}
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// xlsxText converts a workbook to one Markdown table per sheet, the first row
+// as the header. Sheets longer than maxRows (if positive) are cut short.
+func xlsxText(file string, maxRows int) (string, bool, error) {
+	archive, err := zip.OpenReader(file)
+	if err != nil {
+		return "", false, err
+	}
+	defer archive.Close()
+
+	shared, err := sharedStrings(archive)
+	if err != nil {
+		return "", false, err
+	}
+	sheets, err := workbookSheets(archive)
+	if err != nil {
+		return "", false, err
+	}
+
+	var (
+		out       strings.Builder
+		truncated bool
+	)
+	for _, s := range sheets {
+		rows, cut, err := sheetRows(archive, s.part, shared, maxRows)
+		if err != nil {
+			return "", false, fmt.Errorf("sheet %s: %w", s.name, err)
+		}
+		if len(rows) == 0 {
+			continue
+		}
+		truncated = truncated || cut
+		fmt.Fprintf(&out, "## Sheet: %s\n\n%s\n", s.name, markdownTable(rows))
+		if cut {
+			fmt.Fprintf(&out, "[Only the first %d rows are included]\n", maxRows)
+		}
+		out.WriteString("\n")
+	}
+	return strings.TrimSpace(out.String()), truncated, nil
+}
+
+// sharedStrings reads the workbook's table of shared cell strings.
+func sharedStrings(archive *zip.ReadCloser) ([]string, error) {
+	part, err := openPart(archive, "xl/sharedStrings.xml")
+	if err != nil {
+		// Workbooks without text cells have none
+		return nil, nil
+	}
+	defer part.Close()
+
+	var table struct {
+		Items []struct {
+			Text string `xml:"t"`
+			Runs []struct {
+				Text string `xml:"t"`
+			} `xml:"r"`
+		} `xml:"si"`
+	}
+	if err := xml.NewDecoder(part).Decode(&table); err != nil {
+		return nil, err
+	}
+
+	strs := make([]string, len(table.Items))
+	for i, item := range table.Items {
+		strs[i] = item.Text
+		for _, r := range item.Runs {
+			strs[i] += r.Text
+		}
+	}
+	return strs, nil
+}
+
+// sheet is a worksheet and the package part holding it.
+type sheet struct {
+	name string
+	part string
+}
+
+// workbookSheets lists the worksheets in workbook order.
+func workbookSheets(archive *zip.ReadCloser) ([]sheet, error) {
+	part, err := openPart(archive, "xl/workbook.xml")
+	if err != nil {
+		return nil, err
+	}
+	var workbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			ID   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	err = xml.NewDecoder(part).Decode(&workbook)
+	part.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	part, err = openPart(archive, "xl/_rels/workbook.xml.rels")
+	if err != nil {
+		return nil, err
+	}
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	err = xml.NewDecoder(part).Decode(&rels)
+	part.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var sheets []sheet
+	for _, s := range workbook.Sheets {
+		for _, r := range rels.Relationships {
+			if r.ID != s.ID {
+				continue
+			}
+			target := strings.TrimPrefix(r.Target, "/")
+			if !strings.HasPrefix(target, "xl/") {
+				target = path.Join("xl", target)
+			}
+			sheets = append(sheets, sheet{name: s.Name, part: target})
+		}
+	}
+	return sheets, nil
+}
+
+// sheetRows reads the cell values of a worksheet, up to maxRows rows if positive.
+func sheetRows(archive *zip.ReadCloser, name string, shared []string, maxRows int) ([][]string, bool, error) {
+	part, err := openPart(archive, name)
+	if err != nil {
+		return nil, false, err
+	}
+	defer part.Close()
+
+	var data struct {
+		Rows []struct {
+			Cells []struct {
+				Ref    string `xml:"r,attr"`
+				Type   string `xml:"t,attr"`
+				Value  string `xml:"v"`
+				Inline string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.NewDecoder(part).Decode(&data); err != nil {
+		return nil, false, err
+	}
+
+	var rows [][]string
+	for _, r := range data.Rows {
+		if maxRows > 0 && len(rows) == maxRows {
+			return rows, true, nil
+		}
+		var row []string
+		for i, c := range r.Cells {
+			col := column(c.Ref)
+			if col < 0 {
+				col = i
+			}
+			for len(row) <= col {
+				row = append(row, "")
+			}
+			switch c.Type {
+			case "s":
+				if n, err := strconv.Atoi(c.Value); err == nil && n >= 0 && n < len(shared) {
+					row[col] = shared[n]
+				}
+			case "inlineStr":
+				row[col] = c.Inline
+			case "b":
+				row[col] = map[string]string{"0": "FALSE", "1": "TRUE"}[c.Value]
+			default:
+				row[col] = c.Value
+			}
+		}
+		if slices.ContainsFunc(row, func(v string) bool { return strings.TrimSpace(v) != "" }) {
+			rows = append(rows, row)
+		}
+	}
+	return rows, false, nil
+}
+
+// column returns the zero-based column of a cell reference such as "C7", or
+// -1 if the reference has no column.
+func column(ref string) int {
+	col := 0
+	n := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+		n++
+	}
+	if n == 0 {
+		return -1
+	}
+	return col - 1
+}
+
+// markdownTable renders rows as a Markdown table whose header is the first row.
+func markdownTable(rows [][]string) string {
+	width := 0
+	for _, r := range rows {
+		width = max(width, len(r))
+	}
+	if width == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	line := func(cells []string) {
+		b.WriteString("|")
+		for i := range width {
+			cell := ""
+			if i < len(cells) {
+				cell = strings.Join(strings.Fields(cells[i]), " ")
+			}
+			b.WriteString(" " + strings.ReplaceAll(cell, "|", `\|`) + " |")
+		}
+		b.WriteString("\n")
+	}
+	line(rows[0])
+	line(slices.Repeat([]string{"---"}, width))
+	for _, r := range rows[1:] {
+		line(r)
+	}
+	return b.String()
+}
+
+// attr returns the value of an attribute by local name.
+func attr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,258 @@
+// Package errors provides structured errors with machine-readable codes and
+// agent/task/project identity, mappable to gRPC status codes and REST bodies.
+package errors
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Code is a machine-readable error code.
+type Code string
+
+const (
+	CodeInternal          Code = "INTERNAL"
+	CodeInvalidArgument   Code = "INVALID_ARGUMENT"
+	CodeNotFound          Code = "NOT_FOUND"
+	CodeCanceled          Code = "CANCELED"
+	CodeAgentTimeout      Code = "AGENT_TIMEOUT"
+	CodeAgentUnavailable  Code = "AGENT_UNAVAILABLE"
+	CodeDelegationFailed  Code = "DELEGATION_FAILED"
+	CodeLLMUnavailable    Code = "LLM_UNAVAILABLE"
+	CodeLLMRateLimit      Code = "LLM_RATE_LIMIT"
+	CodeLLMFailed         Code = "LLM_FAILED"
+	CodeLLMAuth           Code = "LLM_AUTH" // The provider rejected the API key
+	CodeMemoryUnavailable Code = "MEMORY_UNAVAILABLE"
+	CodeToolDenied        Code = "TOOL_DENIED"
+	CodeOverloaded        Code = "OVERLOADED" // Admission control rejected new work
+)
+
+// Sentinel errors for use with errors.Is; matching compares codes only.
+var (
+	ErrAgentTimeout      = New(CodeAgentTimeout, "agent timed out")
+	ErrAgentUnavailable  = New(CodeAgentUnavailable, "agent unavailable")
+	ErrDelegationFailed  = New(CodeDelegationFailed, "delegation failed")
+	ErrLLMUnavailable    = New(CodeLLMUnavailable, "llm unavailable")
+	ErrLLMRateLimit      = New(CodeLLMRateLimit, "llm rate limit exceeded")
+	ErrMemoryUnavailable = New(CodeMemoryUnavailable, "memory unavailable")
+	ErrToolDenied        = New(CodeToolDenied, "tool denied")
+)
+
+// Error is a structured error carrying a code and the identity of where it happened.
+type Error struct {
+	Err       error
+	Code      Code
+	Message   string
+	AgentID   string
+	TaskID    string
+	ProjectID string
+}
+
+// New creates a structured error.
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Newf creates a structured error with a formatted message.
+func Newf(code Code, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Wrap annotates err with a code and message.
+func Wrap(err error, code Code, message string) *Error {
+	return &Error{Err: err, Code: code, Message: message}
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+	return e.Message + ": " + e.Err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is a structured error with the same code.
+func (e *Error) Is(target error) bool {
+	var t *Error
+	if !stderrors.As(target, &t) {
+		return false
+	}
+	return t.Code == e.Code
+}
+
+// WithAgent returns a copy of the error with an agent ID attached, so
+// shared sentinel errors are never modified.
+func (e *Error) WithAgent(agentID string) *Error {
+	c := *e
+	c.AgentID = agentID
+	return &c
+}
+
+// WithTask returns a copy of the error with a task ID attached, so
+// shared sentinel errors are never modified.
+func (e *Error) WithTask(taskID string) *Error {
+	c := *e
+	c.TaskID = taskID
+	return &c
+}
+
+// WithProject returns a copy of the error with a project ID attached, so
+// shared sentinel errors are never modified.
+func (e *Error) WithProject(projectID string) *Error {
+	c := *e
+	c.ProjectID = projectID
+	return &c
+}
+
+// Is reports whether any error in err's chain matches target.
+func Is(err, target error) bool {
+	return stderrors.Is(err, target)
+}
+
+// As finds the first error in err's chain that matches target.
+func As(err error, target any) bool {
+	return stderrors.As(err, target)
+}
+
+// CodeOf returns the most specific code in err's chain: the code of the
+// innermost structured error, or a code derived from context errors.
+func CodeOf(err error) Code {
+	if err == nil {
+		return ""
+	}
+
+	code := CodeInternal
+	for cur := err; cur != nil; cur = stderrors.Unwrap(cur) {
+		if e, ok := cur.(*Error); ok { //nolint:errorlint // Walking the chain manually to find the innermost code
+			code = e.Code
+		}
+	}
+
+	if code == CodeInternal {
+		switch {
+		case stderrors.Is(err, context.DeadlineExceeded):
+			return CodeAgentTimeout
+		case stderrors.Is(err, context.Canceled):
+			return CodeCanceled
+		}
+	}
+
+	return code
+}
+
+// FromContext converts a context error into a structured error, or returns nil.
+func FromContext(ctx context.Context) *Error {
+	switch {
+	case ctx.Err() == nil:
+		return nil
+	case stderrors.Is(ctx.Err(), context.DeadlineExceeded):
+		return Wrap(ctx.Err(), CodeAgentTimeout, "task deadline exceeded")
+	default:
+		return Wrap(ctx.Err(), CodeCanceled, "task canceled")
+	}
+}
+
+// GRPCCode maps an error code to a gRPC status code.
+func GRPCCode(code Code) codes.Code {
+	switch code {
+	case CodeInvalidArgument:
+		return codes.InvalidArgument
+	case CodeNotFound:
+		return codes.NotFound
+	case CodeCanceled:
+		return codes.Canceled
+	case CodeAgentTimeout:
+		return codes.DeadlineExceeded
+	case CodeAgentUnavailable, CodeLLMUnavailable, CodeMemoryUnavailable:
+		return codes.Unavailable
+	case CodeLLMRateLimit, CodeOverloaded:
+		return codes.ResourceExhausted
+	case CodeToolDenied:
+		return codes.PermissionDenied
+	case CodeLLMAuth:
+		return codes.FailedPrecondition
+	case CodeDelegationFailed, CodeLLMFailed:
+		return codes.Aborted
+	case CodeInternal:
+		return codes.Internal
+	default:
+		return codes.Unknown
+	}
+}
+
+// HTTPStatus maps an error code to an HTTP status code.
+func HTTPStatus(code Code) int {
+	switch code {
+	case CodeInvalidArgument:
+		return http.StatusBadRequest
+	case CodeNotFound:
+		return http.StatusNotFound
+	case CodeCanceled:
+		return 499 // Client closed request
+	case CodeAgentTimeout:
+		return http.StatusGatewayTimeout
+	case CodeAgentUnavailable, CodeLLMUnavailable, CodeMemoryUnavailable:
+		return http.StatusServiceUnavailable
+	case CodeLLMRateLimit, CodeOverloaded:
+		return http.StatusTooManyRequests
+	case CodeToolDenied:
+		return http.StatusForbidden
+	case CodeDelegationFailed, CodeLLMFailed, CodeLLMAuth:
+		return http.StatusBadGateway
+	case CodeInternal:
+		return http.StatusInternalServerError
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// ToGRPC converts err into a gRPC status error carrying its code.
+func ToGRPC(err error) error {
+	if err == nil {
+		return nil
+	}
+	return status.Error(GRPCCode(CodeOf(err)), err.Error())
+}
+
+// Body is the JSON error body returned by REST endpoints.
+type Body struct {
+	Code      Code   `json:"code"`
+	Message   string `json:"message"`
+	AgentID   string `json:"agent_id,omitempty"`
+	TaskID    string `json:"task_id,omitempty"`
+	ProjectID string `json:"project_id,omitempty"`
+}
+
+// ToBody converts err into a REST error body, using the innermost identity available.
+func ToBody(err error) Body {
+	body := Body{
+		Code:    CodeOf(err),
+		Message: err.Error(),
+	}
+
+	for cur := err; cur != nil; cur = stderrors.Unwrap(cur) {
+		if e, ok := cur.(*Error); ok { //nolint:errorlint // Walking the chain manually to collect identity
+			if e.AgentID != "" {
+				body.AgentID = e.AgentID
+			}
+			if e.TaskID != "" {
+				body.TaskID = e.TaskID
+			}
+			if e.ProjectID != "" {
+				body.ProjectID = e.ProjectID
+			}
+		}
+	}
+
+	return body
+}
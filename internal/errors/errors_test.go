@@ -0,0 +1,104 @@
+package errors
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestError_IsAndCodeOf(t *testing.T) {
+	inner := New(CodeLLMRateLimit, "quota exceeded").WithAgent("engineer-1")
+	outer := Wrap(inner, CodeDelegationFailed, "failed to delegate to engineer").WithAgent("manager-1").WithTask("task-1")
+	wrapped := fmt.Errorf("client request failed: %w", outer)
+
+	if !Is(wrapped, ErrDelegationFailed) {
+		t.Error("Expected error chain to match ErrDelegationFailed")
+	}
+
+	if !Is(wrapped, ErrLLMRateLimit) {
+		t.Error("Expected error chain to match ErrLLMRateLimit")
+	}
+
+	if Is(wrapped, ErrToolDenied) {
+		t.Error("Expected error chain not to match ErrToolDenied")
+	}
+
+	if code := CodeOf(wrapped); code != CodeLLMRateLimit {
+		t.Errorf("Expected innermost code %s, got %s", CodeLLMRateLimit, code)
+	}
+
+	if outer.Error() != "failed to delegate to engineer: quota exceeded" {
+		t.Errorf("Unexpected error message: %s", outer.Error())
+	}
+}
+
+func TestCodeOf_ContextErrors(t *testing.T) {
+	if code := CodeOf(fmt.Errorf("llm call: %w", context.DeadlineExceeded)); code != CodeAgentTimeout {
+		t.Errorf("Expected %s, got %s", CodeAgentTimeout, code)
+	}
+
+	if code := CodeOf(context.Canceled); code != CodeCanceled {
+		t.Errorf("Expected %s, got %s", CodeCanceled, code)
+	}
+
+	if code := CodeOf(fmt.Errorf("plain")); code != CodeInternal {
+		t.Errorf("Expected %s, got %s", CodeInternal, code)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if FromContext(ctx) != nil {
+		t.Error("Expected nil error for live context")
+	}
+	cancel()
+	if err := FromContext(ctx); err == nil || err.Code != CodeCanceled {
+		t.Errorf("Expected canceled error, got %v", err)
+	}
+}
+
+func TestToGRPCAndBody(t *testing.T) {
+	err := Wrap(New(CodeAgentTimeout, "deadline"), CodeDelegationFailed, "delegation").WithAgent("director-1").WithTask("task-9")
+
+	st, ok := status.FromError(ToGRPC(err))
+	if !ok {
+		t.Fatal("Expected a gRPC status error")
+	}
+	if st.Code() != codes.DeadlineExceeded {
+		t.Errorf("Expected DeadlineExceeded, got %s", st.Code())
+	}
+
+	body := ToBody(err)
+	if body.Code != CodeAgentTimeout || body.AgentID != "director-1" || body.TaskID != "task-9" {
+		t.Errorf("Unexpected body: %+v", body)
+	}
+
+	if HTTPStatus(body.Code) != http.StatusGatewayTimeout {
+		t.Errorf("Expected 504, got %d", HTTPStatus(body.Code))
+	}
+}
+
+func TestWithCopies(t *testing.T) {
+	err := ErrToolDenied.WithAgent("engineer-1").WithTask("task-3").WithProject("run-7")
+
+	if ErrToolDenied.AgentID != "" || ErrToolDenied.TaskID != "" || ErrToolDenied.ProjectID != "" {
+		t.Errorf("Expected the sentinel left untouched, got %+v", ErrToolDenied)
+	}
+	if !Is(err, ErrToolDenied) {
+		t.Error("Expected the copy to match its sentinel")
+	}
+
+	body := ToBody(err)
+	if body.AgentID != "engineer-1" || body.TaskID != "task-3" || body.ProjectID != "run-7" {
+		t.Errorf("Unexpected body: %+v", body)
+	}
+
+	if st, _ := status.FromError(ToGRPC(err)); st.Code() != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied, got %s", st.Code())
+	}
+	if HTTPStatus(CodeToolDenied) != http.StatusForbidden {
+		t.Errorf("Expected 403, got %d", HTTPStatus(CodeToolDenied))
+	}
+}
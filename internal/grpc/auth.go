@@ -0,0 +1,40 @@
+package grpc
+
+import (
+	"context"
+	"crypto/subtle"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// MetadataAuthorization is the metadata key carrying "Bearer <token>" on
+// servers that require a token.
+const MetadataAuthorization = "authorization"
+
+// tokenServerInterceptor rejects calls that do not carry the bearer token.
+func tokenServerInterceptor(token string) grpc.UnaryServerInterceptor {
+	want := []byte("Bearer " + token)
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		var got string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(MetadataAuthorization); len(values) > 0 {
+				got = values[0]
+			}
+		}
+		if subtle.ConstantTimeCompare([]byte(got), want) != 1 {
+			return nil, status.Error(codes.Unauthenticated, "invalid or missing bearer token")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// tokenClientInterceptor sends the bearer token with each request.
+func tokenClientInterceptor(token string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx = metadata.AppendToOutgoingContext(ctx, MetadataAuthorization, "Bearer "+token)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
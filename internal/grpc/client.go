@@ -13,15 +13,26 @@ import (
 
 // Client represents a gRPC client for communicating with other agents.
 type Client struct {
-	conn     *grpc.ClientConn
-	endpoint string
+	conn        *grpc.ClientConn
+	endpoint    string
+	org         string // Organization the calls address, on servers hosting several
+	token       string // Bearer token sent with each call, if set
+	dialOpts    []grpc.DialOption
+	dialTimeout time.Duration
 }
 
 // NewClient creates a new gRPC client.
-func NewClient(endpoint string) *Client {
-	return &Client{
-		endpoint: endpoint,
+func NewClient(endpoint string, opts ...ClientOption) *Client {
+	c := &Client{
+		endpoint:    endpoint,
+		dialTimeout: 10 * time.Second,
 	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
 }
 
 // connect establishes a connection to the remote agent.
@@ -31,17 +42,25 @@ func (c *Client) connect(ctx context.Context) error {
 	}
 
 	// Create context with timeout
-	dialCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	dialCtx, cancel := context.WithTimeout(ctx, c.dialTimeout)
 	defer cancel()
 
-	// Dial the gRPC server
-	//nolint:staticcheck // grpc.DialContext will be replaced with grpc.NewClient in a future update
-	conn, err := grpc.DialContext(
-		dialCtx,
-		c.endpoint,
+	// Insecure transport by default; callers may override via WithDialOptions
+	dialOpts := append([]grpc.DialOption{
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
 		grpc.WithBlock(),
-	)
+		grpc.WithChainUnaryInterceptor(versionClientInterceptor),
+	}, c.dialOpts...)
+	if c.org != "" {
+		dialOpts = append(dialOpts, grpc.WithChainUnaryInterceptor(organizationClientInterceptor(c.org)))
+	}
+	if c.token != "" {
+		dialOpts = append(dialOpts, grpc.WithChainUnaryInterceptor(tokenClientInterceptor(c.token)))
+	}
+
+	// Dial the gRPC server
+	//nolint:staticcheck // grpc.DialContext will be replaced with grpc.NewClient in a future update
+	conn, err := grpc.DialContext(dialCtx, c.endpoint, dialOpts...)
 	if err != nil {
 		return fmt.Errorf("failed to connect to %s: %w", c.endpoint, err)
 	}
@@ -94,6 +113,23 @@ func (c *Client) GetStatus(ctx context.Context, agentID string) (string, int, in
 	return response.Status, int(response.ActiveTasks), int(response.CompletedTasks), nil
 }
 
+// DescribeAgent retrieves the role, model, capabilities, load and version of
+// a remote agent via gRPC.
+func (c *Client) DescribeAgent(ctx context.Context, agentID string) (types.AgentDescription, error) {
+	// Ensure connection
+	if err := c.connect(ctx); err != nil {
+		return types.AgentDescription{}, err
+	}
+
+	client := protocol.NewAgentServiceClient(c.conn)
+	response, err := client.DescribeAgent(ctx, &protocol.DescribeAgentRequest{AgentId: agentID})
+	if err != nil {
+		return types.AgentDescription{}, fmt.Errorf("failed to describe agent: %w", err)
+	}
+
+	return protoToDescription(response), nil
+}
+
 // Notify sends a notification to a remote agent via gRPC.
 func (c *Client) Notify(ctx context.Context, from, to, notificationType, message string) error {
 	// Ensure connection
@@ -7,14 +7,24 @@ import (
 
 // taskToProto converts types.Task to protocol.TaskRequest.
 func taskToProto(task *types.Task) *protocol.TaskRequest {
+	metadata := task.Metadata
+	if task.RunID != "" {
+		// The run ID travels as metadata since the proto has no dedicated field
+		metadata = make(map[string]string, len(task.Metadata)+1)
+		for k, v := range task.Metadata {
+			metadata[k] = v
+		}
+		metadata[types.MetadataRunID] = task.RunID
+	}
+
 	return &protocol.TaskRequest{
 		Id:          task.ID,
 		Title:       task.Title,
 		Description: task.Description,
 		FromAgent:   task.FromAgent,
 		ToAgent:     task.ToAgent,
-		Metadata:    task.Metadata,
-		Content:     task.Description, // Use description as content
+		Metadata:    metadata,
+		Content:     task.Content,
 		Priority:    int32(task.Priority),
 	}
 }
@@ -31,6 +41,8 @@ func protoToTaskResponse(resp *protocol.TaskResponse) *types.TaskResponse {
 		status = types.StatusCompleted
 	case "failed":
 		status = types.StatusFailed
+	case "partially_completed":
+		status = types.StatusPartiallyCompleted
 	}
 
 	return &types.TaskResponse{
@@ -54,6 +66,8 @@ func taskResponseToProto(resp *types.TaskResponse) *protocol.TaskResponse {
 		statusStr = "completed"
 	case types.StatusFailed:
 		statusStr = "failed"
+	case types.StatusPartiallyCompleted:
+		statusStr = "partially_completed"
 	default:
 		statusStr = "completed"
 	}
@@ -66,3 +80,33 @@ func taskResponseToProto(resp *types.TaskResponse) *protocol.TaskResponse {
 		Error:    resp.Error,
 	}
 }
+
+// descriptionToProto converts types.AgentDescription to protocol.AgentDescription.
+func descriptionToProto(d types.AgentDescription) *protocol.AgentDescription {
+	return &protocol.AgentDescription{
+		AgentId:        d.ID,
+		Role:           string(d.Role),
+		Model:          d.Model,
+		Team:           d.Team,
+		Capabilities:   d.Capabilities,
+		Status:         d.Status,
+		ActiveTasks:    int32(d.ActiveTasks),
+		CompletedTasks: int32(d.CompletedTasks),
+		Version:        d.Version,
+	}
+}
+
+// protoToDescription converts protocol.AgentDescription to types.AgentDescription.
+func protoToDescription(d *protocol.AgentDescription) types.AgentDescription {
+	return types.AgentDescription{
+		ID:             d.AgentId,
+		Role:           types.AgentRole(d.Role),
+		Model:          d.Model,
+		Team:           d.Team,
+		Capabilities:   d.Capabilities,
+		Status:         d.Status,
+		ActiveTasks:    int(d.ActiveTasks),
+		CompletedTasks: int(d.CompletedTasks),
+		Version:        d.Version,
+	}
+}
@@ -0,0 +1,247 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// Broadcast addresses a notification to every subscriber of its type.
+	Broadcast = "*"
+
+	defaultDeliveryAttempts = 3
+	defaultDeliveryBackoff  = 100 * time.Millisecond
+	defaultRemoteTimeout    = 5 * time.Second
+	defaultInboxSize        = 64
+	maxDeadLetters          = 100
+)
+
+// Notification is a message addressed to an agent.
+type Notification struct {
+	Time     time.Time         `json:"time"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	From     string            `json:"from"`
+	To       string            `json:"to"` // Agent ID, or Broadcast
+	Type     string            `json:"type"`
+	Message  string            `json:"message"`
+}
+
+// DeadLetter is a notification that could not be delivered.
+type DeadLetter struct {
+	Time         time.Time    `json:"time"`
+	Notification Notification `json:"notification"`
+	Recipient    string       `json:"recipient"`
+	Reason       string       `json:"reason"`
+	Attempts     int          `json:"attempts"`
+}
+
+// RemoteNotifier delivers notifications to an agent in another process.
+// Client implements it.
+type RemoteNotifier interface {
+	Notify(ctx context.Context, from, to, notificationType, message string) error
+}
+
+// subscription is a local agent's inbox for selected notification types.
+type subscription struct {
+	inbox   chan Notification
+	agentID string
+	types   []string // Empty means every type
+}
+
+// accepts reports whether the subscription wants notifications of the type.
+func (s *subscription) accepts(notificationType string) bool {
+	return len(s.types) == 0 || slices.Contains(s.types, notificationType)
+}
+
+// Router routes notifications to the inboxes of local agents that subscribed
+// to their type and to remote agents over gRPC. Failed deliveries are retried
+// with exponential backoff; notifications that still cannot be delivered, or
+// that are addressed to an unknown agent, become dead letters.
+type Router struct {
+	remotes      map[string]RemoteNotifier
+	onDeadLetter func(DeadLetter)
+	subs         []*subscription
+	deadLetters  []DeadLetter
+	attempts     int
+	backoff      time.Duration
+	inboxSize    int
+	mu           sync.RWMutex
+}
+
+// NewRouter creates a notification router.
+func NewRouter(opts ...RouterOption) *Router {
+	r := &Router{
+		remotes:   make(map[string]RemoteNotifier),
+		attempts:  defaultDeliveryAttempts,
+		backoff:   defaultDeliveryBackoff,
+		inboxSize: defaultInboxSize,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Subscribe opens an inbox for a local agent that receives notifications of
+// the given types, or of every type if none are given. The returned function
+// closes the inbox.
+func (r *Router) Subscribe(agentID string, notificationTypes ...string) (<-chan Notification, func()) {
+	sub := &subscription{
+		inbox:   make(chan Notification, r.inboxSize),
+		agentID: agentID,
+		types:   notificationTypes,
+	}
+
+	r.mu.Lock()
+	r.subs = append(r.subs, sub)
+	r.mu.Unlock()
+
+	var once sync.Once
+	return sub.inbox, func() {
+		once.Do(func() {
+			r.mu.Lock()
+			r.subs = slices.DeleteFunc(r.subs, func(s *subscription) bool { return s == sub })
+			r.mu.Unlock()
+			close(sub.inbox)
+		})
+	}
+}
+
+// AddRemote routes notifications addressed to agentID through notifier.
+func (r *Router) AddRemote(agentID string, notifier RemoteNotifier) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.remotes[agentID] = notifier
+}
+
+// Dispatch routes a notification in the background, so callers such as the
+// Notify RPC can acknowledge it without waiting for retries.
+func (r *Router) Dispatch(n Notification) {
+	go r.Route(context.Background(), n)
+}
+
+// Route delivers a notification to every matching local inbox and remote
+// agent, retrying failed deliveries. It returns the number of recipients the
+// notification was delivered to; undeliverable copies become dead letters.
+func (r *Router) Route(ctx context.Context, n Notification) int {
+	if n.Time.IsZero() {
+		n.Time = time.Now()
+	}
+
+	r.mu.RLock()
+	var subs []*subscription
+	addressed := false
+	for _, s := range r.subs {
+		if n.To == Broadcast || s.agentID == n.To {
+			addressed = true
+			if s.accepts(n.Type) {
+				subs = append(subs, s)
+			}
+		}
+	}
+	remotes := make(map[string]RemoteNotifier)
+	for id, notifier := range r.remotes {
+		if n.To == Broadcast || id == n.To {
+			remotes[id] = notifier
+		}
+	}
+	r.mu.RUnlock()
+
+	// Agents that subscribed to other types only have opted out of this one
+	if !addressed && len(remotes) == 0 && n.To != Broadcast {
+		r.deadLetter(n, n.To, "no route to agent", 0)
+		return 0
+	}
+
+	delivered := 0
+	for _, s := range subs {
+		if r.deliver(ctx, n, s.agentID, func(context.Context) error { return r.send(s, n) }) {
+			delivered++
+		}
+	}
+	for id, notifier := range remotes {
+		send := func(ctx context.Context) error {
+			ctx, cancel := context.WithTimeout(ctx, defaultRemoteTimeout)
+			defer cancel()
+			return notifier.Notify(ctx, n.From, id, n.Type, n.Message)
+		}
+		if r.deliver(ctx, n, id, send) {
+			delivered++
+		}
+	}
+
+	return delivered
+}
+
+// DeadLetters returns the most recent notifications that could not be delivered, oldest first.
+func (r *Router) DeadLetters() []DeadLetter {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.deadLetters)
+}
+
+// deliver calls send until it succeeds or the attempts are used up, doubling
+// the wait between attempts, and reports whether it succeeded.
+func (r *Router) deliver(ctx context.Context, n Notification, recipient string, send func(context.Context) error) bool {
+	backoff := r.backoff
+	var err error
+	for attempt := 1; attempt <= r.attempts; attempt++ {
+		if err = send(ctx); err == nil {
+			return true
+		}
+		if attempt == r.attempts {
+			break
+		}
+
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-ctx.Done():
+			r.deadLetter(n, recipient, ctx.Err().Error(), attempt)
+			return false
+		}
+	}
+
+	r.deadLetter(n, recipient, err.Error(), r.attempts)
+	return false
+}
+
+// deadLetter records an undeliverable notification, keeping the most recent ones.
+func (r *Router) deadLetter(n Notification, recipient, reason string, attempts int) {
+	letter := DeadLetter{Time: time.Now(), Notification: n, Recipient: recipient, Reason: reason, Attempts: attempts}
+
+	r.mu.Lock()
+	r.deadLetters = append(r.deadLetters, letter)
+	if len(r.deadLetters) > maxDeadLetters {
+		r.deadLetters = r.deadLetters[len(r.deadLetters)-maxDeadLetters:]
+	}
+	handler := r.onDeadLetter
+	r.mu.Unlock()
+
+	if handler != nil {
+		handler(letter)
+	}
+}
+
+// send puts a notification in a subscriber's inbox without blocking.
+func (r *Router) send(s *subscription, n Notification) error {
+	// Holding the lock keeps the inbox from being closed while sending
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if !slices.Contains(r.subs, s) {
+		return fmt.Errorf("%s unsubscribed", s.agentID)
+	}
+
+	select {
+	case s.inbox <- n:
+		return nil
+	default:
+		return fmt.Errorf("inbox of %s is full", s.agentID)
+	}
+}
@@ -0,0 +1,130 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kpango/BuildBureau/internal/agent"
+	"github.com/kpango/BuildBureau/pkg/protocol"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// flakyNotifier fails the first failures deliveries and records the rest.
+type flakyNotifier struct {
+	received []string
+	failures int
+	mu       sync.Mutex
+}
+
+func (f *flakyNotifier) Notify(ctx context.Context, from, to, notificationType, message string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failures > 0 {
+		f.failures--
+		return fmt.Errorf("connection refused")
+	}
+	f.received = append(f.received, to+": "+message)
+	return nil
+}
+
+func TestRouter(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Subscriptions", func(t *testing.T) {
+		router := NewRouter()
+		all, closeAll := router.Subscribe("engineer-1")
+		defer closeAll()
+		errorsOnly, closeErrors := router.Subscribe("engineer-2", "error")
+		defer closeErrors()
+
+		if n := router.Route(ctx, Notification{To: "engineer-1", Type: "task_assigned", Message: "hi"}); n != 1 {
+			t.Errorf("expected 1 delivery, got %d", n)
+		}
+		if got := <-all; got.Message != "hi" || got.Time.IsZero() {
+			t.Errorf("unexpected notification %+v", got)
+		}
+
+		// engineer-2 opted out of this type, which is not a delivery failure
+		if n := router.Route(ctx, Notification{To: "engineer-2", Type: "task_assigned"}); n != 0 || len(router.DeadLetters()) != 0 {
+			t.Errorf("expected the notification to be filtered, got %d deliveries and %v", n, router.DeadLetters())
+		}
+
+		if n := router.Route(ctx, Notification{To: Broadcast, Type: "error", Message: "down"}); n != 2 {
+			t.Errorf("expected broadcast to reach 2 inboxes, got %d", n)
+		}
+		<-all
+		if got := <-errorsOnly; got.Message != "down" {
+			t.Errorf("unexpected notification %+v", got)
+		}
+	})
+
+	t.Run("RetriesRemote", func(t *testing.T) {
+		router := NewRouter(WithRetry(3, time.Millisecond))
+		remote := &flakyNotifier{failures: 2}
+		router.AddRemote("manager-9", remote)
+
+		if n := router.Route(ctx, Notification{To: "manager-9", Type: "task_completed", Message: "done"}); n != 1 {
+			t.Errorf("expected delivery after retries, got %d", n)
+		}
+		if len(remote.received) != 1 || remote.received[0] != "manager-9: done" {
+			t.Errorf("unexpected remote deliveries %v", remote.received)
+		}
+	})
+
+	t.Run("DeadLetters", func(t *testing.T) {
+		var handled []DeadLetter
+		router := NewRouter(WithRetry(2, time.Millisecond), WithInboxSize(1), WithDeadLetterHandler(func(d DeadLetter) {
+			handled = append(handled, d)
+		}))
+		router.AddRemote("manager-9", &flakyNotifier{failures: 5})
+		_, unsubscribe := router.Subscribe("engineer-1")
+		defer unsubscribe()
+
+		router.Route(ctx, Notification{To: "nobody", Type: "error"})
+		router.Route(ctx, Notification{To: "manager-9", Type: "error"})
+		router.Route(ctx, Notification{To: "engineer-1", Type: "error", Message: "first"})
+		router.Route(ctx, Notification{To: "engineer-1", Type: "error", Message: "second"}) // Inbox is full
+
+		letters := router.DeadLetters()
+		if len(letters) != 3 || len(handled) != 3 {
+			t.Fatalf("expected 3 dead letters, got %+v", letters)
+		}
+		if letters[0].Reason != "no route to agent" || letters[1].Attempts != 2 || letters[2].Notification.Message != "second" {
+			t.Errorf("unexpected dead letters %+v", letters)
+		}
+	})
+}
+
+func TestServer_NotifyRoutes(t *testing.T) {
+	server := NewServer(agent.NewEngineerAgent("engineer-1", &types.AgentConfig{}, nil), 0)
+	inbox, unsubscribe := server.Router().Subscribe("engineer-1", "task_assigned")
+	defer unsubscribe()
+
+	resp, err := server.Notify(context.Background(), &protocol.NotificationRequest{
+		FromAgent:        "manager-1",
+		ToAgent:          "engineer-1",
+		NotificationType: "task_assigned",
+		Message:          "implement the parser",
+		Metadata:         map[string]string{"task_id": "t1"},
+	})
+	if err != nil || !resp.Acknowledged {
+		t.Fatalf("notification not acknowledged: %v %v", resp, err)
+	}
+
+	select {
+	case n := <-inbox:
+		if n.From != "manager-1" || n.Message != "implement the parser" || n.Metadata["task_id"] != "t1" {
+			t.Errorf("unexpected notification %+v", n)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("notification was not delivered")
+	}
+
+	resp, err = server.Notify(context.Background(), &protocol.NotificationRequest{FromAgent: "manager-1"})
+	if err != nil || resp.Acknowledged || resp.Error == "" {
+		t.Errorf("expected incomplete notification to be rejected, got %v %v", resp, err)
+	}
+}
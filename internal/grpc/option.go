@@ -0,0 +1,114 @@
+package grpc
+
+import (
+	"time"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+	"google.golang.org/grpc"
+)
+
+// ServerOption configures a Server at construction time.
+type ServerOption func(*Server)
+
+// WithGRPCServerOptions passes additional options to the underlying gRPC server.
+func WithGRPCServerOptions(opts ...grpc.ServerOption) ServerOption {
+	return func(s *Server) {
+		s.grpcOpts = append(s.grpcOpts, opts...)
+	}
+}
+
+// WithRouter routes the notifications the server receives through router,
+// which may be shared with other servers in the process.
+func WithRouter(router *Router) ServerOption {
+	return func(s *Server) {
+		s.router = router
+	}
+}
+
+// WithToken requires calls to carry "Bearer <token>" in MetadataAuthorization.
+func WithToken(token string) ServerOption {
+	return func(s *Server) {
+		s.token = token
+	}
+}
+
+// WithOrganization serves the agent of another organization hosted by the
+// process to calls naming it in MetadataOrganization. Its notifications go
+// through router, or through a router of its own if router is nil, so they
+// never reach the agents of another organization.
+func WithOrganization(id string, agent types.Agent, router *Router) ServerOption {
+	return func(s *Server) {
+		if s.orgs == nil {
+			s.orgs = make(map[string]hostedAgent)
+		}
+		if router == nil {
+			router = NewRouter()
+		}
+		s.orgs[id] = hostedAgent{agent: agent, router: router}
+	}
+}
+
+// ClientOption configures a Client at construction time.
+type ClientOption func(*Client)
+
+// WithDialTimeout sets how long the client waits to establish a connection.
+func WithDialTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		c.dialTimeout = timeout
+	}
+}
+
+// WithOrganizationID addresses the client's calls to the organization with
+// the ID, on servers hosting several.
+func WithOrganizationID(id string) ClientOption {
+	return func(c *Client) {
+		c.org = id
+	}
+}
+
+// WithBearerToken sends the token with each call, for servers created WithToken.
+func WithBearerToken(token string) ClientOption {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithDialOptions passes additional dial options, e.g. transport credentials.
+func WithDialOptions(opts ...grpc.DialOption) ClientOption {
+	return func(c *Client) {
+		c.dialOpts = append(c.dialOpts, opts...)
+	}
+}
+
+// RouterOption configures a Router at construction time.
+type RouterOption func(*Router)
+
+// WithRetry sets how many times delivery of a notification is attempted and
+// the wait before the first retry, which doubles on each further retry.
+func WithRetry(attempts int, backoff time.Duration) RouterOption {
+	return func(r *Router) {
+		if attempts > 0 {
+			r.attempts = attempts
+		}
+		if backoff > 0 {
+			r.backoff = backoff
+		}
+	}
+}
+
+// WithInboxSize sets how many undelivered notifications an inbox holds
+// before further deliveries to it are retried.
+func WithInboxSize(size int) RouterOption {
+	return func(r *Router) {
+		if size > 0 {
+			r.inboxSize = size
+		}
+	}
+}
+
+// WithDeadLetterHandler calls handler for every notification that could not be delivered.
+func WithDeadLetterHandler(handler func(DeadLetter)) RouterOption {
+	return func(r *Router) {
+		r.onDeadLetter = handler
+	}
+}
@@ -0,0 +1,52 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// MetadataOrganization is the metadata key carrying the ID of the hosted
+// organization a call addresses. Calls without it address the server's own
+// agent.
+const MetadataOrganization = "buildbureau-org"
+
+// hostedAgent is the agent serving an organization hosted by the server,
+// with the router of that organization's notifications.
+type hostedAgent struct {
+	agent  types.Agent
+	router *Router
+}
+
+// target returns the agent a call addresses and the router its
+// notifications go through: those of the hosted organization named in the
+// call's metadata, or the server's own.
+func (s *Server) target(ctx context.Context) (types.Agent, *Router, error) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(MetadataOrganization); len(ids) > 0 && ids[0] != "" {
+			hosted, ok := s.orgs[ids[0]]
+			if !ok {
+				return nil, nil, status.Errorf(codes.NotFound, "organization %s not found", ids[0])
+			}
+			return hosted.agent, hosted.router, nil
+		}
+	}
+
+	if s.agent == nil {
+		return nil, nil, status.Error(codes.Internal, "agent not initialized")
+	}
+	return s.agent, s.router, nil
+}
+
+// organizationClientInterceptor sends the ID of the organization the client
+// addresses with each request.
+func organizationClientInterceptor(id string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx = metadata.AppendToOutgoingContext(ctx, MetadataOrganization, id)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
@@ -4,11 +4,15 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"time"
 
+	"github.com/kpango/BuildBureau/internal/errors"
+	"github.com/kpango/BuildBureau/internal/version"
 	"github.com/kpango/BuildBureau/pkg/protocol"
 	"github.com/kpango/BuildBureau/pkg/types"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 )
 
@@ -16,18 +20,31 @@ import (
 type Server struct {
 	protocol.UnimplementedAgentServiceServer
 	agent      types.Agent
+	router     *Router
+	orgs       map[string]hostedAgent // Agents of hosted organizations by organization ID
 	listener   net.Listener
 	grpcServer *grpc.Server
+	grpcOpts   []grpc.ServerOption
+	token      string // Bearer token calls must carry, if set
 	port       int
 	running    bool
 }
 
 // NewServer creates a new gRPC server for an agent.
-func NewServer(agent types.Agent, port int) *Server {
-	return &Server{
+func NewServer(agent types.Agent, port int, opts ...ServerOption) *Server {
+	s := &Server{
 		agent: agent,
 		port:  port,
 	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.router == nil {
+		s.router = NewRouter()
+	}
+
+	return s
 }
 
 // Start starts the gRPC server.
@@ -44,7 +61,11 @@ func (s *Server) Start(ctx context.Context) error {
 	s.listener = lis
 
 	// Create gRPC server
-	s.grpcServer = grpc.NewServer()
+	interceptors := []grpc.UnaryServerInterceptor{versionServerInterceptor}
+	if s.token != "" {
+		interceptors = append(interceptors, tokenServerInterceptor(s.token))
+	}
+	s.grpcServer = grpc.NewServer(append([]grpc.ServerOption{grpc.ChainUnaryInterceptor(interceptors...)}, s.grpcOpts...)...)
 
 	// Register the gRPC service with generated proto code
 	protocol.RegisterAgentServiceServer(s.grpcServer, s)
@@ -80,8 +101,9 @@ func (s *Server) Stop(ctx context.Context) error {
 
 // ProcessTask handles an incoming task request (gRPC RPC handler).
 func (s *Server) ProcessTask(ctx context.Context, req *protocol.TaskRequest) (*protocol.TaskResponse, error) {
-	if s.agent == nil {
-		return nil, status.Error(codes.Internal, "agent not initialized")
+	agent, _, err := s.target(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	// Convert proto request to types.Task
@@ -91,14 +113,25 @@ func (s *Server) ProcessTask(ctx context.Context, req *protocol.TaskRequest) (*p
 		Description: req.Description,
 		FromAgent:   req.FromAgent,
 		ToAgent:     req.ToAgent,
+		Content:     req.Content,
 		Priority:    int(req.Priority),
 		Metadata:    req.Metadata,
+		RunID:       req.Metadata[types.MetadataRunID],
+	}
+
+	// Attribute the task to the address it came from, for per-client limits
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		host := p.Addr.String()
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		ctx = types.WithClient(ctx, host)
 	}
 
 	// Process the task
-	resp, err := s.agent.ProcessTask(ctx, task)
+	resp, err := agent.ProcessTask(ctx, task)
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, errors.ToGRPC(err)
 	}
 
 	// Convert response to proto
@@ -107,11 +140,12 @@ func (s *Server) ProcessTask(ctx context.Context, req *protocol.TaskRequest) (*p
 
 // GetStatus returns the current status of the agent (gRPC RPC handler).
 func (s *Server) GetStatus(ctx context.Context, req *protocol.StatusRequest) (*protocol.StatusResponse, error) {
-	if s.agent == nil {
-		return nil, status.Error(codes.Internal, "agent not initialized")
+	agent, _, err := s.target(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	if s.agent.GetID() != req.AgentId {
+	if agent.GetID() != req.AgentId {
 		return nil, status.Error(codes.NotFound, "agent ID mismatch")
 	}
 
@@ -121,7 +155,7 @@ func (s *Server) GetStatus(ctx context.Context, req *protocol.StatusRequest) (*p
 	completedTasks := int32(0)
 
 	// If agent has GetStats method, use it
-	if baseAgent, ok := s.agent.(interface {
+	if baseAgent, ok := agent.(interface {
 		GetStats() (int, int)
 	}); ok {
 		active, completed := baseAgent.GetStats()
@@ -129,6 +163,18 @@ func (s *Server) GetStatus(ctx context.Context, req *protocol.StatusRequest) (*p
 		completedTasks = int32(completed)
 	}
 
+	// Report degraded service while any memory backend is unreachable
+	if reporter, ok := agent.(interface {
+		GetMemoryHealth() []types.StoreHealth
+	}); ok {
+		for _, health := range reporter.GetMemoryHealth() {
+			if !health.Healthy {
+				statusStr = "degraded"
+				break
+			}
+		}
+	}
+
 	return &protocol.StatusResponse{
 		AgentId:        req.AgentId,
 		Status:         statusStr,
@@ -137,20 +183,75 @@ func (s *Server) GetStatus(ctx context.Context, req *protocol.StatusRequest) (*p
 	}, nil
 }
 
-// Notify handles notification requests (gRPC RPC handler).
+// DescribeAgent reports the agent's role, model, capabilities, load and
+// version (gRPC RPC handler).
+func (s *Server) DescribeAgent(ctx context.Context, req *protocol.DescribeAgentRequest) (*protocol.AgentDescription, error) {
+	agent, _, err := s.target(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if agent.GetID() != req.AgentId {
+		return nil, status.Error(codes.NotFound, "agent ID mismatch")
+	}
+
+	if describer, ok := agent.(interface {
+		Describe() types.AgentDescription
+	}); ok {
+		return descriptionToProto(describer.Describe()), nil
+	}
+
+	// Agents that cannot describe themselves report what every agent can
+	d := types.AgentDescription{ID: agent.GetID(), Role: agent.GetRole(), Status: "running", Version: version.Version}
+	if baseAgent, ok := agent.(interface {
+		GetStats() (int, int)
+	}); ok {
+		d.ActiveTasks, d.CompletedTasks = baseAgent.GetStats()
+	}
+	return descriptionToProto(d), nil
+}
+
+// Notify accepts a notification for delivery through the server's router
+// (gRPC RPC handler). Delivery happens in the background; notifications that
+// cannot be delivered are recorded as dead letters.
 func (s *Server) Notify(ctx context.Context, req *protocol.NotificationRequest) (*protocol.NotificationResponse, error) {
-	if s.agent == nil {
-		return nil, status.Error(codes.Internal, "agent not initialized")
+	_, router, err := s.target(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	// Log notification (in a real implementation, this might trigger actual processing)
-	fmt.Printf("Notification from %s to %s: [%s] %s\n", req.FromAgent, req.ToAgent, req.NotificationType, req.Message)
+	if req.ToAgent == "" || req.NotificationType == "" {
+		return &protocol.NotificationResponse{Error: "to_agent and notification_type are required"}, nil
+	}
+
+	router.Dispatch(Notification{
+		Time:     time.Now(),
+		Metadata: req.Metadata,
+		From:     req.FromAgent,
+		To:       req.ToAgent,
+		Type:     req.NotificationType,
+		Message:  req.Message,
+	})
 
 	return &protocol.NotificationResponse{
 		Acknowledged: true,
 	}, nil
 }
 
+// Addr returns the address the server listens on, or nil before it starts.
+// With port 0 the system picks a free port, which Addr reports.
+func (s *Server) Addr() net.Addr {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Router returns the router that delivers the notifications the server receives.
+func (s *Server) Router() *Router {
+	return s.router
+}
+
 // IsRunning returns whether the server is running.
 func (s *Server) IsRunning() bool {
 	return s.running
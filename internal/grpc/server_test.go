@@ -6,8 +6,13 @@ import (
 	"time"
 
 	"github.com/kpango/BuildBureau/internal/agent"
+	"github.com/kpango/BuildBureau/internal/version"
 	"github.com/kpango/BuildBureau/pkg/protocol"
 	"github.com/kpango/BuildBureau/pkg/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 const statusCompleted = "completed"
@@ -141,6 +146,33 @@ func TestServer_GetStatus(t *testing.T) {
 	_ = statusResp.CompletedTasks
 }
 
+func TestServer_DescribeAgent(t *testing.T) {
+	config := &types.AgentConfig{Name: "TestAgent", Model: "claude", Capabilities: []string{"go", "sql"}}
+	testAgent := agent.NewEngineerAgent("test-agent", config, nil)
+	ctx := context.Background()
+	if err := testAgent.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewServer(testAgent, 0)
+	resp, err := server.DescribeAgent(ctx, &protocol.DescribeAgentRequest{AgentId: "test-agent"})
+	if err != nil {
+		t.Fatalf("Failed to describe agent: %v", err)
+	}
+
+	d := protoToDescription(resp)
+	if d.ID != "test-agent" || d.Role != types.RoleEngineer || d.Model != "claude" || d.Status != "running" || d.Version != version.Version {
+		t.Errorf("Unexpected description: %+v", d)
+	}
+	if len(d.Capabilities) != 2 || d.Capabilities[0] != "go" {
+		t.Errorf("Expected the configured capabilities, got %v", d.Capabilities)
+	}
+
+	if _, err := server.DescribeAgent(ctx, &protocol.DescribeAgentRequest{AgentId: "wrong-agent-id"}); err == nil {
+		t.Error("Expected error for wrong agent ID, got nil")
+	}
+}
+
 func TestServer_Notify(t *testing.T) {
 	// Create a test agent
 	config := &types.AgentConfig{
@@ -170,3 +202,162 @@ func TestServer_Notify(t *testing.T) {
 		t.Error("Expected notification to be acknowledged")
 	}
 }
+
+func TestVersionMetadata(t *testing.T) {
+	testAgent := agent.NewEngineerAgent("test-agent", &types.AgentConfig{Name: "TestAgent", Role: "test"}, nil)
+	ctx := context.Background()
+
+	server := NewServer(testAgent, 0)
+	if err := server.Start(ctx); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop(ctx)
+
+	t.Run("ServerHeader", func(t *testing.T) {
+		client := NewClient(server.Addr().String())
+		defer client.Close()
+		if err := client.connect(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		var header metadata.MD
+		_, err := protocol.NewAgentServiceClient(client.conn).GetStatus(ctx, &protocol.StatusRequest{AgentId: "test-agent"}, grpc.Header(&header))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := header.Get(MetadataVersion); len(got) != 1 || got[0] != version.Version {
+			t.Errorf("Expected version header %q, got %v", version.Version, got)
+		}
+	})
+
+	t.Run("ClientMetadata", func(t *testing.T) {
+		var sent []string
+		invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			md, _ := metadata.FromOutgoingContext(ctx)
+			sent = md.Get(MetadataVersion)
+			return nil
+		}
+		if err := versionClientInterceptor(ctx, "/test", nil, nil, nil, invoker); err != nil {
+			t.Fatal(err)
+		}
+		if len(sent) != 1 || sent[0] != version.Version {
+			t.Errorf("Expected version metadata %q, got %v", version.Version, sent)
+		}
+	})
+}
+
+func TestOrganizations(t *testing.T) {
+	own := agent.NewEngineerAgent("engineer-1", &types.AgentConfig{Name: "Own", Role: "test"}, nil)
+	hosted := agent.NewEngineerAgent("engineer-1", &types.AgentConfig{Name: "Hosted", Role: "test", Model: "claude"}, nil)
+	router := NewRouter()
+	server := NewServer(own, 0, WithOrganization("acme", hosted, router))
+	ctx := context.Background()
+	if err := server.Start(ctx); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop(ctx)
+
+	describe := func(opts ...ClientOption) (types.AgentDescription, error) {
+		client := NewClient(server.Addr().String(), opts...)
+		defer client.Close()
+		if err := client.connect(ctx); err != nil {
+			return types.AgentDescription{}, err
+		}
+		resp, err := protocol.NewAgentServiceClient(client.conn).DescribeAgent(ctx, &protocol.DescribeAgentRequest{AgentId: "engineer-1"})
+		if err != nil {
+			return types.AgentDescription{}, err
+		}
+		return protoToDescription(resp), nil
+	}
+
+	t.Run("AddressedByID", func(t *testing.T) {
+		d, err := describe(WithOrganizationID("acme"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.Model != "claude" {
+			t.Errorf("Expected the hosted organization's agent, got %+v", d)
+		}
+		if d, err := describe(); err != nil || d.Model == "claude" {
+			t.Errorf("Expected calls without an ID to address the server's own agent, got %+v, %v", d, err)
+		}
+	})
+
+	t.Run("UnknownOrganization", func(t *testing.T) {
+		if _, err := describe(WithOrganizationID("globex")); status.Code(err) != codes.NotFound {
+			t.Errorf("Expected NotFound for an unknown organization, got %v", err)
+		}
+	})
+
+	t.Run("IsolatedNotifications", func(t *testing.T) {
+		inbox, unsubscribe := router.Subscribe("engineer-1")
+		defer unsubscribe()
+		md := metadata.NewIncomingContext(ctx, metadata.Pairs(MetadataOrganization, "acme"))
+		if _, err := server.Notify(md, &protocol.NotificationRequest{FromAgent: "manager-1", ToAgent: "engineer-1", NotificationType: "task_completed"}); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case n := <-inbox:
+			if n.From != "manager-1" {
+				t.Errorf("Unexpected notification: %+v", n)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Expected the notification on the hosted organization's router")
+		}
+		if server.Router() == router {
+			t.Error("Expected the server's own router to be separate")
+		}
+	})
+}
+
+// recordingAgent completes every task, recording it.
+type recordingAgent struct {
+	tasks []*types.Task
+}
+
+func (a *recordingAgent) GetID() string                   { return "president-1" }
+func (a *recordingAgent) GetRole() types.AgentRole        { return types.RolePresident }
+func (a *recordingAgent) Start(ctx context.Context) error { return nil }
+func (a *recordingAgent) Stop(ctx context.Context) error  { return nil }
+
+func (a *recordingAgent) ProcessTask(ctx context.Context, task *types.Task) (*types.TaskResponse, error) {
+	a.tasks = append(a.tasks, task)
+	return &types.TaskResponse{TaskID: task.ID, Status: types.StatusCompleted, Result: "done"}, nil
+}
+
+func TestHostedProjects(t *testing.T) {
+	own, acme := &recordingAgent{}, &recordingAgent{}
+	server := NewServer(own, 0, WithOrganization("acme", acme, nil), WithToken("secret"))
+	ctx := context.Background()
+	if err := server.Start(ctx); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop(ctx)
+
+	submit := func(opts ...ClientOption) (*types.TaskResponse, error) {
+		client := NewClient(server.Addr().String(), opts...)
+		defer client.Close()
+		return client.ProcessTask(ctx, &types.Task{ID: "task-1", Content: "Build a todo app", ToAgent: "president-1"})
+	}
+
+	t.Run("AddressedByID", func(t *testing.T) {
+		resp, err := submit(WithOrganizationID("acme"), WithBearerToken("secret"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Result != "done" || len(acme.tasks) != 1 || acme.tasks[0].Content != "Build a todo app" || len(own.tasks) != 0 {
+			t.Errorf("Expected only the addressed organization to receive the project, got acme=%d own=%d", len(acme.tasks), len(own.tasks))
+		}
+	})
+
+	t.Run("RequiresToken", func(t *testing.T) {
+		for _, opts := range [][]ClientOption{{WithOrganizationID("acme")}, {WithOrganizationID("acme"), WithBearerToken("wrong")}} {
+			if _, err := submit(opts...); status.Code(err) != codes.Unauthenticated {
+				t.Errorf("Expected Unauthenticated, got %v", err)
+			}
+		}
+		if len(acme.tasks) != 1 {
+			t.Errorf("Expected rejected calls not to reach the organization, got %d tasks", len(acme.tasks))
+		}
+	})
+}
@@ -0,0 +1,26 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/kpango/BuildBureau/internal/version"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// MetadataVersion is the metadata key carrying the BuildBureau version of
+// the other side of a call: clients send it with each request and servers
+// return it as a response header, so mismatched deployments can be told apart.
+const MetadataVersion = "buildbureau-version"
+
+// versionServerInterceptor returns the server's version as a response header.
+func versionServerInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	_ = grpc.SetHeader(ctx, metadata.Pairs(MetadataVersion, version.Version))
+	return handler(ctx, req)
+}
+
+// versionClientInterceptor sends the client's version with each request.
+func versionClientInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	ctx = metadata.AppendToOutgoingContext(ctx, MetadataVersion, version.Version)
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
@@ -0,0 +1,190 @@
+// Package host runs several independent organizations in one process, each
+// with its own configuration, providers, budgets, memory store and activity,
+// addressed by an organization ID.
+package host
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"maps"
+	"net"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/kpango/BuildBureau/internal/agent"
+	"github.com/kpango/BuildBureau/internal/config"
+	"github.com/kpango/BuildBureau/internal/errors"
+	"github.com/kpango/BuildBureau/internal/state"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// DefaultID addresses the organization configured by the main configuration file.
+const DefaultID = "default"
+
+// validID matches organization IDs, which appear in API paths.
+var validID = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// LoadConfigs loads the main configuration at path and the configurations of
+// the organizations it hosts, keyed by organization ID. The main one is
+// DefaultID. Hosted configuration paths are relative to the main file. The
+// files each organization keeps are placed in its state directories, if it
+// enables them.
+func LoadConfigs(path string) (map[string]*types.Config, error) {
+	loader := config.NewLoader()
+	main, err := loader.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := state.Apply(main); err != nil {
+		return nil, err
+	}
+	configs := map[string]*types.Config{DefaultID: main}
+	if main.Hosting == nil {
+		return configs, nil
+	}
+	for _, hosted := range main.Hosting.Organizations {
+		if !validID.MatchString(hosted.ID) {
+			return nil, fmt.Errorf("invalid organization ID %q: use lowercase letters, digits, - and _", hosted.ID)
+		}
+		if _, ok := configs[hosted.ID]; ok {
+			return nil, fmt.Errorf("organization %s is configured twice", hosted.ID)
+		}
+		file := hosted.Config
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(filepath.Dir(path), file)
+		}
+		cfg, err := loader.Load(file)
+		if err != nil {
+			return nil, fmt.Errorf("organization %s: %w", hosted.ID, err)
+		}
+		if _, err := state.Apply(cfg); err != nil {
+			return nil, fmt.Errorf("organization %s: %w", hosted.ID, err)
+		}
+		configs[hosted.ID] = cfg
+	}
+
+	if err := checkIsolation(configs); err != nil {
+		return nil, err
+	}
+	return configs, nil
+}
+
+// checkIsolation fails if two organizations would share a memory store, so
+// one organization can never recall another's memories.
+func checkIsolation(configs map[string]*types.Config) error {
+	owners := make(map[string]string)
+	claim := func(store, id string) error {
+		if owner, ok := owners[store]; ok {
+			return fmt.Errorf("organizations %s and %s share the memory store %s", owner, id, store)
+		}
+		owners[store] = id
+		return nil
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(configs)) {
+		memory := configs[id].Memory
+		if memory == nil || !memory.Enabled {
+			continue
+		}
+		if sqlite := memory.SQLite; sqlite.Enabled && !sqlite.InMemory && sqlite.Path != "" {
+			path, err := filepath.Abs(sqlite.Path)
+			if err != nil {
+				return err
+			}
+			if err := claim("sqlite:"+path, id); err != nil {
+				return err
+			}
+		}
+		if vald := memory.Vald; vald.Enabled {
+			if err := claim("vald:"+net.JoinHostPort(vald.Host, strconv.Itoa(vald.Port)), id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Host runs several organizations.
+type Host struct {
+	orgs map[string]*agent.Organization
+	ids  []string // Sorted, the order organizations are started in
+}
+
+// New creates an organization for every configuration, keyed by ID.
+func New(configs map[string]*types.Config) (*Host, error) {
+	h := &Host{orgs: make(map[string]*agent.Organization, len(configs))}
+	for _, id := range slices.Sorted(maps.Keys(configs)) {
+		org, err := agent.NewOrganization(agent.RestrictForDemo(configs[id]))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create organization %s: %w", id, err)
+		}
+		h.orgs[id] = org
+		h.ids = append(h.ids, id)
+	}
+	return h, nil
+}
+
+// Start starts every organization. If one fails to start, those already
+// started are stopped again.
+func (h *Host) Start(ctx context.Context) error {
+	for i, id := range h.ids {
+		if err := h.orgs[id].Start(ctx); err != nil {
+			for _, started := range slices.Backward(h.ids[:i]) {
+				_ = h.orgs[started].Stop(ctx)
+			}
+			return fmt.Errorf("failed to start organization %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// Stop stops every organization, returning their errors joined.
+func (h *Host) Stop(ctx context.Context) error {
+	var errs []error
+	for _, id := range slices.Backward(h.ids) {
+		if err := h.orgs[id].Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("organization %s: %w", id, err))
+		}
+	}
+	return stderrors.Join(errs...)
+}
+
+// Drain stops every organization admitting projects and waits for their
+// running projects to finish, or for ctx to end.
+func (h *Host) Drain(ctx context.Context) error {
+	var (
+		errs []error
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+	)
+	for _, id := range h.ids {
+		wg.Go(func() {
+			if err := h.orgs[id].Drain(ctx); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("organization %s: %w", id, err))
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+	return stderrors.Join(errs...)
+}
+
+// Organization returns the organization with the ID.
+func (h *Host) Organization(id string) (*agent.Organization, error) {
+	org, ok := h.orgs[id]
+	if !ok {
+		return nil, errors.Newf(errors.CodeNotFound, "organization %s not found", id)
+	}
+	return org, nil
+}
+
+// IDs returns the IDs of the organizations, sorted.
+func (h *Host) IDs() []string {
+	return slices.Clone(h.ids)
+}
@@ -0,0 +1,108 @@
+package host
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kpango/BuildBureau/internal/testsupport"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// writeConfig writes a configuration file to dir.
+func writeConfig(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// llms configures the provider every test configuration uses.
+const llms = "llms:\n  api_keys:\n    gemini: { env: GEMINI_API_KEY }\n"
+
+func TestLoadConfigs(t *testing.T) {
+	t.Setenv("GEMINI_API_KEY", "test-key")
+
+	t.Run("Hosted", func(t *testing.T) {
+		dir := t.TempDir()
+		writeConfig(t, dir, "acme.yaml", llms+"  default_model: claude\n")
+		path := writeConfig(t, dir, "config.yaml", llms+"  default_model: gemini\nhosting:\n  organizations:\n    - { id: acme, config: acme.yaml }\n")
+
+		configs, err := LoadConfigs(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(configs) != 2 || configs[DefaultID].LLMs.DefaultModel != "gemini" || configs["acme"].LLMs.DefaultModel != "claude" {
+			t.Errorf("Expected the main and hosted configurations, got %+v", configs)
+		}
+	})
+
+	t.Run("InvalidID", func(t *testing.T) {
+		dir := t.TempDir()
+		path := writeConfig(t, dir, "config.yaml", llms+"hosting:\n  organizations:\n    - { id: Acme/1, config: acme.yaml }\n")
+		if _, err := LoadConfigs(path); err == nil || !strings.Contains(err.Error(), "invalid organization ID") {
+			t.Errorf("Expected an invalid ID error, got %v", err)
+		}
+	})
+
+	t.Run("SharedMemoryStore", func(t *testing.T) {
+		dir := t.TempDir()
+		memory := llms + "memory:\n  enabled: true\n  sqlite: { enabled: true, path: ./data/memory.db }\n"
+		writeConfig(t, dir, "acme.yaml", memory)
+		path := writeConfig(t, dir, "config.yaml", memory+"hosting:\n  organizations:\n    - { id: acme, config: acme.yaml }\n")
+		if _, err := LoadConfigs(path); err == nil || !strings.Contains(err.Error(), "share the memory store") {
+			t.Errorf("Expected organizations sharing a memory store to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("StateDirectories", func(t *testing.T) {
+		dir := t.TempDir()
+		memory := llms + "memory:\n  enabled: true\n  sqlite: { enabled: true, path: ./data/memory.db }\n"
+		writeConfig(t, dir, "acme.yaml", memory+"state: { enabled: true, work_dir: "+filepath.Join(dir, "acme")+" }\n")
+		path := writeConfig(t, dir, "config.yaml", memory+"state: { enabled: true, work_dir: "+filepath.Join(dir, "main")+" }\nhosting:\n  organizations:\n    - { id: acme, config: acme.yaml }\n")
+
+		configs, err := LoadConfigs(path)
+		if err != nil {
+			t.Fatalf("Expected organizations with their own state directories to share nothing, got %v", err)
+		}
+		if got, want := configs["acme"].Memory.SQLite.Path, filepath.Join(dir, "acme", "data", "memory.db"); got != want {
+			t.Errorf("Expected the memory store in the state directory %s, got %s", want, got)
+		}
+	})
+}
+
+func TestHost(t *testing.T) {
+	shape := testsupport.Shape{Engineers: 1, Managers: 1, Directors: 1}
+	h, err := New(map[string]*types.Config{
+		DefaultID: testsupport.Config(t, shape),
+		"acme":    testsupport.Config(t, shape),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if err := h.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer h.Stop(ctx)
+
+	if ids := h.IDs(); len(ids) != 2 || ids[0] != "acme" || ids[1] != DefaultID {
+		t.Errorf("Expected both organizations sorted by ID, got %v", ids)
+	}
+	acme, err := h.Organization("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	own, _ := h.Organization(DefaultID)
+	if acme == own {
+		t.Error("Expected every organization to be separate")
+	}
+	if _, err := h.Organization("globex"); err == nil {
+		t.Error("Expected an error for an unknown organization")
+	}
+}
@@ -0,0 +1,231 @@
+// Package imagegen generates images for design deliverables, such as UI
+// mockups, logos and architecture diagrams, with DALL·E or Imagen. Each image
+// is written under a directory per run, so the project report can link to it,
+// and returned as a file artifact of the deliverable.
+package imagegen
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+	"google.golang.org/genai"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// Backends.
+const (
+	BackendOpenAI = "openai"
+	BackendImagen = "imagen"
+)
+
+const (
+	defaultOpenAIModel = openai.CreateImageModelDallE3
+	defaultImagenModel = "imagen-3.0-generate-002"
+	defaultSize        = openai.CreateImageSize1024x1024
+	defaultAspectRatio = "1:1"
+	defaultDir         = "images"
+
+	// DefaultMaxPerTask is how many images a task may request by default.
+	DefaultMaxPerTask = 4
+)
+
+// extensions are the file extensions images may be requested with.
+var extensions = []string{".png", ".jpg", ".jpeg", ".webp"}
+
+// Image is a generated image.
+type Image struct {
+	Data      []byte
+	MediaType string // e.g. image/png
+}
+
+// Backend generates an image from a description.
+type Backend interface {
+	Generate(ctx context.Context, prompt string) (*Image, error)
+	Model() string
+}
+
+// Generator generates the images requested by tasks and stores them.
+type Generator struct {
+	backend    Backend
+	dir        string
+	maxPerTask int
+}
+
+// Option configures a Generator.
+type Option func(*Generator)
+
+// WithBackend replaces the backend images are generated with.
+func WithBackend(backend Backend) Option {
+	return func(g *Generator) {
+		g.backend = backend
+	}
+}
+
+// New creates a generator for an images configuration, authenticating with
+// apiKey. A nil or disabled configuration returns nil, which generates nothing.
+func New(config *types.ImagesConfig, apiKey string, opts ...Option) (*Generator, error) {
+	if config == nil || !config.Enabled {
+		return nil, nil
+	}
+
+	g := &Generator{
+		dir:        cmp.Or(config.Dir, defaultDir),
+		maxPerTask: cmp.Or(max(config.MaxPerTask, 0), DefaultMaxPerTask),
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	if g.backend != nil {
+		return g, nil
+	}
+
+	if apiKey == "" {
+		return nil, errors.New("image generation needs an API key")
+	}
+	switch backend := cmp.Or(config.Backend, BackendOpenAI); backend {
+	case BackendOpenAI:
+		g.backend = NewOpenAI(openai.NewClient(apiKey), config.Model, config.Size)
+	case BackendImagen:
+		client, err := genai.NewClient(context.Background(), &genai.ClientConfig{APIKey: apiKey, Backend: genai.BackendGeminiAPI})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Imagen client: %w", err)
+		}
+		g.backend = NewImagen(client, config.Model, config.AspectRatio)
+	default:
+		return nil, fmt.Errorf("unknown image backend %q: must be %s or %s", backend, BackendOpenAI, BackendImagen)
+	}
+	return g, nil
+}
+
+// MaxPerTask returns how many images a task may request; further requests are
+// not generated.
+func (g *Generator) MaxPerTask() int {
+	return g.maxPerTask
+}
+
+// Model returns the model images are generated with.
+func (g *Generator) Model() string {
+	return g.backend.Model()
+}
+
+// Generate generates the image described by prompt and writes it to path
+// under the directory of the run. It returns the image as a file artifact,
+// its content base64-encoded, and the path it was written to.
+func (g *Generator) Generate(ctx context.Context, runID, path, prompt string) (types.FileArtifact, string, error) {
+	path = filepath.ToSlash(filepath.Clean(path))
+	if !filepath.IsLocal(path) {
+		return types.FileArtifact{}, "", fmt.Errorf("refusing to write image outside the deliverable: %s", path)
+	}
+	if !slices.Contains(extensions, strings.ToLower(filepath.Ext(path))) {
+		return types.FileArtifact{}, "", fmt.Errorf("unsupported image %s: must be one of %s", path, strings.Join(extensions, ", "))
+	}
+
+	image, err := g.backend.Generate(ctx, prompt)
+	if err != nil {
+		return types.FileArtifact{}, "", fmt.Errorf("failed to generate %s: %w", path, err)
+	}
+
+	stored := filepath.Join(g.dir, cmp.Or(runID, "unassigned"), filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(stored), 0o755); err != nil {
+		return types.FileArtifact{}, "", fmt.Errorf("failed to store %s: %w", path, err)
+	}
+	if err := os.WriteFile(stored, image.Data, 0o644); err != nil {
+		return types.FileArtifact{}, "", fmt.Errorf("failed to store %s: %w", path, err)
+	}
+
+	return types.FileArtifact{
+		Path:      path,
+		Content:   base64.StdEncoding.EncodeToString(image.Data),
+		Bytes:     len(image.Data),
+		MediaType: cmp.Or(image.MediaType, http.DetectContentType(image.Data)),
+	}, stored, nil
+}
+
+// OpenAI generates images with DALL·E or GPT Image.
+type OpenAI struct {
+	client *openai.Client
+	model  string
+	size   string
+}
+
+// NewOpenAI creates an OpenAI backend. Empty model and size default to
+// dall-e-3 and 1024x1024.
+func NewOpenAI(client *openai.Client, model, size string) *OpenAI {
+	return &OpenAI{client: client, model: cmp.Or(model, defaultOpenAIModel), size: cmp.Or(size, defaultSize)}
+}
+
+// Generate implements Backend.
+func (b *OpenAI) Generate(ctx context.Context, prompt string) (*Image, error) {
+	req := openai.ImageRequest{Prompt: prompt, Model: b.model, N: 1, Size: b.size}
+	// GPT Image always returns base64 and rejects the format parameter
+	if strings.HasPrefix(b.model, "dall-e") {
+		req.ResponseFormat = openai.CreateImageResponseFormatB64JSON
+	}
+	resp, err := b.client.CreateImage(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Data) == 0 || resp.Data[0].B64JSON == "" {
+		return nil, errors.New("no image returned")
+	}
+	data, err := base64.StdEncoding.DecodeString(resp.Data[0].B64JSON)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image data: %w", err)
+	}
+	return &Image{Data: data, MediaType: http.DetectContentType(data)}, nil
+}
+
+// Model implements Backend.
+func (b *OpenAI) Model() string {
+	return b.model
+}
+
+// Imagen generates images with Google's Imagen.
+type Imagen struct {
+	client      *genai.Client
+	model       string
+	aspectRatio string
+}
+
+// NewImagen creates an Imagen backend. Empty model and aspect ratio default
+// to imagen-3.0-generate-002 and 1:1.
+func NewImagen(client *genai.Client, model, aspectRatio string) *Imagen {
+	return &Imagen{client: client, model: cmp.Or(model, defaultImagenModel), aspectRatio: cmp.Or(aspectRatio, defaultAspectRatio)}
+}
+
+// Generate implements Backend.
+func (b *Imagen) Generate(ctx context.Context, prompt string) (*Image, error) {
+	resp, err := b.client.Models.GenerateImages(ctx, b.model, prompt, &genai.GenerateImagesConfig{
+		NumberOfImages: 1,
+		AspectRatio:    b.aspectRatio,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.GeneratedImages) == 0 {
+		return nil, errors.New("no image returned")
+	}
+	generated := resp.GeneratedImages[0]
+	if generated.Image == nil || len(generated.Image.ImageBytes) == 0 {
+		if generated.RAIFilteredReason != "" {
+			return nil, fmt.Errorf("image filtered: %s", generated.RAIFilteredReason)
+		}
+		return nil, errors.New("no image returned")
+	}
+	return &Image{Data: generated.Image.ImageBytes, MediaType: generated.Image.MIMEType}, nil
+}
+
+// Model implements Backend.
+func (b *Imagen) Model() string {
+	return b.model
+}
@@ -0,0 +1,147 @@
+package imagegen
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// pngHeader is enough of a PNG for content sniffing.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+// fakeBackend returns the same image for every prompt and records the prompts.
+type fakeBackend struct {
+	err     error
+	prompts []string
+}
+
+func (b *fakeBackend) Generate(ctx context.Context, prompt string) (*Image, error) {
+	b.prompts = append(b.prompts, prompt)
+	if b.err != nil {
+		return nil, b.err
+	}
+	return &Image{Data: pngHeader}, nil
+}
+
+func (b *fakeBackend) Model() string {
+	return "fake"
+}
+
+func TestNew(t *testing.T) {
+	if g, err := New(&types.ImagesConfig{}, "key"); g != nil || err != nil {
+		t.Errorf("New(disabled) = %v, %v, want nil", g, err)
+	}
+	if _, err := New(&types.ImagesConfig{Enabled: true}, ""); err == nil {
+		t.Error("Expected an error without an API key")
+	}
+	if _, err := New(&types.ImagesConfig{Enabled: true, Backend: "midjourney"}, "key"); err == nil {
+		t.Error("Expected an error for an unknown backend")
+	}
+
+	g, err := New(&types.ImagesConfig{Enabled: true}, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Model() != openai.CreateImageModelDallE3 || g.MaxPerTask() != DefaultMaxPerTask {
+		t.Errorf("Expected dall-e-3 and %d images per task by default, got %s and %d", DefaultMaxPerTask, g.Model(), g.MaxPerTask())
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	newGenerator := func(t *testing.T, backend Backend) (*Generator, string) {
+		t.Helper()
+		dir := t.TempDir()
+		g, err := New(&types.ImagesConfig{Enabled: true, Dir: dir}, "", WithBackend(backend))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return g, dir
+	}
+
+	t.Run("Stores", func(t *testing.T) {
+		backend := &fakeBackend{}
+		g, dir := newGenerator(t, backend)
+
+		file, stored, err := g.Generate(context.Background(), "run-1", "docs/logo.png", "A minimal logo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored != filepath.Join(dir, "run-1", "docs", "logo.png") {
+			t.Errorf("Stored at %s", stored)
+		}
+		if data, err := os.ReadFile(stored); err != nil || !bytes.Equal(data, pngHeader) {
+			t.Errorf("Expected the image at %s, got %q, %v", stored, data, err)
+		}
+		if file.Path != "docs/logo.png" || file.MediaType != "image/png" || !file.Binary() ||
+			file.Content != base64.StdEncoding.EncodeToString(pngHeader) || file.Bytes != len(pngHeader) {
+			t.Errorf("Unexpected artifact %+v", file)
+		}
+		if len(backend.prompts) != 1 || backend.prompts[0] != "A minimal logo" {
+			t.Errorf("Prompts = %q", backend.prompts)
+		}
+	})
+
+	t.Run("RejectsPaths", func(t *testing.T) {
+		backend := &fakeBackend{}
+		g, _ := newGenerator(t, backend)
+
+		for _, path := range []string{"../escape.png", "/etc/logo.png", "docs/diagram.svg"} {
+			if _, _, err := g.Generate(context.Background(), "run-1", path, "A diagram"); err == nil {
+				t.Errorf("Expected %s to be rejected", path)
+			}
+		}
+		if len(backend.prompts) != 0 {
+			t.Errorf("Expected no generation for rejected paths, got %q", backend.prompts)
+		}
+	})
+
+	t.Run("BackendError", func(t *testing.T) {
+		g, dir := newGenerator(t, &fakeBackend{err: errors.New("content policy")})
+
+		if _, _, err := g.Generate(context.Background(), "run-1", "logo.png", "A logo"); err == nil {
+			t.Error("Expected the backend error")
+		}
+		if _, err := os.Stat(filepath.Join(dir, "run-1", "logo.png")); !os.IsNotExist(err) {
+			t.Errorf("Expected nothing stored, got %v", err)
+		}
+	})
+}
+
+func TestOpenAI(t *testing.T) {
+	var req openai.ImageRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(openai.ImageResponse{Data: []openai.ImageResponseDataInner{{B64JSON: base64.StdEncoding.EncodeToString(pngHeader)}}})
+	}))
+	defer server.Close()
+
+	cfg := openai.DefaultConfig("key")
+	cfg.BaseURL = server.URL
+	backend := NewOpenAI(openai.NewClientWithConfig(cfg), "", "")
+
+	image, err := backend.Generate(context.Background(), "A login screen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(image.Data, pngHeader) || image.MediaType != "image/png" {
+		t.Errorf("Unexpected image %+v", image)
+	}
+	if req.Prompt != "A login screen" || req.Model != openai.CreateImageModelDallE3 || req.Size != openai.CreateImageSize1024x1024 ||
+		req.ResponseFormat != openai.CreateImageResponseFormatB64JSON {
+		t.Errorf("Unexpected request %+v", req)
+	}
+}
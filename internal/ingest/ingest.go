@@ -0,0 +1,229 @@
+// Package ingest turns existing documentation into knowledge an organization
+// starts with. Documents are converted to plain text by package document and
+// split into chunks along their headings, each small enough to include in a
+// prompt and labeled with the section it comes from.
+package ingest
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/kpango/BuildBureau/internal/document"
+)
+
+// DefaultChunkSize is the size, in characters, chunks are kept under by default.
+const DefaultChunkSize = 1200
+
+// Chunk is a piece of a document.
+type Chunk struct {
+	Source  string // Path of the document
+	Section string // Headings leading to the chunk, e.g. "Architecture > Storage"
+	Text    string
+	Index   int // Position in the document, from 0
+}
+
+// Ingester reads documents.
+type Ingester struct {
+	pdftotext string
+	chunkSize int
+}
+
+// Option configures an Ingester.
+type Option func(*Ingester)
+
+// WithChunkSize sets the size, in characters, chunks are kept under.
+func WithChunkSize(size int) Option {
+	return func(i *Ingester) {
+		if size > 0 {
+			i.chunkSize = size
+		}
+	}
+}
+
+// WithPDFToText sets the pdftotext binary PDFs are converted with.
+func WithPDFToText(path string) Option {
+	return func(i *Ingester) {
+		i.pdftotext = path
+	}
+}
+
+// New creates an Ingester.
+func New(opts ...Option) *Ingester {
+	i := &Ingester{pdftotext: "pdftotext", chunkSize: DefaultChunkSize}
+	for _, opt := range opts {
+		opt(i)
+	}
+	return i
+}
+
+// Supported reports whether documents with the path's extension can be read.
+func Supported(path string) bool {
+	return document.Format(path) != ""
+}
+
+// Files returns the supported documents under root, which may also be a
+// single document, in lexical order. Hidden directories are skipped.
+func Files(root string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if Supported(path) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents in %s: %w", root, err)
+	}
+	return files, nil
+}
+
+// Read extracts the text of a document and splits it into chunks.
+func (i *Ingester) Read(ctx context.Context, path string) ([]Chunk, error) {
+	doc, err := document.New(document.WithPDFToText(i.pdftotext)).Extract(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	chunks := Split(doc.Text, i.chunkSize)
+	for n := range chunks {
+		chunks[n].Source = filepath.ToSlash(path)
+	}
+	return chunks, nil
+}
+
+// section is the text under one heading.
+type section struct {
+	headings []string
+	body     []string // Paragraphs
+}
+
+// Split splits Markdown-style text into chunks of at most size characters
+// (DefaultChunkSize if not positive). Chunks never span headings; paragraphs
+// of a section are packed together, and paragraphs longer than size are cut
+// at sentence or word boundaries.
+func Split(text string, size int) []Chunk {
+	size = cmp.Or(max(size, 0), DefaultChunkSize)
+
+	var chunks []Chunk
+	for _, s := range sections(text) {
+		var current strings.Builder
+		flush := func() {
+			if t := strings.TrimSpace(current.String()); t != "" {
+				chunks = append(chunks, Chunk{Section: strings.Join(s.headings, " > "), Text: t, Index: len(chunks)})
+			}
+			current.Reset()
+		}
+		for _, paragraph := range s.body {
+			for _, piece := range cut(paragraph, size) {
+				if current.Len() > 0 && current.Len()+2+len(piece) > size {
+					flush()
+				}
+				if current.Len() > 0 {
+					current.WriteString("\n\n")
+				}
+				current.WriteString(piece)
+			}
+		}
+		flush()
+	}
+	return chunks
+}
+
+// sections splits text at Markdown headings, tracking the heading trail.
+// Headings inside code fences are not headings.
+func sections(text string) []section {
+	var (
+		result    []section
+		trail     []string
+		paragraph []string
+		fenced    bool
+	)
+	current := &section{}
+	endParagraph := func() {
+		if p := strings.TrimSpace(strings.Join(paragraph, "\n")); p != "" {
+			current.body = append(current.body, p)
+		}
+		paragraph = nil
+	}
+
+	for line := range strings.Lines(strings.ReplaceAll(text, "\r\n", "\n")) {
+		line = strings.TrimRight(line, "\n")
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fenced = !fenced
+		}
+
+		if level, title, ok := heading(trimmed); ok && !fenced {
+			endParagraph()
+			if len(current.body) > 0 {
+				result = append(result, *current)
+			}
+			trail = append(trail[:min(level-1, len(trail))], title)
+			current = &section{headings: slices.Clone(trail)}
+			continue
+		}
+		if trimmed == "" && !fenced {
+			endParagraph()
+			continue
+		}
+		paragraph = append(paragraph, line)
+	}
+	endParagraph()
+	if len(current.body) > 0 {
+		result = append(result, *current)
+	}
+	return result
+}
+
+// heading parses a Markdown ATX heading such as "## Storage".
+func heading(line string) (int, string, bool) {
+	level := len(line) - len(strings.TrimLeft(line, "#"))
+	if level == 0 || level > 6 || (len(line) > level && line[level] != ' ') {
+		return 0, "", false
+	}
+	title := strings.TrimSpace(strings.TrimRight(line[level:], "# "))
+	return level, title, title != ""
+}
+
+// cut splits a paragraph longer than size at sentence ends, or at spaces if a
+// sentence is too long, or anywhere as a last resort.
+func cut(paragraph string, size int) []string {
+	var pieces []string
+	for len(paragraph) > size {
+		window := paragraph[:size]
+		end := max(strings.LastIndex(window, ". "), strings.LastIndex(window, ".\n")) + 1
+		if end <= 0 {
+			end = strings.LastIndexAny(window, " \n")
+		}
+		if end <= 0 {
+			end = size
+		}
+		// Do not split a UTF-8 sequence
+		for end > 1 && end < len(paragraph) && !isRuneStart(paragraph[end]) {
+			end--
+		}
+		pieces = append(pieces, strings.TrimSpace(paragraph[:end]))
+		paragraph = strings.TrimSpace(paragraph[end:])
+	}
+	if paragraph != "" {
+		pieces = append(pieces, paragraph)
+	}
+	return pieces
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
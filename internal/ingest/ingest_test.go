@@ -0,0 +1,87 @@
+package ingest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	t.Run("Sections", func(t *testing.T) {
+		doc := "Intro paragraph.\n\n# Architecture\n\nServices talk over gRPC.\n\n## Storage\n\nWe use SQLite.\n\n```sh\n# not a heading\nmake db\n```\n\n# Conventions\n\nErrors wrap their cause.\n"
+		chunks := Split(doc, 0)
+
+		want := []Chunk{
+			{Text: "Intro paragraph."},
+			{Section: "Architecture", Text: "Services talk over gRPC.", Index: 1},
+			{Section: "Architecture > Storage", Text: "We use SQLite.\n\n```sh\n# not a heading\nmake db\n```", Index: 2},
+			{Section: "Conventions", Text: "Errors wrap their cause.", Index: 3},
+		}
+		if !slices.Equal(chunks, want) {
+			t.Errorf("Split() = %+v, want %+v", chunks, want)
+		}
+	})
+
+	t.Run("Size", func(t *testing.T) {
+		long := strings.Repeat("Sentence one is here. ", 10)
+		chunks := Split("# Guide\n\nShort.\n\n"+long, 60)
+		if len(chunks) < 4 {
+			t.Fatalf("Expected the long paragraph to be cut, got %+v", chunks)
+		}
+		for _, c := range chunks {
+			if len(c.Text) > 60 || c.Section != "Guide" {
+				t.Errorf("Unexpected chunk: %+v", c)
+			}
+		}
+		if !strings.HasPrefix(chunks[0].Text, "Short.\n\nSentence one is here.") {
+			t.Errorf("Expected paragraphs to be packed together, got %q", chunks[0].Text)
+		}
+	})
+}
+
+func TestRead(t *testing.T) {
+	dir := t.TempDir()
+	for path, content := range map[string]string{
+		"guide.md":         "# Guide\n\nUse Go.\n",
+		"api/index.html":   "<h2>API</h2><p>REST only.</p>",
+		"logo.png":         "not a document",
+		".git/HEAD.md":     "hidden",
+		"notes/readme.txt": "Plain notes.",
+	} {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := Files(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range files {
+		rel, _ := filepath.Rel(dir, f)
+		names = append(names, filepath.ToSlash(rel))
+	}
+	if want := []string{"api/index.html", "guide.md", "notes/readme.txt"}; !slices.Equal(names, want) {
+		t.Fatalf("Files() = %v, want %v", names, want)
+	}
+
+	chunks, err := New().Read(context.Background(), files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 1 || chunks[0].Section != "API" || chunks[0].Text != "REST only." || chunks[0].Source != filepath.ToSlash(files[0]) {
+		t.Errorf("Unexpected chunks: %+v", chunks)
+	}
+
+	if _, err := New(WithPDFToText(filepath.Join(dir, "missing"))).Read(context.Background(), filepath.Join(dir, "spec.pdf")); err == nil {
+		t.Error("Expected an error without pdftotext")
+	}
+}
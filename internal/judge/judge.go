@@ -0,0 +1,215 @@
+// Package judge scores output quality with an LLM acting as a judge. Each
+// judge rates one aspect of an output, such as correctness against its
+// specification, on a 1 to 5 scale whose levels a rubric defines. A Panel runs
+// several judges over the same output, so any loop that produces work (client
+// review, evaluations, picking the best of several drafts) can score it the
+// same way.
+package judge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kpango/BuildBureau/internal/llm"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// Names of the built-in judges.
+const (
+	NameCorrectness = "correctness"
+	NameCodeQuality = "code_quality"
+	NameSecurity    = "security"
+)
+
+// Judge rates one aspect of an output.
+type Judge struct {
+	Name string
+	// Rubric says what is judged and what each rating from 1 to 5 means
+	Rubric string
+}
+
+// Correctness rates how completely and accurately an output meets its specification.
+var Correctness = Judge{
+	Name: NameCorrectness,
+	Rubric: `Judge whether the output does what the specification asks.
+5: Every requirement is met, including edge cases the specification implies.
+4: Every requirement is met; minor gaps in edge cases or error handling.
+3: The main requirements are met, but at least one requirement is missing or wrong.
+2: Only part of the specification is addressed, or the approach cannot work as written.
+1: The output does not address the specification.`,
+}
+
+// CodeQuality rates how readable and maintainable the code in an output is.
+var CodeQuality = Judge{
+	Name: NameCodeQuality,
+	Rubric: `Judge the readability and maintainability of the code in the output, not whether it meets the specification.
+5: Clear structure and naming, idiomatic for the language, errors handled, no duplication.
+4: Readable and idiomatic with a few local issues such as a long function or unclear name.
+3: Works but is hard to follow in places: duplication, deep nesting or unhandled errors.
+2: Disorganized or non-idiomatic throughout; changing it safely would be hard.
+1: The output contains no usable code, or the code is unreadable.
+If the specification does not call for code, rate the clarity and organization of the output instead.`,
+}
+
+// Security rates an output for security smells.
+var Security = Judge{
+	Name: NameSecurity,
+	Rubric: `Look for security smells: injection (SQL, shell, path), hard-coded secrets, missing input
+validation or authorization, unsafe deserialization, weak cryptography and sensitive data in logs.
+5: No security smells.
+4: Minor hardening opportunities only, such as a missing timeout or overly broad file permissions.
+3: At least one smell that is exploitable only under unusual conditions.
+2: At least one exploitable weakness, such as unvalidated input reaching a query or command.
+1: Severe weaknesses, such as a hard-coded credential or remote code execution.`,
+}
+
+// Builtin returns the built-in judges, in the order they are usually reported.
+func Builtin() []Judge {
+	return []Judge{Correctness, CodeQuality, Security}
+}
+
+// Lookup returns the built-in judge with the given name.
+func Lookup(name string) (Judge, bool) {
+	i := slices.IndexFunc(Builtin(), func(j Judge) bool { return j.Name == name })
+	if i < 0 {
+		return Judge{}, false
+	}
+	return Builtin()[i], true
+}
+
+// judgePrompt asks for one rating. The calibration notes keep ratings
+// comparable across judges and models: the rubric decides, not length or tone.
+const judgePrompt = `You are a strict, calibrated reviewer rating one aspect of a piece of work.
+
+Rubric:
+%s
+
+Calibration:
+- Rate only what the rubric describes; ignore every other aspect of the work.
+- A rating is earned by the work, not by its length, confidence or formatting.
+- Most competent work rates 3 or 4. Reserve 5 for work you would accept without any change,
+  and 1 for work with no redeeming value for this aspect.
+- When torn between two ratings, choose the lower one.
+
+Specification:
+%s
+
+Work to rate:
+%s
+
+First reason briefly about the work against the rubric, then rate it.
+Respond ONLY with a JSON object in this exact format:
+{"reason": "...", "rating": 1 to 5}`
+
+// Generator produces text from a prompt; *llm.Manager is one.
+type Generator interface {
+	Generate(ctx context.Context, model, prompt string, opts *llm.GenerateOptions) (string, error)
+}
+
+// Panel scores outputs with several judges.
+type Panel struct {
+	gen    Generator
+	model  string
+	judges []Judge
+}
+
+// NewPanel creates a panel of judges that generate with model, or the
+// generator's default model if it is empty. Without judges it uses the
+// built-in ones.
+func NewPanel(gen Generator, model string, judges ...Judge) *Panel {
+	if len(judges) == 0 {
+		judges = Builtin()
+	}
+	return &Panel{gen: gen, model: model, judges: judges}
+}
+
+// Judges returns the panel's judges.
+func (p *Panel) Judges() []Judge {
+	return slices.Clone(p.judges)
+}
+
+// Score has every judge rate the output against its specification. The
+// judges work concurrently; the scores of those that succeeded are returned
+// in panel order, along with the errors of those that did not.
+func (p *Panel) Score(ctx context.Context, specification, output string) ([]types.JudgeScore, error) {
+	scores := make([]*types.JudgeScore, len(p.judges))
+	errs := make([]error, len(p.judges))
+
+	var wg sync.WaitGroup
+	for i, j := range p.judges {
+		wg.Go(func() {
+			scores[i], errs[i] = p.rate(ctx, j, specification, output)
+		})
+	}
+	wg.Wait()
+
+	var result []types.JudgeScore
+	for _, score := range scores {
+		if score != nil {
+			result = append(result, *score)
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+// rate has one judge rate the output.
+func (p *Panel) rate(ctx context.Context, j Judge, specification, output string) (*types.JudgeScore, error) {
+	text, err := p.gen.Generate(ctx, p.model, fmt.Sprintf(judgePrompt, j.Rubric, specification, output), &llm.GenerateOptions{
+		Temperature: 0,
+		MaxTokens:   512,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s judge failed: %w", j.Name, err)
+	}
+
+	rating, reason, err := parseRating(text)
+	if err != nil {
+		return nil, fmt.Errorf("%s judge: %w", j.Name, err)
+	}
+	return &types.JudgeScore{
+		CreatedAt: time.Now(),
+		Judge:     j.Name,
+		Rating:    rating,
+		Score:     float64(rating-1) / 4,
+		Reason:    reason,
+	}, nil
+}
+
+// parseRating parses a judge's JSON verdict, tolerating surrounding prose and code fences.
+func parseRating(output string) (int, string, error) {
+	start := strings.Index(output, "{")
+	end := strings.LastIndex(output, "}")
+	if start == -1 || end < start {
+		return 0, "", fmt.Errorf("no rating found in output")
+	}
+
+	var verdict struct {
+		Reason string  `json:"reason"`
+		Rating float64 `json:"rating"`
+	}
+	if err := json.Unmarshal([]byte(output[start:end+1]), &verdict); err != nil {
+		return 0, "", fmt.Errorf("failed to parse rating: %w", err)
+	}
+	rating := int(verdict.Rating)
+	if float64(rating) != verdict.Rating || rating < 1 || rating > 5 {
+		return 0, "", fmt.Errorf("rating %v is not a whole number from 1 to 5", verdict.Rating)
+	}
+	return rating, verdict.Reason, nil
+}
+
+// Below returns the scores under minimum.
+func Below(scores []types.JudgeScore, minimum float64) []types.JudgeScore {
+	var low []types.JudgeScore
+	for _, s := range scores {
+		if s.Score < minimum {
+			low = append(low, s)
+		}
+	}
+	return low
+}
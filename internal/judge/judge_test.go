@@ -0,0 +1,72 @@
+package judge
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/kpango/BuildBureau/internal/llm"
+)
+
+// rubricGenerator answers each judge by the rubric in its prompt.
+type rubricGenerator map[string]string
+
+func (g rubricGenerator) Generate(ctx context.Context, model, prompt string, opts *llm.GenerateOptions) (string, error) {
+	for rubric, output := range g {
+		if strings.Contains(prompt, rubric) {
+			return output, nil
+		}
+	}
+	return "", errors.New("unexpected prompt")
+}
+
+func TestPanel(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("ScoresEveryJudge", func(t *testing.T) {
+		gen := rubricGenerator{
+			Correctness.Rubric: `{"reason": "All endpoints present", "rating": 5}`,
+			CodeQuality.Rubric: "```json\n{\"reason\": \"Some duplication\", \"rating\": 3}\n```",
+			Security.Rubric:    `The query is built by string concatenation. {"reason": "SQL injection", "rating": 2}`,
+		}
+		scores, err := NewPanel(gen, "").Score(ctx, "Build a todo API", "func main() {}")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(scores) != 3 {
+			t.Fatalf("Expected a score from every built-in judge, got %+v", scores)
+		}
+		if s := scores[2]; s.Judge != NameSecurity || s.Rating != 2 || s.Score != 0.25 || s.Reason != "SQL injection" {
+			t.Errorf("Unexpected security score: %+v", s)
+		}
+
+		low := Below(scores, 0.5)
+		if len(low) != 1 || low[0].Judge != NameSecurity {
+			t.Errorf("Expected only the security score below 0.5, got %+v", low)
+		}
+	})
+
+	t.Run("InvalidRating", func(t *testing.T) {
+		gen := rubricGenerator{
+			Correctness.Rubric: `{"reason": "Fine", "rating": 4}`,
+			Security.Rubric:    `{"reason": "Excellent", "rating": 10}`,
+		}
+		scores, err := NewPanel(gen, "", Correctness, Security).Score(ctx, "spec", "output")
+		if err == nil || !strings.Contains(err.Error(), "security") {
+			t.Errorf("Expected the out-of-range rating to be reported, got %v", err)
+		}
+		if len(scores) != 1 || scores[0].Judge != NameCorrectness {
+			t.Errorf("Expected the valid score to be kept, got %+v", scores)
+		}
+	})
+
+	t.Run("Lookup", func(t *testing.T) {
+		if j, ok := Lookup(NameCodeQuality); !ok || j.Rubric != CodeQuality.Rubric {
+			t.Errorf("Expected to find the code quality judge, got %+v", j)
+		}
+		if _, ok := Lookup("style"); ok {
+			t.Error("Expected no judge named style")
+		}
+	})
+}
@@ -0,0 +1,231 @@
+package llm
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/kpango/BuildBureau/internal/errors"
+	"github.com/liushuangls/go-anthropic/v2"
+	"github.com/sashabaranov/go-openai"
+	"google.golang.org/genai"
+)
+
+// ProviderErrorKind is the kind of failure a provider reported.
+type ProviderErrorKind string
+
+const (
+	ProviderQuota         ProviderErrorKind = "quota"           // The key's quota is used up
+	ProviderRateLimit     ProviderErrorKind = "rate_limit"      // Too many requests in a short time
+	ProviderAuth          ProviderErrorKind = "auth"            // The key is invalid or lacks access
+	ProviderModelNotFound ProviderErrorKind = "model_not_found" // The model does not exist for the key
+)
+
+// ProviderError is a failure reported by a provider's API, recognized from
+// the shape of its error. Its message tells users which key and model it
+// concerns, when the limit resets if the provider says, and what to do.
+type ProviderError struct {
+	ResetAt  time.Time // When the quota or rate limit resets; zero if unknown
+	cause    *errors.Error
+	Provider string
+	Model    string
+	Key      string // Name of the environment variable holding the key, if known
+	Message  string // Message of the provider
+	Kind     ProviderErrorKind
+	Status   int // HTTP status of the response
+}
+
+// Error returns what happened and what to do about it.
+func (e *ProviderError) Error() string {
+	provider := providerTitle(e.Provider)
+	key := ""
+	if e.Key != "" {
+		key = " " + e.Key
+	}
+	reset := ""
+	if !e.ResetAt.IsZero() {
+		reset = ", resets at " + e.ResetAt.Format("15:04:05 MST")
+	}
+
+	switch e.Kind {
+	case ProviderQuota:
+		return fmt.Sprintf("%s quota exhausted for API key%s on model %s%s: add another key to the rotation or raise the quota with %s", provider, key, e.Model, reset, provider)
+	case ProviderRateLimit:
+		return fmt.Sprintf("%s rate limit reached for API key%s on model %s%s: lower the request rate or add another key to the rotation", provider, key, e.Model, reset)
+	case ProviderAuth:
+		return fmt.Sprintf("%s rejected API key%s: check that it is valid and has access to model %s", provider, key, e.Model)
+	case ProviderModelNotFound:
+		return fmt.Sprintf("%s has no model %s for API key%s: check the model name in the configuration", provider, e.Model, key)
+	default:
+		return fmt.Sprintf("%s request failed: %s", provider, e.Message)
+	}
+}
+
+// Unwrap returns the provider's error, with the code of the failure.
+func (e *ProviderError) Unwrap() error {
+	if e.cause == nil {
+		return nil
+	}
+	return e.cause
+}
+
+// providerError converts a provider SDK error into a ProviderError when its
+// shape is recognized, or wraps it with action otherwise.
+func providerError(provider, model string, err error, action string) error {
+	if e := newProviderError(provider, model, err, time.Now()); e != nil {
+		return e
+	}
+	return fmt.Errorf("%s: %w", action, err)
+}
+
+// newProviderError recognizes the errors of the Gemini, OpenAI and Anthropic
+// SDKs, or returns nil. Reset times the provider reports are relative to now.
+func newProviderError(provider, model string, err error, now time.Time) *ProviderError {
+	e := &ProviderError{Provider: provider, Model: model}
+
+	var geminiErr genai.APIError
+	var openaiErr *openai.APIError
+	var requestErr *openai.RequestError
+	var anthropicErr *anthropic.APIError
+	var anthropicRequestErr *anthropic.RequestError
+	switch {
+	case errors.As(err, &geminiErr):
+		e.Status, e.Message = geminiErr.Code, geminiErr.Message
+		e.Kind = geminiKind(geminiErr)
+		if delay := geminiRetryDelay(geminiErr); delay > 0 {
+			e.ResetAt = now.Add(delay)
+		}
+	case errors.As(err, &openaiErr):
+		e.Status, e.Message = openaiErr.HTTPStatusCode, openaiErr.Message
+		code, _ := openaiErr.Code.(string)
+		e.Kind = openaiKind(openaiErr.HTTPStatusCode, code, openaiErr.Type)
+		if delay := openaiRetryDelay(openaiErr.Message); delay > 0 {
+			e.ResetAt = now.Add(delay)
+		}
+	case errors.As(err, &requestErr):
+		e.Status, e.Message = requestErr.HTTPStatusCode, requestErr.Error()
+		e.Kind = openaiKind(requestErr.HTTPStatusCode, "", "")
+	case errors.As(err, &anthropicErr):
+		e.Message = anthropicErr.Message
+		e.Kind = anthropicKind(anthropicErr.Type)
+	case errors.As(err, &anthropicRequestErr):
+		e.Status, e.Message = anthropicRequestErr.StatusCode, anthropicRequestErr.Error()
+		e.Kind = openaiKind(anthropicRequestErr.StatusCode, "", "")
+	}
+	if e.Kind == "" {
+		return nil
+	}
+
+	code := errors.CodeLLMRateLimit
+	switch e.Kind {
+	case ProviderAuth:
+		code = errors.CodeLLMAuth
+	case ProviderModelNotFound:
+		code = errors.CodeLLMUnavailable
+	}
+	e.cause = errors.Wrap(err, code, provider+" API error")
+	return e
+}
+
+// geminiKind classifies a Gemini API error. Gemini reports both rate limits
+// and quotas as RESOURCE_EXHAUSTED; only daily limits are treated as quotas.
+func geminiKind(err genai.APIError) ProviderErrorKind {
+	switch {
+	case err.Code == http.StatusTooManyRequests || err.Status == "RESOURCE_EXHAUSTED":
+		for _, detail := range err.Details {
+			violations, _ := detail["violations"].([]any)
+			for _, v := range violations {
+				violation, _ := v.(map[string]any)
+				if id, _ := violation["quotaId"].(string); strings.Contains(id, "PerDay") {
+					return ProviderQuota
+				}
+			}
+		}
+		return ProviderRateLimit
+	case err.Code == http.StatusUnauthorized || err.Code == http.StatusForbidden ||
+		err.Status == "UNAUTHENTICATED" || err.Status == "PERMISSION_DENIED":
+		return ProviderAuth
+	case err.Code == http.StatusBadRequest && strings.Contains(err.Message, "API key"):
+		return ProviderAuth // Gemini rejects invalid keys as bad requests
+	case err.Code == http.StatusNotFound || err.Status == "NOT_FOUND":
+		return ProviderModelNotFound
+	default:
+		return ""
+	}
+}
+
+// geminiRetryDelay returns the delay of the RetryInfo detail of a Gemini
+// error, or 0.
+func geminiRetryDelay(err genai.APIError) time.Duration {
+	for _, detail := range err.Details {
+		if kind, _ := detail["@type"].(string); !strings.HasSuffix(kind, "google.rpc.RetryInfo") {
+			continue
+		}
+		if delay, ok := detail["retryDelay"].(string); ok {
+			d, _ := time.ParseDuration(delay)
+			return d
+		}
+	}
+	return 0
+}
+
+// openaiKind classifies an OpenAI error by its status and error code or type.
+func openaiKind(status int, code, errType string) ProviderErrorKind {
+	switch {
+	case code == "insufficient_quota" || errType == "insufficient_quota":
+		return ProviderQuota
+	case status == http.StatusTooManyRequests:
+		return ProviderRateLimit
+	case status == http.StatusUnauthorized || status == http.StatusForbidden || code == "invalid_api_key":
+		return ProviderAuth
+	case status == http.StatusNotFound || code == "model_not_found":
+		return ProviderModelNotFound
+	default:
+		return ""
+	}
+}
+
+// anthropicKind classifies an Anthropic API error by its type. Overloaded
+// errors concern the API, not the key, and are not classified.
+func anthropicKind(errType anthropic.ErrType) ProviderErrorKind {
+	switch errType {
+	case anthropic.ErrTypeRateLimit:
+		return ProviderRateLimit
+	case anthropic.ErrTypeAuthentication, anthropic.ErrTypePermission:
+		return ProviderAuth
+	case anthropic.ErrTypeNotFound:
+		return ProviderModelNotFound
+	default:
+		return ""
+	}
+}
+
+// openaiRetryAfter matches the wait OpenAI suggests in rate limit messages,
+// such as "Please try again in 20s." or "in 6m0s".
+var openaiRetryAfter = regexp.MustCompile(`try again in ((?:[0-9.]+(?:ms|h|m|s))+)`)
+
+// openaiRetryDelay returns the wait suggested by an OpenAI error message, or 0.
+func openaiRetryDelay(message string) time.Duration {
+	match := openaiRetryAfter.FindStringSubmatch(message)
+	if match == nil {
+		return 0
+	}
+	d, _ := time.ParseDuration(match[1])
+	return d
+}
+
+// providerTitle returns the name of a provider as users know it.
+func providerTitle(provider string) string {
+	switch provider {
+	case "openai":
+		return "OpenAI"
+	case "gemini":
+		return "Gemini"
+	case "":
+		return "Provider"
+	default:
+		return strings.ToUpper(provider[:1]) + provider[1:]
+	}
+}
@@ -0,0 +1,163 @@
+package llm
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// Statuses of a call to a provider.
+const (
+	CallOK       = "ok"
+	CallFailed   = "failed"
+	CallCanceled = "canceled"
+)
+
+const (
+	defaultSlowCallThreshold = 30 * time.Second
+	defaultCallsKept         = 1000
+)
+
+// Call records one request to a provider. Token counts are estimates.
+type Call struct {
+	Time             time.Time     `json:"time"`
+	RunID            string        `json:"run_id,omitempty"`
+	Provider         string        `json:"provider"`
+	Model            string        `json:"model"`
+	Status           string        `json:"status"`
+	Error            string        `json:"error,omitempty"`
+	Latency          time.Duration `json:"latency"`
+	PromptTokens     int           `json:"prompt_tokens"`
+	CompletionTokens int           `json:"completion_tokens"`
+	Slow             bool          `json:"slow,omitempty"` // Took longer than the slow threshold
+}
+
+// CallStats summarizes the logged calls to one model of a provider.
+type CallStats struct {
+	Provider         string        `json:"provider"`
+	Model            string        `json:"model"`
+	Calls            int           `json:"calls"`
+	Failures         int           `json:"failures"`
+	Slow             int           `json:"slow"`
+	AverageLatency   time.Duration `json:"average_latency"`
+	MaxLatency       time.Duration `json:"max_latency"`
+	PromptTokens     int           `json:"prompt_tokens"`
+	CompletionTokens int           `json:"completion_tokens"`
+}
+
+// CallLog keeps the most recent calls to providers. It is safe for
+// concurrent use; a nil CallLog records nothing.
+type CallLog struct {
+	calls         []Call // Ring buffer, oldest at next once full
+	next          int
+	keep          int
+	slowThreshold time.Duration
+	mu            sync.Mutex
+}
+
+// NewCallLog creates an empty call log.
+func NewCallLog(cfg types.CallLogConfig) *CallLog {
+	return &CallLog{
+		keep:          cmp.Or(cfg.Keep, defaultCallsKept),
+		slowThreshold: cmp.Or(cfg.SlowThreshold, defaultSlowCallThreshold),
+	}
+}
+
+// SlowThreshold returns the latency above which calls are flagged as slow.
+func (l *CallLog) SlowThreshold() time.Duration {
+	if l == nil {
+		return 0
+	}
+	return l.slowThreshold
+}
+
+// record logs a call, flagging it if it was slow.
+func (l *CallLog) record(c Call) {
+	if l == nil {
+		return
+	}
+	c.Slow = c.Latency > l.slowThreshold
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.calls) < l.keep {
+		l.calls = append(l.calls, c)
+		return
+	}
+	l.calls[l.next] = c
+	l.next = (l.next + 1) % l.keep
+}
+
+// Calls returns the logged calls of a run, or of every run if runID is
+// empty, oldest first.
+func (l *CallLog) Calls(runID string) []Call {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	calls := slices.Concat(l.calls[l.next:], l.calls[:l.next])
+	l.mu.Unlock()
+
+	if runID == "" {
+		return calls
+	}
+	return slices.DeleteFunc(calls, func(c Call) bool { return c.RunID != runID })
+}
+
+// Slowest returns the n slowest logged calls of a run, or of every run if
+// runID is empty, slowest first.
+func (l *CallLog) Slowest(runID string, n int) []Call {
+	calls := l.Calls(runID)
+	slices.SortStableFunc(calls, func(a, b Call) int { return cmp.Compare(b.Latency, a.Latency) })
+	return calls[:min(n, len(calls))]
+}
+
+// Stats summarizes the logged calls by provider and model.
+func (l *CallLog) Stats() []CallStats {
+	byModel := make(map[[2]string]*CallStats)
+	for _, c := range l.Calls("") {
+		key := [2]string{c.Provider, c.Model}
+		s, ok := byModel[key]
+		if !ok {
+			s = &CallStats{Provider: c.Provider, Model: c.Model}
+			byModel[key] = s
+		}
+		s.Calls++
+		if c.Status != CallOK {
+			s.Failures++
+		}
+		if c.Slow {
+			s.Slow++
+		}
+		s.AverageLatency += c.Latency // Divided below
+		s.MaxLatency = max(s.MaxLatency, c.Latency)
+		s.PromptTokens += c.PromptTokens
+		s.CompletionTokens += c.CompletionTokens
+	}
+
+	stats := make([]CallStats, 0, len(byModel))
+	for _, s := range byModel {
+		s.AverageLatency /= time.Duration(s.Calls)
+		stats = append(stats, *s)
+	}
+	slices.SortFunc(stats, func(a, b CallStats) int {
+		return cmp.Or(cmp.Compare(a.Provider, b.Provider), cmp.Compare(a.Model, b.Model))
+	})
+	return stats
+}
+
+// callStatus returns the status of a call that ended with err.
+func callStatus(ctx context.Context, err error) string {
+	switch {
+	case err == nil:
+		return CallOK
+	case ctx.Err() != nil:
+		return CallCanceled
+	default:
+		return CallFailed
+	}
+}
@@ -0,0 +1,157 @@
+package llm
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/liushuangls/go-anthropic/v2"
+	"github.com/sashabaranov/go-openai"
+	"google.golang.org/genai"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+const (
+	defaultClientIdleTimeout   = 10 * time.Minute
+	defaultMaxIdleConnsPerHost = 16
+)
+
+// ClientPoolStats reports how often a ClientPool reused its clients.
+type ClientPoolStats struct {
+	Clients int // Clients currently pooled
+	Created int
+	Reused  int
+	Evicted int
+}
+
+// clientKey identifies a pooled client. The API key is kept as a hash.
+type clientKey struct {
+	provider string
+	model    string
+	apiKey   [sha256.Size]byte
+}
+
+// pooledClient is an SDK client and when it was last handed out.
+type pooledClient struct {
+	client   any
+	lastUsed time.Time
+}
+
+// ClientPool shares provider SDK clients between every agent of an
+// organization. Clients are created on first use, or ahead of it by
+// Manager.Warm, keyed by provider, model and API key. They send requests over
+// one HTTP transport, so connections are reused across clients, and clients
+// unused for the idle timeout are dropped.
+type ClientPool struct {
+	now         func() time.Time
+	clients     map[clientKey]*pooledClient
+	transport   *http.Transport
+	httpClient  *http.Client
+	idleTimeout time.Duration
+	stats       ClientPoolStats
+	mu          sync.Mutex
+}
+
+// NewClientPool creates an empty client pool.
+func NewClientPool(cfg types.ClientPoolConfig) *ClientPool {
+	idleTimeout := cmp.Or(cfg.IdleTimeout, defaultClientIdleTimeout)
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = cmp.Or(cfg.MaxIdleConnsPerHost, defaultMaxIdleConnsPerHost)
+	transport.IdleConnTimeout = idleTimeout
+
+	return &ClientPool{
+		now:         time.Now,
+		clients:     make(map[clientKey]*pooledClient),
+		transport:   transport,
+		httpClient:  &http.Client{Transport: transport},
+		idleTimeout: idleTimeout,
+	}
+}
+
+// Gemini returns the Gemini client for model and apiKey.
+func (p *ClientPool) Gemini(ctx context.Context, model, apiKey string) (*genai.Client, error) {
+	return pooled(p, "gemini", model, apiKey, func() (*genai.Client, error) {
+		client, err := genai.NewClient(ctx, &genai.ClientConfig{
+			APIKey:     apiKey,
+			Backend:    genai.BackendGeminiAPI,
+			HTTPClient: p.httpClient,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Gemini client: %w", err)
+		}
+		return client, nil
+	})
+}
+
+// OpenAI returns the OpenAI client for model and apiKey.
+func (p *ClientPool) OpenAI(model, apiKey string) *openai.Client {
+	client, _ := pooled(p, "openai", model, apiKey, func() (*openai.Client, error) {
+		cfg := openai.DefaultConfig(apiKey)
+		cfg.HTTPClient = p.httpClient
+		return openai.NewClientWithConfig(cfg), nil
+	})
+	return client
+}
+
+// Claude returns the Anthropic client for model and apiKey.
+func (p *ClientPool) Claude(model, apiKey string) *anthropic.Client {
+	client, _ := pooled(p, "claude", model, apiKey, func() (*anthropic.Client, error) {
+		return anthropic.NewClient(apiKey, anthropic.WithHTTPClient(p.httpClient)), nil
+	})
+	return client
+}
+
+// Stats returns the pool's current size and reuse counters.
+func (p *ClientPool) Stats() ClientPoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := p.stats
+	stats.Clients = len(p.clients)
+	return stats
+}
+
+// Close drops every client and closes idle connections.
+func (p *ClientPool) Close() error {
+	p.mu.Lock()
+	p.stats.Evicted += len(p.clients)
+	clear(p.clients)
+	p.mu.Unlock()
+	p.transport.CloseIdleConnections()
+	return nil
+}
+
+// pooled returns the client of provider for model and apiKey, creating it if
+// it is not pooled. Clients idle for longer than the idle timeout are evicted
+// first.
+func pooled[T any](p *ClientPool, provider, model, apiKey string, create func() (T, error)) (T, error) {
+	key := clientKey{provider: provider, model: model, apiKey: sha256.Sum256([]byte(apiKey))}
+	now := p.now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for k, c := range p.clients {
+		if now.Sub(c.lastUsed) >= p.idleTimeout {
+			delete(p.clients, k)
+			p.stats.Evicted++
+		}
+	}
+
+	if c, ok := p.clients[key]; ok {
+		c.lastUsed = now
+		p.stats.Reused++
+		return c.client.(T), nil
+	}
+
+	client, err := create()
+	if err != nil {
+		return client, err
+	}
+	p.clients[key] = &pooledClient{client: client, lastUsed: now}
+	p.stats.Created++
+	return client, nil
+}
@@ -0,0 +1,70 @@
+package llm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+func TestClientPool(t *testing.T) {
+	pool := NewClientPool(types.ClientPoolConfig{IdleTimeout: time.Minute})
+	now := time.Now()
+	pool.now = func() time.Time { return now }
+
+	first := pool.OpenAI("gpt-4o", "key-1")
+	if pool.OpenAI("gpt-4o", "key-1") != first {
+		t.Error("Expected the client reused for the same provider, model and key")
+	}
+	if pool.OpenAI("gpt-4o", "key-2") == first || pool.OpenAI("gpt-4o-mini", "key-1") == first {
+		t.Error("Expected separate clients for other keys and models")
+	}
+	pool.Claude("claude-3-5-sonnet", "key-1")
+	if _, err := pool.Gemini(context.Background(), "gemini-2.0-flash", "key-1"); err != nil {
+		t.Fatal(err)
+	}
+	if stats := pool.Stats(); stats != (ClientPoolStats{Clients: 5, Created: 5, Reused: 1}) {
+		t.Errorf("Stats() = %+v", stats)
+	}
+
+	// Clients idle for the timeout are dropped and created again on use
+	now = now.Add(30 * time.Second)
+	pool.OpenAI("gpt-4o", "key-1")
+	now = now.Add(45 * time.Second)
+	if pool.OpenAI("gpt-4o", "key-1") != first {
+		t.Error("Expected a recently used client kept")
+	}
+	if stats := pool.Stats(); stats.Clients != 1 || stats.Evicted != 4 {
+		t.Errorf("Expected idle clients evicted, got %+v", stats)
+	}
+
+	pool.Close()
+	if pool.OpenAI("gpt-4o", "key-1") == first {
+		t.Error("Expected a new client after Close")
+	}
+}
+
+func TestManagerWarm(t *testing.T) {
+	m, err := NewManager(&types.LLMConfig{DefaultModel: "openai"}, WithProvider("claude", &fakeKeyProvider{name: "claude"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	openaiProvider, err := newPooledOpenAIProvider(m.Clients(), "key", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.AddProvider("openai", openaiProvider)
+
+	if err := m.Warm(context.Background(), "", "openai", "gpt-4o", "gpt-4o", "claude", "unknown"); err != nil {
+		t.Fatal(err)
+	}
+	if stats := m.Clients().Stats(); stats.Created != 2 || stats.Reused != 0 {
+		t.Errorf("Expected clients for the default and the specific model, got %+v", stats)
+	}
+	if openaiProvider.sdk(openai.GPT4TurboPreview) != m.Clients().OpenAI(openai.GPT4TurboPreview, "key") {
+		t.Error("Expected the provider to use the warmed client")
+	}
+}
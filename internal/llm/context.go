@@ -0,0 +1,66 @@
+package llm
+
+import "strings"
+
+// DefaultContextWindow is assumed for models whose context window is unknown,
+// such as remote or self-hosted models.
+const DefaultContextWindow = 32768
+
+// contextWindows maps model name prefixes to context windows in tokens. The
+// longest matching prefix wins, so specific models override their family.
+var contextWindows = map[string]int{
+	"gemini":         1048576,
+	"gemini-1.5-pro": 2097152,
+	"openai":         128000,
+	"gpt-4o":         128000,
+	"gpt-4-turbo":    128000,
+	"gpt-4":          8192,
+	"gpt-3.5":        16385,
+	"claude":         200000,
+	"qwen":           32768,
+}
+
+// ContextWindow returns the context window, in tokens, of a model or provider.
+// Configured windows take precedence over the built-in ones.
+func (m *Manager) ContextWindow(model string) int {
+	if model == "" {
+		model = m.defaultModel
+	}
+
+	// For "provider/model" the specific model decides, falling back to the provider
+	candidates := []string{model}
+	if provider, specific, ok := strings.Cut(model, "/"); ok {
+		candidates = []string{specific, provider}
+	}
+
+	for _, windows := range []map[string]int{m.contextWindows, contextWindows} {
+		for _, candidate := range candidates {
+			if window, _ := longestPrefix(windows, candidate); window > 0 {
+				return window
+			}
+		}
+	}
+
+	return DefaultContextWindow
+}
+
+// longestPrefix returns the value of the longest key that prefixes name.
+func longestPrefix[V any](values map[string]V, name string) (V, bool) {
+	var (
+		value V
+		found bool
+	)
+	best := -1
+	for prefix, v := range values {
+		if strings.HasPrefix(name, prefix) && len(prefix) > best {
+			best, value, found = len(prefix), v, true
+		}
+	}
+	return value, found
+}
+
+// EstimateTokens roughly estimates the number of tokens in text, at about
+// four characters per token.
+func EstimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
@@ -0,0 +1,278 @@
+package llm
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kpango/BuildBureau/internal/errors"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+const (
+	// StrategyRoundRobin cycles through keys in order.
+	StrategyRoundRobin = "round_robin"
+	// StrategyLeastUsed picks the key with the fewest requests in the current window.
+	StrategyLeastUsed = "least_used"
+
+	defaultKeyCooldown = time.Minute
+	defaultQuotaWindow = time.Hour
+)
+
+// KeyStats reports usage of a single API key in a KeyPool.
+type KeyStats struct {
+	ExhaustedUntil time.Time
+	Label          string
+	Requests       int
+	Errors         int
+	WindowRequests int
+	Exhausted      bool
+}
+
+// pooledKey is one API key's provider and usage counters.
+type pooledKey struct {
+	provider       Provider
+	windowStart    time.Time
+	exhaustedUntil time.Time
+	label          string
+	requests       int
+	errors         int
+	windowRequests int
+}
+
+// KeyPool is a Provider that spreads requests across several API keys of the same provider.
+// Keys that hit their quota or are rate limited are taken out of rotation until they reset.
+type KeyPool struct {
+	now      func() time.Time
+	name     string
+	strategy string
+	keys     []*pooledKey
+	quota    int
+	window   time.Duration
+	cooldown time.Duration
+	next     int
+	mu       sync.Mutex
+}
+
+// NewKeyPool creates a pool over providers that each use a different key.
+// labels identify the keys in stats without exposing them.
+func NewKeyPool(name string, providers []Provider, labels []string, cfg types.KeyRotationConfig) *KeyPool {
+	p := &KeyPool{
+		name:     name,
+		strategy: cfg.Strategy,
+		quota:    cfg.Quota,
+		window:   cfg.QuotaWindow,
+		cooldown: cfg.Cooldown,
+		now:      time.Now,
+	}
+
+	if p.strategy == "" {
+		p.strategy = StrategyRoundRobin
+	}
+	if p.window <= 0 {
+		p.window = defaultQuotaWindow
+	}
+	if p.cooldown <= 0 {
+		p.cooldown = defaultKeyCooldown
+	}
+
+	for i, provider := range providers {
+		label := fmt.Sprintf("key-%d", i+1)
+		if i < len(labels) && labels[i] != "" {
+			label = labels[i]
+		}
+		p.keys = append(p.keys, &pooledKey{provider: provider, label: label})
+	}
+
+	return p
+}
+
+// Generate sends the prompt using an available key, moving on to the next key when one is rate limited.
+func (p *KeyPool) Generate(ctx context.Context, prompt string, opts *GenerateOptions) (string, error) {
+	c, err := p.Complete(ctx, prompt, opts)
+	return c.Text, err
+}
+
+// Complete is Generate, also reporting whether the generation stopped at its
+// token limit when the key's provider can tell.
+func (p *KeyPool) Complete(ctx context.Context, prompt string, opts *GenerateOptions) (Completion, error) {
+	var lastErr error
+
+	for range p.keys {
+		key := p.acquire()
+		if key == nil {
+			break
+		}
+		if lastErr != nil {
+			meterFromContext(ctx).add(Usage{Retries: 1})
+		}
+
+		c, err := complete(ctx, key.provider, prompt, opts)
+		p.release(key, err)
+		if err == nil {
+			return c, nil
+		}
+
+		lastErr = err
+		// A canceled request is not retried on another key
+		if ctx.Err() != nil || !isQuotaError(err) {
+			return Completion{}, err
+		}
+	}
+
+	if lastErr != nil {
+		return Completion{}, errors.Wrap(lastErr, errors.CodeLLMRateLimit, fmt.Sprintf("all %s API keys are exhausted", p.name))
+	}
+	if next := p.nextReset(); !next.IsZero() {
+		return Completion{}, errors.Newf(errors.CodeLLMRateLimit, "all %s API keys are exhausted until %s: add another key to the rotation or wait", p.name, next.Format("15:04:05 MST"))
+	}
+	return Completion{}, errors.Newf(errors.CodeLLMRateLimit, "all %s API keys are exhausted", p.name)
+}
+
+// nextReset returns when the first exhausted key can take requests again, or
+// the zero time if no key is exhausted.
+func (p *KeyPool) nextReset() time.Time {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	var next time.Time
+	for _, key := range p.keys {
+		until := key.exhaustedUntil
+		if reset := key.windowStart.Add(p.window); p.quota > 0 && key.windowRequests >= p.quota && reset.After(until) {
+			until = reset
+		}
+		if until.After(now) && (next.IsZero() || until.Before(next)) {
+			next = until
+		}
+	}
+	return next
+}
+
+// acquire selects an available key and counts the request against it, or returns nil if none is available.
+func (p *KeyPool) acquire() *pooledKey {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+
+	var selected *pooledKey
+	for i := range p.keys {
+		idx := (p.next + i) % len(p.keys)
+		key := p.keys[idx]
+		if !p.available(key, now) {
+			continue
+		}
+
+		if p.strategy != StrategyLeastUsed {
+			selected = key
+			p.next = idx + 1
+			break
+		}
+
+		if selected == nil || key.windowRequests < selected.windowRequests {
+			selected = key
+		}
+	}
+
+	if selected != nil {
+		selected.requests++
+		selected.windowRequests++
+	}
+
+	return selected
+}
+
+// available reports whether a key can take a request, resetting its window when it has elapsed.
+func (p *KeyPool) available(key *pooledKey, now time.Time) bool {
+	if now.Sub(key.windowStart) >= p.window {
+		key.windowStart = now
+		key.windowRequests = 0
+	}
+
+	if now.Before(key.exhaustedUntil) {
+		return false
+	}
+
+	return p.quota <= 0 || key.windowRequests < p.quota
+}
+
+// release records the outcome of a request, taking rate-limited keys out of
+// rotation and naming the key in the provider's error.
+func (p *KeyPool) release(key *pooledKey, err error) {
+	if err == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key.errors++
+	var providerErr *ProviderError
+	if errors.As(err, &providerErr) && providerErr.Key == "" {
+		providerErr.Key = key.label
+	}
+	if isQuotaError(err) {
+		key.exhaustedUntil = p.now().Add(p.cooldown)
+		// The provider knows best when the key can be used again
+		if providerErr != nil && !providerErr.ResetAt.IsZero() {
+			key.exhaustedUntil = providerErr.ResetAt
+		}
+	}
+}
+
+// Stats returns usage for every key in the pool.
+func (p *KeyPool) Stats() []KeyStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	stats := make([]KeyStats, 0, len(p.keys))
+	for _, key := range p.keys {
+		stats = append(stats, KeyStats{
+			Label:          key.label,
+			Requests:       key.requests,
+			Errors:         key.errors,
+			WindowRequests: key.windowRequests,
+			Exhausted:      !p.available(key, now),
+			ExhaustedUntil: key.exhaustedUntil,
+		})
+	}
+
+	return stats
+}
+
+// Name returns the name of the provider.
+func (p *KeyPool) Name() string {
+	return p.name
+}
+
+// warm creates the clients of every key's provider for model.
+func (p *KeyPool) warm(ctx context.Context, model string) error {
+	var errs []error
+	for _, key := range p.keys {
+		if w, ok := key.provider.(warmer); ok {
+			errs = append(errs, w.warm(ctx, model))
+		}
+	}
+	return stderrors.Join(errs...)
+}
+
+// Close closes every key's provider.
+func (p *KeyPool) Close() error {
+	for _, key := range p.keys {
+		if closer, ok := key.provider.(interface{ Close() error }); ok {
+			_ = closer.Close()
+		}
+	}
+	return nil
+}
+
+// isQuotaError reports whether err means the key is rate limited or out of quota.
+// Providers report these with CodeLLMRateLimit, recognized from the status
+// codes and error types of their APIs (see newProviderError).
+func isQuotaError(err error) bool {
+	return errors.CodeOf(err) == errors.CodeLLMRateLimit
+}
@@ -0,0 +1,165 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kpango/BuildBureau/internal/errors"
+	"github.com/kpango/BuildBureau/pkg/types"
+	"github.com/sashabaranov/go-openai"
+)
+
+// fakeKeyProvider returns a fixed response, or a rate limit error when limited.
+type fakeKeyProvider struct {
+	name    string
+	calls   int
+	limited bool
+}
+
+func (p *fakeKeyProvider) Generate(ctx context.Context, prompt string, opts *GenerateOptions) (string, error) {
+	p.calls++
+	if p.limited {
+		return "", errors.New(errors.CodeLLMRateLimit, "429 too many requests")
+	}
+	return p.name, nil
+}
+
+func (p *fakeKeyProvider) Name() string { return p.name }
+
+func TestKeyPoolRoundRobin(t *testing.T) {
+	a, b := &fakeKeyProvider{name: "a"}, &fakeKeyProvider{name: "b"}
+	pool := NewKeyPool("test", []Provider{a, b}, []string{"KEY_A", "KEY_B"}, types.KeyRotationConfig{})
+
+	for range 4 {
+		if _, err := pool.Generate(context.Background(), "prompt", nil); err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+	}
+
+	if a.calls != 2 || b.calls != 2 {
+		t.Errorf("Expected requests split evenly, got a=%d b=%d", a.calls, b.calls)
+	}
+}
+
+func TestKeyPoolRateLimitedKeyLeavesRotation(t *testing.T) {
+	a, b := &fakeKeyProvider{name: "a", limited: true}, &fakeKeyProvider{name: "b"}
+	pool := NewKeyPool("test", []Provider{a, b}, nil, types.KeyRotationConfig{Cooldown: time.Minute})
+
+	now := time.Now()
+	pool.now = func() time.Time { return now }
+
+	// The rate-limited key fails over to the next one
+	response, err := pool.Generate(context.Background(), "prompt", nil)
+	if err != nil || response != "b" {
+		t.Fatalf("Expected failover to key b, got %q, %v", response, err)
+	}
+
+	// While cooling down the limited key is skipped entirely
+	_, _ = pool.Generate(context.Background(), "prompt", nil)
+	if a.calls != 1 {
+		t.Errorf("Expected exhausted key to be skipped, got %d calls", a.calls)
+	}
+
+	stats := pool.Stats()
+	if !stats[0].Exhausted || stats[0].Errors != 1 || stats[0].Label != "key-1" {
+		t.Errorf("Unexpected stats for limited key: %+v", stats[0])
+	}
+
+	// After the cooldown the key is back in rotation
+	a.limited = false
+	now = now.Add(2 * time.Minute)
+	_, _ = pool.Generate(context.Background(), "prompt", nil)
+	_, _ = pool.Generate(context.Background(), "prompt", nil)
+	if a.calls != 2 {
+		t.Errorf("Expected key to return after cooldown, got %d calls", a.calls)
+	}
+}
+
+func TestKeyPoolCanceledNotRetried(t *testing.T) {
+	a, b := &fakeKeyProvider{name: "a", limited: true}, &fakeKeyProvider{name: "b"}
+	pool := NewKeyPool("test", []Provider{a, b}, nil, types.KeyRotationConfig{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := pool.Generate(ctx, "prompt", nil); err == nil {
+		t.Fatal("Expected the canceled request to fail")
+	}
+	if b.calls != 0 {
+		t.Errorf("Expected a canceled request not to move on to the next key, got %d calls", b.calls)
+	}
+}
+
+func TestKeyPoolIgnoresQuotaText(t *testing.T) {
+	a := &failingKeyProvider{err: fmt.Errorf("failed to summarize the quota report: 429 lines exceed the rate limit of the parser")}
+	b := &fakeKeyProvider{name: "b"}
+	pool := NewKeyPool("test", []Provider{a, b}, nil, types.KeyRotationConfig{Cooldown: time.Minute})
+
+	if _, err := pool.Generate(context.Background(), "prompt", nil); err == nil {
+		t.Fatal("Expected the error returned")
+	}
+	if b.calls != 0 || pool.Stats()[0].Exhausted {
+		t.Errorf("Expected an error without a rate limit code not to rotate keys, got %d calls, %+v", b.calls, pool.Stats()[0])
+	}
+}
+
+func TestKeyPoolQuota(t *testing.T) {
+	a, b := &fakeKeyProvider{name: "a"}, &fakeKeyProvider{name: "b"}
+	pool := NewKeyPool("test", []Provider{a, b}, nil, types.KeyRotationConfig{
+		Strategy:    StrategyLeastUsed,
+		Quota:       1,
+		QuotaWindow: time.Hour,
+	})
+
+	now := time.Now()
+	pool.now = func() time.Time { return now }
+
+	_, _ = pool.Generate(context.Background(), "prompt", nil)
+	_, _ = pool.Generate(context.Background(), "prompt", nil)
+
+	_, err := pool.Generate(context.Background(), "prompt", nil)
+	if errors.CodeOf(err) != errors.CodeLLMRateLimit {
+		t.Errorf("Expected %s once every key is over quota, got %v", errors.CodeLLMRateLimit, err)
+	}
+
+	// Quotas reset with the window
+	now = now.Add(time.Hour)
+	if _, err := pool.Generate(context.Background(), "prompt", nil); err != nil {
+		t.Errorf("Expected quota to reset, got %v", err)
+	}
+}
+
+func TestKeyPoolNamesKeyInProviderError(t *testing.T) {
+	now := time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC)
+	limited := &failingKeyProvider{err: newProviderError("openai", "gpt-4o", &openai.APIError{
+		HTTPStatusCode: 429,
+		Message:        "Rate limit reached. Please try again in 20s.",
+	}, now)}
+	pool := NewKeyPool("openai", []Provider{limited}, []string{"OPENAI_API_KEY"}, types.KeyRotationConfig{Cooldown: time.Minute})
+	pool.now = func() time.Time { return now }
+
+	_, err := pool.Generate(context.Background(), "prompt", nil)
+	var providerErr *ProviderError
+	if !errors.As(err, &providerErr) || providerErr.Key != "OPENAI_API_KEY" {
+		t.Fatalf("Expected the error to name the key, got %v", err)
+	}
+
+	// The key is skipped until the provider's reset time, not the cooldown
+	_, err = pool.Generate(context.Background(), "prompt", nil)
+	if want := "all openai API keys are exhausted until 12:00:20 UTC"; err == nil || !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("Expected %q, got %v", want, err)
+	}
+}
+
+// failingKeyProvider fails every request with err.
+type failingKeyProvider struct {
+	err error
+}
+
+func (p *failingKeyProvider) Generate(ctx context.Context, prompt string, opts *GenerateOptions) (string, error) {
+	return "", p.err
+}
+
+func (p *failingKeyProvider) Name() string { return "openai" }
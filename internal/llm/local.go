@@ -0,0 +1,160 @@
+package llm
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kpango/BuildBureau/internal/errors"
+)
+
+// Names of the providers that cost nothing to run.
+const (
+	ProviderMock   = "mock"
+	ProviderOllama = "ollama"
+)
+
+const (
+	defaultOllamaEndpoint = "http://localhost:11434"
+	defaultOllamaModel    = "llama3.2"
+)
+
+// MockProvider answers every prompt with a canned response, without any
+// network access. It lets the organization workflow run end to end, such as
+// in demos, at no cost.
+type MockProvider struct{}
+
+// NewMockProvider creates a mock provider.
+func NewMockProvider() *MockProvider {
+	return &MockProvider{}
+}
+
+// Generate returns a placeholder response naming the task of the prompt.
+func (p *MockProvider) Generate(ctx context.Context, prompt string, opts *GenerateOptions) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	subject := "the request"
+	for line := range strings.Lines(prompt) {
+		if title, ok := strings.CutPrefix(strings.TrimSpace(line), "Title: "); ok && title != "" {
+			subject = title
+			break
+		}
+	}
+	return fmt.Sprintf("Plan for %s:\n\n1. Clarify the requirements.\n2. Design the components.\n3. Implement and test them.\n\nThis is a placeholder response from the mock provider; no model was called.", subject), nil
+}
+
+// Name returns the provider name.
+func (p *MockProvider) Name() string {
+	return ProviderMock
+}
+
+// OllamaProvider runs models on an Ollama server.
+type OllamaProvider struct {
+	httpClient *http.Client
+	endpoint   string
+	model      string
+}
+
+// ollamaRequest is the body of a request to Ollama's /api/generate.
+type ollamaRequest struct {
+	Options map[string]any `json:"options,omitempty"`
+	Model   string         `json:"model"`
+	Prompt  string         `json:"prompt"`
+	System  string         `json:"system,omitempty"`
+	Stream  bool           `json:"stream"`
+}
+
+// ollamaResponse is the response of Ollama's /api/generate.
+type ollamaResponse struct {
+	Response   string `json:"response"`
+	DoneReason string `json:"done_reason"`
+	Error      string `json:"error"`
+}
+
+// NewOllamaProvider creates a provider for the Ollama server at endpoint,
+// http://localhost:11434 if empty, running model, llama3.2 if empty.
+func NewOllamaProvider(endpoint, model string) *OllamaProvider {
+	return &OllamaProvider{
+		httpClient: &http.Client{Timeout: 5 * time.Minute},
+		endpoint:   strings.TrimSuffix(cmp.Or(endpoint, defaultOllamaEndpoint), "/"),
+		model:      cmp.Or(model, defaultOllamaModel),
+	}
+}
+
+// Generate sends a prompt to Ollama.
+func (p *OllamaProvider) Generate(ctx context.Context, prompt string, opts *GenerateOptions) (string, error) {
+	c, err := p.Complete(ctx, prompt, opts)
+	return c.Text, err
+}
+
+// Complete is Generate, also reporting whether Ollama stopped at the token limit.
+func (p *OllamaProvider) Complete(ctx context.Context, prompt string, opts *GenerateOptions) (Completion, error) {
+	if opts == nil {
+		opts = &GenerateOptions{Temperature: 0.7, MaxTokens: 2048}
+	}
+
+	options := map[string]any{"temperature": opts.Temperature}
+	if opts.MaxTokens > 0 {
+		options["num_predict"] = opts.MaxTokens
+	}
+	if opts.Seed != nil {
+		options["seed"] = *opts.Seed
+	}
+	body, err := json.Marshal(ollamaRequest{
+		Model:   cmp.Or(opts.Model, p.model),
+		Prompt:  prompt,
+		System:  opts.SystemPrompt,
+		Options: options,
+	})
+	if err != nil {
+		return Completion{}, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint+"/api/generate", bytes.NewReader(body))
+	if err != nil {
+		return Completion{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return Completion{}, errors.Wrap(err, errors.CodeLLMUnavailable, "failed to reach Ollama")
+	}
+	defer resp.Body.Close()
+
+	var result ollamaResponse
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+		message := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &result) == nil && result.Error != "" {
+			message = result.Error
+		}
+		return Completion{}, errors.Newf(errors.CodeLLMFailed, "ollama returned status %d: %s", resp.StatusCode, message)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return Completion{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if result.Response == "" {
+		return Completion{}, fmt.Errorf("empty result from ollama")
+	}
+	return Completion{Text: result.Response, Truncated: result.DoneReason == "length"}, nil
+}
+
+// Name returns the provider name.
+func (p *OllamaProvider) Name() string {
+	return ProviderOllama
+}
+
+// Close closes idle connections to the server.
+func (p *OllamaProvider) Close() error {
+	p.httpClient.CloseIdleConnections()
+	return nil
+}
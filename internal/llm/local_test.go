@@ -0,0 +1,84 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kpango/BuildBureau/internal/errors"
+)
+
+func TestMockProvider(t *testing.T) {
+	p := NewMockProvider()
+
+	out, err := p.Generate(context.Background(), "You are a software engineer.\n\nTitle: Todo API\nDescription: ...", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Todo API") {
+		t.Errorf("Expected the response to name the task, got %q", out)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := p.Generate(ctx, "prompt", nil); err == nil {
+		t.Error("Expected an error for a canceled context")
+	}
+}
+
+func TestOllamaProvider(t *testing.T) {
+	t.Run("Generate", func(t *testing.T) {
+		var req ollamaRequest
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost || r.URL.Path != "/api/generate" {
+				t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatalf("Failed to decode request: %v", err)
+			}
+			json.NewEncoder(w).Encode(ollamaResponse{Response: "func main() {}", DoneReason: "length"})
+		}))
+		defer server.Close()
+
+		p := NewOllamaProvider(server.URL+"/", "")
+		c, err := p.Complete(context.Background(), "Write main", &GenerateOptions{SystemPrompt: "Be brief", MaxTokens: 64, Seed: new(7)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Text != "func main() {}" || !c.Truncated {
+			t.Errorf("Complete() = %+v", c)
+		}
+		if req.Model != defaultOllamaModel || req.Prompt != "Write main" || req.System != "Be brief" || req.Stream {
+			t.Errorf("Unexpected request %+v", req)
+		}
+		if req.Options["num_predict"] != float64(64) || req.Options["seed"] != float64(7) {
+			t.Errorf("Unexpected options %v", req.Options)
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"model \"llama3.2\" not found"}`))
+		}))
+		defer server.Close()
+
+		_, err := NewOllamaProvider(server.URL, "").Generate(context.Background(), "prompt", nil)
+		if errors.CodeOf(err) != errors.CodeLLMFailed || !strings.Contains(err.Error(), "not found") {
+			t.Errorf("Expected the server's error, got %v", err)
+		}
+	})
+
+	t.Run("Unreachable", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		server.Close()
+
+		_, err := NewOllamaProvider(server.URL, "").Generate(context.Background(), "prompt", nil)
+		if errors.CodeOf(err) != errors.CodeLLMUnavailable {
+			t.Errorf("Expected %s, got %v", errors.CodeLLMUnavailable, err)
+		}
+	})
+}
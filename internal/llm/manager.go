@@ -1,11 +1,17 @@
 package llm
 
 import (
+	"cmp"
 	"context"
+	stderrors "errors"
 	"fmt"
+	"maps"
 	"os"
+	"slices"
+	"strings"
 
 	"github.com/kpango/BuildBureau/internal/config"
+	"github.com/kpango/BuildBureau/internal/errors"
 	"github.com/kpango/BuildBureau/pkg/types"
 )
 
@@ -21,63 +27,61 @@ type Provider interface {
 // GenerateOptions contains options for generation.
 type GenerateOptions struct {
 	SystemPrompt string
+	Model        string // Provider model to use instead of the provider's default; set by Manager.Generate
+	Seed         *int   // Sampling seed for providers that accept one; nil leaves sampling unseeded
 	Temperature  float64
 	MaxTokens    int
 }
 
 // Manager manages multiple LLM providers.
 type Manager struct {
-	providers    map[string]Provider
-	defaultModel string
+	providers       map[string]Provider
+	contextWindows  map[string]int
+	defaultModel    string
+	reproducibility types.ReproducibilityConfig
+	outputTokens    types.OutputTokensConfig
+	prices          map[string]types.ModelPrice
+	override        string            // Provider serving every model, if set
+	keyNames        map[string]string // Key variable of each provider with a single key
+	clients         *ClientPool
+	calls           *CallLog
 }
 
 // NewManager creates a new LLM manager with real provider initialization.
-func NewManager(cfg *types.LLMConfig) (*Manager, error) {
+func NewManager(cfg *types.LLMConfig, opts ...Option) (*Manager, error) {
 	m := &Manager{
-		providers:    make(map[string]Provider),
-		defaultModel: cfg.DefaultModel,
+		providers:       make(map[string]Provider),
+		keyNames:        make(map[string]string),
+		contextWindows:  cfg.ContextWindows,
+		defaultModel:    cfg.DefaultModel,
+		reproducibility: cfg.Reproducibility,
+		outputTokens:    cfg.OutputTokens,
+		prices:          cfg.Prices,
+		clients:         NewClientPool(cfg.ClientPool),
+		calls:           NewCallLog(cfg.Calls),
 	}
 
 	// Initialize Gemini provider if API key is available
-	if geminiKey, exists := cfg.APIKeys["gemini"]; exists {
-		apiKey := config.GetEnvValue(geminiKey)
-		if apiKey != "" {
-			provider, err := NewGeminiProvider(apiKey)
-			if err != nil {
-				return nil, fmt.Errorf("failed to initialize Gemini provider: %w", err)
-			}
-			m.providers["gemini"] = provider
-		}
+	if err := m.initProvider(cfg, "gemini", func(apiKey string) (Provider, error) {
+		return newPooledGeminiProvider(m.clients, apiKey)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to initialize Gemini provider: %w", err)
 	}
 
 	// Initialize OpenAI provider if API key is available
-	if openaiKey, exists := cfg.APIKeys["openai"]; exists {
-		apiKey := config.GetEnvValue(openaiKey)
-		if apiKey != "" {
-			// Use model from environment or default
-			model := os.Getenv("OPENAI_MODEL")
-			provider, err := NewOpenAIProvider(apiKey, model)
-			if err != nil {
-				fmt.Printf("Warning: failed to initialize OpenAI provider: %v\n", err)
-			} else {
-				m.providers["openai"] = provider
-			}
-		}
+	if err := m.initProvider(cfg, "openai", func(apiKey string) (Provider, error) {
+		// Use model from environment or default
+		return newPooledOpenAIProvider(m.clients, apiKey, os.Getenv("OPENAI_MODEL"))
+	}); err != nil {
+		fmt.Printf("Warning: failed to initialize OpenAI provider: %v\n", err)
 	}
 
 	// Initialize Claude provider if API key is available
-	if claudeKey, exists := cfg.APIKeys["claude"]; exists {
-		apiKey := config.GetEnvValue(claudeKey)
-		if apiKey != "" {
-			// Use model from environment or default
-			model := os.Getenv("CLAUDE_MODEL")
-			provider, err := NewClaudeProvider(apiKey, model)
-			if err != nil {
-				fmt.Printf("Warning: failed to initialize Claude provider: %v\n", err)
-			} else {
-				m.providers["claude"] = provider
-			}
-		}
+	if err := m.initProvider(cfg, "claude", func(apiKey string) (Provider, error) {
+		// Use model from environment or default
+		return newPooledClaudeProvider(m.clients, apiKey, os.Getenv("CLAUDE_MODEL"))
+	}); err != nil {
+		fmt.Printf("Warning: failed to initialize Claude provider: %v\n", err)
 	}
 
 	// Initialize remote providers for Codex, Qwen, or custom endpoints
@@ -91,46 +95,227 @@ func NewManager(cfg *types.LLMConfig) (*Manager, error) {
 	}
 
 	for _, rp := range remoteProviders {
-		if envVar, exists := cfg.APIKeys[rp.name]; exists {
-			apiKey := config.GetEnvValue(envVar)
-			if apiKey != "" && rp.endpoint != "" {
-				provider, err := NewRemoteProvider(rp.name, rp.endpoint, apiKey)
-				if err != nil {
-					// Log but don't fail - remote providers are optional
-					fmt.Printf("Warning: failed to initialize %s provider: %v\n", rp.name, err)
-					continue
-				}
-				m.providers[rp.name] = provider
-			}
+		if rp.endpoint == "" {
+			continue
+		}
+		if err := m.initProvider(cfg, rp.name, func(apiKey string) (Provider, error) {
+			return NewRemoteProvider(rp.name, rp.endpoint, apiKey)
+		}); err != nil {
+			// Log but don't fail - remote providers are optional
+			fmt.Printf("Warning: failed to initialize %s provider: %v\n", rp.name, err)
 		}
 	}
 
+	for _, opt := range opts {
+		opt(m)
+	}
+
 	if len(m.providers) == 0 {
 		return nil, fmt.Errorf("no LLM providers could be initialized")
 	}
 
+	if m.reproducibility.Enabled {
+		for _, name := range slices.Sorted(maps.Keys(m.providers)) {
+			if m.reproducibility.Models[name] == "" {
+				fmt.Printf("Warning: reproducibility is enabled but provider %s has no pinned model; its default may change between runs\n", name)
+			}
+		}
+	}
+
 	return m, nil
 }
 
-// Generate sends a prompt to the specified model or default.
+// initProvider creates a provider for every configured API key of name.
+// Several keys are combined into a KeyPool that rotates between them.
+func (m *Manager) initProvider(cfg *types.LLMConfig, name string, newProvider func(apiKey string) (Provider, error)) error {
+	envVar, exists := cfg.APIKeys[name]
+	if !exists {
+		return nil
+	}
+
+	keys := config.GetEnvValues(envVar)
+	if len(keys) == 0 {
+		return nil
+	}
+
+	providers := make([]Provider, 0, len(keys))
+	labels := make([]string, 0, len(keys))
+	for _, key := range keys {
+		provider, err := newProvider(key.Value)
+		if err != nil {
+			return err
+		}
+		providers = append(providers, provider)
+		labels = append(labels, key.Name)
+	}
+
+	if len(providers) == 1 {
+		m.providers[name] = providers[0]
+		m.keyNames[name] = labels[0]
+		return nil
+	}
+
+	m.providers[name] = NewKeyPool(name, providers, labels, cfg.KeyRotation)
+	return nil
+}
+
+// KeyStats returns per-key usage for providers configured with multiple API keys.
+func (m *Manager) KeyStats() map[string][]KeyStats {
+	stats := make(map[string][]KeyStats)
+	for name, provider := range m.providers {
+		if pool, ok := provider.(*KeyPool); ok {
+			stats[name] = pool.Stats()
+		}
+	}
+	return stats
+}
+
+// modelFamilies maps model name prefixes to the provider serving them, for
+// models whose names do not start with the provider name.
+var modelFamilies = map[string]string{
+	"gpt-": "openai",
+}
+
+// Generate sends a prompt to the specified model or default. The model is
+// either a provider name such as "claude", or a specific model served by a
+// provider such as "claude-3-5-sonnet" or "claude/claude-3-5-sonnet".
 func (m *Manager) Generate(ctx context.Context, model, prompt string, opts *GenerateOptions) (string, error) {
+	c, err := m.Complete(ctx, model, prompt, opts)
+	return c.Text, err
+}
+
+// Complete is Generate, reporting whether the output is still cut off at the
+// token limit once the configured continuations have run out.
+func (m *Manager) Complete(ctx context.Context, model, prompt string, opts *GenerateOptions) (Completion, error) {
 	if model == "" {
 		model = m.defaultModel
 	}
 
-	provider, ok := m.providers[model]
-	if !ok {
-		return "", fmt.Errorf("model %s not available", model)
+	// Don't start a request for a task that was already canceled
+	if ctx.Err() != nil {
+		return Completion{}, canceled(ctx, nil)
+	}
+
+	provider, providerModel, err := m.resolve(model)
+	if err != nil {
+		return Completion{}, err
+	}
+	if providerModel == "" && m.reproducibility.Enabled {
+		providerModel = m.reproducibility.Models[model]
+	}
+
+	if providerModel != "" || m.reproducibility.Enabled {
+		withModel := GenerateOptions{Temperature: 0.7, MaxTokens: 2048}
+		if opts != nil {
+			withModel = *opts
+		}
+		withModel.Model = providerModel
+		if m.reproducibility.Enabled {
+			withModel.Temperature = 0
+			withModel.Seed = new(m.reproducibility.Seed)
+		}
+		opts = &withModel
+	}
+
+	c, err := m.metered(ctx, model, provider, prompt, opts)
+	if err != nil {
+		if ctx.Err() != nil {
+			return Completion{}, canceled(ctx, err)
+		}
+		// Key pools name their keys; a provider with one key is named here
+		var providerErr *ProviderError
+		if errors.As(err, &providerErr) && providerErr.Key == "" {
+			providerErr.Key = m.keyNames[provider.Name()]
+		}
+		return Completion{}, err
 	}
+	return m.continueGeneration(ctx, model, provider, prompt, opts, c), nil
+}
+
+// canceled reports a generation cut short by its context. Provider SDKs do
+// not all wrap the context error, so the error carries the context's cause
+// for callers to tell cancellation from a provider failure.
+func canceled(ctx context.Context, err error) error {
+	code := errors.CodeCanceled
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		code = errors.CodeAgentTimeout
+	}
+	if err != nil {
+		return errors.Wrap(fmt.Errorf("%w: %w", context.Cause(ctx), err), code, "generation aborted")
+	}
+	return errors.Wrap(context.Cause(ctx), code, "generation aborted")
+}
+
+// Reproducibility returns the reproducibility settings generations follow.
+func (m *Manager) Reproducibility() types.ReproducibilityConfig {
+	return m.reproducibility
+}
+
+// Pinned returns the model a generation for model actually uses: with
+// reproducibility enabled, a provider name becomes "provider/version" for
+// the version it is pinned to. Other models are returned unchanged.
+func (m *Manager) Pinned(model string) string {
+	if model == "" {
+		model = m.defaultModel
+	}
+	if !m.reproducibility.Enabled {
+		return model
+	}
+	if version := m.reproducibility.Models[model]; version != "" {
+		if _, ok := m.providers[model]; ok {
+			return model + "/" + version
+		}
+	}
+	return model
+}
 
-	return provider.Generate(ctx, prompt, opts)
+// HasModel reports whether Generate can serve the model.
+func (m *Manager) HasModel(model string) bool {
+	_, _, err := m.resolve(model)
+	return err == nil
+}
+
+// resolve returns the provider for a model and, if the model names a specific
+// provider model rather than the provider itself, that model's name.
+func (m *Manager) resolve(model string) (Provider, string, error) {
+	if m.override != "" {
+		return m.providers[m.override], "", nil
+	}
+	if provider, ok := m.providers[model]; ok {
+		return provider, "", nil
+	}
+
+	if name, specific, ok := strings.Cut(model, "/"); ok {
+		if provider, ok := m.providers[name]; ok && specific != "" {
+			return provider, specific, nil
+		}
+	}
+
+	// Match in a fixed order so the same model always resolves to the same provider
+	for _, name := range slices.Sorted(maps.Keys(m.providers)) {
+		if strings.HasPrefix(model, name+"-") {
+			return m.providers[name], model, nil
+		}
+	}
+	for _, prefix := range slices.Sorted(maps.Keys(modelFamilies)) {
+		if provider, ok := m.providers[modelFamilies[prefix]]; ok && strings.HasPrefix(model, prefix) {
+			return provider, model, nil
+		}
+	}
+
+	return nil, "", errors.Newf(errors.CodeLLMUnavailable, "model %s not available", model)
+}
+
+// Providers returns the names of the initialized providers, sorted.
+func (m *Manager) Providers() []string {
+	return slices.Sorted(maps.Keys(m.providers))
 }
 
 // GetProvider returns a specific provider.
 func (m *Manager) GetProvider(name string) (Provider, error) {
 	provider, ok := m.providers[name]
 	if !ok {
-		return nil, fmt.Errorf("provider %s not found", name)
+		return nil, errors.Newf(errors.CodeNotFound, "provider %s not found", name)
 	}
 	return provider, nil
 }
@@ -140,7 +325,7 @@ func (m *Manager) AddProvider(name string, provider Provider) {
 	m.providers[name] = provider
 }
 
-// Close closes all providers.
+// Close closes all providers and drops their pooled clients.
 func (m *Manager) Close() error {
 	for name, provider := range m.providers {
 		if closer, ok := provider.(interface{ Close() error }); ok {
@@ -149,5 +334,55 @@ func (m *Manager) Close() error {
 			}
 		}
 	}
-	return nil
+	return m.clients.Close()
+}
+
+// warmer is a provider whose clients can be created ahead of the first request.
+type warmer interface {
+	warm(ctx context.Context, model string) error
+}
+
+// Warm creates the provider clients of models ahead of their first request,
+// so the first task of every role does not pay for client setup. Models no
+// provider serves are skipped.
+func (m *Manager) Warm(ctx context.Context, models ...string) error {
+	var errs []error
+	models = slices.Clone(models)
+	for i, model := range models {
+		models[i] = cmp.Or(model, m.defaultModel)
+	}
+	slices.Sort(models)
+	for _, model := range slices.Compact(models) {
+		provider, specific, err := m.resolve(model)
+		if err != nil {
+			continue
+		}
+		if specific == "" && m.reproducibility.Enabled {
+			specific = m.reproducibility.Models[model]
+		}
+		if w, ok := provider.(warmer); ok {
+			if err := w.warm(ctx, specific); err != nil {
+				errs = append(errs, fmt.Errorf("failed to warm %s: %w", model, err))
+			}
+		}
+	}
+	return stderrors.Join(errs...)
+}
+
+// Clients returns the pool of provider clients shared by every agent using
+// the manager, or nil for a nil manager.
+func (m *Manager) Clients() *ClientPool {
+	if m == nil {
+		return nil
+	}
+	return m.clients
+}
+
+// Calls returns the log of recent calls made through the manager, or nil for
+// a nil manager.
+func (m *Manager) Calls() *CallLog {
+	if m == nil {
+		return nil
+	}
+	return m.calls
 }
@@ -0,0 +1,265 @@
+package llm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+func TestManagerResolvesModels(t *testing.T) {
+	m, err := NewManager(&types.LLMConfig{DefaultModel: "claude"},
+		WithProvider("claude", &fakeKeyProvider{name: "claude"}),
+		WithProvider("openai", &fakeKeyProvider{name: "openai"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		model    string
+		provider Provider
+		specific string
+	}{
+		{"claude", m.providers["claude"], ""},
+		{"claude-3-5-sonnet", m.providers["claude"], "claude-3-5-sonnet"},
+		{"claude/claude-3-opus", m.providers["claude"], "claude-3-opus"},
+		{"gpt-4o", m.providers["openai"], "gpt-4o"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			provider, specific, err := m.resolve(tt.model)
+			if err != nil || provider != tt.provider || specific != tt.specific {
+				t.Errorf("resolve(%q) = %v, %q, %v", tt.model, provider, specific, err)
+			}
+		})
+	}
+
+	if m.HasModel("gemini-2.0-flash") {
+		t.Error("Expected models of unconfigured providers to be unavailable")
+	}
+}
+
+func TestManagerContextWindow(t *testing.T) {
+	m, err := NewManager(&types.LLMConfig{DefaultModel: "claude", ContextWindows: map[string]int{"qwen": 131072}},
+		WithProvider("claude", &fakeKeyProvider{name: "claude"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		model string
+		want  int
+	}{
+		{"", 200000},
+		{"gemini-1.5-pro-002", 2097152},
+		{"gemini-2.0-flash", 1048576},
+		{"gpt-4-0613", 8192},
+		{"openai/gpt-4o", 128000},
+		{"qwen", 131072},
+		{"custom", DefaultContextWindow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			if got := m.ContextWindow(tt.model); got != tt.want {
+				t.Errorf("ContextWindow(%q) = %d, want %d", tt.model, got, tt.want)
+			}
+		})
+	}
+}
+
+// optionsProvider records the options of the last generation.
+type optionsProvider struct {
+	opts *GenerateOptions
+}
+
+func (p *optionsProvider) Generate(ctx context.Context, prompt string, opts *GenerateOptions) (string, error) {
+	p.opts = opts
+	return "ok", nil
+}
+
+func (p *optionsProvider) Name() string { return "claude" }
+
+func TestManagerReproducibility(t *testing.T) {
+	claude := &optionsProvider{}
+	m, err := NewManager(&types.LLMConfig{
+		DefaultModel: "claude",
+		Reproducibility: types.ReproducibilityConfig{
+			Enabled: true,
+			Seed:    42,
+			Models:  map[string]string{"claude": "claude-3-5-sonnet-20241022"},
+		},
+	}, WithProvider("claude", claude))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		model     string
+		specific  string
+		recorded  string
+		generated *GenerateOptions
+	}{
+		{"claude", "claude-3-5-sonnet-20241022", "claude/claude-3-5-sonnet-20241022", &GenerateOptions{Temperature: 0.7, MaxTokens: 100}},
+		{"", "claude-3-5-sonnet-20241022", "claude/claude-3-5-sonnet-20241022", nil},
+		{"claude-3-opus", "claude-3-opus", "claude-3-opus", &GenerateOptions{Temperature: 0.5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			if _, err := m.Generate(context.Background(), tt.model, "prompt", tt.generated); err != nil {
+				t.Fatal(err)
+			}
+			opts := claude.opts
+			if opts.Model != tt.specific || opts.Temperature != 0 || opts.Seed == nil || *opts.Seed != 42 {
+				t.Errorf("Expected %s at temperature 0 with seed 42, got %+v", tt.specific, opts)
+			}
+			if tt.generated != nil && tt.generated.Temperature == 0 {
+				t.Error("Expected the caller's options to be left unchanged")
+			}
+			if got := m.Pinned(tt.model); got != tt.recorded {
+				t.Errorf("Pinned(%q) = %q, want %q", tt.model, got, tt.recorded)
+			}
+		})
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		m, err := NewManager(&types.LLMConfig{DefaultModel: "claude"}, WithProvider("claude", claude))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := m.Generate(context.Background(), "claude", "prompt", &GenerateOptions{Temperature: 0.7}); err != nil {
+			t.Fatal(err)
+		}
+		if claude.opts.Temperature != 0.7 || claude.opts.Seed != nil {
+			t.Errorf("Expected options to pass through unchanged, got %+v", claude.opts)
+		}
+		if got := m.Pinned("claude"); got != "claude" {
+			t.Errorf("Pinned(claude) = %q, want claude", got)
+		}
+	})
+}
+
+func TestManagerUsage(t *testing.T) {
+	m, err := NewManager(&types.LLMConfig{
+		DefaultModel: "claude",
+		Prices:       map[string]types.ModelPrice{"claude": {Input: 3, Output: 15}, "claude-3-opus": {Input: 15, Output: 75}},
+	}, WithProvider("claude", &optionsProvider{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Cost", func(t *testing.T) {
+		tests := []struct {
+			model string
+			want  float64
+		}{
+			{"claude", 0.018},
+			{"claude/claude-3-opus", 0.09},
+			{"gpt-4o", 0},
+		}
+		for _, tt := range tests {
+			if got := m.Cost(tt.model, 1000, 1000); got < tt.want-1e-9 || got > tt.want+1e-9 {
+				t.Errorf("Cost(%q) = %v, want %v", tt.model, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("Meter", func(t *testing.T) {
+		meter := &Meter{}
+		ctx := WithMeter(context.Background(), meter)
+		for range 2 {
+			if _, err := m.Generate(ctx, "claude", "0123456789abcdef", &GenerateOptions{SystemPrompt: "12345678"}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		u := meter.Usage()
+		if u.Generations != 2 || u.PromptTokens != 12 || u.CompletionTokens != 2 || u.Cost <= 0 {
+			t.Errorf("Unexpected usage: %+v", u)
+		}
+	})
+
+	t.Run("Calls", func(t *testing.T) {
+		ctx := types.WithRunID(context.Background(), "run-calls")
+		if _, err := m.Generate(ctx, "claude/claude-3-opus", "0123456789abcdef", nil); err != nil {
+			t.Fatal(err)
+		}
+
+		calls := m.Calls().Calls("run-calls")
+		if len(calls) != 1 {
+			t.Fatalf("Expected 1 call of the run, got %+v", calls)
+		}
+		c := calls[0]
+		if c.Provider != "claude" || c.Model != "claude-3-opus" || c.Status != CallOK || c.PromptTokens != 4 || c.CompletionTokens != 1 || c.Slow {
+			t.Errorf("Unexpected call: %+v", c)
+		}
+	})
+}
+
+func TestCallLog(t *testing.T) {
+	t.Run("Ring", func(t *testing.T) {
+		l := NewCallLog(types.CallLogConfig{Keep: 3})
+		for i := range 5 {
+			l.record(Call{RunID: "run", Provider: "p", Model: "m", Latency: time.Duration(i+1) * time.Second})
+		}
+		calls := l.Calls("")
+		if len(calls) != 3 || calls[0].Latency != 3*time.Second || calls[2].Latency != 5*time.Second {
+			t.Errorf("Expected the 3 newest calls, oldest first, got %+v", calls)
+		}
+	})
+
+	t.Run("Slowest", func(t *testing.T) {
+		l := NewCallLog(types.CallLogConfig{SlowThreshold: 2 * time.Second})
+		l.record(Call{RunID: "a", Provider: "gemini", Model: "flash", Status: CallOK, Latency: time.Second, PromptTokens: 10})
+		l.record(Call{RunID: "a", Provider: "gemini", Model: "flash", Status: CallFailed, Latency: 3 * time.Second, PromptTokens: 20})
+		l.record(Call{RunID: "b", Provider: "claude", Model: "opus", Status: CallOK, Latency: 5 * time.Second})
+
+		slowest := l.Slowest("a", 5)
+		if len(slowest) != 2 || slowest[0].Latency != 3*time.Second || !slowest[0].Slow || slowest[1].Slow {
+			t.Errorf("Unexpected slowest calls of run a: %+v", slowest)
+		}
+		if slowest := l.Slowest("", 1); len(slowest) != 1 || slowest[0].Provider != "claude" {
+			t.Errorf("Expected the claude call to be the slowest, got %+v", slowest)
+		}
+
+		stats := l.Stats()
+		if len(stats) != 2 || stats[0].Provider != "claude" {
+			t.Fatalf("Expected stats sorted by provider, got %+v", stats)
+		}
+		gemini := stats[1]
+		if gemini.Calls != 2 || gemini.Failures != 1 || gemini.Slow != 1 || gemini.AverageLatency != 2*time.Second || gemini.MaxLatency != 3*time.Second || gemini.PromptTokens != 30 {
+			t.Errorf("Unexpected gemini stats: %+v", gemini)
+		}
+	})
+
+	t.Run("Nil", func(t *testing.T) {
+		var l *CallLog
+		l.record(Call{})
+		if l.Calls("") != nil || l.Slowest("", 3) != nil || len(l.Stats()) != 0 || l.SlowThreshold() != 0 {
+			t.Error("Expected a nil call log to record nothing")
+		}
+	})
+}
+
+func TestManagerOverride(t *testing.T) {
+	mock := NewMockProvider()
+	m, err := NewManager(&types.LLMConfig{DefaultModel: "claude"},
+		WithProvider("claude", &fakeKeyProvider{name: "claude"}),
+		WithOverride(ProviderMock, mock),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, model := range []string{"", "claude", "gpt-4o", "gemini-2.0-flash"} {
+		provider, _, err := m.resolve(model)
+		if err != nil || provider != mock {
+			t.Errorf("resolve(%q) = %v, %v, want the override", model, provider, err)
+		}
+	}
+	if _, err := m.Generate(context.Background(), "claude-3-opus", "prompt", nil); err != nil {
+		t.Errorf("Expected the override to serve every model, got %v", err)
+	}
+}
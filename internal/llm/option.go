@@ -0,0 +1,56 @@
+package llm
+
+import (
+	"net/http"
+	"time"
+)
+
+// Option configures a Manager at construction time.
+type Option func(*Manager)
+
+// WithProvider registers an additional provider under the given name.
+// Providers registered this way take precedence over ones built from API keys.
+func WithProvider(name string, provider Provider) Option {
+	return func(m *Manager) {
+		m.providers[name] = provider
+	}
+}
+
+// WithOverride registers provider under name and serves every model with it,
+// whatever the model names the agents are configured with. Demo instances
+// use it so that no configured paid provider is ever called.
+func WithOverride(name string, provider Provider) Option {
+	return func(m *Manager) {
+		m.providers[name] = provider
+		m.override = name
+		m.defaultModel = name
+	}
+}
+
+// WithDefaultModel overrides the configured default model.
+func WithDefaultModel(model string) Option {
+	return func(m *Manager) {
+		m.defaultModel = model
+	}
+}
+
+// RemoteOption configures a RemoteProvider at construction time.
+type RemoteOption func(*RemoteProvider)
+
+// WithHTTPClient sets the HTTP client used for remote requests.
+func WithHTTPClient(client *http.Client) RemoteOption {
+	return func(p *RemoteProvider) {
+		if client != nil {
+			p.httpClient = client
+		}
+	}
+}
+
+// WithRequestTimeout sets the timeout for each remote request. It bounds the
+// request's context, so an HTTP client shared with other providers is left
+// as it is. Zero disables the timeout.
+func WithRequestTimeout(timeout time.Duration) RemoteOption {
+	return func(p *RemoteProvider) {
+		p.timeout = timeout
+	}
+}
@@ -0,0 +1,130 @@
+package llm
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// OutputKind is the kind of deliverable a generation is expected to produce.
+type OutputKind string
+
+const (
+	OutputCode    OutputKind = "code"
+	OutputSpec    OutputKind = "spec"
+	OutputSummary OutputKind = "summary"
+)
+
+// Default output sizes in tokens, used when the configuration does not set them.
+const (
+	defaultCodeTokens    = 8192
+	defaultSpecTokens    = 4096
+	defaultSummaryTokens = 1024
+)
+
+// When stitching a continuation to the output so far, its start is compared
+// with the end of the output. Repeats shorter than minOverlap are taken as
+// coincidence.
+const (
+	minOverlap = 16
+	maxOverlap = 500
+)
+
+// continuationPrompt asks the model to carry on from where its output was cut off.
+const continuationPrompt = `%s
+
+Your previous response was cut off at the length limit. This is what you wrote so far:
+
+%s
+
+Continue exactly where it stopped, without repeating anything already written.`
+
+// outputHints are words by which a task asks for a deliverable other than
+// code. Tasks matching neither kind are expected to produce code.
+var outputHints = map[OutputKind][]string{
+	OutputSummary: {"summarize", "summarise", "summary", "overview", "explain", "explanation"},
+	OutputSpec:    {"spec", "specs", "specification", "documentation", "document", "readme", "rfc", "proposal", "architecture"},
+}
+
+// Completion is a generation with whether it stopped at the token limit.
+type Completion struct {
+	Text      string
+	Truncated bool
+}
+
+// Completer is implemented by providers that report whether a generation
+// stopped at its token limit, so Manager.Complete can continue it.
+type Completer interface {
+	Complete(ctx context.Context, prompt string, opts *GenerateOptions) (Completion, error)
+}
+
+// ClassifyOutput guesses the kind of deliverable a task asks for from its text.
+func ClassifyOutput(text string) OutputKind {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !('a' <= r && r <= 'z')
+	})
+
+	// Summaries win over specs: "summarize the architecture" asks for a summary
+	for _, kind := range []OutputKind{OutputSummary, OutputSpec} {
+		if slices.ContainsFunc(words, func(word string) bool { return slices.Contains(outputHints[kind], word) }) {
+			return kind
+		}
+	}
+	return OutputCode
+}
+
+// OutputTokens returns the token limit for generating a kind of output.
+func (m *Manager) OutputTokens(kind OutputKind) int {
+	switch kind {
+	case OutputSummary:
+		return cmp.Or(m.outputTokens.Summary, defaultSummaryTokens)
+	case OutputSpec:
+		return cmp.Or(m.outputTokens.Spec, defaultSpecTokens)
+	default:
+		return cmp.Or(m.outputTokens.Code, defaultCodeTokens)
+	}
+}
+
+// complete generates with a provider, reporting truncation if it can.
+func complete(ctx context.Context, provider Provider, prompt string, opts *GenerateOptions) (Completion, error) {
+	if c, ok := provider.(Completer); ok {
+		return c.Complete(ctx, prompt, opts)
+	}
+	text, err := provider.Generate(ctx, prompt, opts)
+	return Completion{Text: text}, err
+}
+
+// continueGeneration asks for the rest of a generation that stopped at its
+// token limit, up to the configured number of times, and stitches the parts
+// together. If a continuation fails the output so far is returned. Output
+// still cut off once the continuations run out is returned marked truncated,
+// with a warning.
+func (m *Manager) continueGeneration(ctx context.Context, model string, provider Provider, prompt string, opts *GenerateOptions, c Completion) Completion {
+	text := c.Text
+	for i := 0; c.Truncated && i < m.outputTokens.Continuations; i++ {
+		var err error
+		c, err = m.metered(ctx, model, provider, fmt.Sprintf(continuationPrompt, prompt, text), opts)
+		if err != nil {
+			fmt.Printf("Warning: failed to continue truncated generation: %v\n", err)
+			return Completion{Text: text, Truncated: true}
+		}
+		text = stitch(text, c.Text)
+	}
+	if c.Truncated {
+		fmt.Printf("Warning: generation by %s stopped at its token limit after %d continuation(s)\n", model, m.outputTokens.Continuations)
+	}
+	return Completion{Text: text, Truncated: c.Truncated}
+}
+
+// stitch appends a continuation to the output so far, dropping the start of
+// the continuation if it repeats the end of the output.
+func stitch(text, next string) string {
+	for n := min(len(text), len(next), maxOverlap); n >= minOverlap; n-- {
+		if strings.HasSuffix(text, next[:n]) {
+			return text + next[n:]
+		}
+	}
+	return text + next
+}
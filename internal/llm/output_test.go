@@ -0,0 +1,152 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kpango/BuildBureau/pkg/remoteagent"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// truncatingProvider returns its parts in order, all but the last cut off at the token limit.
+type truncatingProvider struct {
+	parts   []string
+	prompts []string
+}
+
+func (p *truncatingProvider) Complete(ctx context.Context, prompt string, opts *GenerateOptions) (Completion, error) {
+	p.prompts = append(p.prompts, prompt)
+	part := p.parts[0]
+	p.parts = p.parts[1:]
+	return Completion{Text: part, Truncated: len(p.parts) > 0}, nil
+}
+
+func (p *truncatingProvider) Generate(ctx context.Context, prompt string, opts *GenerateOptions) (string, error) {
+	c, err := p.Complete(ctx, prompt, opts)
+	return c.Text, err
+}
+
+func (p *truncatingProvider) Name() string { return "truncating" }
+
+func TestClassifyOutput(t *testing.T) {
+	tests := []struct {
+		text string
+		want OutputKind
+	}{
+		{"Implement the checkout handler", OutputCode},
+		{"Write the API specification for payments", OutputSpec},
+		{"Summarize the architecture for the client", OutputSummary},
+		{"Update README with setup steps", OutputSpec},
+	}
+	for _, tt := range tests {
+		if got := ClassifyOutput(tt.text); got != tt.want {
+			t.Errorf("ClassifyOutput(%q) = %s, want %s", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestOutputTokens(t *testing.T) {
+	defaults, err := NewManager(&types.LLMConfig{}, WithProvider("test", &truncatingProvider{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if defaults.OutputTokens(OutputCode) != 8192 || defaults.OutputTokens(OutputSpec) != 4096 || defaults.OutputTokens(OutputSummary) != 1024 {
+		t.Error("Unexpected default output sizes")
+	}
+
+	configured, err := NewManager(&types.LLMConfig{OutputTokens: types.OutputTokensConfig{Code: 16000}}, WithProvider("test", &truncatingProvider{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if configured.OutputTokens(OutputCode) != 16000 || configured.OutputTokens(OutputSummary) != 1024 {
+		t.Error("Expected configured sizes to override the defaults they set")
+	}
+}
+
+func TestContinuation(t *testing.T) {
+	ctx := context.Background()
+	newManager := func(t *testing.T, continuations int, provider Provider) *Manager {
+		t.Helper()
+		m, err := NewManager(&types.LLMConfig{OutputTokens: types.OutputTokensConfig{Continuations: continuations}}, WithProvider("test", provider))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	t.Run("StitchesParts", func(t *testing.T) {
+		provider := &truncatingProvider{parts: []string{
+			"func main() {\n\tfmt.Println(\"hello\")\n",
+			"\tfmt.Println(\"hello\")\n\tfmt.Println(\"world\")\n", // Repeats the last line
+			"}\n",
+		}}
+		m := newManager(t, 2, provider)
+
+		text, err := m.Generate(ctx, "test", "Write main", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "func main() {\n\tfmt.Println(\"hello\")\n\tfmt.Println(\"world\")\n}\n"; text != want {
+			t.Errorf("Expected stitched output %q, got %q", want, text)
+		}
+		if len(provider.prompts) != 3 || !strings.Contains(provider.prompts[1], "cut off") || !strings.Contains(provider.prompts[1], "Write main") {
+			t.Errorf("Expected continuation prompts carrying the request, got %q", provider.prompts)
+		}
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		provider := &truncatingProvider{parts: []string{"a", "b", "c", "d"}}
+		if text, _ := newManager(t, 2, provider).Generate(ctx, "test", "Write", nil); text != "abc" {
+			t.Errorf("Expected two continuations, got %q", text)
+		}
+	})
+
+	t.Run("MarksTruncated", func(t *testing.T) {
+		provider := &truncatingProvider{parts: []string{"a", "b", "c", "d"}}
+		c, err := newManager(t, 2, provider).Complete(ctx, "test", "Write", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Text != "abc" || !c.Truncated {
+			t.Errorf("Expected output cut off after two continuations to be marked truncated, got %+v", c)
+		}
+
+		provider = &truncatingProvider{parts: []string{"a", "b", "c"}}
+		if c, _ := newManager(t, 2, provider).Complete(ctx, "test", "Write", nil); c.Text != "abc" || c.Truncated {
+			t.Errorf("Expected output completed within the limit not to be marked truncated, got %+v", c)
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		provider := &truncatingProvider{parts: []string{"a", "b"}}
+		if text, _ := newManager(t, 0, provider).Generate(ctx, "test", "Write", nil); text != "a" {
+			t.Errorf("Expected no continuation, got %q", text)
+		}
+	})
+
+	t.Run("Remote", func(t *testing.T) {
+		var requests int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			resp := remoteagent.GenerateResponse{Result: "second half"}
+			if requests == 1 {
+				resp = remoteagent.GenerateResponse{Result: "first half, ", FinishReason: remoteagent.FinishLength}
+			}
+			_ = json.NewEncoder(w).Encode(resp)
+		}))
+		defer server.Close()
+
+		provider, err := NewRemoteProvider("remote", server.URL, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		text, err := newManager(t, 1, provider).Generate(ctx, "test", "Write", nil)
+		if err != nil || text != "first half, second half" {
+			t.Errorf("Expected the remote generation to be continued, got %q, %v", text, err)
+		}
+	})
+}
@@ -2,6 +2,7 @@ package llm
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -10,14 +11,24 @@ import (
 	"strings"
 	"time"
 
+	"github.com/kpango/BuildBureau/internal/errors"
+	"github.com/kpango/BuildBureau/pkg/remoteagent"
 	"github.com/liushuangls/go-anthropic/v2"
 	"github.com/sashabaranov/go-openai"
 	"google.golang.org/genai"
 )
 
+// Models providers use when none is specified.
+const (
+	defaultGeminiModel = "gemini-2.0-flash-exp"
+	defaultClaudeModel = "claude-3-5-sonnet-20241022"
+)
+
 // GeminiProvider implements the Provider interface for Google Gemini using the genai library.
 type GeminiProvider struct {
 	client *genai.Client
+	pool   *ClientPool // Supplies the client instead, if set
+	apiKey string
 	model  string
 }
 
@@ -37,12 +48,40 @@ func NewGeminiProvider(apiKey string) (*GeminiProvider, error) {
 
 	return &GeminiProvider{
 		client: client,
-		model:  "gemini-2.0-flash-exp", // Using latest flash model
+		model:  defaultGeminiModel,
 	}, nil
 }
 
+// newPooledGeminiProvider creates a Gemini provider that takes its client from pool.
+func newPooledGeminiProvider(pool *ClientPool, apiKey string) (*GeminiProvider, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("gemini API key is required")
+	}
+	return &GeminiProvider{pool: pool, apiKey: apiKey, model: defaultGeminiModel}, nil
+}
+
+// sdk returns the client to send a request for model with.
+func (p *GeminiProvider) sdk(ctx context.Context, model string) (*genai.Client, error) {
+	if p.pool == nil {
+		return p.client, nil
+	}
+	return p.pool.Gemini(ctx, model, p.apiKey)
+}
+
+// warm creates the pooled client for model ahead of its first request.
+func (p *GeminiProvider) warm(ctx context.Context, model string) error {
+	_, err := p.sdk(ctx, cmp.Or(model, p.model))
+	return err
+}
+
 // Generate sends a prompt to Gemini and returns the response.
 func (p *GeminiProvider) Generate(ctx context.Context, prompt string, opts *GenerateOptions) (string, error) {
+	c, err := p.Complete(ctx, prompt, opts)
+	return c.Text, err
+}
+
+// Complete is Generate, also reporting whether Gemini stopped at the token limit.
+func (p *GeminiProvider) Complete(ctx context.Context, prompt string, opts *GenerateOptions) (Completion, error) {
 	if opts == nil {
 		opts = &GenerateOptions{
 			Temperature: 0.7,
@@ -63,6 +102,9 @@ func (p *GeminiProvider) Generate(ctx context.Context, prompt string, opts *Gene
 		Temperature:     &temp,
 		MaxOutputTokens: maxTokens,
 	}
+	if opts.Seed != nil {
+		config.Seed = new(int32(*opts.Seed))
+	}
 
 	// Add system instruction if provided
 	if opts.SystemPrompt != "" {
@@ -71,15 +113,24 @@ func (p *GeminiProvider) Generate(ctx context.Context, prompt string, opts *Gene
 		}
 	}
 
+	model := p.model
+	if opts.Model != "" {
+		model = opts.Model
+	}
+
 	// Generate content
-	resp, err := p.client.Models.GenerateContent(ctx, p.model, []*genai.Content{userContent}, config)
+	client, err := p.sdk(ctx, model)
 	if err != nil {
-		return "", fmt.Errorf("failed to generate content: %w", err)
+		return Completion{}, err
+	}
+	resp, err := client.Models.GenerateContent(ctx, model, []*genai.Content{userContent}, config)
+	if err != nil {
+		return Completion{}, providerError("gemini", model, err, "failed to generate content")
 	}
 
 	// Extract text from response
-	if len(resp.Candidates) == 0 {
-		return "", fmt.Errorf("no candidates in response")
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+		return Completion{}, fmt.Errorf("no candidates in response")
 	}
 
 	var responseText strings.Builder
@@ -90,10 +141,13 @@ func (p *GeminiProvider) Generate(ctx context.Context, prompt string, opts *Gene
 	}
 
 	if responseText.Len() == 0 {
-		return "", fmt.Errorf("empty response from Gemini")
+		return Completion{}, fmt.Errorf("empty response from Gemini")
 	}
 
-	return responseText.String(), nil
+	return Completion{
+		Text:      responseText.String(),
+		Truncated: resp.Candidates[0].FinishReason == genai.FinishReasonMaxTokens,
+	}, nil
 }
 
 // Name returns the provider name.
@@ -110,48 +164,50 @@ func (p *GeminiProvider) Close() error {
 
 // RemoteProvider implements the Provider interface for remote LLM services
 // This is used for Claude, Codex, and Qwen via the Remote Agent API.
+// The wire protocol is defined in pkg/remoteagent.
 type RemoteProvider struct {
 	httpClient *http.Client
 	name       string
 	endpoint   string
 	apiKey     string
+	timeout    time.Duration
 }
 
 // RemoteGenerateRequest represents the request to a remote LLM service.
-type RemoteGenerateRequest struct {
-	Prompt       string  `json:"prompt"`
-	Model        string  `json:"model,omitempty"`
-	SystemPrompt string  `json:"system_prompt,omitempty"`
-	Temperature  float64 `json:"temperature,omitempty"`
-	MaxTokens    int     `json:"max_tokens,omitempty"`
-}
+type RemoteGenerateRequest = remoteagent.GenerateRequest
 
 // RemoteGenerateResponse represents the response from a remote LLM service.
-type RemoteGenerateResponse struct {
-	Result string         `json:"result"`
-	Model  string         `json:"model,omitempty"`
-	Usage  map[string]any `json:"usage,omitempty"`
-	Error  string         `json:"error,omitempty"`
-}
+type RemoteGenerateResponse = remoteagent.GenerateResponse
 
 // NewRemoteProvider creates a new remote provider.
-func NewRemoteProvider(name, endpoint, apiKey string) (*RemoteProvider, error) {
+func NewRemoteProvider(name, endpoint, apiKey string, opts ...RemoteOption) (*RemoteProvider, error) {
 	if endpoint == "" {
 		return nil, fmt.Errorf("endpoint is required for remote provider")
 	}
 
-	return &RemoteProvider{
-		name:     name,
-		endpoint: endpoint,
-		apiKey:   apiKey,
-		httpClient: &http.Client{
-			Timeout: 60 * time.Second,
-		},
-	}, nil
+	p := &RemoteProvider{
+		name:       name,
+		endpoint:   endpoint,
+		apiKey:     apiKey,
+		httpClient: &http.Client{},
+		timeout:    60 * time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p, nil
 }
 
 // Generate sends a prompt to the remote provider via HTTP.
 func (p *RemoteProvider) Generate(ctx context.Context, prompt string, opts *GenerateOptions) (string, error) {
+	c, err := p.Complete(ctx, prompt, opts)
+	return c.Text, err
+}
+
+// Complete is Generate, also reporting whether the backend stopped at the token limit.
+func (p *RemoteProvider) Complete(ctx context.Context, prompt string, opts *GenerateOptions) (Completion, error) {
 	if opts == nil {
 		opts = &GenerateOptions{
 			Temperature: 0.7,
@@ -159,28 +215,41 @@ func (p *RemoteProvider) Generate(ctx context.Context, prompt string, opts *Gene
 		}
 	}
 
+	model := p.name
+	if opts.Model != "" {
+		model = opts.Model
+	}
+
 	// Create request body
 	reqBody := RemoteGenerateRequest{
 		Prompt:       prompt,
-		Model:        p.name,
+		Model:        model,
 		Temperature:  opts.Temperature,
 		MaxTokens:    opts.MaxTokens,
 		SystemPrompt: opts.SystemPrompt,
+		Seed:         opts.Seed,
 	}
 
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return Completion{}, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
 	}
 
 	// Create HTTP request
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint+"/v1/generate", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint+remoteagent.PathGenerate, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return Completion{}, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Add headers
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(remoteagent.HeaderVersion, strings.Join(remoteagent.SupportedVersions, ", "))
 	if p.apiKey != "" {
 		req.Header.Set("Authorization", "Bearer "+p.apiKey)
 	}
@@ -188,31 +257,43 @@ func (p *RemoteProvider) Generate(ctx context.Context, prompt string, opts *Gene
 	// Send request
 	resp, err := p.httpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
+		return Completion{}, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// Check status code
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("remote provider returned status %d: %s", resp.StatusCode, string(body))
+		code := errors.CodeLLMFailed
+		switch resp.StatusCode {
+		case http.StatusTooManyRequests:
+			code = errors.CodeLLMRateLimit
+		case http.StatusServiceUnavailable:
+			code = errors.CodeLLMUnavailable
+		}
+		message := string(body)
+		var errResp remoteagent.ErrorResponse
+		if json.Unmarshal(body, &errResp) == nil && errResp.Error != "" {
+			message = errResp.Error
+		}
+		return Completion{}, errors.Newf(code, "remote provider returned status %d: %s", resp.StatusCode, message)
 	}
 
 	// Parse response
 	var result RemoteGenerateResponse
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
+		return Completion{}, fmt.Errorf("failed to decode response: %w", err)
 	}
 
 	if result.Error != "" {
-		return "", fmt.Errorf("remote provider error: %s", result.Error)
+		return Completion{}, fmt.Errorf("remote provider error: %s", result.Error)
 	}
 
 	if result.Result == "" {
-		return "", fmt.Errorf("empty result from remote provider")
+		return Completion{}, fmt.Errorf("empty result from remote provider")
 	}
 
-	return result.Result, nil
+	return Completion{Text: result.Result, Truncated: result.FinishReason == remoteagent.FinishLength}, nil
 }
 
 // Name returns the provider name.
@@ -229,6 +310,8 @@ func (p *RemoteProvider) Close() error {
 // OpenAIProvider implements the Provider interface for OpenAI using the official SDK.
 type OpenAIProvider struct {
 	client *openai.Client
+	pool   *ClientPool // Supplies the client instead, if set
+	apiKey string
 	model  string
 }
 
@@ -251,8 +334,36 @@ func NewOpenAIProvider(apiKey string, model string) (*OpenAIProvider, error) {
 	}, nil
 }
 
+// newPooledOpenAIProvider creates an OpenAI provider that takes its client from pool.
+func newPooledOpenAIProvider(pool *ClientPool, apiKey, model string) (*OpenAIProvider, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("OpenAI API key is required")
+	}
+	return &OpenAIProvider{pool: pool, apiKey: apiKey, model: cmp.Or(model, openai.GPT4TurboPreview)}, nil
+}
+
+// sdk returns the client to send a request for model with.
+func (p *OpenAIProvider) sdk(model string) *openai.Client {
+	if p.pool == nil {
+		return p.client
+	}
+	return p.pool.OpenAI(model, p.apiKey)
+}
+
+// warm creates the pooled client for model ahead of its first request.
+func (p *OpenAIProvider) warm(ctx context.Context, model string) error {
+	p.sdk(cmp.Or(model, p.model))
+	return nil
+}
+
 // Generate sends a prompt to OpenAI and returns the response.
 func (p *OpenAIProvider) Generate(ctx context.Context, prompt string, opts *GenerateOptions) (string, error) {
+	c, err := p.Complete(ctx, prompt, opts)
+	return c.Text, err
+}
+
+// Complete is Generate, also reporting whether OpenAI stopped at the token limit.
+func (p *OpenAIProvider) Complete(ctx context.Context, prompt string, opts *GenerateOptions) (Completion, error) {
 	if opts == nil {
 		opts = &GenerateOptions{
 			Temperature: 0.7,
@@ -277,23 +388,32 @@ func (p *OpenAIProvider) Generate(ctx context.Context, prompt string, opts *Gene
 		}, messages...)
 	}
 
+	model := p.model
+	if opts.Model != "" {
+		model = opts.Model
+	}
+
 	req := openai.ChatCompletionRequest{
-		Model:       p.model,
+		Model:       model,
 		Messages:    messages,
 		Temperature: float32(opts.Temperature),
 		MaxTokens:   opts.MaxTokens,
+		Seed:        opts.Seed,
 	}
 
-	resp, err := p.client.CreateChatCompletion(ctx, req)
+	resp, err := p.sdk(model).CreateChatCompletion(ctx, req)
 	if err != nil {
-		return "", fmt.Errorf("failed to create chat completion: %w", err)
+		return Completion{}, providerError("openai", model, err, "failed to create chat completion")
 	}
 
 	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("no choices in response")
+		return Completion{}, fmt.Errorf("no choices in response")
 	}
 
-	return resp.Choices[0].Message.Content, nil
+	return Completion{
+		Text:      resp.Choices[0].Message.Content,
+		Truncated: resp.Choices[0].FinishReason == openai.FinishReasonLength,
+	}, nil
 }
 
 // Name returns the provider name.
@@ -310,6 +430,8 @@ func (p *OpenAIProvider) Close() error {
 // ClaudeProvider implements the Provider interface for Anthropic Claude using the official SDK.
 type ClaudeProvider struct {
 	client *anthropic.Client
+	pool   *ClientPool // Supplies the client instead, if set
+	apiKey string
 	model  string
 }
 
@@ -323,7 +445,7 @@ func NewClaudeProvider(apiKey string, model string) (*ClaudeProvider, error) {
 
 	// Default to Claude 3.5 Sonnet if no model specified
 	if model == "" {
-		model = "claude-3-5-sonnet-20241022"
+		model = defaultClaudeModel
 	}
 
 	return &ClaudeProvider{
@@ -332,8 +454,36 @@ func NewClaudeProvider(apiKey string, model string) (*ClaudeProvider, error) {
 	}, nil
 }
 
+// newPooledClaudeProvider creates a Claude provider that takes its client from pool.
+func newPooledClaudeProvider(pool *ClientPool, apiKey, model string) (*ClaudeProvider, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("claude API key is required")
+	}
+	return &ClaudeProvider{pool: pool, apiKey: apiKey, model: cmp.Or(model, defaultClaudeModel)}, nil
+}
+
+// sdk returns the client to send a request for model with.
+func (p *ClaudeProvider) sdk(model string) *anthropic.Client {
+	if p.pool == nil {
+		return p.client
+	}
+	return p.pool.Claude(model, p.apiKey)
+}
+
+// warm creates the pooled client for model ahead of its first request.
+func (p *ClaudeProvider) warm(ctx context.Context, model string) error {
+	p.sdk(cmp.Or(model, p.model))
+	return nil
+}
+
 // Generate sends a prompt to Claude and returns the response.
 func (p *ClaudeProvider) Generate(ctx context.Context, prompt string, opts *GenerateOptions) (string, error) {
+	c, err := p.Complete(ctx, prompt, opts)
+	return c.Text, err
+}
+
+// Complete is Generate, also reporting whether Claude stopped at the token limit.
+func (p *ClaudeProvider) Complete(ctx context.Context, prompt string, opts *GenerateOptions) (Completion, error) {
 	if opts == nil {
 		opts = &GenerateOptions{
 			Temperature: 0.7,
@@ -341,8 +491,13 @@ func (p *ClaudeProvider) Generate(ctx context.Context, prompt string, opts *Gene
 		}
 	}
 
+	model := p.model
+	if opts.Model != "" {
+		model = opts.Model
+	}
+
 	req := anthropic.MessagesRequest{
-		Model:       anthropic.Model(p.model),
+		Model:       anthropic.Model(model),
 		MaxTokens:   opts.MaxTokens,
 		Temperature: new(float32(opts.Temperature)),
 		Messages: []anthropic.Message{
@@ -360,13 +515,13 @@ func (p *ClaudeProvider) Generate(ctx context.Context, prompt string, opts *Gene
 		req.System = opts.SystemPrompt
 	}
 
-	resp, err := p.client.CreateMessages(ctx, req)
+	resp, err := p.sdk(model).CreateMessages(ctx, req)
 	if err != nil {
-		return "", fmt.Errorf("failed to create message: %w", err)
+		return Completion{}, providerError("claude", model, err, "failed to create message")
 	}
 
 	if len(resp.Content) == 0 {
-		return "", fmt.Errorf("no content in response")
+		return Completion{}, fmt.Errorf("no content in response")
 	}
 
 	// Extract text from content blocks
@@ -378,10 +533,10 @@ func (p *ClaudeProvider) Generate(ctx context.Context, prompt string, opts *Gene
 	}
 
 	if result.Len() == 0 {
-		return "", fmt.Errorf("empty response from Claude")
+		return Completion{}, fmt.Errorf("empty response from Claude")
 	}
 
-	return result.String(), nil
+	return Completion{Text: result.String(), Truncated: resp.StopReason == anthropic.MessagesStopReasonMaxTokens}, nil
 }
 
 // Name returns the provider name.
@@ -3,9 +3,19 @@ package llm
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/kpango/BuildBureau/internal/errors"
+	"github.com/kpango/BuildBureau/pkg/types"
+	"github.com/liushuangls/go-anthropic/v2"
+	"github.com/sashabaranov/go-openai"
+	"google.golang.org/genai"
 )
 
 func TestRemoteProvider_Generate(t *testing.T) {
@@ -130,3 +140,236 @@ func TestNewRemoteProvider_NoEndpoint(t *testing.T) {
 		t.Error("Expected error when endpoint is empty, got nil")
 	}
 }
+
+func TestRemoteProviderRequestTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+		json.NewEncoder(w).Encode(RemoteGenerateResponse{Result: "late"})
+	}))
+	defer server.Close()
+
+	shared := &http.Client{}
+	fast, _ := NewRemoteProvider("fast", server.URL, "", WithHTTPClient(shared), WithRequestTimeout(50*time.Millisecond))
+	patient, _ := NewRemoteProvider("patient", server.URL, "", WithHTTPClient(shared), WithRequestTimeout(5*time.Second))
+
+	if shared.Timeout != 0 {
+		t.Errorf("Expected the shared client left unchanged, got timeout %v", shared.Timeout)
+	}
+	if _, err := fast.Generate(context.Background(), "hi", nil); err == nil {
+		t.Error("Expected the request to time out")
+	}
+	if result, err := patient.Generate(context.Background(), "hi", nil); err != nil || result != "late" {
+		t.Errorf("Expected the other provider's timeout to apply, got %q, %v", result, err)
+	}
+}
+
+func TestProvidersAbortOnCancel(t *testing.T) {
+	// blockingServer accepts a generation request and holds it until the client goes away
+	blockingServer := func(t *testing.T) (string, <-chan struct{}, <-chan struct{}) {
+		t.Helper()
+		received := make(chan struct{}, 1)
+		aborted := make(chan struct{}, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// The server notices the client going away only once the body is read
+			_, _ = io.Copy(io.Discard, r.Body)
+			select {
+			case received <- struct{}{}:
+			default:
+			}
+			select {
+			case <-r.Context().Done():
+				aborted <- struct{}{}
+			case <-time.After(10 * time.Second):
+			}
+		}))
+		t.Cleanup(server.Close)
+		return server.URL, received, aborted
+	}
+
+	providers := map[string]func(t *testing.T, url string) Provider{
+		"remote": func(t *testing.T, url string) Provider {
+			p, err := NewRemoteProvider("remote", url, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			return p
+		},
+		"openai": func(t *testing.T, url string) Provider {
+			cfg := openai.DefaultConfig("test-key")
+			cfg.BaseURL = url + "/v1"
+			return &OpenAIProvider{client: openai.NewClientWithConfig(cfg), model: "gpt-4o"}
+		},
+		"claude": func(t *testing.T, url string) Provider {
+			return &ClaudeProvider{client: anthropic.NewClient("test-key", anthropic.WithBaseURL(url)), model: "claude-3-5-sonnet-20241022"}
+		},
+		"gemini": func(t *testing.T, url string) Provider {
+			client, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+				APIKey:      "test-key",
+				Backend:     genai.BackendGeminiAPI,
+				HTTPOptions: genai.HTTPOptions{BaseURL: url},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return &GeminiProvider{client: client, model: "gemini-2.0-flash"}
+		},
+	}
+
+	for name, newProvider := range providers {
+		t.Run(name, func(t *testing.T) {
+			url, received, aborted := blockingServer(t)
+			manager, err := NewManager(&types.LLMConfig{}, WithProvider(name, newProvider(t, url)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cause := fmt.Errorf("generation no longer needed")
+			ctx, cancel := context.WithCancelCause(context.Background())
+			result := make(chan error, 1)
+			go func() {
+				_, err := manager.Generate(ctx, name, "Write a todo app", nil)
+				result <- err
+			}()
+
+			select {
+			case <-received:
+			case <-time.After(5 * time.Second):
+				t.Fatal("Provider never sent the request")
+			}
+			cancel(cause)
+
+			select {
+			case err := <-result:
+				if !errors.Is(err, cause) || errors.CodeOf(err) != errors.CodeCanceled {
+					t.Errorf("Expected a canceled error carrying the cause, got %v", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Generate did not return after cancellation")
+			}
+			select {
+			case <-aborted:
+			case <-time.After(5 * time.Second):
+				t.Error("Expected the HTTP request to be aborted")
+			}
+		})
+	}
+
+	t.Run("AlreadyCanceled", func(t *testing.T) {
+		url, received, _ := blockingServer(t)
+		manager, err := NewManager(&types.LLMConfig{}, WithProvider("remote", providers["remote"](t, url)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := manager.Generate(ctx, "remote", "Write a todo app", nil); !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+		select {
+		case <-received:
+			t.Error("Expected no request for a canceled context")
+		default:
+		}
+	})
+}
+
+func TestProviderError(t *testing.T) {
+	now := time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("GeminiDailyQuota", func(t *testing.T) {
+		apiErr := genai.APIError{
+			Code:    http.StatusTooManyRequests,
+			Status:  "RESOURCE_EXHAUSTED",
+			Message: "You exceeded your current quota, please check your plan and billing details.",
+			Details: []map[string]any{
+				{"@type": "type.googleapis.com/google.rpc.QuotaFailure", "violations": []any{
+					map[string]any{"quotaId": "GenerateRequestsPerDayPerProjectPerModel-FreeTier"},
+				}},
+				{"@type": "type.googleapis.com/google.rpc.RetryInfo", "retryDelay": "42s"},
+			},
+		}
+		e := newProviderError("gemini", "gemini-2.0-flash", fmt.Errorf("generate: %w", apiErr), now)
+		if e == nil || e.Kind != ProviderQuota {
+			t.Fatalf("Expected a quota error, got %+v", e)
+		}
+		if !e.ResetAt.Equal(now.Add(42 * time.Second)) {
+			t.Errorf("Expected the quota to reset after the retry delay, got %v", e.ResetAt)
+		}
+		e.Key = "GEMINI_API_KEY"
+		want := "Gemini quota exhausted for API key GEMINI_API_KEY on model gemini-2.0-flash, resets at 12:00:42 UTC"
+		if !strings.HasPrefix(e.Error(), want) {
+			t.Errorf("Expected message to start with %q, got %q", want, e.Error())
+		}
+		if errors.CodeOf(e) != errors.CodeLLMRateLimit || !isQuotaError(e) {
+			t.Errorf("Expected %s, got %s", errors.CodeLLMRateLimit, errors.CodeOf(e))
+		}
+	})
+
+	t.Run("OpenAI", func(t *testing.T) {
+		for _, tt := range []struct {
+			name  string
+			err   error
+			kind  ProviderErrorKind
+			code  errors.Code
+			reset time.Duration
+		}{
+			{"Quota", &openai.APIError{HTTPStatusCode: 429, Code: "insufficient_quota", Type: "insufficient_quota", Message: "You exceeded your current quota."}, ProviderQuota, errors.CodeLLMRateLimit, 0},
+			{"RateLimit", &openai.APIError{HTTPStatusCode: 429, Code: "rate_limit_exceeded", Message: "Rate limit reached for gpt-4o. Please try again in 1m30s."}, ProviderRateLimit, errors.CodeLLMRateLimit, 90 * time.Second},
+			{"Auth", &openai.APIError{HTTPStatusCode: 401, Code: "invalid_api_key", Message: "Incorrect API key provided."}, ProviderAuth, errors.CodeLLMAuth, 0},
+			{"ModelNotFound", &openai.APIError{HTTPStatusCode: 404, Code: "model_not_found", Message: "The model `gpt-9` does not exist."}, ProviderModelNotFound, errors.CodeLLMUnavailable, 0},
+			{"Request", &openai.RequestError{HTTPStatusCode: 429, Err: fmt.Errorf("too many requests")}, ProviderRateLimit, errors.CodeLLMRateLimit, 0},
+		} {
+			t.Run(tt.name, func(t *testing.T) {
+				e := newProviderError("openai", "gpt-4o", tt.err, now)
+				if e == nil || e.Kind != tt.kind {
+					t.Fatalf("Expected %s, got %+v", tt.kind, e)
+				}
+				if errors.CodeOf(e) != tt.code {
+					t.Errorf("Expected %s, got %s", tt.code, errors.CodeOf(e))
+				}
+				if tt.reset > 0 && !e.ResetAt.Equal(now.Add(tt.reset)) {
+					t.Errorf("Expected reset after %v, got %v", tt.reset, e.ResetAt)
+				}
+			})
+		}
+	})
+
+	t.Run("Anthropic", func(t *testing.T) {
+		for _, tt := range []struct {
+			name string
+			err  error
+			kind ProviderErrorKind
+			code errors.Code
+		}{
+			{"RateLimit", &anthropic.APIError{Type: anthropic.ErrTypeRateLimit, Message: "Number of request tokens has exceeded your per-minute rate limit"}, ProviderRateLimit, errors.CodeLLMRateLimit},
+			{"Auth", &anthropic.APIError{Type: anthropic.ErrTypeAuthentication, Message: "invalid x-api-key"}, ProviderAuth, errors.CodeLLMAuth},
+			{"ModelNotFound", &anthropic.APIError{Type: anthropic.ErrTypeNotFound, Message: "model: claude-9"}, ProviderModelNotFound, errors.CodeLLMUnavailable},
+			{"Request", &anthropic.RequestError{StatusCode: 429, Err: fmt.Errorf("too many requests")}, ProviderRateLimit, errors.CodeLLMRateLimit},
+		} {
+			t.Run(tt.name, func(t *testing.T) {
+				e := newProviderError("claude", "claude-3-5-sonnet", fmt.Errorf("create message: %w", tt.err), now)
+				if e == nil || e.Kind != tt.kind {
+					t.Fatalf("Expected %s, got %+v", tt.kind, e)
+				}
+				if errors.CodeOf(e) != tt.code {
+					t.Errorf("Expected %s, got %s", tt.code, errors.CodeOf(e))
+				}
+			})
+		}
+
+		if e := newProviderError("claude", "claude-3-5-sonnet", &anthropic.APIError{Type: anthropic.ErrTypeOverloaded, Message: "Overloaded"}, now); e != nil {
+			t.Errorf("Expected overloaded errors not to concern the key, got %+v", e)
+		}
+	})
+
+	t.Run("Unrecognized", func(t *testing.T) {
+		err := providerError("openai", "gpt-4o", fmt.Errorf("connection reset"), "failed to create chat completion")
+		if err.Error() != "failed to create chat completion: connection reset" {
+			t.Errorf("Expected unrecognized errors to be wrapped, got %q", err)
+		}
+	})
+}
@@ -0,0 +1,122 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// Usage is what a set of generations cost. Token counts are estimates.
+type Usage struct {
+	Cost             float64 // In USD, 0 for models without a configured price
+	PromptTokens     int
+	CompletionTokens int
+	Generations      int
+	Retries          int // Requests retried on another API key
+}
+
+// Meter accumulates the usage of generations made with a context carrying it.
+// It is safe for concurrent use; a nil Meter records nothing.
+type Meter struct {
+	mu    sync.Mutex
+	usage Usage
+}
+
+type meterKey struct{}
+
+// WithMeter returns a context whose generations are recorded by m.
+func WithMeter(ctx context.Context, m *Meter) context.Context {
+	return context.WithValue(ctx, meterKey{}, m)
+}
+
+// meterFromContext returns the meter recording the context's generations, or nil.
+func meterFromContext(ctx context.Context) *Meter {
+	m, _ := ctx.Value(meterKey{}).(*Meter)
+	return m
+}
+
+// Usage returns the usage recorded so far.
+func (m *Meter) Usage() Usage {
+	if m == nil {
+		return Usage{}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.usage
+}
+
+// add records usage.
+func (m *Meter) add(u Usage) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.usage.Cost += u.Cost
+	m.usage.PromptTokens += u.PromptTokens
+	m.usage.CompletionTokens += u.CompletionTokens
+	m.usage.Generations += u.Generations
+	m.usage.Retries += u.Retries
+}
+
+// Cost returns the price of a generation with the given token counts, or 0
+// if no price is configured for the model. For "provider/model" the specific
+// model's price is used, falling back to the provider's.
+func (m *Manager) Cost(model string, promptTokens, completionTokens int) float64 {
+	if model == "" {
+		model = m.defaultModel
+	}
+
+	candidates := []string{model}
+	if provider, specific, ok := strings.Cut(model, "/"); ok {
+		candidates = []string{specific, provider}
+	}
+	for _, candidate := range candidates {
+		if price, ok := longestPrefix(m.prices, candidate); ok {
+			return (float64(promptTokens)*price.Input + float64(completionTokens)*price.Output) / 1_000_000
+		}
+	}
+	return 0
+}
+
+// metered generates with a provider, records the generation with the
+// context's meter and logs the call.
+func (m *Manager) metered(ctx context.Context, model string, provider Provider, prompt string, opts *GenerateOptions) (Completion, error) {
+	start := time.Now()
+	c, err := complete(ctx, provider, prompt, opts)
+	latency := time.Since(start)
+
+	promptTokens := EstimateTokens(prompt)
+	if opts != nil {
+		promptTokens += EstimateTokens(opts.SystemPrompt)
+	}
+	completionTokens := EstimateTokens(c.Text)
+	meterFromContext(ctx).add(Usage{
+		Cost:             m.Cost(model, promptTokens, completionTokens),
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		Generations:      1,
+	})
+
+	call := Call{
+		Time:             start,
+		RunID:            types.RunIDFromContext(ctx),
+		Provider:         provider.Name(),
+		Model:            model,
+		Status:           callStatus(ctx, err),
+		Latency:          latency,
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+	}
+	if opts != nil && opts.Model != "" {
+		call.Model = opts.Model
+	}
+	if err != nil {
+		call.Error = err.Error()
+	}
+	m.calls.record(call)
+	return c, err
+}
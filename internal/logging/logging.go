@@ -0,0 +1,212 @@
+// Package logging writes per-agent activity logs, one rotated JSON-lines file
+// per agent, and reads them back for debugging.
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+const (
+	defaultDir        = "logs"
+	defaultMaxSizeMB  = 10
+	defaultMaxBackups = 3
+)
+
+// Kinds of log entries.
+const (
+	KindPrompt   = "prompt"   // Prompt sent to the LLM
+	KindResponse = "response" // LLM response
+	KindError    = "error"    // Failed generation or delegation
+	KindEvent    = "event"    // Other agent activity, such as delegation and re-planning
+)
+
+// Entry is one line of an agent's activity log.
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Agent   string    `json:"agent"`
+	Role    string    `json:"role,omitempty"`
+	Kind    string    `json:"kind"`
+	RunID   string    `json:"run_id,omitempty"`
+	Model   string    `json:"model,omitempty"`
+	Content string    `json:"content"`
+}
+
+// Manager owns the log files of every agent in an organization.
+type Manager struct {
+	files         map[string]*RotatingFile
+	dir           string
+	maxSize       int64
+	maxBackups    int
+	redactPrompts bool
+	mu            sync.Mutex
+}
+
+// NewManager creates a Manager writing to cfg.Dir, applying defaults.
+func NewManager(cfg types.LoggingConfig) (*Manager, error) {
+	m := &Manager{
+		files:         make(map[string]*RotatingFile),
+		dir:           cfg.Dir,
+		maxSize:       int64(cfg.MaxSizeMB) << 20,
+		maxBackups:    cfg.MaxBackups,
+		redactPrompts: cfg.RedactPrompts,
+	}
+
+	if m.dir == "" {
+		m.dir = defaultDir
+	}
+	if m.maxSize <= 0 {
+		m.maxSize = defaultMaxSizeMB << 20
+	}
+	if m.maxBackups <= 0 {
+		m.maxBackups = defaultMaxBackups
+	}
+
+	if err := os.MkdirAll(m.dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	return m, nil
+}
+
+// Log appends an entry to the agent's log. Failures are reported as warnings
+// so logging never interrupts the agent's work.
+func (m *Manager) Log(agentID string, entry Entry) {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	entry.Agent = agentID
+	if entry.Kind == KindPrompt && m.redactPrompts {
+		entry.Content = fmt.Sprintf("[redacted %d bytes]", len(entry.Content))
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		fmt.Printf("Warning: failed to encode log entry for %s: %v\n", agentID, err)
+		return
+	}
+
+	file, err := m.file(agentID)
+	if err != nil {
+		fmt.Printf("Warning: failed to open log for %s: %v\n", agentID, err)
+		return
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		fmt.Printf("Warning: failed to write log for %s: %v\n", agentID, err)
+	}
+}
+
+// Tail returns up to the last n entries of the agent's log, oldest first,
+// reading into rotated files when the current one is shorter than n.
+func (m *Manager) Tail(agentID string, n int) ([]Entry, error) {
+	path, err := m.path(agentID)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for i := 0; i <= m.maxBackups && len(entries) < n; i++ {
+		p := path
+		if i > 0 {
+			p = backupPath(path, i)
+		}
+
+		older, err := readEntries(p)
+		if os.IsNotExist(err) {
+			if i == 0 {
+				return nil, fmt.Errorf("no log for agent %s", agentID)
+			}
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(older, entries...)
+	}
+
+	if len(entries) > n {
+		entries = entries[len(entries)-n:]
+	}
+	return entries, nil
+}
+
+// Close closes every log file.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var firstErr error
+	for id, file := range m.files {
+		if err := file.Close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to close log for %s: %w", id, err)
+		}
+		delete(m.files, id)
+	}
+	return firstErr
+}
+
+// file returns the agent's open log file, opening it on first use.
+func (m *Manager) file(agentID string) (*RotatingFile, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if file, ok := m.files[agentID]; ok {
+		return file, nil
+	}
+
+	path, err := m.path(agentID)
+	if err != nil {
+		return nil, err
+	}
+	file, err := OpenRotatingFile(path, m.maxSize, m.maxBackups)
+	if err != nil {
+		return nil, err
+	}
+	m.files[agentID] = file
+	return file, nil
+}
+
+// path returns the log file path for an agent, rejecting IDs that would escape the log directory.
+func (m *Manager) path(agentID string) (string, error) {
+	if agentID == "" || agentID == "." || agentID == ".." || strings.ContainsAny(agentID, `/\`) {
+		return "", fmt.Errorf("invalid agent ID %q", agentID)
+	}
+	return filepath.Join(m.dir, agentID+".log"), nil
+}
+
+// readEntries reads every entry in a log file, skipping lines that do not parse.
+func readEntries(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			var entry Entry
+			if json.Unmarshal(line, &entry) == nil {
+				entries = append(entries, entry)
+			}
+		}
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read log: %w", err)
+		}
+	}
+}
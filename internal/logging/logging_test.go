@@ -0,0 +1,102 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.log")
+	f, err := OpenRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	for _, line := range []string{"aaaaaaaa\n", "bbbbbbbb\n", "cccccccc\n", "dddddddd\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for file, want := range map[string]string{path: "dddddddd\n", path + ".1": "cccccccc\n", path + ".2": "bbbbbbbb\n"} {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Errorf("%s = %q, want %q", filepath.Base(file), data, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Error("Expected backups beyond the limit to be removed")
+	}
+}
+
+func TestManager(t *testing.T) {
+	t.Run("TailsAcrossRotations", func(t *testing.T) {
+		m, err := NewManager(types.LoggingConfig{Dir: t.TempDir()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer m.Close()
+		m.maxSize = 300 // Force a rotation every couple of entries
+
+		for i := 0; i < 6; i++ {
+			m.Log("engineer-1", Entry{Kind: KindResponse, Content: fmt.Sprintf("response %d", i)})
+		}
+		m.Log("engineer-2", Entry{Kind: KindResponse, Content: "other agent"})
+
+		entries, err := m.Tail("engineer-1", 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 4 || entries[0].Content != "response 2" || entries[3].Content != "response 5" {
+			t.Errorf("Expected the last 4 entries in order, got %+v", entries)
+		}
+		if entries[0].Agent != "engineer-1" || entries[0].Time.IsZero() {
+			t.Errorf("Expected agent and time to be filled in, got %+v", entries[0])
+		}
+	})
+
+	t.Run("RedactsPrompts", func(t *testing.T) {
+		m, err := NewManager(types.LoggingConfig{Dir: t.TempDir(), RedactPrompts: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer m.Close()
+
+		m.Log("manager-1", Entry{Kind: KindPrompt, Content: "secret requirements"})
+		m.Log("manager-1", Entry{Kind: KindResponse, Content: "design"})
+
+		entries, err := m.Tail("manager-1", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(entries[0].Content, "secret") || entries[1].Content != "design" {
+			t.Errorf("Expected only the prompt to be redacted, got %+v", entries)
+		}
+	})
+
+	t.Run("RejectsInvalidAgentIDs", func(t *testing.T) {
+		m, err := NewManager(types.LoggingConfig{Dir: t.TempDir()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer m.Close()
+
+		for _, id := range []string{"", "..", "../config", `a\b`} {
+			if _, err := m.Tail(id, 1); err == nil {
+				t.Errorf("Expected error for agent ID %q", id)
+			}
+		}
+		if _, err := m.Tail("engineer-9", 1); err == nil {
+			t.Error("Expected error for an agent without a log")
+		}
+	})
+}
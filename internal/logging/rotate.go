@@ -0,0 +1,112 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// RotatingFile is an append-only file that is rotated once it reaches a size
+// limit. Rotated files are renamed path.1, path.2 and so on, oldest last, and
+// files beyond the backup limit are removed.
+type RotatingFile struct {
+	file       *os.File
+	path       string
+	maxSize    int64
+	size       int64
+	maxBackups int
+	mu         sync.Mutex
+}
+
+// OpenRotatingFile opens path for appending, creating it if needed.
+func OpenRotatingFile(path string, maxSize int64, maxBackups int) (*RotatingFile, error) {
+	r := &RotatingFile{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Write appends p, rotating first if p would take the file past its size limit.
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return 0, fmt.Errorf("log file %s is closed", r.path)
+	}
+
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Close closes the file.
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// open opens the current file and records its size. The caller must hold r.mu
+// or have exclusive access.
+func (r *RotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+
+	r.file = f
+	r.size = info.Size()
+	return nil
+}
+
+// rotate shifts the backups, moves the current file to path.1 and starts a new
+// one. The caller must hold r.mu.
+func (r *RotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+	r.file = nil
+
+	if r.maxBackups <= 0 {
+		if err := os.Remove(r.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove log file: %w", err)
+		}
+		return r.open()
+	}
+
+	for i := r.maxBackups; i > 1; i-- {
+		if err := os.Rename(backupPath(r.path, i-1), backupPath(r.path, i)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rotate log file: %w", err)
+		}
+	}
+	if err := os.Rename(r.path, backupPath(r.path, 1)); err != nil {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+
+	return r.open()
+}
+
+// backupPath returns the path of the nth rotated file.
+func backupPath(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
@@ -0,0 +1,348 @@
+package memory
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+const (
+	// DefaultArchiveDir is where archive bundles are written when no directory is configured.
+	DefaultArchiveDir = "./data/archive"
+
+	// defaultArchiveInterval is how often runs are checked for archiving when not configured.
+	defaultArchiveInterval = time.Hour
+
+	// bundleExtension is the file extension of archive bundles.
+	bundleExtension = ".bundle"
+)
+
+// archiveStore is implemented by stores that can move runs to archive bundles.
+type archiveStore interface {
+	InactiveRuns(ctx context.Context, before time.Time) ([]*types.ArchivedRun, error)
+	ArchiveRun(ctx context.Context, run *types.ArchivedRun) (int, error)
+	ArchivedRuns(ctx context.Context) ([]*types.ArchivedRun, error)
+	UpdateArchivedRun(ctx context.Context, run *types.ArchivedRun) error
+	RemoveArchivedRun(ctx context.Context, runID string) error
+	sealBundle(runID string, data []byte) ([]byte, error)
+	openBundle(runID string, data []byte) ([]byte, error)
+}
+
+// bundle is the content of an archive bundle: gzip-compressed JSON, encrypted
+// when memory encryption is enabled.
+type bundle struct {
+	ArchivedAt time.Time            `json:"archived_at"`
+	RunID      string               `json:"run_id"`
+	Entries    []*types.MemoryEntry `json:"entries"`
+}
+
+// ArchiveRuns moves the memories of every run inactive since before into
+// bundles in the archive directory, one per run, and removes them from the
+// stores. The runs stay listed in the archive index so they can be restored.
+func (m *Manager) ArchiveRuns(ctx context.Context, before time.Time) ([]*types.ArchivedRun, error) {
+	store, ok := m.sqliteStore.(archiveStore)
+	if !ok {
+		return nil, fmt.Errorf("sqlite store does not support archiving")
+	}
+
+	// Pending writes may belong to a run about to be archived
+	if err := m.Flush(ctx); err != nil {
+		return nil, err
+	}
+
+	runs, err := store.InactiveRuns(ctx, before)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := m.archiveDir()
+	if len(runs) > 0 {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create archive directory: %w", err)
+		}
+	}
+
+	archived := make([]*types.ArchivedRun, 0, len(runs))
+	for _, run := range runs {
+		if err := m.archiveRun(ctx, store, dir, run); err != nil {
+			return archived, fmt.Errorf("failed to archive run %s: %w", run.RunID, err)
+		}
+		archived = append(archived, run)
+	}
+
+	return archived, nil
+}
+
+// archiveRun writes one run's bundle and then removes its entries.
+func (m *Manager) archiveRun(ctx context.Context, store archiveStore, dir string, run *types.ArchivedRun) error {
+	entries, err := m.sqliteStore.Query(ctx, &types.MemoryQuery{RunID: run.RunID})
+	if err != nil {
+		return fmt.Errorf("failed to query run memories: %w", err)
+	}
+
+	run.ArchivedAt = time.Now()
+	run.Entries = len(entries)
+	run.Path = filepath.Join(dir, filepath.Base(run.RunID)+bundleExtension)
+	if err := writeBundle(store, run.Path, bundle{ArchivedAt: run.ArchivedAt, RunID: run.RunID, Entries: entries}); err != nil {
+		return err
+	}
+
+	if _, err := store.ArchiveRun(ctx, run); err != nil {
+		_ = os.Remove(run.Path)
+		return err
+	}
+
+	if m.valdStore != nil {
+		for _, entry := range entries {
+			if err := m.valdStore.Delete(ctx, entry.ID); err != nil {
+				fmt.Printf("Warning: failed to delete vector %s: %v\n", entry.ID, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// RestoreRun moves an archived run's memories back into the stores and
+// removes its bundle. It returns the number of entries restored.
+func (m *Manager) RestoreRun(ctx context.Context, runID string) (int, error) {
+	store, ok := m.sqliteStore.(archiveStore)
+	if !ok {
+		return 0, fmt.Errorf("sqlite store does not support archiving")
+	}
+
+	runs, err := store.ArchivedRuns(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var run *types.ArchivedRun
+	for _, r := range runs {
+		if r.RunID == runID {
+			run = r
+			break
+		}
+	}
+	if run == nil {
+		return 0, fmt.Errorf("run %s is not archived", runID)
+	}
+
+	b, err := readBundle(store, run)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(b.Entries) > 0 {
+		if err := m.persist(ctx, b.Entries); err != nil {
+			return 0, fmt.Errorf("failed to restore run memories: %w", err)
+		}
+	}
+
+	if err := store.RemoveArchivedRun(ctx, runID); err != nil {
+		return 0, err
+	}
+	if err := os.Remove(run.Path); err != nil {
+		fmt.Printf("Warning: failed to remove archive bundle %s: %v\n", run.Path, err)
+	}
+
+	return len(b.Entries), nil
+}
+
+// eraseArchived removes the entries matching filter from archive bundles,
+// rewriting each bundle that held any and dropping runs left empty from the
+// archive and its index, so that restoring a run cannot bring erased
+// entries back. With dryRun it only counts them. It returns the number of
+// entries matched.
+func (m *Manager) eraseArchived(ctx context.Context, filter *types.MemoryQuery, dryRun bool) (int, error) {
+	store, ok := m.sqliteStore.(archiveStore)
+	if !ok {
+		return 0, nil
+	}
+
+	runs, err := store.ArchivedRuns(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	erased := 0
+	for _, run := range runs {
+		if filter.RunID != "" && run.RunID != filter.RunID {
+			continue
+		}
+		b, err := readBundle(store, run)
+		if err != nil {
+			return erased, fmt.Errorf("failed to erase from archived run %s: %w", run.RunID, err)
+		}
+		kept := slices.DeleteFunc(slices.Clone(b.Entries), func(e *types.MemoryEntry) bool { return matchesQuery(e, filter) })
+		matched := len(b.Entries) - len(kept)
+		if matched == 0 {
+			continue
+		}
+		erased += matched
+		if dryRun {
+			continue
+		}
+
+		if len(kept) == 0 {
+			if err := store.RemoveArchivedRun(ctx, run.RunID); err != nil {
+				return erased, err
+			}
+			if err := os.Remove(run.Path); err != nil {
+				return erased, fmt.Errorf("failed to remove archive bundle %s: %w", run.Path, err)
+			}
+			continue
+		}
+		b.Entries = kept
+		if err := writeBundle(store, run.Path, *b); err != nil {
+			return erased, err
+		}
+		run.Entries = len(kept)
+		if err := store.UpdateArchivedRun(ctx, run); err != nil {
+			return erased, err
+		}
+	}
+
+	return erased, nil
+}
+
+// matchesQuery reports whether an entry meets every criterion of a query
+// other than its limit and offset, as the SQLite store applies them.
+func matchesQuery(entry *types.MemoryEntry, query *types.MemoryQuery) bool {
+	switch {
+	case query.AgentID != "" && entry.AgentID != query.AgentID,
+		query.RunID != "" && entry.RunID != query.RunID,
+		query.Type != "" && entry.Type != query.Type:
+		return false
+	}
+	for _, tag := range query.Tags {
+		if !slices.Contains(entry.Tags, tag) {
+			return false
+		}
+	}
+	if r := query.TimeRange; r != nil && (entry.CreatedAt.Before(r.Start) || entry.CreatedAt.After(r.End)) {
+		return false
+	}
+	return matchesEncryptedFilter(entry, query)
+}
+
+// ArchivedRuns returns the archive index, most recently archived first.
+func (m *Manager) ArchivedRuns(ctx context.Context) ([]*types.ArchivedRun, error) {
+	store, ok := m.sqliteStore.(archiveStore)
+	if !ok {
+		return nil, fmt.Errorf("sqlite store does not support archiving")
+	}
+
+	return store.ArchivedRuns(ctx)
+}
+
+// archiveDir returns the configured archive directory.
+func (m *Manager) archiveDir() string {
+	if m.config.Archive.Dir != "" {
+		return m.config.Archive.Dir
+	}
+	return DefaultArchiveDir
+}
+
+// startArchiver starts archiving inactive runs periodically.
+func (m *Manager) startArchiver() {
+	interval := m.config.Archive.Interval
+	if interval <= 0 {
+		interval = defaultArchiveInterval
+	}
+
+	m.archiverStop = make(chan struct{})
+	m.archiverDone = make(chan struct{})
+
+	go func() {
+		defer close(m.archiverDone)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if m.paused != nil && m.paused() {
+					continue
+				}
+				archived, err := m.ArchiveRuns(context.Background(), time.Now().Add(-m.config.Archive.After))
+				if err != nil {
+					fmt.Printf("Warning: failed to archive runs: %v\n", err)
+				}
+				if len(archived) > 0 {
+					fmt.Printf("Archived %d inactive run(s) to %s\n", len(archived), m.archiveDir())
+				}
+			case <-m.archiverStop:
+				return
+			}
+		}
+	}()
+}
+
+// stopArchiver stops the periodic archiver and waits for a pass in progress.
+func (m *Manager) stopArchiver() {
+	if m.archiverStop == nil {
+		return
+	}
+	close(m.archiverStop)
+	<-m.archiverDone
+	m.archiverStop = nil
+}
+
+// writeBundle compresses and seals a bundle, writing it atomically.
+func writeBundle(store archiveStore, path string, b bundle) error {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(b); err != nil {
+		return fmt.Errorf("failed to encode bundle: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to compress bundle: %w", err)
+	}
+
+	data, err := store.sealBundle(b.RunID, buf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return nil
+}
+
+// readBundle reads the bundle of an archived run written by writeBundle.
+func readBundle(store archiveStore, run *types.ArchivedRun) (*bundle, error) {
+	data, err := os.ReadFile(run.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+
+	data, err = store.openBundle(run.RunID, data)
+	if err != nil {
+		return nil, err
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress bundle: %w", err)
+	}
+	defer zr.Close()
+
+	var b bundle
+	if err := json.NewDecoder(zr).Decode(&b); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle: %w", err)
+	}
+	return &b, nil
+}
@@ -0,0 +1,57 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// assignmentStore is implemented by stores that keep the assignment history.
+type assignmentStore interface {
+	RecordAssignment(ctx context.Context, assignment *types.Assignment) error
+	Assignments(ctx context.Context, agentIDs []string, limit int) ([]*types.Assignment, error)
+	AgentPerformance(ctx context.Context) ([]types.AgentPerformance, error)
+}
+
+// RecordAssignment records which agent handled a task and how it went.
+func (m *Manager) RecordAssignment(ctx context.Context, assignment *types.Assignment) error {
+	store, ok := m.sqliteStore.(assignmentStore)
+	if !ok {
+		return fmt.Errorf("sqlite store does not support assignment history")
+	}
+
+	if assignment.ID == "" {
+		assignment.ID = uuid.New().String()
+	}
+	if assignment.CreatedAt.IsZero() {
+		assignment.CreatedAt = time.Now()
+	}
+
+	return store.RecordAssignment(ctx, assignment)
+}
+
+// Assignments returns the most recent assignments of the given agents, or of
+// every agent if none are given, newest first.
+func (m *Manager) Assignments(ctx context.Context, agentIDs []string, limit int) ([]*types.Assignment, error) {
+	store, ok := m.sqliteStore.(assignmentStore)
+	if !ok {
+		return nil, fmt.Errorf("sqlite store does not support assignment history")
+	}
+
+	return store.Assignments(ctx, agentIDs, limit)
+}
+
+// AgentPerformance returns the success rate and latency of every agent that
+// has handled an assignment.
+func (m *Manager) AgentPerformance(ctx context.Context) ([]types.AgentPerformance, error) {
+	store, ok := m.sqliteStore.(assignmentStore)
+	if !ok {
+		return nil, fmt.Errorf("sqlite store does not support assignment history")
+	}
+
+	return store.AgentPerformance(ctx)
+}
@@ -0,0 +1,189 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+const (
+	// defaultQueueSize is the number of pending writes buffered by the async writer.
+	defaultQueueSize = 256
+	// defaultBatchSize is the maximum number of entries written per transaction.
+	defaultBatchSize = 32
+)
+
+// ErrorHandler is called when an asynchronous write fails to persist.
+type ErrorHandler func(entry *types.MemoryEntry, err error)
+
+// batchStore is implemented by stores that can write several entries at once.
+type batchStore interface {
+	StoreBatch(ctx context.Context, entries []*types.MemoryEntry) error
+}
+
+// startWriter starts the write-behind goroutine.
+func (m *Manager) startWriter(queueSize, batchSize int) {
+	if queueSize <= 0 {
+		queueSize = defaultQueueSize
+	}
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	m.queue = make(chan *types.MemoryEntry, queueSize)
+	m.flushReq = make(chan chan struct{})
+	m.writerDone = make(chan struct{})
+	m.stopping = make(chan struct{})
+	m.batchSize = batchSize
+
+	go m.runWriter()
+}
+
+// runWriter persists queued entries in batches until the queue is closed.
+func (m *Manager) runWriter() {
+	defer close(m.writerDone)
+
+	batch := make([]*types.MemoryEntry, 0, m.batchSize)
+	for {
+		select {
+		case entry, ok := <-m.queue:
+			if !ok {
+				return
+			}
+			var open bool
+			batch, open = m.collect(append(batch[:0], entry), m.batchSize)
+			m.writeBatch(batch)
+			if !open {
+				return
+			}
+		case done := <-m.flushReq:
+			var open bool
+			batch, open = m.collect(batch[:0], 0)
+			m.writeBatch(batch)
+			close(done)
+			if !open {
+				return
+			}
+		}
+	}
+}
+
+// collect appends already queued entries to batch without blocking.
+// A limit of 0 drains the whole queue. It reports whether the queue is still open.
+func (m *Manager) collect(batch []*types.MemoryEntry, limit int) ([]*types.MemoryEntry, bool) {
+	for limit == 0 || len(batch) < limit {
+		select {
+		case entry, ok := <-m.queue:
+			if !ok {
+				return batch, false
+			}
+			batch = append(batch, entry)
+		default:
+			return batch, true
+		}
+	}
+
+	return batch, true
+}
+
+// writeBatch persists a batch and reports failures to the error handler.
+func (m *Manager) writeBatch(batch []*types.MemoryEntry) {
+	if len(batch) == 0 {
+		return
+	}
+
+	// Writes outlive the task that queued them, so they do not inherit its context
+	if err := m.persist(context.Background(), batch); err != nil {
+		for _, entry := range batch {
+			m.onError(entry, err)
+		}
+		return
+	}
+	m.writes.Add(1)
+}
+
+// WriteGeneration counts the batches persisted by the async writer, so that
+// caches of query results can tell when queued entries became visible.
+func (m *Manager) WriteGeneration() uint64 {
+	return m.writes.Load()
+}
+
+// enqueue hands an entry to the async writer, blocking only while the queue is full.
+// The lock is released before the send, so a full queue does not stall Close;
+// stopWriter waits for registered senders before it closes the queue.
+func (m *Manager) enqueue(ctx context.Context, entry *types.MemoryEntry) error {
+	m.mu.RLock()
+	if m.closed {
+		m.mu.RUnlock()
+		return fmt.Errorf("memory manager is closed")
+	}
+	m.senders.Add(1)
+	m.mu.RUnlock()
+	defer m.senders.Done()
+
+	select {
+	case m.queue <- entry:
+		return nil
+	case <-m.stopping:
+		return fmt.Errorf("memory manager is closed")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Flush blocks until every write queued before the call has been persisted.
+// It is a no-op when async writes are disabled.
+func (m *Manager) Flush(ctx context.Context) error {
+	if m.queue == nil {
+		return nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return nil
+	}
+
+	done := make(chan struct{})
+	select {
+	case m.flushReq <- done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// stopWriter closes the queue and waits for pending writes to be persisted.
+// Senders still blocked on a full queue give up with an error.
+func (m *Manager) stopWriter() {
+	if m.queue == nil {
+		return
+	}
+
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return
+	}
+	m.closed = true
+	close(m.stopping)
+	m.mu.Unlock()
+
+	m.senders.Wait()
+	close(m.queue)
+
+	<-m.writerDone
+}
+
+// defaultErrorHandler logs failed writes, matching how other non-fatal memory errors are reported.
+func defaultErrorHandler(entry *types.MemoryEntry, err error) {
+	fmt.Printf("Warning: failed to persist memory %s: %v\n", entry.ID, err)
+}
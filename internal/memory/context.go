@@ -0,0 +1,99 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/kpango/BuildBureau/internal/llm"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// ContextCandidates is how many memories are ranked for a prompt context
+// before it is trimmed to its budget.
+const ContextCandidates = 20
+
+const (
+	contextHeader = "\n\n=== Context from Memory ===\n"
+	contextFooter = "=== End of Memory Context ===\n\n"
+)
+
+// contextSections are the memory types included in a prompt context, in the
+// order they are rendered. Conversations are left out: they record what an
+// agent was asked, not what it learned.
+var contextSections = []struct {
+	memType types.MemoryType
+	heading string
+}{
+	{types.MemoryTypeTask, "Past Work"},
+	{types.MemoryTypeDecision, "Past Decisions"},
+	{types.MemoryTypeKnowledge, "Relevant Knowledge"},
+}
+
+// BuildContext returns the memories of an agent relevant to a task as a block
+// ready to insert into a prompt. Past tasks, decisions and knowledge are
+// fetched in one search, ranked by relevance and kept until the token budget
+// is spent. It returns "" if no relevant memory fits.
+func (m *Manager) BuildContext(ctx context.Context, agentID, taskDescription string, budgetTokens int) (string, error) {
+	if budgetTokens <= 0 {
+		return "", nil
+	}
+
+	entries, err := m.SemanticSearch(ctx, taskDescription, agentID, ContextCandidates)
+	if err != nil {
+		return "", fmt.Errorf("failed to search memories: %w", err)
+	}
+	return RenderContext(entries, agentID, budgetTokens), nil
+}
+
+// RenderContext renders the past tasks, decisions and knowledge among entries
+// as a prompt context of at most budgetTokens estimated tokens. Entries are
+// kept most relevant first; those that do not fit what is left of the budget
+// are skipped. Entries of other agents are left out unless agentID is empty.
+func RenderContext(entries []*types.MemoryEntry, agentID string, budgetTokens int) string {
+	ranked := slices.DeleteFunc(slices.Clone(entries), func(e *types.MemoryEntry) bool {
+		return agentID != "" && e.AgentID != agentID || strings.TrimSpace(e.Content) == "" || sectionHeading(e.Type) == ""
+	})
+	slices.SortStableFunc(ranked, func(a, b *types.MemoryEntry) int { return cmp.Compare(b.Score, a.Score) })
+
+	used := llm.EstimateTokens(contextHeader) + llm.EstimateTokens(contextFooter)
+	kept := make(map[types.MemoryType][]string)
+	for _, e := range ranked {
+		item := "- " + strings.TrimSpace(e.Content) + "\n"
+		cost := llm.EstimateTokens(item)
+		if len(kept[e.Type]) == 0 {
+			cost += llm.EstimateTokens(sectionHeading(e.Type))
+		}
+		if used+cost > budgetTokens {
+			continue
+		}
+		used += cost
+		kept[e.Type] = append(kept[e.Type], item)
+	}
+	if len(kept) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(contextHeader)
+	for _, s := range contextSections {
+		if items := kept[s.memType]; len(items) > 0 {
+			b.WriteString(sectionHeading(s.memType))
+			b.WriteString(strings.Join(items, ""))
+		}
+	}
+	b.WriteString(contextFooter)
+	return b.String()
+}
+
+// sectionHeading returns the heading of the section of a memory type.
+func sectionHeading(memType types.MemoryType) string {
+	for _, s := range contextSections {
+		if s.memType == memType {
+			return "\n" + s.heading + ":\n"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,58 @@
+package memory
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// decayOverfetch is how many candidates per requested result are scored before decay.
+const decayOverfetch = 3
+
+// defaultHalfLife is how quickly each memory type loses relevance when decay is enabled.
+// Conversations go stale within days, while knowledge stays useful for a long time.
+var defaultHalfLife = map[types.MemoryType]time.Duration{
+	types.MemoryTypeConversation: 24 * time.Hour,
+	types.MemoryTypeContext:      7 * 24 * time.Hour,
+	types.MemoryTypeTask:         30 * 24 * time.Hour,
+	types.MemoryTypeDecision:     90 * 24 * time.Hour,
+	types.MemoryTypeKnowledge:    365 * 24 * time.Hour,
+}
+
+// halfLife returns the configured half-life for a memory type, or 0 if it does not decay.
+func (m *Manager) halfLife(memType types.MemoryType) time.Duration {
+	if halfLife, ok := m.config.Decay.HalfLife[memType]; ok {
+		return halfLife
+	}
+	return defaultHalfLife[memType]
+}
+
+// applyDecay weights each entry's score by its age and re-sorts by the weighted score.
+// Entries without a score (text search results) start from 1 so they are ranked by recency.
+func (m *Manager) applyDecay(entries []*types.MemoryEntry, now time.Time) {
+	if !m.config.Decay.Enabled {
+		return
+	}
+
+	for _, entry := range entries {
+		score := entry.Score
+		if score == 0 {
+			score = 1
+		}
+		entry.Score = score * decayFactor(now.Sub(entry.CreatedAt), m.halfLife(entry.Type))
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Score > entries[j].Score
+	})
+}
+
+// decayFactor returns 0.5^(age/halfLife), or 1 if the type does not decay.
+func decayFactor(age, halfLife time.Duration) float32 {
+	if halfLife <= 0 || age <= 0 {
+		return 1
+	}
+	return float32(math.Pow(0.5, float64(age)/float64(halfLife)))
+}
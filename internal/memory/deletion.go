@@ -0,0 +1,183 @@
+package memory
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// DeleteOptions controls a filtered deletion.
+type DeleteOptions struct {
+	Requester string // Who asked for the deletion, recorded in the audit
+	Reason    string // Why, e.g. a data subject request reference
+	DryRun    bool   // Only count matching entries
+}
+
+// DeleteResult reports the outcome of a filtered deletion.
+type DeleteResult struct {
+	AuditID string
+	// VectorFailures lists the entries whose vectors could not be deleted
+	// from Vald, even when retried. Their structured entries are deleted, so
+	// searches never return them, but the vectors remain until deleted again.
+	VectorFailures []string
+	Matched        int
+	Deleted        int
+	DryRun         bool
+}
+
+// deletionStore is implemented by stores that can delete entries and record an audit atomically.
+type deletionStore interface {
+	DeleteMatching(ctx context.Context, filter *types.MemoryQuery, audit *types.DeletionAudit) ([]string, error)
+	DeletionAudits(ctx context.Context, limit int) ([]*types.DeletionAudit, error)
+}
+
+// DeleteByFilter removes every entry matching filter (agent, run, type, tags,
+// time range, content and metadata) from both stores. Structured entries are
+// selected and removed, and the audit recorded, in one transaction; entries of
+// archived runs are erased from their bundles before, and vector deletions
+// follow. The two stores cannot share a transaction: vectors that fail to
+// delete are retried once and then reported in the result, since orphaned
+// vectors are never returned by searches. The audit keeps the filter with its
+// content and metadata values replaced by HMACs under a per-install key, so it
+// holds no copy of what was erased. Agents' cached lookups are dropped. Limit
+// and Offset in filter are ignored.
+func (m *Manager) DeleteByFilter(ctx context.Context, filter *types.MemoryQuery, opts DeleteOptions) (*DeleteResult, error) {
+	if isEmptyFilter(filter) {
+		return nil, fmt.Errorf("delete filter must specify at least one criterion")
+	}
+
+	store, ok := m.sqliteStore.(deletionStore)
+	if !ok {
+		return nil, fmt.Errorf("sqlite store does not support filtered deletion")
+	}
+
+	// Pending writes that match must be deleted too
+	if err := m.Flush(ctx); err != nil {
+		return nil, err
+	}
+
+	query := *filter
+	query.Limit = 0
+	query.Offset = 0
+
+	if opts.DryRun {
+		entries, err := m.sqliteStore.Query(ctx, &query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query matching memories: %w", err)
+		}
+		archived, err := m.eraseArchived(ctx, &query, true)
+		if err != nil {
+			return nil, err
+		}
+		return &DeleteResult{Matched: len(entries) + archived, DryRun: true}, nil
+	}
+
+	// Archived entries are erased first, so a failure stops before any row
+	// is deleted and the request can be repeated
+	archived, err := m.eraseArchived(ctx, &query, false)
+	if err != nil {
+		return nil, err
+	}
+
+	audit := &types.DeletionAudit{
+		ID:        uuid.New().String(),
+		Requester: opts.Requester,
+		Reason:    opts.Reason,
+		Filter:    query,
+		Deleted:   archived,
+		CreatedAt: time.Now(),
+	}
+
+	ids, err := store.DeleteMatching(ctx, &query, audit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DeleteResult{Matched: len(ids) + archived}
+	if result.Matched == 0 {
+		return result, nil
+	}
+	result.Deleted = audit.Deleted
+	result.AuditID = audit.ID
+	m.deletions.Add(1)
+	result.VectorFailures = m.deleteVectors(ctx, ids)
+
+	return result, nil
+}
+
+// deleteVectors deletes the vectors of entries from Vald, retrying failures
+// once, and returns the IDs of those that could not be deleted.
+func (m *Manager) deleteVectors(ctx context.Context, ids []string) []string {
+	if m.valdStore == nil {
+		return nil
+	}
+
+	var failed []string
+	for _, id := range ids {
+		err := m.valdStore.Delete(ctx, id)
+		if err != nil {
+			err = m.valdStore.Delete(ctx, id)
+		}
+		if err != nil {
+			fmt.Printf("Warning: failed to delete vector %s: %v\n", id, err)
+			failed = append(failed, id)
+		}
+	}
+	return failed
+}
+
+// DeletionGeneration counts the deletions of entries, so that caches of
+// query results can tell when they may hold deleted entries.
+func (m *Manager) DeletionGeneration() uint64 {
+	return m.deletions.Load()
+}
+
+// redactFilter returns filter with its content and metadata values replaced
+// by their HMAC-SHA256 under key. The HMACs still show whether two audits of
+// one install erased the same text, without keeping the text. Read without
+// the key, which stays in the database, they cannot be matched against
+// guesses or compared across installs.
+func redactFilter(filter types.MemoryQuery, key []byte) types.MemoryQuery {
+	if filter.Content != "" {
+		filter.Content = redact(filter.Content, key)
+	}
+	if len(filter.Metadata) > 0 {
+		filter.Metadata = maps.Clone(filter.Metadata)
+		for name, value := range filter.Metadata {
+			filter.Metadata[name] = redact(value, key)
+		}
+	}
+	return filter
+}
+
+// redact returns the HMAC recorded in place of value.
+func redact(value string, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return "hmac-sha256:" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// DeletionAudits returns the audit records of past filtered deletions, newest first.
+func (m *Manager) DeletionAudits(ctx context.Context, limit int) ([]*types.DeletionAudit, error) {
+	store, ok := m.sqliteStore.(deletionStore)
+	if !ok {
+		return nil, fmt.Errorf("sqlite store does not support deletion audits")
+	}
+
+	return store.DeletionAudits(ctx, limit)
+}
+
+// isEmptyFilter reports whether a filter would match every entry.
+func isEmptyFilter(filter *types.MemoryQuery) bool {
+	return filter == nil ||
+		(filter.AgentID == "" && filter.RunID == "" && filter.Type == "" && filter.Content == "" &&
+			len(filter.Tags) == 0 && len(filter.Metadata) == 0 && filter.TimeRange == nil)
+}
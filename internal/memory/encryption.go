@@ -0,0 +1,165 @@
+package memory
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/kpango/BuildBureau/internal/config"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// encryptedPrefix marks values encrypted by contentCipher. Values without it are plaintext,
+// which lets encryption be enabled on an existing database.
+const encryptedPrefix = "enc:v2:"
+
+// legacyPrefix marks values encrypted by earlier versions with keys hashed from
+// passphrases, which are no longer accepted.
+const legacyPrefix = "enc:v1:"
+
+// encryptionKeySize is the size of an AES-256 key.
+const encryptionKeySize = 32
+
+// contentCipher encrypts memory content with AES-256-GCM.
+//
+// Encrypted values have the form "enc:v2:<key id>:<base64(nonce || ciphertext)>".
+// The key ID identifies which configured key was used so older keys keep working
+// during rotation. Each value is bound to where it is stored (see binding), so a
+// ciphertext copied to another row fails to decrypt. A nil *contentCipher passes
+// values through unchanged.
+type contentCipher struct {
+	keys      map[string]cipher.AEAD
+	currentID string
+}
+
+// newContentCipher builds a cipher from the encryption config, or returns nil if it is disabled.
+// Keys are read from the environment and must be 32 random bytes, base64 encoded, such as the
+// output of "openssl rand -base64 32".
+func newContentCipher(cfg types.EncryptionConfig) (*contentCipher, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	secret := config.GetEnvValue(cfg.Key)
+	if secret == "" {
+		return nil, fmt.Errorf("encryption key environment variable %s is not set", cfg.Key.Env)
+	}
+
+	c := &contentCipher{keys: make(map[string]cipher.AEAD)}
+
+	currentID, err := c.addKey(cfg.Key.Env, secret)
+	if err != nil {
+		return nil, err
+	}
+	c.currentID = currentID
+
+	for _, previous := range cfg.PreviousKeys {
+		if secret := config.GetEnvValue(previous); secret != "" {
+			if _, err := c.addKey(previous.Env, secret); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return c, nil
+}
+
+// addKey decodes the key in the named environment variable and registers an AEAD
+// for it under its key ID.
+func (c *contentCipher) addKey(name, secret string) (string, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(secret))
+	if err != nil || len(key) != encryptionKeySize {
+		return "", fmt.Errorf("encryption key %s must be %d random bytes, base64 encoded", name, encryptionKeySize)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", fmt.Errorf("failed to create gcm: %w", err)
+	}
+
+	// The key ID is derived from the key so it can be stored without revealing it
+	id := sha256.Sum256(key)
+	keyID := hex.EncodeToString(id[:4])
+	c.keys[keyID] = aead
+
+	return keyID, nil
+}
+
+// binding returns the additional data a value is sealed with: the table, row ID
+// and column it is stored in.
+func binding(table, id, column string) []byte {
+	return []byte(table + "/" + id + "/" + column)
+}
+
+// encrypt encrypts a value with the current key, bound to aad.
+func (c *contentCipher) encrypt(plaintext string, aad []byte) (string, error) {
+	if c == nil {
+		return plaintext, nil
+	}
+
+	aead := c.keys[c.currentID]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), aad)
+	return encryptedPrefix + c.currentID + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decrypt decrypts a value sealed with aad by whichever configured key encrypted it;
+// plaintext is returned as is.
+func (c *contentCipher) decrypt(value string, aad []byte) (string, error) {
+	if strings.HasPrefix(value, legacyPrefix) {
+		return "", fmt.Errorf("value is encrypted with a passphrase-derived key, which is no longer supported")
+	}
+	if !strings.HasPrefix(value, encryptedPrefix) {
+		return value, nil
+	}
+	if c == nil {
+		return "", fmt.Errorf("memory is encrypted but no encryption key is configured")
+	}
+
+	keyID, payload, ok := strings.Cut(strings.TrimPrefix(value, encryptedPrefix), ":")
+	if !ok {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+
+	aead, ok := c.keys[keyID]
+	if !ok {
+		return "", fmt.Errorf("no encryption key configured for key id %s", keyID)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode encrypted value: %w", err)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], aad)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value: %w", err)
+	}
+
+	return string(plaintext), nil
+}
+
+// isCurrent reports whether a value is already encrypted with the current key.
+func (c *contentCipher) isCurrent(value string) bool {
+	if c == nil {
+		return !strings.HasPrefix(value, encryptedPrefix)
+	}
+	return strings.HasPrefix(value, encryptedPrefix+c.currentID+":")
+}
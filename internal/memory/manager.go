@@ -3,6 +3,8 @@ package memory
 import (
 	"context"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,17 +13,37 @@ import (
 	"github.com/kpango/BuildBureau/pkg/types"
 )
 
+// healthReporter is implemented by stores that track their connection state.
+type healthReporter interface {
+	Health() types.StoreHealth
+}
+
 // Manager implements MemoryManager and coordinates SQLite and Vald stores.
 type Manager struct {
 	sqliteStore  types.MemoryStore
 	valdStore    types.VectorStore
 	llmManager   *llm.Manager
 	config       *types.MemoryConfig
+	onError      ErrorHandler
+	paused       func() bool // Pauses background work while true
+	queue        chan *types.MemoryEntry
+	flushReq     chan chan struct{}
+	writerDone   chan struct{}
+	stopping     chan struct{}  // Closed when the writer stops, to release blocked senders
+	senders      sync.WaitGroup // Tracks enqueue calls that may still send to the queue
+	archiverStop chan struct{}
+	archiverDone chan struct{}
 	embeddingDim int
+	queueSize    int
+	batchSize    int
+	deletions    atomic.Uint64 // Counts deletions, see DeletionGeneration
+	writes       atomic.Uint64 // Counts persisted async batches, see WriteGeneration
+	mu           sync.RWMutex
+	closed       bool
 }
 
 // NewManager creates a new memory manager.
-func NewManager(config *types.MemoryConfig, llmManager *llm.Manager) (*Manager, error) {
+func NewManager(config *types.MemoryConfig, llmManager *llm.Manager, opts ...Option) (*Manager, error) {
 	if config == nil || !config.Enabled {
 		return nil, fmt.Errorf("memory is not enabled")
 	}
@@ -30,10 +52,23 @@ func NewManager(config *types.MemoryConfig, llmManager *llm.Manager) (*Manager,
 		config:       config,
 		llmManager:   llmManager,
 		embeddingDim: config.Vald.Dimension,
+		onError:      defaultErrorHandler,
+	}
+
+	if config.Async.Enabled {
+		manager.queueSize = config.Async.QueueSize
+		manager.batchSize = config.Async.BatchSize
+		if manager.queueSize <= 0 {
+			manager.queueSize = defaultQueueSize
+		}
+	}
+
+	for _, opt := range opts {
+		opt(manager)
 	}
 
 	// Initialize SQLite store if enabled
-	if config.SQLite.Enabled {
+	if manager.sqliteStore == nil && config.SQLite.Enabled {
 		sqliteStore, err := NewSQLiteStore(config.SQLite)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create sqlite store: %w", err)
@@ -42,7 +77,7 @@ func NewManager(config *types.MemoryConfig, llmManager *llm.Manager) (*Manager,
 	}
 
 	// Initialize Vald store if enabled
-	if config.Vald.Enabled {
+	if manager.valdStore == nil && config.Vald.Enabled {
 		valdStore, err := NewValdStore(config.Vald)
 		if err != nil {
 			// Log warning but don't fail if Vald is unavailable
@@ -53,16 +88,73 @@ func NewManager(config *types.MemoryConfig, llmManager *llm.Manager) (*Manager,
 		}
 	}
 
+	// Start the write-behind queue if async writes are enabled
+	if manager.queueSize > 0 {
+		manager.startWriter(manager.queueSize, manager.batchSize)
+	}
+
+	// Archive inactive runs in the background if configured
+	if config.Archive.Enabled {
+		if config.Archive.After > 0 {
+			manager.startArchiver()
+		} else {
+			fmt.Printf("Warning: memory archiving is enabled but archive.after is not set; runs will not be archived\n")
+		}
+	}
+
 	return manager, nil
 }
 
 // StoreMemory stores a memory entry in both structured and vector stores.
+// When async writes are enabled the entry is queued and persisted in the background.
 func (m *Manager) StoreMemory(ctx context.Context, entry *types.MemoryEntry) error {
+	m.prepare(ctx, entry)
+
+	if m.queue != nil {
+		return m.enqueue(ctx, entry)
+	}
+
+	return m.persist(ctx, []*types.MemoryEntry{entry})
+}
+
+// StoreBatch stores multiple memory entries, using a single transaction where the store supports it.
+func (m *Manager) StoreBatch(ctx context.Context, entries []*types.MemoryEntry) error {
+	for _, entry := range entries {
+		m.prepare(ctx, entry)
+	}
+
+	if m.queue != nil {
+		for _, entry := range entries {
+			if err := m.enqueue(ctx, entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return m.persist(ctx, entries)
+}
+
+// prepare fills in the ID, run, labels, timestamps and expiration of an entry before it is stored.
+func (m *Manager) prepare(ctx context.Context, entry *types.MemoryEntry) {
 	// Generate ID if not provided
 	if entry.ID == "" {
 		entry.ID = uuid.New().String()
 	}
 
+	// Link the entry to the run being processed
+	if entry.RunID == "" {
+		entry.RunID = types.RunIDFromContext(ctx)
+	}
+
+	// Label the entry like the task it was stored for, so it can be queried by label
+	if labels := types.LabelsFromContext(ctx); len(labels) > 0 {
+		if entry.Metadata == nil {
+			entry.Metadata = make(map[string]string, len(labels))
+		}
+		types.SetLabels(entry.Metadata, labels)
+	}
+
 	// Set timestamps
 	now := time.Now()
 	if entry.CreatedAt.IsZero() {
@@ -75,28 +167,78 @@ func (m *Manager) StoreMemory(ctx context.Context, entry *types.MemoryEntry) err
 		expiresAt := m.calculateExpiration(entry.Type)
 		entry.ExpiresAt = &expiresAt
 	}
+}
 
+// persist writes prepared entries to SQLite and their embeddings to Vald.
+func (m *Manager) persist(ctx context.Context, entries []*types.MemoryEntry) error {
 	// Store in SQLite
 	if m.sqliteStore != nil {
-		if err := m.sqliteStore.Store(ctx, entry); err != nil {
+		if err := m.storeStructured(ctx, entries); err != nil {
 			return fmt.Errorf("failed to store in sqlite: %w", err)
 		}
 	}
 
-	// Generate and store embedding in Vald if enabled
-	if m.valdStore != nil && entry.Content != "" {
-		embedding, err := m.generateEmbedding(ctx, entry.Content)
-		if err != nil {
-			// Log error but don't fail the entire operation
-			fmt.Printf("Warning: failed to generate embedding: %v\n", err)
-		} else {
-			metadata := map[string]string{
-				"agent_id": entry.AgentID,
-				"type":     string(entry.Type),
+	// Generate and store embeddings in Vald if enabled
+	if m.valdStore != nil {
+		items := make([]types.VectorItem, 0, len(entries))
+		for _, entry := range entries {
+			if entry.Content == "" {
+				continue
 			}
-			if err := m.valdStore.Insert(ctx, entry.ID, embedding, metadata); err != nil {
-				fmt.Printf("Warning: failed to store in vald: %v\n", err)
+
+			embedding, err := m.generateEmbedding(ctx, entry.Content)
+			if err != nil {
+				// Log error but don't fail the entire operation
+				fmt.Printf("Warning: failed to generate embedding: %v\n", err)
+				continue
 			}
+
+			items = append(items, types.VectorItem{
+				ID:     entry.ID,
+				Vector: embedding,
+				Metadata: map[string]string{
+					"agent_id": entry.AgentID,
+					"type":     string(entry.Type),
+				},
+			})
+		}
+
+		if err := m.storeVectors(ctx, items); err != nil {
+			fmt.Printf("Warning: failed to store in vald: %v\n", err)
+		}
+	}
+
+	return nil
+}
+
+// storeVectors writes embeddings to the vector store, batching when supported.
+func (m *Manager) storeVectors(ctx context.Context, items []types.VectorItem) error {
+	if len(items) > 1 {
+		if store, ok := m.valdStore.(types.BulkVectorStore); ok {
+			return store.MultiInsert(ctx, items)
+		}
+	}
+
+	for _, item := range items {
+		if err := m.valdStore.Insert(ctx, item.ID, item.Vector, item.Metadata); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// storeStructured writes entries to the structured store, batching when supported.
+func (m *Manager) storeStructured(ctx context.Context, entries []*types.MemoryEntry) error {
+	if len(entries) > 1 {
+		if store, ok := m.sqliteStore.(batchStore); ok {
+			return store.StoreBatch(ctx, entries)
+		}
+	}
+
+	for _, entry := range entries {
+		if err := m.sqliteStore.Store(ctx, entry); err != nil {
+			return err
 		}
 	}
 
@@ -122,14 +264,33 @@ func (m *Manager) QueryMemories(ctx context.Context, query *types.MemoryQuery) (
 }
 
 // SemanticSearch performs semantic similarity search.
+// When decay is enabled scores are weighted by recency; use QueryMemories with a
+// TimeRange for explicit historical queries that should not be decayed.
 func (m *Manager) SemanticSearch(ctx context.Context, query string, agentID string, limit int) ([]*types.MemoryEntry, error) {
-	if m.valdStore == nil {
-		// Fallback to text search if Vald is not available
-		return m.QueryMemories(ctx, &types.MemoryQuery{
+	// Over-fetch when decaying so recent matches just outside the top results can surface
+	fetchLimit := limit
+	if m.config.Decay.Enabled && limit > 0 {
+		fetchLimit = limit * decayOverfetch
+	}
+
+	textSearch := func() ([]*types.MemoryEntry, error) {
+		entries, err := m.QueryMemories(ctx, &types.MemoryQuery{
 			AgentID: agentID,
 			Content: query,
-			Limit:   limit,
+			Limit:   fetchLimit,
 		})
+		if err != nil {
+			return nil, err
+		}
+		return m.rank(entries, limit), nil
+	}
+
+	// Fallback to text search if Vald is not available
+	if m.valdStore == nil {
+		return textSearch()
+	}
+	if reporter, ok := m.valdStore.(healthReporter); ok && !reporter.Health().Healthy {
+		return textSearch()
 	}
 
 	// Generate embedding for the query
@@ -139,9 +300,11 @@ func (m *Manager) SemanticSearch(ctx context.Context, query string, agentID stri
 	}
 
 	// Search in Vald
-	results, err := m.valdStore.Search(ctx, embedding, limit, 0.0)
+	results, err := m.valdStore.Search(ctx, embedding, fetchLimit, 0.0)
 	if err != nil {
-		return nil, fmt.Errorf("failed to search vectors: %w", err)
+		// Degrade to text search rather than failing the caller
+		fmt.Printf("Warning: vector search failed, using text search: %v\n", err)
+		return textSearch()
 	}
 
 	// Retrieve full entries from SQLite
@@ -155,7 +318,17 @@ func (m *Manager) SemanticSearch(ctx context.Context, query string, agentID stri
 		entries = append(entries, entry)
 	}
 
-	return entries, nil
+	return m.rank(entries, limit), nil
+}
+
+// rank prefers recent memories among equally relevant ones and keeps the
+// best limit entries.
+func (m *Manager) rank(entries []*types.MemoryEntry, limit int) []*types.MemoryEntry {
+	m.applyDecay(entries, time.Now())
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries
 }
 
 // DeleteMemory removes a memory entry from both stores.
@@ -166,6 +339,8 @@ func (m *Manager) DeleteMemory(ctx context.Context, id string) error {
 	if m.sqliteStore != nil {
 		if err := m.sqliteStore.Delete(ctx, id); err != nil {
 			errors = append(errors, fmt.Errorf("sqlite: %w", err))
+		} else {
+			m.deletions.Add(1)
 		}
 	}
 
@@ -183,6 +358,32 @@ func (m *Manager) DeleteMemory(ctx context.Context, id string) error {
 	return nil
 }
 
+// GetRunMemories retrieves every memory recorded during a run.
+func (m *Manager) GetRunMemories(ctx context.Context, runID string) ([]*types.MemoryEntry, error) {
+	if err := m.Flush(ctx); err != nil {
+		return nil, err
+	}
+
+	return m.QueryMemories(ctx, &types.MemoryQuery{RunID: runID})
+}
+
+// DeleteRun removes every memory recorded during a run from both stores,
+// supporting data-retention requests. It returns the number of entries deleted.
+func (m *Manager) DeleteRun(ctx context.Context, runID string) (int, error) {
+	if runID == "" {
+		return 0, fmt.Errorf("run id is required")
+	}
+
+	result, err := m.DeleteByFilter(ctx, &types.MemoryQuery{RunID: runID}, DeleteOptions{
+		Reason: "run deletion",
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.Deleted, nil
+}
+
 // GetConversationHistory retrieves conversation history for an agent.
 func (m *Manager) GetConversationHistory(ctx context.Context, agentID string, limit int) ([]*types.MemoryEntry, error) {
 	return m.QueryMemories(ctx, &types.MemoryQuery{
@@ -230,12 +431,31 @@ func (m *Manager) PruneExpiredMemories(ctx context.Context) (int, error) {
 	if err != nil {
 		return 0, fmt.Errorf("failed to delete expired memories: %w", err)
 	}
+	if count > 0 {
+		m.deletions.Add(1)
+	}
 
 	return count, nil
 }
 
-// Close closes all stores.
+// Health reports the connection state of each configured store.
+func (m *Manager) Health() []types.StoreHealth {
+	var health []types.StoreHealth
+
+	for _, store := range []any{m.sqliteStore, m.valdStore} {
+		if reporter, ok := store.(healthReporter); ok {
+			health = append(health, reporter.Health())
+		}
+	}
+
+	return health
+}
+
+// Close flushes pending async writes and closes all stores.
 func (m *Manager) Close() error {
+	m.stopArchiver()
+	m.stopWriter()
+
 	var errors []error
 
 	if m.sqliteStore != nil {
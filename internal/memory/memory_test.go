@@ -1,11 +1,23 @@
 package memory
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
+	"encoding/base64"
 	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
+	"google.golang.org/grpc"
+
+	"github.com/kpango/BuildBureau/internal/errors"
+	"github.com/kpango/BuildBureau/internal/llm"
 	"github.com/kpango/BuildBureau/pkg/types"
 )
 
@@ -222,6 +234,31 @@ func TestMemoryManager(t *testing.T) {
 		}
 	})
 
+	// Test QueryMemories by metadata
+	t.Run("QueryMemoriesByMetadata", func(t *testing.T) {
+		memories, err := manager.QueryMemories(ctx, &types.MemoryQuery{
+			Metadata: map[string]string{"task_id": "task-123"},
+		})
+		if err != nil {
+			t.Errorf("Failed to query memories by metadata: %v", err)
+		}
+
+		if len(memories) != 1 {
+			t.Errorf("Expected 1 memory for task-123, got %d", len(memories))
+		}
+
+		memories, err = manager.QueryMemories(ctx, &types.MemoryQuery{
+			Metadata: map[string]string{"task_id": "unknown"},
+		})
+		if err != nil {
+			t.Errorf("Failed to query memories by metadata: %v", err)
+		}
+
+		if len(memories) != 0 {
+			t.Errorf("Expected no memories for unknown task, got %d", len(memories))
+		}
+	})
+
 	// Test GetConversationHistory
 	t.Run("GetConversationHistory", func(t *testing.T) {
 		history, err := manager.GetConversationHistory(ctx, "agent-1", 10)
@@ -234,3 +271,1061 @@ func TestMemoryManager(t *testing.T) {
 		}
 	})
 }
+
+func TestMemoryManagerBatchAndAsync(t *testing.T) {
+	config := &types.MemoryConfig{
+		Enabled: true,
+		SQLite: types.SQLiteConfig{
+			Enabled:  true,
+			InMemory: true,
+		},
+	}
+
+	ctx := context.Background()
+
+	newEntries := func(agentID string, n int) []*types.MemoryEntry {
+		entries := make([]*types.MemoryEntry, n)
+		for i := range entries {
+			entries[i] = &types.MemoryEntry{
+				AgentID: agentID,
+				Type:    types.MemoryTypeTask,
+				Content: fmt.Sprintf("task %d", i),
+			}
+		}
+		return entries
+	}
+
+	t.Run("StoreBatch", func(t *testing.T) {
+		manager, err := NewManager(config, nil)
+		if err != nil {
+			t.Fatalf("Failed to create memory manager: %v", err)
+		}
+		defer manager.Close()
+
+		if err := manager.StoreBatch(ctx, newEntries("agent-batch", 5)); err != nil {
+			t.Fatalf("Failed to store batch: %v", err)
+		}
+
+		results, err := manager.QueryMemories(ctx, &types.MemoryQuery{AgentID: "agent-batch"})
+		if err != nil {
+			t.Fatalf("Failed to query memories: %v", err)
+		}
+		if len(results) != 5 {
+			t.Errorf("Expected 5 entries, got %d", len(results))
+		}
+	})
+
+	t.Run("AsyncFlush", func(t *testing.T) {
+		manager, err := NewManager(config, nil, WithAsyncWrites(4, 2))
+		if err != nil {
+			t.Fatalf("Failed to create memory manager: %v", err)
+		}
+		defer manager.Close()
+
+		for _, entry := range newEntries("agent-async", 10) {
+			if err := manager.StoreMemory(ctx, entry); err != nil {
+				t.Fatalf("Failed to queue memory: %v", err)
+			}
+		}
+
+		if err := manager.Flush(ctx); err != nil {
+			t.Fatalf("Failed to flush: %v", err)
+		}
+
+		results, err := manager.QueryMemories(ctx, &types.MemoryQuery{AgentID: "agent-async"})
+		if err != nil {
+			t.Fatalf("Failed to query memories: %v", err)
+		}
+		if len(results) != 10 {
+			t.Errorf("Expected 10 entries after flush, got %d", len(results))
+		}
+	})
+
+	t.Run("AsyncErrorHandler", func(t *testing.T) {
+		var failed []string
+		manager, err := NewManager(config, nil,
+			WithAsyncWrites(4, 4),
+			WithErrorHandler(func(entry *types.MemoryEntry, err error) {
+				failed = append(failed, entry.ID)
+			}),
+		)
+		if err != nil {
+			t.Fatalf("Failed to create memory manager: %v", err)
+		}
+
+		entry := &types.MemoryEntry{ID: "dup", AgentID: "agent-err", Type: types.MemoryTypeTask, Content: "first"}
+		duplicate := &types.MemoryEntry{ID: "dup", AgentID: "agent-err", Type: types.MemoryTypeTask, Content: "second"}
+		_ = manager.StoreMemory(ctx, entry)
+		_ = manager.Flush(ctx)
+		_ = manager.StoreMemory(ctx, duplicate)
+
+		// Close flushes the queue before closing the stores
+		if err := manager.Close(); err != nil {
+			t.Fatalf("Failed to close manager: %v", err)
+		}
+
+		if len(failed) != 1 || failed[0] != "dup" {
+			t.Errorf("Expected error handler to receive the duplicate entry, got %v", failed)
+		}
+
+		if err := manager.StoreMemory(ctx, &types.MemoryEntry{Content: "late"}); err == nil {
+			t.Error("Expected error storing after close")
+		}
+	})
+
+	t.Run("AsyncWriteGeneration", func(t *testing.T) {
+		manager, err := NewManager(config, nil, WithAsyncWrites(4, 4))
+		if err != nil {
+			t.Fatalf("Failed to create memory manager: %v", err)
+		}
+		defer manager.Close()
+
+		if err := manager.StoreBatch(ctx, newEntries("agent-generation", 2)); err != nil {
+			t.Fatalf("Failed to queue memories: %v", err)
+		}
+		if err := manager.Flush(ctx); err != nil {
+			t.Fatalf("Failed to flush: %v", err)
+		}
+		if manager.WriteGeneration() == 0 {
+			t.Error("Expected persisted writes to advance the write generation")
+		}
+	})
+
+	t.Run("CloseReleasesBlockedSenders", func(t *testing.T) {
+		sqlite, err := NewSQLiteStore(config.SQLite)
+		if err != nil {
+			t.Fatal(err)
+		}
+		store := &blockingStore{MemoryStore: sqlite, started: make(chan struct{}, 1), release: make(chan struct{})}
+		manager, err := NewManager(config, nil, WithStore(store), WithAsyncWrites(1, 1))
+		if err != nil {
+			t.Fatalf("Failed to create memory manager: %v", err)
+		}
+
+		// The writer blocks on the first entry and the second fills the queue
+		entries := newEntries("agent-blocked", 3)
+		_ = manager.StoreMemory(ctx, entries[0])
+		<-store.started
+		_ = manager.StoreMemory(ctx, entries[1])
+
+		sent := make(chan error, 1)
+		go func() { sent <- manager.StoreMemory(ctx, entries[2]) }()
+		closed := make(chan error, 1)
+		go func() {
+			time.Sleep(50 * time.Millisecond) // Let the sender block on the full queue
+			closed <- manager.Close()
+		}()
+
+		select {
+		case err := <-sent:
+			if err == nil {
+				t.Error("Expected the blocked sender to fail once the manager closes")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected Close to release the sender blocked on a full queue")
+		}
+
+		close(store.release)
+		if err := <-closed; err != nil {
+			t.Fatalf("Failed to close manager: %v", err)
+		}
+	})
+}
+
+// blockingStore is a MemoryStore whose writes wait until released.
+type blockingStore struct {
+	types.MemoryStore
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingStore) Store(ctx context.Context, entry *types.MemoryEntry) error {
+	select {
+	case s.started <- struct{}{}:
+	default:
+	}
+	<-s.release
+	return s.MemoryStore.Store(ctx, entry)
+}
+
+func TestValdStoreReconnect(t *testing.T) {
+	// Reserve a port with nothing listening on it
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to reserve port: %v", err)
+	}
+	addr := lis.Addr().(*net.TCPAddr)
+	lis.Close()
+
+	start := time.Now()
+	store, err := NewValdStore(types.ValdConfig{
+		Enabled:             true,
+		Host:                "127.0.0.1",
+		Port:                addr.Port,
+		PoolSize:            2,
+		RetryBackoff:        50 * time.Millisecond,
+		DialTimeout:         time.Second,
+		HealthCheckInterval: 50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Expected degraded store instead of error, got: %v", err)
+	}
+	defer store.Close()
+
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected the store created without waiting for Vald, took %s", elapsed)
+	}
+	if len(store.conns) != 2 {
+		t.Errorf("Expected a pool of 2 connections, got %d", len(store.conns))
+	}
+
+	if store.Health().Healthy {
+		t.Fatal("Expected store to be unhealthy without a server")
+	}
+
+	err = store.Insert(context.Background(), "id", []float32{1}, nil)
+	if errors.CodeOf(err) != errors.CodeMemoryUnavailable {
+		t.Errorf("Expected %s while degraded, got %v", errors.CodeMemoryUnavailable, err)
+	}
+
+	// Bring a server up on the same port and wait for the background reconnect
+	lis, err = net.Listen("tcp", addr.String())
+	if err != nil {
+		t.Skipf("Port reused before server start: %v", err)
+	}
+	server := grpc.NewServer()
+	go server.Serve(lis)
+	defer server.Stop()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !store.Health().Healthy {
+		if time.Now().After(deadline) {
+			t.Fatalf("Store did not reconnect: %+v", store.Health())
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	if err := store.Close(); err != nil {
+		t.Errorf("Failed to close store: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Errorf("Expected closing twice to do nothing, got %v", err)
+	}
+}
+
+func TestApplyDecay(t *testing.T) {
+	now := time.Now()
+	manager := &Manager{config: &types.MemoryConfig{
+		Decay: types.DecayConfig{
+			Enabled: true,
+			HalfLife: map[types.MemoryType]time.Duration{
+				types.MemoryTypeKnowledge: 0, // Never decays
+			},
+		},
+	}}
+
+	stale := &types.MemoryEntry{ID: "stale", Type: types.MemoryTypeTask, Score: 0.9, CreatedAt: now.AddDate(0, 0, -60)}
+	fresh := &types.MemoryEntry{ID: "fresh", Type: types.MemoryTypeTask, Score: 0.6, CreatedAt: now}
+	knowledge := &types.MemoryEntry{ID: "knowledge", Type: types.MemoryTypeKnowledge, Score: 0.5, CreatedAt: now.AddDate(-5, 0, 0)}
+	entries := []*types.MemoryEntry{stale, knowledge, fresh}
+
+	manager.applyDecay(entries, now)
+
+	// 60 days is two task half-lives, so 0.9 decays to 0.225
+	if entries[0].ID != "fresh" || entries[1].ID != "knowledge" || entries[2].ID != "stale" {
+		t.Errorf("Unexpected order: %s, %s, %s", entries[0].ID, entries[1].ID, entries[2].ID)
+	}
+	if diff := stale.Score - 0.225; diff > 0.001 || diff < -0.001 {
+		t.Errorf("Expected stale score 0.225, got %f", stale.Score)
+	}
+	if knowledge.Score != 0.5 {
+		t.Errorf("Expected knowledge score unchanged with zero half-life, got %f", knowledge.Score)
+	}
+
+	// Disabled decay leaves scores untouched
+	manager.config.Decay.Enabled = false
+	entry := &types.MemoryEntry{Type: types.MemoryTypeConversation, Score: 0.8, CreatedAt: now.AddDate(0, -1, 0)}
+	manager.applyDecay([]*types.MemoryEntry{entry}, now)
+	if entry.Score != 0.8 {
+		t.Errorf("Expected score unchanged when decay disabled, got %f", entry.Score)
+	}
+
+	t.Run("TextFallbackOverfetches", func(t *testing.T) {
+		manager, err := NewManager(&types.MemoryConfig{
+			Enabled: true,
+			SQLite:  types.SQLiteConfig{Enabled: true, InMemory: true},
+			Decay:   types.DecayConfig{Enabled: true},
+		}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer manager.Close()
+
+		// Text search returns the newest matches first, but the stale
+		// conversations decay below the older knowledge
+		ctx := context.Background()
+		for _, entry := range []*types.MemoryEntry{
+			{ID: "knowledge", Type: types.MemoryTypeKnowledge, Content: "payments use Stripe", CreatedAt: now.AddDate(0, 0, -3)},
+			{ID: "conversation-1", Type: types.MemoryTypeConversation, Content: "payments chat", CreatedAt: now.AddDate(0, 0, -2)},
+			{ID: "conversation-2", Type: types.MemoryTypeConversation, Content: "payments chat", CreatedAt: now.AddDate(0, 0, -1)},
+		} {
+			if err := manager.StoreMemory(ctx, entry); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		results, err := manager.SemanticSearch(ctx, "payments", "", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 || results[0].ID != "knowledge" {
+			ids := make([]string, len(results))
+			for i, r := range results {
+				ids[i] = r.ID
+			}
+			t.Errorf("Expected the knowledge ranked first after decay, got %v", ids)
+		}
+	})
+}
+
+func TestRunMemories(t *testing.T) {
+	config := &types.MemoryConfig{
+		Enabled: true,
+		SQLite: types.SQLiteConfig{
+			Enabled: true,
+			Path:    t.TempDir() + "/memory.db",
+		},
+	}
+
+	manager, err := NewManager(config, nil)
+	if err != nil {
+		t.Fatalf("Failed to create memory manager: %v", err)
+	}
+	defer manager.Close()
+
+	ctx := types.WithRunID(context.Background(), "run-1")
+	for i := range 3 {
+		entry := &types.MemoryEntry{AgentID: "agent-1", Type: types.MemoryTypeTask, Content: fmt.Sprintf("run task %d", i)}
+		if err := manager.StoreMemory(ctx, entry); err != nil {
+			t.Fatalf("Failed to store memory: %v", err)
+		}
+	}
+	other := &types.MemoryEntry{AgentID: "agent-1", Type: types.MemoryTypeTask, Content: "other", RunID: "run-2"}
+	if err := manager.StoreMemory(context.Background(), other); err != nil {
+		t.Fatalf("Failed to store memory: %v", err)
+	}
+
+	entries, err := manager.GetRunMemories(context.Background(), "run-1")
+	if err != nil {
+		t.Fatalf("Failed to get run memories: %v", err)
+	}
+	if len(entries) != 3 || entries[0].RunID != "run-1" {
+		t.Fatalf("Expected 3 entries for run-1, got %d", len(entries))
+	}
+
+	deleted, err := manager.DeleteRun(context.Background(), "run-1")
+	if err != nil {
+		t.Fatalf("Failed to delete run: %v", err)
+	}
+	if deleted != 3 {
+		t.Errorf("Expected 3 deleted entries, got %d", deleted)
+	}
+
+	remaining, _ := manager.QueryMemories(context.Background(), &types.MemoryQuery{AgentID: "agent-1"})
+	if len(remaining) != 1 || remaining[0].RunID != "run-2" {
+		t.Errorf("Expected only the run-2 entry to remain, got %d entries", len(remaining))
+	}
+}
+
+func TestBuildContext(t *testing.T) {
+	config := &types.MemoryConfig{
+		Enabled: true,
+		SQLite: types.SQLiteConfig{
+			Enabled: true,
+			Path:    t.TempDir() + "/memory.db",
+		},
+	}
+
+	manager, err := NewManager(config, nil)
+	if err != nil {
+		t.Fatalf("Failed to create memory manager: %v", err)
+	}
+	defer manager.Close()
+
+	ctx := context.Background()
+	for _, entry := range []*types.MemoryEntry{
+		{AgentID: "agent-1", Type: types.MemoryTypeTask, Content: "Built the checkout service"},
+		{AgentID: "agent-1", Type: types.MemoryTypeDecision, Content: "Decision: checkout uses Stripe"},
+		{AgentID: "agent-1", Type: types.MemoryTypeKnowledge, Content: "The checkout API is idempotent"},
+		{AgentID: "agent-1", Type: types.MemoryTypeConversation, Content: "Received checkout task"},
+		{AgentID: "agent-2", Type: types.MemoryTypeTask, Content: "Reviewed the checkout service"},
+	} {
+		if err := manager.StoreMemory(ctx, entry); err != nil {
+			t.Fatalf("Failed to store memory: %v", err)
+		}
+	}
+
+	t.Run("Sections", func(t *testing.T) {
+		block, err := manager.BuildContext(ctx, "agent-1", "checkout", 1000)
+		if err != nil {
+			t.Fatalf("Failed to build context: %v", err)
+		}
+		for _, want := range []string{"Past Work:\n- Built the checkout service", "Past Decisions:\n- Decision: checkout uses Stripe", "Relevant Knowledge:\n- The checkout API is idempotent"} {
+			if !strings.Contains(block, want) {
+				t.Errorf("Expected %q in the context:\n%s", want, block)
+			}
+		}
+		if strings.Contains(block, "Received checkout task") || strings.Contains(block, "Reviewed") {
+			t.Errorf("Expected conversations and other agents' memories left out:\n%s", block)
+		}
+	})
+
+	t.Run("Budget", func(t *testing.T) {
+		entries := []*types.MemoryEntry{
+			{AgentID: "agent-1", Type: types.MemoryTypeKnowledge, Content: "Less relevant", Score: 0.2},
+			{AgentID: "agent-1", Type: types.MemoryTypeTask, Content: "Most relevant", Score: 0.9},
+		}
+		budget := 25 // Room for the most relevant memory only
+		block := RenderContext(entries, "agent-1", budget)
+		if !strings.Contains(block, "Most relevant") || strings.Contains(block, "Less relevant") {
+			t.Errorf("Expected only the most relevant memory within the budget:\n%s", block)
+		}
+		if llm.EstimateTokens(block) > budget {
+			t.Errorf("Expected the context within %d tokens, got %d", budget, llm.EstimateTokens(block))
+		}
+		if block, _ := manager.BuildContext(ctx, "agent-1", "checkout", 0); block != "" {
+			t.Errorf("Expected no context without a budget, got:\n%s", block)
+		}
+	})
+}
+
+func TestLabelMemories(t *testing.T) {
+	config := &types.MemoryConfig{
+		Enabled: true,
+		SQLite: types.SQLiteConfig{
+			Enabled: true,
+			Path:    t.TempDir() + "/memory.db",
+		},
+	}
+
+	manager, err := NewManager(config, nil)
+	if err != nil {
+		t.Fatalf("Failed to create memory manager: %v", err)
+	}
+	defer manager.Close()
+
+	ctx := types.WithLabel(context.Background(), "team", "payments")
+	labeled := &types.MemoryEntry{AgentID: "agent-1", Type: types.MemoryTypeDecision, Content: "refunds", Metadata: map[string]string{"to_agent": "manager-1"}}
+	if err := manager.StoreMemory(ctx, labeled); err != nil {
+		t.Fatalf("Failed to store memory: %v", err)
+	}
+	other := &types.MemoryEntry{AgentID: "agent-1", Type: types.MemoryTypeDecision, Content: "search"}
+	if err := manager.StoreMemory(types.WithLabel(context.Background(), "team", "search"), other); err != nil {
+		t.Fatalf("Failed to store memory: %v", err)
+	}
+
+	entries, err := manager.QueryMemories(context.Background(), &types.MemoryQuery{AgentID: "agent-1", Metadata: map[string]string{"label.team": "payments"}})
+	if err != nil {
+		t.Fatalf("Failed to query memories: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Content != "refunds" || entries[0].Metadata["to_agent"] != "manager-1" {
+		t.Errorf("Expected the payments entry with its own metadata kept, got %+v", entries)
+	}
+}
+
+func TestSQLiteStoreMigratesRunID(t *testing.T) {
+	path := t.TempDir() + "/legacy.db"
+
+	// Create a database with the schema used before run IDs were added
+	legacy, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("Failed to open legacy database: %v", err)
+	}
+	_, err = legacy.Exec(`CREATE TABLE memory_entries (
+		id TEXT PRIMARY KEY, agent_id TEXT NOT NULL, type TEXT NOT NULL, content TEXT NOT NULL,
+		metadata TEXT, created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL, expires_at DATETIME, tags TEXT
+	)`)
+	if err == nil {
+		_, err = legacy.Exec(`INSERT INTO memory_entries VALUES ('old', 'agent-1', 'task', 'legacy', '{}', ?, ?, NULL, '[]')`, time.Now(), time.Now())
+	}
+	legacy.Close()
+	if err != nil {
+		t.Fatalf("Failed to create legacy schema: %v", err)
+	}
+
+	store, err := NewSQLiteStore(types.SQLiteConfig{Enabled: true, Path: path})
+	if err != nil {
+		t.Fatalf("Failed to open legacy database: %v", err)
+	}
+	defer store.Close()
+
+	entry, err := store.Retrieve(context.Background(), "old")
+	if err != nil {
+		t.Fatalf("Failed to retrieve legacy entry: %v", err)
+	}
+	if entry.RunID != "" {
+		t.Errorf("Expected empty run ID for legacy entry, got %q", entry.RunID)
+	}
+}
+
+func TestDeleteByFilter(t *testing.T) {
+	config := &types.MemoryConfig{
+		Enabled: true,
+		SQLite: types.SQLiteConfig{
+			Enabled:  true,
+			InMemory: true,
+		},
+	}
+
+	manager, err := NewManager(config, nil)
+	if err != nil {
+		t.Fatalf("Failed to create memory manager: %v", err)
+	}
+	defer manager.Close()
+
+	ctx := context.Background()
+	entries := []*types.MemoryEntry{
+		{AgentID: "agent-1", Type: types.MemoryTypeTask, Content: "client A requirements", Tags: []string{"client-a"}},
+		{AgentID: "agent-2", Type: types.MemoryTypeKnowledge, Content: "client A design", Tags: []string{"client-a", "design"}},
+		{AgentID: "agent-1", Type: types.MemoryTypeTask, Content: "client B requirements", Tags: []string{"client-b"}, Metadata: map[string]string{"email": "b@example.com"}},
+	}
+	if err := manager.StoreBatch(ctx, entries); err != nil {
+		t.Fatalf("Failed to store entries: %v", err)
+	}
+
+	if _, err := manager.DeleteByFilter(ctx, &types.MemoryQuery{}, DeleteOptions{}); err == nil {
+		t.Error("Expected empty filter to be rejected")
+	}
+
+	filter := &types.MemoryQuery{Tags: []string{"client-a"}}
+
+	dryRun, err := manager.DeleteByFilter(ctx, filter, DeleteOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if dryRun.Matched != 2 || dryRun.Deleted != 0 {
+		t.Errorf("Expected dry run to match 2 and delete 0, got %+v", dryRun)
+	}
+
+	result, err := manager.DeleteByFilter(ctx, filter, DeleteOptions{Requester: "dpo", Reason: "erasure request"})
+	if err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if result.Deleted != 2 || result.AuditID == "" {
+		t.Errorf("Expected 2 deleted with an audit record, got %+v", result)
+	}
+
+	remaining, _ := manager.QueryMemories(ctx, &types.MemoryQuery{})
+	if len(remaining) != 1 || remaining[0].Content != "client B requirements" {
+		t.Errorf("Expected only client B entry to remain, got %d entries", len(remaining))
+	}
+
+	audits, err := manager.DeletionAudits(ctx, 10)
+	if err != nil {
+		t.Fatalf("Failed to get audits: %v", err)
+	}
+	if len(audits) != 1 || audits[0].Requester != "dpo" || audits[0].Deleted != 2 || audits[0].Filter.Tags[0] != "client-a" {
+		t.Errorf("Unexpected audit records: %+v", audits)
+	}
+	if manager.DeletionGeneration() != 1 {
+		t.Errorf("Expected the deletion counted, got generation %d", manager.DeletionGeneration())
+	}
+
+	// The audit must not keep the text it was asked to erase
+	if _, err := manager.DeleteByFilter(ctx, &types.MemoryQuery{Content: "client B", Metadata: map[string]string{"email": "b@example.com"}}, DeleteOptions{}); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	audits, _ = manager.DeletionAudits(ctx, 1)
+	if len(audits) != 1 {
+		t.Fatal("Expected an audit of the content deletion")
+	}
+	if f := audits[0].Filter; !strings.HasPrefix(f.Content, "hmac-sha256:") || strings.Contains(f.Content, "client B") || !strings.HasPrefix(f.Metadata["email"], "hmac-sha256:") {
+		t.Errorf("Expected content and metadata values hashed in the audit, got %+v", f)
+	}
+}
+
+func TestDeletionAuditKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memory.db")
+	open := func() *SQLiteStore {
+		store, err := NewSQLiteStore(types.SQLiteConfig{Enabled: true, Path: path})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store
+	}
+
+	store := open()
+	filter := redactFilter(types.MemoryQuery{Content: "client B"}, store.auditKey)
+	store.Close()
+
+	// The key belongs to the install, so it survives a restart
+	reopened := open()
+	defer reopened.Close()
+	if again := redactFilter(types.MemoryQuery{Content: "client B"}, reopened.auditKey); again.Content != filter.Content {
+		t.Errorf("Expected the same HMAC after reopening, got %s and %s", filter.Content, again.Content)
+	}
+
+	// Another install redacts the same text differently
+	other, err := NewSQLiteStore(types.SQLiteConfig{Enabled: true, InMemory: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	if elsewhere := redactFilter(types.MemoryQuery{Content: "client B"}, other.auditKey); elsewhere.Content == filter.Content {
+		t.Error("Expected installs to use different audit keys")
+	}
+}
+
+func TestAssignments(t *testing.T) {
+	config := &types.MemoryConfig{
+		Enabled: true,
+		SQLite: types.SQLiteConfig{
+			Enabled:  true,
+			InMemory: true,
+		},
+	}
+
+	manager, err := NewManager(config, nil)
+	if err != nil {
+		t.Fatalf("Failed to create memory manager: %v", err)
+	}
+	defer manager.Close()
+
+	ctx := context.Background()
+	start := time.Now().Add(-time.Hour)
+	for i, a := range []*types.Assignment{
+		{AgentID: "engineer-1", TaskID: "t1", Title: "Payment API", Succeeded: true, Latency: 2 * time.Second},
+		{AgentID: "engineer-1", TaskID: "t2", Title: "Payment webhooks", Error: "tests failed", Latency: 4 * time.Second},
+		{AgentID: "engineer-2", TaskID: "t3", Title: "Landing page", Succeeded: true, Latency: time.Second},
+	} {
+		a.CreatedAt = start.Add(time.Duration(i) * time.Minute)
+		if err := manager.RecordAssignment(ctx, a); err != nil {
+			t.Fatalf("Failed to record assignment: %v", err)
+		}
+	}
+
+	assignments, err := manager.Assignments(ctx, []string{"engineer-1"}, 10)
+	if err != nil {
+		t.Fatalf("Failed to get assignments: %v", err)
+	}
+	if len(assignments) != 2 || assignments[0].TaskID != "t2" || assignments[0].Error != "tests failed" || assignments[0].ID == "" {
+		t.Errorf("Expected engineer-1's assignments newest first, got %+v", assignments)
+	}
+
+	stats, err := manager.AgentPerformance(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get performance: %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("Expected stats for 2 agents, got %+v", stats)
+	}
+	if got := stats[0]; got.AgentID != "engineer-1" || got.Assignments != 2 || got.Succeeded != 1 || got.SuccessRate != 0.5 || got.AverageLatency != 3*time.Second {
+		t.Errorf("Unexpected engineer-1 performance: %+v", got)
+	}
+}
+
+func TestJudgeScores(t *testing.T) {
+	config := &types.MemoryConfig{
+		Enabled: true,
+		SQLite: types.SQLiteConfig{
+			Enabled:  true,
+			InMemory: true,
+		},
+	}
+
+	manager, err := NewManager(config, nil)
+	if err != nil {
+		t.Fatalf("Failed to create memory manager: %v", err)
+	}
+	defer manager.Close()
+
+	ctx := context.Background()
+	start := time.Now().Add(-time.Hour)
+	for i, s := range []*types.JudgeScore{
+		{TaskID: "t1", RunID: "run-1", Judge: "correctness", Rating: 4, Score: 0.75, Reason: "Not stored"},
+		{TaskID: "t1", RunID: "run-1", Judge: "security", Rating: 2, Score: 0.25},
+		{TaskID: "t2", RunID: "run-2", Judge: "correctness", Rating: 5, Score: 1},
+	} {
+		s.CreatedAt = start.Add(time.Duration(i) * time.Minute)
+		if err := manager.RecordJudgeScore(ctx, s); err != nil {
+			t.Fatalf("Failed to record judge score: %v", err)
+		}
+	}
+
+	scores, err := manager.JudgeScores(ctx, "run-1", 10)
+	if err != nil {
+		t.Fatalf("Failed to get judge scores: %v", err)
+	}
+	if len(scores) != 2 || scores[0].Judge != "security" || scores[0].Score != 0.25 || scores[0].ID == "" || scores[1].Reason != "" {
+		t.Errorf("Expected run-1's scores newest first, got %+v", scores)
+	}
+
+	if all, _ := manager.JudgeScores(ctx, "", 2); len(all) != 2 || all[0].RunID != "run-2" {
+		t.Errorf("Expected the 2 newest scores of every run, got %+v", all)
+	}
+}
+
+func TestMemoryEffectiveness(t *testing.T) {
+	config := &types.MemoryConfig{
+		Enabled: true,
+		SQLite: types.SQLiteConfig{
+			Enabled:  true,
+			InMemory: true,
+		},
+	}
+
+	manager, err := NewManager(config, nil)
+	if err != nil {
+		t.Fatalf("Failed to create memory manager: %v", err)
+	}
+	defer manager.Close()
+
+	ctx := context.Background()
+	for _, r := range []*types.MemoryRetrieval{
+		{AgentID: "engineer-1", TaskID: "t1", RunID: "run-1", Latency: 10 * time.Millisecond},
+		{AgentID: "engineer-1", TaskID: "t2", RunID: "run-1", Hit: true, Tokens: 300, Latency: 30 * time.Millisecond},
+		{AgentID: "engineer-1", TaskID: "t3", RunID: "run-2", Latency: 20 * time.Millisecond},
+		{AgentID: "engineer-1", TaskID: "t4", RunID: "run-3", Hit: true, Tokens: 100, Latency: 20 * time.Millisecond},
+		{AgentID: "manager-1", TaskID: "t5", RunID: "run-1", Latency: 10 * time.Millisecond},
+	} {
+		if err := manager.RecordRetrieval(ctx, r); err != nil {
+			t.Fatalf("Failed to record retrieval: %v", err)
+		}
+	}
+	for _, s := range []*types.JudgeScore{
+		{TaskID: "t1", RunID: "run-1", Judge: "correctness", Rating: 5, Score: 1},
+		{TaskID: "t1", RunID: "run-1", Judge: "security", Rating: 4, Score: 0.75},
+		{TaskID: "t3", RunID: "run-2", Judge: "correctness", Rating: 2, Score: 0.25},
+	} {
+		if err := manager.RecordJudgeScore(ctx, s); err != nil {
+			t.Fatalf("Failed to record judge score: %v", err)
+		}
+	}
+	if err := manager.StoreMemory(ctx, &types.MemoryEntry{AgentID: "engineer-1", Type: types.MemoryTypeTask, Content: "Task: Payment API"}); err != nil {
+		t.Fatalf("Failed to store memory: %v", err)
+	}
+
+	stats, err := manager.MemoryEffectiveness(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get memory effectiveness: %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("Expected stats for 2 agents, got %+v", stats)
+	}
+	got := stats[0]
+	if got.AgentID != "engineer-1" || got.Retrievals != 4 || got.Hits != 2 || got.HitRate != 0.5 || got.AverageTokens != 200 || got.AverageLatency != 20*time.Millisecond || got.Entries != 1 {
+		t.Errorf("Unexpected engineer-1 lookups: %+v", got)
+	}
+	// run-3 was not scored; run-1 had a hit and run-2 none
+	if got.RunsWithHits != 1 || got.RunsWithoutHits != 1 || got.ScoreWithHits != 0.875 || got.ScoreWithoutHits != 0.25 || got.Lift != 0.625 {
+		t.Errorf("Unexpected engineer-1 outcomes: %+v", got)
+	}
+	if got := stats[1]; got.AgentID != "manager-1" || got.RunsWithoutHits != 1 || got.RunsWithHits != 0 || got.Lift != 0 {
+		t.Errorf("Expected manager-1 without a lift to compare, got %+v", got)
+	}
+}
+
+func TestArchiveRuns(t *testing.T) {
+	t.Setenv("TEST_ARCHIVE_KEY", testEncryptionKey(1))
+
+	for _, tt := range []struct {
+		name       string
+		encryption types.EncryptionConfig
+	}{
+		{"Plain", types.EncryptionConfig{}},
+		{"Encrypted", types.EncryptionConfig{Enabled: true, Key: types.EnvironmentVariable{Env: "TEST_ARCHIVE_KEY"}}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			config := &types.MemoryConfig{
+				Enabled: true,
+				SQLite:  types.SQLiteConfig{Enabled: true, InMemory: true, Encryption: tt.encryption},
+				Archive: types.ArchiveConfig{Dir: filepath.Join(dir, "archive")},
+			}
+
+			manager, err := NewManager(config, nil)
+			if err != nil {
+				t.Fatalf("Failed to create memory manager: %v", err)
+			}
+			defer manager.Close()
+
+			ctx := context.Background()
+			old := time.Now().Add(-48 * time.Hour)
+			entries := []*types.MemoryEntry{
+				{AgentID: "engineer-1", RunID: "run-old", Type: types.MemoryTypeTask, Content: "Old checkout design", CreatedAt: old},
+				{AgentID: "manager-1", RunID: "run-old", Type: types.MemoryTypeDecision, Content: "Old decision", CreatedAt: old},
+				{AgentID: "engineer-1", RunID: "run-new", Type: types.MemoryTypeTask, Content: "Current work"},
+			}
+			if err := manager.StoreBatch(ctx, entries); err != nil {
+				t.Fatalf("Failed to store entries: %v", err)
+			}
+
+			archived, err := manager.ArchiveRuns(ctx, time.Now().Add(-24*time.Hour))
+			if err != nil {
+				t.Fatalf("Failed to archive runs: %v", err)
+			}
+			if len(archived) != 1 || archived[0].RunID != "run-old" || archived[0].Entries != 2 {
+				t.Fatalf("Expected only the inactive run to be archived, got %+v", archived)
+			}
+
+			if remaining, _ := manager.GetRunMemories(ctx, "run-old"); len(remaining) != 0 {
+				t.Errorf("Expected archived memories to leave the database, %d remain", len(remaining))
+			}
+			if current, _ := manager.GetRunMemories(ctx, "run-new"); len(current) != 1 {
+				t.Errorf("Expected the active run to stay, got %d entries", len(current))
+			}
+
+			raw, err := os.ReadFile(archived[0].Path)
+			if err != nil {
+				t.Fatalf("Expected a bundle on disk: %v", err)
+			}
+			if tt.encryption.Enabled && !strings.HasPrefix(string(raw), encryptedPrefix) {
+				t.Error("Expected the bundle to be encrypted")
+			}
+
+			index, err := manager.ArchivedRuns(ctx)
+			if err != nil || len(index) != 1 || index[0].Path != archived[0].Path {
+				t.Fatalf("Expected the run in the archive index, got %+v, %v", index, err)
+			}
+
+			// Erasing reaches archived runs, so restoring cannot bring entries back
+			erased, err := manager.DeleteByFilter(ctx, &types.MemoryQuery{Content: "old decision"}, DeleteOptions{})
+			if err != nil {
+				t.Fatalf("Failed to delete from the archive: %v", err)
+			}
+			if erased.Matched != 1 || erased.Deleted != 1 {
+				t.Errorf("Expected the archived decision erased, got %+v", erased)
+			}
+			if index, _ := manager.ArchivedRuns(ctx); len(index) != 1 || index[0].Entries != 1 {
+				t.Errorf("Expected the archive index to count the entry left, got %+v", index)
+			}
+
+			restored, err := manager.RestoreRun(ctx, "run-old")
+			if err != nil {
+				t.Fatalf("Failed to restore run: %v", err)
+			}
+			if restored != 1 {
+				t.Errorf("Expected 1 entry restored, got %d", restored)
+			}
+			back, _ := manager.GetRunMemories(ctx, "run-old")
+			if len(back) != 1 || back[0].Content != "Old checkout design" || !back[0].CreatedAt.Equal(old) {
+				t.Errorf("Expected the original memories back, got %+v", back)
+			}
+			if index, _ := manager.ArchivedRuns(ctx); len(index) != 0 {
+				t.Errorf("Expected the restored run to leave the index, got %+v", index)
+			}
+			if _, err := os.Stat(archived[0].Path); !os.IsNotExist(err) {
+				t.Errorf("Expected the bundle to be removed after restore, got %v", err)
+			}
+
+			if _, err := manager.RestoreRun(ctx, "run-new"); err == nil {
+				t.Error("Expected restoring a run that is not archived to fail")
+			}
+
+			// Erasing every entry of an archived run removes its bundle
+			again, err := manager.ArchiveRuns(ctx, time.Now().Add(-24*time.Hour))
+			if err != nil || len(again) != 1 {
+				t.Fatalf("Failed to archive the run again: %+v, %v", again, err)
+			}
+			if _, err := manager.DeleteByFilter(ctx, &types.MemoryQuery{RunID: "run-old"}, DeleteOptions{}); err != nil {
+				t.Fatalf("Failed to delete the archived run: %v", err)
+			}
+			if index, _ := manager.ArchivedRuns(ctx); len(index) != 0 {
+				t.Errorf("Expected the erased run to leave the index, got %+v", index)
+			}
+			if _, err := os.Stat(again[0].Path); !os.IsNotExist(err) {
+				t.Errorf("Expected the bundle of the erased run removed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestSQLiteStoreEncryption(t *testing.T) {
+	path := t.TempDir() + "/encrypted.db"
+	ctx := context.Background()
+
+	t.Setenv("TEST_MEMORY_KEY_1", testEncryptionKey(1))
+	t.Setenv("TEST_MEMORY_KEY_2", testEncryptionKey(2))
+	t.Setenv("TEST_MEMORY_PASSPHRASE", "first-secret")
+
+	rawContent := func(id string) string {
+		db, err := sql.Open("sqlite3", path)
+		if err != nil {
+			t.Fatalf("Failed to open database: %v", err)
+		}
+		defer db.Close()
+
+		var content string
+		if err := db.QueryRow("SELECT content FROM memory_entries WHERE id = ?", id).Scan(&content); err != nil {
+			t.Fatalf("Failed to read raw content: %v", err)
+		}
+		return content
+	}
+
+	open := func(encryption types.EncryptionConfig) *SQLiteStore {
+		store, err := NewSQLiteStore(types.SQLiteConfig{Enabled: true, Path: path, Encryption: encryption})
+		if err != nil {
+			t.Fatalf("Failed to open store: %v", err)
+		}
+		return store
+	}
+
+	// Start with a plaintext database
+	store := open(types.EncryptionConfig{})
+	entry := &types.MemoryEntry{
+		ID: "secret-1", AgentID: "agent-1", Type: types.MemoryTypeTask,
+		Content:   "Client requires on-premise deployment",
+		Metadata:  map[string]string{"client": "acme"},
+		CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}
+	if err := store.Store(ctx, entry); err != nil {
+		t.Fatalf("Failed to store entry: %v", err)
+	}
+	store.Close()
+
+	// Enabling encryption migrates existing rows
+	first := types.EncryptionConfig{Enabled: true, Key: types.EnvironmentVariable{Env: "TEST_MEMORY_KEY_1"}}
+	store = open(first)
+	firstCiphertext := rawContent("secret-1")
+	if !strings.HasPrefix(firstCiphertext, encryptedPrefix) || strings.Contains(firstCiphertext, "on-premise") {
+		t.Errorf("Expected content to be encrypted at rest, got %q", firstCiphertext)
+	}
+
+	results, err := store.Query(ctx, &types.MemoryQuery{Content: "ON-PREMISE", Metadata: map[string]string{"client": "acme"}})
+	if err != nil {
+		t.Fatalf("Failed to query encrypted store: %v", err)
+	}
+	if len(results) != 1 || results[0].Content != entry.Content {
+		t.Errorf("Expected decrypted match, got %d results", len(results))
+	}
+	store.Close()
+
+	// Rotating keys rewrites rows under the new key
+	rotated := types.EncryptionConfig{
+		Enabled:      true,
+		Key:          types.EnvironmentVariable{Env: "TEST_MEMORY_KEY_2"},
+		PreviousKeys: []types.EnvironmentVariable{{Env: "TEST_MEMORY_KEY_1"}},
+	}
+	store = open(rotated)
+	if rawContent("secret-1") == firstCiphertext {
+		t.Error("Expected content to be re-encrypted with the new key")
+	}
+	store.Close()
+
+	// Once rotated, the previous key is no longer needed
+	store = open(types.EncryptionConfig{Enabled: true, Key: types.EnvironmentVariable{Env: "TEST_MEMORY_KEY_2"}})
+	defer store.Close()
+	got, err := store.Retrieve(ctx, "secret-1")
+	if err != nil {
+		t.Fatalf("Failed to retrieve after rotation: %v", err)
+	}
+	if got.Content != entry.Content || got.Metadata["client"] != "acme" {
+		t.Errorf("Unexpected decrypted entry: %+v", got)
+	}
+
+	// Ciphertexts are bound to their row, so one copied to another fails to decrypt
+	copied := *entry
+	copied.ID = "secret-2"
+	if err := store.Store(ctx, &copied); err != nil {
+		t.Fatalf("Failed to store entry: %v", err)
+	}
+	if _, err := store.db.ExecContext(ctx, "UPDATE memory_entries SET content = ? WHERE id = ?", rawContent("secret-1"), "secret-2"); err != nil {
+		t.Fatalf("Failed to copy ciphertext: %v", err)
+	}
+	if _, err := store.Retrieve(ctx, "secret-2"); err == nil {
+		t.Error("Expected a ciphertext copied from another row to fail to decrypt")
+	}
+
+	// Passphrases are not keys
+	if _, err := NewSQLiteStore(types.SQLiteConfig{Enabled: true, InMemory: true, Encryption: types.EncryptionConfig{
+		Enabled: true, Key: types.EnvironmentVariable{Env: "TEST_MEMORY_PASSPHRASE"},
+	}}); err == nil || !strings.Contains(err.Error(), "TEST_MEMORY_PASSPHRASE") {
+		t.Errorf("Expected a passphrase rejected as a key, got %v", err)
+	}
+}
+
+// testEncryptionKey returns a base64 encoded 32-byte key filled with b.
+func testEncryptionKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, encryptionKeySize))
+}
+
+// FuzzSQLiteQuery checks that query filters built from untrusted content,
+// tags and metadata are bound as values: a query for a stored entry's own
+// filters finds it, and content matches are literal substrings.
+func FuzzSQLiteQuery(f *testing.F) {
+	f.Add("hello world", "tag", "key", "value")
+	f.Add("' OR 1=1 --", "\") OR 1=1 --", "a'b", "\"; DROP TABLE memory_entries; --")
+	f.Add("100%", "_", "$.key", "%")
+	f.Add("a_b", "[\"x\"]", "nested.key", "")
+	f.Add("%", "tag", "\a", "value")
+	f.Add("_", "tag", "line\nbreak", "value")
+	f.Add("", "", "", "")
+
+	store, err := NewSQLiteStore(types.SQLiteConfig{Enabled: true, InMemory: true})
+	if err != nil {
+		f.Fatalf("Failed to create SQLite store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	var n int
+	f.Fuzz(func(t *testing.T, content, tag, key, value string) {
+		if !utf8.ValidString(content) || !utf8.ValidString(tag) || !utf8.ValidString(key) || !utf8.ValidString(value) {
+			t.Skip("memory content is text")
+		}
+		if strings.ContainsRune(content, 0) {
+			t.Skip("SQLite ends LIKE patterns at a NUL byte")
+		}
+
+		n++
+		agentID := fmt.Sprintf("fuzz-%d", n)
+		now := time.Now()
+		entry := &types.MemoryEntry{
+			ID:        agentID + "-entry",
+			AgentID:   agentID,
+			Type:      types.MemoryTypeConversation,
+			Content:   content,
+			Metadata:  map[string]string{key: value},
+			Tags:      []string{tag},
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		// An entry that only matches content with wildcards in it
+		other := &types.MemoryEntry{
+			ID:        agentID + "-other",
+			AgentID:   agentID,
+			Type:      types.MemoryTypeConversation,
+			Content:   "unrelated",
+			Metadata:  map[string]string{},
+			Tags:      []string{},
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if err := store.StoreBatch(ctx, []*types.MemoryEntry{entry, other}); err != nil {
+			t.Fatalf("Failed to store entries: %v", err)
+		}
+
+		entries, err := store.Query(ctx, &types.MemoryQuery{
+			AgentID:  agentID,
+			Content:  content,
+			Tags:     []string{tag},
+			Metadata: map[string]string{key: value},
+		})
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if len(entries) != 1 || entries[0].ID != entry.ID {
+			t.Fatalf("Expected only %s, got %d entries", entry.ID, len(entries))
+		}
+
+		entries, err = store.Query(ctx, &types.MemoryQuery{AgentID: agentID, Content: content})
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		for _, e := range entries {
+			if !strings.Contains(strings.ToLower(e.Content), strings.ToLower(content)) {
+				t.Fatalf("Content %q matched query %q", e.Content, content)
+			}
+		}
+	})
+}
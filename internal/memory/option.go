@@ -0,0 +1,56 @@
+package memory
+
+import "github.com/kpango/BuildBureau/pkg/types"
+
+// Option configures a Manager at construction time.
+type Option func(*Manager)
+
+// WithStore uses the given structured store instead of creating one from config.
+func WithStore(store types.MemoryStore) Option {
+	return func(m *Manager) {
+		m.sqliteStore = store
+	}
+}
+
+// WithVectorStore uses the given vector store instead of creating one from config.
+func WithVectorStore(store types.VectorStore) Option {
+	return func(m *Manager) {
+		m.valdStore = store
+	}
+}
+
+// WithEmbeddingDimension overrides the embedding dimension from the Vald config.
+func WithEmbeddingDimension(dim int) Option {
+	return func(m *Manager) {
+		m.embeddingDim = dim
+	}
+}
+
+// WithAsyncWrites enables the write-behind queue with the given queue and batch sizes.
+// Non-positive sizes use the defaults.
+func WithAsyncWrites(queueSize, batchSize int) Option {
+	return func(m *Manager) {
+		if queueSize <= 0 {
+			queueSize = defaultQueueSize
+		}
+		m.queueSize = queueSize
+		m.batchSize = batchSize
+	}
+}
+
+// WithErrorHandler sets the callback for writes that fail in the background.
+func WithErrorHandler(handler ErrorHandler) Option {
+	return func(m *Manager) {
+		if handler != nil {
+			m.onError = handler
+		}
+	}
+}
+
+// WithPause sets a check that pauses background work, such as archiving,
+// while it reports true.
+func WithPause(paused func() bool) Option {
+	return func(m *Manager) {
+		m.paused = paused
+	}
+}
@@ -0,0 +1,46 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// retrievalStore is implemented by stores that keep memory lookups.
+type retrievalStore interface {
+	RecordRetrieval(ctx context.Context, retrieval *types.MemoryRetrieval) error
+	MemoryEffectiveness(ctx context.Context) ([]types.MemoryEffectiveness, error)
+}
+
+// RecordRetrieval records an agent's lookup of its memories for a task.
+func (m *Manager) RecordRetrieval(ctx context.Context, retrieval *types.MemoryRetrieval) error {
+	store, ok := m.sqliteStore.(retrievalStore)
+	if !ok {
+		return fmt.Errorf("sqlite store does not support memory retrievals")
+	}
+
+	if retrieval.ID == "" {
+		retrieval.ID = uuid.New().String()
+	}
+	if retrieval.CreatedAt.IsZero() {
+		retrieval.CreatedAt = time.Now()
+	}
+
+	return store.RecordRetrieval(ctx, retrieval)
+}
+
+// MemoryEffectiveness returns, for every agent that has looked up its
+// memories, how often they were found, what the lookups cost, and the judge
+// scores of the projects in which they were and were not.
+func (m *Manager) MemoryEffectiveness(ctx context.Context) ([]types.MemoryEffectiveness, error) {
+	store, ok := m.sqliteStore.(retrievalStore)
+	if !ok {
+		return nil, fmt.Errorf("sqlite store does not support memory retrievals")
+	}
+
+	return store.MemoryEffectiveness(ctx)
+}
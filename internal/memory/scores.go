@@ -0,0 +1,45 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// judgeScoreStore is implemented by stores that keep judge scores.
+type judgeScoreStore interface {
+	RecordJudgeScore(ctx context.Context, score *types.JudgeScore) error
+	JudgeScores(ctx context.Context, runID string, limit int) ([]types.JudgeScore, error)
+}
+
+// RecordJudgeScore records a judge's rating of a task's output.
+func (m *Manager) RecordJudgeScore(ctx context.Context, score *types.JudgeScore) error {
+	store, ok := m.sqliteStore.(judgeScoreStore)
+	if !ok {
+		return fmt.Errorf("sqlite store does not support judge scores")
+	}
+
+	if score.ID == "" {
+		score.ID = uuid.New().String()
+	}
+	if score.CreatedAt.IsZero() {
+		score.CreatedAt = time.Now()
+	}
+
+	return store.RecordJudgeScore(ctx, score)
+}
+
+// JudgeScores returns the most recent judge scores of a run, or of every run
+// if runID is empty, newest first.
+func (m *Manager) JudgeScores(ctx context.Context, runID string, limit int) ([]types.JudgeScore, error) {
+	store, ok := m.sqliteStore.(judgeScoreStore)
+	if !ok {
+		return nil, fmt.Errorf("sqlite store does not support judge scores")
+	}
+
+	return store.JudgeScores(ctx, runID, limit)
+}
@@ -2,9 +2,13 @@ package memory
 
 import (
 	"context"
+	"crypto/rand"
 	"database/sql"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -13,12 +17,22 @@ import (
 )
 
 // SQLiteStore implements MemoryStore using SQLite.
+//
+// When encryption is enabled, entry content and metadata are encrypted at rest.
+// Content and metadata filters are then applied after decryption instead of in SQL.
 type SQLiteStore struct {
-	db *sql.DB
+	db       *sql.DB
+	cipher   *contentCipher
+	auditKey []byte // Per-install HMAC key for the values erased in deletion audits
 }
 
 // NewSQLiteStore creates a new SQLite memory store.
 func NewSQLiteStore(config types.SQLiteConfig) (*SQLiteStore, error) {
+	contentCipher, err := newContentCipher(config.Encryption)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure encryption: %w", err)
+	}
+
 	var dsn string
 	if config.InMemory {
 		dsn = ":memory:"
@@ -31,6 +45,11 @@ func NewSQLiteStore(config types.SQLiteConfig) (*SQLiteStore, error) {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
+	// Every connection to ":memory:" opens a separate database, so keep a single one
+	if config.InMemory {
+		db.SetMaxOpenConns(1)
+	}
+
 	// Enable foreign keys and set pragmas for better performance
 	pragmas := []string{
 		"PRAGMA foreign_keys = ON",
@@ -47,7 +66,7 @@ func NewSQLiteStore(config types.SQLiteConfig) (*SQLiteStore, error) {
 		}
 	}
 
-	store := &SQLiteStore{db: db}
+	store := &SQLiteStore{db: db, cipher: contentCipher}
 
 	// Initialize schema
 	if err := store.initSchema(); err != nil {
@@ -55,6 +74,19 @@ func NewSQLiteStore(config types.SQLiteConfig) (*SQLiteStore, error) {
 		return nil, fmt.Errorf("failed to initialize schema: %w", err)
 	}
 
+	if store.auditKey, err = store.loadAuditKey(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to load audit key: %w", err)
+	}
+
+	// Encrypt plaintext rows and rotate rows written with previous keys
+	if contentCipher != nil {
+		if _, err := store.Reencrypt(context.Background()); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to migrate encryption: %w", err)
+		}
+	}
+
 	return store, nil
 }
 
@@ -70,25 +102,178 @@ func (s *SQLiteStore) initSchema() error {
 		created_at DATETIME NOT NULL,
 		updated_at DATETIME NOT NULL,
 		expires_at DATETIME,
-		tags TEXT
+		tags TEXT,
+		run_id TEXT
 	);
 
 	CREATE INDEX IF NOT EXISTS idx_agent_id ON memory_entries(agent_id);
 	CREATE INDEX IF NOT EXISTS idx_type ON memory_entries(type);
 	CREATE INDEX IF NOT EXISTS idx_created_at ON memory_entries(created_at);
 	CREATE INDEX IF NOT EXISTS idx_expires_at ON memory_entries(expires_at);
+
+	CREATE TABLE IF NOT EXISTS memory_deletions (
+		id TEXT PRIMARY KEY,
+		requester TEXT,
+		reason TEXT,
+		filter TEXT NOT NULL,
+		deleted INTEGER NOT NULL,
+		created_at DATETIME NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS memory_settings (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS assignments (
+		id TEXT PRIMARY KEY,
+		agent_id TEXT NOT NULL,
+		assigned_by TEXT,
+		task_id TEXT NOT NULL,
+		run_id TEXT,
+		title TEXT,
+		succeeded INTEGER NOT NULL,
+		error TEXT,
+		latency_ms INTEGER NOT NULL,
+		created_at DATETIME NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_assignments_agent_id ON assignments(agent_id, created_at);
+
+	CREATE TABLE IF NOT EXISTS judge_scores (
+		id TEXT PRIMARY KEY,
+		task_id TEXT NOT NULL,
+		run_id TEXT,
+		judge TEXT NOT NULL,
+		rating INTEGER NOT NULL,
+		score REAL NOT NULL,
+		created_at DATETIME NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_judge_scores_run_id ON judge_scores(run_id, created_at);
+
+	CREATE TABLE IF NOT EXISTS memory_retrievals (
+		id TEXT PRIMARY KEY,
+		agent_id TEXT NOT NULL,
+		task_id TEXT NOT NULL,
+		run_id TEXT,
+		hit INTEGER NOT NULL,
+		tokens INTEGER NOT NULL,
+		latency_ms INTEGER NOT NULL,
+		created_at DATETIME NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_memory_retrievals_agent_id ON memory_retrievals(agent_id, run_id);
+
+	CREATE TABLE IF NOT EXISTS archived_runs (
+		run_id TEXT PRIMARY KEY,
+		path TEXT NOT NULL,
+		entries INTEGER NOT NULL,
+		last_activity DATETIME NOT NULL,
+		archived_at DATETIME NOT NULL
+	);
 	`
 
-	_, err := s.db.Exec(schema)
+	if _, err := s.db.Exec(schema); err != nil {
+		return err
+	}
+
+	// Databases created before run IDs existed need the column added
+	if err := s.addColumnIfMissing("run_id", "TEXT"); err != nil {
+		return err
+	}
+
+	_, err := s.db.Exec("CREATE INDEX IF NOT EXISTS idx_run_id ON memory_entries(run_id)")
+	return err
+}
+
+// addColumnIfMissing adds a column to memory_entries unless it already exists.
+func (s *SQLiteStore) addColumnIfMissing(name, columnType string) error {
+	rows, err := s.db.Query("SELECT name FROM pragma_table_info('memory_entries')")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return err
+		}
+		if column == name {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(fmt.Sprintf("ALTER TABLE memory_entries ADD COLUMN %s %s", name, columnType))
 	return err
 }
 
 // Store saves a memory entry.
 func (s *SQLiteStore) Store(ctx context.Context, entry *types.MemoryEntry) error {
+	return s.insert(ctx, s.db, entry)
+}
+
+// StoreBatch saves multiple memory entries in a single transaction.
+func (s *SQLiteStore) StoreBatch(ctx context.Context, entries []*types.MemoryEntry) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	for _, entry := range entries {
+		if err := s.insert(ctx, tx, entry); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// settingAuditKey is the memory_settings key holding the audit key.
+const settingAuditKey = "audit_key"
+
+// loadAuditKey returns the install's audit key, generating it on first use.
+func (s *SQLiteStore) loadAuditKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if _, err := s.db.Exec("INSERT OR IGNORE INTO memory_settings (key, value) VALUES (?, ?)", settingAuditKey, base64.StdEncoding.EncodeToString(key)); err != nil {
+		return nil, err
+	}
+
+	var encoded string
+	if err := s.db.QueryRow("SELECT value FROM memory_settings WHERE key = ?", settingAuditKey).Scan(&encoded); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(encoded)
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// insert writes a single entry using the given connection or transaction.
+func (s *SQLiteStore) insert(ctx context.Context, db execer, entry *types.MemoryEntry) error {
 	// Serialize metadata and tags
-	metadataJSON, err := json.Marshal(entry.Metadata)
+	content, metadata, err := s.sealEntry(entry)
 	if err != nil {
-		return fmt.Errorf("failed to marshal metadata: %w", err)
+		return err
 	}
 
 	tagsJSON, err := json.Marshal(entry.Tags)
@@ -97,20 +282,21 @@ func (s *SQLiteStore) Store(ctx context.Context, entry *types.MemoryEntry) error
 	}
 
 	query := `
-		INSERT INTO memory_entries (id, agent_id, type, content, metadata, created_at, updated_at, expires_at, tags)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO memory_entries (id, agent_id, type, content, metadata, created_at, updated_at, expires_at, tags, run_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err = s.db.ExecContext(ctx, query,
+	_, err = db.ExecContext(ctx, query,
 		entry.ID,
 		entry.AgentID,
 		entry.Type,
-		entry.Content,
-		string(metadataJSON),
+		content,
+		metadata,
 		entry.CreatedAt,
 		entry.UpdatedAt,
 		entry.ExpiresAt,
 		string(tagsJSON),
+		entry.RunID,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to store memory: %w", err)
@@ -122,7 +308,7 @@ func (s *SQLiteStore) Store(ctx context.Context, entry *types.MemoryEntry) error
 // Retrieve gets a memory entry by ID.
 func (s *SQLiteStore) Retrieve(ctx context.Context, id string) (*types.MemoryEntry, error) {
 	query := `
-		SELECT id, agent_id, type, content, metadata, created_at, updated_at, expires_at, tags
+		SELECT id, agent_id, type, content, metadata, created_at, updated_at, expires_at, tags, COALESCE(run_id, '')
 		FROM memory_entries
 		WHERE id = ?
 	`
@@ -141,6 +327,7 @@ func (s *SQLiteStore) Retrieve(ctx context.Context, id string) (*types.MemoryEnt
 		&entry.UpdatedAt,
 		&expiresAtStr,
 		&tagsJSON,
+		&entry.RunID,
 	)
 
 	if err == sql.ErrNoRows {
@@ -151,8 +338,8 @@ func (s *SQLiteStore) Retrieve(ctx context.Context, id string) (*types.MemoryEnt
 	}
 
 	// Deserialize metadata and tags
-	if err := json.Unmarshal([]byte(metadataJSON), &entry.Metadata); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+	if err := s.openEntry(&entry, metadataJSON); err != nil {
+		return nil, err
 	}
 
 	if err := json.Unmarshal([]byte(tagsJSON), &entry.Tags); err != nil {
@@ -171,7 +358,12 @@ func (s *SQLiteStore) Retrieve(ctx context.Context, id string) (*types.MemoryEnt
 
 // Query searches for memory entries matching the query.
 func (s *SQLiteStore) Query(ctx context.Context, query *types.MemoryQuery) ([]*types.MemoryEntry, error) {
-	sql := "SELECT id, agent_id, type, content, metadata, created_at, updated_at, expires_at, tags FROM memory_entries WHERE 1=1"
+	return s.query(ctx, s.db, query)
+}
+
+// query runs a memory query using the given connection or transaction.
+func (s *SQLiteStore) query(ctx context.Context, db queryer, query *types.MemoryQuery) ([]*types.MemoryEntry, error) {
+	sql := "SELECT id, agent_id, type, content, metadata, created_at, updated_at, expires_at, tags, COALESCE(run_id, '') FROM memory_entries WHERE 1=1"
 	args := []any{}
 
 	if query.AgentID != "" {
@@ -179,14 +371,36 @@ func (s *SQLiteStore) Query(ctx context.Context, query *types.MemoryQuery) ([]*t
 		args = append(args, query.AgentID)
 	}
 
+	if query.RunID != "" {
+		sql += " AND run_id = ?"
+		args = append(args, query.RunID)
+	}
+
 	if query.Type != "" {
 		sql += " AND type = ?"
 		args = append(args, query.Type)
 	}
 
-	if query.Content != "" {
-		sql += " AND content LIKE ?"
-		args = append(args, "%"+query.Content+"%")
+	// Encrypted content and metadata can only be matched after decryption
+	filterAfter := s.cipher != nil && (query.Content != "" || len(query.Metadata) > 0)
+
+	if query.Content != "" && !filterAfter {
+		sql += ` AND content LIKE ? ESCAPE '\'`
+		args = append(args, "%"+likeEscaper.Replace(query.Content)+"%")
+	}
+
+	for _, tag := range query.Tags {
+		sql += " AND EXISTS (SELECT 1 FROM json_each(tags) WHERE value = ?)"
+		args = append(args, tag)
+	}
+
+	for key, value := range query.Metadata {
+		if filterAfter {
+			break
+		}
+		// Match keys with json_each rather than a JSON path, which would need the key escaped
+		sql += " AND EXISTS (SELECT 1 FROM json_each(metadata) WHERE key = ? AND value = ?)"
+		args = append(args, key, value)
 	}
 
 	if query.TimeRange != nil {
@@ -198,17 +412,17 @@ func (s *SQLiteStore) Query(ctx context.Context, query *types.MemoryQuery) ([]*t
 	sql += " ORDER BY created_at DESC"
 
 	// Add limit and offset
-	if query.Limit > 0 {
+	if query.Limit > 0 && !filterAfter {
 		sql += " LIMIT ?"
 		args = append(args, query.Limit)
 	}
 
-	if query.Offset > 0 {
+	if query.Offset > 0 && !filterAfter {
 		sql += " OFFSET ?"
 		args = append(args, query.Offset)
 	}
 
-	rows, err := s.db.QueryContext(ctx, sql, args...)
+	rows, err := db.QueryContext(ctx, sql, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query memories: %w", err)
 	}
@@ -230,14 +444,19 @@ func (s *SQLiteStore) Query(ctx context.Context, query *types.MemoryQuery) ([]*t
 			&entry.UpdatedAt,
 			&expiresAtStr,
 			&tagsJSON,
+			&entry.RunID,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
 
 		// Deserialize metadata and tags
-		if err := json.Unmarshal([]byte(metadataJSON), &entry.Metadata); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+		if err := s.openEntry(&entry, metadataJSON); err != nil {
+			return nil, err
+		}
+
+		if filterAfter && !matchesEncryptedFilter(&entry, query) {
+			continue
 		}
 
 		if err := json.Unmarshal([]byte(tagsJSON), &entry.Tags); err != nil {
@@ -258,14 +477,18 @@ func (s *SQLiteStore) Query(ctx context.Context, query *types.MemoryQuery) ([]*t
 		return nil, fmt.Errorf("error iterating rows: %w", err)
 	}
 
+	if filterAfter {
+		entries = paginate(entries, query.Offset, query.Limit)
+	}
+
 	return entries, nil
 }
 
 // Update updates an existing memory entry.
 func (s *SQLiteStore) Update(ctx context.Context, entry *types.MemoryEntry) error {
-	metadataJSON, err := json.Marshal(entry.Metadata)
+	content, metadata, err := s.sealEntry(entry)
 	if err != nil {
-		return fmt.Errorf("failed to marshal metadata: %w", err)
+		return err
 	}
 
 	tagsJSON, err := json.Marshal(entry.Tags)
@@ -282,8 +505,8 @@ func (s *SQLiteStore) Update(ctx context.Context, entry *types.MemoryEntry) erro
 	`
 
 	result, err := s.db.ExecContext(ctx, query,
-		entry.Content,
-		string(metadataJSON),
+		content,
+		metadata,
 		entry.UpdatedAt,
 		entry.ExpiresAt,
 		string(tagsJSON),
@@ -325,6 +548,444 @@ func (s *SQLiteStore) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+// DeleteMatching removes the entries matching filter and records the
+// deletion audit in a single transaction, so that entries written meanwhile
+// are neither deleted unaudited nor missed. The audit's filter is redacted with
+// the install's audit key, and the entries deleted are added to its count;
+// nothing is recorded if nothing was deleted. It returns the deleted IDs.
+func (s *SQLiteStore) DeleteMatching(ctx context.Context, filter *types.MemoryQuery, audit *types.DeletionAudit) ([]string, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	entries, err := s.query(ctx, tx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM memory_entries WHERE id = ?", entry.ID); err != nil {
+			return nil, fmt.Errorf("failed to delete memory: %w", err)
+		}
+		ids = append(ids, entry.ID)
+	}
+
+	if audit != nil && len(ids)+audit.Deleted > 0 {
+		audit.Deleted += len(ids) // Counting what was deleted elsewhere, such as from archives
+		audit.Filter = redactFilter(audit.Filter, s.auditKey)
+		filterJSON, err := json.Marshal(audit.Filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal filter: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO memory_deletions (id, requester, reason, filter, deleted, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+			audit.ID, audit.Requester, audit.Reason, string(filterJSON), audit.Deleted, audit.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record deletion audit: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return ids, nil
+}
+
+// DeletionAudits returns recorded deletions, newest first.
+func (s *SQLiteStore) DeletionAudits(ctx context.Context, limit int) ([]*types.DeletionAudit, error) {
+	query := "SELECT id, COALESCE(requester, ''), COALESCE(reason, ''), filter, deleted, created_at FROM memory_deletions ORDER BY created_at DESC"
+	args := []any{}
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deletion audits: %w", err)
+	}
+	defer rows.Close()
+
+	var audits []*types.DeletionAudit
+	for rows.Next() {
+		var audit types.DeletionAudit
+		var filterJSON string
+		if err := rows.Scan(&audit.ID, &audit.Requester, &audit.Reason, &filterJSON, &audit.Deleted, &audit.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		if err := json.Unmarshal([]byte(filterJSON), &audit.Filter); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal filter: %w", err)
+		}
+		audits = append(audits, &audit)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return audits, nil
+}
+
+// RecordAssignment stores an assignment and its outcome. Like entry content,
+// the task title and error are encrypted when encryption is enabled.
+func (s *SQLiteStore) RecordAssignment(ctx context.Context, assignment *types.Assignment) error {
+	title, err := s.cipher.encrypt(assignment.Title, binding("assignments", assignment.ID, "title"))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt title: %w", err)
+	}
+	message, err := s.cipher.encrypt(assignment.Error, binding("assignments", assignment.ID, "error"))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt error: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx,
+		"INSERT INTO assignments (id, agent_id, assigned_by, task_id, run_id, title, succeeded, error, latency_ms, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		assignment.ID, assignment.AgentID, assignment.AssignedBy, assignment.TaskID, assignment.RunID, title,
+		assignment.Succeeded, message, assignment.Latency.Milliseconds(), assignment.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record assignment: %w", err)
+	}
+	return nil
+}
+
+// Assignments returns the assignments of the given agents, or of every agent
+// if none are given, newest first.
+func (s *SQLiteStore) Assignments(ctx context.Context, agentIDs []string, limit int) ([]*types.Assignment, error) {
+	query := "SELECT id, agent_id, COALESCE(assigned_by, ''), task_id, COALESCE(run_id, ''), COALESCE(title, ''), succeeded, COALESCE(error, ''), latency_ms, created_at FROM assignments"
+	args := []any{}
+	if len(agentIDs) > 0 {
+		query += " WHERE agent_id IN (?" + strings.Repeat(", ?", len(agentIDs)-1) + ")"
+		for _, id := range agentIDs {
+			args = append(args, id)
+		}
+	}
+	query += " ORDER BY created_at DESC"
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query assignments: %w", err)
+	}
+	defer rows.Close()
+
+	var assignments []*types.Assignment
+	for rows.Next() {
+		var a types.Assignment
+		var latency int64
+		if err := rows.Scan(&a.ID, &a.AgentID, &a.AssignedBy, &a.TaskID, &a.RunID, &a.Title, &a.Succeeded, &a.Error, &latency, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		if a.Title, err = s.cipher.decrypt(a.Title, binding("assignments", a.ID, "title")); err != nil {
+			return nil, fmt.Errorf("failed to decrypt assignment %s: %w", a.ID, err)
+		}
+		if a.Error, err = s.cipher.decrypt(a.Error, binding("assignments", a.ID, "error")); err != nil {
+			return nil, fmt.Errorf("failed to decrypt assignment %s: %w", a.ID, err)
+		}
+		a.Latency = time.Duration(latency) * time.Millisecond
+		assignments = append(assignments, &a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return assignments, nil
+}
+
+// AgentPerformance aggregates the recorded assignments of every agent.
+func (s *SQLiteStore) AgentPerformance(ctx context.Context) ([]types.AgentPerformance, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT agent_id, COUNT(*), SUM(succeeded), AVG(latency_ms) FROM assignments GROUP BY agent_id ORDER BY agent_id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query agent performance: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []types.AgentPerformance
+	for rows.Next() {
+		var p types.AgentPerformance
+		var latency float64
+		if err := rows.Scan(&p.AgentID, &p.Assignments, &p.Succeeded, &latency); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		p.SuccessRate = float64(p.Succeeded) / float64(p.Assignments)
+		p.AverageLatency = time.Duration(latency) * time.Millisecond
+		stats = append(stats, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return stats, nil
+}
+
+// RecordJudgeScore stores a judge's rating of a task's output.
+func (s *SQLiteStore) RecordJudgeScore(ctx context.Context, score *types.JudgeScore) error {
+	_, err := s.db.ExecContext(ctx,
+		"INSERT INTO judge_scores (id, task_id, run_id, judge, rating, score, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		score.ID, score.TaskID, score.RunID, score.Judge, score.Rating, score.Score, score.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record judge score: %w", err)
+	}
+	return nil
+}
+
+// JudgeScores returns the scores recorded for a run, or for every run if
+// runID is empty, newest first.
+func (s *SQLiteStore) JudgeScores(ctx context.Context, runID string, limit int) ([]types.JudgeScore, error) {
+	query := "SELECT id, task_id, COALESCE(run_id, ''), judge, rating, score, created_at FROM judge_scores"
+	args := []any{}
+	if runID != "" {
+		query += " WHERE run_id = ?"
+		args = append(args, runID)
+	}
+	query += " ORDER BY created_at DESC"
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query judge scores: %w", err)
+	}
+	defer rows.Close()
+
+	var scores []types.JudgeScore
+	for rows.Next() {
+		var sc types.JudgeScore
+		if err := rows.Scan(&sc.ID, &sc.TaskID, &sc.RunID, &sc.Judge, &sc.Rating, &sc.Score, &sc.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		scores = append(scores, sc)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return scores, nil
+}
+
+// RecordRetrieval stores an agent's lookup of its memories.
+func (s *SQLiteStore) RecordRetrieval(ctx context.Context, retrieval *types.MemoryRetrieval) error {
+	_, err := s.db.ExecContext(ctx,
+		"INSERT INTO memory_retrievals (id, agent_id, task_id, run_id, hit, tokens, latency_ms, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		retrieval.ID, retrieval.AgentID, retrieval.TaskID, retrieval.RunID, retrieval.Hit, retrieval.Tokens, retrieval.Latency.Milliseconds(), retrieval.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record memory retrieval: %w", err)
+	}
+	return nil
+}
+
+// MemoryEffectiveness aggregates the recorded lookups of every agent that
+// has looked up its memories. A project counts as one with hits for an agent
+// if any of its lookups in the project found memories, and is scored by the
+// average of its judge scores.
+func (s *SQLiteStore) MemoryEffectiveness(ctx context.Context) ([]types.MemoryEffectiveness, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		WITH runs AS (
+			SELECT agent_id, run_id, MAX(hit) AS hit FROM memory_retrievals
+			WHERE run_id IS NOT NULL AND run_id != '' GROUP BY agent_id, run_id
+		), scores AS (
+			SELECT run_id, AVG(score) AS score FROM judge_scores GROUP BY run_id
+		), outcomes AS (
+			SELECT runs.agent_id,
+				SUM(runs.hit) AS runs_with_hits,
+				SUM(1 - runs.hit) AS runs_without_hits,
+				AVG(CASE WHEN runs.hit = 1 THEN scores.score END) AS score_with_hits,
+				AVG(CASE WHEN runs.hit = 0 THEN scores.score END) AS score_without_hits
+			FROM runs JOIN scores ON scores.run_id = runs.run_id GROUP BY runs.agent_id
+		), entries AS (
+			SELECT agent_id, COUNT(*) AS entries FROM memory_entries GROUP BY agent_id
+		)
+		SELECT r.agent_id, COUNT(*), SUM(r.hit), AVG(r.latency_ms), COALESCE(AVG(CASE WHEN r.hit = 1 THEN r.tokens END), 0),
+			COALESCE(MAX(entries.entries), 0),
+			COALESCE(MAX(outcomes.runs_with_hits), 0), COALESCE(MAX(outcomes.runs_without_hits), 0),
+			MAX(outcomes.score_with_hits), MAX(outcomes.score_without_hits)
+		FROM memory_retrievals r
+		LEFT JOIN outcomes ON outcomes.agent_id = r.agent_id
+		LEFT JOIN entries ON entries.agent_id = r.agent_id
+		GROUP BY r.agent_id ORDER BY r.agent_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query memory effectiveness: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []types.MemoryEffectiveness
+	for rows.Next() {
+		var e types.MemoryEffectiveness
+		var latency, tokens float64
+		var withHits, withoutHits sql.NullFloat64
+		if err := rows.Scan(&e.AgentID, &e.Retrievals, &e.Hits, &latency, &tokens, &e.Entries,
+			&e.RunsWithHits, &e.RunsWithoutHits, &withHits, &withoutHits); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		e.HitRate = float64(e.Hits) / float64(e.Retrievals)
+		e.AverageLatency = time.Duration(latency) * time.Millisecond
+		e.AverageTokens = int(tokens)
+		e.ScoreWithHits, e.ScoreWithoutHits = withHits.Float64, withoutHits.Float64
+		if withHits.Valid && withoutHits.Valid {
+			e.Lift = e.ScoreWithHits - e.ScoreWithoutHits
+		}
+		stats = append(stats, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return stats, nil
+}
+
+// InactiveRuns returns the runs whose newest entry was created before the
+// given time, with that time as their last activity.
+func (s *SQLiteStore) InactiveRuns(ctx context.Context, before time.Time) ([]*types.ArchivedRun, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT run_id, created_at FROM memory_entries WHERE run_id IS NOT NULL AND run_id != ''")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query runs: %w", err)
+	}
+	defer rows.Close()
+
+	// Timestamps are compared in Go since their stored text does not sort across time zones
+	latest := make(map[string]time.Time)
+	for rows.Next() {
+		var runID string
+		var createdAt time.Time
+		if err := rows.Scan(&runID, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		if createdAt.After(latest[runID]) {
+			latest[runID] = createdAt
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	var runs []*types.ArchivedRun
+	for runID, last := range latest {
+		if last.Before(before) {
+			runs = append(runs, &types.ArchivedRun{RunID: runID, LastActivity: last})
+		}
+	}
+	slices.SortFunc(runs, func(a, b *types.ArchivedRun) int { return a.LastActivity.Compare(b.LastActivity) })
+	return runs, nil
+}
+
+// ArchiveRun removes a run's entries and adds the run to the archive index in
+// a single transaction. It returns the number of entries removed.
+func (s *SQLiteStore) ArchiveRun(ctx context.Context, run *types.ArchivedRun) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM memory_entries WHERE run_id = ?", run.RunID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete run memories: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT OR REPLACE INTO archived_runs (run_id, path, entries, last_activity, archived_at) VALUES (?, ?, ?, ?, ?)",
+		run.RunID, run.Path, run.Entries, run.LastActivity, run.ArchivedAt,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to index archived run: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return int(deleted), nil
+}
+
+// ArchivedRuns returns the archive index, most recently archived first.
+func (s *SQLiteStore) ArchivedRuns(ctx context.Context) ([]*types.ArchivedRun, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT run_id, path, entries, last_activity, archived_at FROM archived_runs ORDER BY archived_at DESC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query archived runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []*types.ArchivedRun
+	for rows.Next() {
+		var run types.ArchivedRun
+		if err := rows.Scan(&run.RunID, &run.Path, &run.Entries, &run.LastActivity, &run.ArchivedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		runs = append(runs, &run)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return runs, nil
+}
+
+// UpdateArchivedRun replaces a run's record in the archive index, such as
+// after entries were erased from its bundle.
+func (s *SQLiteStore) UpdateArchivedRun(ctx context.Context, run *types.ArchivedRun) error {
+	_, err := s.db.ExecContext(ctx,
+		"UPDATE archived_runs SET path = ?, entries = ?, last_activity = ?, archived_at = ? WHERE run_id = ?",
+		run.Path, run.Entries, run.LastActivity, run.ArchivedAt, run.RunID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update archived run: %w", err)
+	}
+	return nil
+}
+
+// RemoveArchivedRun removes a run from the archive index.
+func (s *SQLiteStore) RemoveArchivedRun(ctx context.Context, runID string) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM archived_runs WHERE run_id = ?", runID); err != nil {
+		return fmt.Errorf("failed to remove archived run: %w", err)
+	}
+	return nil
+}
+
+// sealBundle encrypts the archive bundle of a run when encryption is enabled,
+// so that archived memories stay encrypted at rest.
+func (s *SQLiteStore) sealBundle(runID string, data []byte) ([]byte, error) {
+	if s.cipher == nil {
+		return data, nil
+	}
+	sealed, err := s.cipher.encrypt(string(data), binding("archived_runs", runID, "bundle"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt bundle: %w", err)
+	}
+	return []byte(sealed), nil
+}
+
+// openBundle decrypts the archive bundle of a run sealed with any configured key.
+func (s *SQLiteStore) openBundle(runID string, data []byte) ([]byte, error) {
+	plaintext, err := s.cipher.decrypt(string(data), binding("archived_runs", runID, "bundle"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt bundle: %w", err)
+	}
+	return []byte(plaintext), nil
+}
+
 // DeleteExpired removes expired memory entries.
 func (s *SQLiteStore) DeleteExpired(ctx context.Context) (int, error) {
 	query := "DELETE FROM memory_entries WHERE expires_at IS NOT NULL AND expires_at < ?"
@@ -341,6 +1002,219 @@ func (s *SQLiteStore) DeleteExpired(ctx context.Context) (int, error) {
 	return int(rowsAffected), nil
 }
 
+// Health reports whether the database is reachable.
+func (s *SQLiteStore) Health() types.StoreHealth {
+	health := types.StoreHealth{
+		Name:      "sqlite",
+		Healthy:   true,
+		LastCheck: time.Now(),
+	}
+
+	if err := s.db.Ping(); err != nil {
+		health.Healthy = false
+		health.LastError = err.Error()
+	}
+
+	return health
+}
+
+// Reencrypt rewrites every entry not encrypted with the current key: plaintext rows
+// from before encryption was enabled and rows written with previous keys.
+// It returns the number of rewritten entries. The encrypted columns of the
+// assignment history are rewritten too but not counted.
+func (s *SQLiteStore) Reencrypt(ctx context.Context) (int, error) {
+	if s.cipher == nil {
+		return 0, nil
+	}
+
+	if err := s.reencryptAssignments(ctx); err != nil {
+		return 0, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT id, content, COALESCE(metadata, '') FROM memory_entries")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query memories: %w", err)
+	}
+
+	type pending struct{ id, content, metadata string }
+	var stale []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.id, &p.content, &p.metadata); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan row: %w", err)
+		}
+		if !s.cipher.isCurrent(p.content) || (p.metadata != "" && !s.cipher.isCurrent(p.metadata)) {
+			stale = append(stale, p)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	if len(stale) == 0 {
+		return 0, nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, p := range stale {
+		content, err := s.reseal(p.content, binding("memory_entries", p.id, "content"))
+		if err != nil {
+			return 0, fmt.Errorf("failed to re-encrypt memory %s: %w", p.id, err)
+		}
+		metadata, err := s.reseal(p.metadata, binding("memory_entries", p.id, "metadata"))
+		if err != nil {
+			return 0, fmt.Errorf("failed to re-encrypt memory %s: %w", p.id, err)
+		}
+
+		if _, err := tx.ExecContext(ctx, "UPDATE memory_entries SET content = ?, metadata = ? WHERE id = ?", content, metadata, p.id); err != nil {
+			return 0, fmt.Errorf("failed to update memory: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return len(stale), nil
+}
+
+// reencryptAssignments rewrites the titles and errors of assignments not
+// encrypted with the current key.
+func (s *SQLiteStore) reencryptAssignments(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, COALESCE(title, ''), COALESCE(error, '') FROM assignments")
+	if err != nil {
+		return fmt.Errorf("failed to query assignments: %w", err)
+	}
+
+	type pending struct{ id, title, message string }
+	var stale []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.id, &p.title, &p.message); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan row: %w", err)
+		}
+		if (p.title != "" && !s.cipher.isCurrent(p.title)) || (p.message != "" && !s.cipher.isCurrent(p.message)) {
+			stale = append(stale, p)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	for _, p := range stale {
+		title, err := s.reseal(p.title, binding("assignments", p.id, "title"))
+		if err != nil {
+			return fmt.Errorf("failed to re-encrypt assignment %s: %w", p.id, err)
+		}
+		message, err := s.reseal(p.message, binding("assignments", p.id, "error"))
+		if err != nil {
+			return fmt.Errorf("failed to re-encrypt assignment %s: %w", p.id, err)
+		}
+
+		if _, err := s.db.ExecContext(ctx, "UPDATE assignments SET title = ?, error = ? WHERE id = ?", title, message, p.id); err != nil {
+			return fmt.Errorf("failed to update assignment: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// reseal decrypts a stored value with any known key and encrypts it with the current one.
+func (s *SQLiteStore) reseal(value string, aad []byte) (string, error) {
+	if value == "" {
+		return value, nil
+	}
+
+	plaintext, err := s.cipher.decrypt(value, aad)
+	if err != nil {
+		return "", err
+	}
+
+	return s.cipher.encrypt(plaintext, aad)
+}
+
+// sealEntry serializes and, if enabled, encrypts an entry's content and metadata.
+func (s *SQLiteStore) sealEntry(entry *types.MemoryEntry) (string, string, error) {
+	metadataJSON, err := json.Marshal(entry.Metadata)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	content, err := s.cipher.encrypt(entry.Content, binding("memory_entries", entry.ID, "content"))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encrypt content: %w", err)
+	}
+
+	metadata, err := s.cipher.encrypt(string(metadataJSON), binding("memory_entries", entry.ID, "metadata"))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encrypt metadata: %w", err)
+	}
+
+	return content, metadata, nil
+}
+
+// openEntry decrypts an entry's content and deserializes its metadata.
+func (s *SQLiteStore) openEntry(entry *types.MemoryEntry, metadataJSON string) error {
+	content, err := s.cipher.decrypt(entry.Content, binding("memory_entries", entry.ID, "content"))
+	if err != nil {
+		return fmt.Errorf("failed to decrypt content: %w", err)
+	}
+	entry.Content = content
+
+	metadata, err := s.cipher.decrypt(metadataJSON, binding("memory_entries", entry.ID, "metadata"))
+	if err != nil {
+		return fmt.Errorf("failed to decrypt metadata: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(metadata), &entry.Metadata); err != nil {
+		return fmt.Errorf("failed to unmarshal metadata: %w", err)
+	}
+
+	return nil
+}
+
+// likeEscaper escapes the LIKE wildcards in text matched literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// matchesEncryptedFilter applies the content and metadata filters of a query to a decrypted entry.
+func matchesEncryptedFilter(entry *types.MemoryEntry, query *types.MemoryQuery) bool {
+	// SQLite LIKE is case-insensitive for ASCII, so match that behavior
+	if query.Content != "" && !strings.Contains(strings.ToLower(entry.Content), strings.ToLower(query.Content)) {
+		return false
+	}
+
+	for key, value := range query.Metadata {
+		if entry.Metadata[key] != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// paginate applies offset and limit to already filtered entries.
+func paginate(entries []*types.MemoryEntry, offset, limit int) []*types.MemoryEntry {
+	if offset >= len(entries) {
+		return nil
+	}
+	entries = entries[offset:]
+
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+
+	return entries
+}
+
 // Close closes the database connection.
 func (s *SQLiteStore) Close() error {
 	return s.db.Close()
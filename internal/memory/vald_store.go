@@ -3,102 +3,292 @@ package memory
 import (
 	"context"
 	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/vdaas/vald-client-go/v1/payload"
 	"github.com/vdaas/vald-client-go/v1/vald"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 
+	"github.com/kpango/BuildBureau/internal/errors"
 	"github.com/kpango/BuildBureau/pkg/types"
 )
 
+const (
+	defaultValdPoolSize            = 1
+	defaultValdRetryBackoff        = 500 * time.Millisecond
+	defaultValdDialTimeout         = 2 * time.Second
+	defaultValdHealthCheckInterval = 30 * time.Second
+	maxValdRetryBackoff            = 30 * time.Second
+)
+
 // ValdStore implements VectorStore using Vald.
+//
+// Calls are spread over a pool of PoolSize connections, so large vector
+// payloads are not serialized through a single HTTP/2 connection. The
+// connections are established in the background: until one is ready the store
+// runs degraded, failing calls fast with CodeMemoryUnavailable, and gRPC
+// re-establishes lost connections with backoff.
 type ValdStore struct {
-	client vald.Client
-	conn   *grpc.ClientConn
-	config types.ValdConfig
+	conns     []*grpc.ClientConn
+	clients   []vald.Client
+	stop      chan struct{}
+	health    types.StoreHealth
+	config    types.ValdConfig
+	wg        sync.WaitGroup
+	next      atomic.Uint32 // Round-robin position in the pool
+	stopOnce  sync.Once
+	mu        sync.RWMutex
+	connected bool // Whether a connection was ever ready, to count reconnects
 }
 
-// NewValdStore creates a new Vald vector store.
+// NewValdStore creates a new Vald vector store. It does not wait for Vald to
+// be reachable; it fails only if the address or options are invalid.
 func NewValdStore(config types.ValdConfig) (*ValdStore, error) {
 	if !config.Enabled {
 		return nil, fmt.Errorf("vald is not enabled")
 	}
 
-	// Connect to Vald server
-	addr := fmt.Sprintf("%s:%d", config.Host, config.Port)
-	//nolint:staticcheck // grpc.Dial will be replaced with grpc.NewClient in a future update
-	conn, err := grpc.Dial(
-		addr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithDefaultCallOptions(
-			grpc.MaxCallRecvMsgSize(256*1024*1024), // 256MB
-			grpc.MaxCallSendMsgSize(256*1024*1024),
-		),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to vald: %w", err)
+	if config.PoolSize <= 0 {
+		config.PoolSize = defaultValdPoolSize
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = defaultValdRetryBackoff
+	}
+	if config.DialTimeout <= 0 {
+		config.DialTimeout = defaultValdDialTimeout
+	}
+	if config.HealthCheckInterval <= 0 {
+		config.HealthCheckInterval = defaultValdHealthCheckInterval
 	}
 
-	client := vald.NewValdClient(conn)
-
-	return &ValdStore{
-		client: client,
-		conn:   conn,
+	v := &ValdStore{
 		config: config,
-	}, nil
+		stop:   make(chan struct{}),
+		health: types.StoreHealth{Name: "vald", LastError: "connecting"},
+	}
+
+	if err := v.dial(); err != nil {
+		return nil, err
+	}
+
+	v.wg.Add(1)
+	go v.monitor()
+
+	return v, nil
+}
+
+// dial creates the connection pool and starts connecting each connection
+// without waiting for it.
+func (v *ValdStore) dial() error {
+	addr := fmt.Sprintf("%s:%d", v.config.Host, v.config.Port)
+
+	for range v.config.PoolSize {
+		conn, err := grpc.NewClient(addr,
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithDefaultCallOptions(
+				grpc.MaxCallRecvMsgSize(256*1024*1024), // 256MB
+				grpc.MaxCallSendMsgSize(256*1024*1024),
+			),
+			grpc.WithConnectParams(grpc.ConnectParams{
+				Backoff: backoff.Config{
+					BaseDelay:  v.config.RetryBackoff,
+					Multiplier: 2,
+					Jitter:     0.2,
+					MaxDelay:   maxValdRetryBackoff,
+				},
+				MinConnectTimeout: v.config.DialTimeout,
+			}),
+		)
+		if err != nil {
+			for _, c := range v.conns {
+				_ = c.Close()
+			}
+			return fmt.Errorf("failed to create vald client: %w", err)
+		}
+
+		conn.Connect()
+		v.conns = append(v.conns, conn)
+		v.clients = append(v.clients, vald.NewValdClient(conn))
+	}
+
+	return nil
+}
+
+// monitor periodically refreshes the health record, which also wakes
+// connections that went idle after being lost.
+func (v *ValdStore) monitor() {
+	defer v.wg.Done()
+
+	ticker := time.NewTicker(v.config.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-v.stop:
+			return
+		case <-ticker.C:
+		}
+
+		v.mu.Lock()
+		v.refresh()
+		v.mu.Unlock()
+	}
+}
+
+// refresh updates the health record from the state of the pooled connections
+// and asks idle ones to reconnect. v.mu must be held.
+func (v *ValdStore) refresh() {
+	healthy := false
+	for _, conn := range v.conns {
+		switch conn.GetState() {
+		case connectivity.Ready:
+			healthy = true
+		case connectivity.Idle:
+			conn.Connect()
+		}
+	}
+
+	switch {
+	case healthy && !v.health.Healthy:
+		if v.connected {
+			v.health.Reconnects++
+		}
+		v.connected = true
+		v.health.LastError = ""
+	case !healthy && v.health.LastError == "" && len(v.conns) > 0:
+		v.health.LastError = fmt.Sprintf("connection state %s", v.conns[0].GetState())
+	}
+	v.health.Healthy = healthy
+	v.health.LastCheck = time.Now()
+}
+
+// observe records err if it indicates Vald itself is unreachable.
+func (v *ValdStore) observe(err error) {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		v.refresh()
+		if !v.health.Healthy {
+			v.health.LastError = err.Error()
+		}
+	}
+}
+
+// activeClient returns the client of the next ready connection in the pool,
+// or an unavailable error while none is ready.
+func (v *ValdStore) activeClient() (vald.Client, error) {
+	v.mu.RLock()
+	for range len(v.conns) {
+		i := int(v.next.Add(1) % uint32(len(v.conns)))
+		if v.conns[i].GetState() == connectivity.Ready {
+			client := v.clients[i]
+			v.mu.RUnlock()
+			return client, nil
+		}
+	}
+	v.mu.RUnlock()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.refresh()
+	return nil, errors.Newf(errors.CodeMemoryUnavailable, "vald unavailable: %s", v.health.LastError)
+}
+
+// Health reports the current connection state.
+func (v *ValdStore) Health() types.StoreHealth {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.refresh()
+	return v.health
 }
 
 // Insert adds a vector with metadata.
 func (v *ValdStore) Insert(ctx context.Context, id string, vector []float32, metadata map[string]string) error {
-	req := &payload.Insert_Request{
-		Vector: &payload.Object_Vector{
-			Id:     id,
-			Vector: vector,
-		},
-		Config: &payload.Insert_Config{
-			SkipStrictExistCheck: false,
-			Timestamp:            0,
-		},
+	client, err := v.activeClient()
+	if err != nil {
+		return err
 	}
 
-	_, err := v.client.Insert(ctx, req)
+	_, err = client.Insert(ctx, insertRequest(id, vector))
 	if err != nil {
+		v.observe(err)
 		return fmt.Errorf("failed to insert vector: %w", err)
 	}
 
 	return nil
 }
 
+// MultiInsert adds several vectors in one request.
+func (v *ValdStore) MultiInsert(ctx context.Context, items []types.VectorItem) error {
+	client, err := v.activeClient()
+	if err != nil {
+		return err
+	}
+
+	req := &payload.Insert_MultiRequest{
+		Requests: make([]*payload.Insert_Request, 0, len(items)),
+	}
+	for _, item := range items {
+		req.Requests = append(req.Requests, insertRequest(item.ID, item.Vector))
+	}
+
+	_, err = client.MultiInsert(ctx, req)
+	if err != nil {
+		v.observe(err)
+		return fmt.Errorf("failed to insert vectors: %w", err)
+	}
+
+	return nil
+}
+
 // Search performs similarity search.
 func (v *ValdStore) Search(ctx context.Context, vector []float32, limit int, minScore float32) ([]types.SearchResult, error) {
-	req := &payload.Search_Request{
-		Vector: vector,
-		Config: &payload.Search_Config{
-			Num:                  uint32(limit),
-			Radius:               -1.0, // Search all
-			Epsilon:              0.01,
-			Timeout:              3000000000, // 3 seconds in nanoseconds
-			MinNum:               1,
-			AggregationAlgorithm: 0,
-		},
+	client, err := v.activeClient()
+	if err != nil {
+		return nil, err
 	}
 
-	resp, err := v.client.Search(ctx, req)
+	resp, err := client.Search(ctx, searchRequest(vector, limit))
 	if err != nil {
+		v.observe(err)
 		return nil, fmt.Errorf("failed to search vectors: %w", err)
 	}
 
-	var results []types.SearchResult
-	if resp != nil && resp.Results != nil {
-		for _, r := range resp.Results {
-			// Filter by minimum score
-			if r.Distance >= minScore {
-				results = append(results, types.SearchResult{
-					ID:    r.Id,
-					Score: r.Distance,
-				})
-			}
+	return searchResults(resp, minScore), nil
+}
+
+// MultiSearch performs several similarity searches in one request.
+func (v *ValdStore) MultiSearch(ctx context.Context, vectors [][]float32, limit int, minScore float32) ([][]types.SearchResult, error) {
+	client, err := v.activeClient()
+	if err != nil {
+		return nil, err
+	}
+
+	req := &payload.Search_MultiRequest{
+		Requests: make([]*payload.Search_Request, 0, len(vectors)),
+	}
+	for _, vector := range vectors {
+		req.Requests = append(req.Requests, searchRequest(vector, limit))
+	}
+
+	resp, err := client.MultiSearch(ctx, req)
+	if err != nil {
+		v.observe(err)
+		return nil, fmt.Errorf("failed to search vectors: %w", err)
+	}
+
+	results := make([][]types.SearchResult, len(vectors))
+	for i, r := range resp.GetResponses() {
+		if i < len(results) {
+			results[i] = searchResults(r, minScore)
 		}
 	}
 
@@ -107,6 +297,11 @@ func (v *ValdStore) Search(ctx context.Context, vector []float32, limit int, min
 
 // Update updates a vector.
 func (v *ValdStore) Update(ctx context.Context, id string, vector []float32) error {
+	client, err := v.activeClient()
+	if err != nil {
+		return err
+	}
+
 	req := &payload.Update_Request{
 		Vector: &payload.Object_Vector{
 			Id:     id,
@@ -118,8 +313,9 @@ func (v *ValdStore) Update(ctx context.Context, id string, vector []float32) err
 		},
 	}
 
-	_, err := v.client.Update(ctx, req)
+	_, err = client.Update(ctx, req)
 	if err != nil {
+		v.observe(err)
 		return fmt.Errorf("failed to update vector: %w", err)
 	}
 
@@ -128,6 +324,11 @@ func (v *ValdStore) Update(ctx context.Context, id string, vector []float32) err
 
 // Delete removes a vector by ID.
 func (v *ValdStore) Delete(ctx context.Context, id string) error {
+	client, err := v.activeClient()
+	if err != nil {
+		return err
+	}
+
 	req := &payload.Remove_Request{
 		Id: &payload.Object_ID{
 			Id: id,
@@ -138,18 +339,81 @@ func (v *ValdStore) Delete(ctx context.Context, id string) error {
 		},
 	}
 
-	_, err := v.client.Remove(ctx, req)
+	_, err = client.Remove(ctx, req)
 	if err != nil {
+		v.observe(err)
 		return fmt.Errorf("failed to delete vector: %w", err)
 	}
 
 	return nil
 }
 
-// Close closes the connection to Vald.
+// Close stops the health monitor and closes the connections to Vald.
+// Closing a closed store does nothing.
 func (v *ValdStore) Close() error {
-	if v.conn != nil {
-		return v.conn.Close()
+	v.stopOnce.Do(func() { close(v.stop) })
+	v.wg.Wait()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	var err error
+	for _, conn := range v.conns {
+		if cerr := conn.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
 	}
-	return nil
+	if v.conns != nil {
+		v.health.LastError = "closed"
+	}
+	v.conns, v.clients = nil, nil
+	v.health.Healthy = false
+	return err
+}
+
+// insertRequest builds a single insert request.
+func insertRequest(id string, vector []float32) *payload.Insert_Request {
+	return &payload.Insert_Request{
+		Vector: &payload.Object_Vector{
+			Id:     id,
+			Vector: vector,
+		},
+		Config: &payload.Insert_Config{
+			SkipStrictExistCheck: false,
+			Timestamp:            0,
+		},
+	}
+}
+
+// searchRequest builds a single search request.
+func searchRequest(vector []float32, limit int) *payload.Search_Request {
+	return &payload.Search_Request{
+		Vector: vector,
+		Config: &payload.Search_Config{
+			Num:                  uint32(limit),
+			Radius:               -1.0, // Search all
+			Epsilon:              0.01,
+			Timeout:              3000000000, // 3 seconds in nanoseconds
+			MinNum:               1,
+			AggregationAlgorithm: 0,
+		},
+	}
+}
+
+// searchResults converts a search response, filtering by minimum score.
+func searchResults(resp *payload.Search_Response, minScore float32) []types.SearchResult {
+	var results []types.SearchResult
+	if resp != nil && resp.Results != nil {
+		for _, r := range resp.Results {
+			// Filter by minimum score
+			if r.Distance >= minScore {
+				results = append(results, types.SearchResult{
+					ID:    r.Id,
+					Score: r.Distance,
+				})
+			}
+		}
+	}
+
+	return results
 }
@@ -0,0 +1,326 @@
+// Package outbox delivers notifications reliably. Notifications are stored in
+// an SQLite table before delivery, retried with exponential backoff while a
+// destination such as Slack is down, and moved to a dead letter state after
+// too many attempts. Notifications still pending at shutdown are delivered
+// when the outbox is opened again.
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// DefaultPath is where the outbox is stored when the configuration does not say.
+const DefaultPath = "./data/outbox.db"
+
+const (
+	defaultMaxAttempts = 5
+	defaultBackoff     = 5 * time.Second
+	defaultMaxBackoff  = 10 * time.Minute
+	deliveryTimeout    = 30 * time.Second
+
+	statePending = "pending"
+	stateDead    = "dead"
+)
+
+// Sender delivers notifications to one destination, e.g. Slack or the desktop.
+type Sender interface {
+	Notify(ctx context.Context, notificationType, message string) error
+}
+
+// Message is a notification for one destination.
+type Message struct {
+	CreatedAt   time.Time `json:"created_at"`
+	NextAttempt time.Time `json:"next_attempt"`
+	Destination string    `json:"destination"`
+	Type        string    `json:"type"`
+	Message     string    `json:"message"`
+	LastError   string    `json:"last_error,omitempty"`
+	ID          int64     `json:"id"`
+	Attempts    int       `json:"attempts"`
+}
+
+// destination is a named sender.
+type destination struct {
+	name   string
+	sender Sender
+}
+
+// Outbox stores notifications and delivers them to every destination in the
+// background. Each destination is retried on its own, so one that is down
+// does not hold up or repeat deliveries to the others.
+type Outbox struct {
+	db           *sql.DB
+	now          func() time.Time
+	wake         chan struct{}
+	cancel       context.CancelFunc
+	destinations []destination
+	wg           sync.WaitGroup
+	maxAttempts  int
+	backoff      time.Duration
+	maxBackoff   time.Duration
+}
+
+// Option configures an Outbox.
+type Option func(*Outbox)
+
+// WithSender adds a destination notifications are delivered to.
+func WithSender(name string, sender Sender) Option {
+	return func(o *Outbox) {
+		o.destinations = append(o.destinations, destination{name: name, sender: sender})
+	}
+}
+
+// WithMaxAttempts sets the delivery attempts after which a notification becomes a dead letter.
+func WithMaxAttempts(attempts int) Option {
+	return func(o *Outbox) {
+		if attempts > 0 {
+			o.maxAttempts = attempts
+		}
+	}
+}
+
+// WithBackoff sets the delay before the first retry, doubled on each further
+// retry up to maxBackoff.
+func WithBackoff(backoff, maxBackoff time.Duration) Option {
+	return func(o *Outbox) {
+		if backoff > 0 {
+			o.backoff = backoff
+		}
+		if maxBackoff > 0 {
+			o.maxBackoff = maxBackoff
+		}
+	}
+}
+
+// Open opens the outbox stored at path and starts delivering notifications.
+// Those left pending by a previous run are attempted first, without waiting
+// for their retry to be due.
+func Open(path string, opts ...Option) (*Outbox, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create outbox directory: %w", err)
+		}
+	}
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open outbox: %w", err)
+	}
+	// A single connection serializes the delivery loop and new notifications
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS outbox (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		destination TEXT NOT NULL,
+		type TEXT NOT NULL,
+		message TEXT NOT NULL,
+		state TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT NOT NULL DEFAULT '',
+		created_at DATETIME NOT NULL,
+		next_attempt INTEGER NOT NULL -- Unix nanoseconds, so it compares as a number
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_outbox_due ON outbox(state, next_attempt);
+	`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize outbox: %w", err)
+	}
+
+	o := &Outbox{
+		db:          db,
+		now:         time.Now,
+		wake:        make(chan struct{}, 1),
+		maxAttempts: defaultMaxAttempts,
+		backoff:     defaultBackoff,
+		maxBackoff:  defaultMaxBackoff,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	// Notifications left pending by a previous run are attempted right away
+	if _, err := db.Exec(`UPDATE outbox SET next_attempt = ? WHERE state = ?`, o.now().UnixNano(), statePending); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to resume outbox: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	o.cancel = cancel
+	o.wg.Go(func() { o.run(ctx) })
+	return o, nil
+}
+
+// Notify stores a notification for every destination and returns without
+// waiting for delivery.
+func (o *Outbox) Notify(ctx context.Context, notificationType, message string) error {
+	// The notification is stored even if the caller's work was canceled
+	ctx = context.WithoutCancel(ctx)
+	now := o.now()
+	for _, d := range o.destinations {
+		_, err := o.db.ExecContext(ctx,
+			`INSERT INTO outbox (destination, type, message, state, created_at, next_attempt) VALUES (?, ?, ?, ?, ?, ?)`,
+			d.name, notificationType, message, statePending, now, now.UnixNano())
+		if err != nil {
+			return fmt.Errorf("failed to store notification for %s: %w", d.name, err)
+		}
+	}
+
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Pending returns the notifications waiting for delivery, oldest first.
+func (o *Outbox) Pending(ctx context.Context) ([]Message, error) {
+	return o.messages(ctx, statePending)
+}
+
+// DeadLetters returns the notifications that could not be delivered, oldest first.
+func (o *Outbox) DeadLetters(ctx context.Context) ([]Message, error) {
+	return o.messages(ctx, stateDead)
+}
+
+// Close stops delivery. Notifications still pending are kept and delivered
+// when the outbox is opened again.
+func (o *Outbox) Close() error {
+	o.cancel()
+	o.wg.Wait()
+	return o.db.Close()
+}
+
+// run delivers due notifications until ctx is done, sleeping until the next
+// retry is due or a notification arrives.
+func (o *Outbox) run(ctx context.Context) {
+	for {
+		o.deliverDue(ctx)
+
+		wait := time.Hour
+		var next sql.NullInt64
+		if err := o.db.QueryRowContext(ctx, `SELECT MIN(next_attempt) FROM outbox WHERE state = ?`, statePending).Scan(&next); err == nil && next.Valid {
+			wait = max(time.Unix(0, next.Int64).Sub(o.now()), 0)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-o.wake:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// deliverDue attempts every pending notification whose retry is due.
+func (o *Outbox) deliverDue(ctx context.Context) {
+	rows, err := o.db.QueryContext(ctx,
+		`SELECT id, destination, type, message, attempts, created_at FROM outbox WHERE state = ? AND next_attempt <= ? ORDER BY id`,
+		statePending, o.now().UnixNano())
+	if err != nil {
+		if ctx.Err() == nil {
+			fmt.Printf("Warning: failed to read outbox: %v\n", err)
+		}
+		return
+	}
+	var due []Message
+	for rows.Next() {
+		var m Message
+		if err := rows.Scan(&m.ID, &m.Destination, &m.Type, &m.Message, &m.Attempts, &m.CreatedAt); err == nil {
+			due = append(due, m)
+		}
+	}
+	rows.Close()
+
+	for _, m := range due {
+		if ctx.Err() != nil {
+			return
+		}
+		o.deliver(ctx, m)
+	}
+}
+
+// deliver sends one notification, then removes it or schedules its retry.
+func (o *Outbox) deliver(ctx context.Context, m Message) {
+	var sender Sender
+	for _, d := range o.destinations {
+		if d.name == m.Destination {
+			sender = d.sender
+		}
+	}
+
+	err := fmt.Errorf("destination %s is not configured", m.Destination)
+	if sender != nil {
+		sendCtx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+		err = sender.Notify(sendCtx, m.Type, m.Message)
+		cancel()
+	}
+	if err == nil {
+		_, err = o.db.ExecContext(ctx, `DELETE FROM outbox WHERE id = ?`, m.ID)
+		if err != nil && ctx.Err() == nil {
+			fmt.Printf("Warning: failed to remove delivered notification from outbox: %v\n", err)
+		}
+		return
+	}
+	if ctx.Err() != nil {
+		// Shutting down; the attempt is retried on the next run
+		return
+	}
+
+	m.Attempts++
+	state, next := statePending, o.now().Add(o.retryDelay(m.Attempts))
+	if m.Attempts >= o.maxAttempts {
+		state = stateDead
+		fmt.Printf("Warning: giving up on %s notification to %s after %d attempts: %v\n", m.Type, m.Destination, m.Attempts, err)
+	}
+	_, dbErr := o.db.ExecContext(ctx, `UPDATE outbox SET state = ?, attempts = ?, last_error = ?, next_attempt = ? WHERE id = ?`,
+		state, m.Attempts, err.Error(), next.UnixNano(), m.ID)
+	if dbErr != nil {
+		fmt.Printf("Warning: failed to record failed delivery in outbox: %v\n", dbErr)
+	}
+}
+
+// retryDelay returns the delay before retrying after the given number of
+// failed attempts: the backoff doubled per attempt, capped at maxBackoff.
+func (o *Outbox) retryDelay(attempts int) time.Duration {
+	delay := float64(o.backoff) * math.Pow(2, float64(attempts-1))
+	return time.Duration(min(delay, float64(o.maxBackoff)))
+}
+
+// messages returns the notifications in a state, oldest first.
+func (o *Outbox) messages(ctx context.Context, state string) ([]Message, error) {
+	rows, err := o.db.QueryContext(ctx,
+		`SELECT id, destination, type, message, attempts, last_error, created_at, next_attempt FROM outbox WHERE state = ? ORDER BY id`, state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbox: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []Message
+	for rows.Next() {
+		var (
+			m    Message
+			next int64
+		)
+		if err := rows.Scan(&m.ID, &m.Destination, &m.Type, &m.Message, &m.Attempts, &m.LastError, &m.CreatedAt, &next); err != nil {
+			return nil, fmt.Errorf("failed to read outbox: %w", err)
+		}
+		m.NextAttempt = time.Unix(0, next)
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
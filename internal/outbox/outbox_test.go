@@ -0,0 +1,125 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakySender fails its first failures deliveries, or every delivery if failures is negative.
+type flakySender struct {
+	mu        sync.Mutex
+	delivered []string
+	failures  int
+	attempts  int
+}
+
+func (s *flakySender) Notify(ctx context.Context, notificationType, message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts++
+	if s.failures < 0 || s.attempts <= s.failures {
+		return errors.New("service unavailable")
+	}
+	s.delivered = append(s.delivered, notificationType+": "+message)
+	return nil
+}
+
+func (s *flakySender) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.delivered...)
+}
+
+// eventually waits for cond to hold.
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestOutbox(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("RetriesEachDestination", func(t *testing.T) {
+		slack := &flakySender{failures: 2}
+		desktop := &flakySender{}
+		box, err := Open(filepath.Join(t.TempDir(), "outbox.db"),
+			WithSender("slack", slack), WithSender("desktop", desktop), WithBackoff(time.Millisecond, 10*time.Millisecond))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer box.Close()
+
+		if err := box.Notify(ctx, "project_completed", "Completed: todo app"); err != nil {
+			t.Fatal(err)
+		}
+		eventually(t, "delivery to Slack", func() bool { return len(slack.received()) == 1 })
+
+		if slack.attempts != 3 || desktop.attempts != 1 || len(desktop.received()) != 1 {
+			t.Errorf("Expected Slack to be retried on its own, got %d Slack and %d desktop attempts", slack.attempts, desktop.attempts)
+		}
+		if pending, _ := box.Pending(ctx); len(pending) != 0 {
+			t.Errorf("Expected delivered notifications to leave the outbox, got %+v", pending)
+		}
+	})
+
+	t.Run("DeadLetter", func(t *testing.T) {
+		sender := &flakySender{failures: -1}
+		box, err := Open(filepath.Join(t.TempDir(), "outbox.db"),
+			WithSender("slack", sender), WithMaxAttempts(2), WithBackoff(time.Millisecond, time.Millisecond))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer box.Close()
+
+		_ = box.Notify(ctx, "error", "Task failed")
+		var dead []Message
+		eventually(t, "the dead letter", func() bool {
+			dead, _ = box.DeadLetters(ctx)
+			return len(dead) == 1
+		})
+		if dead[0].Attempts != 2 || dead[0].Destination != "slack" || dead[0].LastError != "service unavailable" {
+			t.Errorf("Unexpected dead letter: %+v", dead[0])
+		}
+	})
+
+	t.Run("RedeliveredAfterRestart", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "outbox.db")
+		down := &flakySender{failures: -1}
+		box, err := Open(path, WithSender("slack", down), WithBackoff(time.Hour, time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = box.Notify(ctx, "project_failed", "Failed: todo app")
+		eventually(t, "the first attempt", func() bool {
+			down.mu.Lock()
+			defer down.mu.Unlock()
+			return down.attempts == 1
+		})
+		if err := box.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		up := &flakySender{}
+		box, err = Open(path, WithSender("slack", up), WithBackoff(time.Hour, time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer box.Close()
+
+		// The retry was not due for an hour, but a restart attempts it right away
+		eventually(t, "redelivery", func() bool { return len(up.received()) == 1 })
+		if got := up.received(); got[0] != "project_failed: Failed: todo app" {
+			t.Errorf("Expected the notification left pending to be delivered, got %v", got)
+		}
+	})
+}
@@ -0,0 +1,312 @@
+// Package profile keeps long-term agent profiles: the domains each agent has
+// worked in and the techniques that succeeded, accumulated across projects.
+// Profiles let agents specialize over time instead of only through their
+// configuration.
+package profile
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const (
+	// DefaultPath is where profiles are stored when no path is configured.
+	DefaultPath = "./data/profiles.json"
+
+	// maxEntries bounds the domains and techniques kept per agent; the least
+	// frequent are forgotten first.
+	maxEntries = 50
+
+	// summaryEntries is how many domains and techniques a summary lists.
+	summaryEntries = 5
+)
+
+// techniques are the technical terms recognized in successful results.
+var techniques = []string{
+	"go", "python", "java", "javascript", "typescript", "rust", "kotlin", "swift", "ruby", "php", "c#", "c++",
+	"react", "vue", "angular", "svelte", "next.js", "node.js", "django", "flask", "fastapi", "spring", "rails", "express",
+	"postgresql", "mysql", "sqlite", "mongodb", "redis", "elasticsearch", "kafka", "rabbitmq",
+	"rest", "graphql", "grpc", "websocket", "oauth", "jwt",
+	"docker", "kubernetes", "terraform", "aws", "gcp", "azure",
+	"microservices", "event sourcing", "cqrs", "caching", "pagination", "rate limiting", "dependency injection",
+	"unit tests", "integration tests", "tdd",
+}
+
+// stopwords are frequent words that do not describe a domain.
+var stopwords = map[string]struct{}{
+	"about": {}, "after": {}, "also": {}, "build": {}, "client": {}, "create": {}, "description": {},
+	"engineer": {}, "from": {}, "have": {}, "implement": {}, "into": {}, "make": {}, "manager": {},
+	"need": {}, "should": {}, "simple": {}, "some": {}, "task": {}, "that": {}, "their": {}, "them": {},
+	"then": {}, "there": {}, "these": {}, "this": {}, "using": {}, "want": {}, "with": {}, "will": {},
+	"would": {}, "your": {}, "request": {}, "secretary": {}, "director": {}, "president": {},
+}
+
+// Profile is what an agent has learned across projects.
+type Profile struct {
+	UpdatedAt  time.Time      `json:"updated_at"`
+	Domains    map[string]int `json:"domains"`    // Keywords of completed tasks and how often they occurred
+	Techniques map[string]int `json:"techniques"` // Techniques used in successful results
+	Completed  int            `json:"completed"`
+	Failed     int            `json:"failed"`
+}
+
+// Summary describes the profile for inclusion in prompts, or returns "" if
+// the agent has no experience yet.
+func (p Profile) Summary() string {
+	if p.Completed == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Completed tasks: %d", p.Completed)
+	if domains := top(p.Domains, summaryEntries); len(domains) > 0 {
+		b.WriteString("\nDomains: " + strings.Join(domains, ", "))
+	}
+	if used := top(p.Techniques, summaryEntries); len(used) > 0 {
+		b.WriteString("\nTechniques that worked: " + strings.Join(used, ", "))
+	}
+	return b.String()
+}
+
+// Score rates how well the profile matches a task: the share of the task's
+// keywords the agent has worked with, weighted by how often.
+func (p Profile) Score(text string) float64 {
+	keywords := Keywords(text)
+	if len(keywords) == 0 || len(p.Domains) == 0 {
+		return 0
+	}
+
+	score := 0.0
+	for _, k := range keywords {
+		if n := p.Domains[k]; n > 0 {
+			score += 1 - 1/float64(n+1)
+		}
+	}
+	return score / float64(len(keywords))
+}
+
+// Store persists profiles in a JSON file.
+type Store struct {
+	profiles map[string]*Profile
+	path     string
+	mu       sync.RWMutex
+}
+
+// Open loads the profiles stored at path, starting empty if the file does not exist.
+func Open(path string) (*Store, error) {
+	if path == "" {
+		path = DefaultPath
+	}
+
+	s := &Store{path: path, profiles: make(map[string]*Profile)}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profiles: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.profiles); err != nil {
+		return nil, fmt.Errorf("failed to parse profiles: %w", err)
+	}
+
+	return s, nil
+}
+
+// Get returns a copy of an agent's profile.
+func (s *Store) Get(agentID string) Profile {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, ok := s.profiles[agentID]
+	if !ok {
+		return Profile{}
+	}
+	cp := *p
+	cp.Domains = maps.Clone(p.Domains)
+	cp.Techniques = maps.Clone(p.Techniques)
+	return cp
+}
+
+// All returns a copy of every profile, keyed by agent ID.
+func (s *Store) All() map[string]Profile {
+	s.mu.RLock()
+	ids := slices.Collect(maps.Keys(s.profiles))
+	s.mu.RUnlock()
+
+	all := make(map[string]Profile, len(ids))
+	for _, id := range ids {
+		all[id] = s.Get(id)
+	}
+	return all
+}
+
+// RecordSuccess adds a completed task to an agent's profile. The task's
+// keywords become domains and techniques named in the result are remembered.
+func (s *Store) RecordSuccess(agentID, task, result string) error {
+	return s.update(agentID, func(p *Profile) {
+		p.Completed++
+		for _, k := range Keywords(task) {
+			p.Domains[k]++
+		}
+		for _, t := range Techniques(result) {
+			p.Techniques[t]++
+		}
+		prune(p.Domains)
+		prune(p.Techniques)
+	})
+}
+
+// RecordFailure counts a failed task against an agent's profile.
+func (s *Store) RecordFailure(agentID string) error {
+	return s.update(agentID, func(p *Profile) {
+		p.Failed++
+	})
+}
+
+// update applies fn to an agent's profile and saves the store.
+func (s *Store) update(agentID string, fn func(*Profile)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.profiles[agentID]
+	if !ok {
+		p = &Profile{}
+		s.profiles[agentID] = p
+	}
+	if p.Domains == nil {
+		p.Domains = make(map[string]int)
+	}
+	if p.Techniques == nil {
+		p.Techniques = make(map[string]int)
+	}
+
+	fn(p)
+	p.UpdatedAt = time.Now()
+
+	return s.save()
+}
+
+// save writes the profiles to disk. The caller must hold s.mu.
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.profiles, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode profiles: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create profile directory: %w", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves truncated profiles
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write profiles: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to write profiles: %w", err)
+	}
+
+	return nil
+}
+
+// Keywords returns the distinct lowercase words of text that may describe a
+// domain: at least four letters long and not a stopword.
+func Keywords(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	seen := make(map[string]struct{}, len(words))
+	var keywords []string
+	for _, w := range words {
+		if len([]rune(w)) < 4 {
+			continue
+		}
+		if _, stop := stopwords[w]; stop {
+			continue
+		}
+		if _, dup := seen[w]; dup {
+			continue
+		}
+		seen[w] = struct{}{}
+		keywords = append(keywords, w)
+	}
+	return keywords
+}
+
+// Techniques returns the recognized techniques mentioned in text.
+func Techniques(text string) []string {
+	lower := strings.ToLower(text)
+
+	var found []string
+	for _, t := range techniques {
+		if containsTerm(lower, t) {
+			found = append(found, t)
+		}
+	}
+	return found
+}
+
+// containsTerm reports whether term occurs in text as a whole word, so that
+// "go" does not match "good".
+func containsTerm(text, term string) bool {
+	for i := 0; ; {
+		j := strings.Index(text[i:], term)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(term)
+		if boundary(text, start-1) && boundary(text, end) {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+// boundary reports whether position i of text is outside a word.
+func boundary(text string, i int) bool {
+	if i < 0 || i >= len(text) {
+		return true
+	}
+	r := rune(text[i])
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '#' && r != '+'
+}
+
+// top returns the n most frequent keys of counts, most frequent first.
+func top(counts map[string]int, n int) []string {
+	keys := slices.Collect(maps.Keys(counts))
+	slices.SortFunc(keys, func(a, b string) int {
+		if counts[a] != counts[b] {
+			return counts[b] - counts[a]
+		}
+		return strings.Compare(a, b)
+	})
+	return keys[:min(n, len(keys))]
+}
+
+// prune forgets the least frequent entries beyond maxEntries.
+func prune(counts map[string]int) {
+	if len(counts) <= maxEntries {
+		return
+	}
+	keep := make(map[string]struct{}, maxEntries)
+	for _, k := range top(counts, maxEntries) {
+		keep[k] = struct{}{}
+	}
+	for k := range counts {
+		if _, ok := keep[k]; !ok {
+			delete(counts, k)
+		}
+	}
+}
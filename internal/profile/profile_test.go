@@ -0,0 +1,62 @@
+package profile
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profiles", "profiles.json")
+	store, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.RecordSuccess("engineer-1", "Build a payment gateway", "Implemented with Go and PostgreSQL, good caching"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RecordSuccess("engineer-1", "Refund flow for the payment gateway", "A REST endpoint in Go"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RecordFailure("engineer-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Profiles survive reopening the store
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := reopened.Get("engineer-1")
+
+	if p.Completed != 2 || p.Failed != 1 {
+		t.Errorf("Completed/Failed = %d/%d, want 2/1", p.Completed, p.Failed)
+	}
+	if p.Domains["payment"] != 2 || p.Domains["gateway"] != 2 || p.Domains["refund"] != 1 {
+		t.Errorf("Unexpected domains: %v", p.Domains)
+	}
+	if p.Techniques["go"] != 2 || p.Techniques["postgresql"] != 1 || p.Techniques["rest"] != 1 {
+		t.Errorf("Unexpected techniques: %v", p.Techniques)
+	}
+
+	summary := p.Summary()
+	for _, want := range []string{"Completed tasks: 2", "Domains: gateway, payment", "Techniques that worked: go, caching"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("Summary missing %q:\n%s", want, summary)
+		}
+	}
+
+	if p.Score("Add a payment gateway webhook") <= reopened.Get("engineer-2").Score("Add a payment gateway webhook") {
+		t.Error("Expected an experienced agent to score higher than an unknown one")
+	}
+}
+
+func TestTechniques(t *testing.T) {
+	got := Techniques("Use Go with gRPC; a good C++ and Node.js port")
+	want := []string{"go", "c++", "node.js", "grpc"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Techniques = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,156 @@
+package provenance
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+const (
+	// DefaultMinMatchLines is how many consecutive lines must match for a
+	// passage to count as copied when the configuration does not say.
+	DefaultMinMatchLines = 12
+
+	maxCorpusFileSize = 1 << 20
+)
+
+// line is a significant line of a file and its 1-based line number.
+type line struct {
+	text string
+	num  int
+}
+
+// location is where a window of lines starts in the corpus.
+type location struct {
+	source  string
+	license string
+	line    int
+}
+
+// CorpusMatcher finds passages copied from a local corpus of open-source
+// code, such as a directory of checked-out repositories. Lines are compared
+// with surrounding whitespace trimmed, skipping blank lines and lines without
+// letters or digits, so reformatting and brace placement do not hide a copy.
+type CorpusMatcher struct {
+	windows  map[uint64]location // By hash of minLines consecutive significant lines
+	minLines int
+}
+
+// NewCorpusMatcher indexes the code under dir. Passages of at least minLines
+// significant lines, or DefaultMinMatchLines if it is not positive, match.
+func NewCorpusMatcher(dir string, minLines int) (*CorpusMatcher, error) {
+	if minLines <= 0 {
+		minLines = DefaultMinMatchLines
+	}
+	m := &CorpusMatcher{windows: make(map[uint64]location), minLines: minLines}
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info, err := d.Info(); err != nil || info.Size() > maxCorpusFileSize {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil || bytes.IndexByte(data, 0) >= 0 {
+			return nil // Unreadable or binary
+		}
+		rel, _ := filepath.Rel(dir, path)
+		content := string(data)
+		lines := significantLines(content)
+		for i := 0; i+minLines <= len(lines); i++ {
+			h := windowHash(lines[i : i+minLines])
+			if _, ok := m.windows[h]; !ok {
+				m.windows[h] = location{source: filepath.ToSlash(rel), license: declaredLicense(content), line: lines[i].num}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to index code corpus: %w", err)
+	}
+	return m, nil
+}
+
+// Match returns the passages of file that match the corpus, merging
+// overlapping windows from the same source into one passage.
+func (m *CorpusMatcher) Match(ctx context.Context, file types.FileArtifact) ([]types.CodeMatch, error) {
+	lines := significantLines(file.Content)
+
+	var (
+		matches []types.CodeMatch
+		last    = -2 // Index of the window that last matched
+	)
+	for i := 0; i+m.minLines <= len(lines); i++ {
+		if err := ctx.Err(); err != nil {
+			return matches, err
+		}
+		loc, ok := m.windows[windowHash(lines[i:i+m.minLines])]
+		if !ok {
+			continue
+		}
+		end := lines[i+m.minLines-1].num
+		if n := len(matches); n > 0 && last == i-1 && matches[n-1].Source == loc.source {
+			matches[n-1].EndLine = end
+		} else {
+			matches = append(matches, types.CodeMatch{
+				Source:     loc.source,
+				License:    loc.license,
+				StartLine:  lines[i].num,
+				EndLine:    end,
+				SourceLine: loc.line,
+			})
+		}
+		last = i
+	}
+	return matches, nil
+}
+
+// significantLines returns the trimmed lines that contain a letter or digit.
+func significantLines(content string) []line {
+	var lines []line
+	num := 0
+	for l := range strings.Lines(content) {
+		num++
+		text := strings.TrimSpace(l)
+		if strings.IndexFunc(text, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0 {
+			lines = append(lines, line{text: text, num: num})
+		}
+	}
+	return lines
+}
+
+// windowHash hashes a window of lines.
+func windowHash(lines []line) uint64 {
+	h := fnv.New64a()
+	for _, l := range lines {
+		h.Write([]byte(l.text))
+		h.Write([]byte{'\n'})
+	}
+	return h.Sum64()
+}
+
+// declaredLicense returns the SPDX license identifier a file declares, if any.
+func declaredLicense(content string) string {
+	_, after, ok := strings.Cut(content, spdxTag)
+	if !ok {
+		return ""
+	}
+	license, _, _ := strings.Cut(strings.TrimSpace(after), "\n")
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(license), "*/"))
+}
@@ -0,0 +1,158 @@
+// Package provenance records where generated files came from and checks them
+// for compliance. Every file gets provenance metadata: the agent and model
+// that generated it, a hash of the prompt and when. Optionally a license
+// header is added, and files are checked for long passages copied verbatim
+// from known open-source code.
+package provenance
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// spdxTag marks a license header, so files that have one do not get another.
+const spdxTag = "SPDX-License-Identifier:"
+
+// Matcher finds passages of a file that match known open-source code verbatim.
+type Matcher interface {
+	Match(ctx context.Context, file types.FileArtifact) ([]types.CodeMatch, error)
+}
+
+// Stamper records the provenance of generated files. A nil *Stamper records
+// provenance metadata only.
+type Stamper struct {
+	matcher Matcher
+	license string
+	now     func() time.Time
+}
+
+// Option configures a Stamper.
+type Option func(*Stamper)
+
+// WithLicense adds a header with the SPDX license identifier to files in
+// languages whose comment syntax is known.
+func WithLicense(license string) Option {
+	return func(s *Stamper) {
+		s.license = license
+	}
+}
+
+// WithMatcher checks files for verbatim matches with known open-source code.
+func WithMatcher(matcher Matcher) Option {
+	return func(s *Stamper) {
+		s.matcher = matcher
+	}
+}
+
+// New creates a Stamper.
+func New(opts ...Option) *Stamper {
+	s := &Stamper{now: time.Now}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Stamp records the provenance of the files that have none yet, which are the
+// ones generated from prompt; files carried over from earlier generations keep
+// theirs. It returns the newly stamped files that match known code, along
+// with matching failures joined.
+func (s *Stamper) Stamp(ctx context.Context, agentID, model, prompt string, files []types.FileArtifact) ([]types.FileArtifact, error) {
+	now := time.Now
+	if s != nil {
+		now = s.now
+	}
+	hash := PromptHash(prompt)
+
+	var (
+		flagged []types.FileArtifact
+		errs    []error
+	)
+	for i, f := range files {
+		if f.Provenance != nil {
+			continue
+		}
+		p := &types.Provenance{CreatedAt: now(), AgentID: agentID, Model: model, PromptHash: hash}
+
+		if s != nil && s.license != "" {
+			if content, ok := InjectHeader(f.Path, f.Content, s.license); ok {
+				f.Content, f.Bytes = content, len(content)
+				p.License = s.license
+			}
+		}
+		if s != nil && s.matcher != nil {
+			matches, err := s.matcher.Match(ctx, f)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to check %s for known code: %w", f.Path, err))
+			}
+			p.Matches = matches
+		}
+
+		f.Provenance = p
+		files[i] = f
+		if len(p.Matches) > 0 {
+			flagged = append(flagged, f)
+		}
+	}
+	return flagged, errors.Join(errs...)
+}
+
+// PromptHash returns the hex SHA-256 of a prompt, identifying it without storing it.
+func PromptHash(prompt string) string {
+	sum := sha256.Sum256([]byte(prompt))
+	return hex.EncodeToString(sum[:])
+}
+
+// lineComments maps file extensions to their line comment prefix.
+var lineComments = map[string]string{
+	".go": "//", ".js": "//", ".jsx": "//", ".ts": "//", ".tsx": "//", ".java": "//", ".kt": "//",
+	".c": "//", ".h": "//", ".cc": "//", ".cpp": "//", ".hpp": "//", ".cs": "//", ".rs": "//",
+	".swift": "//", ".scala": "//", ".dart": "//", ".php": "//", ".proto": "//",
+	".py": "#", ".rb": "#", ".sh": "#", ".bash": "#", ".pl": "#", ".r": "#",
+	".yaml": "#", ".yml": "#", ".toml": "#", ".tf": "#",
+	".sql": "--", ".lua": "--", ".hs": "--",
+}
+
+// namedComments maps file names without a telling extension to their line comment prefix.
+var namedComments = map[string]string{
+	"Dockerfile": "#",
+	"Makefile":   "#",
+}
+
+// InjectHeader adds a license header to content in the comment syntax of the
+// file's language. It reports false, leaving content unchanged, for unknown
+// languages and files that already declare a license. A shebang line stays first.
+func InjectHeader(path, content, license string) (string, bool) {
+	if strings.Contains(content, spdxTag) {
+		return content, false
+	}
+	return InjectComment(path, content, spdxTag+" "+license)
+}
+
+// InjectComment adds a comment to the top of content in the comment syntax
+// of the file's language. It reports false, leaving content unchanged, for
+// unknown languages. A shebang line stays first.
+func InjectComment(path, content, comment string) (string, bool) {
+	prefix, ok := lineComments[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		prefix, ok = namedComments[filepath.Base(path)]
+	}
+	if !ok {
+		return content, false
+	}
+
+	header := fmt.Sprintf("%s %s\n\n", prefix, comment)
+	if strings.HasPrefix(content, "#!") {
+		shebang, rest, _ := strings.Cut(content, "\n")
+		return shebang + "\n" + header + rest, true
+	}
+	return header + content, true
+}
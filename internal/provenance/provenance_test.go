@@ -0,0 +1,123 @@
+package provenance
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// knownCode is an MIT-licensed file in the test corpus.
+const knownCode = `// SPDX-License-Identifier: MIT
+package ring
+
+// Buffer is a fixed-size ring buffer.
+type Buffer struct {
+	items []int
+	head  int
+	size  int
+}
+
+// Push adds an item, overwriting the oldest when full.
+func (b *Buffer) Push(v int) {
+	b.items[(b.head+b.size)%len(b.items)] = v
+	if b.size < len(b.items) {
+		b.size++
+	} else {
+		b.head = (b.head + 1) % len(b.items)
+	}
+}
+`
+
+func TestStamper(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+
+	t.Run("RecordsProvenance", func(t *testing.T) {
+		kept := &types.Provenance{AgentID: "engineer-2", PromptHash: "earlier"}
+		files := []types.FileArtifact{
+			{Path: "main.go", Content: "package main\n"},
+			{Path: "util.go", Content: "package main\n", Provenance: kept},
+		}
+		flagged, err := (*Stamper)(nil).Stamp(ctx, "engineer-1", "claude", "Implement main", files)
+		if err != nil || len(flagged) != 0 {
+			t.Fatalf("Expected no matches without a matcher, got %+v, %v", flagged, err)
+		}
+
+		p := files[0].Provenance
+		if p == nil || p.AgentID != "engineer-1" || p.Model != "claude" || p.PromptHash != PromptHash("Implement main") || p.CreatedAt.IsZero() {
+			t.Errorf("Unexpected provenance: %+v", p)
+		}
+		if files[0].Content != "package main\n" {
+			t.Errorf("Expected no license header without a license, got %q", files[0].Content)
+		}
+		if files[1].Provenance != kept {
+			t.Errorf("Expected a file from an earlier generation to keep its provenance, got %+v", files[1].Provenance)
+		}
+	})
+
+	t.Run("LicenseHeader", func(t *testing.T) {
+		stamper := New(WithLicense("Apache-2.0"))
+		stamper.now = func() time.Time { return now }
+		files := []types.FileArtifact{
+			{Path: "run.sh", Content: "#!/bin/sh\necho hi\n"},
+			{Path: "main.go", Content: "package main\n"},
+			{Path: "notes.txt", Content: "Notes\n"},
+			{Path: "lib.py", Content: "# SPDX-License-Identifier: MIT\n"},
+		}
+		if _, err := stamper.Stamp(ctx, "engineer-1", "claude", "prompt", files); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := files[0].Content; got != "#!/bin/sh\n# SPDX-License-Identifier: Apache-2.0\n\necho hi\n" {
+			t.Errorf("Expected the header after the shebang, got %q", got)
+		}
+		if got := files[1]; !strings.HasPrefix(got.Content, "// SPDX-License-Identifier: Apache-2.0\n\npackage main") ||
+			got.Bytes != len(got.Content) || got.Provenance.License != "Apache-2.0" || !got.Provenance.CreatedAt.Equal(now) {
+			t.Errorf("Expected a Go comment header, got %+v", got)
+		}
+		if files[2].Provenance.License != "" || files[3].Provenance.License != "" || files[3].Content != "# SPDX-License-Identifier: MIT\n" {
+			t.Errorf("Expected unknown languages and licensed files to be left alone, got %+v", files[2:])
+		}
+	})
+
+	t.Run("FlagsKnownCode", func(t *testing.T) {
+		corpus := t.TempDir()
+		if err := os.MkdirAll(filepath.Join(corpus, "ring"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(corpus, "ring", "buffer.go"), []byte(knownCode), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		matcher, err := NewCorpusMatcher(corpus, 6)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Reindented and surrounded by new code, the copied function still matches
+		copied := "package queue\n\nimport \"fmt\"\n\n" + strings.ReplaceAll(knownCode[strings.Index(knownCode, "// Push"):], "\t", "    ") + "\nfunc Print() { fmt.Println() }\n"
+		files := []types.FileArtifact{
+			{Path: "queue.go", Content: copied},
+			{Path: "original.go", Content: "package queue\n\nfunc Len() int {\n\treturn 0\n}\n"},
+		}
+		flagged, err := New(WithMatcher(matcher)).Stamp(ctx, "engineer-1", "claude", "prompt", files)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(flagged) != 1 || flagged[0].Path != "queue.go" {
+			t.Fatalf("Expected only the copied file to be flagged, got %+v", flagged)
+		}
+		matches := flagged[0].Provenance.Matches
+		if len(matches) != 1 {
+			t.Fatalf("Expected the copied passage as one match, got %+v", matches)
+		}
+		if m := matches[0]; m.Source != "ring/buffer.go" || m.License != "MIT" || m.StartLine != 5 || m.EndLine != 11 || m.SourceLine != 11 {
+			t.Errorf("Unexpected match: %+v", m)
+		}
+	})
+}
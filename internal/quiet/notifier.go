@@ -0,0 +1,121 @@
+package quiet
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sender delivers notifications, e.g. to the desktop.
+type Sender interface {
+	Notify(ctx context.Context, notificationType, message string) error
+}
+
+// Notifier holds back notifications during quiet hours. Held notifications
+// are delivered as one digest per type when quiet hours end, or dropped if
+// the configuration suppresses them. Urgent types are always delivered.
+type Notifier struct {
+	next  Sender
+	hours *Hours
+	now   func() time.Time
+	timer *time.Timer
+	held  map[string][]string // Messages by notification type
+	order []string            // Notification types in the order first held
+	mu    sync.Mutex
+}
+
+// NewNotifier wraps a sender so it respects quiet hours.
+func NewNotifier(hours *Hours, next Sender) *Notifier {
+	return &Notifier{
+		next:  next,
+		hours: hours,
+		now:   time.Now,
+		held:  make(map[string][]string),
+	}
+}
+
+// Notify delivers a notification, or holds it back during quiet hours.
+func (n *Notifier) Notify(ctx context.Context, notificationType, message string) error {
+	now := n.now()
+	end, quiet := n.hours.End(now)
+	if !quiet {
+		n.flush(ctx)
+	}
+	if !quiet || n.hours.Urgent(notificationType) {
+		return n.next.Notify(ctx, notificationType, message)
+	}
+	if !n.hours.Batches() {
+		return nil
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if _, ok := n.held[notificationType]; !ok {
+		n.order = append(n.order, notificationType)
+	}
+	n.held[notificationType] = append(n.held[notificationType], message)
+
+	if n.timer == nil {
+		n.timer = time.AfterFunc(end.Sub(now), func() {
+			n.flush(context.Background())
+		})
+	}
+	return nil
+}
+
+// Held returns how many notifications are waiting for quiet hours to end.
+func (n *Notifier) Held() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.count()
+}
+
+// count returns the number of held notifications. The caller must hold n.mu.
+func (n *Notifier) count() int {
+	held := 0
+	for _, messages := range n.held {
+		held += len(messages)
+	}
+	return held
+}
+
+// flush delivers the held notifications as one digest per type.
+func (n *Notifier) flush(ctx context.Context) {
+	n.mu.Lock()
+	held, order := n.held, n.order
+	n.held, n.order = make(map[string][]string), nil
+	if n.timer != nil {
+		n.timer.Stop()
+		n.timer = nil
+	}
+	n.mu.Unlock()
+
+	for _, notificationType := range order {
+		messages := held[notificationType]
+		message := messages[0]
+		if len(messages) > 1 {
+			message = fmt.Sprintf("%d during quiet hours:\n%s", len(messages), strings.Join(messages, "\n"))
+		}
+		if err := n.next.Notify(ctx, notificationType, message); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+	}
+}
+
+// Close stops waiting for quiet hours to end. Notifications still held are dropped.
+func (n *Notifier) Close() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.timer != nil {
+		n.timer.Stop()
+		n.timer = nil
+	}
+	if held := n.count(); held > 0 {
+		fmt.Printf("Warning: dropping %d notification(s) held for quiet hours\n", held)
+	}
+}
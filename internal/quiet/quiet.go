@@ -0,0 +1,200 @@
+// Package quiet implements quiet hours: recurring periods during which
+// notifications are held back or dropped and background work pauses, so a
+// channel shared with a real team stays silent outside its working hours.
+package quiet
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// Notification handling during quiet hours.
+const (
+	ModeBatch    = "batch"
+	ModeSuppress = "suppress"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// window is a parsed QuietWindow. Times are minutes since midnight.
+type window struct {
+	days  []time.Weekday // Empty means every day
+	start int
+	end   int
+}
+
+// Hours decides whether a moment falls in quiet hours. A nil *Hours is never quiet.
+type Hours struct {
+	loc             *time.Location
+	mode            string
+	windows         []window
+	urgent          []string
+	pauseBackground bool
+}
+
+// New parses a quiet hours configuration. A nil or disabled configuration
+// returns nil, which is never quiet.
+func New(config *types.QuietHoursConfig) (*Hours, error) {
+	if config == nil || !config.Enabled {
+		return nil, nil
+	}
+
+	h := &Hours{
+		loc:             time.Local,
+		mode:            ModeBatch,
+		urgent:          config.Urgent,
+		pauseBackground: config.PauseBackground,
+	}
+
+	if config.Timezone != "" {
+		loc, err := time.LoadLocation(config.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quiet hours timezone %q: %w", config.Timezone, err)
+		}
+		h.loc = loc
+	}
+
+	switch config.Notifications {
+	case "", ModeBatch:
+	case ModeSuppress:
+		h.mode = ModeSuppress
+	default:
+		return nil, fmt.Errorf("invalid quiet hours notifications %q: must be %s or %s", config.Notifications, ModeBatch, ModeSuppress)
+	}
+
+	for i, w := range config.Windows {
+		parsed, err := parseWindow(w)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quiet hours window %d: %w", i+1, err)
+		}
+		h.windows = append(h.windows, parsed)
+	}
+
+	return h, nil
+}
+
+// parseWindow parses the times and days of a window.
+func parseWindow(w types.QuietWindow) (window, error) {
+	var parsed window
+	var err error
+	if parsed.start, err = parseClock(w.Start); err != nil {
+		return parsed, err
+	}
+	if parsed.end, err = parseClock(w.End); err != nil {
+		return parsed, err
+	}
+
+	for _, day := range w.Days {
+		key := strings.ToLower(strings.TrimSpace(day))
+		if len(key) > 3 {
+			key = key[:3]
+		}
+		weekday, ok := weekdays[key]
+		if !ok {
+			return parsed, fmt.Errorf("unknown day %q", day)
+		}
+		parsed.days = append(parsed.days, weekday)
+	}
+
+	return parsed, nil
+}
+
+// parseClock parses HH:MM into minutes since midnight.
+func parseClock(clock string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(clock))
+	if err != nil {
+		return 0, fmt.Errorf("time %q is not HH:MM", clock)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// on reports whether the window may start on a day.
+func (w window) on(day time.Weekday) bool {
+	return len(w.days) == 0 || slices.Contains(w.days, day)
+}
+
+// endAfter returns when the window ends if it covers t, which must be in the
+// window's location.
+func (w window) endAfter(t time.Time) (time.Time, bool) {
+	minute := t.Hour()*60 + t.Minute()
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	yesterday := t.AddDate(0, 0, -1).Weekday()
+
+	switch {
+	case w.start == w.end:
+		if w.on(t.Weekday()) {
+			return midnight.AddDate(0, 0, 1), true
+		}
+	case w.start < w.end:
+		if w.on(t.Weekday()) && minute >= w.start && minute < w.end {
+			return midnight.Add(time.Duration(w.end) * time.Minute), true
+		}
+	default: // Runs past midnight
+		if w.on(t.Weekday()) && minute >= w.start {
+			return midnight.AddDate(0, 0, 1).Add(time.Duration(w.end) * time.Minute), true
+		}
+		if w.on(yesterday) && minute < w.end {
+			return midnight.Add(time.Duration(w.end) * time.Minute), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Active reports whether t falls in quiet hours.
+func (h *Hours) Active(t time.Time) bool {
+	_, quiet := h.End(t)
+	return quiet
+}
+
+// End returns when the quiet hours covering t end, following back-to-back
+// windows, and false if t is not in quiet hours.
+func (h *Hours) End(t time.Time) (time.Time, bool) {
+	if h == nil {
+		return time.Time{}, false
+	}
+
+	t = t.In(h.loc)
+	end, quiet := t, false
+	// Every pass moves past at least one window, so a week of windows bounds the loop
+	for range 8 * len(h.windows) {
+		next := end
+		for _, w := range h.windows {
+			if e, ok := w.endAfter(end); ok && e.After(next) {
+				next = e
+			}
+		}
+		if !next.After(end) {
+			break
+		}
+		end, quiet = next, true
+	}
+	return end, quiet
+}
+
+// Urgent reports whether a notification type is delivered even during quiet hours.
+func (h *Hours) Urgent(notificationType string) bool {
+	return h != nil && slices.Contains(h.urgent, notificationType)
+}
+
+// Batches reports whether notifications held during quiet hours are
+// delivered when they end rather than dropped.
+func (h *Hours) Batches() bool {
+	return h != nil && h.mode == ModeBatch
+}
+
+// PausesBackground reports whether background work should pause now.
+func (h *Hours) PausesBackground() bool {
+	return h != nil && h.pauseBackground && h.Active(time.Now())
+}
@@ -0,0 +1,149 @@
+package quiet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// at returns a time in UTC during the week of Monday 2026-01-05.
+func at(day time.Weekday, hour, minute int) time.Time {
+	return time.Date(2026, 1, 4+int(day), hour, minute, 0, 0, time.UTC)
+}
+
+func TestHours(t *testing.T) {
+	hours, err := New(&types.QuietHoursConfig{
+		Enabled:  true,
+		Timezone: "UTC",
+		Windows: []types.QuietWindow{
+			{Start: "18:00", End: "09:00"},
+			{Start: "00:00", End: "00:00", Days: []string{"sat", "Sunday"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to parse quiet hours: %v", err)
+	}
+
+	t.Run("Active", func(t *testing.T) {
+		cases := []struct {
+			at    time.Time
+			quiet bool
+		}{
+			{at(time.Tuesday, 12, 0), false},
+			{at(time.Tuesday, 18, 0), true},
+			{at(time.Wednesday, 8, 59), true},
+			{at(time.Wednesday, 9, 0), false},
+			{at(time.Saturday, 12, 0), true},
+		}
+		for _, c := range cases {
+			if got := hours.Active(c.at); got != c.quiet {
+				t.Errorf("Active(%s) = %v, want %v", c.at.Format("Mon 15:04"), got, c.quiet)
+			}
+		}
+	})
+
+	t.Run("EndFollowsAdjacentWindows", func(t *testing.T) {
+		// Friday evening runs into the weekend, then into Sunday night
+		end, quiet := hours.End(at(time.Friday, 20, 0))
+		if want := at(time.Saturday, 0, 0).AddDate(0, 0, 2).Add(9 * time.Hour); !quiet || !end.Equal(want) {
+			t.Errorf("Expected quiet hours to end %s, got %s (quiet %v)", want, end, quiet)
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		hours, err := New(&types.QuietHoursConfig{Windows: []types.QuietWindow{{Start: "00:00", End: "00:00"}}})
+		if err != nil || hours != nil {
+			t.Fatalf("Expected no quiet hours when disabled, got %v, %v", hours, err)
+		}
+		if hours.Active(time.Now()) || hours.PausesBackground() {
+			t.Error("Expected nil quiet hours to never be quiet")
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, cfg := range []types.QuietHoursConfig{
+			{Enabled: true, Windows: []types.QuietWindow{{Start: "25:00", End: "08:00"}}},
+			{Enabled: true, Windows: []types.QuietWindow{{Start: "22:00", End: "08:00", Days: []string{"someday"}}}},
+			{Enabled: true, Timezone: "Nowhere/City"},
+			{Enabled: true, Notifications: "later"},
+		} {
+			if _, err := New(&cfg); err == nil {
+				t.Errorf("Expected %+v to be rejected", cfg)
+			}
+		}
+	})
+}
+
+type recordingSender struct {
+	sent []string
+}
+
+func (s *recordingSender) Notify(ctx context.Context, notificationType, message string) error {
+	s.sent = append(s.sent, notificationType+": "+message)
+	return nil
+}
+
+func TestNotifier(t *testing.T) {
+	ctx := context.Background()
+	config := &types.QuietHoursConfig{
+		Enabled:  true,
+		Timezone: "UTC",
+		Windows:  []types.QuietWindow{{Start: "18:00", End: "09:00"}},
+		Urgent:   []string{"approval_requested"},
+	}
+
+	t.Run("Batch", func(t *testing.T) {
+		hours, _ := New(config)
+		sender := &recordingSender{}
+		n := NewNotifier(hours, sender)
+		defer n.Close()
+
+		n.now = func() time.Time { return at(time.Tuesday, 22, 0) }
+		_ = n.Notify(ctx, "project_completed", "Completed: a")
+		_ = n.Notify(ctx, "project_completed", "Completed: b")
+		_ = n.Notify(ctx, "approval_requested", "Approve plan")
+
+		if len(sender.sent) != 1 || sender.sent[0] != "approval_requested: Approve plan" {
+			t.Fatalf("Expected only the urgent notification during quiet hours, got %v", sender.sent)
+		}
+		if n.Held() != 2 {
+			t.Errorf("Expected 2 held notifications, got %d", n.Held())
+		}
+
+		n.now = func() time.Time { return at(time.Wednesday, 10, 0) }
+		_ = n.Notify(ctx, "project_failed", "Failed: c")
+
+		want := []string{
+			"approval_requested: Approve plan",
+			"project_completed: 2 during quiet hours:\nCompleted: a\nCompleted: b",
+			"project_failed: Failed: c",
+		}
+		if len(sender.sent) != len(want) {
+			t.Fatalf("Expected %v, got %v", want, sender.sent)
+		}
+		for i := range want {
+			if sender.sent[i] != want[i] {
+				t.Errorf("Notification %d: expected %q, got %q", i, want[i], sender.sent[i])
+			}
+		}
+	})
+
+	t.Run("Suppress", func(t *testing.T) {
+		suppress := *config
+		suppress.Notifications = ModeSuppress
+		hours, _ := New(&suppress)
+		sender := &recordingSender{}
+		n := NewNotifier(hours, sender)
+
+		n.now = func() time.Time { return at(time.Tuesday, 22, 0) }
+		_ = n.Notify(ctx, "project_completed", "Completed: a")
+		n.now = func() time.Time { return at(time.Wednesday, 10, 0) }
+		_ = n.Notify(ctx, "project_completed", "Completed: b")
+
+		if len(sender.sent) != 1 || sender.sent[0] != "project_completed: Completed: b" {
+			t.Errorf("Expected notifications during quiet hours to be dropped, got %v", sender.sent)
+		}
+	})
+}
@@ -0,0 +1,92 @@
+package report
+
+import (
+	"html/template"
+	"io"
+	"path/filepath"
+	"time"
+)
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"anchor":     anchor,
+	"layerTitle": layerTitle,
+	"clock":      func(t time.Time) string { return t.Format(time.TimeOnly) },
+	"slash":      filepath.ToSlash,
+	"latency":    formatLatency,
+	"callStatus": callStatus,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Project Report {{.Report.RunID}}</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 960px; margin: 2rem auto; padding: 0 1rem; line-height: 1.5; }
+pre { background: #f6f8fa; padding: 1rem; overflow-x: auto; white-space: pre-wrap; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border: 1px solid #d0d7de; padding: .4rem; text-align: left; vertical-align: top; }
+.meta { color: #57606a; }
+img { max-width: 100%; }
+</style>
+</head>
+<body>
+<h1>Project Report</h1>
+<p class="meta">Run ID <code>{{.Report.RunID}}</code> · Generated {{.Report.GeneratedAt.Format "Mon, 02 Jan 2006 15:04:05 MST"}}{{with .Report.Version}} · BuildBureau {{.}}{{end}}</p>
+
+<h2>Contents</h2>
+<ul>{{range .Sections}}
+<li><a href="#{{.anchor}}">{{.title}}</a></li>{{end}}
+</ul>
+
+<h2 id="requirements">Requirements</h2>
+<pre>{{or .Report.Requirements "Not recorded."}}</pre>
+
+<h2 id="result">Result</h2>
+<pre>{{or .Report.Result "Not recorded."}}</pre>
+{{with .Report.Security}}
+<h2 id="security">Security</h2>
+<pre>{{.}}</pre>
+{{end}}{{with .Report.Images}}
+<h2 id="images">Images</h2>{{range .}}
+<h3>{{.Path}}</h3>
+<img src="{{slash .Stored}}" alt="{{.Path}}">
+<p class="meta">By {{.AgentID}}</p>
+<pre>{{.Description}}</pre>{{end}}
+{{end}}{{range .Report.Layers}}
+<h2 id="{{anchor (layerTitle .)}}">{{layerTitle .}}</h2>{{range .Entries}}
+<h3>{{clock .Time}} · {{.AgentID}} · {{.Kind}}</h3>
+<pre>{{.Content}}</pre>{{end}}
+{{end}}
+<h2 id="decisions">Decisions</h2>
+{{if .Report.Decisions}}<table>
+<tr><th>Agent</th><th>Decision</th><th>Reasoning</th><th>Alternatives rejected</th></tr>{{range .Report.Decisions}}
+<tr><td>{{.AgentID}}</td><td>{{.What}}</td><td>{{.Why}}</td><td>{{.Alternatives}}</td></tr>{{end}}
+</table>{{else}}<p><em>None recorded.</em></p>{{end}}
+
+<h2 id="artifacts">Artifacts</h2>
+{{range .Report.Artifacts}}<h3>{{.Title}}</h3>
+<p class="meta">By {{.AgentID}}</p>
+<pre>{{.Content}}</pre>
+{{else}}<p><em>None recorded.</em></p>{{end}}
+{{with .Report.SlowestCalls}}
+<h2 id="slowest-llm-calls">Slowest LLM Calls</h2>
+<table>
+<tr><th>Provider</th><th>Model</th><th>Latency</th><th>Tokens (prompt / completion)</th><th>Status</th></tr>{{range .}}
+<tr><td>{{.Provider}}</td><td>{{.Model}}</td><td>{{latency .}}</td><td>{{.PromptTokens}} / {{.CompletionTokens}}</td><td>{{callStatus .}}</td></tr>{{end}}
+</table>
+{{end}}</body>
+</html>
+`))
+
+// renderHTML writes the report as a standalone HTML page with a table of contents.
+func renderHTML(w io.Writer, r *Report) error {
+	type tocEntry = map[string]string
+	var toc []tocEntry
+	for _, s := range sections(r) {
+		toc = append(toc, tocEntry{"title": s.title, "anchor": s.anchor})
+	}
+
+	return htmlTemplate.Execute(w, map[string]any{
+		"Report":   r,
+		"Sections": toc,
+	})
+}
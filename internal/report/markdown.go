@@ -0,0 +1,165 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// renderMarkdown writes the report as Markdown with a linked table of contents.
+func renderMarkdown(w io.Writer, r *Report) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# Project Report\n\n")
+	fmt.Fprintf(&b, "- **Run ID:** `%s`\n- **Generated:** %s\n", r.RunID, r.GeneratedAt.Format(time.RFC1123))
+	if r.Version != "" {
+		fmt.Fprintf(&b, "- **BuildBureau:** %s\n", r.Version)
+	}
+	b.WriteString("\n")
+
+	b.WriteString("## Contents\n\n")
+	for _, s := range sections(r) {
+		fmt.Fprintf(&b, "- [%s](#%s)\n", s.title, s.anchor)
+	}
+	b.WriteString("\n")
+
+	b.WriteString("## Requirements\n\n")
+	b.WriteString(orNone(r.Requirements))
+	b.WriteString("\n\n")
+
+	b.WriteString("## Result\n\n")
+	b.WriteString(orNone(r.Result))
+	b.WriteString("\n\n")
+
+	if r.Security != "" {
+		b.WriteString("## Security\n\n")
+		b.WriteString(r.Security)
+		b.WriteString("\n\n")
+	}
+
+	if len(r.Images) > 0 {
+		b.WriteString("## Images\n\n")
+		for _, img := range r.Images {
+			fmt.Fprintf(&b, "### %s\n\n![%s](%s)\n\n_By %s_\n\n%s\n\n", img.Path, cell(img.Path), filepath.ToSlash(img.Stored), img.AgentID, quote(img.Description))
+		}
+	}
+
+	for _, layer := range r.Layers {
+		fmt.Fprintf(&b, "## %s\n\n", layerTitle(layer))
+		for _, e := range layer.Entries {
+			fmt.Fprintf(&b, "### %s · %s · %s\n\n", e.Time.Format(time.TimeOnly), e.AgentID, e.Kind)
+			b.WriteString(quote(e.Content))
+			b.WriteString("\n\n")
+		}
+	}
+
+	b.WriteString("## Decisions\n\n")
+	if len(r.Decisions) == 0 {
+		b.WriteString("_None recorded._\n\n")
+	} else {
+		b.WriteString("| Agent | Decision | Reasoning | Alternatives rejected |\n|---|---|---|---|\n")
+		for _, d := range r.Decisions {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", d.AgentID, cell(d.What), cell(d.Why), cell(d.Alternatives))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("## Artifacts\n\n")
+	if len(r.Artifacts) == 0 {
+		b.WriteString("_None recorded._\n")
+	}
+	for _, a := range r.Artifacts {
+		fmt.Fprintf(&b, "### %s\n\n_By %s_\n\n", a.Title, a.AgentID)
+		b.WriteString(a.Content)
+		b.WriteString("\n\n")
+	}
+
+	if len(r.SlowestCalls) > 0 {
+		b.WriteString("## Slowest LLM Calls\n\n")
+		b.WriteString("| Provider | Model | Latency | Tokens (prompt / completion) | Status |\n|---|---|---|---|---|\n")
+		for _, c := range r.SlowestCalls {
+			fmt.Fprintf(&b, "| %s | %s | %s | %d / %d | %s |\n", c.Provider, cell(c.Model), formatLatency(c), c.PromptTokens, c.CompletionTokens, cell(callStatus(c)))
+		}
+		b.WriteString("\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// section is a table of contents entry.
+type section struct {
+	title  string
+	anchor string
+}
+
+// sections lists the report's top-level sections in order.
+func sections(r *Report) []section {
+	titles := []string{"Requirements", "Result"}
+	if r.Security != "" {
+		titles = append(titles, "Security")
+	}
+	if len(r.Images) > 0 {
+		titles = append(titles, "Images")
+	}
+	for _, layer := range r.Layers {
+		titles = append(titles, layerTitle(layer))
+	}
+	titles = append(titles, "Decisions", "Artifacts")
+	if len(r.SlowestCalls) > 0 {
+		titles = append(titles, "Slowest LLM Calls")
+	}
+
+	result := make([]section, len(titles))
+	for i, title := range titles {
+		result[i] = section{title: title, anchor: anchor(title)}
+	}
+	return result
+}
+
+// layerTitle is the heading of a layer's section.
+func layerTitle(layer Layer) string {
+	return fmt.Sprintf("%s Layer", layer.Role)
+}
+
+// anchor returns the GitHub-style anchor of a heading.
+func anchor(title string) string {
+	return strings.ReplaceAll(strings.ToLower(title), " ", "-")
+}
+
+// quote renders text as a Markdown block quote.
+func quote(text string) string {
+	return "> " + strings.ReplaceAll(strings.TrimSpace(text), "\n", "\n> ")
+}
+
+// cell escapes text for a Markdown table cell.
+func cell(text string) string {
+	text = strings.ReplaceAll(text, "|", `\|`)
+	return strings.ReplaceAll(text, "\n", "<br>")
+}
+
+func orNone(text string) string {
+	if text == "" {
+		return "_Not recorded._"
+	}
+	return text
+}
+
+// formatLatency renders a call's latency, marking slow calls.
+func formatLatency(c Call) string {
+	latency := c.Latency.Round(10 * time.Millisecond).String()
+	if c.Slow {
+		latency += " (slow)"
+	}
+	return latency
+}
+
+// callStatus renders a call's status with its error.
+func callStatus(c Call) string {
+	if c.Error == "" {
+		return c.Status
+	}
+	return c.Status + ": " + c.Error
+}
@@ -0,0 +1,257 @@
+// Package report renders a project's transcript, built from the memories
+// recorded during its run, into a shareable Markdown or HTML document.
+package report
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kpango/BuildBureau/internal/version"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// Supported output formats.
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+// MetadataKind marks memories that hold a section of the report rather than
+// agent activity. KindSecurityScan holds the security scan summary and
+// KindImage the description of a generated image, whose path in the
+// deliverable and stored copy are in MetadataPath and MetadataStored.
+const (
+	MetadataKind     = "kind"
+	KindSecurityScan = "security_scan"
+	KindImage        = "image"
+
+	MetadataPath   = "path"
+	MetadataStored = "stored"
+)
+
+// KindLLMCall marks a memory recording one of the project's slowest LLM
+// calls, described by the call metadata keys. Its content is the call's
+// error, if it failed.
+const (
+	KindLLMCall = "llm_call"
+
+	MetadataProvider         = "provider"
+	MetadataModel            = "model"
+	MetadataStatus           = "status"
+	MetadataLatency          = "latency"
+	MetadataPromptTokens     = "prompt_tokens"
+	MetadataCompletionTokens = "completion_tokens"
+	MetadataSlow             = "slow"
+)
+
+// layerOrder is the order layers appear in the report, top of the hierarchy first.
+var layerOrder = []types.AgentRole{
+	types.RoleClient,
+	types.RolePresident,
+	types.RoleSecretary,
+	types.RoleDirector,
+	types.RoleManager,
+	types.RoleEngineer,
+}
+
+// Report is the transcript of one project.
+type Report struct {
+	GeneratedAt  time.Time
+	RunID        string
+	Version      string // BuildBureau build that ran the project
+	Requirements string
+	Result       string
+	Security     string // Summary of the last security scan, if one ran
+	Layers       []Layer
+	Decisions    []Decision
+	Artifacts    []Artifact
+	Images       []Image
+	SlowestCalls []Call // Slowest LLM calls, slowest first
+}
+
+// Layer holds what one layer of the hierarchy said and did, in order.
+type Layer struct {
+	Role    types.AgentRole
+	Entries []Entry
+}
+
+// Entry is a conversation or task record of one agent.
+type Entry struct {
+	Time    time.Time
+	AgentID string
+	Kind    types.MemoryType
+	Content string
+}
+
+// Decision is a decision recorded during the project.
+type Decision struct {
+	AgentID      string
+	What         string
+	Why          string
+	Alternatives string
+}
+
+// Image is an image generated during the project.
+type Image struct {
+	AgentID     string
+	Path        string // In the deliverable
+	Stored      string // Where the image was written; links point here
+	Description string
+}
+
+// Artifact is a design or implementation produced during the project.
+type Artifact struct {
+	AgentID string
+	Title   string
+	Content string
+}
+
+// Call is one of the slowest LLM calls made during the project.
+type Call struct {
+	Provider         string
+	Model            string
+	Status           string
+	Error            string
+	Latency          time.Duration
+	PromptTokens     int
+	CompletionTokens int
+	Slow             bool // Took longer than the slow threshold
+}
+
+// Build assembles a report from the memories recorded during a run.
+func Build(runID string, memories []*types.MemoryEntry) *Report {
+	sorted := slices.Clone(memories)
+	slices.SortStableFunc(sorted, func(a, b *types.MemoryEntry) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	r := &Report{GeneratedAt: time.Now(), RunID: runID, Version: version.Get().String()}
+	layers := make(map[types.AgentRole][]Entry)
+
+	for _, m := range sorted {
+		switch m.Type {
+		case types.MemoryTypeDecision:
+			r.Decisions = append(r.Decisions, Decision{
+				AgentID:      m.AgentID,
+				What:         firstNonEmpty(m.Metadata["decision"], m.Content),
+				Why:          m.Metadata["reasoning"],
+				Alternatives: m.Metadata["alternatives"],
+			})
+
+		case types.MemoryTypeKnowledge:
+			title, content, _ := strings.Cut(m.Content, "\n")
+			r.Artifacts = append(r.Artifacts, Artifact{AgentID: m.AgentID, Title: title, Content: strings.TrimSpace(content)})
+
+		case types.MemoryTypeContext:
+			// Later scans, of revised deliverables, replace earlier ones
+			switch m.Metadata[MetadataKind] {
+			case KindSecurityScan:
+				r.Security = m.Content
+			case KindImage:
+				// Revisions may generate an image of the same path again
+				img := Image{AgentID: m.AgentID, Path: m.Metadata[MetadataPath], Stored: m.Metadata[MetadataStored], Description: m.Content}
+				if i := slices.IndexFunc(r.Images, func(o Image) bool { return o.Path == img.Path }); i >= 0 {
+					r.Images[i] = img
+				} else {
+					r.Images = append(r.Images, img)
+				}
+			case KindLLMCall:
+				r.SlowestCalls = append(r.SlowestCalls, callOf(m))
+			}
+
+		case types.MemoryTypeConversation, types.MemoryTypeTask:
+			role := roleOf(m.AgentID)
+			layers[role] = append(layers[role], Entry{Time: m.CreatedAt, AgentID: m.AgentID, Kind: m.Type, Content: m.Content})
+
+			// The task the president received from the client holds the
+			// requirements and the final result; revisions replace earlier ones
+			if m.Type == types.MemoryTypeTask && roleOf(m.Metadata["from_agent"]) == types.RoleClient {
+				r.Requirements, r.Result = splitTask(m.Content)
+			}
+		}
+	}
+
+	for _, role := range layerOrder {
+		if entries := layers[role]; len(entries) > 0 {
+			r.Layers = append(r.Layers, Layer{Role: role, Entries: entries})
+			delete(layers, role)
+		}
+	}
+	for role, entries := range layers {
+		r.Layers = append(r.Layers, Layer{Role: role, Entries: entries})
+	}
+	slices.SortStableFunc(r.SlowestCalls, func(a, b Call) int { return cmp.Compare(b.Latency, a.Latency) })
+
+	return r
+}
+
+// callOf reads the LLM call recorded in a memory.
+func callOf(m *types.MemoryEntry) Call {
+	latency, _ := time.ParseDuration(m.Metadata[MetadataLatency])
+	promptTokens, _ := strconv.Atoi(m.Metadata[MetadataPromptTokens])
+	completionTokens, _ := strconv.Atoi(m.Metadata[MetadataCompletionTokens])
+	return Call{
+		Provider:         m.Metadata[MetadataProvider],
+		Model:            m.Metadata[MetadataModel],
+		Status:           m.Metadata[MetadataStatus],
+		Error:            m.Content,
+		Latency:          latency,
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		Slow:             m.Metadata[MetadataSlow] == "true",
+	}
+}
+
+// Render writes the report in the given format.
+func Render(w io.Writer, r *Report, format string) error {
+	switch format {
+	case FormatMarkdown, "md", "":
+		return renderMarkdown(w, r)
+	case FormatHTML:
+		return renderHTML(w, r)
+	default:
+		return fmt.Errorf("unsupported report format %q (expected markdown or html)", format)
+	}
+}
+
+// Extension returns the file extension for a format.
+func Extension(format string) string {
+	if format == FormatHTML {
+		return ".html"
+	}
+	return ".md"
+}
+
+// roleOf derives an agent's role from its ID, e.g. "engineer-2" or "secretary-Director".
+func roleOf(agentID string) types.AgentRole {
+	prefix, _, _ := strings.Cut(agentID, "-")
+	for _, role := range layerOrder {
+		if strings.EqualFold(prefix, string(role)) {
+			return role
+		}
+	}
+	return types.AgentRole(prefix)
+}
+
+// splitTask extracts the description and result from a stored task memory.
+func splitTask(content string) (description, result string) {
+	rest, result, _ := strings.Cut(content, "\nResult: ")
+	if _, d, ok := strings.Cut(rest, "\nDescription: "); ok {
+		description = d
+	}
+	return strings.TrimSpace(description), strings.TrimSpace(result)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
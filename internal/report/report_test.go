@@ -0,0 +1,182 @@
+package report
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+func testMemories() []*types.MemoryEntry {
+	start := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	return []*types.MemoryEntry{
+		{
+			AgentID:   "engineer-1",
+			Type:      types.MemoryTypeConversation,
+			Content:   "Implemented the <todo> API",
+			CreatedAt: start.Add(2 * time.Minute),
+		},
+		{
+			AgentID:   "president-1",
+			Type:      types.MemoryTypeTask,
+			Content:   "Task: Client Request\nDescription: Build a todo app\nResult: Done",
+			CreatedAt: start.Add(3 * time.Minute),
+			Metadata:  map[string]string{"from_agent": "client"},
+		},
+		{
+			AgentID:   "manager-1",
+			Type:      types.MemoryTypeDecision,
+			Content:   "Decision: Use SQLite",
+			CreatedAt: start.Add(time.Minute),
+			Metadata:  map[string]string{"decision": "Use SQLite", "reasoning": "Single user | local", "alternatives": "Postgres"},
+		},
+		{
+			AgentID:   "manager-1",
+			Type:      types.MemoryTypeKnowledge,
+			Content:   "Software Design: Todo\nThree endpoints",
+			CreatedAt: start,
+		},
+		{
+			AgentID:   "security-scanner",
+			Type:      types.MemoryTypeContext,
+			Content:   "1 security finding(s), 1 at or above high severity:\n- [high] G101 main.go:3 (gosec): Potential hardcoded credentials",
+			CreatedAt: start.Add(4 * time.Minute),
+			Metadata:  map[string]string{MetadataKind: KindSecurityScan},
+		},
+		{
+			AgentID:   "engineer-1",
+			Type:      types.MemoryTypeContext,
+			Content:   "Login screen wireframe",
+			CreatedAt: start.Add(2 * time.Minute),
+			Metadata:  map[string]string{MetadataKind: KindImage, MetadataPath: "docs/login.png", MetadataStored: "images/run-1/docs/login-old.png"},
+		},
+		{
+			AgentID:   "engineer-1",
+			Type:      types.MemoryTypeContext,
+			Content:   "Login screen wireframe with a dark theme",
+			CreatedAt: start.Add(5 * time.Minute),
+			Metadata:  map[string]string{MetadataKind: KindImage, MetadataPath: "docs/login.png", MetadataStored: "images/run-1/docs/login.png"},
+		},
+		{
+			AgentID:   "secretary-Director",
+			Type:      types.MemoryTypeConversation,
+			Content:   "Forwarded plan",
+			CreatedAt: start,
+		},
+		{
+			AgentID:   "organization",
+			Type:      types.MemoryTypeContext,
+			CreatedAt: start.Add(6 * time.Minute),
+			Metadata: map[string]string{
+				MetadataKind: KindLLMCall, MetadataProvider: "gemini", MetadataModel: "gemini-2.5-flash", MetadataStatus: "ok",
+				MetadataLatency: "4.2s", MetadataPromptTokens: "1200", MetadataCompletionTokens: "300", MetadataSlow: "false",
+			},
+		},
+		{
+			AgentID:   "organization",
+			Type:      types.MemoryTypeContext,
+			Content:   "deadline exceeded",
+			CreatedAt: start.Add(6 * time.Minute),
+			Metadata: map[string]string{
+				MetadataKind: KindLLMCall, MetadataProvider: "claude", MetadataModel: "claude-3-opus", MetadataStatus: "failed",
+				MetadataLatency: "45s", MetadataPromptTokens: "8000", MetadataCompletionTokens: "0", MetadataSlow: "true",
+			},
+		},
+	}
+}
+
+func TestBuild(t *testing.T) {
+	r := Build("run-1", testMemories())
+
+	if r.Requirements != "Build a todo app" || r.Result != "Done" {
+		t.Errorf("Requirements/Result = %q/%q, want the client task's description and result", r.Requirements, r.Result)
+	}
+
+	var roles []types.AgentRole
+	for _, layer := range r.Layers {
+		roles = append(roles, layer.Role)
+	}
+	want := []types.AgentRole{types.RolePresident, types.RoleSecretary, types.RoleEngineer}
+	if len(roles) != len(want) {
+		t.Fatalf("Layers = %v, want %v", roles, want)
+	}
+	for i := range want {
+		if roles[i] != want[i] {
+			t.Errorf("Layers = %v, want %v", roles, want)
+			break
+		}
+	}
+
+	if !strings.Contains(r.Security, "G101 main.go:3") {
+		t.Errorf("Security = %q, want the scan summary", r.Security)
+	}
+	if len(r.Decisions) != 1 || r.Decisions[0].What != "Use SQLite" || r.Decisions[0].Alternatives != "Postgres" {
+		t.Errorf("Decisions = %+v", r.Decisions)
+	}
+	if len(r.Images) != 1 || r.Images[0].Stored != "images/run-1/docs/login.png" || r.Images[0].Description != "Login screen wireframe with a dark theme" {
+		t.Errorf("Images = %+v, want the latest image of docs/login.png", r.Images)
+	}
+	if len(r.Artifacts) != 1 || r.Artifacts[0].Title != "Software Design: Todo" || r.Artifacts[0].Content != "Three endpoints" {
+		t.Errorf("Artifacts = %+v", r.Artifacts)
+	}
+	if len(r.SlowestCalls) != 2 || r.SlowestCalls[0].Provider != "claude" || r.SlowestCalls[0].Latency != 45*time.Second || !r.SlowestCalls[0].Slow || r.SlowestCalls[1].PromptTokens != 1200 {
+		t.Errorf("SlowestCalls = %+v, want both calls, slowest first", r.SlowestCalls)
+	}
+}
+
+func TestRender(t *testing.T) {
+	r := Build("run-1", testMemories())
+
+	t.Run("markdown", func(t *testing.T) {
+		var b strings.Builder
+		if err := Render(&b, r, FormatMarkdown); err != nil {
+			t.Fatal(err)
+		}
+		out := b.String()
+		for _, want := range []string{
+			"- [Engineer Layer](#engineer-layer)",
+			"## Engineer Layer",
+			"> Implemented the <todo> API",
+			`| manager-1 | Use SQLite | Single user \| local | Postgres |`,
+			"### Software Design: Todo",
+			"- **BuildBureau:** ",
+			"- [Security](#security)",
+			"- [high] G101 main.go:3 (gosec): Potential hardcoded credentials",
+			"- [Images](#images)",
+			"![docs/login.png](images/run-1/docs/login.png)",
+			"- [Slowest LLM Calls](#slowest-llm-calls)",
+			"| claude | claude-3-opus | 45s (slow) | 8000 / 0 | failed: deadline exceeded |",
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("Markdown report missing %q:\n%s", want, out)
+			}
+		}
+	})
+
+	t.Run("html", func(t *testing.T) {
+		var b strings.Builder
+		if err := Render(&b, r, FormatHTML); err != nil {
+			t.Fatal(err)
+		}
+		out := b.String()
+		for _, want := range []string{
+			`<a href="#engineer-layer">Engineer Layer</a>`,
+			`<h2 id="engineer-layer">Engineer Layer</h2>`,
+			"Implemented the &lt;todo&gt; API",
+			`<img src="images/run-1/docs/login.png" alt="docs/login.png">`,
+			`<h2 id="slowest-llm-calls">Slowest LLM Calls</h2>`,
+			"<td>45s (slow)</td>",
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("HTML report missing %q:\n%s", want, out)
+			}
+		}
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		if err := Render(&strings.Builder{}, r, "pdf"); err == nil {
+			t.Error("Expected an error for an unsupported format")
+		}
+	})
+}
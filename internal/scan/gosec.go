@@ -0,0 +1,65 @@
+package scan
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// Gosec scans Go code with gosec.
+type Gosec struct {
+	run   runner
+	Path  string   // gosec binary
+	Rules []string // Rule IDs to run, such as G101; empty runs all
+}
+
+// Name returns "gosec".
+func (g *Gosec) Name() string {
+	return "gosec"
+}
+
+// gosecReport is the part of gosec's JSON output that is used.
+type gosecReport struct {
+	Issues []struct {
+		Severity string `json:"severity"`
+		RuleID   string `json:"rule_id"`
+		Details  string `json:"details"`
+		File     string `json:"file"`
+		Line     string `json:"line"` // A range such as "12-14" for multi-line issues
+	} `json:"Issues"`
+}
+
+// Scan runs gosec over the Go packages under dir.
+func (g *Gosec) Scan(ctx context.Context, dir string) ([]types.SecurityFinding, error) {
+	args := []string{"-fmt=json", "-quiet", "-no-fail"}
+	if len(g.Rules) > 0 {
+		args = append(args, "-include="+strings.Join(g.Rules, ","))
+	}
+	out, runErr := g.run(ctx, dir, g.Path, append(args, "./...")...)
+
+	var report gosecReport
+	if err := json.Unmarshal(out, &report); err != nil {
+		if runErr != nil {
+			return nil, runErr
+		}
+		return nil, fmt.Errorf("failed to parse gosec output: %w", err)
+	}
+
+	findings := make([]types.SecurityFinding, 0, len(report.Issues))
+	for _, issue := range report.Issues {
+		first, _, _ := strings.Cut(issue.Line, "-")
+		line, _ := strconv.Atoi(first)
+		findings = append(findings, types.SecurityFinding{
+			Rule:     issue.RuleID,
+			Severity: strings.ToLower(issue.Severity),
+			File:     issue.File,
+			Line:     line,
+			Message:  issue.Details,
+		})
+	}
+	return findings, nil
+}
@@ -0,0 +1,239 @@
+// Package scan runs static security scanners, gosec and semgrep, over the
+// files a project produced. The files are written to a temporary directory,
+// each scanner runs there with its configured rules, and the findings are
+// reported against the files' paths in the deliverable.
+package scan
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// Severities of findings, lowest first.
+const (
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+var severities = []string{SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical}
+
+const (
+	defaultThreshold = SeverityHigh
+	defaultTimeout   = 5 * time.Minute
+)
+
+// runner runs a command in dir and returns its standard output.
+type runner func(ctx context.Context, dir, name string, args ...string) ([]byte, error)
+
+// runCommand runs a command in dir. Scanners exit with a non-zero status when
+// they find something, so the output is returned along with the error.
+func runCommand(ctx context.Context, dir, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil && stderr.Len() > 0 {
+		err = fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out, err
+}
+
+// Scanner finds security problems in the files of a directory.
+type Scanner interface {
+	Name() string
+	Scan(ctx context.Context, dir string) ([]types.SecurityFinding, error)
+}
+
+// Rank orders severities from 1 (low) to 4 (critical). Unknown severities rank 0.
+func Rank(severity string) int {
+	return slices.Index(severities, strings.ToLower(severity)) + 1
+}
+
+// Runner scans deliverables with a set of scanners.
+type Runner struct {
+	scanners  []Scanner
+	threshold string
+	dir       string // Parent of the directories files are scanned in; the system's if empty
+	timeout   time.Duration
+}
+
+// New sets up the scanners of a security scan configuration whose binaries
+// are installed. A nil or disabled configuration returns nil; so does one
+// whose scanners are all missing, with the reason in the error.
+func New(config *types.SecurityScanConfig) (*Runner, error) {
+	if config == nil || !config.Enabled {
+		return nil, nil
+	}
+
+	r := &Runner{
+		threshold: cmp.Or(strings.ToLower(config.Threshold), defaultThreshold),
+		timeout:   cmp.Or(config.Timeout, defaultTimeout),
+		dir:       config.Dir,
+	}
+	if Rank(r.threshold) == 0 {
+		return nil, fmt.Errorf("invalid security scan threshold %q: must be one of %s", config.Threshold, strings.Join(severities, ", "))
+	}
+
+	var errs []error
+	for _, sc := range config.Scanners {
+		var scanner Scanner
+		switch sc.Name {
+		case "gosec":
+			scanner = &Gosec{Path: cmp.Or(sc.Path, "gosec"), Rules: sc.Rules, run: runCommand}
+		case "semgrep":
+			scanner = &Semgrep{Path: cmp.Or(sc.Path, "semgrep"), Rules: sc.Rules, run: runCommand}
+		default:
+			errs = append(errs, fmt.Errorf("unknown scanner %q: must be gosec or semgrep", sc.Name))
+			continue
+		}
+		if _, err := exec.LookPath(cmp.Or(sc.Path, sc.Name)); err != nil {
+			errs = append(errs, fmt.Errorf("%s is not installed: %w", sc.Name, err))
+			continue
+		}
+		r.scanners = append(r.scanners, scanner)
+	}
+	if len(r.scanners) == 0 {
+		return nil, cmp.Or(errors.Join(errs...), errors.New("no scanners configured"))
+	}
+	return r, errors.Join(errs...)
+}
+
+// NewRunner creates a runner for scanners, blocking findings at or above threshold.
+func NewRunner(threshold string, scanners ...Scanner) *Runner {
+	return &Runner{scanners: scanners, threshold: cmp.Or(threshold, defaultThreshold), timeout: defaultTimeout}
+}
+
+// Threshold returns the severity at and above which findings block a deliverable.
+func (r *Runner) Threshold() string {
+	return r.threshold
+}
+
+// Scan runs every scanner over the files and returns their findings, most
+// severe first. Findings of scanners that failed are missing; the failures
+// are returned joined.
+func (r *Runner) Scan(ctx context.Context, files []types.FileArtifact) ([]types.SecurityFinding, error) {
+	// Binary files such as generated images hold no code to scan
+	files = slices.DeleteFunc(slices.Clone(files), types.FileArtifact.Binary)
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	dir, err := os.MkdirTemp(r.dir, "buildbureau-scan-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scan directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := writeFiles(dir, files); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var (
+		findings []types.SecurityFinding
+		errs     []error
+	)
+	for _, s := range r.scanners {
+		found, err := s.Scan(ctx, dir)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.Name(), err))
+		}
+		for _, f := range found {
+			f.Tool = s.Name()
+			f.File = relative(dir, f.File)
+			findings = append(findings, f)
+		}
+	}
+
+	slices.SortStableFunc(findings, func(a, b types.SecurityFinding) int {
+		return cmp.Or(Rank(b.Severity)-Rank(a.Severity), strings.Compare(a.File, b.File), a.Line-b.Line)
+	})
+	return findings, errors.Join(errs...)
+}
+
+// Blocking returns the findings at or above the threshold.
+func (r *Runner) Blocking(findings []types.SecurityFinding) []types.SecurityFinding {
+	var blocking []types.SecurityFinding
+	for _, f := range findings {
+		if Rank(f.Severity) >= Rank(r.threshold) {
+			blocking = append(blocking, f)
+		}
+	}
+	return blocking
+}
+
+// Summary describes the findings for a report: how many there are and how
+// many block the deliverable, followed by one line per finding.
+func (r *Runner) Summary(findings []types.SecurityFinding) string {
+	if len(findings) == 0 {
+		return "No security findings."
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d security finding(s), %d at or above %s severity:", len(findings), len(r.Blocking(findings)), r.threshold)
+	for _, f := range findings {
+		b.WriteString("\n- " + f.String())
+	}
+	return b.String()
+}
+
+// writeFiles writes the files under dir. Paths that would leave dir are
+// rejected. Go files without a go.mod get one so gosec can load them.
+func writeFiles(dir string, files []types.FileArtifact) error {
+	hasGo, hasModule := false, false
+	for _, f := range files {
+		if !filepath.IsLocal(f.Path) {
+			return fmt.Errorf("refusing to scan file outside the deliverable: %s", f.Path)
+		}
+		path := filepath.Join(dir, f.Path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return fmt.Errorf("failed to write %s for scanning: %w", f.Path, err)
+		}
+		if err := os.WriteFile(path, []byte(f.Content), 0o644); err != nil {
+			return fmt.Errorf("failed to write %s for scanning: %w", f.Path, err)
+		}
+		hasGo = hasGo || strings.HasSuffix(f.Path, ".go")
+		hasModule = hasModule || filepath.Base(f.Path) == "go.mod"
+	}
+	if hasGo && !hasModule {
+		if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module deliverable\n"), 0o644); err != nil {
+			return fmt.Errorf("failed to write go.mod for scanning: %w", err)
+		}
+	}
+	return nil
+}
+
+// relative returns a scanner's file path relative to the scan directory.
+func relative(dir, path string) string {
+	if !filepath.IsAbs(path) {
+		return filepath.ToSlash(filepath.Clean(path))
+	}
+	// Scanners may report the directory with symlinks resolved, e.g. /private/var on macOS
+	for _, base := range []string{dir, evalSymlinks(dir)} {
+		if rel, err := filepath.Rel(base, path); err == nil && filepath.IsLocal(rel) {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return path
+}
+
+func evalSymlinks(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return path
+}
@@ -0,0 +1,83 @@
+package scan
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// cannedRunner returns canned scanner output, with the scan directory in
+// place of DIR, after checking the files were written there.
+func cannedRunner(t *testing.T, output string, err error) runner {
+	return func(ctx context.Context, dir, name string, args ...string) ([]byte, error) {
+		if _, statErr := os.Stat(filepath.Join(dir, "cmd", "main.go")); statErr != nil {
+			t.Errorf("Expected the deliverable to be written before %s runs: %v", name, statErr)
+		}
+		return []byte(strings.ReplaceAll(output, "DIR", dir)), err
+	}
+}
+
+func TestRunner(t *testing.T) {
+	ctx := context.Background()
+	files := []types.FileArtifact{{Path: "cmd/main.go", Content: "package main\n"}, {Path: "web/app.js", Content: "eval(input)\n"}}
+
+	t.Run("ScansWithEveryScanner", func(t *testing.T) {
+		gosec := &Gosec{Path: "gosec", run: cannedRunner(t, `{"Issues": [
+			{"severity": "HIGH", "rule_id": "G101", "details": "Potential hardcoded credentials", "file": "DIR/cmd/main.go", "line": "12"},
+			{"severity": "LOW", "rule_id": "G104", "details": "Errors unhandled", "file": "DIR/cmd/main.go", "line": "20-22"}
+		]}`, errors.New("exit status 1"))}
+		semgrep := &Semgrep{Path: "semgrep", run: cannedRunner(t, `{"results": [
+			{"check_id": "javascript.eval", "path": "web/app.js", "start": {"line": 1}, "extra": {"message": "eval of user input", "severity": "ERROR"}},
+			{"check_id": "javascript.debug", "path": "web/app.js", "start": {"line": 3}, "extra": {"message": "Debug output", "severity": "INFO"}}
+		]}`, nil)}
+		runner := NewRunner(SeverityHigh, gosec, semgrep)
+
+		findings, err := runner.Scan(ctx, files)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(findings) != 4 {
+			t.Fatalf("Expected 4 findings, got %+v", findings)
+		}
+		if f := findings[0]; f.Tool != "gosec" || f.File != "cmd/main.go" || f.Line != 12 || f.Severity != SeverityHigh {
+			t.Errorf("Expected the high findings first, with paths relative to the deliverable, got %+v", f)
+		}
+		if f := findings[2]; f.Rule != "G104" || f.Line != 20 {
+			t.Errorf("Expected multi-line findings to start at their first line, got %+v", f)
+		}
+
+		if blocking := runner.Blocking(findings); len(blocking) != 2 || blocking[1].Rule != "javascript.eval" {
+			t.Errorf("Expected the 2 high findings to block, got %+v", blocking)
+		}
+		if summary := runner.Summary(findings); !strings.HasPrefix(summary, "4 security finding(s), 2 at or above high severity") ||
+			!strings.Contains(summary, "[high] G101 cmd/main.go:12 (gosec): Potential hardcoded credentials") {
+			t.Errorf("Unexpected summary: %s", summary)
+		}
+	})
+
+	t.Run("ScannerFailure", func(t *testing.T) {
+		broken := &Semgrep{Path: "semgrep", run: cannedRunner(t, "", errors.New("semgrep: command not found"))}
+		findings, err := NewRunner("", broken).Scan(ctx, files)
+		if err == nil || !strings.Contains(err.Error(), "command not found") || len(findings) != 0 {
+			t.Errorf("Expected the scanner's failure, got %v and %+v", err, findings)
+		}
+	})
+
+	t.Run("PathOutsideDeliverable", func(t *testing.T) {
+		_, err := NewRunner("", &Gosec{}).Scan(ctx, []types.FileArtifact{{Path: "../../etc/passwd"}})
+		if err == nil {
+			t.Error("Expected a file outside the deliverable to be refused")
+		}
+	})
+
+	t.Run("InvalidThreshold", func(t *testing.T) {
+		if _, err := New(&types.SecurityScanConfig{Enabled: true, Threshold: "severe"}); err == nil {
+			t.Error("Expected an unknown threshold to be rejected")
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package scan
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// defaultSemgrepRules is the registry ruleset used when none is configured.
+const defaultSemgrepRules = "p/default"
+
+// Semgrep scans code in any language semgrep supports.
+type Semgrep struct {
+	run   runner
+	Path  string   // semgrep binary
+	Rules []string // Rulesets passed as --config, such as p/golang or a rules file; defaults to p/default
+}
+
+// Name returns "semgrep".
+func (s *Semgrep) Name() string {
+	return "semgrep"
+}
+
+// semgrepReport is the part of semgrep's JSON output that is used.
+type semgrepReport struct {
+	Results []struct {
+		CheckID string `json:"check_id"`
+		Path    string `json:"path"`
+		Start   struct {
+			Line int `json:"line"`
+		} `json:"start"`
+		Extra struct {
+			Message  string `json:"message"`
+			Severity string `json:"severity"`
+		} `json:"extra"`
+	} `json:"results"`
+}
+
+// semgrepSeverities maps semgrep's rule severities to finding severities.
+var semgrepSeverities = map[string]string{
+	"ERROR":   SeverityHigh,
+	"WARNING": SeverityMedium,
+	"INFO":    SeverityLow,
+}
+
+// Scan runs semgrep over dir.
+func (s *Semgrep) Scan(ctx context.Context, dir string) ([]types.SecurityFinding, error) {
+	rules := s.Rules
+	if len(rules) == 0 {
+		rules = []string{defaultSemgrepRules}
+	}
+	args := []string{"scan", "--json", "--quiet", "--metrics=off"}
+	for _, rule := range rules {
+		args = append(args, "--config", rule)
+	}
+	out, runErr := s.run(ctx, dir, s.Path, append(args, ".")...)
+
+	var report semgrepReport
+	if err := json.Unmarshal(out, &report); err != nil {
+		if runErr != nil {
+			return nil, runErr
+		}
+		return nil, fmt.Errorf("failed to parse semgrep output: %w", err)
+	}
+
+	findings := make([]types.SecurityFinding, 0, len(report.Results))
+	for _, result := range report.Results {
+		severity, ok := semgrepSeverities[strings.ToUpper(result.Extra.Severity)]
+		if !ok {
+			// Newer rules use finding severities such as CRITICAL directly
+			severity = strings.ToLower(result.Extra.Severity)
+		}
+		findings = append(findings, types.SecurityFinding{
+			Rule:     result.CheckID,
+			Severity: severity,
+			File:     result.Path,
+			Line:     result.Start.Line,
+			Message:  result.Extra.Message,
+		})
+	}
+	return findings, nil
+}
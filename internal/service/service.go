@@ -0,0 +1,144 @@
+// Package service installs BuildBureau as a system service running
+// "buildbureau serve": a systemd unit on Linux, a launchd job on macOS and a
+// service of the Service Control Manager on Windows.
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// Name is the default name of the service.
+const Name = "buildbureau"
+
+// Options describe the service to install.
+type Options struct {
+	Name            string // Name of the service; defaults to Name
+	Executable      string // Absolute path of the buildbureau binary
+	Config          string // Absolute path of the configuration file
+	EnvironmentFile string // File of environment variables, such as API keys; optional
+	LogDir          string // Where launchd writes the output of the service
+	User            bool   // Install for the current user rather than system-wide
+}
+
+// name returns the name of the service.
+func (o Options) name() string {
+	if o.Name == "" {
+		return Name
+	}
+	return o.Name
+}
+
+// args returns the arguments the service runs the binary with.
+func (o Options) args() []string {
+	return []string{"serve", "-config", o.Config}
+}
+
+var unitTemplate = template.Must(template.New("unit").Parse(`[Unit]
+Description=BuildBureau multi-agent organization
+Documentation=https://github.com/kpango/BuildBureau
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+Type=simple
+ExecStart={{.ExecStart}}
+{{- if .EnvironmentFile}}
+EnvironmentFile=-{{.EnvironmentFile}}
+{{- end}}
+StateDirectory={{.Name}}
+LogsDirectory={{.Name}}
+CacheDirectory={{.Name}}
+WorkingDirectory=%S/{{.Name}}
+KillSignal=SIGTERM
+Restart=on-failure
+RestartSec=5s
+
+[Install]
+WantedBy={{if .User}}default.target{{else}}multi-user.target{{end}}
+`))
+
+// Unit returns the systemd unit of the service. systemd creates the state,
+// log and cache directories of the unit and passes them to BuildBureau in
+// STATE_DIRECTORY, LOGS_DIRECTORY and CACHE_DIRECTORY.
+func Unit(opts Options) (string, error) {
+	args := append([]string{opts.Executable}, opts.args()...)
+	for i, arg := range args {
+		args[i] = systemdQuote(arg)
+	}
+	var buf bytes.Buffer
+	err := unitTemplate.Execute(&buf, map[string]any{
+		"Name":            opts.name(),
+		"ExecStart":       strings.Join(args, " "),
+		"EnvironmentFile": opts.EnvironmentFile,
+		"User":            opts.User,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to render unit: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// systemdQuote quotes arg for a systemd command line if it needs quoting.
+func systemdQuote(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\"'\\$%;") {
+		return arg
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `$$`, `%`, `%%`)
+	return `"` + r.Replace(arg) + `"`
+}
+
+// Label returns the launchd label of the service.
+func Label(opts Options) string {
+	return "com.github.kpango." + opts.name()
+}
+
+var plistTemplate = template.Must(template.New("plist").Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>{{html .Label}}</string>
+	<key>ProgramArguments</key>
+	<array>
+{{- range .Args}}
+		<string>{{html .}}</string>
+{{- end}}
+	</array>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<dict>
+		<key>SuccessfulExit</key>
+		<false/>
+	</dict>
+	<key>ExitTimeOut</key>
+	<integer>60</integer>
+{{- if .LogDir}}
+	<key>StandardOutPath</key>
+	<string>{{html .LogDir}}/{{html .Name}}.log</string>
+	<key>StandardErrorPath</key>
+	<string>{{html .LogDir}}/{{html .Name}}.log</string>
+{{- end}}
+</dict>
+</plist>
+`))
+
+// Plist returns the launchd property list of the service. launchd does not
+// read environment files, so API keys are set with launchctl setenv or in
+// the configuration.
+func Plist(opts Options) (string, error) {
+	var buf bytes.Buffer
+	err := plistTemplate.Execute(&buf, map[string]any{
+		"Label":  Label(opts),
+		"Name":   opts.name(),
+		"Args":   append([]string{opts.Executable}, opts.args()...),
+		"LogDir": opts.LogDir,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to render plist: %w", err)
+	}
+	return buf.String(), nil
+}
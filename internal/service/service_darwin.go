@@ -0,0 +1,83 @@
+//go:build darwin
+
+package service
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+)
+
+// PlistPath returns where the launchd property list of the service is
+// installed: a daemon for the system, an agent for the user.
+func PlistPath(opts Options) (string, error) {
+	dir := "/Library/LaunchDaemons"
+	if opts.User {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, "Library", "LaunchAgents")
+	}
+	return filepath.Join(dir, Label(opts)+".plist"), nil
+}
+
+// Install writes the launchd property list of the service and loads it,
+// which starts it.
+func Install(opts Options) error {
+	plist, err := Plist(opts)
+	if err != nil {
+		return err
+	}
+	path, err := PlistPath(opts)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create launchd directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(plist), 0o644); err != nil {
+		return fmt.Errorf("failed to write property list: %w", err)
+	}
+	return launchctl("bootstrap", domain(opts), path)
+}
+
+// Uninstall unloads the service, stopping it, and removes its property
+// list. The state directories are kept.
+func Uninstall(opts Options) error {
+	path, err := PlistPath(opts)
+	if err != nil {
+		return err
+	}
+	if err := launchctl("bootout", domain(opts)+"/"+Label(opts)); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove property list: %w", err)
+	}
+	return nil
+}
+
+// domain returns the launchd domain of the service.
+func domain(opts Options) string {
+	if opts.User {
+		return "gui/" + strconv.Itoa(os.Getuid())
+	}
+	return "system"
+}
+
+// launchctl runs launchctl.
+func launchctl(args ...string) error {
+	if out, err := exec.Command("launchctl", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("launchctl %s failed: %w: %s", args[0], err, out)
+	}
+	return nil
+}
+
+// Run runs serve. launchd stops jobs with SIGTERM, which serve handles
+// itself, so stop is never closed.
+func Run(serve func(stop <-chan struct{}) int) int {
+	return serve(nil)
+}
@@ -0,0 +1,79 @@
+//go:build linux
+
+package service
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// UnitPath returns where the systemd unit of the service is installed.
+func UnitPath(opts Options) (string, error) {
+	dir := "/etc/systemd/system"
+	if opts.User {
+		config, err := os.UserConfigDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(config, "systemd", "user")
+	}
+	return filepath.Join(dir, opts.name()+".service"), nil
+}
+
+// Install writes the systemd unit of the service and enables it. It does
+// not start the service.
+func Install(opts Options) error {
+	unit, err := Unit(opts)
+	if err != nil {
+		return err
+	}
+	path, err := UnitPath(opts)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create unit directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(unit), 0o644); err != nil {
+		return fmt.Errorf("failed to write unit: %w", err)
+	}
+	if err := systemctl(opts, "daemon-reload"); err != nil {
+		return err
+	}
+	return systemctl(opts, "enable", opts.name()+".service")
+}
+
+// Uninstall stops and disables the service and removes its unit. The state
+// directories are kept.
+func Uninstall(opts Options) error {
+	path, err := UnitPath(opts)
+	if err != nil {
+		return err
+	}
+	if err := systemctl(opts, "disable", "--now", opts.name()+".service"); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove unit: %w", err)
+	}
+	return systemctl(opts, "daemon-reload")
+}
+
+// systemctl runs systemctl for the system or the user's service manager.
+func systemctl(opts Options, args ...string) error {
+	if opts.User {
+		args = append([]string{"--user"}, args...)
+	}
+	if out, err := exec.Command("systemctl", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("systemctl %s failed: %w: %s", args[len(args)-1], err, out)
+	}
+	return nil
+}
+
+// Run runs serve. Services of systemd are stopped with SIGTERM, which serve
+// handles itself, so stop is never closed.
+func Run(serve func(stop <-chan struct{}) int) int {
+	return serve(nil)
+}
@@ -0,0 +1,20 @@
+//go:build !linux && !darwin && !windows
+
+package service
+
+import "fmt"
+
+// Install is not implemented on this platform.
+func Install(opts Options) error {
+	return fmt.Errorf("installing a service is not supported on this platform")
+}
+
+// Uninstall is not implemented on this platform.
+func Uninstall(opts Options) error {
+	return fmt.Errorf("uninstalling a service is not supported on this platform")
+}
+
+// Run runs serve, which is never asked to stop but by signals.
+func Run(serve func(stop <-chan struct{}) int) int {
+	return serve(nil)
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnit(t *testing.T) {
+	t.Run("System", func(t *testing.T) {
+		unit, err := Unit(Options{Executable: "/usr/local/bin/buildbureau", Config: "/etc/buildbureau/config.yaml", EnvironmentFile: "/etc/buildbureau/env"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range []string{
+			"ExecStart=/usr/local/bin/buildbureau serve -config /etc/buildbureau/config.yaml",
+			"EnvironmentFile=-/etc/buildbureau/env",
+			"StateDirectory=buildbureau",
+			"LogsDirectory=buildbureau",
+			"CacheDirectory=buildbureau",
+			"WantedBy=multi-user.target",
+		} {
+			if !strings.Contains(unit, line+"\n") {
+				t.Errorf("Expected the unit to contain %q, got:\n%s", line, unit)
+			}
+		}
+	})
+
+	t.Run("User", func(t *testing.T) {
+		unit, err := Unit(Options{Name: "acme", Executable: "/opt/build bureau/buildbureau", Config: "/home/ada/config.yaml", User: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range []string{
+			`ExecStart="/opt/build bureau/buildbureau" serve -config /home/ada/config.yaml`,
+			"StateDirectory=acme",
+			"WantedBy=default.target",
+		} {
+			if !strings.Contains(unit, line+"\n") {
+				t.Errorf("Expected the unit to contain %q, got:\n%s", line, unit)
+			}
+		}
+		if strings.Contains(unit, "EnvironmentFile") {
+			t.Errorf("Expected no environment file, got:\n%s", unit)
+		}
+	})
+}
+
+func TestPlist(t *testing.T) {
+	plist, err := Plist(Options{Executable: "/usr/local/bin/buildbureau", Config: "/Users/ada/R&D/config.yaml", LogDir: "/Library/Logs/BuildBureau"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"<string>com.github.kpango.buildbureau</string>",
+		"<string>/Users/ada/R&amp;D/config.yaml</string>",
+		"<string>/Library/Logs/BuildBureau/buildbureau.log</string>",
+	} {
+		if !strings.Contains(plist, want) {
+			t.Errorf("Expected the property list to contain %q, got:\n%s", want, plist)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+//go:build windows
+
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// Install registers the service with the Service Control Manager, started
+// automatically at boot under the LocalSystem account. It does not start
+// the service.
+func Install(opts Options) error {
+	if opts.User {
+		return fmt.Errorf("per-user services are not supported on Windows: install without -user")
+	}
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("failed to connect to the service manager: %w", err)
+	}
+	defer m.Disconnect()
+
+	s, err := m.CreateService(opts.name(), opts.Executable, mgr.Config{
+		DisplayName: "BuildBureau",
+		Description: "BuildBureau multi-agent organization",
+		StartType:   mgr.StartAutomatic,
+	}, opts.args()...)
+	if err != nil {
+		return fmt.Errorf("failed to create service %s: %w", opts.name(), err)
+	}
+	defer s.Close()
+	return s.SetRecoveryActions([]mgr.RecoveryAction{{Type: mgr.ServiceRestart, Delay: 5 * time.Second}}, 0)
+}
+
+// Uninstall stops the service and removes it from the Service Control
+// Manager. The state directories are kept.
+func Uninstall(opts Options) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("failed to connect to the service manager: %w", err)
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(opts.name())
+	if err != nil {
+		return fmt.Errorf("failed to open service %s: %w", opts.name(), err)
+	}
+	defer s.Close()
+	if _, err := s.Control(svc.Stop); err != nil && !errors.Is(err, windows.ERROR_SERVICE_NOT_ACTIVE) {
+		fmt.Printf("Warning: failed to stop service %s: %v\n", opts.name(), err)
+	}
+	if err := s.Delete(); err != nil {
+		return fmt.Errorf("failed to delete service %s: %w", opts.name(), err)
+	}
+	return nil
+}
+
+// Run runs serve, as a service if the Service Control Manager started the
+// process: stop is closed when the manager stops the service or the system
+// shuts down.
+func Run(serve func(stop <-chan struct{}) int) int {
+	isService, err := svc.IsWindowsService()
+	if err != nil || !isService {
+		return serve(nil)
+	}
+	h := &handler{serve: serve}
+	if err := svc.Run(Name, h); err != nil {
+		fmt.Printf("Warning: service failed: %v\n", err)
+		return 1
+	}
+	return h.code
+}
+
+// handler runs serve for the Service Control Manager.
+type handler struct {
+	serve func(stop <-chan struct{}) int
+	code  int
+}
+
+// Execute runs serve until it returns, closing its stop channel when the
+// service is asked to stop.
+func (h *handler) Execute(_ []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	const accepted = svc.AcceptStop | svc.AcceptShutdown
+	status <- svc.Status{State: svc.StartPending}
+
+	stop := make(chan struct{})
+	done := make(chan int, 1)
+	go func() { done <- h.serve(stop) }()
+	status <- svc.Status{State: svc.Running, Accepts: accepted}
+
+	for {
+		select {
+		case h.code = <-done:
+			status <- svc.Status{State: svc.StopPending}
+			return false, uint32(h.code) //nolint:gosec // Exit codes are small
+		case req := <-requests:
+			switch req.Cmd {
+			case svc.Interrogate:
+				status <- req.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending}
+				if stop != nil {
+					close(stop)
+					stop = nil
+				}
+			}
+		}
+	}
+}
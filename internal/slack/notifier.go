@@ -10,6 +10,10 @@ import (
 	"github.com/slack-go/slack"
 )
 
+// TypeError is the notification type of errors users need to act on, such as
+// an exhausted provider quota.
+const TypeError = "error"
+
 // Notifier handles Slack notifications with real API integration.
 type Notifier struct {
 	config  *types.SlackConfig
@@ -91,5 +95,5 @@ func (n *Notifier) NotifyTaskCompleted(ctx context.Context, taskID string, statu
 func (n *Notifier) NotifyError(ctx context.Context, taskID string, err error) error {
 	message := fmt.Sprintf("❌ Error in task `%s`: %v at %s",
 		taskID, err, time.Now().Format(time.RFC3339))
-	return n.Notify(ctx, "error", message)
+	return n.Notify(ctx, TypeError, message)
 }
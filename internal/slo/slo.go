@@ -0,0 +1,269 @@
+// Package slo tracks service level objectives on the tasks agents handle,
+// such as the 95th percentile latency of engineer tasks or the share of
+// tasks that fail. Objectives are evaluated over a rolling window of recent
+// tasks, and an alert fires when one is breached and again when it recovers.
+package slo
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// Metrics an objective can be set on.
+const (
+	MetricLatency   = "latency"
+	MetricErrorRate = "error_rate"
+)
+
+// Notification types sent when an objective is breached and when it recovers.
+const (
+	TypeBreached  = "slo_breached"
+	TypeRecovered = "slo_recovered"
+)
+
+const (
+	defaultWindow     = time.Hour
+	defaultMinSamples = 10
+	defaultPercentile = 95
+)
+
+// Status is the current state of an objective.
+type Status struct {
+	Name      string `json:"name"`
+	Role      string `json:"role,omitempty"`
+	Metric    string `json:"metric"`
+	Objective string `json:"objective"` // e.g. "p95 < 5m0s"
+	Current   string `json:"current"`   // Empty until enough tasks are in the window
+	// Value and Target are in seconds for latency and a fraction for error rate
+	Value    float64 `json:"value"`
+	Target   float64 `json:"target"`
+	Samples  int     `json:"samples"`
+	Breached bool    `json:"breached"`
+}
+
+// String describes the status for alerts and logs.
+func (s Status) String() string {
+	current := s.Current
+	if current == "" {
+		current = "not enough tasks"
+	}
+	return fmt.Sprintf("%s: %s (objective %s, %d tasks)", s.Name, current, s.Objective, s.Samples)
+}
+
+// sample is one finished task.
+type sample struct {
+	at      time.Time
+	role    string
+	latency time.Duration
+	failed  bool
+}
+
+// objective is a configured objective and whether it was last seen breached.
+type objective struct {
+	types.SLOObjective
+	breached bool
+}
+
+// Tracker evaluates objectives over the tasks finished in the window. A nil
+// *Tracker records nothing.
+type Tracker struct {
+	now        func() time.Time
+	alert      func(Status)
+	samples    []sample
+	objectives []*objective
+	window     time.Duration
+	minSamples int
+	mu         sync.Mutex
+}
+
+// Option configures a Tracker.
+type Option func(*Tracker)
+
+// WithAlert calls fn when an objective is breached and when it recovers. It is
+// called without the tracker's lock held, on the goroutine recording the task.
+func WithAlert(fn func(Status)) Option {
+	return func(t *Tracker) {
+		t.alert = fn
+	}
+}
+
+// New parses an SLO configuration. A nil or disabled configuration returns
+// nil, which records nothing.
+func New(config *types.SLOConfig, opts ...Option) (*Tracker, error) {
+	if config == nil || !config.Enabled {
+		return nil, nil
+	}
+
+	t := &Tracker{
+		now:        time.Now,
+		window:     defaultWindow,
+		minSamples: defaultMinSamples,
+	}
+	if config.Window > 0 {
+		t.window = config.Window
+	}
+	if config.MinSamples > 0 {
+		t.minSamples = config.MinSamples
+	}
+
+	for i, o := range config.Objectives {
+		switch o.Metric {
+		case MetricLatency:
+			if o.Percentile == 0 {
+				o.Percentile = defaultPercentile
+			}
+			if o.Percentile < 0 || o.Percentile > 100 {
+				return nil, fmt.Errorf("invalid SLO %d: percentile %v must be between 0 and 100", i+1, o.Percentile)
+			}
+			if o.MaxLatency <= 0 {
+				return nil, fmt.Errorf("invalid SLO %d: latency objectives need max_latency", i+1)
+			}
+		case MetricErrorRate:
+			if o.MaxErrorRate <= 0 || o.MaxErrorRate >= 1 {
+				return nil, fmt.Errorf("invalid SLO %d: max_error_rate %v must be a fraction between 0 and 1", i+1, o.MaxErrorRate)
+			}
+		default:
+			return nil, fmt.Errorf("invalid SLO %d: metric %q must be %s or %s", i+1, o.Metric, MetricLatency, MetricErrorRate)
+		}
+		if o.Name == "" {
+			o.Name = defaultName(o)
+		}
+		t.objectives = append(t.objectives, &objective{SLOObjective: o})
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t, nil
+}
+
+// defaultName names an objective after what it measures, e.g. "engineer p95 latency".
+func defaultName(o types.SLOObjective) string {
+	name := "error rate"
+	if o.Metric == MetricLatency {
+		name = fmt.Sprintf("p%g latency", o.Percentile)
+	}
+	if o.Role != "" {
+		name = strings.ToLower(o.Role) + " " + name
+	}
+	return name
+}
+
+// Record adds a finished task by an agent in role and alerts on objectives
+// it breaches or brings back within target.
+func (t *Tracker) Record(role string, latency time.Duration, failed bool) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	now := t.now()
+	t.samples = append(t.samples, sample{at: now, role: role, latency: latency, failed: failed})
+	t.prune(now)
+
+	var changed []Status
+	for _, o := range t.objectives {
+		if !matches(o, role) {
+			continue
+		}
+		status := t.evaluate(o)
+		if status.Current == "" || status.Breached == o.breached {
+			continue
+		}
+		o.breached = status.Breached
+		changed = append(changed, status)
+	}
+	t.mu.Unlock()
+
+	if t.alert != nil {
+		for _, status := range changed {
+			t.alert(status)
+		}
+	}
+}
+
+// Status returns the current state of every objective, in configuration order.
+func (t *Tracker) Status() []Status {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.prune(t.now())
+
+	statuses := make([]Status, len(t.objectives))
+	for i, o := range t.objectives {
+		statuses[i] = t.evaluate(o)
+	}
+	return statuses
+}
+
+// prune drops samples that have left the window. Samples are kept in the
+// order they were recorded, so the oldest come first.
+func (t *Tracker) prune(now time.Time) {
+	cutoff := now.Add(-t.window)
+	i := 0
+	for i < len(t.samples) && t.samples[i].at.Before(cutoff) {
+		i++
+	}
+	t.samples = slices.Delete(t.samples, 0, i)
+}
+
+// matches reports whether tasks by an agent in role count toward o.
+func matches(o *objective, role string) bool {
+	return o.Role == "" || strings.EqualFold(o.Role, role)
+}
+
+// evaluate measures o over the samples in the window. Objectives with fewer
+// than the minimum number of tasks are reported without a value.
+func (t *Tracker) evaluate(o *objective) Status {
+	status := Status{Name: o.Name, Role: o.Role, Metric: o.Metric}
+
+	var (
+		latencies []time.Duration
+		failures  int
+	)
+	for _, s := range t.samples {
+		if !matches(o, s.role) {
+			continue
+		}
+		latencies = append(latencies, s.latency)
+		if s.failed {
+			failures++
+		}
+	}
+	status.Samples = len(latencies)
+
+	switch o.Metric {
+	case MetricLatency:
+		status.Objective = fmt.Sprintf("p%g < %s", o.Percentile, o.MaxLatency)
+		status.Target = o.MaxLatency.Seconds()
+		if status.Samples < t.minSamples {
+			return status
+		}
+		slices.Sort(latencies)
+		// Nearest rank: the smallest latency at least this share of tasks finished within
+		rank := int(math.Ceil(o.Percentile / 100 * float64(len(latencies))))
+		value := latencies[max(rank-1, 0)]
+		status.Value = value.Seconds()
+		status.Current = fmt.Sprintf("p%g %s", o.Percentile, value.Round(time.Millisecond))
+		status.Breached = value >= o.MaxLatency
+	case MetricErrorRate:
+		status.Objective = fmt.Sprintf("error rate < %g%%", o.MaxErrorRate*100)
+		status.Target = o.MaxErrorRate
+		if status.Samples < t.minSamples {
+			return status
+		}
+		status.Value = float64(failures) / float64(status.Samples)
+		status.Current = fmt.Sprintf("error rate %.1f%%", status.Value*100)
+		status.Breached = status.Value >= o.MaxErrorRate
+	}
+	return status
+}
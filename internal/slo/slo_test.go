@@ -0,0 +1,97 @@
+package slo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+func TestTracker(t *testing.T) {
+	t.Run("Disabled", func(t *testing.T) {
+		tracker, err := New(&types.SLOConfig{Objectives: []types.SLOObjective{{Metric: MetricErrorRate, MaxErrorRate: 0.02}}})
+		if err != nil || tracker != nil {
+			t.Fatalf("Expected no tracker for a disabled configuration, got %v, %v", tracker, err)
+		}
+		tracker.Record("Engineer", time.Second, true) // A nil tracker records nothing
+		if tracker.Status() != nil {
+			t.Error("Expected no status from a nil tracker")
+		}
+	})
+
+	t.Run("InvalidObjective", func(t *testing.T) {
+		for _, o := range []types.SLOObjective{
+			{Metric: "throughput"},
+			{Metric: MetricLatency},
+			{Metric: MetricLatency, MaxLatency: time.Minute, Percentile: 120},
+			{Metric: MetricErrorRate, MaxErrorRate: 2},
+		} {
+			if _, err := New(&types.SLOConfig{Enabled: true, Objectives: []types.SLOObjective{o}}); err == nil {
+				t.Errorf("Expected %+v to be rejected", o)
+			}
+		}
+	})
+
+	t.Run("LatencyBreachAndRecovery", func(t *testing.T) {
+		var alerts []Status
+		tracker, err := New(&types.SLOConfig{
+			Enabled:    true,
+			MinSamples: 4,
+			Window:     time.Hour,
+			Objectives: []types.SLOObjective{{Role: "engineer", Metric: MetricLatency, MaxLatency: 5 * time.Minute}},
+		}, WithAlert(func(s Status) { alerts = append(alerts, s) }))
+		if err != nil {
+			t.Fatal(err)
+		}
+		now := time.Date(2025, 3, 3, 12, 0, 0, 0, time.UTC)
+		tracker.now = func() time.Time { return now }
+
+		tracker.Record("Manager", time.Hour, false) // Other roles do not count
+		for range 3 {
+			tracker.Record("Engineer", time.Minute, false)
+		}
+		if status := tracker.Status()[0]; status.Current != "" || status.Samples != 3 {
+			t.Errorf("Expected the objective to wait for enough tasks, got %+v", status)
+		}
+
+		tracker.Record("Engineer", 10*time.Minute, false)
+		if len(alerts) != 1 || !alerts[0].Breached || alerts[0].Name != "engineer p95 latency" {
+			t.Fatalf("Expected a breach alert, got %+v", alerts)
+		}
+		if status := tracker.Status()[0]; status.Value != 600 || status.Target != 300 {
+			t.Errorf("Expected p95 of 10m against 5m, got %+v", status)
+		}
+
+		// Once the slow task leaves the window, fast tasks bring the objective back
+		now = now.Add(59 * time.Minute)
+		tracker.Record("Engineer", time.Minute, false)
+		if len(alerts) != 1 {
+			t.Fatalf("Expected no alert while the slow task is in the window, got %+v", alerts)
+		}
+		now = now.Add(2 * time.Minute)
+		for range 4 {
+			tracker.Record("Engineer", time.Minute, false)
+		}
+		if len(alerts) != 2 || alerts[1].Breached {
+			t.Errorf("Expected a recovery alert, got %+v", alerts)
+		}
+	})
+
+	t.Run("ErrorRate", func(t *testing.T) {
+		tracker, err := New(&types.SLOConfig{
+			Enabled:    true,
+			MinSamples: 1,
+			Objectives: []types.SLOObjective{{Name: "failures", Metric: MetricErrorRate, MaxErrorRate: 0.25}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, failed := range []bool{false, true, false, false, false} {
+			tracker.Record("Director", time.Second, failed)
+		}
+		status := tracker.Status()[0]
+		if status.Breached || status.Value != 0.2 || status.Current != "error rate 20.0%" {
+			t.Errorf("Expected a 20%% error rate within objective, got %+v", status)
+		}
+	})
+}
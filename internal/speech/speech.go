@@ -0,0 +1,176 @@
+// Package speech renders text as audio with OpenAI text-to-speech, so
+// project digests can be listened to by people who use screen readers or
+// who take their standup updates as audio.
+package speech
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// MaxInput is the most characters one rendering may read, the limit of the
+// OpenAI speech API. Longer text is cut at the last sentence that fits.
+const MaxInput = 4096
+
+const (
+	defaultModel  = openai.TTSModel1
+	defaultVoice  = openai.VoiceAlloy
+	defaultFormat = openai.SpeechResponseFormatMp3
+	defaultDir    = "digests"
+)
+
+// mediaTypes are the media types of the supported audio formats.
+var mediaTypes = map[string]string{
+	"mp3":  "audio/mpeg",
+	"opus": "audio/ogg",
+	"aac":  "audio/aac",
+	"flac": "audio/flac",
+	"wav":  "audio/wav",
+}
+
+// Backend synthesizes speech.
+type Backend interface {
+	Synthesize(ctx context.Context, text string) ([]byte, error)
+}
+
+// Speaker renders text as audio files.
+type Speaker struct {
+	backend Backend
+	dir     string
+	format  string
+}
+
+// Option configures a Speaker.
+type Option func(*Speaker)
+
+// WithBackend replaces the backend speech is synthesized with.
+func WithBackend(backend Backend) Option {
+	return func(s *Speaker) {
+		s.backend = backend
+	}
+}
+
+// New creates a speaker for a speech configuration, authenticating with
+// apiKey. A nil or disabled configuration returns nil, which renders nothing.
+func New(config *types.SpeechConfig, apiKey string, opts ...Option) (*Speaker, error) {
+	if config == nil || !config.Enabled {
+		return nil, nil
+	}
+
+	format := cmp.Or(config.Format, string(defaultFormat))
+	if _, ok := mediaTypes[format]; !ok {
+		return nil, fmt.Errorf("unsupported audio format %q: must be one of %s", format, strings.Join(slices.Sorted(maps.Keys(mediaTypes)), ", "))
+	}
+
+	s := &Speaker{dir: cmp.Or(config.Dir, defaultDir), format: format}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.backend != nil {
+		return s, nil
+	}
+
+	if apiKey == "" {
+		return nil, errors.New("text-to-speech needs an OpenAI API key")
+	}
+	s.backend = NewOpenAI(openai.NewClient(apiKey), config.Model, config.Voice, format)
+	return s, nil
+}
+
+// Render reads text aloud and writes the audio to name, with the format's
+// extension, under the configured directory. It returns the audio as a file
+// artifact, its content base64-encoded, and the path it was written to.
+func (s *Speaker) Render(ctx context.Context, name, text string) (types.FileArtifact, string, error) {
+	path := name + "." + s.format
+	if !filepath.IsLocal(path) {
+		return types.FileArtifact{}, "", fmt.Errorf("refusing to write audio outside %s: %s", s.dir, path)
+	}
+
+	data, err := s.backend.Synthesize(ctx, Clip(text))
+	if err != nil {
+		return types.FileArtifact{}, "", fmt.Errorf("failed to synthesize speech: %w", err)
+	}
+
+	stored := filepath.Join(s.dir, path)
+	if err := os.MkdirAll(filepath.Dir(stored), 0o755); err != nil {
+		return types.FileArtifact{}, "", fmt.Errorf("failed to store %s: %w", path, err)
+	}
+	if err := os.WriteFile(stored, data, 0o644); err != nil {
+		return types.FileArtifact{}, "", fmt.Errorf("failed to store %s: %w", path, err)
+	}
+
+	return types.FileArtifact{
+		Path:      filepath.ToSlash(path),
+		Content:   base64.StdEncoding.EncodeToString(data),
+		Bytes:     len(data),
+		MediaType: mediaTypes[s.format],
+	}, stored, nil
+}
+
+// Clip cuts text to MaxInput characters, at the end of a sentence if one fits.
+func Clip(text string) string {
+	runes := []rune(text)
+	if len(runes) <= MaxInput {
+		return text
+	}
+	clipped := string(runes[:MaxInput])
+	if i := strings.LastIndex(clipped, ". "); i > 0 {
+		return clipped[:i+1]
+	}
+	return clipped
+}
+
+// OpenAI synthesizes speech with the OpenAI speech API.
+type OpenAI struct {
+	client *openai.Client
+	model  openai.SpeechModel
+	voice  openai.SpeechVoice
+	format openai.SpeechResponseFormat
+}
+
+// NewOpenAI creates an OpenAI backend. Empty model, voice and format default
+// to tts-1, alloy and mp3.
+func NewOpenAI(client *openai.Client, model, voice, format string) *OpenAI {
+	return &OpenAI{
+		client: client,
+		model:  openai.SpeechModel(cmp.Or(model, string(defaultModel))),
+		voice:  openai.SpeechVoice(cmp.Or(voice, string(defaultVoice))),
+		format: openai.SpeechResponseFormat(cmp.Or(format, string(defaultFormat))),
+	}
+}
+
+// Synthesize implements Backend.
+func (b *OpenAI) Synthesize(ctx context.Context, text string) ([]byte, error) {
+	resp, err := b.client.CreateSpeech(ctx, openai.CreateSpeechRequest{
+		Model:          b.model,
+		Input:          text,
+		Voice:          b.voice,
+		ResponseFormat: b.format,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Close()
+
+	data, err := io.ReadAll(resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audio: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, errors.New("no audio returned")
+	}
+	return data, nil
+}
@@ -0,0 +1,101 @@
+package speech
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// fakeBackend returns the text it was given as the audio.
+type fakeBackend struct{}
+
+func (fakeBackend) Synthesize(ctx context.Context, text string) ([]byte, error) {
+	return []byte(text), nil
+}
+
+func TestNew(t *testing.T) {
+	if s, err := New(&types.SpeechConfig{}, "key"); s != nil || err != nil {
+		t.Errorf("New(disabled) = %v, %v, want nil", s, err)
+	}
+	if _, err := New(&types.SpeechConfig{Enabled: true}, ""); err == nil {
+		t.Error("Expected an error without an API key")
+	}
+	if _, err := New(&types.SpeechConfig{Enabled: true, Format: "ogg"}, "key"); err == nil {
+		t.Error("Expected an error for an unsupported format")
+	}
+}
+
+func TestRender(t *testing.T) {
+	dir := t.TempDir()
+	s, err := New(&types.SpeechConfig{Enabled: true, Dir: dir, Format: "wav"}, "", WithBackend(fakeBackend{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, stored, err := s.Render(context.Background(), "run-1", "The project is complete.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored != filepath.Join(dir, "run-1.wav") {
+		t.Errorf("Stored at %s", stored)
+	}
+	if data, err := os.ReadFile(stored); err != nil || string(data) != "The project is complete." {
+		t.Errorf("Expected the audio at %s, got %q, %v", stored, data, err)
+	}
+	if file.Path != "run-1.wav" || file.MediaType != "audio/wav" || file.Content != base64.StdEncoding.EncodeToString([]byte("The project is complete.")) {
+		t.Errorf("Unexpected artifact %+v", file)
+	}
+
+	if _, _, err := s.Render(context.Background(), "../run-1", "text"); err == nil {
+		t.Error("Expected a name outside the directory to be rejected")
+	}
+}
+
+func TestClip(t *testing.T) {
+	short := "One sentence."
+	if got := Clip(short); got != short {
+		t.Errorf("Clip(%q) = %q", short, got)
+	}
+
+	long := strings.Repeat("A sentence of words. ", 300)
+	got := Clip(long)
+	if len([]rune(got)) > MaxInput || !strings.HasSuffix(got, "words.") {
+		t.Errorf("Expected text cut at a sentence within %d characters, got %d ending %q", MaxInput, len(got), got[len(got)-10:])
+	}
+}
+
+func TestOpenAI(t *testing.T) {
+	var req openai.CreateSpeechRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.Write([]byte("ID3 audio"))
+	}))
+	defer server.Close()
+
+	cfg := openai.DefaultConfig("key")
+	cfg.BaseURL = server.URL
+	data, err := NewOpenAI(openai.NewClientWithConfig(cfg), "", "nova", "").Synthesize(context.Background(), "Hello team")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "ID3 audio" {
+		t.Errorf("Audio = %q", data)
+	}
+	if req.Input != "Hello team" || req.Model != openai.TTSModel1 || req.Voice != openai.VoiceNova || req.ResponseFormat != openai.SpeechResponseFormatMp3 {
+		t.Errorf("Unexpected request %+v", req)
+	}
+}
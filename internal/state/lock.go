@@ -0,0 +1,77 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// LockFile is the file in the work directory held by the instance using it,
+// recording the instance's process ID.
+const LockFile = "buildbureau.lock"
+
+// ErrLocked is returned when another instance is using the work directory.
+var ErrLocked = errors.New("state directory is in use by another instance")
+
+// InstanceLock is held by the instance using a work directory. The operating
+// system releases it if the process dies, so a crash leaves no stale lock.
+type InstanceLock struct {
+	file *os.File
+	path string
+}
+
+// Lock takes the lock of the work directory dir, failing with ErrLocked if
+// another instance holds it.
+func Lock(dir string) (*InstanceLock, error) {
+	path := filepath.Join(dir, LockFile)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		pid := "unknown"
+		if data, readErr := os.ReadFile(path); readErr == nil && len(strings.TrimSpace(string(data))) > 0 {
+			pid = strings.TrimSpace(string(data))
+		}
+		return nil, fmt.Errorf("%w: %s is held by pid %s", ErrLocked, dir, pid)
+	}
+
+	if err := f.Truncate(0); err == nil {
+		_, err = f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	if err != nil {
+		_ = release(f, path)
+		return nil, fmt.Errorf("failed to write lock file: %w", err)
+	}
+	return &InstanceLock{file: f, path: path}, nil
+}
+
+// Release gives up the lock and removes the lock file.
+func (l *InstanceLock) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	err := release(l.file, l.path)
+	l.file = nil
+	return err
+}
+
+// Acquire takes the lock of the work directory of cfg, or returns a nil
+// lock if state directories are not enabled. Releasing a nil lock does
+// nothing.
+func Acquire(cfg *types.Config) (*InstanceLock, error) {
+	if cfg.State == nil || !cfg.State.Enabled {
+		return nil, nil
+	}
+	dirs, err := Resolve(cfg.State)
+	if err != nil {
+		return nil, err
+	}
+	return Lock(dirs.Work)
+}
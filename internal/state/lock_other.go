@@ -0,0 +1,20 @@
+//go:build !linux && !darwin && !windows
+
+package state
+
+import "os"
+
+// lockFile does nothing: file locks are not supported on this platform, so
+// the lock file only records the process ID.
+func lockFile(f *os.File) error {
+	return nil
+}
+
+// release removes the lock file.
+func release(f *os.File, path string) error {
+	err := os.Remove(path)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,23 @@
+//go:build linux || darwin
+
+package state
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile takes an exclusive lock on f without waiting.
+func lockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+}
+
+// release removes the lock file while still holding the lock, so no other
+// instance can lock the file just before it is removed, then unlocks it.
+func release(f *os.File, path string) error {
+	err := os.Remove(path)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,25 @@
+//go:build windows
+
+package state
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockFile takes an exclusive lock on f without waiting.
+func lockFile(f *os.File) error {
+	return windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, &windows.Overlapped{})
+}
+
+// release unlocks f and removes the lock file. Windows cannot remove a file
+// that is open, so it is closed first; if another instance opens it in
+// between, the file stays and that instance keeps its lock.
+func release(f *os.File, path string) error {
+	if err := f.Close(); err != nil {
+		return err
+	}
+	_ = os.Remove(path)
+	return nil
+}
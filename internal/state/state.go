@@ -0,0 +1,141 @@
+// Package state places the files BuildBureau keeps, such as the memory
+// database and activity logs, in platform state directories, and makes sure
+// only one instance at a time uses them.
+package state
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/kpango/BuildBureau/internal/agent"
+	"github.com/kpango/BuildBureau/internal/memory"
+	"github.com/kpango/BuildBureau/internal/outbox"
+	"github.com/kpango/BuildBureau/internal/profile"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// Dirs are the directories BuildBureau keeps its files in.
+type Dirs struct {
+	Work  string `json:"work"`
+	Log   string `json:"log"`
+	Cache string `json:"cache"`
+}
+
+// Resolve returns the directories of cfg: those it configures, then those
+// systemd sets up for the unit (STATE_DIRECTORY, LOGS_DIRECTORY and
+// CACHE_DIRECTORY), then the platform's defaults for a service or a user.
+func Resolve(cfg *types.StateConfig) (Dirs, error) {
+	home, err := os.UserHomeDir()
+	if err != nil && !cfg.System {
+		return Dirs{}, fmt.Errorf("failed to find the home directory: %w", err)
+	}
+	defaults := defaultDirs(runtime.GOOS, cfg.System, os.Getenv, home)
+	return Dirs{
+		Work:  cmp.Or(cfg.WorkDir, systemdDir(os.Getenv("STATE_DIRECTORY")), defaults.Work),
+		Log:   cmp.Or(cfg.LogDir, systemdDir(os.Getenv("LOGS_DIRECTORY")), defaults.Log),
+		Cache: cmp.Or(cfg.CacheDir, systemdDir(os.Getenv("CACHE_DIRECTORY")), defaults.Cache),
+	}, nil
+}
+
+// systemdDir returns the first of the colon-separated directories systemd
+// sets up for a unit, or "".
+func systemdDir(dirs string) string {
+	dir, _, _ := strings.Cut(dirs, ":")
+	return dir
+}
+
+// defaultDirs returns the platform's default directories: system-wide for a
+// service, otherwise the user's, following the XDG base directories on Unix.
+func defaultDirs(goos string, system bool, getenv func(string) string, home string) Dirs {
+	switch goos {
+	case "windows":
+		base := filepath.Join(cmp.Or(getenv("LOCALAPPDATA"), filepath.Join(home, "AppData", "Local")), "BuildBureau")
+		if system {
+			base = filepath.Join(cmp.Or(getenv("ProgramData"), `C:\ProgramData`), "BuildBureau")
+		}
+		return Dirs{Work: base, Log: filepath.Join(base, "logs"), Cache: filepath.Join(base, "cache")}
+	case "darwin":
+		library := filepath.Join(home, "Library")
+		if system {
+			library = "/Library"
+		}
+		return Dirs{
+			Work:  filepath.Join(library, "Application Support", "BuildBureau"),
+			Log:   filepath.Join(library, "Logs", "BuildBureau"),
+			Cache: filepath.Join(library, "Caches", "BuildBureau"),
+		}
+	default:
+		if system {
+			return Dirs{Work: "/var/lib/buildbureau", Log: "/var/log/buildbureau", Cache: "/var/cache/buildbureau"}
+		}
+		xdg := func(env, fallback string) string {
+			if dir := getenv(env); filepath.IsAbs(dir) {
+				return filepath.Join(dir, "buildbureau")
+			}
+			return filepath.Join(home, fallback, "buildbureau")
+		}
+		return Dirs{
+			Work:  xdg("XDG_DATA_HOME", filepath.Join(".local", "share")),
+			Log:   filepath.Join(xdg("XDG_STATE_HOME", filepath.Join(".local", "state")), "logs"),
+			Cache: xdg("XDG_CACHE_HOME", ".cache"),
+		}
+	}
+}
+
+// Apply creates the state directories of cfg and resolves the relative
+// paths of cfg against them, including defaults left unset: files kept
+// across runs against the work directory, activity logs to the log directory
+// and scan files to the cache directory. It returns the directories, or
+// zero Dirs if state directories are not enabled.
+func Apply(cfg *types.Config) (Dirs, error) {
+	if cfg.State == nil || !cfg.State.Enabled {
+		return Dirs{}, nil
+	}
+
+	dirs, err := Resolve(cfg.State)
+	if err != nil {
+		return Dirs{}, err
+	}
+	for _, dir := range []string{dirs.Work, dirs.Log, dirs.Cache} {
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			return Dirs{}, fmt.Errorf("failed to create state directory: %w", err)
+		}
+	}
+
+	work := func(path, fallback string) string {
+		path = cmp.Or(path, fallback)
+		if filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(dirs.Work, path)
+	}
+	if m := cfg.Memory; m != nil {
+		if !m.SQLite.InMemory && m.SQLite.Path != "" {
+			m.SQLite.Path = work(m.SQLite.Path, "")
+		}
+		m.Archive.Dir = work(m.Archive.Dir, memory.DefaultArchiveDir)
+	}
+	if cfg.Outbox != nil {
+		cfg.Outbox.Path = work(cfg.Outbox.Path, outbox.DefaultPath)
+	}
+	if cfg.Profiles != nil {
+		cfg.Profiles.Path = work(cfg.Profiles.Path, profile.DefaultPath)
+	}
+	if cfg.Reports != nil {
+		cfg.Reports.Dir = work(cfg.Reports.Dir, agent.DefaultReportDir)
+	}
+	if cfg.Workspace != nil {
+		cfg.Workspace.Dir = work(cfg.Workspace.Dir, agent.DefaultWorkspaceDir)
+	}
+	if cfg.Logging != nil && !filepath.IsAbs(cfg.Logging.Dir) {
+		cfg.Logging.Dir = dirs.Log
+	}
+	if cfg.SecurityScan != nil && cfg.SecurityScan.Dir == "" {
+		cfg.SecurityScan.Dir = dirs.Cache
+	}
+	return dirs, nil
+}
@@ -0,0 +1,188 @@
+package state
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+func TestDefaultDirs(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(key string) string { return vars[key] }
+	}
+
+	tests := []struct {
+		name   string
+		goos   string
+		getenv func(string) string
+		want   Dirs
+		system bool
+	}{
+		{
+			name:   "LinuxSystem",
+			goos:   "linux",
+			system: true,
+			getenv: env(nil),
+			want:   Dirs{Work: "/var/lib/buildbureau", Log: "/var/log/buildbureau", Cache: "/var/cache/buildbureau"},
+		},
+		{
+			name:   "LinuxUser",
+			goos:   "linux",
+			getenv: env(nil),
+			want: Dirs{
+				Work:  "/home/ada/.local/share/buildbureau",
+				Log:   "/home/ada/.local/state/buildbureau/logs",
+				Cache: "/home/ada/.cache/buildbureau",
+			},
+		},
+		{
+			name:   "LinuxXDG",
+			goos:   "linux",
+			getenv: env(map[string]string{"XDG_DATA_HOME": "/data", "XDG_STATE_HOME": "relative", "XDG_CACHE_HOME": "/cache"}),
+			want: Dirs{
+				Work:  "/data/buildbureau",
+				Log:   "/home/ada/.local/state/buildbureau/logs",
+				Cache: "/cache/buildbureau",
+			},
+		},
+		{
+			name:   "DarwinSystem",
+			goos:   "darwin",
+			system: true,
+			getenv: env(nil),
+			want: Dirs{
+				Work:  "/Library/Application Support/BuildBureau",
+				Log:   "/Library/Logs/BuildBureau",
+				Cache: "/Library/Caches/BuildBureau",
+			},
+		},
+		{
+			name:   "WindowsSystem",
+			goos:   "windows",
+			system: true,
+			getenv: env(map[string]string{"ProgramData": "/ProgramData"}),
+			want: Dirs{
+				Work:  "/ProgramData/BuildBureau",
+				Log:   "/ProgramData/BuildBureau/logs",
+				Cache: "/ProgramData/BuildBureau/cache",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := defaultDirs(tt.goos, tt.system, tt.getenv, "/home/ada"); got != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	t.Run("Disabled", func(t *testing.T) {
+		cfg := &types.Config{Outbox: &types.OutboxConfig{Path: "outbox.db"}}
+		if _, err := Apply(cfg); err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Outbox.Path != "outbox.db" {
+			t.Errorf("Expected paths to be left alone, got %s", cfg.Outbox.Path)
+		}
+	})
+
+	t.Run("Enabled", func(t *testing.T) {
+		dir := t.TempDir()
+		cfg := &types.Config{
+			State: &types.StateConfig{
+				WorkDir:  filepath.Join(dir, "work"),
+				LogDir:   filepath.Join(dir, "log"),
+				CacheDir: filepath.Join(dir, "cache"),
+				Enabled:  true,
+			},
+			Memory:       &types.MemoryConfig{SQLite: types.SQLiteConfig{Path: "./data/memory.db"}},
+			Outbox:       &types.OutboxConfig{Path: "/var/tmp/outbox.db"},
+			Reports:      &types.ReportsConfig{},
+			Logging:      &types.LoggingConfig{Dir: "logs"},
+			SecurityScan: &types.SecurityScanConfig{},
+		}
+		dirs, err := Apply(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, d := range []string{dirs.Work, dirs.Log, dirs.Cache} {
+			if _, err := os.Stat(d); err != nil {
+				t.Errorf("Expected %s to be created: %v", d, err)
+			}
+		}
+		for _, path := range []struct{ got, want string }{
+			{cfg.Memory.SQLite.Path, filepath.Join(dir, "work", "data", "memory.db")},
+			{cfg.Memory.Archive.Dir, filepath.Join(dir, "work", "data", "archive")},
+			{cfg.Outbox.Path, "/var/tmp/outbox.db"},
+			{cfg.Reports.Dir, filepath.Join(dir, "work", "reports")},
+			{cfg.Logging.Dir, filepath.Join(dir, "log")},
+			{cfg.SecurityScan.Dir, filepath.Join(dir, "cache")},
+		} {
+			if path.got != path.want {
+				t.Errorf("Expected %s, got %s", path.want, path.got)
+			}
+		}
+	})
+
+	t.Run("SystemdDirectories", func(t *testing.T) {
+		dir := t.TempDir()
+		t.Setenv("STATE_DIRECTORY", filepath.Join(dir, "state")+":"+filepath.Join(dir, "other"))
+		t.Setenv("LOGS_DIRECTORY", filepath.Join(dir, "logs"))
+		t.Setenv("CACHE_DIRECTORY", filepath.Join(dir, "cache"))
+		dirs, err := Apply(&types.Config{State: &types.StateConfig{Enabled: true}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := Dirs{Work: filepath.Join(dir, "state"), Log: filepath.Join(dir, "logs"), Cache: filepath.Join(dir, "cache")}
+		if dirs != want {
+			t.Errorf("Expected %+v, got %+v", want, dirs)
+		}
+	})
+}
+
+func TestLock(t *testing.T) {
+	dir := t.TempDir()
+	lock, err := Lock(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, LockFile))
+	if err != nil || strings.TrimSpace(string(data)) != strconv.Itoa(os.Getpid()) {
+		t.Errorf("Expected the lock file to hold the process ID, got %q (%v)", data, err)
+	}
+
+	t.Run("Held", func(t *testing.T) {
+		_, err := Lock(dir)
+		if !errors.Is(err, ErrLocked) || !strings.Contains(err.Error(), strconv.Itoa(os.Getpid())) {
+			t.Errorf("Expected a locked error naming the holder, got %v", err)
+		}
+	})
+
+	t.Run("Released", func(t *testing.T) {
+		if err := lock.Release(); err != nil {
+			t.Fatal(err)
+		}
+		if err := lock.Release(); err != nil {
+			t.Errorf("Expected releasing twice to do nothing, got %v", err)
+		}
+		again, err := Lock(dir)
+		if err != nil {
+			t.Fatalf("Expected the released lock to be taken again, got %v", err)
+		}
+		_ = again.Release()
+	})
+
+	t.Run("Nil", func(t *testing.T) {
+		var lock *InstanceLock
+		if err := lock.Release(); err != nil {
+			t.Errorf("Expected releasing a nil lock to do nothing, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package testsupport
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/kpango/BuildBureau/internal/llm"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// ProviderName is the name the recording provider is registered under, and
+// the model agents select it with.
+const ProviderName = "recording"
+
+// Call is a prompt sent to the recording provider.
+type Call struct {
+	SystemPrompt string
+	Prompt       string
+}
+
+// Provider is an llm.Provider that records the prompts it receives and
+// answers each with Output.
+type Provider struct {
+	Output string
+	calls  []Call
+	mu     sync.Mutex
+}
+
+// Generate records the prompt and returns Output.
+func (p *Provider) Generate(ctx context.Context, prompt string, opts *llm.GenerateOptions) (string, error) {
+	call := Call{Prompt: prompt}
+	if opts != nil {
+		call.SystemPrompt = opts.SystemPrompt
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls = append(p.calls, call)
+	return p.Output, nil
+}
+
+// Name returns ProviderName.
+func (p *Provider) Name() string {
+	return ProviderName
+}
+
+// Calls returns the prompts received so far, oldest first.
+func (p *Provider) Calls() []Call {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Call(nil), p.calls...)
+}
+
+// LLMManager returns an LLM manager whose default model is served by provider.
+func LLMManager(t testing.TB, provider *Provider) *llm.Manager {
+	t.Helper()
+	m, err := llm.NewManager(&types.LLMConfig{DefaultModel: ProviderName}, llm.WithProvider(ProviderName, provider))
+	if err != nil {
+		t.Fatalf("Failed to create LLM manager: %v", err)
+	}
+	return m
+}
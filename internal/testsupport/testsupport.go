@@ -0,0 +1,127 @@
+// Package testsupport provides factories for the configurations, tasks,
+// memories and organizations tests build, and golden files for comparing
+// generated text such as prompts.
+package testsupport
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/kpango/BuildBureau/internal/agent"
+	"github.com/kpango/BuildBureau/internal/llm"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// Epoch is the time memories created by the factories are dated.
+var Epoch = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Shape describes the layers of an organization. A count of 0 leaves its
+// layer out.
+type Shape struct {
+	Model       string             // Model of every agent; the default model if empty
+	Secretaries []string           // Layers a secretary is attached to, such as "President"
+	Teams       []types.TeamConfig // Replace the director, manager and engineer layers
+	Directors   int
+	Managers    int
+	Engineers   int
+	Client      bool
+}
+
+// Task returns a task with fixed IDs and text, changed by opts.
+func Task(opts ...func(*types.Task)) *types.Task {
+	task := &types.Task{
+		ID:          "task-1",
+		RunID:       "run-1",
+		Title:       "Build a URL shortener",
+		Description: "A service that shortens URLs and redirects short links to them",
+		Content:     "Expose a REST API to create short links and redirect them.",
+		FromAgent:   "client",
+		Priority:    1,
+	}
+	for _, opt := range opts {
+		opt(task)
+	}
+	return task
+}
+
+// MemoryEntry returns a memory of an agent created at Epoch.
+func MemoryEntry(agentID string, memType types.MemoryType, content string) *types.MemoryEntry {
+	return &types.MemoryEntry{
+		AgentID:   agentID,
+		RunID:     "run-1",
+		Type:      memType,
+		Content:   content,
+		CreatedAt: Epoch,
+		UpdatedAt: Epoch,
+	}
+}
+
+// AgentConfig returns the configuration of an agent of role, whose system
+// prompt names the role.
+func AgentConfig(role types.AgentRole, model string) *types.AgentConfig {
+	return &types.AgentConfig{
+		Name:         string(role),
+		Role:         string(role),
+		Model:        model,
+		SystemPrompt: "You are the " + string(role) + " of a software company.",
+	}
+}
+
+// Config returns a configuration for an organization of shape, writing the
+// configurations of its agents to a temporary directory.
+func Config(t testing.TB, shape Shape) *types.Config {
+	t.Helper()
+	dir := t.TempDir()
+	agentFile := func(role types.AgentRole) string {
+		data, err := yaml.Marshal(AgentConfig(role, shape.Model))
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, string(role)+".yaml")
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	layers := []types.LayerConfig{{Name: "President", Agent: agentFile(types.RolePresident)}}
+	if len(shape.Secretaries) > 0 {
+		layers = append(layers, types.LayerConfig{Name: "Secretary", Agent: agentFile(types.RoleSecretary), AttachTo: shape.Secretaries})
+	}
+	for _, layer := range []struct {
+		role  types.AgentRole
+		count int
+	}{
+		{types.RoleDirector, shape.Directors},
+		{types.RoleManager, shape.Managers},
+		{types.RoleEngineer, shape.Engineers},
+	} {
+		// Teams need the layers' agent configurations even without counts
+		if layer.count > 0 || len(shape.Teams) > 0 {
+			layers = append(layers, types.LayerConfig{Name: string(layer.role), Agent: agentFile(layer.role), Count: layer.count})
+		}
+	}
+	if shape.Client {
+		layers = append(layers, types.LayerConfig{Name: "Client", Agent: agentFile(types.RoleClient)})
+	}
+
+	return &types.Config{
+		Organization: types.OrganizationConfig{Layers: layers, Teams: shape.Teams},
+		LLMs:         types.LLMConfig{DefaultModel: shape.Model},
+	}
+}
+
+// Organization creates an organization of shape whose agents use llmManager.
+// It is not started.
+func Organization(t testing.TB, shape Shape, llmManager *llm.Manager) *agent.Organization {
+	t.Helper()
+	org, err := agent.NewOrganizationWithLLM(Config(t, shape), llmManager)
+	if err != nil {
+		t.Fatalf("Failed to create organization: %v", err)
+	}
+	return org
+}
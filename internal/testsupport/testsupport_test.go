@@ -0,0 +1,45 @@
+package testsupport
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+func TestOrganization(t *testing.T) {
+	shape := Shape{Model: ProviderName, Secretaries: []string{"President"}, Directors: 1, Managers: 2, Engineers: 3, Client: true}
+	org := Organization(t, shape, LLMManager(t, &Provider{}))
+
+	counts := make(map[types.AgentRole]int)
+	for _, info := range org.Agents() {
+		counts[info.Role]++
+		if info.Model != ProviderName {
+			t.Errorf("Expected %s to use the %s model, got %q", info.ID, ProviderName, info.Model)
+		}
+	}
+	want := map[types.AgentRole]int{
+		types.RolePresident: 1,
+		types.RoleSecretary: 1,
+		types.RoleDirector:  1,
+		types.RoleManager:   2,
+		types.RoleEngineer:  3,
+		types.RoleClient:    1,
+	}
+	for role, n := range want {
+		if counts[role] != n {
+			t.Errorf("Expected %d %s agent(s), got %d", n, role, counts[role])
+		}
+	}
+
+	t.Run("Teams", func(t *testing.T) {
+		org := Organization(t, Shape{Teams: []types.TeamConfig{{Name: "backend"}, {Name: "frontend"}}}, nil)
+		var ids []string
+		for _, info := range org.Agents() {
+			ids = append(ids, info.ID)
+		}
+		if !slices.Contains(ids, "engineer-backend-1") || !slices.Contains(ids, "engineer-frontend-1") {
+			t.Errorf("Expected an engineer in each team, got %v", ids)
+		}
+	})
+}
@@ -0,0 +1,35 @@
+package tui
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/kpango/BuildBureau/internal/agent"
+)
+
+// contextBarWidth is the width of the utilization bars in the context panel.
+const contextBarWidth = 20
+
+// renderContextPanel renders each agent's context window usage, flagging
+// agents whose prompts were truncated.
+func renderContextPanel(agents []agent.AgentInfo) string {
+	var b strings.Builder
+	b.WriteString("Context budget (estimated tokens)")
+
+	for _, a := range agents {
+		stats := a.Context
+		fmt.Fprintf(&b, "\n%-20s %s %s", a.ID, contextBar(stats.Utilization()), stats)
+		if stats.Truncations > 0 {
+			fmt.Fprintf(&b, " ⚠ last %s", stats.LastTruncation.Format("15:04:05"))
+		}
+	}
+
+	return b.String()
+}
+
+// contextBar renders a utilization between 0 and 1 as a bar.
+func contextBar(utilization float64) string {
+	filled := int(math.Round(min(max(utilization, 0), 1) * contextBarWidth))
+	return "[" + strings.Repeat("█", filled) + strings.Repeat("░", contextBarWidth-filled) + "]"
+}
@@ -0,0 +1,34 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kpango/BuildBureau/internal/agent"
+)
+
+func TestRenderContextPanel(t *testing.T) {
+	panel := renderContextPanel([]agent.AgentInfo{
+		{ID: "engineer-1", Context: agent.ContextStats{Limit: 1000, PromptTokens: 400, ReservedTokens: 100, Generations: 1}},
+		{ID: "manager-1", Context: agent.ContextStats{
+			Limit: 1000, PromptTokens: 1200, Generations: 2, Truncations: 1,
+			LastTruncation: time.Date(2026, 1, 2, 10, 30, 0, 0, time.Local),
+		}},
+		{ID: "director-1"},
+	})
+
+	lines := strings.Split(panel, "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected a header and one line per agent, got:\n%s", panel)
+	}
+	if !strings.Contains(lines[1], "[██████████░░░░░░░░░░] 50% of 1.0k") {
+		t.Errorf("Expected a half full bar for engineer-1, got %q", lines[1])
+	}
+	if !strings.Contains(lines[2], "[████████████████████]") || !strings.Contains(lines[2], "⚠ last 10:30:00") {
+		t.Errorf("Expected a full bar and truncation warning for manager-1, got %q", lines[2])
+	}
+	if !strings.Contains(lines[3], "unused") {
+		t.Errorf("Expected director-1 to be unused, got %q", lines[3])
+	}
+}
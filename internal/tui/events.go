@@ -0,0 +1,242 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kpango/BuildBureau/internal/errors"
+	"github.com/kpango/BuildBureau/internal/llm"
+	"github.com/kpango/BuildBureau/internal/logging"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// maxEvents bounds the event log; the oldest events are dropped beyond it.
+const maxEvents = 10000
+
+// severity ranks events for filtering.
+type severity int
+
+const (
+	severityDebug severity = iota // Prompts and LLM responses
+	severityInfo                  // Delegation, results and other activity
+	severityError
+)
+
+func (s severity) String() string {
+	switch s {
+	case severityDebug:
+		return "DEBUG"
+	case severityError:
+		return "ERROR"
+	default:
+		return "INFO"
+	}
+}
+
+// roleFilters are cycled through by the role filter key; "" shows every role.
+var roleFilters = []types.AgentRole{
+	"",
+	types.RolePresident,
+	types.RoleSecretary,
+	types.RoleDirector,
+	types.RoleManager,
+	types.RoleEngineer,
+	types.RoleClient,
+}
+
+// event is one entry of the event log.
+type event struct {
+	time     time.Time
+	role     types.AgentRole
+	source   string // Agent ID, or "you" for submitted instructions
+	text     string
+	severity severity
+}
+
+// eventFromActivity converts an agent activity entry into an event.
+func eventFromActivity(e logging.Entry) event {
+	ev := event{
+		time:     e.Time,
+		role:     types.AgentRole(e.Role),
+		source:   e.Agent,
+		text:     e.Content,
+		severity: severityInfo,
+	}
+
+	switch e.Kind {
+	case logging.KindPrompt:
+		ev.severity = severityDebug
+		ev.text = fmt.Sprintf("prompt to %s:\n%s", e.Model, e.Content)
+	case logging.KindResponse:
+		ev.severity = severityDebug
+		ev.text = fmt.Sprintf("response from %s:\n%s", e.Model, e.Content)
+	case logging.KindError:
+		ev.severity = severityError
+	}
+
+	return ev
+}
+
+// errorText describes a failed task. Provider failures are told apart from
+// other errors, since the user has to act on them, with the provider's own
+// message below.
+func errorText(err error) string {
+	var providerErr *llm.ProviderError
+	if !errors.As(err, &providerErr) {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	text := "LLM provider error: " + providerErr.Error()
+	if providerErr.Message != "" {
+		text += "\nprovider message: " + providerErr.Message
+	}
+	return text
+}
+
+// eventLog holds every event and the filters applied to the view.
+type eventLog struct {
+	events      []event
+	query       string
+	role        types.AgentRole
+	minSeverity severity
+}
+
+// newEventLog creates an event log that hides debug events.
+func newEventLog() eventLog {
+	return eventLog{minSeverity: severityInfo}
+}
+
+// add appends an event, dropping the oldest beyond maxEvents.
+func (l *eventLog) add(ev event) {
+	l.events = append(l.events, ev)
+	if len(l.events) > maxEvents {
+		l.events = l.events[len(l.events)-maxEvents:]
+	}
+}
+
+// matches reports whether an event passes the current filters.
+func (l *eventLog) matches(ev event) bool {
+	if ev.severity < l.minSeverity {
+		return false
+	}
+	if l.role != "" && ev.role != l.role {
+		return false
+	}
+	if l.query != "" {
+		query := strings.ToLower(l.query)
+		if !strings.Contains(strings.ToLower(ev.text), query) && !strings.Contains(strings.ToLower(ev.source), query) {
+			return false
+		}
+	}
+	return true
+}
+
+// visible returns the events that pass the current filters.
+func (l *eventLog) visible() []event {
+	var visible []event
+	for _, ev := range l.events {
+		if l.matches(ev) {
+			visible = append(visible, ev)
+		}
+	}
+	return visible
+}
+
+// render returns the filtered view and the line each visible event starts on.
+func (l *eventLog) render() (string, []int) {
+	var b strings.Builder
+	var starts []int
+	line := 0
+
+	for _, ev := range l.visible() {
+		starts = append(starts, line)
+		text := formatEvent(ev)
+		b.WriteString(text)
+		b.WriteString("\n")
+		line += strings.Count(text, "\n") + 1
+	}
+
+	return b.String(), starts
+}
+
+// lineAt returns the line of the first visible event at or after t.
+func (l *eventLog) lineAt(t time.Time) (int, bool) {
+	_, starts := l.render()
+	for i, ev := range l.visible() {
+		if !ev.time.Before(t) {
+			return starts[i], true
+		}
+	}
+	return 0, false
+}
+
+// export writes the filtered view to path and returns how many events it contains.
+func (l *eventLog) export(path string) (int, error) {
+	content, starts := l.render()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		return 0, fmt.Errorf("failed to export events: %w", err)
+	}
+	return len(starts), nil
+}
+
+// cycleRole switches the role filter to the next role.
+func (l *eventLog) cycleRole() {
+	for i, role := range roleFilters {
+		if role == l.role {
+			l.role = roleFilters[(i+1)%len(roleFilters)]
+			return
+		}
+	}
+	l.role = ""
+}
+
+// cycleSeverity switches the minimum severity shown to the next level.
+func (l *eventLog) cycleSeverity() {
+	l.minSeverity = (l.minSeverity + 1) % (severityError + 1)
+}
+
+// clearFilters shows every event again, except debug events.
+func (l *eventLog) clearFilters() {
+	l.query = ""
+	l.role = ""
+	l.minSeverity = severityInfo
+}
+
+// describe summarizes the active filters.
+func (l *eventLog) describe() string {
+	role := "all"
+	if l.role != "" {
+		role = string(l.role)
+	}
+
+	desc := fmt.Sprintf("role: %s | severity: %s+", role, l.minSeverity)
+	if l.query != "" {
+		desc += fmt.Sprintf(" | search: %q", l.query)
+	}
+	return fmt.Sprintf("%s | %d/%d events", desc, len(l.visible()), len(l.events))
+}
+
+// formatEvent renders an event, indenting continuation lines under its header.
+func formatEvent(ev event) string {
+	text := strings.ReplaceAll(strings.TrimRight(ev.text, "\n"), "\n", "\n    ")
+	return fmt.Sprintf("%s %-5s [%s] %s", ev.time.Format(time.TimeOnly), ev.severity, ev.source, text)
+}
+
+// parseJumpTime parses a time to jump to: a clock time such as "14:05" or
+// "14:05:30" today, or a duration before now such as "-10m" or "10m".
+func parseJumpTime(input string, now time.Time) (time.Time, error) {
+	input = strings.TrimSpace(input)
+
+	if d, err := time.ParseDuration(strings.TrimPrefix(input, "-")); err == nil {
+		return now.Add(-d), nil
+	}
+
+	for _, layout := range []string{time.TimeOnly, "15:04"} {
+		if t, err := time.ParseInLocation(layout, input, now.Location()); err == nil {
+			return time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), t.Second(), 0, now.Location()), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time %q: use HH:MM, HH:MM:SS or a duration such as 10m", input)
+}
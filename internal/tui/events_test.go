@@ -0,0 +1,152 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/kpango/BuildBureau/internal/llm"
+	"github.com/kpango/BuildBureau/internal/logging"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+func testEvents() eventLog {
+	base := time.Date(2026, 1, 2, 10, 0, 0, 0, time.Local)
+	l := newEventLog()
+	for _, e := range []logging.Entry{
+		{Time: base, Agent: "manager-1", Role: "Manager", Kind: logging.KindEvent, Content: "delegating task t1 to engineer-1"},
+		{Time: base.Add(time.Minute), Agent: "engineer-1", Role: "Engineer", Kind: logging.KindPrompt, Model: "gemini", Content: "Implement login"},
+		{Time: base.Add(2 * time.Minute), Agent: "engineer-1", Role: "Engineer", Kind: logging.KindError, Content: "model gemini not available"},
+		{Time: base.Add(3 * time.Minute), Agent: "engineer-2", Role: "Engineer", Kind: logging.KindEvent, Content: "reused result\nof task t1"},
+	} {
+		l.add(eventFromActivity(e))
+	}
+	return l
+}
+
+func TestEventLog(t *testing.T) {
+	t.Run("HidesDebugByDefault", func(t *testing.T) {
+		l := testEvents()
+		if got := len(l.visible()); got != 3 {
+			t.Errorf("Expected 3 visible events, got %d", got)
+		}
+	})
+
+	t.Run("Filters", func(t *testing.T) {
+		l := testEvents()
+
+		l.query = "TASK T1"
+		if got := len(l.visible()); got != 2 {
+			t.Errorf("Expected case-insensitive search to match 2 events, got %d", got)
+		}
+
+		l.query = ""
+		l.cycleRole() // President
+		l.cycleRole() // Secretary
+		l.cycleRole() // Director
+		l.cycleRole() // Manager
+		if l.role != types.RoleManager || len(l.visible()) != 1 {
+			t.Errorf("Expected role filter Manager to show 1 event, got %q with %d", l.role, len(l.visible()))
+		}
+
+		l.clearFilters()
+		l.cycleSeverity()
+		if got := l.visible(); len(got) != 1 || got[0].severity != severityError {
+			t.Errorf("Expected only the error, got %+v", got)
+		}
+
+		l.cycleSeverity()
+		if got := len(l.visible()); got != 4 {
+			t.Errorf("Expected debug severity to show every event, got %d", got)
+		}
+	})
+
+	t.Run("JumpsToTime", func(t *testing.T) {
+		l := testEvents()
+		_, starts := l.render()
+
+		line, ok := l.lineAt(time.Date(2026, 1, 2, 10, 2, 30, 0, time.Local))
+		if !ok || line != starts[2] {
+			t.Errorf("Expected jump to the event at 10:03 on line %d, got %d (%v)", starts[2], line, ok)
+		}
+		if _, ok := l.lineAt(time.Date(2026, 1, 2, 11, 0, 0, 0, time.Local)); ok {
+			t.Error("Expected no event after the last one")
+		}
+	})
+
+	t.Run("ExportsFilteredView", func(t *testing.T) {
+		l := testEvents()
+		l.role = types.RoleEngineer
+		path := filepath.Join(t.TempDir(), "events.log")
+
+		n, err := l.export(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := os.ReadFile(path)
+		if n != 2 || strings.Contains(string(data), "manager-1") || !strings.Contains(string(data), "    of task t1") {
+			t.Errorf("Unexpected export of %d events:\n%s", n, data)
+		}
+	})
+}
+
+func TestParseJumpTime(t *testing.T) {
+	now := time.Date(2026, 1, 2, 15, 0, 0, 0, time.Local)
+
+	tests := map[string]time.Time{
+		"14:05":    time.Date(2026, 1, 2, 14, 5, 0, 0, time.Local),
+		"14:05:30": time.Date(2026, 1, 2, 14, 5, 30, 0, time.Local),
+		"-10m":     now.Add(-10 * time.Minute),
+		"1h":       now.Add(-time.Hour),
+	}
+	for input, want := range tests {
+		got, err := parseJumpTime(input, now)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("parseJumpTime(%q) = %v, %v; want %v", input, got, err, want)
+		}
+	}
+
+	if _, err := parseJumpTime("yesterday", now); err == nil {
+		t.Error("Expected error for an invalid time")
+	}
+}
+
+func TestModelSearch(t *testing.T) {
+	m := NewModel(nil)
+	m.events = testEvents()
+
+	keys := []tea.KeyMsg{
+		{Type: tea.KeyTab},
+		{Type: tea.KeyRunes, Runes: []rune("/")},
+		{Type: tea.KeyRunes, Runes: []rune("login")},
+		{Type: tea.KeyEnter},
+	}
+	var model tea.Model = m
+	for _, key := range keys {
+		model, _ = model.Update(key)
+	}
+
+	m = model.(Model)
+	if m.focus != focusEvents || m.events.query != "login" {
+		t.Errorf("Expected search for %q with focus on the event log, got %q focus=%d", "login", m.events.query, m.focus)
+	}
+	if m.textarea.Value() != "" {
+		t.Errorf("Expected search keys not to reach the instruction input, got %q", m.textarea.Value())
+	}
+}
+
+func TestErrorText(t *testing.T) {
+	if got := errorText(fmt.Errorf("delegation failed")); got != "Error: delegation failed" {
+		t.Errorf("Expected a plain error, got %q", got)
+	}
+
+	providerErr := &llm.ProviderError{Provider: "gemini", Model: "gemini-2.0-flash", Key: "GEMINI_API_KEY", Kind: llm.ProviderAuth, Message: "API key not valid."}
+	got := errorText(fmt.Errorf("task failed: %w", providerErr))
+	if !strings.HasPrefix(got, "LLM provider error: Gemini rejected API key GEMINI_API_KEY") || !strings.HasSuffix(got, "provider message: API key not valid.") {
+		t.Errorf("Expected the provider error to be told apart, got %q", got)
+	}
+}
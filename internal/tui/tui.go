@@ -4,12 +4,17 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/kpango/BuildBureau/internal/agent"
+	"github.com/kpango/BuildBureau/internal/document"
+	"github.com/kpango/BuildBureau/internal/logging"
+	"github.com/kpango/BuildBureau/pkg/types"
 )
 
 const (
@@ -18,6 +23,9 @@ const (
 	defaultHeight         = 20
 	defaultTextareaHeight = 3
 	defaultCharLimit      = 1000
+
+	// activityBuffer is how many agent activity entries may wait for the UI.
+	activityBuffer = 1024
 )
 
 var (
@@ -44,16 +52,35 @@ var (
 			MarginTop(1)
 )
 
+// focus is the part of the UI that receives key presses.
+type focus int
+
+const (
+	focusInput  focus = iota // Instruction textarea
+	focusEvents              // Event log navigation and filter keys
+	focusSearch              // Search prompt
+	focusJump                // Time jump prompt
+)
+
+// welcomeText is shown until the first event arrives.
+const welcomeText = "Welcome to BuildBureau!\n\nEnter your task and press Ctrl+S to submit. Attach a PDF, Word or Excel document with a line like @./spec.pdf.\nPress Tab to browse the event log, Ctrl+C or Esc to quit."
+
 type Model struct {
-	textarea   textarea.Model
-	err        error
-	org        *agent.Organization
-	output     string
-	viewport   viewport.Model
-	width      int
-	height     int
-	ready      bool
-	processing bool
+	textarea    textarea.Model
+	prompt      textinput.Model
+	err         error
+	org         *agent.Organization
+	activity    chan logging.Entry
+	status      string
+	user        string // Submitter recorded on projects
+	events      eventLog
+	viewport    viewport.Model
+	width       int
+	height      int
+	focus       focus
+	ready       bool
+	processing  bool
+	showContext bool // Show the per-agent context budget panel
 }
 
 func NewModel(org *agent.Organization) Model {
@@ -65,56 +92,61 @@ func NewModel(org *agent.Organization) Model {
 	ta.SetHeight(defaultTextareaHeight)
 
 	vp := viewport.New(defaultWidth, defaultHeight)
-	vp.SetContent("Welcome to BuildBureau!\n\nEnter your task and press Ctrl+S to submit.\nPress Ctrl+C or Esc to quit.")
+	vp.SetContent(welcomeText)
+
+	// Agent activity is delivered through a buffered channel because
+	// subscribers must not block; events are dropped if the UI falls behind
+	activity := make(chan logging.Entry, activityBuffer)
+	if org != nil {
+		org.SubscribeActivity(func(e logging.Entry) {
+			select {
+			case activity <- e:
+			default:
+			}
+		})
+	}
 
 	return Model{
 		org:      org,
 		textarea: ta,
+		prompt:   textinput.New(),
 		viewport: vp,
-		output:   vp.View(),
+		activity: activity,
+		events:   newEventLog(),
 		ready:    true,
 	}
 }
 
+// WithUser returns the model with projects submitted as the given user.
+func (m Model) WithUser(user string) Model {
+	m.user = user
+	return m
+}
+
 func (m Model) Init() tea.Cmd {
-	return textarea.Blink
+	return tea.Batch(textarea.Blink, waitForActivity(m.activity))
 }
 
 type taskResultMsg struct {
 	err    error
 	result string
+	runID  string
 }
 
-func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
-	var (
-		taCmd tea.Cmd
-		vpCmd tea.Cmd
-	)
+// activityMsg carries an agent activity entry to the UI.
+type activityMsg logging.Entry
+
+// waitForActivity waits for the next agent activity entry.
+func waitForActivity(activity chan logging.Entry) tea.Cmd {
+	return func() tea.Msg {
+		return activityMsg(<-activity)
+	}
+}
 
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		//nolint:exhaustive // Key handling intentionally only covers specific cases
-		switch msg.Type {
-		case tea.KeyCtrlC, tea.KeyEsc:
-			return m, tea.Quit
-
-		case tea.KeyCtrlS:
-			if !m.processing && m.textarea.Value() != "" {
-				m.processing = true
-				instruction := m.textarea.Value()
-				m.textarea.Reset()
-
-				// Process task asynchronously
-				return m, func() tea.Msg {
-					ctx := context.Background()
-					response, err := m.org.ProcessClientTask(ctx, instruction)
-					if err != nil {
-						return taskResultMsg{err: err}
-					}
-					return taskResultMsg{result: response.Result}
-				}
-			}
-		}
+		return m.updateKey(msg)
 
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
@@ -122,28 +154,215 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		// Update viewport and textarea sizes
 		headerHeight := 3
-		footerHeight := 8
+		footerHeight := 9
 		m.viewport.Width = msg.Width - 4
 		m.viewport.Height = msg.Height - headerHeight - footerHeight
 		m.textarea.SetWidth(msg.Width - 6)
 
 	case taskResultMsg:
 		m.processing = false
+		ev := event{time: time.Now(), role: types.RolePresident, source: "president-1", severity: severityInfo}
 		if msg.err != nil {
-			m.output = fmt.Sprintf("Error: %v\n\n%s", msg.err, m.output)
+			ev.severity = severityError
+			ev.text = errorText(msg.err)
 		} else {
-			m.output = fmt.Sprintf("=== Task Result ===\n%s\n\n%s", msg.result, m.output)
+			ev.text = fmt.Sprintf("=== Task Result (run %s) ===\n%s", msg.runID, msg.result)
 		}
-		m.viewport.SetContent(m.output)
-		m.viewport.GotoTop()
+		m.addEvent(ev)
+
+	case activityMsg:
+		m.addEvent(eventFromActivity(logging.Entry(msg)))
+		return m, waitForActivity(m.activity)
 	}
 
+	var taCmd, vpCmd tea.Cmd
 	m.textarea, taCmd = m.textarea.Update(msg)
 	m.viewport, vpCmd = m.viewport.Update(msg)
 
 	return m, tea.Batch(taCmd, vpCmd)
 }
 
+// updateKey routes a key press to the focused part of the UI.
+func (m Model) updateKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.Type == tea.KeyCtrlC {
+		return m, tea.Quit
+	}
+
+	var cmd tea.Cmd
+	switch m.focus {
+	case focusSearch, focusJump:
+		//nolint:exhaustive // Key handling intentionally only covers specific cases
+		switch msg.Type {
+		case tea.KeyEsc:
+			m.setFocus(focusEvents)
+		case tea.KeyEnter:
+			m.applyPrompt()
+			m.setFocus(focusEvents)
+		default:
+			m.prompt, cmd = m.prompt.Update(msg)
+		}
+		return m, cmd
+
+	case focusEvents:
+		//nolint:exhaustive // Key handling intentionally only covers specific cases
+		switch msg.Type {
+		case tea.KeyEsc:
+			return m, tea.Quit
+		case tea.KeyTab:
+			m.setFocus(focusInput)
+			return m, nil
+		}
+
+		switch msg.String() {
+		case "/":
+			m.prompt.Placeholder = "search text"
+			m.prompt.SetValue(m.events.query)
+			m.setFocus(focusSearch)
+		case "t":
+			m.prompt.Placeholder = "HH:MM, HH:MM:SS or 10m ago"
+			m.prompt.SetValue("")
+			m.setFocus(focusJump)
+		case "r":
+			m.events.cycleRole()
+			m.refresh(false)
+		case "s":
+			m.events.cycleSeverity()
+			m.refresh(false)
+		case "c":
+			m.events.clearFilters()
+			m.refresh(false)
+		case "x":
+			m.exportEvents()
+		case "b":
+			m.showContext = !m.showContext
+		case "g":
+			m.viewport.GotoTop()
+		case "G":
+			m.viewport.GotoBottom()
+		default:
+			m.viewport, cmd = m.viewport.Update(msg)
+		}
+		return m, cmd
+	}
+
+	//nolint:exhaustive // Key handling intentionally only covers specific cases
+	switch msg.Type {
+	case tea.KeyEsc:
+		return m, tea.Quit
+
+	case tea.KeyTab:
+		m.setFocus(focusEvents)
+		return m, nil
+
+	case tea.KeyCtrlS:
+		if !m.processing && m.textarea.Value() != "" {
+			m.processing = true
+			instruction := m.textarea.Value()
+			m.textarea.Reset()
+			m.addEvent(event{time: time.Now(), source: "you", text: instruction, severity: severityInfo})
+
+			// Process task asynchronously
+			return m, func() tea.Msg {
+				instruction, attachments := splitAttachments(instruction)
+				ctx := agent.WithDocuments(types.WithUser(context.Background(), m.user), attachments...)
+				response, err := m.org.ProcessClientTask(ctx, instruction)
+				if err != nil {
+					return taskResultMsg{err: err}
+				}
+				return taskResultMsg{result: response.Result, runID: response.Metadata[types.MetadataRunID]}
+			}
+		}
+		return m, nil
+	}
+
+	m.textarea, cmd = m.textarea.Update(msg)
+	return m, cmd
+}
+
+// splitAttachments separates the lines of an instruction that attach a
+// document, such as "@./spec.pdf", from the rest of it.
+func splitAttachments(input string) (string, []string) {
+	var (
+		lines       []string
+		attachments []string
+	)
+	for line := range strings.Lines(input) {
+		if path, ok := strings.CutPrefix(strings.TrimSpace(line), "@"); ok && document.Format(path) != "" {
+			attachments = append(attachments, path)
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "")), attachments
+}
+
+// setFocus moves key input to part of the UI.
+func (m *Model) setFocus(f focus) {
+	m.focus = f
+	m.textarea.Blur()
+	m.prompt.Blur()
+
+	switch f {
+	case focusInput:
+		m.textarea.Focus()
+	case focusSearch, focusJump:
+		m.prompt.Focus()
+	}
+}
+
+// applyPrompt applies the search or time jump typed into the prompt.
+func (m *Model) applyPrompt() {
+	if m.focus == focusSearch {
+		m.events.query = strings.TrimSpace(m.prompt.Value())
+		m.refresh(false)
+		return
+	}
+
+	t, err := parseJumpTime(m.prompt.Value(), time.Now())
+	if err != nil {
+		m.status = err.Error()
+		return
+	}
+	line, ok := m.events.lineAt(t)
+	if !ok {
+		m.status = fmt.Sprintf("No events at or after %s", t.Format(time.TimeOnly))
+		return
+	}
+	m.viewport.SetYOffset(line)
+	m.status = fmt.Sprintf("Jumped to %s", t.Format(time.TimeOnly))
+}
+
+// exportEvents writes the filtered event log to a file in the working directory.
+func (m *Model) exportEvents() {
+	path := fmt.Sprintf("buildbureau-events-%s.log", time.Now().Format("20060102-150405"))
+	n, err := m.events.export(path)
+	if err != nil {
+		m.status = err.Error()
+		return
+	}
+	m.status = fmt.Sprintf("Exported %d event(s) to %s", n, path)
+}
+
+// addEvent appends an event and refreshes the view, following new events if
+// the view was scrolled to the bottom.
+func (m *Model) addEvent(ev event) {
+	follow := m.viewport.AtBottom() || len(m.events.events) == 0
+	m.events.add(ev)
+	m.refresh(follow)
+}
+
+// refresh re-renders the event log after events or filters change.
+func (m *Model) refresh(follow bool) {
+	content, _ := m.events.render()
+	if len(m.events.events) == 0 {
+		content = welcomeText
+	}
+	m.viewport.SetContent(content)
+	if follow {
+		m.viewport.GotoBottom()
+	}
+}
+
 func (m Model) View() string {
 	if m.err != nil {
 		return fmt.Sprintf("Error: %v\n", m.err)
@@ -155,12 +374,26 @@ func (m Model) View() string {
 	b.WriteString(titleStyle.Render("🏢 BuildBureau - Multi-Agent Development System"))
 	b.WriteString("\n\n")
 
-	// Output viewport
+	// Event log viewport and its filters
 	b.WriteString(outputStyle.Render(m.viewport.View()))
-	b.WriteString("\n\n")
+	b.WriteString("\n")
+	b.WriteString(helpStyle.Render(m.events.describe()))
+	b.WriteString("\n")
+
+	if m.showContext && m.org != nil {
+		b.WriteString(outputStyle.Render(renderContextPanel(m.org.Agents())))
+		b.WriteString("\n")
+	}
 
-	// Input area
-	b.WriteString(inputStyle.Render(m.textarea.View()))
+	// Input area, replaced by the prompt while searching or jumping
+	switch m.focus {
+	case focusSearch:
+		b.WriteString(inputStyle.Render("/" + m.prompt.View()))
+	case focusJump:
+		b.WriteString(inputStyle.Render("Jump to: " + m.prompt.View()))
+	default:
+		b.WriteString(inputStyle.Render(m.textarea.View()))
+	}
 	b.WriteString("\n")
 
 	// Help text
@@ -168,7 +401,18 @@ func (m Model) View() string {
 	if m.processing {
 		status = " [Processing...]"
 	}
-	b.WriteString(helpStyle.Render(fmt.Sprintf("Ctrl+S: Submit | Ctrl+C/Esc: Quit%s", status)))
+	if m.status != "" {
+		status += " " + m.status
+	}
+
+	help := "Ctrl+S: Submit | Tab: Event log | Ctrl+C/Esc: Quit"
+	switch m.focus {
+	case focusEvents:
+		help = "/: Search | r: Role | s: Severity | t: Jump to time | c: Clear | x: Export | b: Context | g/G: Top/Bottom | Tab: Input"
+	case focusSearch, focusJump:
+		help = "Enter: Apply | Esc: Cancel"
+	}
+	b.WriteString(helpStyle.Render(help + status))
 
 	return b.String()
 }
@@ -0,0 +1,30 @@
+package version
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	defer func(version, commit, date string) {
+		Version, Commit, BuildDate = version, commit, date
+	}(Version, Commit, BuildDate)
+
+	Version, Commit, BuildDate = "v1.2.0", "abc1234", "2026-01-02_03:04:05"
+	info := Get()
+	if info.Version != "v1.2.0" || info.Commit != "abc1234" || info.BuildDate != "2026-01-02_03:04:05" {
+		t.Errorf("Expected the values set at build time, got %+v", info)
+	}
+	if info.GoVersion != runtime.Version() {
+		t.Errorf("Expected Go version %s, got %s", runtime.Version(), info.GoVersion)
+	}
+	if s := info.String(); !strings.Contains(s, "v1.2.0") || !strings.Contains(s, "abc1234") {
+		t.Errorf("Unexpected string %q", s)
+	}
+
+	Commit, BuildDate = "", ""
+	if info := Get(); info.Commit == "" || info.BuildDate == "" {
+		t.Errorf("Expected unset fields to be filled in, got %+v", info)
+	}
+}
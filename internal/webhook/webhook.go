@@ -0,0 +1,278 @@
+// Package webhook delivers project lifecycle events to callback URLs, so
+// external orchestration systems learn when a project starts, reaches a
+// milestone, needs approval and finishes without polling. Each payload is JSON
+// signed with HMAC-SHA256; receivers check the signature with Verify. Failed
+// deliveries are retried with exponential backoff in the background.
+package webhook
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// Lifecycle events.
+const (
+	EventStarted        = "project_started"
+	EventMilestone      = "project_milestone"
+	EventApprovalNeeded = "approval_needed"
+	EventCompleted      = "project_completed"
+	EventFailed         = "project_failed"
+)
+
+var events = []string{EventStarted, EventMilestone, EventApprovalNeeded, EventCompleted, EventFailed}
+
+// Headers of a delivery.
+const (
+	HeaderEvent     = "X-BuildBureau-Event"
+	HeaderDelivery  = "X-BuildBureau-Delivery"  // Payload ID, the same on every attempt
+	HeaderTimestamp = "X-BuildBureau-Timestamp" // Unix seconds of the attempt
+	HeaderSignature = "X-BuildBureau-Signature" // sha256=<hex HMAC of "<timestamp>.<body>">
+)
+
+const (
+	defaultMaxAttempts = 5
+	defaultBackoff     = 2 * time.Second
+	defaultMaxBackoff  = time.Minute
+	defaultTimeout     = 10 * time.Second
+
+	// DefaultTolerance is how old a signed timestamp Verify accepts by default.
+	DefaultTolerance = 5 * time.Minute
+
+	signaturePrefix = "sha256="
+)
+
+// Payload is the JSON body of a delivery.
+type Payload struct {
+	Time      time.Time           `json:"time"`
+	Response  *types.TaskResponse `json:"response,omitempty"` // The deliverable, on completion
+	ID        string              `json:"id"`
+	Event     string              `json:"event"`
+	RunID     string              `json:"run_id"`
+	User      string              `json:"user,omitempty"`
+	Milestone string              `json:"milestone,omitempty"` // What was reached, for milestone events
+	Message   string              `json:"message,omitempty"`
+	Error     string              `json:"error,omitempty"` // Why the project failed, for failure events
+}
+
+// Dispatcher delivers payloads to the configured URLs and those of each
+// submission.
+type Dispatcher struct {
+	client      *http.Client
+	now         func() time.Time
+	stop        chan struct{}
+	stopOnce    sync.Once
+	secret      []byte
+	urls        []string
+	events      []string
+	wg          sync.WaitGroup
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+}
+
+// Option configures a Dispatcher.
+type Option func(*Dispatcher)
+
+// WithHTTPClient replaces the client deliveries are sent with.
+func WithHTTPClient(client *http.Client) Option {
+	return func(d *Dispatcher) {
+		d.client = client
+	}
+}
+
+// New creates a dispatcher for a webhooks configuration, signing payloads
+// with secret. A nil or disabled configuration returns nil, which delivers
+// nothing.
+func New(config *types.WebhooksConfig, secret string, opts ...Option) (*Dispatcher, error) {
+	if config == nil || !config.Enabled {
+		return nil, nil
+	}
+	if secret == "" {
+		return nil, errors.New("webhooks need a secret to sign payloads with")
+	}
+	for _, e := range config.Events {
+		if !slices.Contains(events, e) {
+			return nil, fmt.Errorf("unknown webhook event %q: must be one of %s", e, strings.Join(events, ", "))
+		}
+	}
+
+	d := &Dispatcher{
+		client:      &http.Client{Timeout: cmp.Or(config.Timeout, defaultTimeout)},
+		now:         time.Now,
+		stop:        make(chan struct{}),
+		secret:      []byte(secret),
+		urls:        config.URLs,
+		events:      config.Events,
+		maxAttempts: cmp.Or(max(config.MaxAttempts, 0), defaultMaxAttempts),
+		backoff:     cmp.Or(max(config.Backoff, 0), defaultBackoff),
+		maxBackoff:  cmp.Or(max(config.MaxBackoff, 0), defaultMaxBackoff),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d, nil
+}
+
+// Send delivers a payload to the configured URLs and to callbacks, the URLs
+// of the submission it belongs to, without waiting for delivery. Events the
+// configuration does not list are dropped. The payload's ID and time are set
+// if empty.
+func (d *Dispatcher) Send(p Payload, callbacks ...string) {
+	if d == nil || (len(d.events) > 0 && !slices.Contains(d.events, p.Event)) {
+		return
+	}
+	if p.ID == "" {
+		p.ID = uuid.New().String()
+	}
+	if p.Time.IsZero() {
+		p.Time = d.now()
+	}
+	body, err := json.Marshal(p)
+	if err != nil {
+		fmt.Printf("Warning: failed to encode %s webhook: %v\n", p.Event, err)
+		return
+	}
+
+	for _, url := range slices.Compact(slices.Sorted(slices.Values(append(slices.Clone(d.urls), callbacks...)))) {
+		d.wg.Go(func() { d.deliver(url, p, body) })
+	}
+}
+
+// Close waits for deliveries in progress, including their retries, until ctx
+// is done; deliveries still failing then are dropped.
+func (d *Dispatcher) Close(ctx context.Context) {
+	if d == nil {
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		d.stopOnce.Do(func() { close(d.stop) })
+	}
+}
+
+// deliver posts a payload until the receiver accepts it, the attempts run
+// out or closing the dispatcher times out. Client errors other than 408 and 429 are
+// not retried: the same request would be rejected again.
+func (d *Dispatcher) deliver(url string, p Payload, body []byte) {
+	for attempt := 1; ; attempt++ {
+		retry, err := d.post(url, p, body)
+		if err == nil {
+			return
+		}
+		if !retry || attempt >= d.maxAttempts {
+			fmt.Printf("Warning: giving up on %s webhook to %s after %d attempt(s): %v\n", p.Event, url, attempt, err)
+			return
+		}
+
+		timer := time.NewTimer(d.retryDelay(attempt))
+		select {
+		case <-d.stop:
+			timer.Stop()
+			fmt.Printf("Warning: dropping %s webhook to %s at shutdown: %v\n", p.Event, url, err)
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// post makes one delivery attempt and reports whether a failure is worth retrying.
+func (d *Dispatcher) post(url string, p Payload, body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	timestamp := strconv.FormatInt(d.now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "BuildBureau-Webhook")
+	req.Header.Set(HeaderEvent, p.Event)
+	req.Header.Set(HeaderDelivery, p.ID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(d.secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	resp.Body.Close()
+
+	switch code := resp.StatusCode; {
+	case code >= 200 && code < 300:
+		return false, nil
+	case code == http.StatusRequestTimeout || code == http.StatusTooManyRequests || code >= 500:
+		return true, fmt.Errorf("receiver returned %s", resp.Status)
+	default:
+		return false, fmt.Errorf("receiver returned %s", resp.Status)
+	}
+}
+
+// retryDelay returns the delay before retrying after the given number of
+// failed attempts: the backoff doubled per attempt, capped at maxBackoff.
+func (d *Dispatcher) retryDelay(attempts int) time.Duration {
+	delay := float64(d.backoff) * math.Pow(2, float64(attempts-1))
+	return time.Duration(min(delay, float64(d.maxBackoff)))
+}
+
+// Sign returns the signature header value of a body sent at timestamp (Unix
+// seconds). The timestamp is signed along with the body so a captured
+// delivery cannot be replayed later.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of a delivery received by an HTTP handler and
+// returns its payload. Deliveries signed more than tolerance ago, or
+// DefaultTolerance if it is zero, are rejected. The request body is consumed.
+func Verify(r *http.Request, secret []byte, tolerance time.Duration) (*Payload, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook body: %w", err)
+	}
+
+	timestamp := r.Header.Get(HeaderTimestamp)
+	sent, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s header %q", HeaderTimestamp, timestamp)
+	}
+	if age := time.Since(time.Unix(sent, 0)); age.Abs() > cmp.Or(tolerance, DefaultTolerance) {
+		return nil, fmt.Errorf("webhook timestamp is %s off", age.Round(time.Second))
+	}
+	if !hmac.Equal([]byte(r.Header.Get(HeaderSignature)), []byte(Sign(secret, timestamp, body))) {
+		return nil, errors.New("invalid webhook signature")
+	}
+
+	var p Payload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse webhook payload: %w", err)
+	}
+	return &p, nil
+}
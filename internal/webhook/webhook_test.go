@@ -0,0 +1,162 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// receiver records verified deliveries and answers with scripted status codes.
+type receiver struct {
+	statuses []int
+	payloads []*Payload
+	attempts int
+	mu       sync.Mutex
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.attempts++
+	if len(r.statuses) > 0 {
+		status := r.statuses[0]
+		r.statuses = r.statuses[1:]
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+	}
+	p, err := Verify(req, []byte("secret"), 0)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	r.payloads = append(r.payloads, p)
+}
+
+func newDispatcher(t *testing.T, config *types.WebhooksConfig) *Dispatcher {
+	t.Helper()
+	config.Enabled = true
+	config.Backoff = time.Millisecond
+	d, err := New(config, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestDispatcher(t *testing.T) {
+	t.Run("DeliversSignedPayloads", func(t *testing.T) {
+		configured, callback := &receiver{}, &receiver{}
+		configuredServer, callbackServer := httptest.NewServer(configured), httptest.NewServer(callback)
+		defer configuredServer.Close()
+		defer callbackServer.Close()
+
+		d := newDispatcher(t, &types.WebhooksConfig{URLs: []string{configuredServer.URL}})
+		d.Send(Payload{Event: EventCompleted, RunID: "run-1", Response: &types.TaskResponse{Result: "done"}}, callbackServer.URL, configuredServer.URL)
+		d.Close(context.Background())
+
+		if len(configured.payloads) != 1 || len(callback.payloads) != 1 {
+			t.Fatalf("Expected one delivery to each URL, got %d and %d", len(configured.payloads), len(callback.payloads))
+		}
+		p := callback.payloads[0]
+		if p.Event != EventCompleted || p.RunID != "run-1" || p.ID == "" || p.Time.IsZero() || p.Response.Result != "done" {
+			t.Errorf("Unexpected payload: %+v", p)
+		}
+	})
+
+	t.Run("RetriesServerErrors", func(t *testing.T) {
+		r := &receiver{statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK}}
+		server := httptest.NewServer(r)
+		defer server.Close()
+
+		d := newDispatcher(t, &types.WebhooksConfig{URLs: []string{server.URL}})
+		d.Send(Payload{Event: EventStarted, RunID: "run-1"})
+		d.Close(context.Background())
+
+		if r.attempts != 3 || len(r.payloads) != 1 {
+			t.Errorf("Expected delivery on the third attempt, got %d attempts and %d payloads", r.attempts, len(r.payloads))
+		}
+	})
+
+	t.Run("GivesUp", func(t *testing.T) {
+		failing, rejecting := &receiver{statuses: []int{500, 500, 500, 500}}, &receiver{statuses: []int{http.StatusBadRequest}}
+		failingServer, rejectingServer := httptest.NewServer(failing), httptest.NewServer(rejecting)
+		defer failingServer.Close()
+		defer rejectingServer.Close()
+
+		d := newDispatcher(t, &types.WebhooksConfig{URLs: []string{failingServer.URL, rejectingServer.URL}, MaxAttempts: 3})
+		d.Send(Payload{Event: EventFailed, RunID: "run-1"})
+		d.Close(context.Background())
+
+		if failing.attempts != 3 {
+			t.Errorf("Expected 3 attempts before giving up, got %d", failing.attempts)
+		}
+		if rejecting.attempts != 1 {
+			t.Errorf("Expected a rejected delivery not to be retried, got %d attempts", rejecting.attempts)
+		}
+	})
+
+	t.Run("FiltersEvents", func(t *testing.T) {
+		r := &receiver{}
+		server := httptest.NewServer(r)
+		defer server.Close()
+
+		d := newDispatcher(t, &types.WebhooksConfig{URLs: []string{server.URL}, Events: []string{EventCompleted}})
+		d.Send(Payload{Event: EventMilestone, RunID: "run-1"})
+		d.Send(Payload{Event: EventCompleted, RunID: "run-1"})
+		d.Close(context.Background())
+
+		if len(r.payloads) != 1 || r.payloads[0].Event != EventCompleted {
+			t.Errorf("Expected only the completion, got %+v", r.payloads)
+		}
+	})
+
+	t.Run("InvalidConfig", func(t *testing.T) {
+		if d, err := New(nil, ""); d != nil || err != nil {
+			t.Errorf("Expected no dispatcher without a configuration, got %v, %v", d, err)
+		}
+		if _, err := New(&types.WebhooksConfig{Enabled: true}, ""); err == nil {
+			t.Error("Expected an error without a secret")
+		}
+		if _, err := New(&types.WebhooksConfig{Enabled: true, Events: []string{"project_paused"}}, "secret"); err == nil {
+			t.Error("Expected an error for an unknown event")
+		}
+	})
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"event":"project_started","run_id":"run-1"}`)
+	request := func(secret, timestamp string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		r.Header.Set(HeaderTimestamp, timestamp)
+		r.Header.Set(HeaderSignature, Sign([]byte(secret), timestamp, body))
+		return r
+	}
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+
+	if p, err := Verify(request("secret", now), []byte("secret"), 0); err != nil || p.RunID != "run-1" {
+		t.Errorf("Expected a valid signature to verify, got %+v, %v", p, err)
+	}
+	if _, err := Verify(request("other", now), []byte("secret"), 0); err == nil {
+		t.Error("Expected a signature with another secret to be rejected")
+	}
+	old := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	if _, err := Verify(request("secret", old), []byte("secret"), 0); err == nil {
+		t.Error("Expected an old delivery to be rejected")
+	}
+
+	tampered := request("secret", now)
+	tampered.Body = http.NoBody
+	if _, err := Verify(tampered, []byte("secret"), 0); err == nil {
+		t.Error("Expected a tampered body to be rejected")
+	}
+}
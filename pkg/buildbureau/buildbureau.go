@@ -0,0 +1,468 @@
+package buildbureau
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/kpango/BuildBureau/internal/agent"
+	"github.com/kpango/BuildBureau/internal/config"
+	"github.com/kpango/BuildBureau/internal/llm"
+	"github.com/kpango/BuildBureau/internal/logging"
+	"github.com/kpango/BuildBureau/internal/profile"
+	"github.com/kpango/BuildBureau/internal/report"
+	"github.com/kpango/BuildBureau/internal/webhook"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+// Agent is the interface implemented by every agent in an organization.
+type Agent = types.Agent
+
+// Task is a unit of work passed between agents.
+type Task = types.Task
+
+// TaskResponse is the result of processing a task.
+type TaskResponse = types.TaskResponse
+
+// Config is the top-level organization configuration.
+type Config = types.Config
+
+// MemoryManager is the persistent memory backend used by agents.
+type MemoryManager = types.MemoryManager
+
+// MemoryEntry is a single stored memory.
+type MemoryEntry = types.MemoryEntry
+
+// Provider is an LLM backend that can be registered with an organization.
+type Provider = llm.Provider
+
+// GenerateOptions are the options passed to a Provider.
+type GenerateOptions = llm.GenerateOptions
+
+// AgentInfo describes an agent and the model it generates with.
+type AgentInfo = agent.AgentInfo
+
+// ContextStats reports how much of its model's context window an agent uses.
+type ContextStats = agent.ContextStats
+
+// AgentProfile is what an agent has learned across projects.
+type AgentProfile = profile.Profile
+
+// LogEntry is one line of an agent's activity log.
+type LogEntry = logging.Entry
+
+// AdmissionStats describes the projects running and waiting for admission.
+type AdmissionStats = agent.AdmissionStats
+
+// ProjectStats reports how a submission was served by the organization's agents.
+type ProjectStats = agent.ProjectStats
+
+// Generation describes an LLM generation in progress.
+type Generation = agent.Generation
+
+// ErrGenerationCanceled is the cause of a generation aborted with CancelGeneration.
+var ErrGenerationCanceled = agent.ErrGenerationCanceled
+
+// WebhookPayload is the JSON body of a webhook delivery.
+type WebhookPayload = webhook.Payload
+
+// Option configures an Organization.
+type Option func(*options)
+
+type options struct {
+	providers     map[string]Provider
+	memoryManager MemoryManager
+	defaultModel  string
+	eventLogDir   string
+}
+
+// WithProvider registers an LLM provider under the given model name.
+// Agents whose config references this name will use the provider.
+func WithProvider(name string, provider Provider) Option {
+	return func(o *options) {
+		o.providers[name] = provider
+	}
+}
+
+// WithDefaultModel overrides the default model used when an agent does not specify one.
+func WithDefaultModel(name string) Option {
+	return func(o *options) {
+		o.defaultModel = name
+	}
+}
+
+// WithMemoryManager attaches a memory backend instead of the one described in the config.
+func WithMemoryManager(manager MemoryManager) Option {
+	return func(o *options) {
+		o.memoryManager = manager
+	}
+}
+
+// WithEventLog writes every event to an append-only log in dir before it is
+// delivered, so events survive a crash and consumers registered with Consume
+// resume after a restart from the last event they processed.
+func WithEventLog(dir string) Option {
+	return func(o *options) {
+		o.eventLogDir = dir
+	}
+}
+
+// eventBus creates the event bus, opening the event log if one is configured.
+func (o *options) eventBus() (*eventBus, error) {
+	if o.eventLogDir == "" {
+		return newEventBus(nil), nil
+	}
+	log, err := openEventLog(o.eventLogDir)
+	if err != nil {
+		return nil, err
+	}
+	return newEventBus(log), nil
+}
+
+// Organization is an embeddable BuildBureau organization.
+type Organization struct {
+	org    *agent.Organization
+	events *eventBus
+}
+
+// LoadConfig reads an organization configuration file.
+func LoadConfig(path string) (*Config, error) {
+	return config.NewLoader().Load(path)
+}
+
+// New builds an organization from configuration.
+func New(cfg *Config, opts ...Option) (*Organization, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config is required")
+	}
+
+	o := &options{
+		providers:    make(map[string]Provider),
+		defaultModel: cfg.LLMs.DefaultModel,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	llmManager, err := newLLMManager(cfg, o)
+	if err != nil {
+		return nil, err
+	}
+
+	org, err := agent.NewOrganizationWithLLM(cfg, llmManager)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create organization: %w", err)
+	}
+
+	if o.memoryManager != nil {
+		org.SetMemoryManager(o.memoryManager)
+	}
+
+	events, err := o.eventBus()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Organization{
+		org:    org,
+		events: events,
+	}, nil
+}
+
+// newLLMManager combines providers configured by API keys with registered providers.
+// It returns a nil manager when neither is available, in which case agents work
+// without LLM assistance just like the CLI.
+func newLLMManager(cfg *Config, o *options) (*llm.Manager, error) {
+	llmOpts := []llm.Option{llm.WithDefaultModel(o.defaultModel)}
+	for name, provider := range o.providers {
+		llmOpts = append(llmOpts, llm.WithProvider(name, provider))
+	}
+
+	manager, err := llm.NewManager(&cfg.LLMs, llmOpts...)
+	if err != nil && len(o.providers) > 0 {
+		return nil, fmt.Errorf("failed to initialize LLM providers: %w", err)
+	}
+
+	return manager, nil
+}
+
+// Restore recreates an organization from a snapshot written by SaveSnapshot,
+// possibly on another machine. Options are applied as in New. Instructions
+// that were in flight when the snapshot was taken are resumed by ResumePending.
+func Restore(path string, opts ...Option) (*Organization, error) {
+	snap, err := agent.LoadSnapshot(path)
+	if err != nil {
+		return nil, err
+	}
+
+	o := &options{
+		providers:    make(map[string]Provider),
+		defaultModel: snap.Config.LLMs.DefaultModel,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	llmManager, err := newLLMManager(snap.Config, o)
+	if err != nil {
+		return nil, err
+	}
+
+	org, err := agent.RestoreOrganization(snap, llmManager)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore organization: %w", err)
+	}
+
+	if o.memoryManager != nil {
+		org.SetMemoryManager(o.memoryManager)
+	}
+
+	events, err := o.eventBus()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Organization{
+		org:    org,
+		events: events,
+	}, nil
+}
+
+// SaveSnapshot writes the organization's runtime state to path: its
+// configuration including agent definitions, agent counters, instructions in
+// flight, and references to its memory database.
+func (o *Organization) SaveSnapshot(path string) error {
+	return o.org.SaveSnapshot(path)
+}
+
+// ResumePending resubmits the instructions that were in flight when the
+// snapshot this organization was restored from was taken. Each keeps its
+// original run ID, user, labels and callback URLs. It returns the responses of those that succeeded and the
+// first error encountered.
+func (o *Organization) ResumePending(ctx context.Context) ([]*TaskResponse, error) {
+	var responses []*TaskResponse
+	var firstErr error
+
+	for _, t := range o.org.TakePending() {
+		taskCtx := types.WithLabels(types.WithUser(ctx, t.Task.Metadata[types.MetadataUser]), types.Labels(t.Task.Metadata))
+		taskCtx = agent.WithTaskCallbacks(taskCtx, t.Task)
+		resp, err := o.submit(taskCtx, t.Task.RunID, t.Task.Content)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		responses = append(responses, resp)
+	}
+
+	return responses, firstErr
+}
+
+// Start starts every agent in the organization.
+func (o *Organization) Start(ctx context.Context) error {
+	return o.org.Start(ctx)
+}
+
+// Stop gracefully shuts down every agent in the organization and closes the event log.
+func (o *Organization) Stop(ctx context.Context) error {
+	err := o.org.Stop(ctx)
+	if o.events.log != nil {
+		if closeErr := o.events.log.close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("failed to close event log: %w", closeErr)
+		}
+	}
+	return err
+}
+
+// President returns the top-level agent that receives submitted work.
+func (o *Organization) President() Agent {
+	return o.org.GetPresident()
+}
+
+// Agents describes every agent in the organization.
+func (o *Organization) Agents() []AgentInfo {
+	return o.org.Agents()
+}
+
+// SetModel switches the model of every agent with the given role (e.g.
+// "engineer") or of the agent with the given ID, while the organization runs.
+// It waits for the agents' in-flight generations to finish on the old model
+// and returns the IDs of the agents that were switched.
+func (o *Organization) SetModel(target, model string) ([]string, error) {
+	return o.org.SetModel(target, model)
+}
+
+// Generations returns the LLM generations in progress, oldest first.
+func (o *Organization) Generations() []Generation {
+	return o.org.Generations()
+}
+
+// CancelGeneration aborts one generation in progress without stopping the
+// agent running it; the task it belongs to fails with ErrGenerationCanceled
+// and may be re-planned by the recovery policy.
+func (o *Organization) CancelGeneration(id string) error {
+	return o.org.CancelGeneration(id)
+}
+
+// Profiles returns the long-term profile of every agent, keyed by agent ID.
+// It is empty unless profiles are enabled in the configuration.
+func (o *Organization) Profiles() map[string]AgentProfile {
+	return o.org.Profiles()
+}
+
+// Logs returns up to the last n entries of an agent's activity log, oldest
+// first. Logging must be enabled in the configuration.
+func (o *Organization) Logs(agentID string, n int) ([]LogEntry, error) {
+	return o.org.GetLogs(agentID, n)
+}
+
+// Submit sends a client instruction to the organization and waits for the result.
+func (o *Organization) Submit(ctx context.Context, instruction string) (*TaskResponse, error) {
+	return o.submit(ctx, uuid.New().String(), instruction)
+}
+
+// submit processes an instruction as part of the given run, publishing its events.
+func (o *Organization) submit(ctx context.Context, runID, instruction string) (*TaskResponse, error) {
+	ctx = types.WithRunID(ctx, runID)
+	user := types.UserFromContext(ctx)
+
+	o.events.publish(Event{Type: EventSubmitted, RunID: runID, User: user, Instruction: instruction})
+	ctx = agent.WithQueueFeedback(ctx, func(position int) {
+		o.events.publish(Event{Type: EventQueued, RunID: runID, User: user, Instruction: instruction, Position: position})
+	})
+
+	resp, err := o.org.ProcessClientTask(ctx, instruction)
+	if err != nil {
+		o.events.publish(Event{Type: EventFailed, RunID: runID, User: user, Instruction: instruction, Err: err})
+		return nil, err
+	}
+
+	o.events.publish(Event{Type: EventCompleted, TaskID: resp.TaskID, RunID: runID, User: user, Instruction: instruction, Response: resp})
+	return resp, nil
+}
+
+// Admission reports the projects running and queued under admission control.
+func (o *Organization) Admission() AdmissionStats {
+	return o.org.Admission()
+}
+
+// Projects reports the scheduling statistics of running and recent
+// submissions, including their throughput in agent tasks per minute.
+func (o *Organization) Projects() []ProjectStats {
+	return o.org.Projects()
+}
+
+// ProjectsOf reports the statistics of the running and recent submissions
+// of one user, as set with WithUser.
+func (o *Organization) ProjectsOf(user string) []ProjectStats {
+	return o.org.ProjectsOf(user)
+}
+
+// ProjectsLabeled reports the statistics of the running and recent
+// submissions carrying the label key=value, as set with WithLabel.
+func (o *Organization) ProjectsLabeled(key, value string) []ProjectStats {
+	return o.org.ProjectsLabeled(key, value)
+}
+
+// WithUser returns a context whose submission is attributed to the given
+// user. The user is recorded on the project and its events, and counts
+// against the admission's max_per_user limit.
+func WithUser(ctx context.Context, user string) context.Context {
+	return types.WithUser(ctx, user)
+}
+
+// WithLabel returns a context whose submission carries the label key=value.
+// Labels travel with every task of the project and are recorded on its
+// memories, statistics and response, and routing rules can send labeled work
+// to a given team or agent.
+func WithLabel(ctx context.Context, key, value string) context.Context {
+	return types.WithLabel(ctx, key, value)
+}
+
+// WithProjectWeight returns a context whose submission is scheduled with the
+// given weight when fair scheduling is enabled. The default weight is 1.
+func WithProjectWeight(ctx context.Context, weight float64) context.Context {
+	return agent.WithProjectWeight(ctx, weight)
+}
+
+// WithCallbackURL returns a context whose submission reports its lifecycle
+// events to url as signed JSON payloads, in addition to the URLs in the
+// webhooks configuration. Webhooks must be enabled in the configuration,
+// which holds the signing secret. Call it again to add more URLs.
+func WithCallbackURL(ctx context.Context, url string) context.Context {
+	return agent.WithCallbackURL(ctx, url)
+}
+
+// WithDocuments returns a context whose submission has documents attached:
+// PDF (converted with pdftotext), Word (DOCX), Excel (XLSX), HTML, Markdown or
+// text files. Their text is added to the instruction within the limits of the
+// documents configuration; a document that cannot be read fails the submission.
+func WithDocuments(ctx context.Context, paths ...string) context.Context {
+	return agent.WithDocuments(ctx, paths...)
+}
+
+// VerifyWebhook checks the signature of a webhook delivery received by an
+// HTTP handler against the configured secret and returns its payload.
+// Deliveries signed more than tolerance ago are rejected; zero means five
+// minutes. The request body is consumed.
+func VerifyWebhook(r *http.Request, secret []byte, tolerance time.Duration) (*WebhookPayload, error) {
+	return webhook.Verify(r, secret, tolerance)
+}
+
+// RunMemories returns every memory recorded while processing the run with the given ID.
+// The run ID is reported in events and in the "run_id" metadata of Submit's response.
+func (o *Organization) RunMemories(ctx context.Context, runID string) ([]*MemoryEntry, error) {
+	return o.org.GetRunMemories(ctx, runID)
+}
+
+// WriteReport renders the transcript of a run as a Markdown ("markdown") or
+// HTML ("html") report with a table of contents.
+func (o *Organization) WriteReport(ctx context.Context, runID, format string, w io.Writer) error {
+	r, err := o.org.Report(ctx, runID)
+	if err != nil {
+		return err
+	}
+	return report.Render(w, r, format)
+}
+
+// SharedKnowledge returns the lessons shared across the organization.
+// It fails unless knowledge sharing and memory are enabled.
+func (o *Organization) SharedKnowledge(ctx context.Context) ([]*MemoryEntry, error) {
+	return o.org.SharedKnowledge(ctx)
+}
+
+// DeleteRun removes every memory recorded for a run and returns how many were deleted.
+func (o *Organization) DeleteRun(ctx context.Context, runID string) (int, error) {
+	return o.org.DeleteRun(ctx, runID)
+}
+
+// Subscribe registers a handler that receives organization events.
+// Handlers are called synchronously and must not block.
+// The returned function removes the subscription.
+func (o *Organization) Subscribe(handler func(Event)) func() {
+	return o.events.subscribe(handler)
+}
+
+// Consume registers a durable subscriber. The handler first receives the
+// logged events the named consumer has not yet processed, including those
+// published while it was not running, then new events like a Subscribe
+// handler. The consumer's offset is saved after each event, so each consumer
+// goes at its own pace and resumes where it left off. It requires
+// WithEventLog. The returned function removes the subscription.
+func (o *Organization) Consume(consumer string, handler func(Event)) (func(), error) {
+	return o.events.consume(consumer, handler)
+}
+
+// Events returns the logged events with an offset greater than after, oldest
+// first. It requires WithEventLog.
+func (o *Organization) Events(after int64) ([]Event, error) {
+	if o.events.log == nil {
+		return nil, fmt.Errorf("reading events requires an event log; use WithEventLog")
+	}
+	return o.events.log.since(after)
+}
@@ -0,0 +1,281 @@
+package buildbureau
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+type stubProvider struct {
+	calls int
+}
+
+func (p *stubProvider) Generate(ctx context.Context, prompt string, opts *GenerateOptions) (string, error) {
+	p.calls++
+	return "stub implementation", nil
+}
+
+func (p *stubProvider) Name() string {
+	return "stub"
+}
+
+func writeAgentConfig(t *testing.T, dir, name, role string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name+".yaml")
+	content := "name: " + name + "\nrole: " + role + "\nmodel: stub\nsystem_prompt: test\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write agent config: %v", err)
+	}
+	return path
+}
+
+func TestOrganization_SubmitWithRegisteredProvider(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &types.Config{
+		LLMs: types.LLMConfig{DefaultModel: "stub"},
+		Organization: types.OrganizationConfig{
+			Layers: []types.LayerConfig{
+				{Name: "President", Agent: writeAgentConfig(t, dir, "president", "President")},
+				{Name: "Secretary", Agent: writeAgentConfig(t, dir, "secretary", "Secretary"), AttachTo: []string{"President"}},
+				{Name: "Director", Agent: writeAgentConfig(t, dir, "director", "Director")},
+				{Name: "Manager", Agent: writeAgentConfig(t, dir, "manager", "Manager")},
+				{Name: "Engineer", Agent: writeAgentConfig(t, dir, "engineer", "Engineer")},
+			},
+		},
+	}
+
+	provider := &stubProvider{}
+	org, err := New(cfg, WithProvider("stub", provider))
+	if err != nil {
+		t.Fatalf("Failed to create organization: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := org.Start(ctx); err != nil {
+		t.Fatalf("Failed to start organization: %v", err)
+	}
+	defer org.Stop(ctx)
+
+	var events []EventType
+	unsubscribe := org.Subscribe(func(e Event) {
+		events = append(events, e.Type)
+	})
+
+	resp, err := org.Submit(ctx, "Build a calculator")
+	if err != nil {
+		t.Fatalf("Failed to submit: %v", err)
+	}
+
+	if resp.Status != types.StatusCompleted {
+		t.Errorf("Expected status 'completed', got '%s'", resp.Status)
+	}
+
+	if provider.calls == 0 {
+		t.Error("Expected registered provider to be used")
+	}
+
+	if len(events) != 2 || events[0] != EventSubmitted || events[1] != EventCompleted {
+		t.Errorf("Expected [submitted completed] events, got %v", events)
+	}
+
+	unsubscribe()
+	if _, err := org.Submit(ctx, "Build another calculator"); err != nil {
+		t.Fatalf("Failed to submit: %v", err)
+	}
+
+	if len(events) != 2 {
+		t.Errorf("Expected no events after unsubscribe, got %v", events)
+	}
+
+	if org.President() == nil || !strings.HasPrefix(org.President().GetID(), "president") {
+		t.Error("Expected president agent to be available")
+	}
+}
+
+func TestNew_RequiresConfig(t *testing.T) {
+	if _, err := New(nil); err == nil {
+		t.Error("Expected error for nil config")
+	}
+}
+
+// blockingProvider blocks every generation until release is closed.
+type blockingProvider struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (p *blockingProvider) Generate(ctx context.Context, prompt string, opts *GenerateOptions) (string, error) {
+	select {
+	case p.started <- struct{}{}:
+	default:
+	}
+	<-p.release
+	return "stub implementation", nil
+}
+
+func (p *blockingProvider) Name() string {
+	return "stub"
+}
+
+func TestOrganization_SnapshotAndRestore(t *testing.T) {
+	dir := t.TempDir()
+	agentsDir := filepath.Join(dir, "agents")
+	if err := os.Mkdir(agentsDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &types.Config{
+		LLMs: types.LLMConfig{DefaultModel: "stub"},
+		Organization: types.OrganizationConfig{
+			Layers: []types.LayerConfig{
+				{Name: "President", Agent: writeAgentConfig(t, agentsDir, "president", "President")},
+				{Name: "Secretary", Agent: writeAgentConfig(t, agentsDir, "secretary", "Secretary"), AttachTo: []string{"President"}},
+				{Name: "Director", Agent: writeAgentConfig(t, agentsDir, "director", "Director")},
+				{Name: "Manager", Agent: writeAgentConfig(t, agentsDir, "manager", "Manager")},
+				{Name: "Engineer", Agent: writeAgentConfig(t, agentsDir, "engineer", "Engineer")},
+			},
+		},
+	}
+
+	provider := &blockingProvider{started: make(chan struct{}, 1), release: make(chan struct{})}
+	org, err := New(cfg, WithProvider("stub", provider))
+	if err != nil {
+		t.Fatalf("Failed to create organization: %v", err)
+	}
+
+	ctx := context.Background()
+	var runID string
+	org.Subscribe(func(e Event) {
+		if e.Type == EventSubmitted {
+			runID = e.RunID
+		}
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := org.Submit(ctx, "Build a calculator")
+		done <- err
+	}()
+
+	// Snapshot while the instruction is still being processed
+	select {
+	case <-provider.started:
+	case err := <-done:
+		t.Fatalf("Expected submission to block in the provider, finished with %v", err)
+	}
+	path := filepath.Join(dir, "org.snapshot.json")
+	if err := org.SaveSnapshot(path); err != nil {
+		t.Fatalf("Failed to save snapshot: %v", err)
+	}
+	close(provider.release)
+	if err := <-done; err != nil {
+		t.Fatalf("Failed to submit: %v", err)
+	}
+
+	// Agent definitions are embedded, so the original files are not needed
+	if err := os.RemoveAll(agentsDir); err != nil {
+		t.Fatal(err)
+	}
+
+	stub := &stubProvider{}
+	restored, err := Restore(path, WithProvider("stub", stub))
+	if err != nil {
+		t.Fatalf("Failed to restore organization: %v", err)
+	}
+
+	var resumedRun string
+	restored.Subscribe(func(e Event) {
+		if e.Type == EventCompleted {
+			resumedRun = e.RunID
+		}
+	})
+
+	responses, err := restored.ResumePending(ctx)
+	if err != nil {
+		t.Fatalf("Failed to resume: %v", err)
+	}
+
+	if len(responses) != 1 || stub.calls == 0 {
+		t.Fatalf("Expected the in-flight instruction to be resumed, got %d responses", len(responses))
+	}
+	if resumedRun == "" || resumedRun != runID {
+		t.Errorf("Expected resumed instruction to keep run ID %q, got %q", runID, resumedRun)
+	}
+
+	if responses, _ := restored.ResumePending(ctx); len(responses) != 0 {
+		t.Error("Expected pending instructions to be resumed only once")
+	}
+}
+
+func TestEventLog(t *testing.T) {
+	dir := t.TempDir()
+	open := func() *eventBus {
+		t.Helper()
+		log, err := openEventLog(dir)
+		if err != nil {
+			t.Fatalf("Failed to open event log: %v", err)
+		}
+		t.Cleanup(func() { log.close() })
+		return newEventBus(log)
+	}
+
+	bus := open()
+	bus.publish(Event{Type: EventSubmitted, TaskID: "t1"})
+	bus.publish(Event{Type: EventFailed, TaskID: "t1", Err: fmt.Errorf("provider down")})
+
+	var seen []Event
+	if _, err := bus.consume("tui", func(e Event) { seen = append(seen, e) }); err != nil {
+		t.Fatal(err)
+	}
+	bus.publish(Event{Type: EventSubmitted, TaskID: "t2"})
+	if len(seen) != 3 || seen[0].Offset != 1 || seen[2].Offset != 3 {
+		t.Fatalf("Expected offsets 1 to 3, got %+v", seen)
+	}
+	if seen[1].Err == nil || seen[1].Err.Error() != "provider down" {
+		t.Errorf("Expected the logged error to be replayed, got %v", seen[1].Err)
+	}
+
+	// A crash mid-write leaves a partial event that is discarded on restart
+	bus.log.close()
+	f, err := os.OpenFile(filepath.Join(dir, eventLogFile), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"time":"2026-`)
+	f.Close()
+
+	bus = open()
+	bus.publish(Event{Type: EventCompleted, TaskID: "t2"})
+
+	t.Run("ResumesFromOffset", func(t *testing.T) {
+		var resumed []Event
+		if _, err := bus.consume("tui", func(e Event) { resumed = append(resumed, e) }); err != nil {
+			t.Fatal(err)
+		}
+		if len(resumed) != 1 || resumed[0].Offset != 4 || resumed[0].Type != EventCompleted {
+			t.Errorf("Expected only event 4 after restart, got %+v", resumed)
+		}
+	})
+
+	t.Run("NewConsumerReplaysAll", func(t *testing.T) {
+		var all []Event
+		if _, err := bus.consume("analytics", func(e Event) { all = append(all, e) }); err != nil {
+			t.Fatal(err)
+		}
+		if len(all) != 4 {
+			t.Errorf("Expected 4 events, got %+v", all)
+		}
+	})
+
+	t.Run("RequiresLog", func(t *testing.T) {
+		if _, err := newEventBus(nil).consume("tui", func(Event) {}); err == nil {
+			t.Error("Expected an error without an event log")
+		}
+	})
+}
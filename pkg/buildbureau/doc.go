@@ -0,0 +1,45 @@
+// Package buildbureau is the supported public API for embedding a BuildBureau
+// organization in other Go programs.
+//
+// Everything else in this module lives under internal/ and may change without
+// notice. The identifiers exported from this package follow semantic
+// versioning: they are only removed or changed incompatibly in a new major
+// version.
+//
+// A minimal embedding looks like:
+//
+//	cfg, err := buildbureau.LoadConfig("config.yaml")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	org, err := buildbureau.New(cfg,
+//		buildbureau.WithProvider("my-llm", myProvider),
+//	)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	if err := org.Start(ctx); err != nil {
+//		log.Fatal(err)
+//	}
+//	defer org.Stop(ctx)
+//
+//	unsubscribe := org.Subscribe(func(e buildbureau.Event) {
+//		log.Printf("%s: %s", e.Type, e.TaskID)
+//	})
+//	defer unsubscribe()
+//
+//	resp, err := org.Submit(ctx, "Build a REST API for user management")
+//
+// Subscribe handlers only see events published while they are registered.
+// With WithEventLog, events are also written to a durable log, and Consume
+// registers a named consumer that first catches up on the events it missed,
+// even across restarts:
+//
+//	org, err := buildbureau.New(cfg, buildbureau.WithEventLog("data/events"))
+//	...
+//	stop, err := org.Consume("dashboard", func(e buildbureau.Event) {
+//		log.Printf("#%d %s: %s", e.Offset, e.Type, e.TaskID)
+//	})
+package buildbureau
@@ -0,0 +1,213 @@
+package buildbureau
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	eventLogFile    = "events.jsonl"
+	eventOffsetFile = "offsets.json"
+)
+
+// loggedEvent is the form of an Event in the event log.
+type loggedEvent struct {
+	Time        time.Time     `json:"time"`
+	Response    *TaskResponse `json:"response,omitempty"`
+	Type        EventType     `json:"type"`
+	TaskID      string        `json:"task_id,omitempty"`
+	RunID       string        `json:"run_id,omitempty"`
+	User        string        `json:"user,omitempty"`
+	Instruction string        `json:"instruction,omitempty"`
+	Error       string        `json:"error,omitempty"`
+	Offset      int64         `json:"offset"`
+	Position    int           `json:"position,omitempty"`
+}
+
+func toLogged(e Event) loggedEvent {
+	l := loggedEvent{
+		Time:        e.Time,
+		Response:    e.Response,
+		Type:        e.Type,
+		TaskID:      e.TaskID,
+		RunID:       e.RunID,
+		User:        e.User,
+		Instruction: e.Instruction,
+		Offset:      e.Offset,
+		Position:    e.Position,
+	}
+	if e.Err != nil {
+		l.Error = e.Err.Error()
+	}
+	return l
+}
+
+func (l loggedEvent) event() Event {
+	e := Event{
+		Time:        l.Time,
+		Response:    l.Response,
+		Type:        l.Type,
+		TaskID:      l.TaskID,
+		RunID:       l.RunID,
+		User:        l.User,
+		Instruction: l.Instruction,
+		Offset:      l.Offset,
+		Position:    l.Position,
+	}
+	if l.Error != "" {
+		e.Err = errors.New(l.Error)
+	}
+	return e
+}
+
+// eventLog is an append-only file of events, each numbered with an offset,
+// together with the offset each named consumer has processed up to. Events
+// are written before they are delivered, so they survive a crash and
+// consumers can resume after a restart.
+type eventLog struct {
+	file    *os.File
+	offsets map[string]int64
+	dir     string
+	next    int64
+	mu      sync.Mutex
+}
+
+// openEventLog opens the event log in dir, creating it if needed. A partial
+// event left by a crash mid-write is discarded.
+func openEventLog(dir string) (*eventLog, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create event log directory: %w", err)
+	}
+
+	file, err := os.OpenFile(filepath.Join(dir, eventLogFile), os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event log: %w", err)
+	}
+
+	l := &eventLog{file: file, dir: dir, next: 1, offsets: make(map[string]int64)}
+	end, err := l.scan(0, func(e loggedEvent) { l.next = e.Offset + 1 })
+	if err == nil {
+		err = file.Truncate(end)
+	}
+	if err == nil {
+		_, err = file.Seek(end, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read event log: %w", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, eventOffsetFile))
+	if err != nil && !os.IsNotExist(err) {
+		file.Close()
+		return nil, fmt.Errorf("failed to read consumer offsets: %w", err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &l.offsets); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to parse consumer offsets: %w", err)
+		}
+	}
+
+	return l, nil
+}
+
+// scan calls fn for every complete event with an offset after the given one,
+// and returns the file position just past the last complete event.
+func (l *eventLog) scan(after int64, fn func(loggedEvent)) (int64, error) {
+	dec := json.NewDecoder(io.NewSectionReader(l.file, 0, 1<<62))
+	var end int64
+	for {
+		var e loggedEvent
+		if err := dec.Decode(&e); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return end, nil
+			}
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				// A partial line written when the process died
+				return end, nil
+			}
+			return end, err
+		}
+		end = dec.InputOffset()
+		if e.Offset > after {
+			fn(e)
+		}
+	}
+}
+
+// append numbers an event and writes it to the log.
+func (l *eventLog) append(e *Event) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e.Offset = l.next
+	data, err := json.Marshal(toLogged(*e))
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	if _, err := l.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write event: %w", err)
+	}
+	l.next++
+	return nil
+}
+
+// since returns the events after the given offset, oldest first.
+func (l *eventLog) since(after int64) ([]Event, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var events []Event
+	if _, err := l.scan(after, func(e loggedEvent) { events = append(events, e.event()) }); err != nil {
+		return nil, fmt.Errorf("failed to read event log: %w", err)
+	}
+	return events, nil
+}
+
+// offset returns the offset of the last event the consumer processed.
+func (l *eventLog) offset(consumer string) int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.offsets[consumer]
+}
+
+// commit records that the consumer has processed events up to offset.
+func (l *eventLog) commit(consumer string, offset int64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if offset <= l.offsets[consumer] {
+		return nil
+	}
+	l.offsets[consumer] = offset
+
+	data, err := json.MarshalIndent(l.offsets, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal consumer offsets: %w", err)
+	}
+	path := filepath.Join(l.dir, eventOffsetFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write consumer offsets: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to write consumer offsets: %w", err)
+	}
+	return nil
+}
+
+// close closes the log file.
+func (l *eventLog) close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
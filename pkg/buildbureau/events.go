@@ -0,0 +1,126 @@
+package buildbureau
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// EventType identifies the kind of organization event.
+type EventType string
+
+const (
+	// EventSubmitted is emitted when an instruction is submitted.
+	EventSubmitted EventType = "submitted"
+	// EventQueued is emitted when a submission waits for admission, and again
+	// whenever its position in the queue changes.
+	EventQueued EventType = "queued"
+	// EventCompleted is emitted when an instruction finishes successfully.
+	EventCompleted EventType = "completed"
+	// EventFailed is emitted when an instruction fails.
+	EventFailed EventType = "failed"
+)
+
+// Event describes something that happened in an organization.
+type Event struct {
+	Time        time.Time
+	Err         error
+	Response    *TaskResponse
+	Type        EventType
+	TaskID      string
+	RunID       string // Links the event to memories recorded during the run
+	User        string // Who submitted the project, if known
+	Instruction string
+	Position    int   // Queue position of a queued submission; 1 is next
+	Offset      int64 // Position in the event log, from 1; 0 without an event log
+}
+
+// eventBus fans events out to subscribers, first writing them to the event
+// log if there is one.
+type eventBus struct {
+	log      *eventLog
+	handlers map[int]func(Event)
+	mu       sync.Mutex
+	nextID   int
+}
+
+func newEventBus(log *eventLog) *eventBus {
+	return &eventBus{
+		log:      log,
+		handlers: make(map[int]func(Event)),
+	}
+}
+
+// subscribe adds a handler and returns a function that removes it.
+func (b *eventBus) subscribe(handler func(Event)) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.add(handler)
+}
+
+// add registers a handler. The caller must hold b.mu.
+func (b *eventBus) add(handler func(Event)) func() {
+	id := b.nextID
+	b.nextID++
+	b.handlers[id] = handler
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.handlers, id)
+	}
+}
+
+// consume delivers the logged events the consumer has not processed, then
+// new events as they are published, saving the consumer's offset after each.
+func (b *eventBus) consume(consumer string, handler func(Event)) (func(), error) {
+	if b.log == nil {
+		return nil, fmt.Errorf("consuming events requires an event log; use WithEventLog")
+	}
+	if consumer == "" {
+		return nil, fmt.Errorf("consumer name is required")
+	}
+
+	deliver := func(event Event) {
+		handler(event)
+		if err := b.log.commit(consumer, event.Offset); err != nil {
+			fmt.Printf("Warning: failed to save offset of event consumer %s: %v\n", consumer, err)
+		}
+	}
+
+	// Holding the lock keeps events from being published between the replay and the subscription
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	missed, err := b.log.since(b.log.offset(consumer))
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range missed {
+		deliver(event)
+	}
+
+	return b.add(deliver), nil
+}
+
+// publish logs an event and delivers it to every subscriber in the order
+// events were published.
+func (b *eventBus) publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.log != nil {
+		if err := b.log.append(&event); err != nil {
+			fmt.Printf("Warning: failed to log %s event: %v\n", event.Type, err)
+		}
+	}
+
+	for _, handler := range b.handlers {
+		handler(event)
+	}
+}
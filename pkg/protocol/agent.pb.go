@@ -444,6 +444,160 @@ func (x *NotificationResponse) GetError() string {
 	return ""
 }
 
+// DescribeAgentRequest asks an agent to describe itself
+type DescribeAgentRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AgentId       string                 `protobuf:"bytes,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DescribeAgentRequest) Reset() {
+	*x = DescribeAgentRequest{}
+	mi := &file_pkg_protocol_agent_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DescribeAgentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DescribeAgentRequest) ProtoMessage() {}
+
+func (x *DescribeAgentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_protocol_agent_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DescribeAgentRequest.ProtoReflect.Descriptor instead.
+func (*DescribeAgentRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_protocol_agent_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *DescribeAgentRequest) GetAgentId() string {
+	if x != nil {
+		return x.AgentId
+	}
+	return ""
+}
+
+// AgentDescription reports what an agent can do and how busy it is
+type AgentDescription struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	AgentId        string                 `protobuf:"bytes,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	Role           string                 `protobuf:"bytes,2,opt,name=role,proto3" json:"role,omitempty"`
+	Model          string                 `protobuf:"bytes,3,opt,name=model,proto3" json:"model,omitempty"`
+	Team           string                 `protobuf:"bytes,4,opt,name=team,proto3" json:"team,omitempty"`
+	Capabilities   []string               `protobuf:"bytes,5,rep,name=capabilities,proto3" json:"capabilities,omitempty"`
+	Status         string                 `protobuf:"bytes,6,opt,name=status,proto3" json:"status,omitempty"`
+	ActiveTasks    int32                  `protobuf:"varint,7,opt,name=active_tasks,json=activeTasks,proto3" json:"active_tasks,omitempty"`
+	CompletedTasks int32                  `protobuf:"varint,8,opt,name=completed_tasks,json=completedTasks,proto3" json:"completed_tasks,omitempty"`
+	Version        string                 `protobuf:"bytes,9,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *AgentDescription) Reset() {
+	*x = AgentDescription{}
+	mi := &file_pkg_protocol_agent_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AgentDescription) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AgentDescription) ProtoMessage() {}
+
+func (x *AgentDescription) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_protocol_agent_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AgentDescription.ProtoReflect.Descriptor instead.
+func (*AgentDescription) Descriptor() ([]byte, []int) {
+	return file_pkg_protocol_agent_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *AgentDescription) GetAgentId() string {
+	if x != nil {
+		return x.AgentId
+	}
+	return ""
+}
+
+func (x *AgentDescription) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+func (x *AgentDescription) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *AgentDescription) GetTeam() string {
+	if x != nil {
+		return x.Team
+	}
+	return ""
+}
+
+func (x *AgentDescription) GetCapabilities() []string {
+	if x != nil {
+		return x.Capabilities
+	}
+	return nil
+}
+
+func (x *AgentDescription) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *AgentDescription) GetActiveTasks() int32 {
+	if x != nil {
+		return x.ActiveTasks
+	}
+	return 0
+}
+
+func (x *AgentDescription) GetCompletedTasks() int32 {
+	if x != nil {
+		return x.CompletedTasks
+	}
+	return 0
+}
+
+func (x *AgentDescription) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
 var File_pkg_protocol_agent_proto protoreflect.FileDescriptor
 
 const file_pkg_protocol_agent_proto_rawDesc = "" +
@@ -490,11 +644,24 @@ const file_pkg_protocol_agent_proto_rawDesc = "" +
 	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"P\n" +
 	"\x14NotificationResponse\x12\"\n" +
 	"\facknowledged\x18\x01 \x01(\bR\facknowledged\x12\x14\n" +
-	"\x05error\x18\x02 \x01(\tR\x05error2\xd5\x01\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"1\n" +
+	"\x14DescribeAgentRequest\x12\x19\n" +
+	"\bagent_id\x18\x01 \x01(\tR\aagentId\"\x8d\x02\n" +
+	"\x10AgentDescription\x12\x19\n" +
+	"\bagent_id\x18\x01 \x01(\tR\aagentId\x12\x12\n" +
+	"\x04role\x18\x02 \x01(\tR\x04role\x12\x14\n" +
+	"\x05model\x18\x03 \x01(\tR\x05model\x12\x12\n" +
+	"\x04team\x18\x04 \x01(\tR\x04team\x12\"\n" +
+	"\fcapabilities\x18\x05 \x03(\tR\fcapabilities\x12\x16\n" +
+	"\x06status\x18\x06 \x01(\tR\x06status\x12!\n" +
+	"\factive_tasks\x18\a \x01(\x05R\vactiveTasks\x12'\n" +
+	"\x0fcompleted_tasks\x18\b \x01(\x05R\x0ecompletedTasks\x12\x18\n" +
+	"\aversion\x18\t \x01(\tR\aversion2\xa2\x02\n" +
 	"\fAgentService\x12<\n" +
 	"\vProcessTask\x12\x15.protocol.TaskRequest\x1a\x16.protocol.TaskResponse\x12>\n" +
 	"\tGetStatus\x12\x17.protocol.StatusRequest\x1a\x18.protocol.StatusResponse\x12G\n" +
-	"\x06Notify\x12\x1d.protocol.NotificationRequest\x1a\x1e.protocol.NotificationResponseB,Z*github.com/kpango/BuildBureau/pkg/protocolb\x06proto3"
+	"\x06Notify\x12\x1d.protocol.NotificationRequest\x1a\x1e.protocol.NotificationResponse\x12K\n" +
+	"\rDescribeAgent\x12\x1e.protocol.DescribeAgentRequest\x1a\x1a.protocol.AgentDescriptionB,Z*github.com/kpango/BuildBureau/pkg/protocolb\x06proto3"
 
 var (
 	file_pkg_protocol_agent_proto_rawDescOnce sync.Once
@@ -508,7 +675,7 @@ func file_pkg_protocol_agent_proto_rawDescGZIP() []byte {
 	return file_pkg_protocol_agent_proto_rawDescData
 }
 
-var file_pkg_protocol_agent_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_pkg_protocol_agent_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
 var file_pkg_protocol_agent_proto_goTypes = []any{
 	(*TaskRequest)(nil),          // 0: protocol.TaskRequest
 	(*TaskResponse)(nil),         // 1: protocol.TaskResponse
@@ -516,25 +683,29 @@ var file_pkg_protocol_agent_proto_goTypes = []any{
 	(*StatusResponse)(nil),       // 3: protocol.StatusResponse
 	(*NotificationRequest)(nil),  // 4: protocol.NotificationRequest
 	(*NotificationResponse)(nil), // 5: protocol.NotificationResponse
-	nil,                          // 6: protocol.TaskRequest.MetadataEntry
-	nil,                          // 7: protocol.TaskResponse.MetadataEntry
-	nil,                          // 8: protocol.NotificationRequest.MetadataEntry
+	(*DescribeAgentRequest)(nil), // 6: protocol.DescribeAgentRequest
+	(*AgentDescription)(nil),     // 7: protocol.AgentDescription
+	nil,                          // 8: protocol.TaskRequest.MetadataEntry
+	nil,                          // 9: protocol.TaskResponse.MetadataEntry
+	nil,                          // 10: protocol.NotificationRequest.MetadataEntry
 }
 var file_pkg_protocol_agent_proto_depIdxs = []int32{
-	6, // 0: protocol.TaskRequest.metadata:type_name -> protocol.TaskRequest.MetadataEntry
-	7, // 1: protocol.TaskResponse.metadata:type_name -> protocol.TaskResponse.MetadataEntry
-	8, // 2: protocol.NotificationRequest.metadata:type_name -> protocol.NotificationRequest.MetadataEntry
-	0, // 3: protocol.AgentService.ProcessTask:input_type -> protocol.TaskRequest
-	2, // 4: protocol.AgentService.GetStatus:input_type -> protocol.StatusRequest
-	4, // 5: protocol.AgentService.Notify:input_type -> protocol.NotificationRequest
-	1, // 6: protocol.AgentService.ProcessTask:output_type -> protocol.TaskResponse
-	3, // 7: protocol.AgentService.GetStatus:output_type -> protocol.StatusResponse
-	5, // 8: protocol.AgentService.Notify:output_type -> protocol.NotificationResponse
-	6, // [6:9] is the sub-list for method output_type
-	3, // [3:6] is the sub-list for method input_type
-	3, // [3:3] is the sub-list for extension type_name
-	3, // [3:3] is the sub-list for extension extendee
-	0, // [0:3] is the sub-list for field type_name
+	8,  // 0: protocol.TaskRequest.metadata:type_name -> protocol.TaskRequest.MetadataEntry
+	9,  // 1: protocol.TaskResponse.metadata:type_name -> protocol.TaskResponse.MetadataEntry
+	10, // 2: protocol.NotificationRequest.metadata:type_name -> protocol.NotificationRequest.MetadataEntry
+	0,  // 3: protocol.AgentService.ProcessTask:input_type -> protocol.TaskRequest
+	2,  // 4: protocol.AgentService.GetStatus:input_type -> protocol.StatusRequest
+	4,  // 5: protocol.AgentService.Notify:input_type -> protocol.NotificationRequest
+	6,  // 6: protocol.AgentService.DescribeAgent:input_type -> protocol.DescribeAgentRequest
+	1,  // 7: protocol.AgentService.ProcessTask:output_type -> protocol.TaskResponse
+	3,  // 8: protocol.AgentService.GetStatus:output_type -> protocol.StatusResponse
+	5,  // 9: protocol.AgentService.Notify:output_type -> protocol.NotificationResponse
+	7,  // 10: protocol.AgentService.DescribeAgent:output_type -> protocol.AgentDescription
+	7,  // [7:11] is the sub-list for method output_type
+	3,  // [3:7] is the sub-list for method input_type
+	3,  // [3:3] is the sub-list for extension type_name
+	3,  // [3:3] is the sub-list for extension extendee
+	0,  // [0:3] is the sub-list for field type_name
 }
 
 func init() { file_pkg_protocol_agent_proto_init() }
@@ -548,7 +719,7 @@ func file_pkg_protocol_agent_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pkg_protocol_agent_proto_rawDesc), len(file_pkg_protocol_agent_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   9,
+			NumMessages:   11,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -20,9 +20,10 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	AgentService_ProcessTask_FullMethodName = "/protocol.AgentService/ProcessTask"
-	AgentService_GetStatus_FullMethodName   = "/protocol.AgentService/GetStatus"
-	AgentService_Notify_FullMethodName      = "/protocol.AgentService/Notify"
+	AgentService_ProcessTask_FullMethodName   = "/protocol.AgentService/ProcessTask"
+	AgentService_GetStatus_FullMethodName     = "/protocol.AgentService/GetStatus"
+	AgentService_Notify_FullMethodName        = "/protocol.AgentService/Notify"
+	AgentService_DescribeAgent_FullMethodName = "/protocol.AgentService/DescribeAgent"
 )
 
 // AgentServiceClient is the client API for AgentService service.
@@ -37,6 +38,8 @@ type AgentServiceClient interface {
 	GetStatus(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*StatusResponse, error)
 	// Notify sends a notification to an agent
 	Notify(ctx context.Context, in *NotificationRequest, opts ...grpc.CallOption) (*NotificationResponse, error)
+	// DescribeAgent reports an agent's role, model, capabilities, load and version
+	DescribeAgent(ctx context.Context, in *DescribeAgentRequest, opts ...grpc.CallOption) (*AgentDescription, error)
 }
 
 type agentServiceClient struct {
@@ -77,6 +80,16 @@ func (c *agentServiceClient) Notify(ctx context.Context, in *NotificationRequest
 	return out, nil
 }
 
+func (c *agentServiceClient) DescribeAgent(ctx context.Context, in *DescribeAgentRequest, opts ...grpc.CallOption) (*AgentDescription, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AgentDescription)
+	err := c.cc.Invoke(ctx, AgentService_DescribeAgent_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // AgentServiceServer is the server API for AgentService service.
 // All implementations must embed UnimplementedAgentServiceServer
 // for forward compatibility.
@@ -89,6 +102,8 @@ type AgentServiceServer interface {
 	GetStatus(context.Context, *StatusRequest) (*StatusResponse, error)
 	// Notify sends a notification to an agent
 	Notify(context.Context, *NotificationRequest) (*NotificationResponse, error)
+	// DescribeAgent reports an agent's role, model, capabilities, load and version
+	DescribeAgent(context.Context, *DescribeAgentRequest) (*AgentDescription, error)
 	mustEmbedUnimplementedAgentServiceServer()
 }
 
@@ -108,6 +123,9 @@ func (UnimplementedAgentServiceServer) GetStatus(context.Context, *StatusRequest
 func (UnimplementedAgentServiceServer) Notify(context.Context, *NotificationRequest) (*NotificationResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method Notify not implemented")
 }
+func (UnimplementedAgentServiceServer) DescribeAgent(context.Context, *DescribeAgentRequest) (*AgentDescription, error) {
+	return nil, status.Error(codes.Unimplemented, "method DescribeAgent not implemented")
+}
 func (UnimplementedAgentServiceServer) mustEmbedUnimplementedAgentServiceServer() {}
 func (UnimplementedAgentServiceServer) testEmbeddedByValue()                      {}
 
@@ -183,6 +201,24 @@ func _AgentService_Notify_Handler(srv interface{}, ctx context.Context, dec func
 	return interceptor(ctx, in, info, handler)
 }
 
+func _AgentService_DescribeAgent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DescribeAgentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).DescribeAgent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AgentService_DescribeAgent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).DescribeAgent(ctx, req.(*DescribeAgentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // AgentService_ServiceDesc is the grpc.ServiceDesc for AgentService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -202,6 +238,10 @@ var AgentService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Notify",
 			Handler:    _AgentService_Notify_Handler,
 		},
+		{
+			MethodName: "DescribeAgent",
+			Handler:    _AgentService_DescribeAgent_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "pkg/protocol/agent.proto",
@@ -0,0 +1,34 @@
+// Package remoteagent defines the HTTP protocol BuildBureau uses to talk to
+// remote LLM backends, and helpers for implementing compliant servers in Go.
+//
+// A backend serves three endpoints:
+//
+//	POST /v1/generate         GenerateRequest → GenerateResponse
+//	POST /v1/generate/stream  GenerateRequest → Server-Sent Events
+//	GET  /v1/status           StatusResponse
+//
+// Clients send the protocol versions they understand in the
+// BuildBureau-Protocol-Version header, most preferred first. The server
+// answers with the version it chose in the same header, or 400 with the
+// versions it supports in BuildBureau-Protocol-Supported. A request without
+// the header is treated as version 1, so backends written against the
+// original ad-hoc contract keep working.
+//
+// Errors are returned as an ErrorResponse with a non-2xx status. Clients
+// treat 429 as rate limiting and 503 as temporary unavailability.
+//
+// The stream endpoint emits "delta" events whose data is a StreamDelta,
+// followed by exactly one "done" event carrying the final GenerateResponse
+// or an "error" event carrying an ErrorResponse.
+//
+// A minimal Go backend looks like:
+//
+//	gen := remoteagent.GeneratorFunc(func(ctx context.Context, req *remoteagent.GenerateRequest) (*remoteagent.GenerateResponse, error) {
+//		return &remoteagent.GenerateResponse{Result: callMyModel(ctx, req.Prompt)}, nil
+//	})
+//
+//	handler := remoteagent.NewHandler(gen, remoteagent.WithModel("my-model"))
+//	log.Fatal(http.ListenAndServe(":8080", remoteagent.RequireBearerToken(token)(handler)))
+//
+// The identifiers in this package follow semantic versioning, like pkg/buildbureau.
+package remoteagent
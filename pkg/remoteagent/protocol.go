@@ -0,0 +1,99 @@
+package remoteagent
+
+import (
+	"strings"
+)
+
+const (
+	// Version1 is the original /v1/generate contract.
+	Version1 = "1"
+
+	// CurrentVersion is the newest protocol version.
+	CurrentVersion = Version1
+
+	// HeaderVersion carries the requested versions on requests and the chosen one on responses.
+	HeaderVersion = "BuildBureau-Protocol-Version"
+	// HeaderSupported lists the versions a server supports when negotiation fails.
+	HeaderSupported = "BuildBureau-Protocol-Supported"
+
+	PathGenerate = "/v1/generate"
+	PathStream   = "/v1/generate/stream"
+	PathStatus   = "/v1/status"
+
+	// Stream event names.
+	EventDelta = "delta"
+	EventDone  = "done"
+	EventError = "error"
+)
+
+// SupportedVersions lists the protocol versions this package implements, newest first.
+var SupportedVersions = []string{Version1}
+
+// GenerateRequest asks a backend to generate text.
+type GenerateRequest struct {
+	Prompt       string  `json:"prompt"`
+	Model        string  `json:"model,omitempty"`
+	SystemPrompt string  `json:"system_prompt,omitempty"`
+	Seed         *int    `json:"seed,omitempty"` // Sampling seed, for backends that support reproducible sampling
+	Temperature  float64 `json:"temperature"`    // Always sent, so 0 is not mistaken for unset
+	MaxTokens    int     `json:"max_tokens,omitempty"`
+}
+
+// FinishLength is the FinishReason of a generation that stopped at MaxTokens.
+const FinishLength = "length"
+
+// GenerateResponse is the result of a generation.
+// A non-empty Error reports a failure even with a 200 status, for older backends.
+type GenerateResponse struct {
+	Usage        *Usage `json:"usage,omitempty"`
+	Result       string `json:"result"`
+	Model        string `json:"model,omitempty"`
+	Error        string `json:"error,omitempty"`
+	FinishReason string `json:"finish_reason,omitempty"` // FinishLength if output stopped at MaxTokens; clients may ask for the rest
+}
+
+// Usage reports token consumption for a generation.
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens,omitempty"`
+	CompletionTokens int `json:"completion_tokens,omitempty"`
+	TotalTokens      int `json:"total_tokens,omitempty"`
+}
+
+// StatusResponse describes a backend.
+type StatusResponse struct {
+	Status       string   `json:"status"`
+	Model        string   `json:"model,omitempty"`
+	Capabilities []string `json:"capabilities,omitempty"`
+	Versions     []string `json:"versions,omitempty"`
+}
+
+// StreamDelta is the data of a "delta" stream event.
+type StreamDelta struct {
+	Text string `json:"text"`
+}
+
+// ErrorResponse is the body of a non-2xx response and the data of an "error" stream event.
+type ErrorResponse struct {
+	Error     string   `json:"error"`
+	Supported []string `json:"supported,omitempty"`
+}
+
+// Negotiate picks the first version in the client's header value that the
+// server supports. An empty header selects Version1 for compatibility with
+// clients that predate negotiation.
+func Negotiate(header string, supported []string) (string, bool) {
+	if strings.TrimSpace(header) == "" {
+		header = Version1
+	}
+
+	for _, requested := range strings.Split(header, ",") {
+		requested = strings.TrimSpace(requested)
+		for _, v := range supported {
+			if requested == v {
+				return v, true
+			}
+		}
+	}
+
+	return "", false
+}
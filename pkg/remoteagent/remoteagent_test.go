@@ -0,0 +1,258 @@
+package remoteagent_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kpango/BuildBureau/internal/errors"
+	"github.com/kpango/BuildBureau/internal/llm"
+	"github.com/kpango/BuildBureau/pkg/remoteagent"
+)
+
+// echo is a Generator that returns the prompt, streaming it word by word.
+type echo struct{}
+
+func (echo) Generate(ctx context.Context, req *remoteagent.GenerateRequest) (*remoteagent.GenerateResponse, error) {
+	if req.Prompt == "limit" {
+		return nil, remoteagent.NewError(http.StatusTooManyRequests, "slow down")
+	}
+	return &remoteagent.GenerateResponse{Result: "echo: " + req.Prompt}, nil
+}
+
+func (e echo) GenerateStream(ctx context.Context, req *remoteagent.GenerateRequest, emit func(string) error) (*remoteagent.GenerateResponse, error) {
+	for _, word := range strings.Fields(req.Prompt) {
+		if err := emit(word + " "); err != nil {
+			return nil, err
+		}
+	}
+	return e.Generate(ctx, req)
+}
+
+func post(t *testing.T, url, version, body string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != "" {
+		req.Header.Set(remoteagent.HeaderVersion, version)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestWireFormat(t *testing.T) {
+	// Field names are the public contract; changing them breaks third-party backends
+	req := remoteagent.GenerateRequest{Prompt: "p", Model: "m", SystemPrompt: "s", Temperature: 0.5, MaxTokens: 10}
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"prompt":"p","model":"m","system_prompt":"s","temperature":0.5,"max_tokens":10}`
+	if string(data) != want {
+		t.Errorf("Request encoding changed:\n got %s\nwant %s", data, want)
+	}
+
+	// Backends written against the original contract send usage as a loose object
+	var resp remoteagent.GenerateResponse
+	legacy := `{"result":"r","model":"m","usage":{"prompt_tokens":1,"completion_tokens":2,"total_tokens":3}}`
+	if err := json.Unmarshal([]byte(legacy), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Result != "r" || resp.Usage == nil || resp.Usage.TotalTokens != 3 {
+		t.Errorf("Failed to decode legacy response: %+v", resp)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	supported := []string{"2", "1"}
+
+	tests := map[string]string{
+		"":     "1",
+		"1":    "1",
+		"3, 2": "2",
+		"3,1":  "1",
+	}
+	for header, want := range tests {
+		if got, ok := remoteagent.Negotiate(header, supported); !ok || got != want {
+			t.Errorf("Negotiate(%q) = %q, %v; want %q", header, got, ok, want)
+		}
+	}
+
+	if _, ok := remoteagent.Negotiate("9", supported); ok {
+		t.Error("Expected unsupported version to fail negotiation")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	srv := httptest.NewServer(remoteagent.NewHandler(echo{}, remoteagent.WithModel("echo-model")))
+	defer srv.Close()
+
+	t.Run("LegacyClient", func(t *testing.T) {
+		resp := post(t, srv.URL+remoteagent.PathGenerate, "", `{"prompt":"hi"}`)
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", resp.StatusCode)
+		}
+		if v := resp.Header.Get(remoteagent.HeaderVersion); v != remoteagent.Version1 {
+			t.Errorf("Expected version %s, got %q", remoteagent.Version1, v)
+		}
+
+		var out remoteagent.GenerateResponse
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			t.Fatal(err)
+		}
+		if out.Result != "echo: hi" || out.Model != "echo-model" {
+			t.Errorf("Unexpected response: %+v", out)
+		}
+	})
+
+	t.Run("UnsupportedVersion", func(t *testing.T) {
+		resp := post(t, srv.URL+remoteagent.PathGenerate, "99", `{"prompt":"hi"}`)
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("Expected 400, got %d", resp.StatusCode)
+		}
+		if resp.Header.Get(remoteagent.HeaderSupported) == "" {
+			t.Error("Expected supported versions header")
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		resp := post(t, srv.URL+remoteagent.PathGenerate, "", `{"prompt":""}`)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected 400 for empty prompt, got %d", resp.StatusCode)
+		}
+
+		resp = post(t, srv.URL+remoteagent.PathGenerate, "", `{"prompt":"limit"}`)
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusTooManyRequests {
+			t.Errorf("Expected 429, got %d", resp.StatusCode)
+		}
+
+		var out remoteagent.ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil || out.Error != "slow down" {
+			t.Errorf("Expected error body, got %+v (%v)", out, err)
+		}
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		resp := post(t, srv.URL+remoteagent.PathStream, remoteagent.CurrentVersion, `{"prompt":"one two three"}`)
+		defer resp.Body.Close()
+
+		if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+			t.Errorf("Expected event stream, got %q", ct)
+		}
+
+		var streamed bytes.Buffer
+		out, err := remoteagent.ReadStream(resp.Body, func(text string) error {
+			streamed.WriteString(text)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if streamed.String() != "one two three " {
+			t.Errorf("Unexpected deltas: %q", streamed.String())
+		}
+		if out.Result != "echo: one two three" {
+			t.Errorf("Unexpected final response: %+v", out)
+		}
+	})
+
+	t.Run("StreamWithoutStreamGenerator", func(t *testing.T) {
+		gen := remoteagent.GeneratorFunc(func(ctx context.Context, req *remoteagent.GenerateRequest) (*remoteagent.GenerateResponse, error) {
+			return &remoteagent.GenerateResponse{Result: "whole"}, nil
+		})
+		plain := httptest.NewServer(remoteagent.NewHandler(gen))
+		defer plain.Close()
+
+		resp := post(t, plain.URL+remoteagent.PathStream, "", `{"prompt":"x"}`)
+		defer resp.Body.Close()
+
+		var deltas []string
+		out, err := remoteagent.ReadStream(resp.Body, func(text string) error {
+			deltas = append(deltas, text)
+			return nil
+		})
+		if err != nil || out.Result != "whole" || len(deltas) != 1 {
+			t.Errorf("Expected single delta and result, got %v %+v %v", deltas, out, err)
+		}
+	})
+
+	t.Run("Status", func(t *testing.T) {
+		resp, err := http.Get(srv.URL + remoteagent.PathStatus)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		var out remoteagent.StatusResponse
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			t.Fatal(err)
+		}
+		if out.Status != "ready" || out.Model != "echo-model" || len(out.Versions) == 0 {
+			t.Errorf("Unexpected status: %+v", out)
+		}
+	})
+}
+
+func TestRequireBearerToken(t *testing.T) {
+	srv := httptest.NewServer(remoteagent.RequireBearerToken("secret")(remoteagent.NewHandler(echo{})))
+	defer srv.Close()
+
+	resp := post(t, srv.URL+remoteagent.PathGenerate, "", `{"prompt":"hi"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without token, got %d", resp.StatusCode)
+	}
+
+	resp, err := http.Get(srv.URL + remoteagent.PathStatus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected public status endpoint, got %d", resp.StatusCode)
+	}
+}
+
+// TestRemoteProviderCompatibility checks BuildBureau's own client against the server helpers.
+func TestRemoteProviderCompatibility(t *testing.T) {
+	srv := httptest.NewServer(remoteagent.RequireBearerToken("secret")(remoteagent.NewHandler(echo{})))
+	defer srv.Close()
+
+	provider, err := llm.NewRemoteProvider("echo", srv.URL, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := provider.Generate(context.Background(), "hello", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "echo: hello" {
+		t.Errorf("Unexpected result: %q", result)
+	}
+
+	_, err = provider.Generate(context.Background(), "limit", nil)
+	if errors.CodeOf(err) != errors.CodeLLMRateLimit {
+		t.Errorf("Expected rate limit error, got %v", err)
+	}
+	if err == nil || !strings.Contains(err.Error(), "slow down") {
+		t.Errorf("Expected server message in error, got %v", err)
+	}
+}
@@ -0,0 +1,240 @@
+package remoteagent
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// maxRequestBytes bounds the size of a GenerateRequest body.
+const maxRequestBytes = 10 << 20
+
+// Generator produces a response for a request. Implementations return an *Error
+// to control the HTTP status sent to the client.
+type Generator interface {
+	Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error)
+}
+
+// GeneratorFunc adapts a function to the Generator interface.
+type GeneratorFunc func(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error)
+
+// Generate calls f.
+func (f GeneratorFunc) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	return f(ctx, req)
+}
+
+// StreamGenerator is a Generator that can emit output incrementally.
+// Generators that do not implement it are streamed as a single delta.
+type StreamGenerator interface {
+	Generator
+	GenerateStream(ctx context.Context, req *GenerateRequest, emit func(text string) error) (*GenerateResponse, error)
+}
+
+// Error is a generation failure with the HTTP status to report it with.
+type Error struct {
+	Message string
+	Status  int
+}
+
+// NewError creates an Error with a formatted message.
+func NewError(status int, format string, args ...any) *Error {
+	return &Error{Status: status, Message: fmt.Sprintf(format, args...)}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// HandlerOption configures the handler created by NewHandler.
+type HandlerOption func(*handler)
+
+// WithModel sets the model name reported by /v1/status and used for responses
+// that do not set one.
+func WithModel(model string) HandlerOption {
+	return func(h *handler) {
+		h.model = model
+	}
+}
+
+// WithCapabilities sets the capabilities reported by /v1/status.
+func WithCapabilities(capabilities ...string) HandlerOption {
+	return func(h *handler) {
+		h.capabilities = capabilities
+	}
+}
+
+type handler struct {
+	gen          Generator
+	model        string
+	capabilities []string
+}
+
+// NewHandler returns an http.Handler serving the remote agent protocol for gen,
+// including version negotiation.
+func NewHandler(gen Generator, opts ...HandlerOption) http.Handler {
+	h := &handler{
+		gen:          gen,
+		capabilities: []string{"text-generation"},
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(PathGenerate, h.generate)
+	mux.HandleFunc(PathStream, h.stream)
+	mux.HandleFunc(PathStatus, h.status)
+
+	return Negotiation(SupportedVersions...)(mux)
+}
+
+// Negotiation rejects requests for protocol versions not in supported and
+// reports the chosen version on responses.
+func Negotiation(supported ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			version, ok := Negotiate(r.Header.Get(HeaderVersion), supported)
+			if !ok {
+				w.Header().Set(HeaderSupported, strings.Join(supported, ", "))
+				writeError(w, http.StatusBadRequest, ErrorResponse{
+					Error:     fmt.Sprintf("unsupported protocol version %q", r.Header.Get(HeaderVersion)),
+					Supported: supported,
+				})
+				return
+			}
+
+			w.Header().Set(HeaderVersion, version)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireBearerToken rejects requests without "Authorization: Bearer <token>".
+// The status endpoint stays public so health checks need no credentials.
+func RequireBearerToken(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != PathStatus && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+				writeError(w, http.StatusUnauthorized, ErrorResponse{Error: "invalid or missing bearer token"})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// decode reads and validates a GenerateRequest, writing an error response on failure.
+func (h *handler) decode(w http.ResponseWriter, r *http.Request) (*GenerateRequest, bool) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, ErrorResponse{Error: "method not allowed"})
+		return nil, false
+	}
+
+	var req GenerateRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("invalid request: %v", err)})
+		return nil, false
+	}
+
+	if req.Prompt == "" {
+		writeError(w, http.StatusBadRequest, ErrorResponse{Error: "prompt is required"})
+		return nil, false
+	}
+
+	return &req, true
+}
+
+func (h *handler) generate(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.decode(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := h.gen.Generate(r.Context(), req)
+	if err != nil {
+		writeError(w, statusOf(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.complete(resp))
+}
+
+func (h *handler) stream(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.decode(w, r)
+	if !ok {
+		return
+	}
+
+	sse, ok := newEventWriter(w)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, ErrorResponse{Error: "streaming not supported"})
+		return
+	}
+
+	emit := func(text string) error {
+		return sse.event(EventDelta, StreamDelta{Text: text})
+	}
+
+	var resp *GenerateResponse
+	var err error
+	if streamer, ok := h.gen.(StreamGenerator); ok {
+		resp, err = streamer.GenerateStream(r.Context(), req, emit)
+	} else if resp, err = h.gen.Generate(r.Context(), req); err == nil {
+		err = emit(resp.Result)
+	}
+
+	if err != nil {
+		_ = sse.event(EventError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	_ = sse.event(EventDone, h.complete(resp))
+}
+
+func (h *handler) status(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, StatusResponse{
+		Status:       "ready",
+		Model:        h.model,
+		Capabilities: h.capabilities,
+		Versions:     SupportedVersions,
+	})
+}
+
+// complete fills in response fields the generator left empty.
+func (h *handler) complete(resp *GenerateResponse) *GenerateResponse {
+	if resp == nil {
+		resp = &GenerateResponse{}
+	}
+	if resp.Model == "" {
+		resp.Model = h.model
+	}
+	return resp
+}
+
+// statusOf maps a generator error to an HTTP status.
+func statusOf(err error) int {
+	var e *Error
+	switch {
+	case errors.As(err, &e) && e.Status != 0:
+		return e.Status
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, resp ErrorResponse) {
+	writeJSON(w, status, resp)
+}
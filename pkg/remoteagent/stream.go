@@ -0,0 +1,115 @@
+package remoteagent
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// eventWriter writes Server-Sent Events, flushing after each one.
+type eventWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+// newEventWriter prepares w for an event stream, or reports false if w cannot flush.
+func newEventWriter(w http.ResponseWriter) (*eventWriter, bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, false
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	return &eventWriter{w: w, flusher: flusher}, true
+}
+
+// event writes one event with v encoded as JSON data.
+func (e *eventWriter) event(name string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(e.w, "event: %s\ndata: %s\n\n", name, data); err != nil {
+		return err
+	}
+	e.flusher.Flush()
+
+	return nil
+}
+
+// ReadStream consumes a /v1/generate/stream response body, calling onDelta
+// for each chunk of text, and returns the final response. onDelta may be nil.
+func ReadStream(r io.Reader, onDelta func(text string) error) (*GenerateResponse, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRequestBytes)
+
+	var name string
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case line == "":
+			if name == "" && len(data) == 0 {
+				continue
+			}
+
+			resp, done, err := dispatch(name, strings.Join(data, "\n"), onDelta)
+			if err != nil || done {
+				return resp, err
+			}
+			name, data = "", nil
+		case strings.HasPrefix(line, ":"):
+			// Comment, used as a keep-alive
+		case strings.HasPrefix(line, "event:"):
+			name = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stream: %w", err)
+	}
+
+	return nil, fmt.Errorf("stream ended without a %s event", EventDone)
+}
+
+// dispatch handles one complete event, reporting whether the stream is finished.
+func dispatch(name, data string, onDelta func(string) error) (*GenerateResponse, bool, error) {
+	switch name {
+	case EventDelta:
+		var delta StreamDelta
+		if err := json.Unmarshal([]byte(data), &delta); err != nil {
+			return nil, true, fmt.Errorf("invalid delta event: %w", err)
+		}
+		if onDelta != nil {
+			if err := onDelta(delta.Text); err != nil {
+				return nil, true, err
+			}
+		}
+		return nil, false, nil
+	case EventDone:
+		var resp GenerateResponse
+		if err := json.Unmarshal([]byte(data), &resp); err != nil {
+			return nil, true, fmt.Errorf("invalid done event: %w", err)
+		}
+		return &resp, true, nil
+	case EventError:
+		var resp ErrorResponse
+		if err := json.Unmarshal([]byte(data), &resp); err != nil {
+			return nil, true, fmt.Errorf("invalid error event: %w", err)
+		}
+		return nil, true, fmt.Errorf("remote agent error: %s", resp.Error)
+	default:
+		// Unknown events are ignored so servers can add new ones
+		return nil, false, nil
+	}
+}
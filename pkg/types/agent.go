@@ -2,6 +2,8 @@ package types
 
 import (
 	"context"
+	"fmt"
+	"time"
 )
 
 // AgentRole represents the role of an agent in the organization.
@@ -13,6 +15,7 @@ const (
 	RoleDirector  AgentRole = "Director"
 	RoleManager   AgentRole = "Manager"
 	RoleEngineer  AgentRole = "Engineer"
+	RoleClient    AgentRole = "Client" // Simulated client that reviews final deliverables
 )
 
 // Agent represents the core interface that all agents must implement.
@@ -42,7 +45,9 @@ type Task struct {
 	ToAgent     string            `json:"to_agent"`
 	Metadata    map[string]string `json:"metadata,omitempty"`
 	Content     string            `json:"content"`
+	RunID       string            `json:"run_id,omitempty"` // Links every task, memory and event of one client request
 	Priority    int               `json:"priority"`
+	Files       []FileArtifact    `json:"files,omitempty"` // Files of a draft the task revises
 }
 
 // TaskResponse represents the response from an agent after processing a task.
@@ -52,6 +57,95 @@ type TaskResponse struct {
 	Result   string            `json:"result"`
 	Metadata map[string]string `json:"metadata,omitempty"`
 	Error    string            `json:"error,omitempty"`
+	Files    []FileArtifact    `json:"files,omitempty"` // Manifest of the files the task produced
+	Usage    *TaskUsage        `json:"usage,omitempty"`
+	Scores   []JudgeScore      `json:"scores,omitempty"`   // Quality scores of the output, when judges are enabled
+	Findings []SecurityFinding `json:"findings,omitempty"` // Security scan findings in Files, when scanning is enabled
+	Subtasks []SubtaskResult   `json:"subtasks,omitempty"` // Outcome of each subtask delegated in parallel
+}
+
+// AgentDescription reports what an agent can do and how busy it is, for
+// orchestrators choosing where to send work.
+type AgentDescription struct {
+	ID             string    `json:"id"`
+	Role           AgentRole `json:"role"`
+	Model          string    `json:"model,omitempty"`
+	Team           string    `json:"team,omitempty"`
+	Capabilities   []string  `json:"capabilities,omitempty"`
+	Status         string    `json:"status"` // "running", "degraded" while a memory backend is unreachable, or "stopped"
+	ActiveTasks    int       `json:"active_tasks"`
+	CompletedTasks int       `json:"completed_tasks"`
+	Version        string    `json:"version"` // BuildBureau version serving the agent
+}
+
+// SubtaskResult is the outcome of one subtask of a response.
+type SubtaskResult struct {
+	TaskID  string     `json:"task_id"`
+	AgentID string     `json:"agent_id"`
+	Title   string     `json:"title"`
+	Status  TaskStatus `json:"status"`
+	Error   string     `json:"error,omitempty"`
+}
+
+// TaskUsage is what a task cost, including every subtask it delegated. Token
+// counts are estimates; the cost is 0 for models without a configured price.
+type TaskUsage struct {
+	AgentID          string        `json:"agent_id"`
+	Subtasks         []*TaskUsage  `json:"subtasks,omitempty"`
+	Latency          time.Duration `json:"latency"` // Wall-clock time from dispatch to response
+	Cost             float64       `json:"cost"`    // In USD
+	PromptTokens     int           `json:"prompt_tokens"`
+	CompletionTokens int           `json:"completion_tokens"`
+	Generations      int           `json:"generations"`
+	Retries          int           `json:"retries"` // Generations retried on another API key and re-planned subtasks
+}
+
+// FileArtifact is a file produced by a task.
+type FileArtifact struct {
+	Provenance *Provenance `json:"provenance,omitempty"`
+	Path       string      `json:"path"`
+	Content    string      `json:"content"`              // Base64-encoded for binary files
+	MediaType  string      `json:"media_type,omitempty"` // Set for binary files, e.g. image/png
+	Bytes      int         `json:"bytes"`
+}
+
+// Binary reports whether the file is binary, its content base64-encoded.
+func (f FileArtifact) Binary() bool {
+	return f.MediaType != ""
+}
+
+// Provenance records how a file was generated.
+type Provenance struct {
+	CreatedAt  time.Time   `json:"created_at"`
+	AgentID    string      `json:"agent_id"`
+	Model      string      `json:"model"`
+	PromptHash string      `json:"prompt_hash"`       // SHA-256 of the prompt that generated the file
+	License    string      `json:"license,omitempty"` // SPDX identifier of the license header added to the file
+	Matches    []CodeMatch `json:"matches,omitempty"` // Passages matching known open-source code verbatim
+}
+
+// CodeMatch is a passage of a file that matches known open-source code verbatim.
+type CodeMatch struct {
+	Source     string `json:"source"`            // File in the known code
+	License    string `json:"license,omitempty"` // SPDX identifier the source declares
+	StartLine  int    `json:"start_line"`
+	EndLine    int    `json:"end_line"`
+	SourceLine int    `json:"source_line"`
+}
+
+// SecurityFinding is a problem a security scanner found in a produced file.
+type SecurityFinding struct {
+	Tool     string `json:"tool"`
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"` // low, medium, high or critical
+	File     string `json:"file"`
+	Message  string `json:"message"`
+	Line     int    `json:"line"`
+}
+
+// String describes the finding on one line.
+func (f SecurityFinding) String() string {
+	return fmt.Sprintf("[%s] %s %s:%d (%s): %s", f.Severity, f.Rule, f.File, f.Line, f.Tool, f.Message)
 }
 
 // TaskStatus represents the status of a task.
@@ -63,4 +157,8 @@ const (
 	StatusCompleted  TaskStatus = "completed"
 	StatusFailed     TaskStatus = "failed"
 	StatusDelegated  TaskStatus = "delegated"
+
+	// StatusPartiallyCompleted marks a response some of whose subtasks
+	// failed while the rest were delivered. Subtasks lists which.
+	StatusPartiallyCompleted TaskStatus = "partially_completed"
 )
@@ -1,24 +1,285 @@
 package types
 
+import "time"
+
 // Config represents the main configuration structure for BuildBureau.
 type Config struct {
-	LLMs         LLMConfig          `yaml:"llms"`
-	Slack        *SlackConfig       `yaml:"slack,omitempty"`
-	Memory       *MemoryConfig      `yaml:"memory,omitempty"`
-	Organization OrganizationConfig `yaml:"organization"`
+	LLMs         LLMConfig           `yaml:"llms"`
+	Slack        *SlackConfig        `yaml:"slack,omitempty"`
+	Desktop      *DesktopConfig      `yaml:"desktop,omitempty"`
+	Outbox       *OutboxConfig       `yaml:"outbox,omitempty"`
+	Memory       *MemoryConfig       `yaml:"memory,omitempty"`
+	Admin        *AdminConfig        `yaml:"admin,omitempty"`
+	Logging      *LoggingConfig      `yaml:"logging,omitempty"`
+	Reports      *ReportsConfig      `yaml:"reports,omitempty"`
+	Workspace    *WorkspaceConfig    `yaml:"workspace,omitempty"`
+	Profiles     *ProfilesConfig     `yaml:"profiles,omitempty"`
+	QuietHours   *QuietHoursConfig   `yaml:"quiet_hours,omitempty"`
+	SLOs         *SLOConfig          `yaml:"slos,omitempty"`
+	Judges       *JudgesConfig       `yaml:"judges,omitempty"`
+	SecurityScan *SecurityScanConfig `yaml:"security_scan,omitempty"`
+	Provenance   *ProvenanceConfig   `yaml:"provenance,omitempty"`
+	Webhooks     *WebhooksConfig     `yaml:"webhooks,omitempty"`
+	Documents    *DocumentsConfig    `yaml:"documents,omitempty"`
+	Images       *ImagesConfig       `yaml:"images,omitempty"`
+	Speech       *SpeechConfig       `yaml:"speech,omitempty"`
+	Demo         *DemoConfig         `yaml:"demo,omitempty"`
+	Hosting      *HostingConfig      `yaml:"hosting,omitempty"`
+	State        *StateConfig        `yaml:"state,omitempty"`
+	Organization OrganizationConfig  `yaml:"organization"`
+}
+
+// LoggingConfig controls the per-agent activity logs written to <dir>/<agent-id>.log.
+type LoggingConfig struct {
+	Dir           string `yaml:"dir"`            // Defaults to ./logs
+	MaxSizeMB     int    `yaml:"max_size_mb"`    // Size at which a log is rotated; defaults to 10
+	MaxBackups    int    `yaml:"max_backups"`    // Rotated files kept per agent; defaults to 3
+	RedactPrompts bool   `yaml:"redact_prompts"` // Log only the size of prompts, not their text
+	Console       bool   `yaml:"console"`        // Also print agent activity to stderr
+	Enabled       bool   `yaml:"enabled"`
+}
+
+// ProfilesConfig controls the long-term agent profiles kept across projects.
+type ProfilesConfig struct {
+	Path    string `yaml:"path"` // Defaults to ./data/profiles.json
+	Enabled bool   `yaml:"enabled"`
+}
+
+// ReportsConfig controls the project reports written when a client task finishes.
+type ReportsConfig struct {
+	Dir     string `yaml:"dir"`    // Defaults to ./reports
+	Format  string `yaml:"format"` // markdown (default) or html
+	Enabled bool   `yaml:"enabled"`
+}
+
+// WorkspaceConfig controls the workspace the files of finished projects are
+// written to, those of each project in <dir>/<run-id>.
+type WorkspaceConfig struct {
+	Dir     string `yaml:"dir"` // Defaults to ./workspace
+	Enabled bool   `yaml:"enabled"`
+}
+
+// AdminConfig defines the admin API used to manage a running organization.
+type AdminConfig struct {
+	Address string              `yaml:"address"` // Listen address; defaults to 127.0.0.1:8090
+	Token   EnvironmentVariable `yaml:"token"`   // Bearer token required by the API, if set
+	Enabled bool                `yaml:"enabled"`
+}
+
+// StateConfig keeps the files BuildBureau writes in state directories rather
+// than relative to the working directory, as suits a service. Relative paths
+// of the other sections are resolved against them, and only one instance at a
+// time may use the work directory.
+type StateConfig struct {
+	WorkDir  string `yaml:"work_dir"`  // Memory database, outbox, profiles, archives, workspaces and reports
+	LogDir   string `yaml:"log_dir"`   // Agent activity logs
+	CacheDir string `yaml:"cache_dir"` // Scratch files, such as files written for security scans
+	System   bool   `yaml:"system"`    // Default to the system-wide directories of a service rather than the user's
+	Enabled  bool   `yaml:"enabled"`
+}
+
+// HostingConfig lists the organizations `buildbureau serve` hosts besides the
+// one configured by the file itself. Each has its own configuration file, so
+// its providers, budgets and memory store are its own.
+type HostingConfig struct {
+	Organizations []HostedOrganizationConfig `yaml:"organizations"`
+	GRPCPort      int                        `yaml:"grpc_port"` // Serves projects over gRPC on this port; 0 disables it
+}
+
+// HostedOrganizationConfig is an organization hosted alongside the main one.
+type HostedOrganizationConfig struct {
+	ID     string `yaml:"id"`     // Addresses the organization in the APIs
+	Config string `yaml:"config"` // Configuration file, relative to the main one
 }
 
 // OrganizationConfig defines the agent hierarchy.
 type OrganizationConfig struct {
-	Layers []LayerConfig `yaml:"layers"`
+	Layers         []LayerConfig          `yaml:"layers"`
+	Acceptance     AcceptanceConfig       `yaml:"acceptance,omitempty"`
+	Recovery       RecoveryConfig         `yaml:"recovery,omitempty"`
+	PartialFailure PartialFailureConfig   `yaml:"partial_failure,omitempty"`
+	Deduplication  DeduplicationConfig    `yaml:"deduplication,omitempty"`
+	Knowledge      KnowledgeSharingConfig `yaml:"knowledge_sharing,omitempty"`
+	StatusReports  StatusReportsConfig    `yaml:"status_reports,omitempty"`
+	Questions      QuestionsConfig        `yaml:"questions,omitempty"`
+	Pairing        PairingConfig          `yaml:"pairing,omitempty"`
+	Blackboard     BlackboardConfig       `yaml:"blackboard,omitempty"`
+	Inbox          InboxConfig            `yaml:"inbox,omitempty"`
+	Admission      AdmissionConfig        `yaml:"admission,omitempty"`
+	Shutdown       ShutdownConfig         `yaml:"shutdown,omitempty"`
+	Teams          []TeamConfig           `yaml:"teams,omitempty"`
+	Partitioning   PartitioningConfig     `yaml:"partitioning,omitempty"`
+	Translation    TranslationConfig      `yaml:"translation,omitempty"`
+	Routing        []RoutingRule          `yaml:"routing,omitempty"`
+	Conventions    ConventionsConfig      `yaml:"conventions,omitempty"`
+}
+
+// ConventionsConfig sets output conventions for the whole organization. They
+// are rendered by a template into the prompt of every agent that produces a
+// deliverable, and deliverables can be checked for deviations from them.
+type ConventionsConfig struct {
+	Language     string `yaml:"language,omitempty"`      // Language of prose, code comments and documentation
+	StyleGuide   string `yaml:"style_guide,omitempty"`   // Code style guide to follow, such as a URL or "Effective Go"
+	CommitFormat string `yaml:"commit_format,omitempty"` // Format of commit messages, such as "Conventional Commits"
+	DocTemplate  string `yaml:"doc_template,omitempty"`  // Path to a template documentation follows
+	Template     string `yaml:"template,omitempty"`      // Text template rendering the conventions; defaults to a built-in one
+	Model        string `yaml:"model,omitempty"`         // Model that checks deliverables; defaults to the president's
+	Validate     bool   `yaml:"validate,omitempty"`      // Check each deliverable for deviations
+	Enabled      bool   `yaml:"enabled"`
+}
+
+// RoutingRule sends work carrying a label to a team or agent. An agent
+// delegating a labeled task follows the first rule whose label the task
+// carries and whose target is among its subordinates; tasks no rule matches
+// are routed as usual.
+type RoutingRule struct {
+	Label string `yaml:"label"` // key=value, such as team=payments
+	To    string `yaml:"to"`    // Team name or agent ID
+}
+
+// TranslationConfig lets clients submit projects in any language. Requests in
+// another language are translated so agents work in the canonical language,
+// and the deliverable is translated back for the requester.
+type TranslationConfig struct {
+	Language string `yaml:"language"` // Canonical language agents work in; defaults to English
+	Model    string `yaml:"model"`    // Model that detects and translates; defaults to the president's
+	Enabled  bool   `yaml:"enabled"`
+}
+
+// PartitioningConfig lets directors split a project by specialty. The
+// director's LLM divides the project into parts, each labeled with the
+// manager whose capabilities fit it; parts that fit no manager go to the
+// least loaded one.
+type PartitioningConfig struct {
+	MaxParts int  `yaml:"max_parts"` // Parts a project is split into at most; defaults to 5
+	Enabled  bool `yaml:"enabled"`
+}
+
+// TeamConfig describes a branch of the organization: its directors, the
+// managers under each director and the engineers under each manager. When
+// teams are configured they replace the Director, Manager and Engineer
+// layers, whose agent definitions remain the defaults for team roles.
+type TeamConfig struct {
+	Name        string         `yaml:"name"`
+	Specialties []string       `yaml:"specialties,omitempty"` // Added to the capabilities of every agent in the team, so matching work is routed to it
+	Director    TeamRoleConfig `yaml:"director,omitempty"`
+	Manager     TeamRoleConfig `yaml:"manager,omitempty"`
+	Engineer    TeamRoleConfig `yaml:"engineer,omitempty"`
+}
+
+// TeamRoleConfig defines the agents of one role in a team.
+type TeamRoleConfig struct {
+	Agent       string   `yaml:"agent,omitempty"`       // Agent definition; defaults to the agent of the layer named after the role
+	Specialties []string `yaml:"specialties,omitempty"` // Added to the team's specialties for this role
+	Count       int      `yaml:"count,omitempty"`       // Directors in the team, or agents under each agent of the role above; defaults to 1
+}
+
+// ShutdownConfig controls graceful shutdown. On SIGTERM the organization stops
+// admitting projects and waits up to DrainTimeout for running ones to finish.
+type ShutdownConfig struct {
+	DrainTimeout time.Duration `yaml:"drain_timeout"` // Defaults to 30s
+}
+
+// AdmissionConfig bounds the projects an organization works on at once.
+// Submissions beyond MaxConcurrent wait in a queue of QueueDepth; once the
+// queue is full, further submissions are rejected.
+type AdmissionConfig struct {
+	MaxConcurrent int `yaml:"max_concurrent"` // Projects processed at once; 0 means unlimited
+	QueueDepth    int `yaml:"queue_depth"`    // Projects that may wait for a slot; 0 rejects as soon as all slots are busy
+	MaxPerUser    int `yaml:"max_per_user"`   // Projects one user may have running or queued; 0 means unlimited
+}
+
+// InboxConfig controls agent inboxes. When enabled, tasks are handed to an
+// agent through a priority queue that its workers drain, so senders never
+// block on a busy agent.
+type InboxConfig struct {
+	Dir               string        `yaml:"dir"`                // Persists unacknowledged messages so they survive restarts; in memory if empty
+	VisibilityTimeout time.Duration `yaml:"visibility_timeout"` // How long a received message stays hidden before it is redelivered; defaults to 30m
+	Workers           int           `yaml:"workers"`            // Messages each agent handles concurrently; defaults to 1
+	Fair              bool          `yaml:"fair"`               // Share agents between projects by weight rather than strictly by priority
+	Enabled           bool          `yaml:"enabled"`
+}
+
+// BlackboardConfig controls shared blackboards: key-value entries and notes
+// that the agents working on one task subtree read and write.
+type BlackboardConfig struct {
+	Scope   string `yaml:"scope"` // Role whose tasks each get a blackboard for their subtree; defaults to Manager
+	Enabled bool   `yaml:"enabled"`
+}
+
+// PairingConfig controls pair work between engineers: one drafts, a second
+// critiques the draft, and the first revises it before submitting.
+type PairingConfig struct {
+	Rounds  map[string]int `yaml:"rounds,omitempty"` // Critique rounds per task complexity (low, medium, high); defaults to 0, 1 and 2
+	Enabled bool           `yaml:"enabled"`
+}
+
+// QuestionsConfig controls clarifying questions agents ask mid-task. Questions
+// travel up the hierarchy; ones no agent can answer are put to the human.
+type QuestionsConfig struct {
+	OnTimeout  string        `yaml:"on_timeout"`   // proceed (default) or fail when nobody answers
+	Timeout    time.Duration `yaml:"timeout"`      // How long to wait for a human answer; defaults to 10m
+	MaxPerTask int           `yaml:"max_per_task"` // Questions an agent may ask per task; defaults to 2
+	Enabled    bool          `yaml:"enabled"`
+}
+
+// StatusReportsConfig controls upward reporting. When enabled, each agent sends
+// its parent a compact status summary (what was done, blockers, artifacts)
+// separate from the deliverable, instead of its full output.
+type StatusReportsConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// KnowledgeSharingConfig controls promotion of lessons learned by one agent
+// to organization-wide knowledge available to all agents.
+type KnowledgeSharingConfig struct {
+	ConflictPolicy     string  `yaml:"conflict_policy"`     // most_confirmed (default) or newest
+	DuplicateThreshold float64 `yaml:"duplicate_threshold"` // Similarity (0-1] above which lessons are duplicates; defaults to 0.8
+	ConflictThreshold  float64 `yaml:"conflict_threshold"`  // Word overlap (0-1] above which differing lessons conflict; defaults to 0.5
+	Limit              int     `yaml:"limit"`               // Lessons included in a prompt; defaults to 5
+	Enabled            bool    `yaml:"enabled"`
+}
+
+// DeduplicationConfig controls sharing of results between engineers that
+// receive nearly identical tasks.
+type DeduplicationConfig struct {
+	Threshold float64       `yaml:"threshold"` // Similarity (0-1] above which tasks are duplicates; defaults to 0.9
+	TTL       time.Duration `yaml:"ttl"`       // How long a finished result can be reused; defaults to 10m
+	Enabled   bool          `yaml:"enabled"`
+}
+
+// RecoveryConfig controls how a delegating agent re-plans a failed branch
+// instead of failing the whole task.
+type RecoveryConfig struct {
+	Strategies []string `yaml:"strategies,omitempty"` // "reassign", "alternative", "reduce_scope"; tried in order
+	MaxReplans int      `yaml:"max_replans"`          // Re-plan attempts per failed branch; 0 disables recovery
+}
+
+// PartialFailureConfig sets how agents treat subtasks delegated in parallel
+// when some succeed and others fail, for every role or for one role.
+type PartialFailureConfig struct {
+	Roles   map[string]PartialFailurePolicy `yaml:"roles,omitempty"` // Policies by role, e.g. "director", overriding the default
+	Default PartialFailurePolicy            `yaml:"default"`
+}
+
+// PartialFailurePolicy is how an agent treats mixed outcomes of its subtasks.
+type PartialFailurePolicy struct {
+	Mode   string  `yaml:"mode"`   // "fail_fast" (default), "best_effort" or "quorum"
+	Quorum float64 `yaml:"quorum"` // Share of subtasks that must succeed under "quorum"; defaults to 0.5
+}
+
+// AcceptanceConfig controls the review cycle run when a Client layer is configured.
+type AcceptanceConfig struct {
+	MaxRevisions int `yaml:"max_revisions"` // Revisions allowed after the client requests changes; defaults to 2
 }
 
 // LayerConfig defines a layer in the organization.
 type LayerConfig struct {
-	Name     string   `yaml:"name"`
-	Agent    string   `yaml:"agent,omitempty"`
-	AttachTo []string `yaml:"attach_to,omitempty"`
-	Count    int      `yaml:"count,omitempty"`
+	Name     string        `yaml:"name"`
+	Agent    string        `yaml:"agent,omitempty"`
+	AttachTo []string      `yaml:"attach_to,omitempty"`
+	Count    int           `yaml:"count,omitempty"`
+	Timeout  time.Duration `yaml:"timeout,omitempty"` // How long an agent of the layer may spend on one task, delegation included; 0 = no limit
 }
 
 // SlackConfig defines Slack notification settings.
@@ -29,26 +290,237 @@ type SlackConfig struct {
 	Enabled  bool                `yaml:"enabled"`
 }
 
+// OutboxConfig stores notifications before they are delivered, so they are
+// retried while Slack or the desktop is unreachable and survive restarts.
+type OutboxConfig struct {
+	Path        string        `yaml:"path"`         // Defaults to ./data/outbox.db
+	MaxAttempts int           `yaml:"max_attempts"` // Attempts before a notification is dead-lettered; defaults to 5
+	Backoff     time.Duration `yaml:"backoff"`      // Delay before the first retry, doubled per attempt; defaults to 5s
+	MaxBackoff  time.Duration `yaml:"max_backoff"`  // Defaults to 10m
+	Enabled     bool          `yaml:"enabled"`
+}
+
+// DocumentsConfig limits how much of each document attached to a submission
+// is included in prompts.
+type DocumentsConfig struct {
+	PDFToText string `yaml:"pdftotext"` // Binary PDFs are converted with; defaults to pdftotext in PATH
+	MaxPages  int    `yaml:"max_pages"` // PDF pages; defaults to 50
+	MaxRows   int    `yaml:"max_rows"`  // Rows per spreadsheet sheet; defaults to 200
+	MaxChars  int    `yaml:"max_chars"` // Characters per document; defaults to 50000
+}
+
+// ImagesConfig enables image generation, with which engineers produce UI
+// mockups, logos and diagrams as part of their deliverables.
+type ImagesConfig struct {
+	APIKey      EnvironmentVariable `yaml:"api_key"`      // Defaults to the openai or gemini key of llms.api_keys
+	Backend     string              `yaml:"backend"`      // openai (DALL·E) or imagen; defaults to openai
+	Model       string              `yaml:"model"`        // Defaults to dall-e-3 or imagen-3.0-generate-002
+	Size        string              `yaml:"size"`         // OpenAI image size; defaults to 1024x1024
+	AspectRatio string              `yaml:"aspect_ratio"` // Imagen aspect ratio; defaults to 1:1
+	Dir         string              `yaml:"dir"`          // Images are stored under <dir>/<run-id>; defaults to ./images
+	MaxPerTask  int                 `yaml:"max_per_task"` // Images one task may request; defaults to 4
+	Enabled     bool                `yaml:"enabled"`
+}
+
+// SpeechConfig enables spoken project digests, rendered with OpenAI
+// text-to-speech when each project finishes.
+type SpeechConfig struct {
+	APIKey  EnvironmentVariable `yaml:"api_key"` // Defaults to the openai key of llms.api_keys
+	Model   string              `yaml:"model"`   // Defaults to tts-1
+	Voice   string              `yaml:"voice"`   // Defaults to alloy
+	Format  string              `yaml:"format"`  // mp3, opus, aac, flac or wav; defaults to mp3
+	Dir     string              `yaml:"dir"`     // Digests are written to <dir>/<run-id>.<format>; defaults to ./digests
+	Enabled bool                `yaml:"enabled"`
+}
+
+// DemoConfig runs the organization as a public demo: every model is served
+// by a provider that costs nothing, projects are kept small, integrations
+// that reach outside the organization are disabled and every deliverable is
+// watermarked.
+type DemoConfig struct {
+	Provider            string `yaml:"provider"`              // mock (default) or ollama
+	OllamaURL           string `yaml:"ollama_url"`            // Defaults to http://localhost:11434
+	OllamaModel         string `yaml:"ollama_model"`          // Defaults to llama3.2
+	Watermark           string `yaml:"watermark"`             // Notice added to every deliverable and text file
+	MaxInstructionChars int    `yaml:"max_instruction_chars"` // Longest request accepted; defaults to 2000
+	ProjectsPerHour     int    `yaml:"projects_per_hour"`     // Projects one client address may have accepted per hour; defaults to 5
+	Enabled             bool   `yaml:"enabled"`
+}
+
+// WebhooksConfig defines the callback URLs that receive signed JSON payloads
+// on project lifecycle events. Submissions can add their own URLs.
+type WebhooksConfig struct {
+	URLs        []string            `yaml:"urls"`         // Receive the events of every project
+	Secret      EnvironmentVariable `yaml:"secret"`       // Key of the HMAC-SHA256 signature of each payload
+	Events      []string            `yaml:"events"`       // Events delivered; defaults to all
+	MaxAttempts int                 `yaml:"max_attempts"` // Attempts per delivery; defaults to 5
+	Backoff     time.Duration       `yaml:"backoff"`      // Delay before the first retry, doubled per attempt; defaults to 2s
+	MaxBackoff  time.Duration       `yaml:"max_backoff"`  // Defaults to 1m
+	Timeout     time.Duration       `yaml:"timeout"`      // Per attempt; defaults to 10s
+	Enabled     bool                `yaml:"enabled"`
+}
+
+// JudgesConfig defines the LLM judges that score deliverables.
+type JudgesConfig struct {
+	Model    string   `yaml:"model"`     // Defaults to the default model
+	Judges   []string `yaml:"judges"`    // correctness, code_quality and security; defaults to all
+	MinScore float64  `yaml:"min_score"` // Deliverables scoring below it on any judge are sent back for revision; 0 never sends them back
+	Enabled  bool     `yaml:"enabled"`
+}
+
+// ProvenanceConfig defines the compliance checks on generated files. Their
+// provenance is recorded regardless.
+type ProvenanceConfig struct {
+	License       string `yaml:"license"`         // SPDX identifier added as a header to generated files; empty adds none
+	Corpus        string `yaml:"corpus"`          // Directory of known open-source code to check files against; empty skips the check
+	MinMatchLines int    `yaml:"min_match_lines"` // Consecutive matching lines that flag a copy; defaults to 12
+}
+
+// SecurityScanConfig defines the security scanners run on produced files.
+type SecurityScanConfig struct {
+	Threshold string          `yaml:"threshold"` // Findings at or above this severity block acceptance; defaults to high
+	Scanners  []ScannerConfig `yaml:"scanners"`
+	Timeout   time.Duration   `yaml:"timeout"` // For all scanners together; defaults to 5m
+	Dir       string          `yaml:"dir"`     // Where files are written to be scanned; the system's temporary directory if empty
+	Enabled   bool            `yaml:"enabled"`
+}
+
+// ScannerConfig configures one security scanner.
+type ScannerConfig struct {
+	Name  string   `yaml:"name"`  // gosec or semgrep
+	Path  string   `yaml:"path"`  // Binary; defaults to the name, looked up in PATH
+	Rules []string `yaml:"rules"` // gosec rule IDs to include, or semgrep --config rulesets
+}
+
+// SLOConfig defines service level objectives on the tasks agents handle,
+// evaluated over a rolling window.
+type SLOConfig struct {
+	Objectives []SLOObjective `yaml:"objectives"`
+	Window     time.Duration  `yaml:"window"`      // Defaults to 1h
+	MinSamples int            `yaml:"min_samples"` // Tasks in the window before an objective is judged; defaults to 10
+	Enabled    bool           `yaml:"enabled"`
+}
+
+// SLOObjective is an objective on task latency or error rate.
+type SLOObjective struct {
+	Name         string        `yaml:"name"`
+	Role         string        `yaml:"role"`           // Role whose tasks count, e.g. "engineer"; empty counts every role
+	Metric       string        `yaml:"metric"`         // "latency" or "error_rate"
+	Percentile   float64       `yaml:"percentile"`     // Latency percentile; defaults to 95
+	MaxLatency   time.Duration `yaml:"max_latency"`    // For latency
+	MaxErrorRate float64       `yaml:"max_error_rate"` // For error_rate, as a fraction such as 0.02
+}
+
+// DesktopConfig defines OS desktop notification settings.
+type DesktopConfig struct {
+	NotifyOn []string `yaml:"notify_on"` // Defaults to approval_requested, project_completed and project_failed
+	Enabled  bool     `yaml:"enabled"`
+}
+
+// QuietHoursConfig defines when notifications are held back and background
+// work pauses, e.g. outside the working hours of the team being notified.
+type QuietHoursConfig struct {
+	Timezone        string        `yaml:"timezone"`      // IANA name such as Europe/Berlin; defaults to the local time zone
+	Notifications   string        `yaml:"notifications"` // "batch" (default) delivers held notifications when quiet hours end; "suppress" drops them
+	Windows         []QuietWindow `yaml:"windows"`
+	Urgent          []string      `yaml:"urgent"` // Notification types delivered even during quiet hours
+	PauseBackground bool          `yaml:"pause_background"`
+	Enabled         bool          `yaml:"enabled"`
+}
+
+// QuietWindow is a recurring quiet period. A window whose end is before its
+// start runs past midnight; one whose start equals its end lasts all day.
+type QuietWindow struct {
+	Start string   `yaml:"start"` // HH:MM
+	End   string   `yaml:"end"`   // HH:MM
+	Days  []string `yaml:"days"`  // Days the window starts on, such as sat or sunday; empty means every day
+}
+
 // LLMConfig defines LLM configuration.
 type LLMConfig struct {
-	APIKeys      map[string]EnvironmentVariable `yaml:"api_keys"`
-	DefaultModel string                         `yaml:"default_model"`
+	APIKeys         map[string]EnvironmentVariable `yaml:"api_keys"`
+	ContextWindows  map[string]int                 `yaml:"context_windows"` // Context window in tokens by model name prefix
+	DefaultModel    string                         `yaml:"default_model"`
+	Reproducibility ReproducibilityConfig          `yaml:"reproducibility"`
+	KeyRotation     KeyRotationConfig              `yaml:"key_rotation"`
+	OutputTokens    OutputTokensConfig             `yaml:"output_tokens"`
+	Prices          map[string]ModelPrice          `yaml:"prices"` // Token prices by model name prefix, for cost reporting
+	ClientPool      ClientPoolConfig               `yaml:"client_pool"`
+	Calls           CallLogConfig                  `yaml:"calls"`
+}
+
+// CallLogConfig controls the log of recent LLM calls: each call's provider,
+// model, tokens, latency and status, kept to find slow models.
+type CallLogConfig struct {
+	SlowThreshold time.Duration `yaml:"slow_threshold"` // Calls taking longer are flagged as slow; defaults to 30s
+	Keep          int           `yaml:"keep"`           // Most recent calls kept; defaults to 1000
+}
+
+// ClientPoolConfig controls the provider clients shared by every agent.
+// Clients are created when the organization starts for the models its agents
+// use, or on first use, and reuse their connections.
+type ClientPoolConfig struct {
+	IdleTimeout         time.Duration `yaml:"idle_timeout"`            // Clients and connections unused this long are dropped; defaults to 10m
+	MaxIdleConnsPerHost int           `yaml:"max_idle_conns_per_host"` // Idle connections kept open to each provider; defaults to 16
+}
+
+// ModelPrice is what a model charges, in USD per million tokens.
+type ModelPrice struct {
+	Input  float64 `yaml:"input"`
+	Output float64 `yaml:"output"`
+}
+
+// OutputTokensConfig sizes the output of a generation by the kind of
+// deliverable expected, and continues generations cut off at that size.
+type OutputTokensConfig struct {
+	Code          int `yaml:"code"`          // Source files; defaults to 8192
+	Spec          int `yaml:"spec"`          // Designs and specifications; defaults to 4096
+	Summary       int `yaml:"summary"`       // Summaries and short answers; defaults to 1024
+	Continuations int `yaml:"continuations"` // Follow-up requests when output reaches its limit; 0 disables continuation
+}
+
+// ReproducibilityConfig makes generations as repeatable as the providers
+// allow, so two runs of the same project can be compared when debugging or
+// evaluating: temperature 0, a fixed sampling seed and pinned model versions.
+type ReproducibilityConfig struct {
+	Models  map[string]string `yaml:"models"` // Model version each provider name is pinned to
+	Seed    int               `yaml:"seed"`   // Sampling seed sent to providers that accept one
+	Enabled bool              `yaml:"enabled"`
+}
+
+// KeyRotationConfig controls how requests are spread across multiple API keys of one provider.
+type KeyRotationConfig struct {
+	Strategy    string        `yaml:"strategy"`     // "round_robin" (default) or "least_used"
+	Quota       int           `yaml:"quota"`        // Requests allowed per key per window; 0 = unlimited
+	QuotaWindow time.Duration `yaml:"quota_window"` // Window after which per-key quotas reset
+	Cooldown    time.Duration `yaml:"cooldown"`     // How long a rate-limited key is taken out of rotation
 }
 
 // EnvironmentVariable represents a value that comes from an environment variable.
+// Envs lists additional variables for settings that accept several values, such as API keys.
 type EnvironmentVariable struct {
-	Env string `yaml:"env"`
+	Env  string   `yaml:"env"`
+	Envs []string `yaml:"envs,omitempty"`
 }
 
 // AgentConfig represents the configuration for an individual agent.
 type AgentConfig struct {
-	Name         string           `yaml:"name"`
-	Role         string           `yaml:"role"`
-	Description  string           `yaml:"description"`
-	Model        string           `yaml:"model,omitempty"`
-	SystemPrompt string           `yaml:"system_prompt"`
-	SubAgents    []SubAgentConfig `yaml:"sub_agents,omitempty"`
-	Capabilities []string         `yaml:"capabilities,omitempty"`
+	Name         string            `yaml:"name"`
+	Role         string            `yaml:"role"`
+	Description  string            `yaml:"description"`
+	Model        string            `yaml:"model,omitempty"`
+	SystemPrompt string            `yaml:"system_prompt"`
+	SubAgents    []SubAgentConfig  `yaml:"sub_agents,omitempty"`
+	Capabilities []string          `yaml:"capabilities,omitempty"`
+	Delegation   *DelegationConfig `yaml:"delegation,omitempty"`
+}
+
+// DelegationConfig controls what context an agent passes down to its subordinates.
+type DelegationConfig struct {
+	Template       string   `yaml:"template"`        // text/template for subtask content; defaults to the role's built-in content
+	Metadata       []string `yaml:"metadata"`        // Task metadata keys forwarded to subtasks; "*" forwards all
+	MaxChars       int      `yaml:"max_chars"`       // Truncate subtask content beyond this many characters; 0 = no limit
+	MemoryExcerpts int      `yaml:"memory_excerpts"` // Related past tasks from the agent's memory available to the template
 }
 
 // SubAgentConfig represents a sub-agent configuration (for remote agents).
@@ -66,26 +538,74 @@ type RemoteConfig struct {
 
 // MemoryConfig represents memory storage configuration.
 type MemoryConfig struct {
-	SQLite    SQLiteConfig    `yaml:"sqlite"`
-	Vald      ValdConfig      `yaml:"vald"`
-	Retention RetentionConfig `yaml:"retention"`
-	Enabled   bool            `yaml:"enabled"`
+	SQLite     SQLiteConfig     `yaml:"sqlite"`
+	Vald       ValdConfig       `yaml:"vald"`
+	Retention  RetentionConfig  `yaml:"retention"`
+	Async      AsyncConfig      `yaml:"async"`
+	Decay      DecayConfig      `yaml:"decay"`
+	Archive    ArchiveConfig    `yaml:"archive"`
+	Compaction CompactionConfig `yaml:"compaction"`
+	Enabled    bool             `yaml:"enabled"`
 }
 
 // SQLiteConfig represents SQLite database configuration.
 type SQLiteConfig struct {
-	Path     string `yaml:"path"`
-	Enabled  bool   `yaml:"enabled"`
-	InMemory bool   `yaml:"in_memory"`
+	Path       string           `yaml:"path"`
+	Encryption EncryptionConfig `yaml:"encryption"`
+	Enabled    bool             `yaml:"enabled"`
+	InMemory   bool             `yaml:"in_memory"`
+}
+
+// EncryptionConfig represents at-rest encryption of memory content.
+type EncryptionConfig struct {
+	Key          EnvironmentVariable   `yaml:"key"`           // Current key, used for new writes: 32 bytes, base64 encoded
+	PreviousKeys []EnvironmentVariable `yaml:"previous_keys"` // Older keys still accepted for reads during rotation
+	Enabled      bool                  `yaml:"enabled"`
+}
+
+// AsyncConfig represents write-behind settings for memory persistence.
+type AsyncConfig struct {
+	QueueSize int  `yaml:"queue_size"` // Pending writes before StoreMemory blocks
+	BatchSize int  `yaml:"batch_size"` // Maximum entries written per transaction
+	Enabled   bool `yaml:"enabled"`
+}
+
+// DecayConfig represents recency weighting applied to semantic search scores.
+type DecayConfig struct {
+	// HalfLife is the age at which a memory's score is halved, per memory type.
+	// Types not listed use built-in defaults; a zero duration disables decay for that type.
+	HalfLife map[MemoryType]time.Duration `yaml:"half_life,omitempty"`
+	Enabled  bool                         `yaml:"enabled"`
+}
+
+// ArchiveConfig moves the memories of runs that have been inactive for a while
+// out of SQLite into compressed bundles, keeping an index to restore them.
+type ArchiveConfig struct {
+	Dir      string        `yaml:"dir"`      // Where bundles are written; a mounted bucket works as object storage
+	After    time.Duration `yaml:"after"`    // Inactivity after which a run is archived
+	Interval time.Duration `yaml:"interval"` // How often to look for runs to archive
+	Enabled  bool          `yaml:"enabled"`
+}
+
+// CompactionConfig condenses each agent's conversations and tasks of a session
+// into a short profile memory when the organization shuts down gracefully.
+// The next session starts from the profile instead of the raw history.
+type CompactionConfig struct {
+	Model       string `yaml:"model"`        // Model that writes the profiles; defaults to each agent's
+	MaxMemories int    `yaml:"max_memories"` // Most recent memories of a session summarized per agent; defaults to 50
+	Enabled     bool   `yaml:"enabled"`
 }
 
 // ValdConfig represents Vald vector database configuration.
 type ValdConfig struct {
-	Host      string `yaml:"host"`
-	Port      int    `yaml:"port"`
-	Dimension int    `yaml:"dimension"`
-	PoolSize  int    `yaml:"pool_size"`
-	Enabled   bool   `yaml:"enabled"`
+	Host                string        `yaml:"host"`
+	Port                int           `yaml:"port"`
+	Dimension           int           `yaml:"dimension"`
+	PoolSize            int           `yaml:"pool_size"`             // Connections calls are spread over; defaults to 1
+	RetryBackoff        time.Duration `yaml:"retry_backoff"`         // Initial delay between connection attempts, doubled each retry
+	DialTimeout         time.Duration `yaml:"dial_timeout"`          // Timeout for a single connection attempt
+	HealthCheckInterval time.Duration `yaml:"health_check_interval"` // How often to check health and wake idle connections
+	Enabled             bool          `yaml:"enabled"`
 }
 
 // RetentionConfig represents memory retention policies.
@@ -0,0 +1,74 @@
+package types
+
+import (
+	"context"
+	"maps"
+	"strings"
+)
+
+// MetadataLabelPrefix prefixes the task and memory metadata keys holding
+// user-defined labels: the label team=payments is kept as "label.team".
+const MetadataLabelPrefix = "label."
+
+type labelsKey struct{}
+
+// WithLabel returns a context whose project submission carries the label
+// key=value in addition to those already set.
+func WithLabel(ctx context.Context, key, value string) context.Context {
+	return WithLabels(ctx, map[string]string{key: value})
+}
+
+// WithLabels returns a context carrying the given labels in addition to those
+// already set. Labels travel with every task of the project and every memory
+// stored for it.
+func WithLabels(ctx context.Context, labels map[string]string) context.Context {
+	if len(labels) == 0 {
+		return ctx
+	}
+	merged := maps.Clone(LabelsFromContext(ctx))
+	for name, value := range labels {
+		if name == "" {
+			continue
+		}
+		if merged == nil {
+			merged = make(map[string]string, len(labels))
+		}
+		merged[name] = value
+	}
+	if len(merged) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, labelsKey{}, merged)
+}
+
+// LabelsFromContext returns the labels set on the context, or nil if there are none.
+func LabelsFromContext(ctx context.Context) map[string]string {
+	labels, _ := ctx.Value(labelsKey{}).(map[string]string)
+	return labels
+}
+
+// Labels returns the labels held in metadata, keyed without their prefix, or
+// nil if there are none.
+func Labels(metadata map[string]string) map[string]string {
+	var labels map[string]string
+	for key, value := range metadata {
+		if name, ok := strings.CutPrefix(key, MetadataLabelPrefix); ok && name != "" {
+			if labels == nil {
+				labels = make(map[string]string)
+			}
+			labels[name] = value
+		}
+	}
+	return labels
+}
+
+// SetLabels adds labels to metadata under their prefixed keys. Labels already
+// in metadata are kept.
+func SetLabels(metadata, labels map[string]string) {
+	for name, value := range labels {
+		key := MetadataLabelPrefix + name
+		if _, ok := metadata[key]; !ok {
+			metadata[key] = value
+		}
+	}
+}
@@ -20,6 +20,7 @@ const (
 type MemoryEntry struct {
 	ID        string            `json:"id"`
 	AgentID   string            `json:"agent_id"`
+	RunID     string            `json:"run_id,omitempty"`
 	Type      MemoryType        `json:"type"`
 	Content   string            `json:"content"`
 	Metadata  map[string]string `json:"metadata,omitempty"`
@@ -36,6 +37,7 @@ type MemoryQuery struct {
 	Metadata      map[string]string `json:"metadata,omitempty"`
 	TimeRange     *TimeRange        `json:"time_range,omitempty"`
 	AgentID       string            `json:"agent_id,omitempty"`
+	RunID         string            `json:"run_id,omitempty"`
 	Type          MemoryType        `json:"type,omitempty"`
 	Content       string            `json:"content,omitempty"`
 	Tags          []string          `json:"tags,omitempty"`
@@ -44,6 +46,92 @@ type MemoryQuery struct {
 	SimilarityMin float32           `json:"similarity_min,omitempty"`
 }
 
+// DeletionAudit records a filtered deletion of memories.
+type DeletionAudit struct {
+	CreatedAt time.Time   `json:"created_at"`
+	ID        string      `json:"id"`
+	Requester string      `json:"requester,omitempty"`
+	Reason    string      `json:"reason,omitempty"`
+	Filter    MemoryQuery `json:"filter"`
+	Deleted   int         `json:"deleted"`
+}
+
+// Assignment records a task delegated to an agent and how it went.
+type Assignment struct {
+	CreatedAt  time.Time     `json:"created_at"`
+	ID         string        `json:"id"`
+	AgentID    string        `json:"agent_id"`
+	AssignedBy string        `json:"assigned_by"`
+	TaskID     string        `json:"task_id"`
+	RunID      string        `json:"run_id,omitempty"`
+	Title      string        `json:"title"`
+	Error      string        `json:"error,omitempty"`
+	Latency    time.Duration `json:"latency"`
+	Succeeded  bool          `json:"succeeded"`
+}
+
+// JudgeScore is a judge's rating of a task's output. The reason is not stored
+// with the score.
+type JudgeScore struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        string    `json:"id,omitempty"`
+	TaskID    string    `json:"task_id,omitempty"`
+	RunID     string    `json:"run_id,omitempty"`
+	Judge     string    `json:"judge"`
+	Reason    string    `json:"reason,omitempty"`
+	Score     float64   `json:"score"`  // From 0 (worst) to 1 (best)
+	Rating    int       `json:"rating"` // On the judge's 1 to 5 rubric
+}
+
+// AgentPerformance summarizes the assignments an agent has handled.
+type AgentPerformance struct {
+	AgentID        string        `json:"agent_id"`
+	AverageLatency time.Duration `json:"average_latency"`
+	SuccessRate    float64       `json:"success_rate"`
+	Assignments    int           `json:"assignments"`
+	Succeeded      int           `json:"succeeded"`
+}
+
+// MemoryRetrieval records an agent looking up its past work for a task.
+type MemoryRetrieval struct {
+	CreatedAt time.Time     `json:"created_at"`
+	ID        string        `json:"id"`
+	AgentID   string        `json:"agent_id"`
+	TaskID    string        `json:"task_id"`
+	RunID     string        `json:"run_id,omitempty"`
+	Latency   time.Duration `json:"latency"`
+	Tokens    int           `json:"tokens"` // Estimated tokens of the memories added to the prompt, if any
+	Hit       bool          `json:"hit"`    // Related memories were found and added to the prompt
+}
+
+// MemoryEffectiveness summarizes whether an agent's memories changed the
+// outcome of its projects: how often lookups found related memories, what
+// they cost, and the average judge score of the projects in which they did
+// and did not. Scores range from 0 to 1 and are 0 without scored projects.
+type MemoryEffectiveness struct {
+	AgentID          string        `json:"agent_id"`
+	AverageLatency   time.Duration `json:"average_latency"`
+	HitRate          float64       `json:"hit_rate"`
+	ScoreWithHits    float64       `json:"score_with_hits"`
+	ScoreWithoutHits float64       `json:"score_without_hits"`
+	Lift             float64       `json:"lift"`    // ScoreWithHits minus ScoreWithoutHits, 0 unless both are known
+	Entries          int           `json:"entries"` // Memories the agent keeps
+	Retrievals       int           `json:"retrievals"`
+	Hits             int           `json:"hits"`
+	AverageTokens    int           `json:"average_tokens"`    // Of the lookups that found memories
+	RunsWithHits     int           `json:"runs_with_hits"`    // Scored projects in which a lookup found memories
+	RunsWithoutHits  int           `json:"runs_without_hits"` // Scored projects in which none did
+}
+
+// ArchivedRun indexes a run whose memories were moved to an archive bundle.
+type ArchivedRun struct {
+	LastActivity time.Time `json:"last_activity"`
+	ArchivedAt   time.Time `json:"archived_at"`
+	RunID        string    `json:"run_id"`
+	Path         string    `json:"path"`
+	Entries      int       `json:"entries"`
+}
+
 // TimeRange represents a time range for queries.
 type TimeRange struct {
 	Start time.Time `json:"start"`
@@ -89,6 +177,33 @@ type VectorStore interface {
 	Close() error
 }
 
+// BulkVectorStore is implemented by vector stores that support batched operations.
+type BulkVectorStore interface {
+	VectorStore
+
+	// MultiInsert adds several vectors in one request
+	MultiInsert(ctx context.Context, items []VectorItem) error
+
+	// MultiSearch performs several similarity searches in one request
+	MultiSearch(ctx context.Context, vectors [][]float32, limit int, minScore float32) ([][]SearchResult, error)
+}
+
+// VectorItem is a vector to insert with its ID and metadata.
+type VectorItem struct {
+	Metadata map[string]string `json:"metadata,omitempty"`
+	ID       string            `json:"id"`
+	Vector   []float32         `json:"vector"`
+}
+
+// StoreHealth reports the connection state of a memory backend.
+type StoreHealth struct {
+	LastCheck  time.Time `json:"last_check"`
+	Name       string    `json:"name"`
+	LastError  string    `json:"last_error,omitempty"`
+	Reconnects int       `json:"reconnects"`
+	Healthy    bool      `json:"healthy"`
+}
+
 // SearchResult represents a vector search result.
 type SearchResult struct {
 	Metadata map[string]string `json:"metadata,omitempty"`
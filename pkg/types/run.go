@@ -0,0 +1,28 @@
+package types
+
+import "context"
+
+// MetadataRunID is the task metadata key used to carry the run ID across process boundaries.
+const MetadataRunID = "run_id"
+
+type runIDKey struct{}
+
+// WithRunID returns a context carrying the run ID that memories and events should be linked to.
+func WithRunID(ctx context.Context, runID string) context.Context {
+	if runID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, runIDKey{}, runID)
+}
+
+// RunIDFromContext returns the run ID carried by ctx, or an empty string.
+func RunIDFromContext(ctx context.Context) string {
+	runID, _ := ctx.Value(runIDKey{}).(string)
+	return runID
+}
+
+// WithoutRunID returns a context whose memories and events are not linked to
+// any run, for data that outlives the run being processed.
+func WithoutRunID(ctx context.Context) context.Context {
+	return context.WithValue(ctx, runIDKey{}, "")
+}
@@ -0,0 +1,41 @@
+package types
+
+import "context"
+
+// MetadataUser is the task metadata key holding the user who submitted a project.
+const MetadataUser = "user"
+
+type userKey struct{}
+
+// WithUser returns a context whose project submission is attributed to the
+// given user, such as the authenticated API caller or the person at the CLI.
+func WithUser(ctx context.Context, user string) context.Context {
+	if user == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// UserFromContext returns the user set on the context, or "" if there is none.
+func UserFromContext(ctx context.Context) string {
+	user, _ := ctx.Value(userKey{}).(string)
+	return user
+}
+
+type clientKey struct{}
+
+// WithClient returns a context whose project submission comes from the given
+// client as identified by the server that received it, such as the network
+// address of the caller. Unlike the user, it is never taken from the request.
+func WithClient(ctx context.Context, client string) context.Context {
+	if client == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, clientKey{}, client)
+}
+
+// ClientFromContext returns the client set on the context, or "" if there is none.
+func ClientFromContext(ctx context.Context) string {
+	client, _ := ctx.Value(clientKey{}).(string)
+	return client
+}
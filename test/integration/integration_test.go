@@ -0,0 +1,246 @@
+//go:build integration
+
+// Package integration runs BuildBureau end to end against the fixtures in
+// docker-compose.yml: a mock LLM speaking the remote agent protocol and a
+// standalone Vald agent. Run it with `make test-integration`, or start the
+// fixtures yourself and run
+//
+//	BUILDBUREAU_IT_LLM_ENDPOINT=http://localhost:18080 \
+//	BUILDBUREAU_IT_VALD_ADDR=localhost:18081 \
+//	go test -tags integration ./test/integration/...
+package integration
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kpango/BuildBureau/internal/agent"
+	"github.com/kpango/BuildBureau/internal/config"
+	"github.com/kpango/BuildBureau/internal/grpc"
+	"github.com/kpango/BuildBureau/internal/memory"
+	"github.com/kpango/BuildBureau/pkg/types"
+)
+
+const (
+	// Environment variables that point the tests at the fixtures.
+	envLLMEndpoint = "BUILDBUREAU_IT_LLM_ENDPOINT"
+	envValdAddr    = "BUILDBUREAU_IT_VALD_ADDR"
+
+	// apiKey is the key the mock LLM requires.
+	apiKey = "integration"
+
+	// embeddingDimension matches the dimension in testdata/vald-agent.yaml.
+	embeddingDimension = 128
+
+	projectTimeout = 2 * time.Minute
+)
+
+// loadConfig loads testdata/config.yaml with the mock LLM as the custom
+// provider and memory in a fresh SQLite database.
+func loadConfig(t *testing.T) *types.Config {
+	t.Helper()
+
+	endpoint := os.Getenv(envLLMEndpoint)
+	if endpoint == "" {
+		t.Skipf("%s is not set; run make test-integration", envLLMEndpoint)
+	}
+	t.Setenv("CUSTOM_LLM_ENDPOINT", endpoint)
+	t.Setenv("BUILDBUREAU_IT_API_KEY", apiKey)
+
+	cfg, err := config.NewLoader().Load(filepath.Join("testdata", "config.yaml"))
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	cfg.Memory.SQLite.Path = filepath.Join(t.TempDir(), "memory.db")
+
+	return cfg
+}
+
+// startOrganization creates and starts an organization, stopping it when the test ends.
+func startOrganization(t *testing.T, cfg *types.Config) *agent.Organization {
+	t.Helper()
+
+	org, err := agent.NewOrganization(cfg)
+	if err != nil {
+		t.Fatalf("failed to create organization: %v", err)
+	}
+	if err := org.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start organization: %v", err)
+	}
+	t.Cleanup(func() { _ = org.Stop(context.Background()) })
+
+	return org
+}
+
+func TestConfig(t *testing.T) {
+	cfg := loadConfig(t)
+
+	if got := len(cfg.Organization.Layers); got != 5 {
+		t.Errorf("expected 5 layers, got %d", got)
+	}
+	if cfg.LLMs.DefaultModel != "custom" {
+		t.Errorf("expected default model custom, got %q", cfg.LLMs.DefaultModel)
+	}
+	if !cfg.Memory.Enabled || !cfg.Memory.SQLite.Enabled {
+		t.Error("expected SQLite memory to be enabled")
+	}
+}
+
+func TestProject(t *testing.T) {
+	cfg := loadConfig(t)
+	org := startOrganization(t, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), projectTimeout)
+	defer cancel()
+
+	resp, err := org.ProcessClientTask(ctx, "Build a command line tool that counts words in a file")
+	if err != nil {
+		t.Fatalf("project failed: %v", err)
+	}
+	if resp.Status != types.StatusCompleted {
+		t.Fatalf("expected completed project, got %s: %s", resp.Status, resp.Error)
+	}
+	if strings.TrimSpace(resp.Result) == "" {
+		t.Error("expected the project to produce a deliverable")
+	}
+	runID := resp.Metadata[types.MetadataRunID]
+	if runID == "" {
+		t.Fatal("expected the response to carry its run ID")
+	}
+
+	t.Run("Memory persists across restarts", func(t *testing.T) {
+		if err := org.Stop(context.Background()); err != nil {
+			t.Fatalf("failed to stop organization: %v", err)
+		}
+
+		mem, err := memory.NewManager(cfg.Memory, nil)
+		if err != nil {
+			t.Fatalf("failed to reopen memory: %v", err)
+		}
+		defer mem.Close()
+
+		entries, err := mem.GetRunMemories(context.Background(), runID)
+		if err != nil {
+			t.Fatalf("failed to read run memories: %v", err)
+		}
+		if len(entries) == 0 {
+			t.Error("expected the project's memories to survive the restart")
+		}
+	})
+}
+
+func TestGRPC(t *testing.T) {
+	cfg := loadConfig(t)
+	org := startOrganization(t, cfg)
+	president := org.GetPresident()
+
+	server := grpc.NewServer(president, 0)
+	if err := server.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start gRPC server: %v", err)
+	}
+	defer server.Stop(context.Background())
+
+	port := server.Addr().(*net.TCPAddr).Port
+	client := grpc.NewClient(net.JoinHostPort("localhost", strconv.Itoa(port)))
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), projectTimeout)
+	defer cancel()
+
+	t.Run("Status", func(t *testing.T) {
+		status, _, _, err := client.GetStatus(ctx, president.GetID())
+		if err != nil {
+			t.Fatalf("GetStatus failed: %v", err)
+		}
+		if status != "running" {
+			t.Errorf("expected status running, got %q", status)
+		}
+	})
+
+	t.Run("ProcessTask", func(t *testing.T) {
+		resp, err := client.ProcessTask(ctx, &types.Task{
+			ID:          "integration-task",
+			Title:       "Word counter",
+			Description: "Build a command line tool that counts words in a file",
+			Content:     "Count words separated by whitespace",
+			FromAgent:   "integration",
+			ToAgent:     president.GetID(),
+			Priority:    1,
+		})
+		if err != nil {
+			t.Fatalf("ProcessTask failed: %v", err)
+		}
+		if resp.Status != types.StatusCompleted {
+			t.Fatalf("expected completed task, got %s: %s", resp.Status, resp.Error)
+		}
+		if resp.TaskID != "integration-task" {
+			t.Errorf("expected task ID to round-trip, got %q", resp.TaskID)
+		}
+	})
+}
+
+func TestVald(t *testing.T) {
+	addr := os.Getenv(envValdAddr)
+	if addr == "" {
+		t.Skipf("%s is not set; run make test-integration", envValdAddr)
+	}
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatalf("invalid %s %q: %v", envValdAddr, addr, err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		t.Fatalf("invalid %s %q: %v", envValdAddr, addr, err)
+	}
+
+	store, err := memory.NewValdStore(types.ValdConfig{
+		Enabled:   true,
+		Host:      host,
+		Port:      port,
+		Dimension: embeddingDimension,
+	})
+	if err != nil {
+		t.Fatalf("failed to create Vald store: %v", err)
+	}
+	defer store.Close()
+
+	// The store connects in the background
+	deadline := time.Now().Add(30 * time.Second)
+	for health := store.Health(); !health.Healthy; health = store.Health() {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected Vald to be reachable, got %s", health.LastError)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	ctx := context.Background()
+	id := fmt.Sprintf("integration-%d", time.Now().UnixNano())
+	vector := make([]float32, embeddingDimension)
+	for i := range vector {
+		vector[i] = float32(i) / embeddingDimension
+	}
+	if err := store.Insert(ctx, id, vector, nil); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	defer store.Delete(ctx, id)
+
+	// The agent indexes inserted vectors in the background
+	deadline = time.Now().Add(30 * time.Second)
+	for {
+		results, err := store.Search(ctx, vector, 1, 0)
+		if err == nil && len(results) > 0 && results[0].ID == id {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("inserted vector was not found: results %v, error %v", results, err)
+		}
+		time.Sleep(time.Second)
+	}
+}